@@ -44,6 +44,13 @@ class QRCodeService {
         return generateQRCode(from: jsonString)
     }
     
+    // MARK: - Generate QR Code for Ticket Token
+    // Tokens come from GET /api/v1/bookings/ticket-token and are signed by the
+    // backend, so unlike booking JSON they cannot be forged or edited
+    func generateTicketQRCode(token: String) -> UIImage? {
+        return generateQRCode(from: token)
+    }
+    
     // MARK: - Generate QR Code for Show
     func generateShowQRCode(show: Show) -> UIImage? {
         let qrData = ShowQRData(
@@ -0,0 +1,119 @@
+package bookings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Amendment is one change to the number of tickets on an existing booking.
+// Amendments are numbered per booking: Version is the booking's version
+// after the change, 1 for the first amendment.
+type Amendment struct {
+	BookingID   string    `json:"booking_id"`
+	Version     int32     `json:"version"`
+	TicketType  string    `json:"ticket_type,omitempty"` // Type added or dropped, for shows with ticket types
+	FromTickets int32     `json:"from_tickets"`
+	ToTickets   int32     `json:"to_tickets"`
+	FromAmount  int32     `json:"from_amount"`
+	ToAmount    int32     `json:"to_amount"`
+	AmountDelta int32     `json:"amount_delta"` // ToAmount - FromAmount; negative when money is owed back
+	ChangedBy   string    `json:"changed_by"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewAmendment records a booking's ticket count and amount due before and
+// after a change
+func NewAmendment(before, after *Booking, version int32, ticketType, changedBy, reason string) *Amendment {
+	return &Amendment{
+		BookingID:   after.BookingID,
+		Version:     version,
+		TicketType:  ticketType,
+		FromTickets: before.NumberOfTickets,
+		ToTickets:   after.NumberOfTickets,
+		FromAmount:  before.TotalAmount,
+		ToAmount:    after.TotalAmount,
+		AmountDelta: after.TotalAmount - before.TotalAmount,
+		ChangedBy:   changedBy,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// TicketDelta returns how many tickets the amendment added (negative when it dropped some)
+func (a *Amendment) TicketDelta() int32 {
+	return a.ToTickets - a.FromTickets
+}
+
+// AmendTickets changes the booking to numberOfTickets tickets by adding or
+// dropping tickets of ticketType, which may be left empty for shows without
+// ticket types or bookings of a single type. Added tickets cost the unit
+// price the booking was made at, less the same share of discount; dropped
+// tickets take their share of the discount with them, as in RemoveTicket.
+// It returns the ticket type that was changed.
+func (b *Booking) AmendTickets(ticketType string, numberOfTickets int32) (string, error) {
+	if numberOfTickets <= 0 {
+		return "", fmt.Errorf("invalid amendment: number_of_tickets must be greater than 0; cancel the booking instead")
+	}
+
+	ticketType = strings.ToLower(strings.TrimSpace(ticketType))
+	switch {
+	case len(b.TicketLines) == 0 && ticketType != "":
+		return "", fmt.Errorf("invalid amendment: booking %s has no ticket types", b.BookingID)
+	case len(b.TicketLines) == 1 && ticketType == "":
+		ticketType = b.TicketLines[0].TicketType
+	case len(b.TicketLines) > 1 && ticketType == "":
+		return "", fmt.Errorf("invalid amendment: ticket_type is required for bookings with several ticket types")
+	}
+
+	delta := numberOfTickets - b.NumberOfTickets
+	if delta == 0 {
+		return "", fmt.Errorf("invalid amendment: booking %s already has %d tickets", b.BookingID, numberOfTickets)
+	}
+
+	if delta > 0 {
+		return ticketType, b.addTickets(ticketType, delta)
+	}
+	for i := delta; i < 0; i++ {
+		if _, err := b.RemoveTicket(ticketType); err != nil {
+			return "", fmt.Errorf("invalid amendment: %w", err)
+		}
+	}
+	return ticketType, nil
+}
+
+// addTickets adds count tickets of the given type at the booking's own unit
+// price and effective discount
+func (b *Booking) addTickets(ticketType string, count int32) error {
+	gross := b.TotalAmount + b.DiscountAmount
+	ticketPrice := int32(0)
+	if b.NumberOfTickets > 0 {
+		ticketPrice = gross / b.NumberOfTickets
+	}
+
+	if len(b.TicketLines) > 0 {
+		var line *TicketLine
+		for _, candidate := range b.TicketLines {
+			if candidate.TicketType == ticketType {
+				line = candidate
+				break
+			}
+		}
+		if line == nil {
+			return fmt.Errorf("invalid amendment: booking %s has no %s tickets", b.BookingID, ticketType)
+		}
+		ticketPrice = line.UnitPrice
+		line.Quantity += count
+	}
+
+	added := ticketPrice * count
+	charged := added
+	if gross > 0 {
+		charged = int32(int64(b.TotalAmount) * int64(added) / int64(gross))
+	}
+	b.NumberOfTickets += count
+	b.TotalAmount += charged
+	b.DiscountAmount += added - charged
+	return nil
+}
@@ -0,0 +1,83 @@
+package bookings
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAmendTickets(t *testing.T) {
+	// 4 x 100 with 10% off
+	booking := NewBooking(uuid.New(), "email", "fan@example.com", 4, 360)
+	booking.DiscountAmount = 40
+	before := *booking
+
+	if _, err := booking.AmendTickets("", 6); err != nil {
+		t.Fatalf("AmendTickets() error: %v", err)
+	}
+	if booking.NumberOfTickets != 6 || booking.TotalAmount != 540 || booking.DiscountAmount != 60 {
+		t.Errorf("Expected 6 tickets for 540 with 60 off, got %d for %d with %d off",
+			booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount)
+	}
+
+	amendment := NewAmendment(&before, booking, 1, "", "api", "two friends joined")
+	if amendment.AmountDelta != 180 || amendment.TicketDelta() != 2 || amendment.Version != 1 {
+		t.Errorf("Expected +2 tickets for +180 at version 1, got %+v", amendment)
+	}
+
+	if _, err := booking.AmendTickets("", 3); err != nil {
+		t.Fatalf("AmendTickets() error: %v", err)
+	}
+	if booking.NumberOfTickets != 3 || booking.TotalAmount != 270 || booking.DiscountAmount != 30 {
+		t.Errorf("Expected 3 tickets for 270 with 30 off, got %d for %d with %d off",
+			booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount)
+	}
+
+	for _, numberOfTickets := range []int32{0, 3} {
+		if _, err := booking.AmendTickets("", numberOfTickets); err == nil {
+			t.Errorf("Expected error amending to %d tickets", numberOfTickets)
+		}
+	}
+	if _, err := booking.AmendTickets("adult", 4); err == nil {
+		t.Error("Expected error for a ticket type on a single-price booking")
+	}
+}
+
+func TestAmendTicketsWithTicketTypes(t *testing.T) {
+	typed := NewBooking(uuid.New(), "email", "fan@example.com", 3, 12500)
+	typed.TicketLines = []*TicketLine{
+		{TicketType: "adult", Quantity: 2, UnitPrice: 5000},
+		{TicketType: "child", Quantity: 1, UnitPrice: 2500},
+	}
+
+	if _, err := typed.AmendTickets("", 4); err == nil {
+		t.Error("Expected error without a ticket type on a booking with several types")
+	}
+	if _, err := typed.AmendTickets("senior", 4); err == nil {
+		t.Error("Expected error for a ticket type the booking does not have")
+	}
+
+	ticketType, err := typed.AmendTickets(" Child ", 4)
+	if err != nil {
+		t.Fatalf("AmendTickets() error: %v", err)
+	}
+	if ticketType != "child" || typed.TotalAmount != 15000 || typed.TicketLines[1].Quantity != 2 {
+		t.Errorf("Expected 2 child tickets for 15000, got %+v for %d", typed.TicketLines, typed.TotalAmount)
+	}
+
+	if _, err := typed.AmendTickets("adult", 2); err != nil {
+		t.Fatalf("AmendTickets() error: %v", err)
+	}
+	if typed.TotalAmount != 5000 || len(typed.TicketLines) != 1 || typed.TicketLines[0].TicketType != "child" {
+		t.Errorf("Expected only the child line for 5000, got %+v for %d", typed.TicketLines, typed.TotalAmount)
+	}
+
+	// A single remaining type is used when none is given
+	ticketType, err = typed.AmendTickets("", 3)
+	if err != nil {
+		t.Fatalf("AmendTickets() error: %v", err)
+	}
+	if ticketType != "child" || typed.TotalAmount != 7500 {
+		t.Errorf("Expected 3 child tickets for 7500, got %s for %d", ticketType, typed.TotalAmount)
+	}
+}
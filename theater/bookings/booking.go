@@ -1,9 +1,11 @@
 package bookings
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,26 +16,38 @@ import (
 
 // Booking represents a theater booking
 type Booking struct {
-	BookingID       string    `json:"booking_id"`       // Hash-generated unique ID
-	ShowID          uuid.UUID `json:"show_id"`          // Reference to the show
-	ContactType     string    `json:"contact_type"`     // "mobile" or "email"
-	ContactValue    string    `json:"contact_value"`    // Mobile number or email address
-	NumberOfTickets int32     `json:"number_of_tickets"`
-	BookingDate     time.Time `json:"booking_date"`
-	Status          string    `json:"status"`           // "confirmed", "pending", "cancelled"
-	CustomerName    string    `json:"customer_name,omitempty"`
-	TotalAmount     int32     `json:"total_amount"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	BookingID       string        `json:"booking_id"`    // Hash-generated internal ID
+	Reference       string        `json:"reference"`     // Customer-facing code such as K7MPQ-3XR9A, assigned when saved
+	ShowID          uuid.UUID     `json:"show_id"`       // Reference to the show
+	ContactType     string        `json:"contact_type"`  // "mobile" or "email"
+	ContactValue    string        `json:"contact_value"` // Mobile number or email address
+	NumberOfTickets int32         `json:"number_of_tickets"`
+	BookingDate     time.Time     `json:"booking_date"`
+	Status          string        `json:"status"` // One of the Status* constants
+	CustomerName    string        `json:"customer_name,omitempty"`
+	TotalAmount     int32         `json:"total_amount"` // Amount due after any discount
+	PromoCode       string        `json:"promo_code,omitempty"`
+	DiscountAmount  int32         `json:"discount_amount,omitempty"` // Taken off the ticket prices by PromoCode
+	HoldExpiresAt   *time.Time    `json:"hold_expires_at,omitempty"` // When a pending booking releases its tickets
+	Seats           []string      `json:"seats,omitempty"`           // Seat IDs for reserved-seating shows
+	TicketLines     []*TicketLine `json:"ticket_lines,omitempty"`    // Per-type breakdown for shows with ticket types
+	OrderID         string        `json:"order_id,omitempty"`        // Parent order for bookings made through a multi-show order
+	UserID          string        `json:"user_id,omitempty"`         // Account of the logged-in customer who made the booking
+	BalanceDue      int32         `json:"balance_due,omitempty"`     // Owed for tickets added to a confirmed booking, or a dearer exchange
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
 }
 
 // BookingRequest represents the request payload for creating a booking
 type BookingRequest struct {
-	ShowID          string `json:"show_id"`
-	ContactType     string `json:"contact_type"`     // "mobile" or "email"
-	ContactValue    string `json:"contact_value"`
-	NumberOfTickets int32  `json:"number_of_tickets"`
-	CustomerName    string `json:"customer_name,omitempty"`
+	ShowID          string           `json:"show_id"`
+	ContactType     string           `json:"contact_type"` // "mobile" or "email"
+	ContactValue    string           `json:"contact_value"`
+	NumberOfTickets int32            `json:"number_of_tickets"` // May be omitted when ticket_types is given
+	CustomerName    string           `json:"customer_name,omitempty"`
+	Seats           []string         `json:"seats,omitempty"`        // Required for reserved-seating shows
+	TicketTypes     map[string]int32 `json:"ticket_types,omitempty"` // Quantity per ticket type code, e.g. {"adult": 2}
+	PromoCode       string           `json:"promo_code,omitempty"`
 }
 
 // NewBooking creates a new booking with generated hash ID
@@ -47,7 +61,7 @@ func NewBooking(showID uuid.UUID, contactType, contactValue string, numberOfTick
 		NumberOfTickets: numberOfTickets,
 		TotalAmount:     totalAmount,
 		BookingDate:     now,
-		Status:          "pending",
+		Status:          StatusPending,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
@@ -64,8 +78,15 @@ func NewBookingFromRequest(r *http.Request) (*Booking, error) {
 	contactValue := r.URL.Query().Get("contact_value")
 	numberOfTicketsStr := r.URL.Query().Get("number_of_tickets")
 	customerName := r.URL.Query().Get("customer_name")
+	seats := ParseSeatList(r.URL.Query().Get("seats"))
+	promoCode := r.URL.Query().Get("promo_code")
 	
-	if showIDStr == "" || contactType == "" || contactValue == "" || numberOfTicketsStr == "" {
+	ticketTypes, err := ParseTicketSelection(r.URL.Query().Get("ticket_types"))
+	if err != nil {
+		return nil, err
+	}
+	
+	if showIDStr == "" || contactType == "" || contactValue == "" || (numberOfTicketsStr == "" && len(ticketTypes) == 0) {
 		return nil, fmt.Errorf("missing required parameters: show_id, contact_type, contact_value, number_of_tickets")
 	}
 	
@@ -74,21 +95,21 @@ func NewBookingFromRequest(r *http.Request) (*Booking, error) {
 		return nil, fmt.Errorf("invalid show_id format: %w", err)
 	}
 	
-	numberOfTickets, err := strconv.ParseInt(numberOfTicketsStr, 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("invalid number_of_tickets: %w", err)
-	}
-	
-	if numberOfTickets <= 0 {
-		return nil, fmt.Errorf("number_of_tickets must be greater than 0")
-	}
-	
-	if !isValidContactType(contactType) {
-		return nil, fmt.Errorf("contact_type must be either 'mobile' or 'email'")
+	var numberOfTickets int64
+	if numberOfTicketsStr != "" {
+		numberOfTickets, err = strconv.ParseInt(numberOfTicketsStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number_of_tickets: %w", err)
+		}
+		
+		if numberOfTickets <= 0 {
+			return nil, fmt.Errorf("number_of_tickets must be greater than 0")
+		}
 	}
 	
-	if !isValidContactValue(contactType, contactValue) {
-		return nil, fmt.Errorf("invalid contact_value for contact_type %s", contactType)
+	contactValue, err = NormalizeContact(contactType, contactValue)
+	if err != nil {
+		return nil, err
 	}
 	
 	now := time.Now()
@@ -98,12 +119,18 @@ func NewBookingFromRequest(r *http.Request) (*Booking, error) {
 		ContactValue:    contactValue,
 		NumberOfTickets: int32(numberOfTickets),
 		CustomerName:    customerName,
+		Seats:           seats,
+		PromoCode:       normalizePromoCode(promoCode),
 		BookingDate:     now,
-		Status:          "pending",
+		Status:          StatusPending,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
 	
+	if err := booking.applyTicketSelection(ticketTypes); err != nil {
+		return nil, err
+	}
+	
 	booking.BookingID = booking.generateHashID()
 	return booking, nil
 }
@@ -115,7 +142,12 @@ func NewBookingFromJSON(r *http.Request) (*Booking, error) {
 		return nil, fmt.Errorf("invalid JSON payload: %w", err)
 	}
 	
-	if req.ShowID == "" || req.ContactType == "" || req.ContactValue == "" || req.NumberOfTickets <= 0 {
+	return NewBookingFromPayload(&req)
+}
+
+// NewBookingFromPayload validates a decoded booking request and creates a draft booking
+func NewBookingFromPayload(req *BookingRequest) (*Booking, error) {
+	if req.ShowID == "" || req.ContactType == "" || req.ContactValue == "" || (req.NumberOfTickets <= 0 && len(req.TicketTypes) == 0) {
 		return nil, fmt.Errorf("missing required fields: show_id, contact_type, contact_value, number_of_tickets")
 	}
 	
@@ -124,40 +156,50 @@ func NewBookingFromJSON(r *http.Request) (*Booking, error) {
 		return nil, fmt.Errorf("invalid show_id format: %w", err)
 	}
 	
-	if !isValidContactType(req.ContactType) {
-		return nil, fmt.Errorf("contact_type must be either 'mobile' or 'email'")
-	}
-	
-	if !isValidContactValue(req.ContactType, req.ContactValue) {
-		return nil, fmt.Errorf("invalid contact_value for contact_type %s", req.ContactType)
+	contactValue, err := NormalizeContact(req.ContactType, req.ContactValue)
+	if err != nil {
+		return nil, err
 	}
 	
 	now := time.Now()
 	booking := &Booking{
 		ShowID:          showID,
 		ContactType:     req.ContactType,
-		ContactValue:    req.ContactValue,
+		ContactValue:    contactValue,
 		NumberOfTickets: req.NumberOfTickets,
 		CustomerName:    req.CustomerName,
+		Seats:           req.Seats,
+		PromoCode:       normalizePromoCode(req.PromoCode),
 		BookingDate:     now,
-		Status:          "pending",
+		Status:          StatusPending,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
 	
+	if err := booking.applyTicketSelection(req.TicketTypes); err != nil {
+		return nil, err
+	}
+	
 	booking.BookingID = booking.generateHashID()
 	return booking, nil
 }
 
-// generateHashID creates a unique hash-based ID from booking information
+// generateHashID creates a unique hash-based ID from booking information.
+// A random nonce keeps identical bookings made at the same moment apart.
 func (b *Booking) generateHashID() string {
-	// Create hash from show_id + contact_type + contact_value + booking_date + number_of_tickets
-	hashInput := fmt.Sprintf("%s:%s:%s:%d:%d",
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		log.Fatalf("Failed to generate booking ID nonce: %v", err)
+	}
+
+	// Create hash from show_id + contact_type + contact_value + booking_date + number_of_tickets + nonce
+	hashInput := fmt.Sprintf("%s:%s:%s:%d:%d:%x",
 		b.ShowID.String(),
 		b.ContactType,
 		b.ContactValue,
-		b.BookingDate.Unix(),
+		b.BookingDate.UnixNano(),
 		b.NumberOfTickets,
+		nonce,
 	)
 	
 	hash := sha256.Sum256([]byte(hashInput))
@@ -165,21 +207,43 @@ func (b *Booking) generateHashID() string {
 	return fmt.Sprintf("BK-%X", hash)[:18] // BK- prefix + 16 hex chars
 }
 
-// UpdateStatus updates the booking status and timestamp
+// UpdateStatus updates the booking status and timestamp.
+// A booking that leaves pending for confirmed no longer needs its hold,
+// and one that releases its tickets gives up its seats.
 func (b *Booking) UpdateStatus(status string) {
 	b.Status = status
 	b.UpdatedAt = time.Now()
+	if status == StatusConfirmed {
+		b.HoldExpiresAt = nil
+	}
+	if !HoldsInventory(status) {
+		b.Seats = nil
+	}
+}
+
+// PlaceHold keeps the tickets of a pending booking reserved for the given duration
+func (b *Booking) PlaceHold(duration time.Duration) {
+	expiresAt := time.Now().Add(duration)
+	b.HoldExpiresAt = &expiresAt
+}
+
+// IsHoldExpired reports whether a pending booking's hold has lapsed
+func (b *Booking) IsHoldExpired(now time.Time) bool {
+	return b.Status == StatusPending && b.HoldExpiresAt != nil && !now.Before(*b.HoldExpiresAt)
+}
+
+// CheckStatusChange rejects status changes the state machine does not allow,
+// including confirming a booking whose hold has lapsed
+func (b *Booking) CheckStatusChange(status string, now time.Time) error {
+	if status == StatusConfirmed && (b.Status == StatusExpired || b.IsHoldExpired(now)) {
+		return ErrHoldExpired
+	}
+	return ValidateTransition(b.Status, status)
 }
 
 // IsValidStatus checks if the status is valid
 func (b *Booking) IsValidStatus() bool {
-	validStatuses := []string{"pending", "confirmed", "cancelled"}
-	for _, valid := range validStatuses {
-		if b.Status == valid {
-			return true
-		}
-	}
-	return false
+	return IsKnownStatus(b.Status)
 }
 
 // ToJSON converts booking to JSON string
@@ -200,6 +264,7 @@ func (b *Booking) FromJSON(data string) error {
 func (b *Booking) ToMap() map[string]interface{} {
 	return map[string]interface{}{
 		"booking_id":        b.BookingID,
+		"reference":         b.Reference,
 		"show_id":           b.ShowID.String(),
 		"contact_type":      b.ContactType,
 		"contact_value":     b.ContactValue,
@@ -224,23 +289,49 @@ type BookingResponse struct {
 
 // Helper functions for validation
 
+// ParseSeatList splits a comma-separated seat list such as "STALLS-A-1,STALLS-A-2"
+func ParseSeatList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var seats []string
+	for _, seat := range strings.Split(value, ",") {
+		if seat = strings.TrimSpace(seat); seat != "" {
+			seats = append(seats, seat)
+		}
+	}
+	return seats
+}
+
+// ValidateContact checks a contact type and value pair the same way booking requests are checked
+func ValidateContact(contactType, contactValue string) error {
+	_, err := NormalizeContact(contactType, contactValue)
+	return err
+}
+
+// ApplyDiscount takes a promo code discount off the booking's priced total.
+// Call it after ApplyPrices.
+func (b *Booking) ApplyDiscount(discount int32) {
+	if discount > b.TotalAmount {
+		discount = b.TotalAmount
+	}
+	b.DiscountAmount = discount
+	b.TotalAmount -= discount
+}
+
+// normalizePromoCode upper-cases and trims a promo code as entered by a customer
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
 func isValidContactType(contactType string) bool {
-	return contactType == "mobile" || contactType == "email"
+	return contactType == ContactMobile || contactType == ContactEmail
 }
 
 func isValidContactValue(contactType, contactValue string) bool {
-	switch contactType {
-	case "mobile":
-		// Basic mobile number validation (10-15 digits, may include + and -)
-		return len(contactValue) >= 10 && len(contactValue) <= 15 && 
-			   strings.ContainsAny(contactValue, "0123456789")
-	case "email":
-		// Basic email validation
-		return strings.Contains(contactValue, "@") && strings.Contains(contactValue, ".") &&
-			   len(contactValue) > 5
-	default:
-		return false
-	}
+	_, err := NormalizeContact(contactType, contactValue)
+	return err == nil
 }
 
 // GetBookingsByShow returns bookings for a specific show (to be used by repository)
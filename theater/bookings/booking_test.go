@@ -280,6 +280,79 @@ func TestBookingUpdateStatus(t *testing.T) {
 	}
 }
 
+func TestBookingConfirmClearsHold(t *testing.T) {
+	booking := NewBooking(uuid.New(), "mobile", "1234567890", 2, 200)
+	booking.PlaceHold(15 * time.Minute)
+
+	booking.UpdateStatus(StatusConfirmed)
+
+	if booking.HoldExpiresAt != nil {
+		t.Errorf("Expected hold to be cleared on confirmation, got %v", booking.HoldExpiresAt)
+	}
+}
+
+func TestBookingHoldExpiry(t *testing.T) {
+	booking := NewBooking(uuid.New(), "mobile", "1234567890", 2, 200)
+	now := time.Now()
+
+	if booking.IsHoldExpired(now) {
+		t.Error("Booking without a hold should never expire")
+	}
+
+	booking.PlaceHold(15 * time.Minute)
+
+	if booking.HoldExpiresAt == nil {
+		t.Fatal("Expected HoldExpiresAt to be set")
+	}
+
+	if booking.IsHoldExpired(now) {
+		t.Error("Hold should not be expired yet")
+	}
+
+	if !booking.IsHoldExpired(now.Add(16 * time.Minute)) {
+		t.Error("Hold should be expired after its duration")
+	}
+
+	booking.Status = StatusConfirmed
+	if booking.IsHoldExpired(now.Add(16 * time.Minute)) {
+		t.Error("Only pending bookings can have an expired hold")
+	}
+}
+
+func TestBookingCheckStatusChange(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	active := now.Add(time.Minute)
+
+	tests := []struct {
+		name          string
+		status        string
+		holdExpiresAt *time.Time
+		newStatus     string
+		expectError   bool
+	}{
+		{"confirm active hold", StatusPending, &active, StatusConfirmed, false},
+		{"confirm booking without hold", StatusPending, nil, StatusConfirmed, false},
+		{"confirm lapsed hold", StatusPending, &expired, StatusConfirmed, true},
+		{"confirm expired booking", StatusExpired, &expired, StatusConfirmed, true},
+		{"cancel lapsed hold", StatusPending, &expired, StatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := &Booking{Status: tt.status, HoldExpiresAt: tt.holdExpiresAt}
+			err := booking.CheckStatusChange(tt.newStatus, now)
+
+			if tt.expectError && err != ErrHoldExpired {
+				t.Errorf("Expected ErrHoldExpired, got %v", err)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestBookingIsValidStatus(t *testing.T) {
 	tests := []struct {
 		status string
@@ -288,6 +361,10 @@ func TestBookingIsValidStatus(t *testing.T) {
 		{"pending", true},
 		{"confirmed", true},
 		{"cancelled", true},
+		{"expired", true},
+		{"checked_in", true},
+		{"refunded", true},
+		{"no_show", true},
 		{"invalid", false},
 		{"", false},
 	}
@@ -421,9 +498,11 @@ func TestIsValidContactValue(t *testing.T) {
 		valid        bool
 	}{
 		{"mobile", "1234567890", true},
-		{"mobile", "123456789012345", true},
+		{"mobile", "+44 7700 900123", true},
+		{"mobile", "123456789012345", false}, // Too long for a national number in the default region
 		{"mobile", "123", false},
 		{"mobile", "abc", false},
+		{"mobile", "555-CALL-NOW", false},
 		{"email", "test@example.com", true},
 		{"email", "test@example", false},
 		{"email", "test", false},
@@ -439,3 +518,70 @@ func TestIsValidContactValue(t *testing.T) {
 		})
 	}
 }
+
+func TestBookingCheckStatusChangeRejectsInvalidTransition(t *testing.T) {
+	booking := &Booking{Status: StatusCancelled}
+
+	err := booking.CheckStatusChange(StatusConfirmed, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "invalid status transition") {
+		t.Errorf("Expected invalid status transition error, got %v", err)
+	}
+}
+
+func TestNewBookingFromRequestWithSeats(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/bookings/create?show_id="+uuid.New().String()+
+		"&contact_type=email&contact_value=test@example.com&number_of_tickets=2&seats=STALLS-A-1,%20STALLS-A-2,", nil)
+
+	booking, err := NewBookingFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewBookingFromRequest() error: %v", err)
+	}
+
+	if len(booking.Seats) != 2 || booking.Seats[0] != "STALLS-A-1" || booking.Seats[1] != "STALLS-A-2" {
+		t.Errorf("Expected seats [STALLS-A-1 STALLS-A-2], got %v", booking.Seats)
+	}
+}
+
+func TestBookingReleaseClearsSeats(t *testing.T) {
+	booking := &Booking{Status: StatusPending, Seats: []string{"STALLS-A-1"}}
+
+	booking.UpdateStatus(StatusConfirmed)
+	if len(booking.Seats) != 1 {
+		t.Errorf("Confirming should keep seats, got %v", booking.Seats)
+	}
+
+	booking.UpdateStatus(StatusCancelled)
+	if booking.Seats != nil {
+		t.Errorf("Cancelling should release seats, got %v", booking.Seats)
+	}
+}
+
+func TestBookingApplyDiscount(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/bookings/create?show_id="+uuid.New().String()+
+		"&contact_type=email&contact_value=test@example.com&number_of_tickets=2&promo_code=%20spring20", nil)
+
+	booking, err := NewBookingFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewBookingFromRequest() error: %v", err)
+	}
+	if booking.PromoCode != "SPRING20" {
+		t.Errorf("Expected promo code SPRING20, got %q", booking.PromoCode)
+	}
+
+	if err := booking.ApplyPrices(5000, nil); err != nil {
+		t.Fatalf("ApplyPrices() error: %v", err)
+	}
+	booking.ApplyDiscount(2000)
+	if booking.TotalAmount != 8000 || booking.DiscountAmount != 2000 {
+		t.Errorf("Expected total 8000 with discount 2000, got %d and %d", booking.TotalAmount, booking.DiscountAmount)
+	}
+
+	// Repricing clears the discount so it is never applied twice
+	if err := booking.ApplyPrices(5000, nil); err != nil {
+		t.Fatalf("ApplyPrices() error: %v", err)
+	}
+	booking.ApplyDiscount(20000)
+	if booking.TotalAmount != 0 || booking.DiscountAmount != 10000 {
+		t.Errorf("Expected discount capped at 10000, got total %d and discount %d", booking.TotalAmount, booking.DiscountAmount)
+	}
+}
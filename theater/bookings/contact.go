@@ -0,0 +1,251 @@
+package bookings
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// Contact types a booking can be made with
+const (
+	ContactMobile = "mobile"
+	ContactEmail  = "email"
+)
+
+const (
+	// maxE164Digits is the most digits an E.164 number can have, country code included
+	maxE164Digits = 15
+	// minE164Digits is the fewest digits accepted for a number in a region the
+	// table below does not describe
+	minE164Digits = 8
+	// maxEmailLength and maxEmailLocalLength are the RFC 5321 limits
+	maxEmailLength      = 254
+	maxEmailLocalLength = 64
+)
+
+// phoneRegion describes how numbers are dialled within a region
+type phoneRegion struct {
+	callingCode string // Country calling code, e.g. "44"
+	trunkPrefix string // Dialled before national numbers and dropped in E.164, e.g. "0"
+	minLength   int    // Shortest national significant number
+	maxLength   int    // Longest national significant number
+}
+
+// phoneRegions are the regions national numbers can be read in, by ISO 3166
+// code. Numbers written with a country code may be from anywhere; those from
+// these regions also have their length checked.
+var phoneRegions = map[string]phoneRegion{
+	"AE": {"971", "0", 8, 9},
+	"AU": {"61", "0", 9, 9},
+	"CA": {"1", "1", 10, 10},
+	"DE": {"49", "0", 10, 11},
+	"ES": {"34", "", 9, 9},
+	"FR": {"33", "0", 9, 9},
+	"GB": {"44", "0", 9, 10},
+	"IE": {"353", "0", 7, 9},
+	"IN": {"91", "0", 10, 10},
+	"IT": {"39", "", 9, 10},
+	"NL": {"31", "0", 9, 9},
+	"NZ": {"64", "0", 8, 10},
+	"SG": {"65", "", 8, 8},
+	"US": {"1", "1", 10, 10},
+	"ZA": {"27", "0", 9, 9},
+}
+
+// defaultPhoneRegion is the region mobile numbers without a country code are read in
+var defaultPhoneRegion = "US"
+
+// SetDefaultPhoneRegion sets the region mobile numbers written without a
+// country code are read in. Call it at startup, before contacts are parsed.
+func SetDefaultPhoneRegion(region string) error {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if _, ok := phoneRegions[region]; !ok {
+		return fmt.Errorf("unsupported phone region %q: expected one of %s", region, strings.Join(PhoneRegions(), ", "))
+	}
+	defaultPhoneRegion = region
+	return nil
+}
+
+// DefaultPhoneRegion returns the region mobile numbers without a country code are read in
+func DefaultPhoneRegion() string {
+	return defaultPhoneRegion
+}
+
+// PhoneRegions lists the supported phone regions in order
+func PhoneRegions() []string {
+	regions := make([]string, 0, len(phoneRegions))
+	for region := range phoneRegions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// NormalizeContact checks a contact and returns it in canonical form: mobile
+// numbers in E.164 (e.g. +15551234567) and email addresses lower-cased.
+// Contacts are stored and looked up in this form, so the same customer is
+// found however they typed it.
+func NormalizeContact(contactType, contactValue string) (string, error) {
+	var normalized string
+	var err error
+	switch contactType {
+	case ContactMobile:
+		normalized, err = normalizeMobile(contactValue, defaultPhoneRegion)
+	case ContactEmail:
+		normalized, err = normalizeEmail(contactValue)
+	default:
+		return "", fmt.Errorf("contact_type must be either 'mobile' or 'email'")
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid contact_value for contact_type %s: %w", contactType, err)
+	}
+	return normalized, nil
+}
+
+// normalizeMobile parses a phone number as a customer may write it, with
+// spaces, dashes, dots or brackets, and returns it in E.164. Numbers starting
+// with + or 00 carry their country code; others are national numbers in the
+// given region, with or without its trunk prefix.
+func normalizeMobile(value, region string) (string, error) {
+	value = strings.TrimSpace(value)
+	// "+44 (0)7700 900123" repeats the trunk prefix after the country code
+	value = strings.Replace(value, "(0)", "", 1)
+
+	international := strings.HasPrefix(value, "+")
+	digits := make([]byte, 0, len(value))
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0:
+		case r == ' ', r == '-', r == '.', r == '(', r == ')', r == '/':
+		default:
+			return "", fmt.Errorf("mobile number contains %q", r)
+		}
+	}
+	number := string(digits)
+	if number == "" {
+		return "", fmt.Errorf("mobile number has no digits")
+	}
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+
+	if international {
+		return checkE164(number)
+	}
+
+	local, ok := phoneRegions[region]
+	if !ok {
+		return "", fmt.Errorf("unsupported phone region %q", region)
+	}
+	national, err := nationalNumber(number, local)
+	if err != nil {
+		return "", fmt.Errorf("%w for region %s", err, region)
+	}
+	return checkE164(local.callingCode + national)
+}
+
+// nationalNumber strips a region's trunk prefix from a nationally written
+// number. The prefix is kept when dropping it would leave too few digits, as
+// in the US number 123-456-7890.
+func nationalNumber(number string, region phoneRegion) (string, error) {
+	if region.trunkPrefix != "" && strings.HasPrefix(number, region.trunkPrefix) {
+		if stripped := number[len(region.trunkPrefix):]; len(stripped) >= region.minLength && len(stripped) <= region.maxLength {
+			return stripped, nil
+		}
+	}
+	if len(number) < region.minLength || len(number) > region.maxLength {
+		return "", fmt.Errorf("mobile number has %d digits, expected %s", len(number), lengthRange(region))
+	}
+	if region.trunkPrefix == "0" && strings.HasPrefix(number, "0") {
+		return "", fmt.Errorf("national number cannot start with 0")
+	}
+	return number, nil
+}
+
+// checkE164 checks a number's digits, country code first, and returns it
+// with its leading +. Numbers from a known region also have their length checked.
+func checkE164(number string) (string, error) {
+	if len(number) < minE164Digits || len(number) > maxE164Digits {
+		return "", fmt.Errorf("international number has %d digits, expected %d to %d", len(number), minE164Digits, maxE164Digits)
+	}
+	if number[0] == '0' {
+		return "", fmt.Errorf("country code cannot start with 0")
+	}
+
+	// Country codes are prefix-free, so at most one of these matches
+	for size := 1; size <= 3; size++ {
+		for _, region := range phoneRegions {
+			if region.callingCode != number[:size] {
+				continue
+			}
+			national := number[size:]
+			if len(national) < region.minLength || len(national) > region.maxLength {
+				return "", fmt.Errorf("number has %d digits after country code +%s, expected %s", len(national), region.callingCode, lengthRange(region))
+			}
+			return "+" + number, nil
+		}
+	}
+	return "+" + number, nil
+}
+
+// lengthRange describes the national number lengths of a region, e.g. "10" or "9 to 10"
+func lengthRange(region phoneRegion) string {
+	if region.minLength == region.maxLength {
+		return fmt.Sprintf("%d", region.minLength)
+	}
+	return fmt.Sprintf("%d to %d", region.minLength, region.maxLength)
+}
+
+// normalizeEmail checks that a value is a plain RFC 5322 address, without a
+// display name or comments, at a domain name, and lower-cases it
+func normalizeEmail(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > maxEmailLength {
+		return "", fmt.Errorf("email address is longer than %d characters", maxEmailLength)
+	}
+
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Name != "" || address.Address != value {
+		return "", fmt.Errorf("not a plain email address")
+	}
+
+	at := strings.LastIndex(value, "@")
+	local, domain := value[:at], value[at+1:]
+	if len(local) > maxEmailLocalLength {
+		return "", fmt.Errorf("email local part is longer than %d characters", maxEmailLocalLength)
+	}
+	if err := checkEmailDomain(domain); err != nil {
+		return "", err
+	}
+	return strings.ToLower(value), nil
+}
+
+// checkEmailDomain requires a domain name of at least two labels with an
+// alphabetic top-level domain. Address literals such as [192.0.2.1] are refused.
+func checkEmailDomain(domain string) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("email domain %q has no top-level domain", domain)
+	}
+
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("email domain %q is not a valid domain name", domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("email domain %q is not a valid domain name", domain)
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || strings.Trim(tld, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf("email domain %q has no valid top-level domain", domain)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package bookings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeContactMobile(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"+1 555-123-4567", "+15551234567"},
+		{"15551234567", "+15551234567"},
+		{"(555) 123-4567", "+15551234567"},
+		{"555.123.4567", "+15551234567"},
+		{"1234567890", "+11234567890"},
+		{"+44 (0)7700 900123", "+447700900123"},
+		{"0044 7700 900123", "+447700900123"},
+		{"+91 98765 43210", "+919876543210"},
+		{"+86 138 0013 8000", "+8613800138000"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeContact(ContactMobile, tt.value)
+		if err != nil {
+			t.Errorf("NormalizeContact(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("NormalizeContact(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+
+		// Normalized values normalize to themselves
+		if again, err := NormalizeContact(ContactMobile, got); err != nil || again != got {
+			t.Errorf("NormalizeContact(%q) = %q, %v, expected it unchanged", got, again, err)
+		}
+	}
+}
+
+func TestNormalizeContactMobileRejects(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"555-CALL-NOW",
+		"555 123 4567 x12",
+		"12345",
+		"123456789012345",
+		"+1 555 123 456",
+		"+44 7700 900123 456",
+		"+0 555 123 4567",
+		"+1234567",
+		"5551+234567",
+	} {
+		_, err := NormalizeContact(ContactMobile, value)
+		if err == nil {
+			t.Errorf("Expected NormalizeContact(%q) to fail", value)
+		} else if !strings.Contains(err.Error(), "invalid contact_value") {
+			t.Errorf("Expected an invalid contact_value error for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestNormalizeContactDefaultRegion(t *testing.T) {
+	defer SetDefaultPhoneRegion(DefaultPhoneRegion())
+
+	if err := SetDefaultPhoneRegion("gb"); err != nil {
+		t.Fatalf("SetDefaultPhoneRegion() error: %v", err)
+	}
+	tests := map[string]string{
+		"07700 900123":    "+447700900123",
+		"7700 900123":     "+447700900123",
+		"+1 555-123-4567": "+15551234567",
+	}
+	for value, expected := range tests {
+		if got, err := NormalizeContact(ContactMobile, value); err != nil || got != expected {
+			t.Errorf("NormalizeContact(%q) = %q, %v, expected %q", value, got, err, expected)
+		}
+	}
+	if _, err := NormalizeContact(ContactMobile, "1 555 123 4567"); err == nil {
+		t.Error("Expected a US number without its + to fail in region GB")
+	}
+
+	if err := SetDefaultPhoneRegion("XX"); err == nil {
+		t.Error("Expected an unsupported region to fail")
+	}
+	if DefaultPhoneRegion() != "GB" {
+		t.Errorf("Expected a failed change to keep region GB, got %s", DefaultPhoneRegion())
+	}
+}
+
+func TestNormalizeContactEmail(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"fan@example.com", "fan@example.com"},
+		{"  Jane.Doe@Example.COM ", "jane.doe@example.com"},
+		{"first+tickets@mail.example.co.uk", "first+tickets@mail.example.co.uk"},
+		{"o'brien@theater-tickets.ie", "o'brien@theater-tickets.ie"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeContact(ContactEmail, tt.value)
+		if err != nil {
+			t.Errorf("NormalizeContact(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("NormalizeContact(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestNormalizeContactEmailRejects(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"fan",
+		"fan@example",
+		"fan@@example.com",
+		"fan@example..com",
+		"fan@-example.com",
+		"fan@example.c",
+		"fan@example.123",
+		"fan@[192.0.2.1]",
+		"Fan <fan@example.com>",
+		"fan@example.com (Fan)",
+		"fan smith@example.com",
+		".fan@example.com",
+		strings.Repeat("a", 65) + "@example.com",
+	} {
+		if _, err := NormalizeContact(ContactEmail, value); err == nil {
+			t.Errorf("Expected NormalizeContact(%q) to fail", value)
+		}
+	}
+}
+
+func TestNormalizeContactType(t *testing.T) {
+	_, err := NormalizeContact("fax", "5551234567")
+	if err == nil || !strings.Contains(err.Error(), "contact_type must be either 'mobile' or 'email'") {
+		t.Errorf("Expected a contact_type error, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package bookings
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Exchange links a booking to the booking its tickets were moved to on
+// another show. A booking whose every ticket moved ends up exchanged; after
+// a partial exchange it keeps the remaining tickets.
+type Exchange struct {
+	ExchangeID      string    `json:"exchange_id"` // Random unique ID, e.g. EX-9F86D081884C7D65
+	SourceBookingID string    `json:"source_booking_id"`
+	TargetBookingID string    `json:"target_booking_id"`
+	SourceShowID    uuid.UUID `json:"source_show_id"`
+	TargetShowID    uuid.UUID `json:"target_show_id"`
+	NumberOfTickets int32     `json:"number_of_tickets"`
+	SourceAmount    int32     `json:"source_amount"`    // What the moved tickets cost on the source booking
+	TargetAmount    int32     `json:"target_amount"`    // What they cost on the target show
+	PriceDifference int32     `json:"price_difference"` // TargetAmount - SourceAmount; positive to collect, negative to refund
+	ChangedBy       string    `json:"changed_by"`
+	Reason          string    `json:"reason,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ExchangeSelection says which of a booking's tickets move to another show:
+// a number of tickets, or a quantity per ticket type for bookings with
+// ticket types. Leaving both empty moves every ticket.
+type ExchangeSelection struct {
+	NumberOfTickets int32
+	TicketTypes     map[string]int32
+}
+
+// NewExchange links source, which gave up the tickets, to target, which received them
+func NewExchange(source, moved, target *Booking, changedBy, reason string) (*Exchange, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate exchange ID: %w", err)
+	}
+
+	return &Exchange{
+		ExchangeID:      fmt.Sprintf("EX-%X", buf),
+		SourceBookingID: source.BookingID,
+		TargetBookingID: target.BookingID,
+		SourceShowID:    source.ShowID,
+		TargetShowID:    target.ShowID,
+		NumberOfTickets: moved.NumberOfTickets,
+		SourceAmount:    moved.TotalAmount,
+		TargetAmount:    target.TotalAmount,
+		PriceDifference: target.TotalAmount - moved.TotalAmount,
+		ChangedBy:       changedBy,
+		Reason:          reason,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+// SplitTickets takes the selected tickets out of the booking and returns them
+// as a draft booking for the same customer, carrying the ticket lines moved
+// and the amount they accounted for after any discount. all reports whether
+// every ticket moved; the booking itself is then left as it was. Otherwise
+// the moved tickets are removed from it as in RemoveTicket.
+func (b *Booking) SplitTickets(selection ExchangeSelection) (moved *Booking, all bool, err error) {
+	lines, quantity, err := b.selectTickets(selection)
+	if err != nil {
+		return nil, false, err
+	}
+
+	moved = &Booking{
+		BookingID:       b.BookingID,
+		ShowID:          b.ShowID,
+		ContactType:     b.ContactType,
+		ContactValue:    b.ContactValue,
+		CustomerName:    b.CustomerName,
+		UserID:          b.UserID,
+		Status:          b.Status,
+		HoldExpiresAt:   b.HoldExpiresAt,
+		NumberOfTickets: quantity,
+		TicketLines:     lines,
+	}
+
+	if quantity == b.NumberOfTickets {
+		moved.TotalAmount = b.TotalAmount
+		moved.DiscountAmount = b.DiscountAmount
+		return moved, true, nil
+	}
+
+	if len(lines) == 0 {
+		for i := int32(0); i < quantity; i++ {
+			reduction, err := b.RemoveTicket("")
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid exchange: %w", err)
+			}
+			moved.TotalAmount += reduction
+		}
+		return moved, false, nil
+	}
+
+	for _, line := range lines {
+		for i := int32(0); i < line.Quantity; i++ {
+			reduction, err := b.RemoveTicket(line.TicketType)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid exchange: %w", err)
+			}
+			moved.TotalAmount += reduction
+		}
+	}
+	return moved, false, nil
+}
+
+// selectTickets resolves an exchange selection into the ticket lines moved,
+// priced at the booking's unit prices, and the number of tickets moved
+func (b *Booking) selectTickets(selection ExchangeSelection) ([]*TicketLine, int32, error) {
+	if len(selection.TicketTypes) == 0 {
+		quantity := selection.NumberOfTickets
+		if quantity == 0 {
+			quantity = b.NumberOfTickets
+		}
+		if quantity < 0 || quantity > b.NumberOfTickets {
+			return nil, 0, fmt.Errorf("invalid exchange: booking %s has %d tickets, cannot move %d",
+				b.BookingID, b.NumberOfTickets, quantity)
+		}
+
+		switch {
+		case len(b.TicketLines) == 0:
+			return nil, quantity, nil
+		case quantity == b.NumberOfTickets:
+			lines := make([]*TicketLine, 0, len(b.TicketLines))
+			for _, line := range b.TicketLines {
+				copied := *line
+				lines = append(lines, &copied)
+			}
+			return lines, quantity, nil
+		case len(b.TicketLines) == 1:
+			line := *b.TicketLines[0]
+			line.Quantity = quantity
+			return []*TicketLine{&line}, quantity, nil
+		default:
+			return nil, 0, fmt.Errorf("invalid exchange: ticket_types is required to move part of a booking with several ticket types")
+		}
+	}
+
+	if len(b.TicketLines) == 0 {
+		return nil, 0, fmt.Errorf("invalid exchange: booking %s has no ticket types", b.BookingID)
+	}
+
+	lines, err := NewTicketLines(selection.TicketTypes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid exchange: %w", err)
+	}
+	quantity := TotalQuantity(lines)
+	if selection.NumberOfTickets != 0 && selection.NumberOfTickets != quantity {
+		return nil, 0, fmt.Errorf("invalid exchange: number_of_tickets (%d) does not match ticket type quantities (%d)",
+			selection.NumberOfTickets, quantity)
+	}
+
+	held := make(map[string]*TicketLine, len(b.TicketLines))
+	for _, line := range b.TicketLines {
+		held[line.TicketType] = line
+	}
+	for _, line := range lines {
+		bookingLine, ok := held[line.TicketType]
+		if !ok || bookingLine.Quantity < line.Quantity {
+			return nil, 0, fmt.Errorf("invalid exchange: booking %s does not have %d %s tickets",
+				b.BookingID, line.Quantity, line.TicketType)
+		}
+		line.UnitPrice = bookingLine.UnitPrice
+	}
+	return lines, quantity, nil
+}
@@ -0,0 +1,116 @@
+package bookings
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSplitTicketsWholeBooking(t *testing.T) {
+	booking := NewBooking(uuid.New(), "email", "fan@example.com", 4, 360)
+	booking.DiscountAmount = 40
+	booking.Status = StatusConfirmed
+
+	moved, all, err := booking.SplitTickets(ExchangeSelection{})
+	if err != nil {
+		t.Fatalf("SplitTickets() error: %v", err)
+	}
+	if !all {
+		t.Error("Expected every ticket to move")
+	}
+	if moved.NumberOfTickets != 4 || moved.TotalAmount != 360 || moved.Status != StatusConfirmed {
+		t.Errorf("Expected 4 confirmed tickets for 360, got %d %s tickets for %d",
+			moved.NumberOfTickets, moved.Status, moved.TotalAmount)
+	}
+	if booking.NumberOfTickets != 4 || booking.TotalAmount != 360 {
+		t.Errorf("Expected the booking to be left as it was, got %d tickets for %d",
+			booking.NumberOfTickets, booking.TotalAmount)
+	}
+}
+
+func TestSplitTicketsPartOfBooking(t *testing.T) {
+	// 4 x 100 with 10% off
+	booking := NewBooking(uuid.New(), "email", "fan@example.com", 4, 360)
+	booking.DiscountAmount = 40
+
+	moved, all, err := booking.SplitTickets(ExchangeSelection{NumberOfTickets: 1})
+	if err != nil {
+		t.Fatalf("SplitTickets() error: %v", err)
+	}
+	if all {
+		t.Error("Expected part of the booking to move")
+	}
+	if moved.NumberOfTickets != 1 || moved.TotalAmount != 90 {
+		t.Errorf("Expected 1 ticket worth 90 to move, got %d worth %d", moved.NumberOfTickets, moved.TotalAmount)
+	}
+	if booking.NumberOfTickets != 3 || booking.TotalAmount != 270 || booking.DiscountAmount != 30 {
+		t.Errorf("Expected 3 tickets for 270 with 30 off left, got %d for %d with %d off",
+			booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount)
+	}
+
+	for _, selection := range []ExchangeSelection{
+		{NumberOfTickets: 4},
+		{NumberOfTickets: -1},
+		{TicketTypes: map[string]int32{"adult": 1}},
+	} {
+		if _, _, err := booking.SplitTickets(selection); err == nil {
+			t.Errorf("Expected error for selection %+v", selection)
+		}
+	}
+}
+
+func TestSplitTicketsWithTicketTypes(t *testing.T) {
+	booking := NewBooking(uuid.New(), "email", "fan@example.com", 3, 12500)
+	booking.TicketLines = []*TicketLine{
+		{TicketType: "adult", Quantity: 2, UnitPrice: 5000},
+		{TicketType: "child", Quantity: 1, UnitPrice: 2500},
+	}
+
+	if _, _, err := booking.SplitTickets(ExchangeSelection{NumberOfTickets: 1}); err == nil {
+		t.Error("Expected error without ticket types on a booking with several types")
+	}
+	if _, _, err := booking.SplitTickets(ExchangeSelection{TicketTypes: map[string]int32{"child": 2}}); err == nil {
+		t.Error("Expected error for more tickets of a type than the booking has")
+	}
+	if _, _, err := booking.SplitTickets(ExchangeSelection{NumberOfTickets: 2, TicketTypes: map[string]int32{"adult": 1}}); err == nil {
+		t.Error("Expected error when number_of_tickets does not match the ticket types")
+	}
+
+	moved, all, err := booking.SplitTickets(ExchangeSelection{TicketTypes: map[string]int32{" Adult ": 1}})
+	if err != nil {
+		t.Fatalf("SplitTickets() error: %v", err)
+	}
+	if all || moved.NumberOfTickets != 1 || moved.TotalAmount != 5000 {
+		t.Errorf("Expected 1 ticket worth 5000 to move, got %d worth %d (all %v)", moved.NumberOfTickets, moved.TotalAmount, all)
+	}
+	if len(moved.TicketLines) != 1 || moved.TicketLines[0].TicketType != "adult" || moved.TicketLines[0].UnitPrice != 5000 {
+		t.Errorf("Expected one adult line at 5000, got %+v", moved.TicketLines)
+	}
+	if booking.NumberOfTickets != 2 || booking.TotalAmount != 7500 {
+		t.Errorf("Expected 2 tickets for 7500 left, got %d for %d", booking.NumberOfTickets, booking.TotalAmount)
+	}
+}
+
+func TestNewExchange(t *testing.T) {
+	source := NewBooking(uuid.New(), "email", "fan@example.com", 2, 200)
+	moved, _, err := source.SplitTickets(ExchangeSelection{})
+	if err != nil {
+		t.Fatalf("SplitTickets() error: %v", err)
+	}
+	target := NewBooking(uuid.New(), "email", "fan@example.com", 2, 150)
+
+	exchange, err := NewExchange(source, moved, target, "box-office", "customer is travelling")
+	if err != nil {
+		t.Fatalf("NewExchange() error: %v", err)
+	}
+	if len(exchange.ExchangeID) != 19 || exchange.ExchangeID[:3] != "EX-" {
+		t.Errorf("Unexpected exchange ID %q", exchange.ExchangeID)
+	}
+	if exchange.SourceBookingID != source.BookingID || exchange.TargetBookingID != target.BookingID {
+		t.Errorf("Expected %s -> %s, got %s -> %s", source.BookingID, target.BookingID,
+			exchange.SourceBookingID, exchange.TargetBookingID)
+	}
+	if exchange.PriceDifference != -50 || exchange.NumberOfTickets != 2 {
+		t.Errorf("Expected 2 tickets with a difference of -50, got %d with %d", exchange.NumberOfTickets, exchange.PriceDifference)
+	}
+}
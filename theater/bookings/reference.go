@@ -0,0 +1,92 @@
+package bookings
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// referenceAlphabet is the characters used in booking references. It leaves
+// out 0/O, 1/I/L and U (heard as V) so a reference can be read out over the
+// phone without mix-ups. The check character relies on its length being even.
+const referenceAlphabet = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// A reference is referencePayloadLength random characters followed by one
+// check character, shown in two groups, e.g. "K7MPQ-3XR9A"
+const (
+	referencePayloadLength = 9
+	referenceLength        = referencePayloadLength + 1
+	referenceGroupLength   = referenceLength / 2
+)
+
+// NewReference generates a random customer-facing booking reference. It is
+// unique with overwhelming probability; the bookings table's unique key
+// catches the rest and the repository retries with a new reference.
+func NewReference() (string, error) {
+	payload := make([]byte, 0, referencePayloadLength)
+	buf := make([]byte, referencePayloadLength*2)
+
+	// Rejection sampling keeps every character equally likely
+	limit := byte(256 - 256%len(referenceAlphabet))
+	for len(payload) < referencePayloadLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate booking reference: %w", err)
+		}
+		for _, b := range buf {
+			if b >= limit || len(payload) == referencePayloadLength {
+				continue
+			}
+			payload = append(payload, referenceAlphabet[int(b)%len(referenceAlphabet)])
+		}
+	}
+
+	return formatReference(string(payload) + string(referenceCheckCharacter(string(payload)))), nil
+}
+
+// ParseReference normalizes a reference typed by a customer or clerk
+// (any case, with or without the dash or spaces) and verifies its check
+// character. It returns the reference in its canonical form.
+func ParseReference(value string) (string, error) {
+	compact := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(value)))
+	if len(compact) != referenceLength {
+		return "", fmt.Errorf("invalid booking reference: %s must have %d characters", value, referenceLength)
+	}
+	for i := 0; i < len(compact); i++ {
+		if strings.IndexByte(referenceAlphabet, compact[i]) < 0 {
+			return "", fmt.Errorf("invalid booking reference: %s contains %q", value, compact[i])
+		}
+	}
+
+	payload, check := compact[:referencePayloadLength], compact[referencePayloadLength]
+	if referenceCheckCharacter(payload) != check {
+		return "", fmt.Errorf("invalid booking reference: %s failed its check character; please re-check it", value)
+	}
+
+	return formatReference(compact), nil
+}
+
+// IsBookingID reports whether value is an internal booking ID rather than a reference
+func IsBookingID(value string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(value)), "BK-")
+}
+
+// referenceCheckCharacter computes the Luhn mod N check character of a
+// payload. It catches any single mistyped character and most swaps of
+// adjacent characters.
+func referenceCheckCharacter(payload string) byte {
+	n := len(referenceAlphabet)
+	factor := 2
+	sum := 0
+
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(referenceAlphabet, payload[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+
+	return referenceAlphabet[(n-sum%n)%n]
+}
+
+func formatReference(compact string) string {
+	return compact[:referenceGroupLength] + "-" + compact[referenceGroupLength:]
+}
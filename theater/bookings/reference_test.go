@@ -0,0 +1,80 @@
+package bookings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewReference(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		reference, err := NewReference()
+		if err != nil {
+			t.Fatalf("NewReference() error: %v", err)
+		}
+
+		if len(reference) != referenceLength+1 || reference[referenceGroupLength] != '-' {
+			t.Fatalf("Expected XXXXX-XXXXX, got %q", reference)
+		}
+		if strings.ContainsAny(reference, "01ILOU") {
+			t.Errorf("Reference %q contains an ambiguous character", reference)
+		}
+		if parsed, err := ParseReference(reference); err != nil || parsed != reference {
+			t.Errorf("ParseReference(%q) = %q, %v", reference, parsed, err)
+		}
+		if seen[reference] {
+			t.Errorf("Duplicate reference %q", reference)
+		}
+		seen[reference] = true
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	reference, err := NewReference()
+	if err != nil {
+		t.Fatalf("NewReference() error: %v", err)
+	}
+
+	compact := strings.Replace(reference, "-", "", 1)
+	for _, typed := range []string{strings.ToLower(reference), compact, " " + compact[:3] + " " + compact[3:] + " "} {
+		if parsed, err := ParseReference(typed); err != nil || parsed != reference {
+			t.Errorf("ParseReference(%q) = %q, %v; expected %q", typed, parsed, err, reference)
+		}
+	}
+
+	for _, typed := range []string{"", "ABCD-EFGH", "ABCDE-FGHJKM", "ABCDE-FGH0K"} {
+		if _, err := ParseReference(typed); err == nil || !strings.Contains(err.Error(), "invalid booking reference") {
+			t.Errorf("ParseReference(%q) should be rejected, got %v", typed, err)
+		}
+	}
+}
+
+func TestReferenceCheckCharacterCatchesTypos(t *testing.T) {
+	reference, err := NewReference()
+	if err != nil {
+		t.Fatalf("NewReference() error: %v", err)
+	}
+	compact := strings.Replace(reference, "-", "", 1)
+
+	// Every single-character substitution is detected
+	for i := 0; i < len(compact); i++ {
+		for j := 0; j < len(referenceAlphabet); j++ {
+			if referenceAlphabet[j] == compact[i] {
+				continue
+			}
+			typo := compact[:i] + string(referenceAlphabet[j]) + compact[i+1:]
+			if _, err := ParseReference(typo); err == nil {
+				t.Errorf("Substituting position %d of %s gave valid reference %s", i, compact, typo)
+			}
+		}
+	}
+}
+
+func TestIsBookingID(t *testing.T) {
+	if !IsBookingID("BK-1234567890ABCDEF") || !IsBookingID("bk-1234567890abcdef") {
+		t.Error("BK- prefixed values should be booking IDs")
+	}
+	if IsBookingID("K7MPQ-3XR9A") {
+		t.Error("References should not be treated as booking IDs")
+	}
+}
@@ -0,0 +1,126 @@
+package bookings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Booking statuses
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	StatusCheckedIn = "checked_in"
+	StatusRefunded  = "refunded"
+	StatusNoShow    = "no_show"
+	StatusExchanged = "exchanged"
+)
+
+// Actors recorded in the status history for changes not made through the API
+const (
+	ActorCustomer   = "customer"
+	ActorHoldReaper = "system:hold-reaper"
+	ActorWaitlist   = "system:waitlist"
+)
+
+// ErrHoldExpired is returned when confirming a booking whose hold has lapsed
+var ErrHoldExpired = fmt.Errorf("booking hold has expired")
+
+// AllStatuses lists every booking status in lifecycle order
+var AllStatuses = []string{
+	StatusPending,
+	StatusConfirmed,
+	StatusCheckedIn,
+	StatusNoShow,
+	StatusCancelled,
+	StatusExpired,
+	StatusRefunded,
+	StatusExchanged,
+}
+
+// transitions lists the statuses each status may move to. Anything not
+// listed is rejected, so released tickets are never silently re-taken
+// without going back through a capacity check.
+var transitions = map[string][]string{
+	StatusPending:   {StatusConfirmed, StatusCancelled, StatusExpired, StatusExchanged},
+	StatusConfirmed: {StatusCheckedIn, StatusNoShow, StatusCancelled, StatusRefunded, StatusExchanged},
+	StatusCheckedIn: {},
+	StatusNoShow:    {StatusCheckedIn, StatusRefunded},
+	StatusCancelled: {StatusRefunded},
+	StatusExpired:   {},
+	StatusRefunded:  {},
+	StatusExchanged: {},
+}
+
+// InventoryEffect describes what a status transition does to a show's sold tickets
+type InventoryEffect int
+
+const (
+	// InventoryUnchanged leaves the booking's tickets where they are
+	InventoryUnchanged InventoryEffect = iota
+	// InventoryReleased returns the booking's tickets to the show
+	InventoryReleased
+	// InventoryReserved takes tickets from the show and needs a capacity check
+	InventoryReserved
+)
+
+// StatusChange is one entry in a booking's status history
+type StatusChange struct {
+	ID         int64     `json:"id"`
+	BookingID  string    `json:"booking_id"`
+	FromStatus string    `json:"from_status,omitempty"` // Empty for the initial status
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	Reason     string    `json:"reason,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// IsKnownStatus reports whether status is one of the booking statuses
+func IsKnownStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// HoldsInventory reports whether a booking in this status counts against the
+// show's capacity. A no-show keeps its seat; the show has already happened.
+func HoldsInventory(status string) bool {
+	switch status {
+	case StatusPending, StatusConfirmed, StatusCheckedIn, StatusNoShow:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a booking may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error when a booking may not move from one status to another
+func ValidateTransition(from, to string) error {
+	if !IsKnownStatus(to) {
+		return fmt.Errorf("invalid status: %s. Valid statuses are: %s", to, strings.Join(AllStatuses, ", "))
+	}
+	if !CanTransition(from, to) {
+		return fmt.Errorf("invalid status transition: %s -> %s", from, to)
+	}
+	return nil
+}
+
+// TransitionEffect returns what a transition does to the show's sold tickets
+func TransitionEffect(from, to string) InventoryEffect {
+	switch {
+	case HoldsInventory(from) && !HoldsInventory(to):
+		return InventoryReleased
+	case !HoldsInventory(from) && HoldsInventory(to):
+		return InventoryReserved
+	}
+	return InventoryUnchanged
+}
@@ -0,0 +1,90 @@
+package bookings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from        string
+		to          string
+		expectError bool
+	}{
+		{StatusPending, StatusConfirmed, false},
+		{StatusPending, StatusCancelled, false},
+		{StatusPending, StatusExpired, false},
+		{StatusPending, StatusCheckedIn, true},
+		{StatusConfirmed, StatusCheckedIn, false},
+		{StatusConfirmed, StatusNoShow, false},
+		{StatusConfirmed, StatusRefunded, false},
+		{StatusConfirmed, StatusPending, true},
+		{StatusNoShow, StatusCheckedIn, false},
+		{StatusCancelled, StatusConfirmed, true},
+		{StatusCancelled, StatusRefunded, false},
+		{StatusExpired, StatusPending, true},
+		{StatusCheckedIn, StatusCancelled, true},
+		{StatusRefunded, StatusConfirmed, true},
+		{StatusConfirmed, StatusExchanged, false},
+		{StatusExchanged, StatusConfirmed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			err := ValidateTransition(tt.from, tt.to)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected transition %s -> %s to be rejected", tt.from, tt.to)
+				} else if !strings.Contains(err.Error(), "invalid status transition") {
+					t.Errorf("Expected invalid status transition error, got %v", err)
+				}
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTransitionUnknownStatus(t *testing.T) {
+	err := ValidateTransition(StatusPending, "shipped")
+	if err == nil || !strings.Contains(err.Error(), "invalid status: shipped") {
+		t.Errorf("Expected invalid status error, got %v", err)
+	}
+}
+
+func TestTransitionEffect(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected InventoryEffect
+	}{
+		{StatusPending, StatusConfirmed, InventoryUnchanged},
+		{StatusPending, StatusExpired, InventoryReleased},
+		{StatusConfirmed, StatusCancelled, InventoryReleased},
+		{StatusConfirmed, StatusNoShow, InventoryUnchanged},
+		{StatusCancelled, StatusRefunded, InventoryUnchanged},
+		{StatusCancelled, StatusConfirmed, InventoryReserved},
+		{StatusConfirmed, StatusExchanged, InventoryReleased},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := TransitionEffect(tt.from, tt.to); got != tt.expected {
+				t.Errorf("Expected effect %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEveryStatusHasTransitions(t *testing.T) {
+	for _, status := range AllStatuses {
+		if !IsKnownStatus(status) {
+			t.Errorf("Status %s is missing from the transition table", status)
+		}
+	}
+	if len(AllStatuses) != len(transitions) {
+		t.Errorf("Expected %d statuses in the transition table, got %d", len(AllStatuses), len(transitions))
+	}
+}
@@ -0,0 +1,188 @@
+package bookings
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TicketLine is the quantity of one ticket type in a booking
+type TicketLine struct {
+	TicketType string `json:"ticket_type"`
+	Quantity   int32  `json:"quantity"`
+	UnitPrice  int32  `json:"unit_price"` // Price of one ticket when the booking was made
+}
+
+// Subtotal returns the line's price
+func (l *TicketLine) Subtotal() int32 {
+	return l.UnitPrice * l.Quantity
+}
+
+// NewTicketLines builds ticket lines from per-type quantities, ordered by ticket type.
+// Unit prices are filled in when the booking is priced.
+func NewTicketLines(quantities map[string]int32) ([]*TicketLine, error) {
+	merged := make(map[string]int32, len(quantities))
+	for ticketType, quantity := range quantities {
+		code := strings.ToLower(strings.TrimSpace(ticketType))
+		if code == "" {
+			return nil, fmt.Errorf("ticket type cannot be empty")
+		}
+		if quantity <= 0 {
+			return nil, fmt.Errorf("quantity for ticket type %s must be greater than 0", code)
+		}
+		merged[code] += quantity
+	}
+
+	lines := make([]*TicketLine, 0, len(merged))
+	for code, quantity := range merged {
+		lines = append(lines, &TicketLine{TicketType: code, Quantity: quantity})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].TicketType < lines[j].TicketType
+	})
+	return lines, nil
+}
+
+// TotalQuantity returns the number of tickets across all lines
+func TotalQuantity(lines []*TicketLine) int32 {
+	total := int32(0)
+	for _, line := range lines {
+		total += line.Quantity
+	}
+	return total
+}
+
+// PriceTicketLines fills in each line's unit price from a show's current
+// ticket type prices and returns the total for quantity tickets. A show
+// without ticket types (no prices) sells every ticket at basePrice and does
+// not accept ticket lines.
+func PriceTicketLines(lines []*TicketLine, quantity, basePrice int32, prices map[string]int32) (int32, error) {
+	if len(prices) == 0 {
+		if len(lines) > 0 {
+			return 0, fmt.Errorf("invalid ticket selection: show does not offer ticket types")
+		}
+		return basePrice * quantity, nil
+	}
+
+	if len(lines) == 0 {
+		codes := make([]string, 0, len(prices))
+		for code := range prices {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		return 0, fmt.Errorf("invalid ticket selection: choose quantities for ticket types (%s)", strings.Join(codes, ", "))
+	}
+
+	total := int32(0)
+	for _, line := range lines {
+		price, offered := prices[line.TicketType]
+		if !offered {
+			return 0, fmt.Errorf("invalid ticket selection: unknown ticket type %s", line.TicketType)
+		}
+		line.UnitPrice = price
+		total += line.Subtotal()
+	}
+	return total, nil
+}
+
+// ApplyPrices prices the booking's ticket lines and sets its total amount
+func (b *Booking) ApplyPrices(basePrice int32, prices map[string]int32) error {
+	total, err := PriceTicketLines(b.TicketLines, b.NumberOfTickets, basePrice, prices)
+	if err != nil {
+		return err
+	}
+	b.TotalAmount = total
+	b.DiscountAmount = 0
+	return nil
+}
+
+// RemoveTicket takes one ticket of the given type (empty for shows without
+// ticket types) out of the booking and returns how much the amount due went
+// down. The ticket's share of any discount is taken off the discount, so the
+// remaining tickets keep the same effective price.
+func (b *Booking) RemoveTicket(ticketType string) (int32, error) {
+	if b.NumberOfTickets <= 1 {
+		return 0, fmt.Errorf("booking %s has no tickets to spare", b.BookingID)
+	}
+
+	gross := b.TotalAmount + b.DiscountAmount
+	ticketPrice := gross / b.NumberOfTickets
+	if len(b.TicketLines) > 0 {
+		index := -1
+		for i, line := range b.TicketLines {
+			if line.TicketType == ticketType {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return 0, fmt.Errorf("booking %s has no %s tickets", b.BookingID, ticketType)
+		}
+
+		line := b.TicketLines[index]
+		ticketPrice = line.UnitPrice
+		line.Quantity--
+		if line.Quantity == 0 {
+			b.TicketLines = append(b.TicketLines[:index], b.TicketLines[index+1:]...)
+		}
+	}
+
+	reduction := int32(0)
+	if gross > 0 {
+		reduction = int32(int64(b.TotalAmount) * int64(ticketPrice) / int64(gross))
+	}
+	b.NumberOfTickets--
+	b.TotalAmount -= reduction
+	b.DiscountAmount -= ticketPrice - reduction
+	return reduction, nil
+}
+
+// ParseTicketSelection parses a form value such as "adult:2,child:1"
+func ParseTicketSelection(value string) (map[string]int32, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	quantities := make(map[string]int32)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		ticketType, quantityStr, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid ticket_types entry %q, expected <type>:<quantity>", part)
+		}
+		quantity, err := strconv.ParseInt(strings.TrimSpace(quantityStr), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for ticket type %s: %w", ticketType, err)
+		}
+		quantities[ticketType] += int32(quantity)
+	}
+	return quantities, nil
+}
+
+// applyTicketSelection sets a booking's ticket lines from per-type quantities.
+// The booking's ticket count is derived from the lines; when the request also
+// gave number_of_tickets the two must agree.
+func (b *Booking) applyTicketSelection(quantities map[string]int32) error {
+	if len(quantities) == 0 {
+		return nil
+	}
+
+	lines, err := NewTicketLines(quantities)
+	if err != nil {
+		return err
+	}
+
+	total := TotalQuantity(lines)
+	if b.NumberOfTickets != 0 && b.NumberOfTickets != total {
+		return fmt.Errorf("number_of_tickets (%d) does not match ticket type quantities (%d)", b.NumberOfTickets, total)
+	}
+
+	b.TicketLines = lines
+	b.NumberOfTickets = total
+	return nil
+}
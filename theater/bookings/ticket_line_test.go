@@ -0,0 +1,146 @@
+package bookings
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewTicketLines(t *testing.T) {
+	lines, err := NewTicketLines(map[string]int32{"child": 1, "Adult": 2, "adult ": 1})
+	if err != nil {
+		t.Fatalf("NewTicketLines() error: %v", err)
+	}
+
+	if len(lines) != 2 || lines[0].TicketType != "adult" || lines[0].Quantity != 3 || lines[1].TicketType != "child" {
+		t.Errorf("Expected merged lines sorted by type, got %+v", lines)
+	}
+	if TotalQuantity(lines) != 4 {
+		t.Errorf("Expected 4 tickets, got %d", TotalQuantity(lines))
+	}
+
+	if _, err := NewTicketLines(map[string]int32{"adult": 0}); err == nil {
+		t.Error("Expected error for zero quantity")
+	}
+	if _, err := NewTicketLines(map[string]int32{" ": 1}); err == nil {
+		t.Error("Expected error for empty ticket type")
+	}
+}
+
+func TestPriceTicketLines(t *testing.T) {
+	prices := map[string]int32{"adult": 5000, "child": 2500}
+
+	lines, _ := NewTicketLines(map[string]int32{"adult": 2, "child": 1})
+	total, err := PriceTicketLines(lines, 3, 4000, prices)
+	if err != nil {
+		t.Fatalf("PriceTicketLines() error: %v", err)
+	}
+	if total != 12500 {
+		t.Errorf("Expected total 12500, got %d", total)
+	}
+	if lines[0].UnitPrice != 5000 || lines[1].Subtotal() != 2500 {
+		t.Errorf("Expected unit price snapshots, got %+v", lines)
+	}
+
+	// Shows without ticket types use the base price
+	total, err = PriceTicketLines(nil, 3, 4000, nil)
+	if err != nil || total != 12000 {
+		t.Errorf("Expected 12000 at base price, got %d (%v)", total, err)
+	}
+
+	errorTests := []struct {
+		name   string
+		lines  map[string]int32
+		prices map[string]int32
+	}{
+		{"types for single-price show", map[string]int32{"adult": 1}, nil},
+		{"no types for tiered show", nil, prices},
+		{"unknown type", map[string]int32{"vip": 1}, prices},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, _ := NewTicketLines(tt.lines)
+			_, err := PriceTicketLines(lines, TotalQuantity(lines), 4000, tt.prices)
+			if err == nil || !strings.Contains(err.Error(), "invalid ticket selection") {
+				t.Errorf("Expected invalid ticket selection error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTicketSelection(t *testing.T) {
+	quantities, err := ParseTicketSelection("adult:2, child:1")
+	if err != nil {
+		t.Fatalf("ParseTicketSelection() error: %v", err)
+	}
+	if quantities["adult"] != 2 || quantities["child"] != 1 {
+		t.Errorf("Unexpected quantities: %v", quantities)
+	}
+
+	if quantities, err := ParseTicketSelection(""); err != nil || quantities != nil {
+		t.Errorf("Expected no selection for empty value, got %v (%v)", quantities, err)
+	}
+
+	for _, value := range []string{"adult", "adult:two"} {
+		if _, err := ParseTicketSelection(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestBookingTicketTypesFromRequest(t *testing.T) {
+	showID := uuid.New().String()
+
+	req := httptest.NewRequest("POST", "/api/v1/bookings/create?show_id="+showID+
+		"&contact_type=email&contact_value=fan@example.com&ticket_types=adult:2,child:1", nil)
+	booking, err := NewBookingFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewBookingFromRequest() error: %v", err)
+	}
+	if booking.NumberOfTickets != 3 || len(booking.TicketLines) != 2 {
+		t.Errorf("Expected 3 tickets in 2 lines, got %d in %d", booking.NumberOfTickets, len(booking.TicketLines))
+	}
+
+	body := `{"show_id":"` + showID + `","contact_type":"email","contact_value":"fan@example.com","number_of_tickets":2,"ticket_types":{"adult":2,"child":1}}`
+	if _, err := NewBookingFromJSON(httptest.NewRequest("POST", "/api/v1/bookings/create", strings.NewReader(body))); err == nil {
+		t.Error("Expected error when number_of_tickets does not match ticket types")
+	}
+}
+
+func TestRemoveTicket(t *testing.T) {
+	// 4 x 100 with 10% off
+	booking := NewBooking(uuid.New(), "email", "fan@example.com", 4, 360)
+	booking.DiscountAmount = 40
+
+	reduction, err := booking.RemoveTicket("")
+	if err != nil {
+		t.Fatalf("RemoveTicket() error: %v", err)
+	}
+	if reduction != 90 || booking.NumberOfTickets != 3 || booking.TotalAmount != 270 || booking.DiscountAmount != 30 {
+		t.Errorf("Expected 3 tickets for 270 with 30 off (reduced by 90), got %d for %d with %d off (reduced by %d)",
+			booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount, reduction)
+	}
+
+	typed := NewBooking(uuid.New(), "email", "fan@example.com", 2, 7500)
+	typed.TicketLines = []*TicketLine{
+		{TicketType: "adult", Quantity: 1, UnitPrice: 5000},
+		{TicketType: "child", Quantity: 1, UnitPrice: 2500},
+	}
+	if _, err := typed.RemoveTicket("senior"); err == nil {
+		t.Error("Expected error for a ticket type the booking does not have")
+	}
+	reduction, err = typed.RemoveTicket("child")
+	if err != nil {
+		t.Fatalf("RemoveTicket() error: %v", err)
+	}
+	if reduction != 2500 || typed.TotalAmount != 5000 || len(typed.TicketLines) != 1 || typed.TicketLines[0].TicketType != "adult" {
+		t.Errorf("Expected only the adult line for 5000, got %+v for %d", typed.TicketLines, typed.TotalAmount)
+	}
+
+	if _, err := typed.RemoveTicket("adult"); err == nil {
+		t.Error("Expected error when removing the last ticket")
+	}
+}
@@ -0,0 +1,123 @@
+// Command normalize-contacts rewrites the contact values stored on bookings,
+// orders and waitlist entries into the canonical form new ones are saved in:
+// mobile numbers in E.164 and email addresses lower-cased. Rows written before
+// contacts were normalized are otherwise missed by contact lookups.
+//
+// It reports what it would change unless run with -apply. Rows whose contact
+// cannot be parsed are listed and left as they are. Running it again is safe.
+//
+//	go run ./cmd/normalize-contacts -region US          # dry run
+//	go run ./cmd/normalize-contacts -region US -apply
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+)
+
+// tableReport counts what happened to the contacts of one table
+type tableReport struct {
+	scanned   int
+	unchanged int
+	changed   int
+	invalid   int
+	skipped   int // Changed by someone else between reading and rewriting
+}
+
+func main() {
+	apply := flag.Bool("apply", false, "rewrite contacts (without it, only report what would change)")
+	region := flag.String("region", utils.GetEnvOrDefault("CONTACT_DEFAULT_REGION", bookings.DefaultPhoneRegion()),
+		"region mobile numbers without a country code are read in")
+	batchSize := flag.Int("batch", 500, "rows read per query")
+	flag.Parse()
+
+	if err := bookings.SetDefaultPhoneRegion(*region); err != nil {
+		log.Fatalf("Invalid -region: %v", err)
+	}
+	if *batchSize <= 0 {
+		log.Fatalf("Invalid -batch: must be positive")
+	}
+
+	database := db.GetDatabase()
+	defer database.Close()
+
+	mode := "Dry run"
+	if *apply {
+		mode = "Applying"
+	}
+	log.Printf("%s: normalizing contacts with default region %s", mode, bookings.DefaultPhoneRegion())
+
+	contactRepository := repository.NewContactRepository()
+	failed := false
+	for _, table := range repository.ContactTables() {
+		report, err := normalizeTable(contactRepository, table, *batchSize, *apply)
+		if err != nil {
+			log.Printf("Error normalizing %s: %v", table, err)
+			failed = true
+		}
+		log.Printf("%s: %d scanned, %d already normalized, %d normalized, %d invalid, %d skipped",
+			table, report.scanned, report.unchanged, report.changed, report.invalid, report.skipped)
+	}
+
+	if !*apply {
+		log.Println("Nothing was changed; run again with -apply to rewrite the contacts listed above")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// normalizeTable walks a table in primary key order and rewrites every
+// contact that is not in canonical form
+func normalizeTable(contactRepository *repository.ContactRepository, table string, batchSize int, apply bool) (*tableReport, error) {
+	report := &tableReport{}
+	afterID := ""
+
+	for {
+		records, err := contactRepository.ListContacts(table, afterID, batchSize)
+		if err != nil {
+			return report, err
+		}
+
+		for _, record := range records {
+			report.scanned++
+			normalized, err := bookings.NormalizeContact(record.ContactType, record.ContactValue)
+			if err != nil {
+				report.invalid++
+				log.Printf("%s %s: left unchanged, %v", table, record.ID, err)
+				continue
+			}
+			if normalized == record.ContactValue {
+				report.unchanged++
+				continue
+			}
+
+			log.Printf("%s %s: %q -> %q", table, record.ID, record.ContactValue, normalized)
+			if !apply {
+				report.changed++
+				continue
+			}
+
+			updated, err := contactRepository.UpdateContactValue(table, record.ID, record.ContactValue, normalized)
+			if err != nil {
+				return report, err
+			}
+			if updated {
+				report.changed++
+			} else {
+				report.skipped++
+			}
+		}
+
+		if len(records) < batchSize {
+			return report, nil
+		}
+		afterID = records[len(records)-1].ID
+	}
+}
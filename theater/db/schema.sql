@@ -4,6 +4,29 @@
 CREATE DATABASE IF NOT EXISTS theater_booking;
 USE theater_booking;
 
+-- Venues describe a physical seat layout that shows can be assigned to
+CREATE TABLE IF NOT EXISTS venues (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    INDEX idx_venue_name (name)
+);
+
+CREATE TABLE IF NOT EXISTS venue_seats (
+    venue_id VARCHAR(36) NOT NULL,
+    seat_id VARCHAR(64) NOT NULL,
+    section VARCHAR(50) NOT NULL,
+    row_label VARCHAR(10) NOT NULL,
+    seat_number INT NOT NULL,
+    accessible BOOLEAN DEFAULT FALSE,
+    restricted_view BOOLEAN DEFAULT FALSE,
+    
+    PRIMARY KEY (venue_id, seat_id),
+    FOREIGN KEY (venue_id) REFERENCES venues(id) ON DELETE CASCADE
+);
+
 -- Shows table with optimized indexing
 CREATE TABLE IF NOT EXISTS shows (
     id VARCHAR(36) PRIMARY KEY,
@@ -17,9 +40,14 @@ CREATE TABLE IF NOT EXISTS shows (
     show_date TIMESTAMP,
     images JSON,
     videos JSON,
+    hold_minutes INT DEFAULT 0,
+    require_verified_contact BOOLEAN NOT NULL DEFAULT FALSE,
+    venue_id VARCHAR(36) NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
+    FOREIGN KEY (venue_id) REFERENCES venues(id),
+    
     -- Primary indexes for common search patterns
     INDEX idx_location (location),
     INDEX idx_price (price),
@@ -36,25 +64,69 @@ CREATE TABLE IF NOT EXISTS shows (
     FULLTEXT INDEX ft_search (name, details)
 );
 
+-- Customer accounts. Sessions and their tokens live in Redis.
+CREATE TABLE IF NOT EXISTS users (
+    user_id VARCHAR(36) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NULL,
+    provider ENUM('local', 'google') NOT NULL DEFAULT 'local',
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY uq_users_email (email)
+);
+
+-- Orders group bookings for several shows made in one checkout
+CREATE TABLE IF NOT EXISTS orders (
+    order_id VARCHAR(50) PRIMARY KEY,
+    contact_type ENUM('mobile', 'email') NOT NULL,
+    contact_value VARCHAR(255) NOT NULL,
+    customer_name VARCHAR(255),
+    status ENUM('pending', 'confirmed', 'cancelled', 'expired') DEFAULT 'pending',
+    total_amount INT NOT NULL,
+    hold_expires_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    INDEX idx_orders_contact (contact_type, contact_value),
+    INDEX idx_orders_status (status)
+);
+
 -- Bookings table with optimized indexing
 CREATE TABLE IF NOT EXISTS bookings (
     booking_id VARCHAR(50) PRIMARY KEY,
+    reference VARCHAR(11) NULL,
     show_id VARCHAR(36) NOT NULL,
     contact_type ENUM('mobile', 'email') NOT NULL,
     contact_value VARCHAR(255) NOT NULL,
     number_of_tickets INT NOT NULL,
     customer_name VARCHAR(255),
     total_amount INT NOT NULL,
+    promo_code VARCHAR(50) NULL,
+    discount_amount INT NOT NULL DEFAULT 0,
+    order_id VARCHAR(50) NULL,
+    user_id VARCHAR(36) NULL,
+    balance_due INT NOT NULL DEFAULT 0,
     booking_date TIMESTAMP NOT NULL,
-    status ENUM('pending', 'confirmed', 'cancelled') DEFAULT 'pending',
+    status ENUM('pending', 'confirmed', 'cancelled', 'expired', 'checked_in', 'refunded', 'no_show', 'exchanged') DEFAULT 'pending',
+    hold_expires_at TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
     -- Foreign key constraint
     FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    
+    -- Customer-facing references are unique
+    UNIQUE KEY uq_bookings_reference (reference),
     
     -- Indexes for common queries
     INDEX idx_show_id (show_id),
+    INDEX idx_order_id (order_id),
+    INDEX idx_user_id (user_id),
     INDEX idx_contact (contact_type, contact_value),
     INDEX idx_status (status),
     INDEX idx_booking_date (booking_date),
@@ -62,7 +134,363 @@ CREATE TABLE IF NOT EXISTS bookings (
     
     -- Composite indexes
     INDEX idx_show_status (show_id, status),
-    INDEX idx_contact_booking (contact_type, contact_value, booking_date)
+    INDEX idx_status_hold (status, hold_expires_at),
+    INDEX idx_contact_booking (contact_type, contact_value, booking_date),
+    INDEX idx_promo_contact (promo_code, contact_type, contact_value)
+);
+
+-- Per-performance seat inventory, copied from venue_seats when a venue is assigned
+CREATE TABLE IF NOT EXISTS show_seats (
+    show_id VARCHAR(36) NOT NULL,
+    seat_id VARCHAR(64) NOT NULL,
+    section VARCHAR(50) NOT NULL,
+    row_label VARCHAR(10) NOT NULL,
+    seat_number INT NOT NULL,
+    accessible BOOLEAN DEFAULT FALSE,
+    restricted_view BOOLEAN DEFAULT FALSE,
+    status ENUM('available', 'reserved') NOT NULL DEFAULT 'available',
+    booking_id VARCHAR(50) NULL,
+    
+    PRIMARY KEY (show_id, seat_id),
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_show_seat_booking (booking_id),
+    INDEX idx_show_seat_status (show_id, status)
+);
+
+-- Audit trail of booking status transitions
+CREATE TABLE IF NOT EXISTS booking_status_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    booking_id VARCHAR(50) NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    reason VARCHAR(500),
+    changed_at TIMESTAMP NOT NULL,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_booking_changed (booking_id, changed_at)
+);
+
+-- Versioned changes to a booking's ticket count
+CREATE TABLE IF NOT EXISTS booking_amendments (
+    booking_id VARCHAR(50) NOT NULL,
+    version INT NOT NULL,
+    ticket_type VARCHAR(30) NULL,
+    from_tickets INT NOT NULL,
+    to_tickets INT NOT NULL,
+    from_amount INT NOT NULL,
+    to_amount INT NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    reason VARCHAR(500),
+    created_at TIMESTAMP NOT NULL,
+    
+    PRIMARY KEY (booking_id, version),
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE
+);
+
+-- Links bookings exchanged to another show to the bookings that replaced them
+CREATE TABLE IF NOT EXISTS booking_exchanges (
+    exchange_id VARCHAR(50) PRIMARY KEY,
+    source_booking_id VARCHAR(50) NOT NULL,
+    target_booking_id VARCHAR(50) NOT NULL,
+    source_show_id VARCHAR(36) NOT NULL,
+    target_show_id VARCHAR(36) NOT NULL,
+    number_of_tickets INT NOT NULL,
+    source_amount INT NOT NULL,
+    target_amount INT NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    reason VARCHAR(500),
+    created_at TIMESTAMP NOT NULL,
+    
+    FOREIGN KEY (source_booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    FOREIGN KEY (target_booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    
+    INDEX idx_exchanges_source (source_booking_id),
+    INDEX idx_exchanges_target (target_booking_id),
+    INDEX idx_exchanges_source_show (source_show_id, created_at),
+    INDEX idx_exchanges_target_show (target_show_id, created_at)
+);
+
+-- Per-show ticket types (price tiers). Shows without rows sell every ticket at shows.price.
+CREATE TABLE IF NOT EXISTS show_ticket_types (
+    show_id VARCHAR(36) NOT NULL,
+    code VARCHAR(30) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    price INT NOT NULL,
+    quota INT NULL,
+    eligibility VARCHAR(255) NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    
+    PRIMARY KEY (show_id, code),
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE
+);
+
+-- Refund windows per show. Shows without a row can always be cancelled with a full refund.
+CREATE TABLE IF NOT EXISTS show_cancellation_policies (
+    show_id VARCHAR(36) PRIMARY KEY,
+    full_refund_hours INT NOT NULL,
+    partial_refund_percent INT NOT NULL,
+    cutoff_hours INT NOT NULL,
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE
+);
+
+-- Per-type breakdown of a booking with the unit price it was sold at
+CREATE TABLE IF NOT EXISTS booking_ticket_lines (
+    booking_id VARCHAR(50) NOT NULL,
+    ticket_type VARCHAR(30) NOT NULL,
+    quantity INT NOT NULL,
+    unit_price INT NOT NULL,
+    
+    PRIMARY KEY (booking_id, ticket_type),
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_ticket_lines_type (ticket_type)
+);
+
+-- Individual tickets of a booking, one row per admission
+CREATE TABLE IF NOT EXISTS tickets (
+    ticket_id VARCHAR(50) PRIMARY KEY,
+    booking_id VARCHAR(50) NOT NULL,
+    show_id VARCHAR(36) NOT NULL,
+    ticket_type VARCHAR(30) NULL,
+    seat_id VARCHAR(64) NULL,
+    attendee_name VARCHAR(255) NULL,
+    status ENUM('active', 'checked_in', 'voided', 'released') DEFAULT 'active',
+    void_reason VARCHAR(255) NULL,
+    checked_in_at TIMESTAMP NULL,
+    checked_in_gate VARCHAR(50) NULL,
+    checked_in_scan_id VARCHAR(64) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_tickets_booking (booking_id),
+    INDEX idx_tickets_show_status (show_id, status)
+);
+
+-- Payment attempts for bookings, one row per intent started with the provider
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id VARCHAR(50) PRIMARY KEY,
+    booking_id VARCHAR(50) NOT NULL,
+    provider VARCHAR(30) NOT NULL,
+    provider_payment_id VARCHAR(100) NULL,
+    amount INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    purpose ENUM('booking', 'balance') NOT NULL DEFAULT 'booking',
+    status ENUM('pending', 'authorized', 'captured', 'failed', 'refunded') DEFAULT 'pending',
+    failure_reason VARCHAR(255) NULL,
+    refund_id VARCHAR(100) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    UNIQUE KEY uq_payments_provider_id (provider, provider_payment_id),
+    INDEX idx_payments_booking (booking_id),
+    INDEX idx_payments_status (status)
+);
+
+-- Ledger of refunds owed for cancelled bookings and what the processor did with them
+CREATE TABLE IF NOT EXISTS refunds (
+    refund_id VARCHAR(50) PRIMARY KEY,
+    booking_id VARCHAR(50) NOT NULL,
+    payment_id VARCHAR(50) NULL,
+    amount INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    rule VARCHAR(30) NOT NULL,
+    reason VARCHAR(255) NULL,
+    status ENUM('pending', 'succeeded', 'failed') DEFAULT 'pending',
+    processor_refund_id VARCHAR(100) NULL,
+    failure_reason VARCHAR(255) NULL,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    FOREIGN KEY (payment_id) REFERENCES payments(payment_id),
+    INDEX idx_refunds_booking (booking_id),
+    INDEX idx_refunds_status (status)
+);
+
+-- Every scan made at the door, including denied and duplicate scans
+CREATE TABLE IF NOT EXISTS ticket_scans (
+    scan_id VARCHAR(64) PRIMARY KEY,
+    ticket_id VARCHAR(50) NULL,
+    booking_id VARCHAR(50) NULL,
+    show_id VARCHAR(36) NULL,
+    gate_id VARCHAR(50) NOT NULL,
+    source ENUM('live', 'batch') NOT NULL,
+    result ENUM('checked_in', 'duplicate', 'denied') NOT NULL,
+    reason VARCHAR(50) NULL,
+    scanned_at TIMESTAMP NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    
+    INDEX idx_ticket_scans_ticket (ticket_id),
+    INDEX idx_ticket_scans_show (show_id, scanned_at)
+);
+
+-- Waitlist for sold-out shows. Offers are pending bookings held for WAITLIST_OFFER_DURATION.
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id VARCHAR(36) PRIMARY KEY,
+    show_id VARCHAR(36) NOT NULL,
+    contact_type ENUM('mobile', 'email') NOT NULL,
+    contact_value VARCHAR(255) NOT NULL,
+    customer_name VARCHAR(255),
+    number_of_tickets INT NOT NULL,
+    ticket_types JSON NULL,
+    status ENUM('waiting', 'offered', 'accepted', 'declined', 'expired', 'removed') NOT NULL DEFAULT 'waiting',
+    booking_id VARCHAR(50) NULL,
+    offer_expires_at TIMESTAMP NULL,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_waitlist_queue (show_id, status, created_at),
+    INDEX idx_waitlist_contact (show_id, contact_type, contact_value),
+    INDEX idx_waitlist_booking (booking_id)
+);
+
+-- Promo codes. Redemptions are the active bookings carrying the code.
+CREATE TABLE IF NOT EXISTS promotions (
+    code VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255),
+    discount_type ENUM('percentage', 'fixed') NOT NULL,
+    discount_value INT NOT NULL,
+    show_id VARCHAR(36) NULL,
+    valid_from TIMESTAMP NULL,
+    valid_until TIMESTAMP NULL,
+    max_redemptions INT NULL,
+    max_per_contact INT NULL,
+    min_tickets INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_promotions_show (show_id)
+);
+
+-- Idempotency keys: the first request's fingerprint and response, replayed to retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(50) NOT NULL,
+    owner_hash CHAR(64) NOT NULL DEFAULT '',
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT NULL,
+    response_body MEDIUMTEXT NULL,
+    claimed_by VARCHAR(36) NULL,
+    locked_until TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    
+    PRIMARY KEY (scope, owner_hash, idempotency_key),
+    INDEX idx_idempotency_expires (expires_at)
+);
+
+-- Transactional outbox: domain events written with the change they describe, delivered to subscribers afterwards
+CREATE TABLE IF NOT EXISTS outbox_events (
+    sequence BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(50) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_type VARCHAR(20) NOT NULL,
+    aggregate_id VARCHAR(50) NOT NULL,
+    payload JSON NOT NULL,
+    status ENUM('pending', 'delivered', 'dead') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_to VARCHAR(500) NULL,
+    last_error VARCHAR(1000) NULL,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP NULL,
+    claimed_by VARCHAR(36) NULL,
+    claimed_until TIMESTAMP NULL,
+    
+    INDEX idx_outbox_due (status, next_attempt_at),
+    INDEX idx_outbox_aggregate (aggregate_type, aggregate_id, sequence),
+    INDEX idx_outbox_delivered (status, delivered_at)
+);
+
+-- Partner webhook subscriptions
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    subscription_id VARCHAR(50) PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    event_types VARCHAR(255) NULL,
+    secret VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP NULL,
+    disabled_reason VARCHAR(255) NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    
+    INDEX idx_webhook_subscriptions_active (active)
+);
+
+-- Events queued for each webhook subscription
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id VARCHAR(50) PRIMARY KEY,
+    subscription_id VARCHAR(50) NOT NULL,
+    event_id VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSON NOT NULL,
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_status_code INT NULL,
+    last_error VARCHAR(1000) NULL,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP NULL,
+    claimed_by VARCHAR(36) NULL,
+    claimed_until TIMESTAMP NULL,
+    
+    FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    
+    UNIQUE KEY uk_webhook_deliveries_event (subscription_id, event_id),
+    INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_subscription (subscription_id, created_at),
+    INDEX idx_webhook_deliveries_delivered (status, delivered_at)
+);
+
+-- Delivery log: every request made for a webhook delivery
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    delivery_id VARCHAR(50) NOT NULL,
+    attempted_at TIMESTAMP NOT NULL,
+    status_code INT NULL,
+    response_body VARCHAR(1000) NULL,
+    error VARCHAR(1000) NULL,
+    duration_ms INT NOT NULL,
+    
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(delivery_id) ON DELETE CASCADE,
+    
+    INDEX idx_webhook_attempts_delivery (delivery_id, attempted_at)
+);
+
+-- Emails and text messages queued for bookings, and their delivery log
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id VARCHAR(50) PRIMARY KEY,
+    booking_id VARCHAR(50) NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    channel ENUM('email', 'mobile') NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NULL,
+    body TEXT NOT NULL,
+    source_id VARCHAR(50) NOT NULL,
+    status ENUM('pending', 'sent', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    transport VARCHAR(20) NULL,
+    last_error VARCHAR(1000) NULL,
+    created_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NULL,
+    claimed_by VARCHAR(36) NULL,
+    claimed_until TIMESTAMP NULL,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    
+    UNIQUE KEY uk_notifications_source (source_id, booking_id, kind),
+    INDEX idx_notifications_due (status, next_attempt_at),
+    INDEX idx_notifications_booking (booking_id, created_at)
 );
 
 -- Create a view for show availability with computed available tickets
@@ -114,31 +542,12 @@ BEGIN
     WHERE show_id = NEW.id;
 END$$
 
-CREATE TRIGGER update_availability_on_booking_insert
-AFTER INSERT ON bookings
-FOR EACH ROW
-BEGIN
-    UPDATE shows 
-    SET booked_tickets = booked_tickets + NEW.number_of_tickets
-    WHERE id = NEW.show_id;
-END$$
-
-CREATE TRIGGER update_availability_on_booking_update
-AFTER UPDATE ON bookings
-FOR EACH ROW
-BEGIN
-    IF OLD.status != NEW.status THEN
-        IF NEW.status = 'cancelled' AND OLD.status != 'cancelled' THEN
-            UPDATE shows 
-            SET booked_tickets = booked_tickets - NEW.number_of_tickets
-            WHERE id = NEW.show_id;
-        ELSEIF OLD.status = 'cancelled' AND NEW.status != 'cancelled' THEN
-            UPDATE shows 
-            SET booked_tickets = booked_tickets + NEW.number_of_tickets
-            WHERE id = NEW.show_id;
-        END IF;
-    END IF;
-END$$
+-- Note: shows.booked_tickets is maintained by the application inside the same
+-- transaction as each booking change, and recomputed from the bookings table by
+-- the reconciliation job. There are deliberately no triggers on bookings, as
+-- they would double count alongside the application updates.
+DROP TRIGGER IF EXISTS update_availability_on_booking_insert$$
+DROP TRIGGER IF EXISTS update_availability_on_booking_update$$
 
 DELIMITER ;
 
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types written to the outbox
+const (
+	TypeShowCreated          = "show.created"
+	TypeShowUpdated          = "show.updated"
+	TypeBookingCreated       = "booking.created"
+	TypeBookingStatusChanged = "booking.status_changed"
+	TypeBookingDeleted       = "booking.deleted"
+)
+
+// AllTypes lists every event type, for validating subscriptions
+var AllTypes = []string{
+	TypeShowCreated,
+	TypeShowUpdated,
+	TypeBookingCreated,
+	TypeBookingStatusChanged,
+	TypeBookingDeleted,
+}
+
+// Aggregates that events are about, the part of the event type before the dot
+const (
+	AggregateShow    = "show"
+	AggregateBooking = "booking"
+)
+
+// Delivery statuses of an outbox event
+const (
+	StatusPending   = "pending"   // Waiting for (another) delivery attempt
+	StatusDelivered = "delivered" // Every subscriber handled it
+	StatusDead      = "dead"      // Gave up after too many failed attempts; see the dead-letter list
+)
+
+// Parts of a show that a show.updated event reports as changed
+const (
+	ChangeDetails             = "details"
+	ChangeHoldMinutes         = "hold_minutes"
+	ChangeTicketTypes         = "ticket_types"
+	ChangeCancellationPolicy  = "cancellation_policy"
+	ChangeVenue               = "venue"
+	ChangeContactVerification = "contact_verification"
+)
+
+// Retry backoff: the first retry waits retryBaseDelay, doubling each time up to retryMaxDelay
+const (
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 30 * time.Minute
+)
+
+// maxErrorLength matches the last_error column width
+const maxErrorLength = 1000
+
+// Event is a domain event written to the outbox in the same transaction as
+// the change it describes, and delivered to subscribers afterwards
+type Event struct {
+	Sequence      int64           `json:"sequence"` // Outbox order
+	EventID       string          `json:"event_id"` // Random unique ID, e.g. EV-9F86D081884C7D65
+	Type          string          `json:"type"`     // One of the Type* constants
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"` // Show or booking ID
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredTo   []string        `json:"delivered_to,omitempty"` // Subscribers that have handled the event
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// ShowUpdate is the payload of show.updated
+type ShowUpdate struct {
+	ShowID uuid.UUID `json:"show_id"`
+	Change string    `json:"change"` // One of the Change* constants
+}
+
+// BookingStatusChange is the payload of booking.status_changed
+type BookingStatusChange struct {
+	BookingID  string    `json:"booking_id"`
+	ShowID     uuid.UUID `json:"show_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	Reason     string    `json:"reason,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// BookingDeletion is the payload of booking.deleted
+type BookingDeletion struct {
+	BookingID string    `json:"booking_id"`
+	ShowID    uuid.UUID `json:"show_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// NewEvent creates a pending event about a show or booking, given by the
+// prefix of eventType, with payload encoded as JSON
+func NewEvent(eventType, aggregateID string, payload interface{}) (*Event, error) {
+	if !IsKnownType(eventType) {
+		return nil, fmt.Errorf("invalid event type: %s", eventType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	now := time.Now()
+	return &Event{
+		EventID:       fmt.Sprintf("EV-%X", buf),
+		Type:          eventType,
+		AggregateType: eventType[:strings.Index(eventType, ".")],
+		AggregateID:   aggregateID,
+		Payload:       data,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// IsKnownType reports whether eventType is one of the event types
+func IsKnownType(eventType string) bool {
+	for _, known := range AllTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode unmarshals the event's payload into v
+func (e *Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// IsDeliveredTo reports whether a subscriber has already handled the event
+func (e *Event) IsDeliveredTo(subscriber string) bool {
+	for _, name := range e.DeliveredTo {
+		if name == subscriber {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowID returns the show an event is about. Every payload carries a
+// show_id: the show itself, a booking or a change to either.
+func (e *Event) ShowID() (uuid.UUID, error) {
+	var subject struct {
+		ShowID uuid.UUID `json:"show_id"`
+	}
+	if err := e.Decode(&subject); err != nil {
+		return uuid.Nil, err
+	}
+	if subject.ShowID == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("%s event %s has no show_id", e.Type, e.EventID)
+	}
+	return subject.ShowID, nil
+}
+
+// truncateError keeps an error message within the last_error column
+func truncateError(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
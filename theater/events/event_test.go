@@ -0,0 +1,68 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewEvent(t *testing.T) {
+	showID := uuid.New()
+	event, err := NewEvent(TypeShowUpdated, showID.String(), &ShowUpdate{ShowID: showID, Change: ChangeTicketTypes})
+	if err != nil {
+		t.Fatalf("NewEvent() error: %v", err)
+	}
+
+	if !strings.HasPrefix(event.EventID, "EV-") || len(event.EventID) != 19 {
+		t.Errorf("Expected an EV- event ID of 19 characters, got %s", event.EventID)
+	}
+	if event.AggregateType != AggregateShow || event.AggregateID != showID.String() {
+		t.Errorf("Expected show %s, got %s %s", showID, event.AggregateType, event.AggregateID)
+	}
+	if event.Status != StatusPending || event.Attempts != 0 {
+		t.Errorf("Expected a pending event with no attempts, got %s with %d", event.Status, event.Attempts)
+	}
+
+	var update ShowUpdate
+	if err := event.Decode(&update); err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if update.ShowID != showID || update.Change != ChangeTicketTypes {
+		t.Errorf("Expected %s/%s, got %s/%s", showID, ChangeTicketTypes, update.ShowID, update.Change)
+	}
+}
+
+func TestNewEventUnknownType(t *testing.T) {
+	if _, err := NewEvent("show.renamed", "1", nil); err == nil {
+		t.Error("Expected an error for an unknown event type")
+	}
+}
+
+func TestEventShowID(t *testing.T) {
+	showID := uuid.New()
+	event, err := NewEvent(TypeBookingStatusChanged, "BK-1", &BookingStatusChange{
+		BookingID: "BK-1",
+		ShowID:    showID,
+		ToStatus:  "confirmed",
+	})
+	if err != nil {
+		t.Fatalf("NewEvent() error: %v", err)
+	}
+	if event.AggregateType != AggregateBooking {
+		t.Errorf("Expected aggregate %s, got %s", AggregateBooking, event.AggregateType)
+	}
+
+	got, err := event.ShowID()
+	if err != nil {
+		t.Fatalf("ShowID() error: %v", err)
+	}
+	if got != showID {
+		t.Errorf("Expected show %s, got %s", showID, got)
+	}
+
+	event.Payload = []byte(`{"booking_id":"BK-1"}`)
+	if _, err := event.ShowID(); err == nil {
+		t.Error("Expected an error for a payload without show_id")
+	}
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/utils"
+)
+
+// Handler processes one event. Events are delivered at least once, so a
+// handler may see the same event again and must be idempotent; returning an
+// error schedules another attempt.
+type Handler func(event *Event) error
+
+// Subscription is an in-process subscriber to outbox events
+type Subscription struct {
+	Name   string   // Unique; recorded in an event's delivered_to once handled
+	Types  []string // Event types to receive; empty for every type
+	Handle Handler
+}
+
+// NewSubscription subscribes a named handler to the given event types, or to every type when none are given
+func NewSubscription(name string, handler Handler, eventTypes ...string) (*Subscription, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid subscription: name cannot be empty")
+	}
+	if strings.Contains(name, ",") {
+		return nil, fmt.Errorf("invalid subscription: name %q cannot contain a comma", name)
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("invalid subscription: %s has no handler", name)
+	}
+	for _, eventType := range eventTypes {
+		if !IsKnownType(eventType) {
+			return nil, fmt.Errorf("invalid subscription: %s subscribes to unknown event type %s", name, eventType)
+		}
+	}
+
+	return &Subscription{Name: name, Types: eventTypes, Handle: handler}, nil
+}
+
+// Wants reports whether the subscription receives events of the given type
+func (s *Subscription) Wants(eventType string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, wanted := range s.Types {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Deliver hands the event to every subscription that wants it and has not
+// handled it yet, and records the outcome on the event. Once every handler
+// has succeeded the event is delivered. Otherwise the attempt counts as
+// failed: the event is retried with backoff, skipping the handlers that
+// succeeded, until maxAttempts is reached and it goes to the dead-letter
+// list. It returns the failures of this attempt, if any.
+func Deliver(event *Event, subscriptions []*Subscription, maxAttempts int32, now time.Time) error {
+	var failures []string
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(event.Type) || event.IsDeliveredTo(subscription.Name) {
+			continue
+		}
+		if err := handle(subscription, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", subscription.Name, err))
+			continue
+		}
+		event.DeliveredTo = append(event.DeliveredTo, subscription.Name)
+	}
+
+	if len(failures) == 0 {
+		event.Status = StatusDelivered
+		event.LastError = ""
+		event.DeliveredAt = &now
+		return nil
+	}
+
+	event.Attempts++
+	event.LastError = truncateError(strings.Join(failures, "; "))
+	if event.Attempts >= maxAttempts {
+		event.Status = StatusDead
+	} else {
+		event.NextAttemptAt = now.Add(utils.RetryDelay(event.Attempts, retryBaseDelay, retryMaxDelay))
+	}
+	return fmt.Errorf("event %s delivery failed: %s", event.EventID, event.LastError)
+}
+
+// handle runs one subscriber, turning a panic into an error so that one bad
+// handler cannot stop the dispatcher
+func handle(subscription *Subscription, event *Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return subscription.Handle(event)
+}
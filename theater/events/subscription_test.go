@@ -0,0 +1,159 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestEvent(t *testing.T) *Event {
+	t.Helper()
+	showID := uuid.New()
+	event, err := NewEvent(TypeShowUpdated, showID.String(), &ShowUpdate{ShowID: showID, Change: ChangeDetails})
+	if err != nil {
+		t.Fatalf("NewEvent() error: %v", err)
+	}
+	return event
+}
+
+func mustSubscribe(t *testing.T, name string, handler Handler, eventTypes ...string) *Subscription {
+	t.Helper()
+	subscription, err := NewSubscription(name, handler, eventTypes...)
+	if err != nil {
+		t.Fatalf("NewSubscription() error: %v", err)
+	}
+	return subscription
+}
+
+func TestNewSubscriptionValidation(t *testing.T) {
+	noop := func(*Event) error { return nil }
+
+	tests := []struct {
+		name       string
+		subscriber string
+		handler    Handler
+		eventTypes []string
+	}{
+		{"empty name", " ", noop, nil},
+		{"comma in name", "mail,sms", noop, nil},
+		{"no handler", "mail", nil, nil},
+		{"unknown type", "mail", noop, []string{"show.renamed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSubscription(tt.subscriber, tt.handler, tt.eventTypes...); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSubscriptionWants(t *testing.T) {
+	noop := func(*Event) error { return nil }
+
+	all := mustSubscribe(t, "all", noop)
+	if !all.Wants(TypeBookingDeleted) {
+		t.Error("Expected a subscription without types to want every type")
+	}
+
+	bookingsOnly := mustSubscribe(t, "bookings", noop, TypeBookingCreated)
+	if !bookingsOnly.Wants(TypeBookingCreated) || bookingsOnly.Wants(TypeShowCreated) {
+		t.Error("Expected the subscription to want booking.created only")
+	}
+}
+
+func TestDeliverSuccess(t *testing.T) {
+	event := newTestEvent(t)
+	calls := 0
+	subscriptions := []*Subscription{
+		mustSubscribe(t, "index", func(*Event) error { calls++; return nil }),
+		mustSubscribe(t, "mail", func(*Event) error { calls++; return nil }, TypeBookingCreated),
+	}
+
+	now := time.Now()
+	if err := Deliver(event, subscriptions, 3, now); err != nil {
+		t.Fatalf("Deliver() error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected only the interested subscriber to be called, got %d calls", calls)
+	}
+	if event.Status != StatusDelivered || event.DeliveredAt == nil || !event.DeliveredAt.Equal(now) {
+		t.Errorf("Expected the event to be delivered at %s, got %s", now, event.Status)
+	}
+	if !event.IsDeliveredTo("index") || event.IsDeliveredTo("mail") {
+		t.Errorf("Expected delivered_to [index], got %v", event.DeliveredTo)
+	}
+}
+
+func TestDeliverRetriesOnlyFailedSubscribers(t *testing.T) {
+	event := newTestEvent(t)
+	indexCalls, mailCalls := 0, 0
+	mailErr := fmt.Errorf("smtp unavailable")
+	subscriptions := []*Subscription{
+		mustSubscribe(t, "index", func(*Event) error { indexCalls++; return nil }),
+		mustSubscribe(t, "mail", func(*Event) error { mailCalls++; return mailErr }),
+	}
+
+	now := time.Now()
+	if err := Deliver(event, subscriptions, 3, now); err == nil {
+		t.Fatal("Expected a delivery error")
+	}
+	if event.Status != StatusPending || event.Attempts != 1 {
+		t.Errorf("Expected a pending event after 1 attempt, got %s after %d", event.Status, event.Attempts)
+	}
+	if !event.NextAttemptAt.Equal(now.Add(retryBaseDelay)) {
+		t.Errorf("Expected the next attempt at %s, got %s", now.Add(retryBaseDelay), event.NextAttemptAt)
+	}
+	if event.LastError != "mail: smtp unavailable" {
+		t.Errorf("Expected last error %q, got %q", "mail: smtp unavailable", event.LastError)
+	}
+
+	mailErr = nil
+	if err := Deliver(event, subscriptions, 3, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Deliver() error on retry: %v", err)
+	}
+	if indexCalls != 1 || mailCalls != 2 {
+		t.Errorf("Expected index called once and mail twice, got %d and %d", indexCalls, mailCalls)
+	}
+	if event.Status != StatusDelivered || event.LastError != "" {
+		t.Errorf("Expected a delivered event without error, got %s %q", event.Status, event.LastError)
+	}
+}
+
+func TestDeliverDeadAfterMaxAttempts(t *testing.T) {
+	event := newTestEvent(t)
+	subscriptions := []*Subscription{
+		mustSubscribe(t, "webhook", func(*Event) error { return fmt.Errorf("timeout") }),
+	}
+
+	now := time.Now()
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := Deliver(event, subscriptions, 2, now); err == nil {
+			t.Fatalf("Expected a delivery error on attempt %d", attempt)
+		}
+	}
+
+	if event.Status != StatusDead || event.Attempts != 2 {
+		t.Errorf("Expected a dead event after 2 attempts, got %s after %d", event.Status, event.Attempts)
+	}
+}
+
+func TestDeliverRecoversFromPanic(t *testing.T) {
+	event := newTestEvent(t)
+	subscriptions := []*Subscription{
+		mustSubscribe(t, "broken", func(*Event) error { panic("nil map") }),
+	}
+
+	err := Deliver(event, subscriptions, 3, time.Now())
+	if err == nil {
+		t.Fatal("Expected a delivery error")
+	}
+	if !strings.Contains(event.LastError, "broken: panic: nil map") {
+		t.Errorf("Expected the panic in last error, got %q", event.LastError)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/utils"
+)
+
+var reconciliationService *service.ReconciliationService
+
+// InitializeReconciliationService initializes the reconciliation service
+func InitializeReconciliationService() {
+	reconciliationService = service.NewReconciliationService()
+}
+
+// RequireAdmin checks the request carries the admin API key as a bearer token.
+// Without ADMIN_API_KEY admin endpoints are refused with 503, unless
+// ADMIN_AUTH_DISABLED opens them for local development.
+func RequireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if isAdmin(r) {
+		return true
+	}
+
+	if utils.GetEnvOrDefault("ADMIN_API_KEY", "") == "" {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Admin endpoints are not configured",
+			&HTTPError{Code: http.StatusServiceUnavailable, Message: "ADMIN_API_KEY is not configured"})
+		return false
+	}
+
+	WriteErrorResponse(w, http.StatusUnauthorized, "Admin authorization required", ErrUnauthorized)
+	return false
+}
+
+// isAdmin reports whether the request carries the admin API key, or admin
+// endpoints are open for local development
+func isAdmin(r *http.Request) bool {
+	adminKey := utils.GetEnvOrDefault("ADMIN_API_KEY", "")
+	if adminKey == "" {
+		return adminAuthDisabled()
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) == 1
+}
+
+// adminAuthDisabled reports whether ADMIN_AUTH_DISABLED opens the admin
+// endpoints. It is ignored when GO_ENV is production.
+func adminAuthDisabled() bool {
+	return utils.GetEnvOrDefault("ADMIN_AUTH_DISABLED", "") == "true" &&
+		utils.GetEnvOrDefault("GO_ENV", "") != "production"
+}
+
+// CheckAdminConfig warns at startup when the admin endpoints cannot be used
+// or are open to everyone
+func CheckAdminConfig() {
+	switch {
+	case utils.GetEnvOrDefault("ADMIN_API_KEY", "") != "":
+		return
+	case adminAuthDisabled():
+		log.Printf("Warning: ADMIN_AUTH_DISABLED is set, admin endpoints are open to everyone")
+	default:
+		log.Printf("Warning: ADMIN_API_KEY not configured, admin endpoints are refused")
+	}
+}
+
+// ReconciliationReportHandler reports booked_tickets drift without changing anything
+func ReconciliationReportHandler(w http.ResponseWriter, r *http.Request) {
+	if reconciliationService == nil {
+		InitializeReconciliationService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	handleReconciliation(w, r, false)
+}
+
+// ReconciliationRepairHandler reports and repairs booked_tickets drift
+func ReconciliationRepairHandler(w http.ResponseWriter, r *http.Request) {
+	if reconciliationService == nil {
+		InitializeReconciliationService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	handleReconciliation(w, r, true)
+}
+
+func handleReconciliation(w http.ResponseWriter, r *http.Request, repair bool) {
+	var showID *uuid.UUID
+	if showIDStr := r.URL.Query().Get("show_id"); showIDStr != "" {
+		parsed, err := uuid.Parse(showIDStr)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid show ID format", err)
+			return
+		}
+		showID = &parsed
+	}
+
+	report, err := reconciliationService.Reconcile(showID, repair)
+	if err != nil {
+		log.Printf("Error reconciling bookings: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to reconcile bookings", err)
+		return
+	}
+
+	message := "Reconciliation report generated successfully"
+	if repair {
+		message = "Reconciliation repair completed successfully"
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, message, report)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+)
+
+var amendmentService *service.AmendmentService
+
+// InitializeAmendmentService initializes the amendment service
+func InitializeAmendmentService() {
+	amendmentService = service.NewAmendmentService()
+}
+
+// AmendBookingHandler changes the number of tickets on a pending or confirmed
+// booking, for an admin or the booking's owner. Pass version to refuse the
+// change if the booking was amended since it was read.
+func AmendBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if amendmentService == nil {
+		InitializeAmendmentService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+	if !RequireBookingOwner(w, r, bookingID) {
+		return
+	}
+
+	numberOfTicketsStr := r.URL.Query().Get("number_of_tickets")
+	if numberOfTicketsStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "number_of_tickets parameter is required"})
+		return
+	}
+	numberOfTickets, err := strconv.ParseInt(numberOfTicketsStr, 10, 32)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid number_of_tickets parameter", err)
+		return
+	}
+
+	var expectedVersion *int32
+	if versionStr := r.URL.Query().Get("version"); versionStr != "" {
+		version, err := strconv.ParseInt(versionStr, 10, 32)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid version parameter", err)
+			return
+		}
+		value := int32(version)
+		expectedVersion = &value
+	}
+
+	changedBy, reason := statusChangeAuthor(r)
+	result, err := amendmentService.AmendBooking(bookingID, r.URL.Query().Get("ticket_type"), int32(numberOfTickets),
+		expectedVersion, changedBy, reason)
+	if err != nil {
+		log.Printf("Error amending booking: %v", err)
+		WriteErrorResponse(w, amendmentErrorCode(err), "Failed to amend booking", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Booking amended successfully", result)
+}
+
+// GetBookingAmendmentsHandler lists the versioned amendments of a booking
+func GetBookingAmendmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if amendmentService == nil {
+		InitializeAmendmentService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	amendments, err := amendmentService.GetAmendments(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking amendments: %v", err)
+		WriteErrorResponse(w, amendmentErrorCode(err), "Failed to retrieve amendments", err)
+		return
+	}
+
+	version := int32(0)
+	if len(amendments) > 0 {
+		version = amendments[len(amendments)-1].Version
+	}
+
+	responseData := map[string]interface{}{
+		"version":    version,
+		"amendments": amendments,
+		"count":      len(amendments),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Booking amendments retrieved successfully", responseData)
+}
+
+// amendmentErrorCode maps a booking amendment error to an HTTP status code
+func amendmentErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "version conflict"),
+		strings.Contains(err.Error(), "hold has expired"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid amendment"),
+		strings.Contains(err.Error(), "invalid booking reference"):
+		return http.StatusBadRequest
+	default:
+		return reservationErrorCode(err)
+	}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/users"
+	"github.com/gsmayya/theater/verification"
+)
+
+var userService *service.UserService
+
+// InitializeUserService initializes the user service
+func InitializeUserService() {
+	userService = service.NewUserService()
+}
+
+// Authenticate wraps the router so requests carrying an account access token
+// in the Authorization header have the user attached to their context. An
+// invalid or revoked token is refused. Requests without one, or with a
+// bearer credential that is not an account token such as the admin API key,
+// pass through unchanged.
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if r.Method == "OPTIONS" || !users.LooksLikeToken(token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if userService == nil {
+			InitializeUserService()
+		}
+
+		user, sessionID, err := userService.Authenticate(token)
+		if err != nil {
+			log.Printf("Error authenticating request: %v", err)
+			WriteErrorResponse(w, authErrorCode(err), "Authentication failed", err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(users.NewContext(r.Context(), user, sessionID)))
+	})
+}
+
+// RequireUser checks that the request was made by a signed-in customer and
+// returns them. Otherwise it writes a 401 response and returns nil.
+func RequireUser(w http.ResponseWriter, r *http.Request) *users.User {
+	user, ok := users.FromContext(r.Context())
+	if !ok {
+		WriteErrorResponse(w, http.StatusUnauthorized, "Sign-in required", ErrUnauthorized)
+		return nil
+	}
+	return user
+}
+
+// RequireBookingOwner checks that the request was made by an admin or by the
+// customer a booking belongs to: signed in to the account it was made in, or
+// holding a verification token for its contact. Otherwise it writes an error
+// response and returns false.
+func RequireBookingOwner(w http.ResponseWriter, r *http.Request, idOrReference string) bool {
+	if isAdmin(r) {
+		return true
+	}
+
+	if bookingService == nil {
+		InitializeBookingService()
+	}
+	booking, err := bookingService.FindBooking(idOrReference)
+	if err != nil {
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to retrieve booking", err)
+		return false
+	}
+
+	user, signedIn := users.FromContext(r.Context())
+	if signedIn && booking.UserID != "" && booking.UserID == user.UserID {
+		return true
+	}
+
+	token := r.Header.Get(verification.TokenHeader)
+	if token != "" {
+		if verificationService == nil {
+			InitializeVerificationService()
+		}
+		err := verificationService.CheckContactToken(booking, token)
+		if err == nil {
+			return true
+		}
+		if !strings.Contains(err.Error(), "contact not verified") {
+			log.Printf("Error checking booking owner: %v", err)
+			WriteErrorResponse(w, verificationErrorCode(err), "Failed to check booking owner", err)
+			return false
+		}
+	}
+
+	if !signedIn && token == "" {
+		WriteErrorResponse(w, http.StatusUnauthorized, "Sign-in or contact verification required", ErrUnauthorized)
+		return false
+	}
+	WriteErrorResponse(w, http.StatusForbidden, "Only the booking's owner can change it", ErrForbidden)
+	return false
+}
+
+// RegisterHandler creates a customer account and signs it in
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if userService == nil {
+		InitializeUserService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	req, err := users.RegisterRequestFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid registration request", err)
+		return
+	}
+
+	session, err := userService.Register(req)
+	if err != nil {
+		log.Printf("Error registering user: %v", err)
+		WriteErrorResponse(w, authErrorCode(err), "Failed to register", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Account created successfully", session)
+}
+
+// LoginHandler signs a customer in with their email address and password
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if userService == nil {
+		InitializeUserService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	req, err := users.LoginRequestFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid login request", err)
+		return
+	}
+
+	session, err := userService.Login(req)
+	if err != nil {
+		log.Printf("Error signing in: %v", err)
+		WriteErrorResponse(w, authErrorCode(err), "Failed to sign in", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Signed in successfully", session)
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new token pair
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if userService == nil {
+		InitializeUserService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	req, err := users.RefreshRequestFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid refresh request", err)
+		return
+	}
+
+	tokens, err := userService.Refresh(req.RefreshToken)
+	if err != nil {
+		log.Printf("Error refreshing tokens: %v", err)
+		WriteErrorResponse(w, authErrorCode(err), "Failed to refresh tokens", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Tokens refreshed successfully", tokens)
+}
+
+// LogoutHandler ends the signed-in customer's session, or every session of
+// theirs with ?all=true
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if userService == nil {
+		InitializeUserService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	user := RequireUser(w, r)
+	if user == nil {
+		return
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+	ended, err := userService.Logout(user, users.SessionFromContext(r.Context()), all)
+	if err != nil {
+		log.Printf("Error signing out: %v", err)
+		WriteErrorResponse(w, authErrorCode(err), "Failed to sign out", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Signed out successfully", map[string]int{"sessions_ended": ended})
+}
+
+// CurrentUserHandler returns the signed-in customer's account
+func CurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	user := RequireUser(w, r)
+	if user == nil {
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "User retrieved successfully", user)
+}
+
+// UserBookingsHandler lists the bookings the signed-in customer made while signed in
+func UserBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	if userService == nil {
+		InitializeUserService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	user := RequireUser(w, r)
+	if user == nil {
+		return
+	}
+
+	userBookings, err := userService.GetUserBookings(user.UserID)
+	if err != nil {
+		log.Printf("Error getting user bookings: %v", err)
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get bookings", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Bookings retrieved successfully", userBookings)
+}
+
+// authErrorCode maps an account error to an HTTP status code
+func authErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "invalid credentials"),
+		strings.Contains(err.Error(), "invalid token"):
+		return http.StatusUnauthorized
+	case strings.Contains(err.Error(), "already registered"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "too many"):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid email"),
+		strings.Contains(err.Error(), "invalid password"),
+		strings.Contains(err.Error(), "invalid name"),
+		strings.Contains(err.Error(), "invalid phone"),
+		strings.Contains(err.Error(), "missing required fields"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -8,7 +9,10 @@ import (
 	"time"
 
 	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/idempotency"
 	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/users"
+	"github.com/gsmayya/theater/verification"
 	"github.com/google/uuid"
 )
 
@@ -35,6 +39,13 @@ func CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Retries carrying the same Idempotency-Key get the original response back
+	w, finish, handled := beginIdempotentRequest(w, r, idempotency.ScopeCreateBooking)
+	if handled {
+		return
+	}
+	defer finish()
+
 	var booking *bookings.Booking
 	var err error
 
@@ -54,44 +65,65 @@ func CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Bookings made while signed in belong to the customer's account
+	if user, ok := users.FromContext(r.Context()); ok {
+		booking.UserID = user.UserID
+	}
+
+	// Shows can require proof that the customer owns the booking's contact
+	if verificationService == nil {
+		InitializeVerificationService()
+	}
+	if err := verificationService.CheckBookingContact(booking, r.Header.Get(verification.TokenHeader)); err != nil {
+		log.Printf("Error checking booking contact: %v", err)
+		WriteErrorResponse(w, verificationErrorCode(err), "Contact verification required", err)
+		return
+	}
+
 	// Create booking using service
-	createdBooking, err := bookingService.CreateBooking(
-		booking.ShowID,
-		booking.ContactType,
-		booking.ContactValue,
-		booking.NumberOfTickets,
-		booking.CustomerName,
-	)
+	createdBooking, err := bookingService.CreateBooking(booking)
 
 	if err != nil {
 		log.Printf("Error creating booking: %v", err)
 		
 		// Determine appropriate HTTP status code based on error type
-		var statusCode int
-		switch {
-		case strings.Contains(err.Error(), "not found"):
-			statusCode = http.StatusNotFound
-		case strings.Contains(err.Error(), "insufficient tickets"):
-			statusCode = http.StatusConflict
-		default:
-			statusCode = http.StatusInternalServerError
-		}
+		statusCode := reservationErrorCode(err)
 		
-		WriteErrorResponse(w, statusCode, "Failed to create booking", err)
+		message := "Failed to create booking"
+		if strings.Contains(err.Error(), "insufficient tickets") {
+			message = "Not enough tickets available; join the waitlist at /api/v1/waitlist/join to be offered released tickets"
+		}
+
+		WriteErrorResponse(w, statusCode, message, err)
+		return
+	}
+
+	// Start paying for the booking; it is confirmed by the provider's webhook
+	if paymentService == nil {
+		InitializePaymentService()
+	}
+	payment, err := paymentService.StartPayment(createdBooking)
+	if err != nil {
+		log.Printf("Error starting payment: %v", err)
+		WriteErrorResponse(w, paymentErrorCode(err), "Failed to start payment", err)
 		return
 	}
 
 	// Success response
 	responseData := map[string]interface{}{
 		"booking_id": createdBooking.BookingID,
+		"reference":  createdBooking.Reference,
 		"show_id":    createdBooking.ShowID.String(),
 		"booking":    createdBooking,
 	}
+	if payment != nil {
+		responseData["payment"] = payment
+	}
 
 	WriteSuccessResponse(w, http.StatusCreated, "Booking created successfully", responseData)
 }
 
-// GetBookingHandler retrieves a specific booking by ID
+// GetBookingHandler retrieves a specific booking by ID or reference
 func GetBookingHandler(w http.ResponseWriter, r *http.Request) {
 	if bookingService == nil {
 		InitializeBookingService()
@@ -102,20 +134,26 @@ func GetBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Either the internal booking ID or the customer-facing reference
 	bookingID := r.URL.Query().Get("booking_id")
+	if bookingID == "" {
+		bookingID = r.URL.Query().Get("reference")
+	}
 	if bookingID == "" {
 		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter", 
-			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id parameter is required"})
+			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id or reference parameter is required"})
 		return
 	}
 
-	booking, err := bookingService.GetBooking(bookingID)
+	booking, err := bookingService.FindBooking(bookingID)
 	if err != nil {
 		log.Printf("Error getting booking: %v", err)
 		
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
 			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid booking reference") {
+			statusCode = http.StatusBadRequest
 		}
 		
 		WriteErrorResponse(w, statusCode, "Failed to retrieve booking", err)
@@ -125,7 +163,21 @@ func GetBookingHandler(w http.ResponseWriter, r *http.Request) {
 	WriteSuccessResponse(w, http.StatusOK, "Booking retrieved successfully", booking)
 }
 
-// UpdateBookingStatusHandler updates a booking's status
+// statusEndpoints names the endpoint that moves a booking to each status
+// with its own flow. The generic update-status endpoint refuses these, since
+// setting them directly would skip payment, the refund ledger or check-in.
+var statusEndpoints = map[string]string{
+	bookings.StatusPending:   "/api/v1/bookings",
+	bookings.StatusConfirmed: "/api/v1/payments/start or /api/v1/bookings/confirm",
+	bookings.StatusCancelled: "/api/v1/bookings/cancel",
+	bookings.StatusExpired:   "the hold expiry",
+	bookings.StatusCheckedIn: "/api/v1/checkin/scan",
+	bookings.StatusRefunded:  "/api/v1/admin/refunds/process",
+	bookings.StatusExchanged: "/api/v1/bookings/exchange",
+}
+
+// UpdateBookingStatusHandler sets a booking's status for transitions that
+// have no dedicated flow, such as marking a booking no_show. Admin only.
 func UpdateBookingStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if bookingService == nil {
 		InitializeBookingService()
@@ -141,6 +193,10 @@ func UpdateBookingStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !RequireAdmin(w, r) {
+		return
+	}
+
 	bookingID := r.URL.Query().Get("booking_id")
 	status := r.URL.Query().Get("status")
 
@@ -150,18 +206,17 @@ func UpdateBookingStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := bookingService.UpdateBookingStatus(bookingID, status)
+	if endpoint, ok := statusEndpoints[status]; ok {
+		WriteErrorResponse(w, http.StatusConflict, "Failed to update booking status",
+			fmt.Errorf("invalid status transition: bookings are moved to %s through %s", status, endpoint))
+		return
+	}
+
+	changedBy, reason := statusChangeAuthor(r)
+	err := bookingService.UpdateBookingStatus(bookingID, status, changedBy, reason)
 	if err != nil {
 		log.Printf("Error updating booking status: %v", err)
-		
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "not found") {
-			statusCode = http.StatusNotFound
-		} else if strings.Contains(err.Error(), "invalid status") {
-			statusCode = http.StatusBadRequest
-		}
-		
-		WriteErrorResponse(w, statusCode, "Failed to update booking status", err)
+		WriteErrorResponse(w, statusChangeErrorCode(err), "Failed to update booking status", err)
 		return
 	}
 
@@ -236,7 +291,13 @@ func GetBookingsByContactHandler(w http.ResponseWriter, r *http.Request) {
 	bookingsList, err := bookingService.GetBookingsByContact(contactType, contactValue)
 	if err != nil {
 		log.Printf("Error getting bookings by contact: %v", err)
-		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve bookings", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid contact_value") || strings.Contains(err.Error(), "contact_type must be") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve bookings", err)
 		return
 	}
 
@@ -398,7 +459,8 @@ func GetShowBookingSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	WriteSuccessResponse(w, http.StatusOK, "Booking summary retrieved successfully", summary)
 }
 
-// ConfirmBookingHandler confirms a pending booking
+// ConfirmBookingHandler confirms a pending booking. Customers' bookings are
+// confirmed by their payment, so confirming one by hand is for admins only.
 func ConfirmBookingHandler(w http.ResponseWriter, r *http.Request) {
 	if bookingService == nil {
 		InitializeBookingService()
@@ -414,6 +476,10 @@ func ConfirmBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !RequireAdmin(w, r) {
+		return
+	}
+
 	bookingID := r.URL.Query().Get("booking_id")
 	if bookingID == "" {
 		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter", 
@@ -421,16 +487,11 @@ func ConfirmBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := bookingService.ConfirmBooking(bookingID)
+	changedBy, reason := statusChangeAuthor(r)
+	err := bookingService.ConfirmBooking(bookingID, changedBy, reason)
 	if err != nil {
 		log.Printf("Error confirming booking: %v", err)
-		
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "not found") {
-			statusCode = http.StatusNotFound
-		}
-		
-		WriteErrorResponse(w, statusCode, "Failed to confirm booking", err)
+		WriteErrorResponse(w, statusChangeErrorCode(err), "Failed to confirm booking", err)
 		return
 	}
 
@@ -442,10 +503,13 @@ func ConfirmBookingHandler(w http.ResponseWriter, r *http.Request) {
 	WriteSuccessResponse(w, http.StatusOK, "Booking confirmed successfully", responseData)
 }
 
-// CancelBookingHandler cancels a booking
+// CancelBookingHandler cancels a booking, for an admin or the booking's owner,
+// under its show's cancellation policy and refunds what the policy allows.
+// Admins can pass override=true to cancel when the policy no longer allows
+// it, and refund_percent to set the refund.
 func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
-	if bookingService == nil {
-		InitializeBookingService()
+	if refundService == nil {
+		InitializeRefundService()
 	}
 
 	// Handle CORS preflight requests
@@ -464,24 +528,111 @@ func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id parameter is required"})
 		return
 	}
+	if !RequireBookingOwner(w, r, bookingID) {
+		return
+	}
+
+	override, refundPercent, ok := parseRefundOverride(w, r)
+	if !ok {
+		return
+	}
 
-	err := bookingService.CancelBooking(bookingID)
+	changedBy, reason := statusChangeAuthor(r)
+	cancellation, err := refundService.CancelBooking(bookingID, changedBy, reason, override, refundPercent)
 	if err != nil {
 		log.Printf("Error cancelling booking: %v", err)
-		
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to cancel booking", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Booking cancelled successfully", cancellation)
+}
+
+// GetBookingHistoryHandler returns the status history of a booking
+func GetBookingHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if bookingService == nil {
+		InitializeBookingService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID := r.URL.Query().Get("booking_id")
+	if bookingID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id parameter is required"})
+		return
+	}
+
+	history, err := bookingService.GetBookingHistory(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking history: %v", err)
+
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
 			statusCode = http.StatusNotFound
 		}
-		
-		WriteErrorResponse(w, statusCode, "Failed to cancel booking", err)
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve booking history", err)
 		return
 	}
 
 	responseData := map[string]interface{}{
 		"booking_id": bookingID,
-		"status":     "cancelled",
+		"history":    history,
+		"count":      len(history),
 	}
 
-	WriteSuccessResponse(w, http.StatusOK, "Booking cancelled successfully", responseData)
+	WriteSuccessResponse(w, http.StatusOK, "Booking history retrieved successfully", responseData)
+}
+
+// statusChangeAuthor returns who is changing a booking's status and why. The
+// author comes from the request's credentials, never its parameters: "admin"
+// for the admin API key, "user:<id>" for a signed-in customer, otherwise "api".
+func statusChangeAuthor(r *http.Request) (string, string) {
+	reason := r.URL.Query().Get("reason")
+	if isAdmin(r) {
+		return "admin", reason
+	}
+	if user, ok := users.FromContext(r.Context()); ok {
+		return "user:" + user.UserID, reason
+	}
+	return "api", reason
+}
+
+// reservationErrorCode maps an error from reserving a booking's tickets to an HTTP status code
+func reservationErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "insufficient tickets"),
+		strings.Contains(err.Error(), "seats unavailable"),
+		strings.Contains(err.Error(), "promo code limit reached"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid seat selection"),
+		strings.Contains(err.Error(), "invalid ticket selection"),
+		strings.Contains(err.Error(), "invalid promo code"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statusChangeErrorCode maps a booking status change error to an HTTP status code
+func statusChangeErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid status transition"),
+		strings.Contains(err.Error(), "cancellation not allowed"),
+		strings.Contains(err.Error(), "hold has expired"),
+		strings.Contains(err.Error(), "insufficient tickets"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid status"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/service"
+)
+
+var eventService *service.EventService
+
+// InitializeEventService initializes the event service
+func InitializeEventService() {
+	eventService = service.NewEventService()
+}
+
+// ListEventsHandler lists outbox events (admin function). By default it
+// returns the dead-letter list; pass status for another status, show_id or
+// booking_id for the events of one show or booking, or event_id for one event.
+func ListEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if eventService == nil {
+		InitializeEventService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	if eventID := query.Get("event_id"); eventID != "" {
+		event, err := eventService.GetEvent(eventID)
+		if err != nil {
+			log.Printf("Error getting event: %v", err)
+			WriteErrorResponse(w, eventErrorCode(err), "Failed to retrieve event", err)
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, "Event retrieved successfully", event)
+		return
+	}
+
+	var outbox []*events.Event
+	var err error
+	response := map[string]interface{}{}
+	switch {
+	case query.Get("show_id") != "":
+		outbox, err = eventService.GetAggregateEvents(events.AggregateShow, query.Get("show_id"))
+		response["show_id"] = query.Get("show_id")
+	case query.Get("booking_id") != "":
+		outbox, err = eventService.GetAggregateEvents(events.AggregateBooking, query.Get("booking_id"))
+		response["booking_id"] = query.Get("booking_id")
+	default:
+		status := query.Get("status")
+		if status == "" {
+			status = events.StatusDead
+		}
+		outbox, err = eventService.ListEvents(status)
+		response["status"] = status
+	}
+	if err != nil {
+		log.Printf("Error listing events: %v", err)
+		WriteErrorResponse(w, eventErrorCode(err), "Failed to list events", err)
+		return
+	}
+
+	response["events"] = outbox
+	response["count"] = len(outbox)
+
+	WriteSuccessResponse(w, http.StatusOK, "Events retrieved successfully", response)
+}
+
+// RetryEventHandler puts a dead event back in the delivery queue (admin function)
+func RetryEventHandler(w http.ResponseWriter, r *http.Request) {
+	if eventService == nil {
+		InitializeEventService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "event_id parameter is required"})
+		return
+	}
+
+	event, err := eventService.RetryEvent(eventID)
+	if err != nil {
+		log.Printf("Error retrying event: %v", err)
+		WriteErrorResponse(w, eventErrorCode(err), "Failed to retry event", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Event requeued successfully", event)
+}
+
+// eventErrorCode maps an outbox event error to an HTTP status code
+func eventErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid event status"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid status filter"),
+		strings.Contains(err.Error(), "cannot be empty"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
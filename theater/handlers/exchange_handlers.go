@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/service"
+)
+
+var exchangeService *service.ExchangeService
+
+// InitializeExchangeService initializes the exchange service
+func InitializeExchangeService() {
+	exchangeService = service.NewExchangeService()
+}
+
+// ExchangeBookingHandler moves a booking, or some of its tickets, to another
+// performance, for an admin or the booking's owner. Leave number_of_tickets and ticket_types out to move every
+// ticket; seats are required when the target show has reserved seating.
+func ExchangeBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if exchangeService == nil {
+		InitializeExchangeService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+	if !RequireBookingOwner(w, r, bookingID) {
+		return
+	}
+
+	targetShowIDStr := r.URL.Query().Get("target_show_id")
+	if targetShowIDStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "target_show_id parameter is required"})
+		return
+	}
+	targetShowID, err := uuid.Parse(targetShowIDStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid target_show_id format", err)
+		return
+	}
+
+	var selection bookings.ExchangeSelection
+	if numberOfTicketsStr := r.URL.Query().Get("number_of_tickets"); numberOfTicketsStr != "" {
+		numberOfTickets, err := strconv.ParseInt(numberOfTicketsStr, 10, 32)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid number_of_tickets parameter", err)
+			return
+		}
+		selection.NumberOfTickets = int32(numberOfTickets)
+	}
+	selection.TicketTypes, err = bookings.ParseTicketSelection(r.URL.Query().Get("ticket_types"))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid ticket_types parameter", err)
+		return
+	}
+
+	seats := bookings.ParseSeatList(r.URL.Query().Get("seats"))
+	changedBy, reason := statusChangeAuthor(r)
+	result, err := exchangeService.ExchangeBooking(bookingID, targetShowID, selection, seats, changedBy, reason)
+	if err != nil {
+		log.Printf("Error exchanging booking: %v", err)
+		WriteErrorResponse(w, exchangeErrorCode(err), "Failed to exchange booking", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Booking exchanged successfully", result)
+}
+
+// GetBookingExchangesHandler lists the exchanges a booking was the source or target of
+func GetBookingExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	if exchangeService == nil {
+		InitializeExchangeService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	exchanges, err := exchangeService.GetBookingExchanges(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking exchanges: %v", err)
+		WriteErrorResponse(w, exchangeErrorCode(err), "Failed to retrieve exchanges", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"exchanges": exchanges,
+		"count":     len(exchanges),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Booking exchanges retrieved successfully", responseData)
+}
+
+// GetShowExchangesHandler lists the exchanges into and out of a show, for reporting
+func GetShowExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	if exchangeService == nil {
+		InitializeExchangeService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	showIDStr := r.URL.Query().Get("show_id")
+	if showIDStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "show_id parameter is required"})
+		return
+	}
+	showID, err := uuid.Parse(showIDStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid show_id format", err)
+		return
+	}
+
+	exchanges, err := exchangeService.GetShowExchanges(showID)
+	if err != nil {
+		log.Printf("Error getting show exchanges: %v", err)
+		WriteErrorResponse(w, exchangeErrorCode(err), "Failed to retrieve exchanges", err)
+		return
+	}
+
+	exchangedIn, exchangedOut := int32(0), int32(0)
+	for _, exchange := range exchanges {
+		if exchange.TargetShowID == showID {
+			exchangedIn += exchange.NumberOfTickets
+		} else {
+			exchangedOut += exchange.NumberOfTickets
+		}
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":     showID,
+		"exchanges":   exchanges,
+		"count":       len(exchanges),
+		"tickets_in":  exchangedIn,
+		"tickets_out": exchangedOut,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Show exchanges retrieved successfully", responseData)
+}
+
+// exchangeErrorCode maps a booking exchange error to an HTTP status code
+func exchangeErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "hold has expired"),
+		strings.Contains(err.Error(), "invalid status transition"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid exchange"),
+		strings.Contains(err.Error(), "invalid booking reference"):
+		return http.StatusBadRequest
+	default:
+		return reservationErrorCode(err)
+	}
+}
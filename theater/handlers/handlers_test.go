@@ -2,10 +2,20 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/idempotency"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/users"
+	"github.com/gsmayya/theater/utils"
 )
 
 func TestDefaultHandler(t *testing.T) {
@@ -292,3 +302,477 @@ func TestJSONEncodingError(t *testing.T) {
 		t.Error("Expected some status code to be set")
 	}
 }
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminKey      string
+		authDisabled  string
+		goEnv         string
+		authorization string
+		expected      bool
+		code          int
+	}{
+		{"No key configured", "", "", "", "", false, http.StatusServiceUnavailable},
+		{"No key configured with a token", "", "", "", "Bearer anything", false, http.StatusServiceUnavailable},
+		{"Auth disabled for development", "", "true", "development", "", true, http.StatusOK},
+		{"Auth disabled in production", "", "true", "production", "", false, http.StatusServiceUnavailable},
+		{"Valid bearer token", "secret-key", "", "", "Bearer secret-key", true, http.StatusOK},
+		{"Wrong bearer token", "secret-key", "", "", "Bearer wrong-key", false, http.StatusUnauthorized},
+		{"Missing header", "secret-key", "", "", "", false, http.StatusUnauthorized},
+		{"Key configured with auth disabled", "secret-key", "true", "development", "", false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("ADMIN_API_KEY", tt.adminKey)
+			defer os.Unsetenv("ADMIN_API_KEY")
+			os.Setenv("ADMIN_AUTH_DISABLED", tt.authDisabled)
+			defer os.Unsetenv("ADMIN_AUTH_DISABLED")
+			os.Setenv("GO_ENV", tt.goEnv)
+			defer os.Unsetenv("GO_ENV")
+
+			req := httptest.NewRequest("GET", "/api/v1/admin/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			result := RequireAdmin(w, req)
+
+			if result != tt.expected {
+				t.Errorf("Expected RequireAdmin to return %v, got %v", tt.expected, result)
+			}
+
+			if w.Code != tt.code {
+				t.Errorf("Expected status %d, got %d", tt.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestStatusChangeAuthor(t *testing.T) {
+	os.Setenv("ADMIN_API_KEY", "secret-key")
+	defer os.Unsetenv("ADMIN_API_KEY")
+
+	tests := []struct {
+		name          string
+		authorization string
+		user          *users.User
+		expected      string
+	}{
+		{"Admin key", "Bearer secret-key", nil, "admin"},
+		{"Signed-in customer", "", &users.User{UserID: "user-1"}, "user:user-1"},
+		{"Wrong admin key", "Bearer wrong-key", nil, "api"},
+		{"Anonymous", "", nil, "api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/bookings/cancel?changed_by=admin&reason=sold+out", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.user != nil {
+				req = req.WithContext(users.NewContext(req.Context(), tt.user, "session-1"))
+			}
+
+			changedBy, reason := statusChangeAuthor(req)
+			if changedBy != tt.expected {
+				t.Errorf("Expected changed by %q, got %q", tt.expected, changedBy)
+			}
+			if reason != "sold out" {
+				t.Errorf("Expected reason %q, got %q", "sold out", reason)
+			}
+		})
+	}
+}
+
+func TestStatusChangeErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("failed to update booking status: booking not found: BK-1"), http.StatusNotFound},
+		{fmt.Errorf("failed to update booking status: invalid status transition: cancelled -> confirmed"), http.StatusConflict},
+		{fmt.Errorf("failed to update booking status: booking hold has expired"), http.StatusConflict},
+		{fmt.Errorf("invalid status: shipped. Valid statuses are: pending"), http.StatusBadRequest},
+		{fmt.Errorf("failed to update booking status: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := statusChangeErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTicketErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("ticket not found: TK-1"), http.StatusNotFound},
+		{fmt.Errorf("failed to void ticket: invalid ticket status: ticket TK-1 is voided"), http.StatusConflict},
+		{fmt.Errorf("failed to void ticket: booking hold has expired"), http.StatusConflict},
+		{fmt.Errorf("invalid attendee name: must be at most 255 characters"), http.StatusBadRequest},
+		{fmt.Errorf("failed to void ticket: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := ticketErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPaymentErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("payment not found: mock pi_mock_1"), http.StatusNotFound},
+		{fmt.Errorf("mock payments are not enabled"), http.StatusNotFound},
+		{fmt.Errorf("invalid webhook signature"), http.StatusBadRequest},
+		{fmt.Errorf("invalid outcome: \"paid\" must be succeeded or failed"), http.StatusBadRequest},
+		{fmt.Errorf("payment provider unavailable: connection refused"), http.StatusBadGateway},
+		{fmt.Errorf("failed to capture payment PAY-1: timeout"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := paymentErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEventErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("event not found: EV-1"), http.StatusNotFound},
+		{fmt.Errorf("invalid event status: event EV-1 is delivered, only dead events can be retried"), http.StatusConflict},
+		{fmt.Errorf("invalid status filter: \"lost\""), http.StatusBadRequest},
+		{fmt.Errorf("booking ID cannot be empty"), http.StatusBadRequest},
+		{fmt.Errorf("failed to get events: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := eventErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNotificationErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("booking not found: BK-1"), http.StatusNotFound},
+		{fmt.Errorf("too many requests: a confirmation was queued at 2026-03-14T12:00:00Z, try again in 4m0s"), http.StatusTooManyRequests},
+		{fmt.Errorf("invalid booking status: booking BK-1 is pending, only confirmed bookings have a confirmation to resend"), http.StatusConflict},
+		{fmt.Errorf("notifications unavailable: no mobile transport configured"), http.StatusServiceUnavailable},
+		{fmt.Errorf("booking ID cannot be empty"), http.StatusBadRequest},
+		{fmt.Errorf("failed to queue notification: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := notificationErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIdempotencyErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{idempotency.ErrFingerprintMismatch, http.StatusUnprocessableEntity},
+		{idempotency.ErrInProgress, http.StatusConflict},
+		{fmt.Errorf("invalid idempotency key: key cannot be empty"), http.StatusBadRequest},
+		{fmt.Errorf("failed to claim idempotency key: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := idempotencyErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBeginIdempotentRequestWithoutKey(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/bookings/create", strings.NewReader(`{"show_id":"x"}`))
+	w := httptest.NewRecorder()
+
+	writer, finish, handled := beginIdempotentRequest(w, req, idempotency.ScopeCreateBooking)
+	if handled {
+		t.Fatal("Requests without an Idempotency-Key should pass through")
+	}
+	if writer != w {
+		t.Error("Requests without an Idempotency-Key should keep the original writer")
+	}
+	finish()
+}
+
+func TestResponseRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := &responseRecorder{ResponseWriter: w}
+
+	WriteSuccessResponse(recorder, http.StatusCreated, "Booking created successfully", map[string]string{"booking_id": "BK-1"})
+
+	if recorder.statusCode != http.StatusCreated || w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d to be recorded and passed through, got %d and %d", http.StatusCreated, recorder.statusCode, w.Code)
+	}
+	if recorder.body.String() != w.Body.String() || !strings.Contains(recorder.body.String(), "BK-1") {
+		t.Errorf("Expected the body to be recorded, got %q", recorder.body.String())
+	}
+
+	replay := httptest.NewRecorder()
+	writeReplayedResponse(replay, &idempotency.Record{StatusCode: recorder.statusCode, Body: recorder.body.String()})
+	if replay.Code != http.StatusCreated || replay.Body.String() != w.Body.String() {
+		t.Errorf("Replay should match the original response, got %d %q", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get(replayedHeader) != "true" {
+		t.Errorf("Expected %s header on replayed responses", replayedHeader)
+	}
+}
+
+func TestVerificationErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("show not found"), http.StatusNotFound},
+		{fmt.Errorf("too many requests: a code was sent recently, try again in 42s"), http.StatusTooManyRequests},
+		{fmt.Errorf("too many attempts: request a new code"), http.StatusTooManyRequests},
+		{fmt.Errorf("contact not verified: the verification token is invalid or has expired"), http.StatusForbidden},
+		{fmt.Errorf("verification unavailable: no mobile transport configured"), http.StatusServiceUnavailable},
+		{fmt.Errorf("failed to send verification code: smtp: connection refused"), http.StatusBadGateway},
+		{fmt.Errorf("invalid contact: contact_type must be either 'mobile' or 'email'"), http.StatusBadRequest},
+		{fmt.Errorf("invalid verification code: 3 attempts left"), http.StatusBadRequest},
+		{fmt.Errorf("verification code cannot be empty"), http.StatusBadRequest},
+		{fmt.Errorf("failed to save verification code: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := verificationErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAuthErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{fmt.Errorf("invalid credentials: email or password is incorrect"), http.StatusUnauthorized},
+		{fmt.Errorf("invalid token: expired"), http.StatusUnauthorized},
+		{fmt.Errorf("invalid token: refresh token was already used, the session has been revoked"), http.StatusUnauthorized},
+		{fmt.Errorf("email already registered: fan@example.com"), http.StatusConflict},
+		{fmt.Errorf("too many sign-in attempts: try again later"), http.StatusTooManyRequests},
+		{fmt.Errorf("invalid email: invalid contact_value for contact_type email: not a plain email address"), http.StatusBadRequest},
+		{fmt.Errorf("invalid password: must be at least 8 characters"), http.StatusBadRequest},
+		{fmt.Errorf("invalid name: name cannot be empty"), http.StatusBadRequest},
+		{fmt.Errorf("invalid phone: invalid contact_value for contact_type mobile: mobile number has no digits"), http.StatusBadRequest},
+		{fmt.Errorf("failed to create session: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := authErrorCode(tt.err); got != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAuthenticatePassesThroughWithoutAccountToken(t *testing.T) {
+	var reached bool
+	handler := Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		if RequireUser(w, r) != nil {
+			t.Error("Expected no user on an unauthenticated request")
+		}
+	}))
+
+	for _, authorization := range []string{"", "Bearer admin-api-key"} {
+		reached = false
+		req := httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if !reached {
+			t.Errorf("Expected the request with Authorization %q to reach the handler", authorization)
+		}
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected RequireUser to answer %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+// requireDatabase skips the test when MySQL is not reachable, since
+// db.GetDatabase exits the process if it cannot connect
+func requireDatabase(t *testing.T) {
+	t.Helper()
+
+	addr := net.JoinHostPort(
+		utils.GetEnvOrDefault("DB_HOST", "localhost"),
+		utils.GetEnvOrDefault("DB_PORT", "3306"),
+	)
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Skipf("Skipping integration test - database not reachable at %s", addr)
+	}
+	conn.Close()
+}
+
+// createOwnedBooking books a ticket on a new show for a signed-in customer.
+// The show and its bookings are deleted when the test ends.
+func createOwnedBooking(t *testing.T, userID string) *bookings.Booking {
+	t.Helper()
+
+	showService := service.NewShowService()
+	show, err := showService.CreateShow("Owner Test", "Integration test", "Test Hall", 100, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to create show: %v", err)
+	}
+	t.Cleanup(func() { showService.DeleteShow(show.Show_Id.String()) })
+
+	draft := bookings.NewBooking(show.Show_Id, "email", "owner@example.com", 1, 0)
+	draft.UserID = userID
+	booking, err := service.NewBookingService().CreateBooking(draft)
+	if err != nil {
+		t.Fatalf("Failed to create booking: %v", err)
+	}
+	return booking
+}
+
+// requestAs signs a request in as the given account, or leaves it anonymous
+// when userID is empty
+func requestAs(req *http.Request, userID string) *http.Request {
+	if userID == "" {
+		return req
+	}
+	return req.WithContext(users.NewContext(req.Context(), &users.User{UserID: userID}, "session-1"))
+}
+
+func TestCancelBookingRequiresOwner(t *testing.T) {
+	requireDatabase(t)
+	os.Setenv("ADMIN_API_KEY", "secret-key")
+	defer os.Unsetenv("ADMIN_API_KEY")
+
+	booking := createOwnedBooking(t, "owner-1")
+
+	tests := []struct {
+		name   string
+		userID string
+		code   int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"another customer", "intruder-1", http.StatusForbidden},
+		{"owner", "owner-1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/bookings/cancel?booking_id="+booking.BookingID, nil)
+			w := httptest.NewRecorder()
+
+			CancelBookingHandler(w, requestAs(req, tt.userID))
+
+			if w.Code != tt.code {
+				t.Errorf("Expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTicketTokensRequiresOwner(t *testing.T) {
+	requireDatabase(t)
+	os.Setenv("ADMIN_API_KEY", "secret-key")
+	defer os.Unsetenv("ADMIN_API_KEY")
+
+	booking := createOwnedBooking(t, "owner-1")
+
+	tests := []struct {
+		name   string
+		userID string
+		code   int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"another customer", "intruder-1", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/bookings/ticket-token?booking_id="+booking.BookingID, nil)
+			w := httptest.NewRecorder()
+
+			GetTicketTokensHandler(w, requestAs(req, tt.userID))
+
+			if w.Code != tt.code {
+				t.Errorf("Expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTicketHandlersRequireOwner(t *testing.T) {
+	requireDatabase(t)
+	os.Setenv("ADMIN_API_KEY", "secret-key")
+	defer os.Unsetenv("ADMIN_API_KEY")
+
+	booking := createOwnedBooking(t, "owner-1")
+	bookingTickets, err := service.NewTicketService().GetBookingTickets(booking.BookingID)
+	if err != nil || len(bookingTickets) == 0 {
+		t.Fatalf("Failed to get booking tickets: %v", err)
+	}
+	ticketID := bookingTickets[0].TicketID
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		url     string
+	}{
+		{"booking tickets", GetBookingTicketsHandler, "GET", "/api/v1/bookings/tickets?booking_id=" + booking.BookingID},
+		{"get ticket", GetTicketHandler, "GET", "/api/v1/tickets/get?ticket_id=" + ticketID},
+		{"set attendee", SetAttendeeHandler, "PUT", "/api/v1/tickets/attendee?ticket_id=" + ticketID + "&attendee_name=Mallory"},
+		{"void ticket", VoidTicketHandler, "POST", "/api/v1/tickets/void?ticket_id=" + ticketID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			tt.handler(w, requestAs(req, "intruder-1"))
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/idempotency"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/users"
+)
+
+// replayedHeader marks responses that were replayed for a repeated idempotency key
+const replayedHeader = "Idempotent-Replayed"
+
+var idempotencyService *service.IdempotencyService
+
+// InitializeIdempotencyService initializes the idempotency service
+func InitializeIdempotencyService() {
+	idempotencyService = service.NewIdempotencyService()
+}
+
+// beginIdempotentRequest honors the Idempotency-Key header of a create
+// request. Requests without the header pass straight through. Otherwise the
+// key is claimed and the returned writer records the response, which the
+// returned finish func stores once the handler is done. When handled is true
+// a response (a replay or an error) has already been written and the handler
+// must return.
+func beginIdempotentRequest(w http.ResponseWriter, r *http.Request, scope string) (writer http.ResponseWriter, finish func(), handled bool) {
+	key := r.Header.Get(idempotency.HeaderName)
+	if key == "" {
+		return w, func() {}, false
+	}
+
+	if idempotencyService == nil {
+		InitializeIdempotencyService()
+	}
+
+	// Read the body for the fingerprint and put it back for the handler
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Failed to read request body", err)
+		return w, nil, true
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	fingerprint := idempotency.Fingerprint(r.Method, r.URL.Path, r.URL.RawQuery, body)
+	claim, replay, err := idempotencyService.BeginRequest(scope, idempotencyOwner(r), key, fingerprint)
+	if err != nil {
+		log.Printf("Error processing idempotency key %s: %v", key, err)
+		WriteErrorResponse(w, idempotencyErrorCode(err), "Idempotency key rejected", err)
+		return w, nil, true
+	}
+
+	if replay != nil {
+		writeReplayedResponse(w, replay)
+		return w, nil, true
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w}
+	finish = func() {
+		statusCode := recorder.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		idempotencyService.FinishRequest(claim, statusCode, recorder.body.Bytes())
+	}
+	return recorder, finish, false
+}
+
+// idempotencyOwner identifies who is using an idempotency key: the
+// signed-in customer, otherwise whatever Authorization the request carries.
+// Keys of different callers never replay each other's responses.
+func idempotencyOwner(r *http.Request) string {
+	if user, ok := users.FromContext(r.Context()); ok {
+		return idempotency.Owner("user:" + user.UserID)
+	}
+	return idempotency.Owner(r.Header.Get("Authorization"))
+}
+
+// idempotencyErrorCode maps an idempotency key error to an HTTP status code
+func idempotencyErrorCode(err error) int {
+	switch {
+	case err == idempotency.ErrFingerprintMismatch:
+		return http.StatusUnprocessableEntity
+	case err == idempotency.ErrInProgress:
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid idempotency key"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeReplayedResponse writes the stored response of an earlier request
+func writeReplayedResponse(w http.ResponseWriter, record *idempotency.Record) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", replayedHeader)
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write([]byte(record.Body))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status code and body
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	if rec.statusCode == 0 {
+		rec.statusCode = statusCode
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if rec.statusCode == 0 {
+		rec.statusCode = http.StatusOK
+	}
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
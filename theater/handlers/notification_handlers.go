@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+)
+
+var notificationService *service.NotificationService
+
+// InitializeNotificationService initializes the notification service
+func InitializeNotificationService() {
+	notificationService = service.NewNotificationService()
+}
+
+// GetBookingNotificationsHandler lists the emails and text messages queued for
+// a booking, with their delivery status
+func GetBookingNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if notificationService == nil {
+		InitializeNotificationService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	queued, err := notificationService.GetBookingNotifications(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking notifications: %v", err)
+		WriteErrorResponse(w, notificationErrorCode(err), "Failed to retrieve notifications", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"notifications": queued,
+		"count":         len(queued),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Notifications retrieved successfully", response)
+}
+
+// ResendConfirmationHandler queues a booking's confirmation to be sent again
+// to its contact
+func ResendConfirmationHandler(w http.ResponseWriter, r *http.Request) {
+	if notificationService == nil {
+		InitializeNotificationService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	notification, err := notificationService.ResendConfirmation(bookingID)
+	if err != nil {
+		log.Printf("Error resending confirmation: %v", err)
+		WriteErrorResponse(w, notificationErrorCode(err), "Failed to resend confirmation", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusAccepted, "Confirmation queued for delivery", notification)
+}
+
+// notificationErrorCode maps a notification error to an HTTP status code
+func notificationErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "too many requests"):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), "invalid booking status"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "notifications unavailable"):
+		return http.StatusServiceUnavailable
+	case strings.Contains(err.Error(), "cannot be empty"),
+		strings.Contains(err.Error(), "invalid booking reference"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/orders"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/users"
+)
+
+var orderService *service.OrderService
+
+// InitializeOrderService initializes the order service
+func InitializeOrderService() {
+	orderService = service.NewOrderService()
+}
+
+// CreateOrderHandler reserves tickets for several shows in a single checkout
+func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if orderService == nil {
+		InitializeOrderService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	order, err := orders.NewOrderFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing order request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid order request", err)
+		return
+	}
+
+	// Bookings made while signed in belong to the customer's account
+	if user, ok := users.FromContext(r.Context()); ok {
+		for _, booking := range order.Bookings {
+			booking.UserID = user.UserID
+		}
+	}
+
+	createdOrder, err := orderService.CreateOrder(order)
+	if err != nil {
+		log.Printf("Error creating order: %v", err)
+		WriteErrorResponse(w, reservationErrorCode(err), "Failed to create order", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Order created successfully", createdOrder)
+}
+
+// GetOrderHandler retrieves an order with its bookings
+func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if orderService == nil {
+		InitializeOrderService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	orderID := r.URL.Query().Get("order_id")
+	if orderID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "order_id parameter is required"})
+		return
+	}
+
+	order, err := orderService.GetOrder(orderID)
+	if err != nil {
+		log.Printf("Error getting order: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve order", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Order retrieved successfully", order)
+}
+
+// ConfirmOrderHandler confirms every booking of a pending order. Orders are
+// not paid through the payment provider, so they are confirmed by an admin.
+func ConfirmOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if orderService == nil {
+		InitializeOrderService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	orderID := r.URL.Query().Get("order_id")
+	if orderID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "order_id parameter is required"})
+		return
+	}
+
+	changedBy, reason := statusChangeAuthor(r)
+	order, err := orderService.ConfirmOrder(orderID, changedBy, reason)
+	if err != nil {
+		log.Printf("Error confirming order: %v", err)
+		WriteErrorResponse(w, statusChangeErrorCode(err), "Failed to confirm order", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Order confirmed successfully", order)
+}
+
+// CancelOrderHandler cancels every booking of an order
+func CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if orderService == nil {
+		InitializeOrderService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	orderID := r.URL.Query().Get("order_id")
+	if orderID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "order_id parameter is required"})
+		return
+	}
+
+	changedBy, reason := statusChangeAuthor(r)
+	order, err := orderService.CancelOrder(orderID, changedBy, reason)
+	if err != nil {
+		log.Printf("Error cancelling order: %v", err)
+		WriteErrorResponse(w, statusChangeErrorCode(err), "Failed to cancel order", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Order cancelled successfully", order)
+}
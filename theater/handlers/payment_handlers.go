@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+)
+
+// maxWebhookBytes caps the size of a payment webhook body
+const maxWebhookBytes = 1 << 20
+
+var paymentService *service.PaymentService
+
+// InitializePaymentService initializes the payment service
+func InitializePaymentService() {
+	paymentService = service.NewPaymentService()
+}
+
+// PaymentWebhookHandler receives the payment provider's webhooks. Only
+// requests with a valid signature are applied; a non-2xx response tells the
+// provider to deliver the webhook again.
+func PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if paymentService == nil {
+		InitializePaymentService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid webhook", err)
+		return
+	}
+
+	payment, err := paymentService.HandleWebhook(payload, r.Header)
+	if err != nil {
+		log.Printf("Error handling payment webhook: %v", err)
+		WriteErrorResponse(w, paymentErrorCode(err), "Failed to process webhook", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook processed", payment)
+}
+
+// GetBookingPaymentsHandler lists the payment attempts of a booking
+func GetBookingPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	if paymentService == nil {
+		InitializePaymentService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	// Either the internal booking ID or the customer-facing reference
+	bookingID := r.URL.Query().Get("booking_id")
+	if bookingID == "" {
+		bookingID = r.URL.Query().Get("reference")
+	}
+	if bookingID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id or reference parameter is required"})
+		return
+	}
+
+	bookingPayments, err := paymentService.GetBookingPayments(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking payments: %v", err)
+		WriteErrorResponse(w, paymentErrorCode(err), "Failed to retrieve payments", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"payments": bookingPayments,
+		"count":    len(bookingPayments),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Payments retrieved successfully", response)
+}
+
+// StartPaymentHandler starts paying for a pending booking that was not paid
+// for when it was made, such as a waitlist offer
+func StartPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if paymentService == nil {
+		InitializePaymentService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	payment, err := paymentService.PayBooking(bookingID)
+	if err != nil {
+		log.Printf("Error starting payment: %v", err)
+		WriteErrorResponse(w, paymentErrorCode(err), "Failed to start payment", err)
+		return
+	}
+	if payment == nil {
+		WriteSuccessResponse(w, http.StatusOK, "Nothing to pay; booking confirmed", nil)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Payment started successfully", payment)
+}
+
+// SimulateMockPaymentHandler completes a mock payment for local development,
+// by sending the signed webhook the mock gateway would send
+func SimulateMockPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if paymentService == nil {
+		InitializePaymentService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	paymentID := r.URL.Query().Get("payment_id")
+	if paymentID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "payment_id parameter is required"})
+		return
+	}
+
+	outcome := r.URL.Query().Get("outcome")
+	if outcome == "" {
+		outcome = "succeeded"
+	}
+
+	payment, err := paymentService.SimulateMockPayment(paymentID, outcome, r.URL.Query().Get("failure_reason"))
+	if err != nil {
+		log.Printf("Error simulating mock payment: %v", err)
+		WriteErrorResponse(w, paymentErrorCode(err), "Failed to complete mock payment", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Mock payment "+outcome, payment)
+}
+
+// paymentErrorCode maps a payment error to an HTTP status code
+func paymentErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"),
+		strings.Contains(err.Error(), "not enabled"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid webhook"),
+		strings.Contains(err.Error(), "invalid payment"),
+		strings.Contains(err.Error(), "invalid outcome"),
+		strings.Contains(err.Error(), "invalid booking reference"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "invalid status transition"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "payment provider unavailable"):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/promotions"
+	"github.com/gsmayya/theater/service"
+)
+
+var promotionService *service.PromotionService
+
+// InitializePromotionService initializes the promotion service
+func InitializePromotionService() {
+	promotionService = service.NewPromotionService()
+}
+
+// ListPromotionsHandler lists all promo codes (admin function)
+func ListPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	promotionList, err := promotionService.GetAllPromotions()
+	if err != nil {
+		log.Printf("Error getting promotions: %v", err)
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve promotions", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"promotions": promotionList,
+		"count":      len(promotionList),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Promotions retrieved successfully", responseData)
+}
+
+// CreatePromotionHandler creates a promo code from a JSON body (admin function)
+func CreatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	promotion, err := promotions.NewPromotionFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing promotion request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid promotion request", err)
+		return
+	}
+
+	createdPromotion, err := promotionService.CreatePromotion(promotion)
+	if err != nil {
+		log.Printf("Error creating promotion: %v", err)
+		WriteErrorResponse(w, promotionErrorStatus(err), "Failed to create promotion", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Promotion created successfully", createdPromotion)
+}
+
+// GetPromotionHandler retrieves a promo code and its redemption count (admin function)
+func GetPromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	code, ok := requireCodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	promotion, err := promotionService.GetPromotion(code)
+	if err != nil {
+		log.Printf("Error getting promotion: %v", err)
+		WriteErrorResponse(w, promotionErrorStatus(err), "Failed to retrieve promotion", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Promotion retrieved successfully", promotion)
+}
+
+// UpdatePromotionHandler replaces a promo code's rules from a JSON body (admin function)
+func UpdatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	code, ok := requireCodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	promotion, err := promotions.NewPromotionFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing promotion request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid promotion request", err)
+		return
+	}
+
+	updatedPromotion, err := promotionService.UpdatePromotion(code, promotion)
+	if err != nil {
+		log.Printf("Error updating promotion: %v", err)
+		WriteErrorResponse(w, promotionErrorStatus(err), "Failed to update promotion", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Promotion updated successfully", updatedPromotion)
+}
+
+// DeletePromotionHandler deletes an unused promo code (admin function)
+func DeletePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "DELETE", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	code, ok := requireCodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := promotionService.DeletePromotion(code); err != nil {
+		log.Printf("Error deleting promotion: %v", err)
+		WriteErrorResponse(w, promotionErrorStatus(err), "Failed to delete promotion", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Promotion deleted successfully", map[string]interface{}{
+		"code": promotions.NormalizeCode(code),
+	})
+}
+
+// PromotionReportHandler reports redemptions of every promo code, or of the
+// one given by the optional code parameter (admin function)
+func PromotionReportHandler(w http.ResponseWriter, r *http.Request) {
+	if promotionService == nil {
+		InitializePromotionService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	report, err := promotionService.GetRedemptionReport(r.URL.Query().Get("code"))
+	if err != nil {
+		log.Printf("Error getting promotion report: %v", err)
+		WriteErrorResponse(w, promotionErrorStatus(err), "Failed to retrieve promotion report", err)
+		return
+	}
+
+	var redemptions, totalDiscount, revenue int32
+	for _, summary := range report {
+		redemptions += summary.Redemptions
+		totalDiscount += summary.TotalDiscount
+		revenue += summary.Revenue
+	}
+
+	responseData := map[string]interface{}{
+		"promotions":     report,
+		"redemptions":    redemptions,
+		"total_discount": totalDiscount,
+		"revenue":        revenue,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Promotion report retrieved successfully", responseData)
+}
+
+// requireCodeParam reads the required code query parameter
+func requireCodeParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "code parameter is required"})
+		return "", false
+	}
+	return code, true
+}
+
+// promotionErrorStatus maps promotion errors to HTTP status codes
+func promotionErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already exists"),
+		strings.Contains(err.Error(), "cannot delete"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid"),
+		strings.Contains(err.Error(), "cannot be empty"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
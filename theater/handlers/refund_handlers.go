@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+)
+
+var refundService *service.RefundService
+
+// InitializeRefundService initializes the refund service
+func InitializeRefundService() {
+	refundService = service.NewRefundService()
+}
+
+// GetCancellationQuoteHandler shows what cancelling a booking now would refund
+func GetCancellationQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	if refundService == nil {
+		InitializeRefundService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := refundService.QuoteCancellation(bookingID)
+	if err != nil {
+		log.Printf("Error quoting cancellation: %v", err)
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to quote cancellation", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Cancellation quote retrieved successfully", quote)
+}
+
+// GetBookingRefundsHandler lists the refund ledger entries of a booking
+func GetBookingRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	if refundService == nil {
+		InitializeRefundService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	refunds, err := refundService.GetBookingRefunds(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking refunds: %v", err)
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to retrieve refunds", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"refunds": refunds,
+		"count":   len(refunds),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Refunds retrieved successfully", response)
+}
+
+// ListRefundsHandler lists refund ledger entries by status, pending by default (admin function)
+func ListRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	if refundService == nil {
+		InitializeRefundService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	refunds, err := refundService.ListRefunds(status)
+	if err != nil {
+		log.Printf("Error listing refunds: %v", err)
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to list refunds", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":  status,
+		"refunds": refunds,
+		"count":   len(refunds),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Refunds retrieved successfully", response)
+}
+
+// ProcessRefundHandler retries a failed refund with the payment processor, or
+// records a refund made by hand (admin function)
+func ProcessRefundHandler(w http.ResponseWriter, r *http.Request) {
+	if refundService == nil {
+		InitializeRefundService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	refundID := r.URL.Query().Get("refund_id")
+	if refundID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "refund_id parameter is required"})
+		return
+	}
+
+	changedBy, _ := statusChangeAuthor(r)
+	refund, err := refundService.ProcessRefund(refundID, r.URL.Query().Get("reference"), changedBy)
+	if err != nil {
+		log.Printf("Error processing refund: %v", err)
+		WriteErrorResponse(w, refundErrorCode(err), "Failed to process refund", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Refund processed successfully", refund)
+}
+
+// parseRefundOverride reads the admin override parameters of a cancellation:
+// override=true to cancel outside the show's policy, and refund_percent to
+// set the share refunded. Both require the admin token.
+func parseRefundOverride(w http.ResponseWriter, r *http.Request) (bool, *int32, bool) {
+	override := r.URL.Query().Get("override") == "true"
+
+	var refundPercent *int32
+	if percentStr := r.URL.Query().Get("refund_percent"); percentStr != "" {
+		percent, err := strconv.ParseInt(percentStr, 10, 32)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid refund_percent parameter", err)
+			return false, nil, false
+		}
+		value := int32(percent)
+		refundPercent = &value
+		override = true
+	}
+
+	if override && !RequireAdmin(w, r) {
+		return false, nil, false
+	}
+	return override, refundPercent, true
+}
+
+// requireBookingIDOrReference reads the booking_id parameter, or the
+// customer-facing reference in its place
+func requireBookingIDOrReference(w http.ResponseWriter, r *http.Request) (string, bool) {
+	bookingID := r.URL.Query().Get("booking_id")
+	if bookingID == "" {
+		bookingID = r.URL.Query().Get("reference")
+	}
+	if bookingID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "booking_id or reference parameter is required"})
+		return "", false
+	}
+	return bookingID, true
+}
+
+// refundErrorCode maps a cancellation or refund error to an HTTP status code
+func refundErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "refund failed"):
+		return http.StatusBadGateway
+	case strings.Contains(err.Error(), "invalid refund"),
+		strings.Contains(err.Error(), "invalid booking reference"):
+		return http.StatusBadRequest
+	default:
+		return statusChangeErrorCode(err)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/service"
+)
+
+// defaultReminderPreviewWindow is how far ahead the preview looks when within is not given
+const defaultReminderPreviewWindow = 24 * time.Hour
+
+var reminderService *service.ReminderService
+
+// InitializeReminderService initializes the reminder service
+func InitializeReminderService() {
+	reminderService = service.NewReminderService()
+}
+
+// ListUpcomingRemindersHandler previews the pre-show reminders falling due in
+// the next day, or the duration given as within, e.g. within=72h (admin function)
+func ListUpcomingRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	if reminderService == nil {
+		InitializeReminderService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	within := defaultReminderPreviewWindow
+	if value := r.URL.Query().Get("within"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid within parameter",
+				&HTTPError{Code: http.StatusBadRequest, Message: "within must be a duration such as 24h"})
+			return
+		}
+		within = parsed
+	}
+
+	reminders, err := reminderService.UpcomingReminders(within)
+	if err != nil {
+		log.Printf("Error previewing reminders: %v", err)
+		WriteErrorResponse(w, reminderErrorCode(err), "Failed to preview reminders", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"reminders":  reminders,
+		"count":      len(reminders),
+		"lead_times": reminderService.LeadTimes(),
+		"within":     within.String(),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Upcoming reminders retrieved successfully", response)
+}
+
+// reminderErrorCode maps a reminder error to an HTTP status code
+func reminderErrorCode(err error) int {
+	if strings.Contains(err.Error(), "invalid preview window") {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
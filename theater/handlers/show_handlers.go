@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gsmayya/theater/idempotency"
 	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/shows"
 )
 
 var showService *service.ShowService
@@ -197,6 +199,13 @@ func CreateShowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Retries carrying the same Idempotency-Key get the original response back
+	w, finish, handled := beginIdempotentRequest(w, r, idempotency.ScopeCreateShow)
+	if handled {
+		return
+	}
+	defer finish()
+
 	// Parse form data or query parameters
 	name := r.URL.Query().Get("name")
 	details := r.URL.Query().Get("details")
@@ -222,8 +231,19 @@ func CreateShowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional per-show hold for pending bookings
+	holdMinutes := int64(0)
+	if holdMinutesStr := r.URL.Query().Get("hold_minutes"); holdMinutesStr != "" {
+		holdMinutes, err = strconv.ParseInt(holdMinutesStr, 10, 32)
+		if err != nil || holdMinutes < 0 {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid hold_minutes value",
+				&HTTPError{Code: http.StatusBadRequest, Message: "hold_minutes must be a non-negative integer"})
+			return
+		}
+	}
+
 	// Create show using service
-	show, err := showService.CreateShow(name, details, location, int32(price), int32(totalTickets))
+	show, err := showService.CreateShow(name, details, location, int32(price), int32(totalTickets), int32(holdMinutes))
 	if err != nil {
 		log.Printf("Error creating show: %v", err)
 		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create show", err)
@@ -267,7 +287,8 @@ func GetShowHandler(w http.ResponseWriter, r *http.Request) {
 	WriteSuccessResponse(w, http.StatusOK, "Show retrieved successfully", show)
 }
 
-// UpdateShowAvailabilityHandler efficiently updates just the availability information
+// UpdateShowAvailabilityHandler recounts a show's booked tickets from its
+// bookings and refreshes its availability. Admin only.
 func UpdateShowAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
 	if showService == nil {
 		InitializeService()
@@ -283,34 +304,387 @@ func UpdateShowAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !RequireAdmin(w, r) {
+		return
+	}
+
 	showID := r.URL.Query().Get("id")
-	bookedTicketsStr := r.URL.Query().Get("booked_tickets")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
 
-	if showID == "" || bookedTicketsStr == "" {
-		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameters", 
-			&HTTPError{Code: http.StatusBadRequest, Message: "Both id and booked_tickets parameters are required"})
+	inventory, err := showService.RecountAvailability(showID)
+	if err != nil {
+		log.Printf("Error recounting show availability: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to update show availability", err)
 		return
 	}
 
-	bookedTickets, err := strconv.ParseInt(bookedTicketsStr, 10, 32)
+	responseData := map[string]interface{}{
+		"show_id":           showID,
+		"total_tickets":     inventory.TotalTickets,
+		"booked_tickets":    inventory.BookedTickets,
+		"available_tickets": inventory.AvailableTickets(),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Show availability updated successfully", responseData)
+}
+
+// UpdateShowHoldHandler lets an admin set how long pending bookings for a show keep their tickets
+func UpdateShowHoldHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	holdMinutesStr := r.URL.Query().Get("hold_minutes")
+
+	if showID == "" || holdMinutesStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameters",
+			&HTTPError{Code: http.StatusBadRequest, Message: "Both id and hold_minutes parameters are required"})
+		return
+	}
+
+	holdMinutes, err := strconv.ParseInt(holdMinutesStr, 10, 32)
 	if err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "Invalid booked_tickets value", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid hold_minutes value", err)
 		return
 	}
 
-	err = showService.UpdateTicketAvailability(showID, int32(bookedTickets))
+	err = showService.UpdateHoldMinutes(showID, int32(holdMinutes))
 	if err != nil {
-		log.Printf("Error updating show availability: %v", err)
-		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update show availability", err)
+		log.Printf("Error updating show hold: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to update show hold", err)
 		return
 	}
 
 	responseData := map[string]interface{}{
-		"show_id":        showID,
-		"booked_tickets": bookedTickets,
+		"show_id":      showID,
+		"hold_minutes": holdMinutes,
 	}
 
-	WriteSuccessResponse(w, http.StatusOK, "Show availability updated successfully", responseData)
+	WriteSuccessResponse(w, http.StatusOK, "Show hold updated successfully", responseData)
+}
+
+// UpdateShowContactVerificationHandler turns contact verification on or off
+// for a show's new bookings (admin function)
+func UpdateShowContactVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	requiredStr := r.URL.Query().Get("required")
+
+	if showID == "" || requiredStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameters",
+			&HTTPError{Code: http.StatusBadRequest, Message: "Both id and required parameters are required"})
+		return
+	}
+
+	required, err := strconv.ParseBool(requiredStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid required value",
+			&HTTPError{Code: http.StatusBadRequest, Message: "required must be true or false"})
+		return
+	}
+
+	err = showService.UpdateContactVerification(showID, required)
+	if err != nil {
+		log.Printf("Error updating contact verification: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to update contact verification", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":                  showID,
+		"require_verified_contact": required,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Contact verification updated successfully", responseData)
+}
+
+// GetShowTicketTypesHandler lists a show's ticket types and their prices
+func GetShowTicketTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	ticketTypes, err := showService.GetTicketTypes(showID)
+	if err != nil {
+		log.Printf("Error getting ticket types: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve ticket types", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":      showID,
+		"ticket_types": ticketTypes,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Ticket types retrieved successfully", responseData)
+}
+
+// UpdateShowTicketTypesHandler replaces a show's ticket types (admin function)
+func UpdateShowTicketTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	ticketTypes, err := shows.TicketTypesFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid ticket types", err)
+		return
+	}
+
+	err = showService.UpdateTicketTypes(showID, ticketTypes)
+	if err != nil {
+		log.Printf("Error updating ticket types: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "cannot remove"),
+			strings.Contains(err.Error(), "cannot set"):
+			statusCode = http.StatusConflict
+		case strings.Contains(err.Error(), "invalid"):
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to update ticket types", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":      showID,
+		"ticket_types": ticketTypes,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Ticket types updated successfully", responseData)
+}
+
+// GetShowCancellationPolicyHandler returns a show's cancellation policy
+func GetShowCancellationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	show, err := showService.GetShow(showID)
+	if err != nil {
+		log.Printf("Error getting cancellation policy: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve cancellation policy", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":             showID,
+		"cancellation_policy": show.CancellationPolicy,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Cancellation policy retrieved successfully", responseData)
+}
+
+// UpdateShowCancellationPolicyHandler sets or removes a show's cancellation policy (admin function)
+func UpdateShowCancellationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	policy, err := shows.CancellationPolicyFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cancellation policy", err)
+		return
+	}
+
+	err = showService.UpdateCancellationPolicy(showID, policy)
+	if err != nil {
+		log.Printf("Error updating cancellation policy: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "invalid"):
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to update cancellation policy", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":             showID,
+		"cancellation_policy": policy,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Cancellation policy updated successfully", responseData)
+}
+
+// GetSeatMapHandler returns seat-level availability for a reserved-seating show
+func GetSeatMapHandler(w http.ResponseWriter, r *http.Request) {
+	if showService == nil {
+		InitializeService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	showID := r.URL.Query().Get("id")
+	if showID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	seatMap, err := showService.GetSeatMap(showID)
+	if err != nil {
+		log.Printf("Error getting seat map: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve seat map", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Seat map retrieved successfully", seatMap)
 }
 
 // GetSearchStatsHandler returns statistics about search indexes
@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/tickets"
+)
+
+var ticketService *service.TicketService
+
+// InitializeTicketService initializes the ticket service
+func InitializeTicketService() {
+	ticketService = service.NewTicketService()
+}
+
+// GetBookingTicketsHandler lists the individual tickets of a booking, for an
+// admin or the booking's owner
+func GetBookingTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	if !RequireBookingOwner(w, r, bookingID) {
+		return
+	}
+
+	bookingTickets, err := ticketService.GetBookingTickets(bookingID)
+	if err != nil {
+		log.Printf("Error getting booking tickets: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to retrieve tickets", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"tickets": bookingTickets,
+		"count":   len(bookingTickets),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Tickets retrieved successfully", response)
+}
+
+// GetTicketHandler retrieves a single ticket, for an admin or the owner of its booking
+func GetTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	ticketID, ok := requireTicketID(w, r)
+	if !ok {
+		return
+	}
+
+	ticket, ok := requireTicketOwner(w, r, ticketID)
+	if !ok {
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Ticket retrieved successfully", ticket)
+}
+
+// SetAttendeeHandler names or renames the attendee of a ticket, for an admin
+// or the owner of its booking. An empty attendee_name clears it.
+func SetAttendeeHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	ticketID, ok := requireTicketID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, ok := requireTicketOwner(w, r, ticketID); !ok {
+		return
+	}
+
+	ticket, err := ticketService.SetAttendeeName(ticketID, r.URL.Query().Get("attendee_name"))
+	if err != nil {
+		log.Printf("Error setting attendee name: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to update attendee", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Attendee updated successfully", ticket)
+}
+
+// VoidTicketHandler voids a single ticket of a booking and returns its place to
+// the show. Only an admin or the booking's owner can void it.
+func VoidTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	ticketID, ok := requireTicketID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, ok := requireTicketOwner(w, r, ticketID); !ok {
+		return
+	}
+
+	ticket, err := ticketService.VoidTicket(ticketID, r.URL.Query().Get("reason"))
+	if err != nil {
+		log.Printf("Error voiding ticket: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to void ticket", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Ticket voided successfully", ticket)
+}
+
+// GetTicketTokensHandler issues signed QR tokens for the tickets of a
+// confirmed booking, or for one of its tickets when ticket_id is given. Only an
+// admin or the booking's owner can get them.
+func GetTicketTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	bookingID, ok := requireBookingIDOrReference(w, r)
+	if !ok {
+		return
+	}
+
+	if !RequireBookingOwner(w, r, bookingID) {
+		return
+	}
+
+	tokens, err := ticketService.IssueTokens(bookingID, r.URL.Query().Get("ticket_id"))
+	if err != nil {
+		log.Printf("Error issuing ticket tokens: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to issue ticket tokens", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"tokens": tokens,
+		"count":  len(tokens),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Ticket tokens issued successfully", response)
+}
+
+// VerifyTicketHandler checks a scanned ticket token and answers admit or
+// deny with a reason. Denied tickets are still a 200 response; only a
+// malformed request or a failed lookup is an error.
+func VerifyTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	// Handle both JSON and form-encoded requests
+	req := tickets.VerifyRequest{
+		Token:  r.URL.Query().Get("token"),
+		ShowID: r.URL.Query().Get("show_id"),
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid verify request", err)
+			return
+		}
+	}
+	if req.Token == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "token is required"})
+		return
+	}
+
+	verification, err := ticketService.VerifyToken(req.Token, req.ShowID)
+	if err != nil {
+		log.Printf("Error verifying ticket token: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid show_id") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to verify ticket", err)
+		return
+	}
+
+	message := "Ticket admitted"
+	if !verification.Admit {
+		message = "Ticket denied: " + verification.Reason
+	}
+	WriteSuccessResponse(w, http.StatusOK, message, verification)
+}
+
+// CheckInScanHandler checks in a ticket scanned at a gate, or every ticket of
+// a booking. A ticket that was already checked in gets a 409 with the scan
+// that let it in; a denied ticket is still a 200 with the reason.
+func CheckInScanHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	// Handle both JSON and form-encoded requests
+	query := r.URL.Query()
+	req := tickets.ScanRequest{
+		ScanID:    query.Get("scan_id"),
+		Token:     query.Get("token"),
+		TicketID:  query.Get("ticket_id"),
+		BookingID: query.Get("booking_id"),
+		GateID:    query.Get("gate_id"),
+		ShowID:    query.Get("show_id"),
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid scan request", err)
+			return
+		}
+	}
+
+	results, err := ticketService.Scan(&req)
+	if err != nil {
+		log.Printf("Error checking in scan: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to check in", err)
+		return
+	}
+
+	counts := countScanResults(results)
+	response := map[string]interface{}{
+		"results": results,
+		"counts":  counts,
+	}
+
+	// Only a scan that let nobody new in and found someone already inside is a conflict
+	if counts[tickets.ScanDuplicate] > 0 && counts[tickets.ScanCheckedIn] == 0 {
+		writeJSONResponse(w, http.StatusConflict, APIResponse{
+			Success:   false,
+			Message:   "Already checked in",
+			Data:      response,
+			Error:     "ticket already checked in",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	message := "Checked in"
+	if counts[tickets.ScanCheckedIn] == 0 {
+		message = "Check-in denied: " + results[0].Scan.Reason
+	}
+	WriteSuccessResponse(w, http.StatusOK, message, response)
+}
+
+// SyncScansHandler uploads the scans an offline scanner buffered. Each scan
+// gets its own result; conflicting scans of one ticket are settled in favour
+// of the earliest.
+func SyncScansHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	var batch tickets.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid batch request", err)
+		return
+	}
+
+	results, err := ticketService.SyncBatch(&batch)
+	if err != nil {
+		log.Printf("Error syncing scans: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to sync scans", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"results": results,
+		"counts":  countScanResults(results),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Scans synced successfully", response)
+}
+
+// GetShowAttendanceHandler reports how many ticket holders have arrived for a show
+func GetShowAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	if ticketService == nil {
+		InitializeTicketService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	showID, ok := parseShowIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	attendance, err := ticketService.GetAttendance(showID)
+	if err != nil {
+		log.Printf("Error getting attendance: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to retrieve attendance", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Attendance retrieved successfully", attendance)
+}
+
+// countScanResults tallies scan results by outcome
+func countScanResults(results []*tickets.ScanResult) map[string]int {
+	counts := map[string]int{
+		tickets.ScanCheckedIn: 0,
+		tickets.ScanDuplicate: 0,
+		tickets.ScanDenied:    0,
+	}
+	for _, result := range results {
+		counts[result.Scan.Result]++
+	}
+	return counts
+}
+
+// requireTicketID reads the ticket_id parameter, writing an error response when it is missing
+func requireTicketID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ticketID := r.URL.Query().Get("ticket_id")
+	if ticketID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "ticket_id parameter is required"})
+		return "", false
+	}
+	return ticketID, true
+}
+
+// requireTicketOwner looks up a ticket and checks the request was made by an
+// admin or the owner of its booking, writing an error response otherwise
+func requireTicketOwner(w http.ResponseWriter, r *http.Request, ticketID string) (*tickets.Ticket, bool) {
+	ticket, err := ticketService.GetTicket(ticketID)
+	if err != nil {
+		log.Printf("Error getting ticket: %v", err)
+		WriteErrorResponse(w, ticketErrorCode(err), "Failed to retrieve ticket", err)
+		return nil, false
+	}
+
+	if !RequireBookingOwner(w, r, ticket.BookingID) {
+		return nil, false
+	}
+	return ticket, true
+}
+
+// ticketErrorCode maps a ticket error to an HTTP status code
+func ticketErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid ticket status"),
+		strings.Contains(err.Error(), "hold has expired"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid attendee name"),
+		strings.Contains(err.Error(), "invalid booking reference"),
+		strings.Contains(err.Error(), "invalid scan"),
+		strings.Contains(err.Error(), "invalid show_id"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
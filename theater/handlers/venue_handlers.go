@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/venues"
+)
+
+var venueService *service.VenueService
+
+// InitializeVenueService initializes the venue service
+func InitializeVenueService() {
+	venueService = service.NewVenueService()
+}
+
+// CreateVenueHandler creates a venue from a JSON seat layout
+func CreateVenueHandler(w http.ResponseWriter, r *http.Request) {
+	if venueService == nil {
+		InitializeVenueService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	venue, err := venues.NewVenueFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing venue request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid venue layout", err)
+		return
+	}
+
+	createdVenue, err := venueService.CreateVenue(venue)
+	if err != nil {
+		log.Printf("Error creating venue: %v", err)
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create venue", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"venue_id":   createdVenue.VenueID.String(),
+		"seat_count": createdVenue.SeatCount,
+		"venue":      createdVenue,
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Venue created successfully", responseData)
+}
+
+// GetVenueHandler retrieves a venue with its seat layout
+func GetVenueHandler(w http.ResponseWriter, r *http.Request) {
+	if venueService == nil {
+		InitializeVenueService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	venueID := r.URL.Query().Get("id")
+	if venueID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "id parameter is required"})
+		return
+	}
+
+	venue, err := venueService.GetVenue(venueID)
+	if err != nil {
+		log.Printf("Error getting venue: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve venue", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Venue retrieved successfully", venue)
+}
+
+// ListVenuesHandler lists all venues with their seat counts
+func ListVenuesHandler(w http.ResponseWriter, r *http.Request) {
+	if venueService == nil {
+		InitializeVenueService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	venueList, err := venueService.GetAllVenues()
+	if err != nil {
+		log.Printf("Error listing venues: %v", err)
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve venues", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"venues": venueList,
+		"count":  len(venueList),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Venues retrieved successfully", responseData)
+}
+
+// AssignVenueHandler gives a show a venue's seat layout
+func AssignVenueHandler(w http.ResponseWriter, r *http.Request) {
+	if venueService == nil {
+		InitializeVenueService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST", "PUT") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID := r.URL.Query().Get("show_id")
+	venueID := r.URL.Query().Get("venue_id")
+
+	if showID == "" || venueID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameters",
+			&HTTPError{Code: http.StatusBadRequest, Message: "Both show_id and venue_id parameters are required"})
+		return
+	}
+
+	if err := venueService.AssignVenue(showID, venueID); err != nil {
+		log.Printf("Error assigning venue: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "already has"):
+			statusCode = http.StatusConflict
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "has no seats"):
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to assign venue", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id":  showID,
+		"venue_id": venueID,
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Venue assigned successfully", responseData)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/verification"
+)
+
+var verificationService *service.VerificationService
+
+// InitializeVerificationService initializes the verification service
+func InitializeVerificationService() {
+	verificationService = service.NewVerificationService()
+}
+
+// RequestVerificationCodeHandler sends a one-time code to a customer's email
+// address or mobile number
+func RequestVerificationCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if verificationService == nil {
+		InitializeVerificationService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	req, err := verification.RequestFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid verification request", err)
+		return
+	}
+
+	challenge, err := verificationService.RequestCode(req.ContactType, req.ContactValue)
+	if err != nil {
+		log.Printf("Error sending verification code: %v", err)
+		WriteErrorResponse(w, verificationErrorCode(err), "Failed to send verification code", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusAccepted, "Verification code sent", challenge)
+}
+
+// VerifyContactHandler checks a one-time code and returns the verification
+// token to book with
+func VerifyContactHandler(w http.ResponseWriter, r *http.Request) {
+	if verificationService == nil {
+		InitializeVerificationService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	req, err := verification.RequestFromJSON(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid verification request", err)
+		return
+	}
+
+	verified, err := verificationService.VerifyCode(req.ContactType, req.ContactValue, req.Code)
+	if err != nil {
+		log.Printf("Error verifying contact: %v", err)
+		WriteErrorResponse(w, verificationErrorCode(err), "Failed to verify contact", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Contact verified successfully", verified)
+}
+
+// verificationErrorCode maps a verification error to an HTTP status code
+func verificationErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "too many"):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), "contact not verified"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "verification unavailable"):
+		return http.StatusServiceUnavailable
+	case strings.Contains(err.Error(), "failed to send verification code"):
+		return http.StatusBadGateway
+	case strings.Contains(err.Error(), "invalid contact"),
+		strings.Contains(err.Error(), "invalid verification code"),
+		strings.Contains(err.Error(), "cannot be empty"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/waitlist"
+)
+
+var waitlistService *service.WaitlistService
+
+// InitializeWaitlistService initializes the waitlist service
+func InitializeWaitlistService() {
+	waitlistService = service.NewWaitlistService()
+}
+
+// JoinWaitlistHandler puts a customer on the waitlist of a sold-out show
+func JoinWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	if waitlistService == nil {
+		InitializeWaitlistService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	var entry *waitlist.Entry
+	var err error
+
+	// Handle both JSON and form-encoded requests
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		entry, err = waitlist.NewEntryFromJSON(r)
+	} else {
+		entry, err = waitlist.NewEntryFromRequest(r)
+	}
+
+	if err != nil {
+		log.Printf("Error parsing waitlist request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid waitlist request", err)
+		return
+	}
+
+	joined, err := waitlistService.JoinWaitlist(entry)
+	if err != nil {
+		log.Printf("Error joining waitlist: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "tickets available"),
+			strings.Contains(err.Error(), "already on the waitlist"):
+			statusCode = http.StatusConflict
+		case strings.Contains(err.Error(), "invalid ticket selection"):
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to join waitlist", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Joined waitlist successfully", joined)
+}
+
+// GetWaitlistEntryHandler reports a waitlist entry's position or offer
+func GetWaitlistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if waitlistService == nil {
+		InitializeWaitlistService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	entryID := r.URL.Query().Get("entry_id")
+	if entryID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "entry_id parameter is required"})
+		return
+	}
+
+	entry, err := waitlistService.GetEntry(entryID)
+	if err != nil {
+		log.Printf("Error getting waitlist entry: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve waitlist entry", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Waitlist entry retrieved successfully", entry)
+}
+
+// ListWaitlistHandler lists a show's waitlist in queue order (admin function)
+func ListWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	if waitlistService == nil {
+		InitializeWaitlistService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID, ok := parseShowIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := waitlistService.GetWaitlist(showID, r.URL.Query().Get("status"))
+	if err != nil {
+		log.Printf("Error getting waitlist: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid status") {
+			statusCode = http.StatusBadRequest
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to retrieve waitlist", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id": showID.String(),
+		"entries": entries,
+		"count":   len(entries),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Waitlist retrieved successfully", responseData)
+}
+
+// RemoveWaitlistEntryHandler takes a waiting customer off the waitlist (admin function)
+func RemoveWaitlistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if waitlistService == nil {
+		InitializeWaitlistService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST", "DELETE") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	entryID := r.URL.Query().Get("entry_id")
+	if entryID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "entry_id parameter is required"})
+		return
+	}
+
+	if err := waitlistService.RemoveEntry(entryID); err != nil {
+		log.Printf("Error removing waitlist entry: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "cannot remove"):
+			statusCode = http.StatusConflict
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to remove waitlist entry", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Waitlist entry removed successfully", map[string]interface{}{
+		"entry_id": entryID,
+		"status":   waitlist.StatusRemoved,
+	})
+}
+
+// PromoteWaitlistHandler offers a show's available tickets to its waitlist now,
+// e.g. after its capacity was raised (admin function)
+func PromoteWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	if waitlistService == nil {
+		InitializeWaitlistService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	showID, ok := parseShowIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	offers, err := waitlistService.PromoteWaitlist(showID)
+	if err != nil {
+		log.Printf("Error promoting waitlist: %v", err)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		WriteErrorResponse(w, statusCode, "Failed to promote waitlist", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"show_id": showID.String(),
+		"offers":  offers,
+		"count":   len(offers),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Waitlist promoted successfully", responseData)
+}
+
+// parseShowIDParam reads the required show_id query parameter
+func parseShowIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	showIDStr := r.URL.Query().Get("show_id")
+	if showIDStr == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "show_id parameter is required"})
+		return uuid.Nil, false
+	}
+
+	showID, err := uuid.Parse(showIDStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid show ID format", err)
+		return uuid.Nil, false
+	}
+
+	return showID, true
+}
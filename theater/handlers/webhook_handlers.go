@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/webhooks"
+)
+
+var webhookService *service.WebhookService
+
+// InitializeWebhookService initializes the webhook service
+func InitializeWebhookService() {
+	webhookService = service.NewWebhookService()
+}
+
+// ListWebhooksHandler lists webhook subscriptions, without their secrets (admin function)
+func ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	subscriptions, err := webhookService.GetAllSubscriptions()
+	if err != nil {
+		log.Printf("Error getting webhook subscriptions: %v", err)
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve webhook subscriptions", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook subscriptions retrieved successfully", responseData)
+}
+
+// CreateWebhookHandler creates a webhook subscription from a JSON body. The
+// response is the only one that includes the signing secret (admin function).
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	subscription, err := webhooks.NewSubscriptionFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing webhook subscription request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid webhook subscription request", err)
+		return
+	}
+
+	createdSubscription, err := webhookService.CreateSubscription(subscription)
+	if err != nil {
+		log.Printf("Error creating webhook subscription: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to create webhook subscription", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, "Webhook subscription created successfully", createdSubscription)
+}
+
+// GetWebhookHandler retrieves a webhook subscription (admin function)
+func GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	subscriptionID, ok := requireSubscriptionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, err := webhookService.GetSubscription(subscriptionID)
+	if err != nil {
+		log.Printf("Error getting webhook subscription: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to retrieve webhook subscription", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook subscription retrieved successfully", subscription)
+}
+
+// UpdateWebhookHandler replaces a webhook subscription from a JSON body;
+// setting "active": true re-enables a disabled subscription (admin function)
+func UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "PUT", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	subscriptionID, ok := requireSubscriptionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	update, err := webhooks.NewSubscriptionFromJSON(r)
+	if err != nil {
+		log.Printf("Error parsing webhook subscription request: %v", err)
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid webhook subscription request", err)
+		return
+	}
+
+	updatedSubscription, err := webhookService.UpdateSubscription(subscriptionID, update)
+	if err != nil {
+		log.Printf("Error updating webhook subscription: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to update webhook subscription", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook subscription updated successfully", updatedSubscription)
+}
+
+// DeleteWebhookHandler deletes a webhook subscription and its delivery log (admin function)
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "DELETE", "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	subscriptionID, ok := requireSubscriptionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := webhookService.DeleteSubscription(subscriptionID); err != nil {
+		log.Printf("Error deleting webhook subscription: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to delete webhook subscription", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook subscription deleted successfully", map[string]interface{}{
+		"subscription_id": subscriptionID,
+	})
+}
+
+// ListWebhookDeliveriesHandler returns a subscription's delivery log, or one
+// delivery with every attempt made for it when delivery_id is given (admin function)
+func ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "GET") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	if deliveryID := r.URL.Query().Get("delivery_id"); deliveryID != "" {
+		delivery, err := webhookService.GetDelivery(deliveryID)
+		if err != nil {
+			log.Printf("Error getting webhook delivery: %v", err)
+			WriteErrorResponse(w, webhookErrorCode(err), "Failed to retrieve webhook delivery", err)
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, "Webhook delivery retrieved successfully", delivery)
+		return
+	}
+
+	subscriptionID, ok := requireSubscriptionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	deliveries, err := webhookService.GetDeliveries(subscriptionID, status)
+	if err != nil {
+		log.Printf("Error getting webhook deliveries: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to retrieve webhook deliveries", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"subscription_id": subscriptionID,
+		"deliveries":      deliveries,
+		"count":           len(deliveries),
+	}
+	if status != "" {
+		responseData["status"] = status
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook deliveries retrieved successfully", responseData)
+}
+
+// RedeliverWebhookHandler queues a succeeded or failed delivery again (admin function)
+func RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookService == nil {
+		InitializeWebhookService()
+	}
+
+	// Handle CORS preflight requests
+	if HandleCORS(w, r) {
+		return
+	}
+
+	// Validate HTTP method
+	if !ValidateMethod(w, r, "POST") {
+		return
+	}
+
+	if !RequireAdmin(w, r) {
+		return
+	}
+
+	deliveryID := r.URL.Query().Get("delivery_id")
+	if deliveryID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "delivery_id parameter is required"})
+		return
+	}
+
+	delivery, err := webhookService.Redeliver(deliveryID)
+	if err != nil {
+		log.Printf("Error redelivering webhook: %v", err)
+		WriteErrorResponse(w, webhookErrorCode(err), "Failed to redeliver webhook", err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "Webhook delivery requeued successfully", delivery)
+}
+
+// requireSubscriptionIDParam reads the required subscription_id query parameter
+func requireSubscriptionIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	subscriptionID := r.URL.Query().Get("subscription_id")
+	if subscriptionID == "" {
+		WriteErrorResponse(w, http.StatusBadRequest, "Missing required parameter",
+			&HTTPError{Code: http.StatusBadRequest, Message: "subscription_id parameter is required"})
+		return "", false
+	}
+	return subscriptionID, true
+}
+
+// webhookErrorCode maps a webhook subscription or delivery error to an HTTP status code
+func webhookErrorCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid delivery status"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid"),
+		strings.Contains(err.Error(), "cannot be empty"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
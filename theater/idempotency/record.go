@@ -0,0 +1,144 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeaderName is the request header clients use to make a POST safe to retry
+const HeaderName = "Idempotency-Key"
+
+// Scopes keep keys for different endpoints apart, so a client may reuse a
+// key across endpoints without collisions
+const (
+	ScopeCreateBooking = "bookings.create"
+	ScopeCreateShow    = "shows.create"
+)
+
+// maxKeyLength matches the idempotency key column width
+const maxKeyLength = 255
+
+var (
+	// ErrFingerprintMismatch is returned when a key is replayed with a different request
+	ErrFingerprintMismatch = fmt.Errorf("idempotency key reused with a different request payload")
+	// ErrInProgress is returned when a replay arrives before the original request has finished
+	ErrInProgress = fmt.Errorf("a request with this idempotency key is still in progress")
+)
+
+// Record is a claimed idempotency key and, once the request has finished,
+// the response to replay
+type Record struct {
+	Scope       string    `json:"scope"`
+	Owner       string    `json:"owner"` // Hash of the caller's identity, empty for anonymous callers
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"` // Hash of the request the key was first used with
+	StatusCode  int       `json:"status_code"` // 0 while the original request is in progress
+	Body        string    `json:"body"`        // Response body to replay
+	ClaimID     string    `json:"-"`           // Identifies the request holding the key
+	LockedUntil time.Time `json:"-"`           // When a retry may take over an unfinished claim
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"` // After this the key may be reused
+}
+
+// NewRecord claims a key for a request fingerprint for the given window. The
+// claim is leased: if the request has not finished when the lease runs out,
+// a retry may take the key over.
+func NewRecord(scope, owner, key, fingerprint string, window, lease time.Duration) *Record {
+	now := time.Now()
+	return &Record{
+		Scope:       scope,
+		Owner:       owner,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ClaimID:     uuid.New().String(),
+		LockedUntil: now.Add(lease),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(window),
+	}
+}
+
+// Owner hashes the identity of the caller using a key, so keys of different
+// callers never collide and credentials are not stored. Anonymous callers
+// share the empty owner.
+func Owner(identity string) string {
+	if identity == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(hash[:])
+}
+
+// ValidateKey checks a client-supplied idempotency key
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("invalid idempotency key: key cannot be empty")
+	}
+	if len(key) > maxKeyLength {
+		return fmt.Errorf("invalid idempotency key: key is longer than %d characters", maxKeyLength)
+	}
+	for _, c := range key {
+		if c < 0x21 || c > 0x7e {
+			return fmt.Errorf("invalid idempotency key: key may only contain printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+// Fingerprint hashes the parts of a request that determine its outcome: the
+// method, path, query string and body
+func Fingerprint(method, path, rawQuery string, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{method, path, rawQuery} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// IsCompleted reports whether the original request has finished and its response was stored
+func (r *Record) IsCompleted() bool {
+	return r.StatusCode != 0
+}
+
+// IsStale reports whether the request holding the key has not finished
+// within its lease at time now, so a retry may take the key over
+func (r *Record) IsStale(now time.Time) bool {
+	return !r.IsCompleted() && !now.Before(r.LockedUntil)
+}
+
+// IsExpired reports whether the key's window has passed at time now
+func (r *Record) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// CheckReplay returns the error for replaying this key with a request of the
+// given fingerprint, or nil when the stored response can be replayed
+func (r *Record) CheckReplay(fingerprint string) error {
+	if r.Fingerprint != fingerprint {
+		return ErrFingerprintMismatch
+	}
+	if !r.IsCompleted() {
+		return ErrInProgress
+	}
+	return nil
+}
+
+// ToJSON converts the record to a JSON string
+func (r *Record) ToJSON() (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FromJSON populates the record from a JSON string
+func (r *Record) FromJSON(data string) error {
+	return json.Unmarshal([]byte(data), r)
+}
@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"3f2c6b1e-8d6a-4c1e-9f0a-2b7d5e4c3a10", "checkout-42"}
+	for _, key := range valid {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) error: %v", key, err)
+		}
+	}
+
+	invalid := []string{"", "has space", "tab\tkey", strings.Repeat("k", maxKeyLength+1)}
+	for _, key := range invalid {
+		if err := ValidateKey(key); err == nil {
+			t.Errorf("ValidateKey(%q) should be rejected", key)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	body := []byte(`{"show_id":"abc","number_of_tickets":2}`)
+	fingerprint := Fingerprint("POST", "/api/v1/bookings/create", "", body)
+
+	if len(fingerprint) != 64 {
+		t.Errorf("Expected a 64 character hex fingerprint, got %d characters", len(fingerprint))
+	}
+	if Fingerprint("POST", "/api/v1/bookings/create", "", body) != fingerprint {
+		t.Error("Fingerprint should be stable for the same request")
+	}
+
+	different := []string{
+		Fingerprint("POST", "/api/v1/bookings/create", "", []byte(`{"show_id":"abc","number_of_tickets":3}`)),
+		Fingerprint("POST", "/api/v1/bookings/create", "number_of_tickets=2", body),
+		Fingerprint("POST", "/api/v1/shows/create", "", body),
+		// Parts are separated, so moving text between them changes the hash
+		Fingerprint("POST", "/api/v1/bookings/create?", "", body),
+	}
+	for i, other := range different {
+		if other == fingerprint {
+			t.Errorf("Fingerprint %d should differ from the original request", i)
+		}
+	}
+}
+
+func TestRecordCheckReplay(t *testing.T) {
+	record := NewRecord(ScopeCreateBooking, "", "checkout-42", "abc", time.Hour, time.Minute)
+
+	if err := record.CheckReplay("abc"); err != ErrInProgress {
+		t.Errorf("Expected ErrInProgress before the response is stored, got %v", err)
+	}
+
+	record.StatusCode = 201
+	record.Body = `{"success":true}`
+	if err := record.CheckReplay("abc"); err != nil {
+		t.Errorf("CheckReplay() error: %v", err)
+	}
+	if err := record.CheckReplay("def"); err != ErrFingerprintMismatch {
+		t.Errorf("Expected ErrFingerprintMismatch, got %v", err)
+	}
+
+	if record.IsExpired(time.Now()) || !record.IsExpired(record.ExpiresAt) {
+		t.Error("Record should expire exactly at ExpiresAt")
+	}
+
+	data, err := record.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error: %v", err)
+	}
+	restored := &Record{}
+	if err := restored.FromJSON(data); err != nil {
+		t.Fatalf("FromJSON() error: %v", err)
+	}
+	if restored.Key != record.Key || restored.StatusCode != 201 || restored.Body != record.Body {
+		t.Errorf("Round trip lost fields: %+v", restored)
+	}
+}
+
+func TestRecordIsStale(t *testing.T) {
+	record := NewRecord(ScopeCreateBooking, Owner("user:user-1"), "checkout-42", "abc", time.Hour, time.Minute)
+
+	if record.IsStale(time.Now()) {
+		t.Error("Expected a fresh claim to hold its key")
+	}
+	if !record.IsStale(record.LockedUntil) {
+		t.Error("Expected the claim to go stale when its lease runs out")
+	}
+
+	record.StatusCode = 201
+	if record.IsStale(record.LockedUntil) {
+		t.Error("Expected a completed record never to go stale")
+	}
+}
+
+func TestOwner(t *testing.T) {
+	if Owner("") != "" {
+		t.Error("Expected anonymous callers to share the empty owner")
+	}
+
+	owner := Owner("Bearer secret-key")
+	if len(owner) != 64 || strings.Contains(owner, "secret-key") {
+		t.Errorf("Expected a hash of the identity, got %q", owner)
+	}
+	if Owner("user:user-1") == Owner("user:user-2") || Owner("Bearer secret-key") != owner {
+		t.Error("Expected each identity to have its own stable owner")
+	}
+}
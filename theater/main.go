@@ -9,8 +9,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gsmayya/theater/bookings"
 	"github.com/gsmayya/theater/db"
 	"github.com/gsmayya/theater/handlers"
+	"github.com/gsmayya/theater/service"
+	"github.com/gsmayya/theater/utils"
 )
 
 const (
@@ -37,18 +40,46 @@ func main() {
 	}
 	log.Println("✅ Database connection established successfully")
 
+	// Mobile numbers written without a country code are read in this region
+	if err := bookings.SetDefaultPhoneRegion(utils.GetEnvOrDefault("CONTACT_DEFAULT_REGION", bookings.DefaultPhoneRegion())); err != nil {
+		log.Printf("Warning: %v for CONTACT_DEFAULT_REGION, using %s", err, bookings.DefaultPhoneRegion())
+	}
+
 	// Initialize services
 	handlers.InitializeService()
 	handlers.InitializeBookingService()
+	handlers.InitializeReconciliationService()
+	handlers.InitializeVenueService()
+	handlers.InitializeWaitlistService()
+	handlers.InitializePromotionService()
+	handlers.InitializeOrderService()
+	handlers.InitializeTicketService()
+	handlers.InitializePaymentService()
+	handlers.InitializeRefundService()
+	handlers.InitializeAmendmentService()
+	handlers.InitializeExchangeService()
+	handlers.InitializeIdempotencyService()
+	handlers.InitializeEventService()
+	handlers.InitializeWebhookService()
+	handlers.InitializeNotificationService()
+	handlers.InitializeReminderService()
+	handlers.InitializeVerificationService()
+	handlers.InitializeUserService()
+	handlers.CheckAdminConfig()
 	log.Println("✅ Services initialized successfully")
 
+	// Start background jobs; they stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	startBackgroundJobs(jobsCtx)
+
 	// Setup routes
 	router := setupRoutes()
 
 	// Create HTTP server with timeouts
 	server := &http.Server{
 		Addr:         "0.0.0.0:" + getPort(),
-		Handler:      router,
+		Handler:      handlers.Authenticate(router),
 		ReadTimeout:  ReadTimeout,
 		WriteTimeout: WriteTimeout,
 		IdleTimeout:  IdleTimeout,
@@ -68,6 +99,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("🛑 Shutting down server...")
+	stopJobs()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -99,7 +131,22 @@ func setupRoutes() *http.ServeMux {
 	mux.HandleFunc(apiV1+"/shows/create", handlers.CreateShowHandler)
 	mux.HandleFunc(apiV1+"/shows/get", handlers.GetShowHandler)
 	mux.HandleFunc(apiV1+"/shows/update-availability", handlers.UpdateShowAvailabilityHandler)
+	mux.HandleFunc(apiV1+"/shows/update-hold", handlers.UpdateShowHoldHandler)
+	mux.HandleFunc(apiV1+"/shows/update-contact-verification", handlers.UpdateShowContactVerificationHandler)
 	mux.HandleFunc(apiV1+"/shows/booking-summary", handlers.GetShowBookingSummaryHandler)
+	mux.HandleFunc(apiV1+"/shows/seatmap", handlers.GetSeatMapHandler)
+	mux.HandleFunc(apiV1+"/shows/ticket-types", handlers.GetShowTicketTypesHandler)
+	mux.HandleFunc(apiV1+"/shows/update-ticket-types", handlers.UpdateShowTicketTypesHandler)
+	mux.HandleFunc(apiV1+"/shows/cancellation-policy", handlers.GetShowCancellationPolicyHandler)
+	mux.HandleFunc(apiV1+"/shows/update-cancellation-policy", handlers.UpdateShowCancellationPolicyHandler)
+	mux.HandleFunc(apiV1+"/shows/assign-venue", handlers.AssignVenueHandler)
+	mux.HandleFunc(apiV1+"/shows/attendance", handlers.GetShowAttendanceHandler)
+	mux.HandleFunc(apiV1+"/shows/exchanges", handlers.GetShowExchangesHandler)
+
+	// Venue management endpoints
+	mux.HandleFunc(apiV1+"/venues", handlers.ListVenuesHandler)
+	mux.HandleFunc(apiV1+"/venues/create", handlers.CreateVenueHandler)
+	mux.HandleFunc(apiV1+"/venues/get", handlers.GetVenueHandler)
 
 	// Booking management endpoints
 	mux.HandleFunc(apiV1+"/bookings/create", handlers.CreateBookingHandler)
@@ -107,18 +154,136 @@ func setupRoutes() *http.ServeMux {
 	mux.HandleFunc(apiV1+"/bookings/update-status", handlers.UpdateBookingStatusHandler)
 	mux.HandleFunc(apiV1+"/bookings/confirm", handlers.ConfirmBookingHandler)
 	mux.HandleFunc(apiV1+"/bookings/cancel", handlers.CancelBookingHandler)
+	mux.HandleFunc(apiV1+"/bookings/amend", handlers.AmendBookingHandler)
+	mux.HandleFunc(apiV1+"/bookings/amendments", handlers.GetBookingAmendmentsHandler)
+	mux.HandleFunc(apiV1+"/bookings/exchange", handlers.ExchangeBookingHandler)
+	mux.HandleFunc(apiV1+"/bookings/exchanges", handlers.GetBookingExchangesHandler)
 	mux.HandleFunc(apiV1+"/bookings/by-show", handlers.GetBookingsByShowHandler)
 	mux.HandleFunc(apiV1+"/bookings/by-contact", handlers.GetBookingsByContactHandler)
 	mux.HandleFunc(apiV1+"/bookings/search", handlers.SearchBookingsHandler)
 	mux.HandleFunc(apiV1+"/bookings/stats", handlers.GetBookingStatsHandler)
+	mux.HandleFunc(apiV1+"/bookings/history", handlers.GetBookingHistoryHandler)
+	mux.HandleFunc(apiV1+"/bookings/tickets", handlers.GetBookingTicketsHandler)
+	mux.HandleFunc(apiV1+"/bookings/ticket-token", handlers.GetTicketTokensHandler)
+	mux.HandleFunc(apiV1+"/bookings/payments", handlers.GetBookingPaymentsHandler)
+	mux.HandleFunc(apiV1+"/bookings/cancellation-quote", handlers.GetCancellationQuoteHandler)
+	mux.HandleFunc(apiV1+"/bookings/refunds", handlers.GetBookingRefundsHandler)
+	mux.HandleFunc(apiV1+"/bookings/notifications", handlers.GetBookingNotificationsHandler)
+	mux.HandleFunc(apiV1+"/bookings/notifications/resend", handlers.ResendConfirmationHandler)
+
+	// Contact verification endpoints
+	mux.HandleFunc(apiV1+"/verification/request", handlers.RequestVerificationCodeHandler)
+	mux.HandleFunc(apiV1+"/verification/verify", handlers.VerifyContactHandler)
+
+	// Customer account endpoints
+	mux.HandleFunc(apiV1+"/auth/register", handlers.RegisterHandler)
+	mux.HandleFunc(apiV1+"/auth/login", handlers.LoginHandler)
+	mux.HandleFunc(apiV1+"/auth/refresh", handlers.RefreshTokenHandler)
+	mux.HandleFunc(apiV1+"/auth/logout", handlers.LogoutHandler)
+	mux.HandleFunc(apiV1+"/auth/me", handlers.CurrentUserHandler)
+	mux.HandleFunc(apiV1+"/auth/bookings", handlers.UserBookingsHandler)
+
+	// Ticket endpoints
+	mux.HandleFunc(apiV1+"/tickets/get", handlers.GetTicketHandler)
+	mux.HandleFunc(apiV1+"/tickets/attendee", handlers.SetAttendeeHandler)
+	mux.HandleFunc(apiV1+"/tickets/void", handlers.VoidTicketHandler)
+	mux.HandleFunc(apiV1+"/tickets/verify", handlers.VerifyTicketHandler)
+
+	// Door check-in endpoints
+	mux.HandleFunc(apiV1+"/checkin/scan", handlers.CheckInScanHandler)
+	mux.HandleFunc(apiV1+"/checkin/batch", handlers.SyncScansHandler)
+
+	// Payment endpoints
+	mux.HandleFunc(apiV1+"/payments/start", handlers.StartPaymentHandler)
+	mux.HandleFunc(apiV1+"/payments/webhook", handlers.PaymentWebhookHandler)
+	mux.HandleFunc(apiV1+"/payments/mock/complete", handlers.SimulateMockPaymentHandler)
+
+	// Order endpoints
+	mux.HandleFunc(apiV1+"/orders/create", handlers.CreateOrderHandler)
+	mux.HandleFunc(apiV1+"/orders/get", handlers.GetOrderHandler)
+	mux.HandleFunc(apiV1+"/orders/confirm", handlers.ConfirmOrderHandler)
+	mux.HandleFunc(apiV1+"/orders/cancel", handlers.CancelOrderHandler)
+
+	// Waitlist endpoints
+	mux.HandleFunc(apiV1+"/waitlist/join", handlers.JoinWaitlistHandler)
+	mux.HandleFunc(apiV1+"/waitlist/get", handlers.GetWaitlistEntryHandler)
 
 	// System endpoints
 	mux.HandleFunc(apiV1+"/stats", handlers.GetSearchStatsHandler)
 	mux.HandleFunc(apiV1+"/health", handlers.HealthCheckHandler)
 
+	// Admin endpoints
+	mux.HandleFunc(apiV1+"/admin/reconciliation", handlers.ReconciliationReportHandler)
+	mux.HandleFunc(apiV1+"/admin/reconciliation/repair", handlers.ReconciliationRepairHandler)
+	mux.HandleFunc(apiV1+"/admin/waitlist", handlers.ListWaitlistHandler)
+	mux.HandleFunc(apiV1+"/admin/waitlist/remove", handlers.RemoveWaitlistEntryHandler)
+	mux.HandleFunc(apiV1+"/admin/waitlist/promote", handlers.PromoteWaitlistHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions", handlers.ListPromotionsHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions/create", handlers.CreatePromotionHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions/get", handlers.GetPromotionHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions/update", handlers.UpdatePromotionHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions/delete", handlers.DeletePromotionHandler)
+	mux.HandleFunc(apiV1+"/admin/promotions/report", handlers.PromotionReportHandler)
+	mux.HandleFunc(apiV1+"/admin/refunds", handlers.ListRefundsHandler)
+	mux.HandleFunc(apiV1+"/admin/refunds/process", handlers.ProcessRefundHandler)
+	mux.HandleFunc(apiV1+"/admin/events", handlers.ListEventsHandler)
+	mux.HandleFunc(apiV1+"/admin/events/retry", handlers.RetryEventHandler)
+	mux.HandleFunc(apiV1+"/admin/reminders", handlers.ListUpcomingRemindersHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks", handlers.ListWebhooksHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/create", handlers.CreateWebhookHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/get", handlers.GetWebhookHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/update", handlers.UpdateWebhookHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/delete", handlers.DeleteWebhookHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/deliveries", handlers.ListWebhookDeliveriesHandler)
+	mux.HandleFunc(apiV1+"/admin/webhooks/deliveries/redeliver", handlers.RedeliverWebhookHandler)
+
 	return mux
 }
 
+func startBackgroundJobs(ctx context.Context) {
+	// Booking reconciliation (set RECONCILE_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("RECONCILE_INTERVAL", 15*time.Minute); interval > 0 {
+		repair := utils.GetBoolOrDefault("RECONCILE_AUTO_REPAIR", true)
+		go service.NewReconciliationService().Run(ctx, interval, repair)
+	}
+
+	// Release tickets held by lapsed pending bookings (set HOLD_REAPER_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("HOLD_REAPER_INTERVAL", time.Minute); interval > 0 {
+		go service.NewBookingService().RunHoldReaper(ctx, interval)
+	}
+
+	// Delete idempotency keys whose window has passed (set IDEMPOTENCY_PURGE_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("IDEMPOTENCY_PURGE_INTERVAL", time.Hour); interval > 0 {
+		go service.NewIdempotencyService().RunPurger(ctx, interval)
+	}
+
+	// Deliver outbox events to subscribers (set EVENT_DISPATCH_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("EVENT_DISPATCH_INTERVAL", time.Second); interval > 0 {
+		eventService := service.NewEventService()
+		if err := eventService.RegisterDefaultSubscribers(); err != nil {
+			log.Printf("Warning: Failed to register event subscribers: %v", err)
+		}
+		retention := utils.GetDurationOrDefault("EVENT_RETENTION", 7*24*time.Hour)
+		go eventService.RunDispatcher(ctx, interval, retention)
+	}
+
+	// Send queued partner webhooks (set WEBHOOK_DELIVERY_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("WEBHOOK_DELIVERY_INTERVAL", 5*time.Second); interval > 0 {
+		retention := utils.GetDurationOrDefault("WEBHOOK_LOG_RETENTION", 30*24*time.Hour)
+		go service.NewWebhookService().RunDeliverer(ctx, interval, retention)
+	}
+
+	// Send queued customer notifications (set NOTIFY_SEND_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("NOTIFY_SEND_INTERVAL", 5*time.Second); interval > 0 {
+		go service.NewNotificationService().RunSender(ctx, interval)
+	}
+
+	// Queue pre-show reminders (set REMINDER_INTERVAL=0 to disable)
+	if interval := utils.GetDurationOrDefault("REMINDER_INTERVAL", 5*time.Minute); interval > 0 {
+		go service.NewReminderService().RunScheduler(ctx, interval)
+	}
+}
+
 func getPort() string {
 	if port := os.Getenv("PORT"); port != "" {
 		return port
@@ -142,21 +307,110 @@ func logRoutes() {
 	log.Println("    GET  /api/v1/shows/by-price-range - Shows by price range")
 	log.Println("    POST /api/v1/shows/create      - Create new show")
 	log.Println("    GET  /api/v1/shows/get         - Get show details")
-	log.Println("    PUT  /api/v1/shows/update-availability - Update show availability")
+	log.Println("    PUT  /api/v1/shows/update-availability - Recount show availability (admin)")
+	log.Println("    PUT  /api/v1/shows/update-hold - Update pending booking hold (admin)")
 	log.Println("    GET  /api/v1/shows/booking-summary - Show booking summary")
+	log.Println("    GET  /api/v1/shows/seatmap     - Seat-level availability")
+	log.Println("    GET  /api/v1/shows/ticket-types - Ticket types and prices")
+	log.Println("    GET  /api/v1/shows/cancellation-policy - Cancellation windows and refunds")
+	log.Println("    GET  /api/v1/shows/exchanges   - Bookings exchanged into or out of a show")
+	log.Println("")
+	log.Println("  🏛️ Venue management (API v1):")
+	log.Println("    GET  /api/v1/venues            - List venues")
+	log.Println("    POST /api/v1/venues/create     - Create venue from seat layout")
+	log.Println("    GET  /api/v1/venues/get        - Get venue layout")
 	log.Println("")
 	log.Println("  🎟️ Booking management (API v1):")
 	log.Println("    POST /api/v1/bookings/create   - Create new booking")
 	log.Println("    GET  /api/v1/bookings/get      - Get booking details")
-	log.Println("    PUT  /api/v1/bookings/update-status - Update booking status")
-	log.Println("    PUT  /api/v1/bookings/confirm  - Confirm booking")
-	log.Println("    PUT  /api/v1/bookings/cancel   - Cancel booking")
+	log.Println("    PUT  /api/v1/bookings/update-status - Update booking status (admin)")
+	log.Println("    PUT  /api/v1/bookings/confirm  - Confirm booking (admin)")
+	log.Println("    PUT  /api/v1/bookings/cancel   - Cancel booking and refund under the show's policy")
+	log.Println("    PUT  /api/v1/bookings/amend    - Add or drop tickets on a booking")
+	log.Println("    GET  /api/v1/bookings/amendments - Versioned amendments of a booking")
+	log.Println("    POST /api/v1/bookings/exchange - Move a booking to another performance")
+	log.Println("    GET  /api/v1/bookings/exchanges - Exchanges a booking was part of")
 	log.Println("    GET  /api/v1/bookings/by-show  - Get bookings for a show")
 	log.Println("    GET  /api/v1/bookings/by-contact - Get bookings by contact")
 	log.Println("    GET  /api/v1/bookings/search   - Search bookings")
 	log.Println("    GET  /api/v1/bookings/stats    - Booking statistics")
+	log.Println("    GET  /api/v1/bookings/history  - Booking status history")
+	log.Println("    GET  /api/v1/bookings/tickets  - Individual tickets of a booking")
+	log.Println("    GET  /api/v1/bookings/ticket-token - Signed QR tokens for a booking's tickets")
+	log.Println("    GET  /api/v1/bookings/payments - Payment attempts for a booking")
+	log.Println("    GET  /api/v1/bookings/cancellation-quote - Refund due if cancelled now")
+	log.Println("    GET  /api/v1/bookings/refunds  - Refund ledger entries for a booking")
+	log.Println("    GET  /api/v1/bookings/notifications - Emails and text messages sent for a booking")
+	log.Println("    POST /api/v1/bookings/notifications/resend - Send the booking confirmation again")
+	log.Println("")
+	log.Println("  ✉️ Contact verification (API v1):")
+	log.Println("    POST /api/v1/verification/request - Send a one-time code to an email or mobile")
+	log.Println("    POST /api/v1/verification/verify - Exchange a code for a verification token")
+	log.Println("")
+	log.Println("  👤 Customer accounts (API v1):")
+	log.Println("    POST /api/v1/auth/register     - Create an account and sign in")
+	log.Println("    POST /api/v1/auth/login        - Sign in with email and password")
+	log.Println("    POST /api/v1/auth/refresh      - Exchange a refresh token for new tokens")
+	log.Println("    POST /api/v1/auth/logout       - End this session (?all=true for every session)")
+	log.Println("    GET  /api/v1/auth/me           - The signed-in account")
+	log.Println("    GET  /api/v1/auth/bookings     - Bookings made while signed in")
+	log.Println("")
+	log.Println("  💳 Payments (API v1):")
+	log.Println("    POST /api/v1/payments/start    - Pay for a pending booking, e.g. a waitlist offer")
+	log.Println("    POST /api/v1/payments/webhook  - Payment provider webhook (signed)")
+	log.Println("    POST /api/v1/payments/mock/complete - Complete a mock payment (admin, mock provider only)")
+	log.Println("")
+	log.Println("  🎫 Tickets (API v1):")
+	log.Println("    GET  /api/v1/tickets/get       - Get ticket details")
+	log.Println("    PUT  /api/v1/tickets/attendee  - Name or rename a ticket's attendee")
+	log.Println("    POST /api/v1/tickets/void      - Void a single ticket")
+	log.Println("    POST /api/v1/tickets/verify    - Verify a scanned ticket token (admin)")
+	log.Println("")
+	log.Println("  🚪 Door Check-in (API v1):")
+	log.Println("    POST /api/v1/checkin/scan      - Check in a scanned ticket or booking (admin)")
+	log.Println("    POST /api/v1/checkin/batch     - Sync scans buffered by an offline scanner (admin)")
+	log.Println("    GET  /api/v1/shows/attendance  - Live attendance and no-shows for a show")
+	log.Println("")
+	log.Println("  🛒 Orders (API v1):")
+	log.Println("    POST /api/v1/orders/create     - Book several shows in one checkout")
+	log.Println("    GET  /api/v1/orders/get        - Get order with its bookings")
+	log.Println("    PUT  /api/v1/orders/confirm    - Confirm every booking in an order (admin)")
+	log.Println("    PUT  /api/v1/orders/cancel     - Cancel every booking in an order")
+	log.Println("")
+	log.Println("  ⏳ Waitlist (API v1):")
+	log.Println("    POST /api/v1/waitlist/join     - Join a sold-out show's waitlist")
+	log.Println("    GET  /api/v1/waitlist/get      - Waitlist position or offer")
 	log.Println("")
 	log.Println("  📊 System endpoints (API v1):")
 	log.Println("    GET  /api/v1/stats             - Search statistics")
 	log.Println("    GET  /api/v1/health            - Health check")
+	log.Println("")
+	log.Println("  🔐 Admin endpoints (API v1):")
+	log.Println("    GET  /api/v1/admin/reconciliation - Booked tickets drift report")
+	log.Println("    POST /api/v1/admin/reconciliation/repair - Repair booked tickets drift")
+	log.Println("    POST /api/v1/shows/assign-venue - Assign a venue seat layout to a show")
+	log.Println("    PUT  /api/v1/shows/update-ticket-types - Replace a show's ticket types and prices")
+	log.Println("    PUT  /api/v1/shows/update-cancellation-policy - Set or remove a show's cancellation policy")
+	log.Println("    PUT  /api/v1/shows/update-contact-verification - Require verified contacts for a show's bookings")
+	log.Println("    GET  /api/v1/admin/waitlist    - Show waitlist")
+	log.Println("    POST /api/v1/admin/waitlist/remove - Remove a waitlist entry")
+	log.Println("    POST /api/v1/admin/waitlist/promote - Offer available tickets to the waitlist")
+	log.Println("    GET  /api/v1/admin/promotions  - List promo codes")
+	log.Println("    POST /api/v1/admin/promotions/create - Create promo code")
+	log.Println("    GET  /api/v1/admin/promotions/get - Get promo code")
+	log.Println("    PUT  /api/v1/admin/promotions/update - Update promo code")
+	log.Println("    DELETE /api/v1/admin/promotions/delete - Delete unused promo code")
+	log.Println("    GET  /api/v1/admin/promotions/report - Promo code redemption report")
+	log.Println("    GET  /api/v1/admin/refunds     - Refund ledger entries by status")
+	log.Println("    POST /api/v1/admin/refunds/process - Retry a failed refund or record a manual one")
+	log.Println("    GET  /api/v1/admin/events      - Outbox events (dead-letter list by default)")
+	log.Println("    POST /api/v1/admin/events/retry - Requeue a dead event")
+	log.Println("    GET  /api/v1/admin/reminders   - Pre-show reminders falling due soon")
+	log.Println("    GET  /api/v1/admin/webhooks    - List webhook subscriptions")
+	log.Println("    POST /api/v1/admin/webhooks/create - Create webhook subscription")
+	log.Println("    GET  /api/v1/admin/webhooks/get - Get webhook subscription")
+	log.Println("    PUT  /api/v1/admin/webhooks/update - Update or re-enable webhook subscription")
+	log.Println("    DELETE /api/v1/admin/webhooks/delete - Delete webhook subscription")
+	log.Println("    GET  /api/v1/admin/webhooks/deliveries - Webhook delivery log")
+	log.Println("    POST /api/v1/admin/webhooks/deliveries/redeliver - Redeliver a webhook")
 }
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes messages to a file or stdout instead of sending them,
+// for local development
+type LogNotifier struct {
+	channel string
+	mu      sync.Mutex
+	out     io.Writer
+}
+
+// NewLogNotifier creates a transport for channel that writes to out
+func NewLogNotifier(channel string, out io.Writer) *LogNotifier {
+	return &LogNotifier{channel: channel, out: out}
+}
+
+// Name identifies the log transport
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Channel is the channel the transport stands in for
+func (n *LogNotifier) Channel() string {
+	return n.channel
+}
+
+// Send writes the message
+func (n *LogNotifier) Send(message *Message) error {
+	if err := validateAddress(message.To); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.out, "----- %s %s to %s -----\n", time.Now().Format(time.RFC3339), message.Channel, message.To)
+	if err == nil && message.Subject != "" {
+		_, err = fmt.Fprintf(n.out, "Subject: %s\n\n", message.Subject)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(n.out, "%s\n\n", message.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
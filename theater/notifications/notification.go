@@ -0,0 +1,108 @@
+package notifications
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/gsmayya/theater/utils"
+)
+
+// Notification statuses
+const (
+	StatusPending = "pending" // Waiting for (another) attempt
+	StatusSent    = "sent"    // The transport accepted the message
+	StatusFailed  = "failed"  // Gave up after too many failed attempts
+)
+
+// Retry backoff: the first retry waits retryBaseDelay, doubling each time up to retryMaxDelay
+const (
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = time.Hour
+)
+
+// maxErrorLength matches the last_error column width
+const maxErrorLength = 1000
+
+// Notification is a message queued for a booking's contact, and its entry in
+// the booking's delivery log
+type Notification struct {
+	NotificationID string     `json:"notification_id"` // Random unique ID, e.g. NT-9F86D081884C7D65
+	BookingID      string     `json:"booking_id"`
+	Kind           string     `json:"kind"`    // One of the Kind* constants
+	Channel        string     `json:"channel"` // One of the Channel* constants
+	Recipient      string     `json:"recipient"`
+	Subject        string     `json:"subject,omitempty"`
+	Body           string     `json:"body"`
+	SourceID       string     `json:"source_id"` // Event that caused it, or the notification's own ID for a resend
+	Status         string     `json:"status"`    // One of the Status* constants
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	Transport      string     `json:"transport,omitempty"` // Notifier that made the last attempt
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
+// NewNotification queues a rendered message about a booking. sourceID
+// identifies what caused it, so the same event never queues the same kind of
+// message for a booking twice; an empty sourceID marks a one-off resend.
+func NewNotification(bookingID, kind, sourceID string, message *Message) (*Notification, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate notification ID: %w", err)
+	}
+
+	now := time.Now()
+	notification := &Notification{
+		NotificationID: fmt.Sprintf("NT-%X", buf),
+		BookingID:      bookingID,
+		Kind:           kind,
+		Channel:        message.Channel,
+		Recipient:      message.To,
+		Subject:        message.Subject,
+		Body:           message.Body,
+		SourceID:       sourceID,
+		Status:         StatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+	if notification.SourceID == "" {
+		notification.SourceID = notification.NotificationID
+	}
+	return notification, nil
+}
+
+// Message returns the message to hand to a transport
+func (n *Notification) Message() *Message {
+	return &Message{Channel: n.Channel, To: n.Recipient, Subject: n.Subject, Body: n.Body}
+}
+
+// Record applies the outcome of an attempt through transport: the
+// notification is sent, retried with backoff, or fails for good once
+// maxAttempts is reached
+func (n *Notification) Record(transport string, sendErr error, now time.Time, maxAttempts int32) {
+	n.Attempts++
+	n.Transport = transport
+
+	switch {
+	case sendErr == nil:
+		n.Status = StatusSent
+		n.LastError = ""
+		n.SentAt = &now
+	case n.Attempts >= maxAttempts:
+		n.Status = StatusFailed
+		n.LastError = truncateError(sendErr.Error())
+	default:
+		n.LastError = truncateError(sendErr.Error())
+		n.NextAttemptAt = now.Add(utils.RetryDelay(n.Attempts, retryBaseDelay, retryMaxDelay))
+	}
+}
+
+// truncateError keeps an error message within the last_error column
+func truncateError(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
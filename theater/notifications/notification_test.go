@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestNotification(t *testing.T, sourceID string) *Notification {
+	t.Helper()
+	message := &Message{Channel: ChannelEmail, To: "asha@example.com", Subject: "Booking confirmed", Body: "See you there"}
+	notification, err := NewNotification("BK-1", KindBookingConfirmed, sourceID, message)
+	if err != nil {
+		t.Fatalf("NewNotification() error: %v", err)
+	}
+	return notification
+}
+
+func TestNewNotification(t *testing.T) {
+	notification := newTestNotification(t, "EV-1")
+
+	if !strings.HasPrefix(notification.NotificationID, "NT-") || len(notification.NotificationID) != 19 {
+		t.Errorf("Expected an NT- ID of 19 characters, got %q", notification.NotificationID)
+	}
+	if notification.Status != StatusPending || notification.Attempts != 0 {
+		t.Errorf("Expected a pending notification without attempts, got %s after %d", notification.Status, notification.Attempts)
+	}
+	if notification.SourceID != "EV-1" {
+		t.Errorf("Expected source EV-1, got %q", notification.SourceID)
+	}
+
+	message := notification.Message()
+	if message.To != "asha@example.com" || message.Subject != "Booking confirmed" || message.Body != "See you there" {
+		t.Errorf("Expected the queued message back, got %+v", message)
+	}
+
+	// A resend has no source event, so it is its own source and never deduplicated
+	resend := newTestNotification(t, "")
+	if resend.SourceID != resend.NotificationID {
+		t.Errorf("Expected a resend to use its own ID as source, got %q", resend.SourceID)
+	}
+}
+
+func TestNotificationRecord(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	notification := newTestNotification(t, "EV-1")
+	notification.Record("smtp", errors.New("connection refused"), now, 3)
+	if notification.Status != StatusPending || notification.Attempts != 1 || notification.LastError != "connection refused" {
+		t.Errorf("Expected a pending retry after one failure, got %s/%d/%q", notification.Status, notification.Attempts, notification.LastError)
+	}
+	if !notification.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected the retry a minute later, got %v", notification.NextAttemptAt)
+	}
+
+	notification.Record("smtp", nil, now, 3)
+	if notification.Status != StatusSent || notification.LastError != "" || notification.SentAt == nil || notification.Transport != "smtp" {
+		t.Errorf("Expected a sent notification, got %+v", notification)
+	}
+
+	failing := newTestNotification(t, "EV-2")
+	for i := 0; i < 3; i++ {
+		failing.Record("sms", errors.New(strings.Repeat("x", 2000)), now, 3)
+	}
+	if failing.Status != StatusFailed || failing.Attempts != 3 {
+		t.Errorf("Expected failed after 3 attempts, got %s after %d", failing.Status, failing.Attempts)
+	}
+	if len(failing.LastError) != maxErrorLength {
+		t.Errorf("Expected the error truncated to %d characters, got %d", maxErrorLength, len(failing.LastError))
+	}
+}
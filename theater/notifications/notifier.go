@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channels a message is sent over. They match the booking contact types.
+const (
+	ChannelEmail  = "email"
+	ChannelMobile = "mobile"
+)
+
+// Message is a rendered notification ready for a transport
+type Message struct {
+	Channel string `json:"channel"` // One of the Channel* constants
+	To      string `json:"to"`      // Email address or mobile number
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// Notifier is a transport that delivers messages over one channel. Send
+// returning an error schedules another attempt, so transports should only
+// report success once the message has been handed over.
+type Notifier interface {
+	// Name identifies the transport in the delivery log, e.g. "smtp"
+	Name() string
+	// Channel is the channel the transport delivers, e.g. ChannelEmail
+	Channel() string
+	// Send delivers one message
+	Send(message *Message) error
+}
+
+// validateAddress rejects empty recipients and header injection through
+// line breaks
+func validateAddress(to string) error {
+	if strings.TrimSpace(to) == "" {
+		return fmt.Errorf("invalid recipient: address cannot be empty")
+	}
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient: %q contains a line break", to)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package notifications
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reminderSourcePrefix starts the source ID of a reminder, followed by its
+// lead time, e.g. RM-48h. The notifications unique key on source, booking and
+// kind then allows one reminder per booking per lead time.
+const reminderSourcePrefix = "RM-"
+
+// Reminder is an upcoming pre-show reminder for a confirmed booking
+type Reminder struct {
+	BookingID string    `json:"booking_id"`
+	Reference string    `json:"reference"`
+	ShowID    string    `json:"show_id"`
+	ShowName  string    `json:"show_name"`
+	ShowDate  time.Time `json:"show_date"`
+	LeadTime  string    `json:"lead_time"` // e.g. 48h
+	DueAt     time.Time `json:"due_at"`    // ShowDate minus the lead time; in the past when overdue
+	Channel   string    `json:"channel"`
+	Recipient string    `json:"recipient"`
+}
+
+// ParseLeadTimes parses a comma-separated list of reminder lead times such as
+// "48h,2h". The result is deduplicated and sorted longest first.
+func ParseLeadTimes(value string) ([]time.Duration, error) {
+	seen := make(map[time.Duration]bool)
+	var leadTimes []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lead, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lead time %q: %w", part, err)
+		}
+		if lead <= 0 {
+			return nil, fmt.Errorf("invalid lead time %q: must be positive", part)
+		}
+		if !seen[lead] {
+			seen[lead] = true
+			leadTimes = append(leadTimes, lead)
+		}
+	}
+	if len(leadTimes) == 0 {
+		return nil, fmt.Errorf("invalid lead times %q: at least one is required", value)
+	}
+
+	sort.Slice(leadTimes, func(i, j int) bool { return leadTimes[i] > leadTimes[j] })
+	return leadTimes, nil
+}
+
+// FormatLeadTime formats a lead time compactly, e.g. 48h or 1h30m
+func FormatLeadTime(lead time.Duration) string {
+	formatted := lead.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
+
+// ReminderSource returns the source ID recorded for reminders sent lead
+// before a show
+func ReminderSource(lead time.Duration) string {
+	return reminderSourcePrefix + FormatLeadTime(lead)
+}
+
+// StartsIn describes how long until a show starts, e.g. "2 days" or
+// "3 hours", rounded down to the largest whole unit
+func StartsIn(untilShow time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case untilShow >= 48*time.Hour:
+		return plural(int64(untilShow/(24*time.Hour)), "day")
+	case untilShow >= time.Hour:
+		return plural(int64(untilShow/time.Hour), "hour")
+	case untilShow >= time.Minute:
+		return plural(int64(untilShow/time.Minute), "minute")
+	default:
+		return "less than a minute"
+	}
+}
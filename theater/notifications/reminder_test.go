@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLeadTimes(t *testing.T) {
+	leadTimes, err := ParseLeadTimes(" 2h, 48h,,2h, 30m ")
+	if err != nil {
+		t.Fatalf("ParseLeadTimes() error: %v", err)
+	}
+	expected := []time.Duration{48 * time.Hour, 2 * time.Hour, 30 * time.Minute}
+	if !reflect.DeepEqual(leadTimes, expected) {
+		t.Errorf("Expected %v, got %v", expected, leadTimes)
+	}
+
+	for _, value := range []string{"", " , ", "2 days", "-1h", "0s"} {
+		if _, err := ParseLeadTimes(value); err == nil {
+			t.Errorf("Expected ParseLeadTimes(%q) to fail", value)
+		}
+	}
+}
+
+func TestFormatLeadTime(t *testing.T) {
+	tests := []struct {
+		lead     time.Duration
+		expected string
+	}{
+		{48 * time.Hour, "48h"},
+		{2 * time.Hour, "2h"},
+		{90 * time.Minute, "1h30m"},
+		{30 * time.Minute, "30m"},
+		{90 * time.Second, "1m30s"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatLeadTime(tt.lead); got != tt.expected {
+			t.Errorf("FormatLeadTime(%v) = %q, expected %q", tt.lead, got, tt.expected)
+		}
+	}
+
+	if source := ReminderSource(48 * time.Hour); source != "RM-48h" {
+		t.Errorf("Expected source RM-48h, got %q", source)
+	}
+}
+
+func TestStartsIn(t *testing.T) {
+	tests := []struct {
+		untilShow time.Duration
+		expected  string
+	}{
+		{72*time.Hour + 5*time.Minute, "3 days"},
+		{48 * time.Hour, "2 days"},
+		{47 * time.Hour, "47 hours"},
+		{time.Hour, "1 hour"},
+		{119 * time.Minute, "1 hour"},
+		{45 * time.Minute, "45 minutes"},
+		{time.Minute, "1 minute"},
+		{10 * time.Second, "less than a minute"},
+	}
+
+	for _, tt := range tests {
+		if got := StartsIn(tt.untilShow); got != tt.expected {
+			t.Errorf("StartsIn(%v) = %q, expected %q", tt.untilShow, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SMSNotifier sends text messages through an HTTP SMS gateway. Each message
+// is posted as JSON {"to", "from", "body"} with the API key as a bearer
+// token; any 2xx answer means the gateway accepted it.
+type SMSNotifier struct {
+	endpoint string
+	apiKey   string
+	from     string
+	client   *http.Client
+}
+
+// NewSMSNotifier creates a mobile transport posting to the gateway at endpoint
+func NewSMSNotifier(endpoint, apiKey, from string, timeout time.Duration) (*SMSNotifier, error) {
+	gateway, err := url.Parse(endpoint)
+	if err != nil || (gateway.Scheme != "http" && gateway.Scheme != "https") || gateway.Host == "" {
+		return nil, fmt.Errorf("invalid SMS gateway URL %q", endpoint)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("SMS sender cannot be empty")
+	}
+
+	return &SMSNotifier{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		from:     from,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name identifies the SMS gateway transport
+func (n *SMSNotifier) Name() string {
+	return "sms"
+}
+
+// Channel is mobile
+func (n *SMSNotifier) Channel() string {
+	return ChannelMobile
+}
+
+// Send posts the message to the gateway
+func (n *SMSNotifier) Send(message *Message) error {
+	if err := validateAddress(message.To); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"to":   message.To,
+		"from": n.from,
+		"body": message.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode SMS: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+n.apiKey)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SMS gateway unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("SMS gateway answered %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends email through an SMTP server. The server must offer
+// STARTTLS when a username is configured, since net/smtp refuses to send
+// credentials in the clear to anything but localhost.
+type SMTPNotifier struct {
+	addr string
+	auth smtp.Auth // nil for servers that accept mail without login
+	from *mail.Address
+}
+
+// NewSMTPNotifier creates an email transport for host:port sending as from
+func NewSMTPNotifier(host, port, username, password, from string) (*SMTPNotifier, error) {
+	if host == "" {
+		return nil, fmt.Errorf("SMTP host cannot be empty")
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP from address %q: %w", from, err)
+	}
+
+	notifier := &SMTPNotifier{addr: net.JoinHostPort(host, port), from: sender}
+	if username != "" {
+		notifier.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return notifier, nil
+}
+
+// Name identifies the SMTP transport
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Channel is email
+func (n *SMTPNotifier) Channel() string {
+	return ChannelEmail
+}
+
+// Send delivers the message to the SMTP server
+func (n *SMTPNotifier) Send(message *Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	email, err := buildEmail(n.from, to, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from.Address, []string{to.Address}, email); err != nil {
+		return fmt.Errorf("SMTP send failed: %w", err)
+	}
+	return nil
+}
+
+// buildEmail formats a plain-text UTF-8 email
+func buildEmail(from, to *mail.Address, message *Message, now time.Time) ([]byte, error) {
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return nil, fmt.Errorf("invalid subject: %q contains a line break", message.Subject)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(message.Body)
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
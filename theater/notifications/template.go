@@ -0,0 +1,152 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of notification sent to customers
+const (
+	KindBookingCreated   = "booking_created"   // A booking is holding tickets until it is paid for
+	KindBookingConfirmed = "booking_confirmed" // A booking is confirmed; also what a resend sends
+	KindBookingCancelled = "booking_cancelled" // A booking was cancelled or its hold expired
+	KindShowChanged      = "show_changed"      // A show's details or venue changed after booking
+	KindShowReminder     = "show_reminder"     // A confirmed booking's show starts soon
+)
+
+// TemplateData is what message templates can refer to
+type TemplateData struct {
+	CustomerName    string
+	Reference       string
+	ShowName        string
+	ShowDate        time.Time
+	ShowLocation    string
+	NumberOfTickets int32
+	TotalAmount     int32
+	Seats           []string
+	HoldExpiresAt   *time.Time
+	Reason          string // Why a booking was cancelled or what changed about a show
+	StartsIn        string // How long until the show starts, for reminders, e.g. "2 days"
+}
+
+// messageTemplate holds a subject and a body per channel. SMS bodies are
+// kept short enough for one or two segments.
+type messageTemplate struct {
+	subject *template.Template
+	email   *template.Template
+	sms     *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("Mon 2 Jan 2006, 15:04") },
+	"join": strings.Join,
+}
+
+func mustTemplate(kind, part, text string) *template.Template {
+	return template.Must(template.New(kind + "." + part).Funcs(templateFuncs).Parse(text))
+}
+
+const emailGreeting = `{{if .CustomerName}}Hi {{.CustomerName}},{{else}}Hello,{{end}}
+`
+
+const emailBookingDetails = `
+Booking reference: {{.Reference}}
+Show: {{.ShowName}}
+When: {{date .ShowDate}}
+Where: {{.ShowLocation}}
+Tickets: {{.NumberOfTickets}}{{if .Seats}}
+Seats: {{join .Seats ", "}}{{end}}
+Total: {{.TotalAmount}}
+`
+
+var templates = map[string]*messageTemplate{
+	KindBookingCreated: {
+		subject: mustTemplate(KindBookingCreated, "subject", `Your tickets for {{.ShowName}} are on hold`),
+		email: mustTemplate(KindBookingCreated, "email", emailGreeting+`
+We are holding your tickets{{if .HoldExpiresAt}} until {{date .HoldExpiresAt}}{{end}}. Complete your payment to confirm the booking.
+`+emailBookingDetails),
+		sms: mustTemplate(KindBookingCreated, "sms",
+			`{{.ShowName}}: {{.NumberOfTickets}} ticket(s) on hold{{if .HoldExpiresAt}} until {{date .HoldExpiresAt}}{{end}}. Ref {{.Reference}}. Pay to confirm.`),
+	},
+	KindBookingConfirmed: {
+		subject: mustTemplate(KindBookingConfirmed, "subject", `Booking confirmed: {{.ShowName}} ({{.Reference}})`),
+		email: mustTemplate(KindBookingConfirmed, "email", emailGreeting+`
+Your booking is confirmed. Show your tickets at the door.
+`+emailBookingDetails),
+		sms: mustTemplate(KindBookingConfirmed, "sms",
+			`Confirmed: {{.NumberOfTickets}} ticket(s) for {{.ShowName}}, {{date .ShowDate}}{{if .Seats}}, seats {{join .Seats " "}}{{end}}. Ref {{.Reference}}.`),
+	},
+	KindBookingCancelled: {
+		subject: mustTemplate(KindBookingCancelled, "subject", `Booking cancelled: {{.ShowName}} ({{.Reference}})`),
+		email: mustTemplate(KindBookingCancelled, "email", emailGreeting+`
+Your booking has been cancelled{{if .Reason}}: {{.Reason}}{{end}}. Any refund due is returned to your original payment method.
+`+emailBookingDetails),
+		sms: mustTemplate(KindBookingCancelled, "sms",
+			`Your booking {{.Reference}} for {{.ShowName}} has been cancelled{{if .Reason}}: {{.Reason}}{{end}}.`),
+	},
+	KindShowChanged: {
+		subject: mustTemplate(KindShowChanged, "subject", `Change to {{.ShowName}}`),
+		email: mustTemplate(KindShowChanged, "email", emailGreeting+`
+There has been a change to a show you have booked{{if .Reason}}: {{.Reason}}{{end}}. The latest details are below; your booking still stands.
+`+emailBookingDetails),
+		sms: mustTemplate(KindShowChanged, "sms",
+			`Change to {{.ShowName}}{{if .Reason}} ({{.Reason}}){{end}}: now {{date .ShowDate}} at {{.ShowLocation}}. Ref {{.Reference}}.`),
+	},
+	KindShowReminder: {
+		subject: mustTemplate(KindShowReminder, "subject", `Reminder: {{.ShowName}} starts {{if .StartsIn}}in {{.StartsIn}}{{else}}soon{{end}}`),
+		email: mustTemplate(KindShowReminder, "email", emailGreeting+`
+This is a reminder that {{.ShowName}} starts {{if .StartsIn}}in {{.StartsIn}}{{else}}soon{{end}}. Bring your tickets and allow time to find your seats.
+`+emailBookingDetails),
+		sms: mustTemplate(KindShowReminder, "sms",
+			`Reminder: {{.ShowName}} {{date .ShowDate}} at {{.ShowLocation}}{{if .Seats}}, seats {{join .Seats " "}}{{end}}. Ref {{.Reference}}.`),
+	},
+}
+
+// IsKnownKind reports whether kind is one of the notification kinds
+func IsKnownKind(kind string) bool {
+	_, ok := templates[kind]
+	return ok
+}
+
+// Render builds the message of a kind for a channel and recipient
+func Render(kind, channel, to string, data *TemplateData) (*Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid notification kind: %s", kind)
+	}
+
+	message := &Message{Channel: channel, To: to}
+	switch channel {
+	case ChannelEmail:
+		subject, err := execute(tmpl.subject, data)
+		if err != nil {
+			return nil, err
+		}
+		body, err := execute(tmpl.email, data)
+		if err != nil {
+			return nil, err
+		}
+		message.Subject = strings.Join(strings.Fields(subject), " ")
+		message.Body = body
+	case ChannelMobile:
+		body, err := execute(tmpl.sms, data)
+		if err != nil {
+			return nil, err
+		}
+		message.Body = body
+	default:
+		return nil, fmt.Errorf("invalid notification channel: %s", channel)
+	}
+	return message, nil
+}
+
+func execute(tmpl *template.Template, data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTemplateData() *TemplateData {
+	holdExpiresAt := time.Date(2026, 3, 14, 19, 15, 0, 0, time.UTC)
+	return &TemplateData{
+		CustomerName:    "Asha",
+		Reference:       "TKT-7QX4-M2PD",
+		ShowName:        "Hamlet",
+		ShowDate:        time.Date(2026, 3, 20, 19, 30, 0, 0, time.UTC),
+		ShowLocation:    "Main Hall",
+		NumberOfTickets: 2,
+		TotalAmount:     3000,
+		Seats:           []string{"A1", "A2"},
+		HoldExpiresAt:   &holdExpiresAt,
+	}
+}
+
+func TestRenderEveryKind(t *testing.T) {
+	kinds := []string{KindBookingCreated, KindBookingConfirmed, KindBookingCancelled, KindShowChanged, KindShowReminder}
+
+	for _, kind := range kinds {
+		t.Run(kind, func(t *testing.T) {
+			if !IsKnownKind(kind) {
+				t.Fatalf("Expected %s to be a known kind", kind)
+			}
+
+			email, err := Render(kind, ChannelEmail, "asha@example.com", testTemplateData())
+			if err != nil {
+				t.Fatalf("Render(email) error: %v", err)
+			}
+			if email.Channel != ChannelEmail || email.To != "asha@example.com" {
+				t.Errorf("Expected an email to asha@example.com, got %s to %s", email.Channel, email.To)
+			}
+			if !strings.Contains(email.Subject, "Hamlet") {
+				t.Errorf("Expected the subject to name the show, got %q", email.Subject)
+			}
+			for _, want := range []string{"Hi Asha,", "TKT-7QX4-M2PD", "Fri 20 Mar 2026, 19:30", "Main Hall", "Seats: A1, A2"} {
+				if !strings.Contains(email.Body, want) {
+					t.Errorf("Expected the email body to contain %q, got:\n%s", want, email.Body)
+				}
+			}
+
+			sms, err := Render(kind, ChannelMobile, "+14155550123", testTemplateData())
+			if err != nil {
+				t.Fatalf("Render(sms) error: %v", err)
+			}
+			if sms.Subject != "" {
+				t.Errorf("Expected no subject for a text message, got %q", sms.Subject)
+			}
+			if !strings.Contains(sms.Body, "TKT-7QX4-M2PD") || strings.Contains(sms.Body, "\n") {
+				t.Errorf("Expected a one-line text with the reference, got %q", sms.Body)
+			}
+			if len(sms.Body) > 160*2 {
+				t.Errorf("Expected a text of at most two segments, got %d characters", len(sms.Body))
+			}
+		})
+	}
+}
+
+func TestRenderReasonAndHold(t *testing.T) {
+	data := testTemplateData()
+	data.Reason = "the hold expired before payment"
+	cancelled, err := Render(KindBookingCancelled, ChannelMobile, "+14155550123", data)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(cancelled.Body, "cancelled: the hold expired before payment") {
+		t.Errorf("Expected the reason in the text, got %q", cancelled.Body)
+	}
+
+	data = testTemplateData()
+	data.CustomerName = ""
+	data.HoldExpiresAt = nil
+	created, err := Render(KindBookingCreated, ChannelEmail, "asha@example.com", data)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.HasPrefix(created.Body, "Hello,") {
+		t.Errorf("Expected a generic greeting without a name, got %q", created.Body)
+	}
+	if strings.Contains(created.Body, " until ") {
+		t.Errorf("Expected no hold deadline without HoldExpiresAt, got %q", created.Body)
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	if _, err := Render("booking_lost", ChannelEmail, "asha@example.com", testTemplateData()); err == nil ||
+		!strings.Contains(err.Error(), "invalid notification kind") {
+		t.Errorf("Expected an invalid kind error, got %v", err)
+	}
+	if _, err := Render(KindBookingConfirmed, "pigeon", "asha@example.com", testTemplateData()); err == nil ||
+		!strings.Contains(err.Error(), "invalid notification channel") {
+		t.Errorf("Expected an invalid channel error, got %v", err)
+	}
+	if IsKnownKind("booking_lost") {
+		t.Error("Expected booking_lost to be unknown")
+	}
+}
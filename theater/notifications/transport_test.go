@@ -0,0 +1,99 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogNotifier(t *testing.T) {
+	var out bytes.Buffer
+	notifier := NewLogNotifier(ChannelEmail, &out)
+
+	err := notifier.Send(&Message{Channel: ChannelEmail, To: "asha@example.com", Subject: "Booking confirmed", Body: "See you there"})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	for _, want := range []string{"email to asha@example.com", "Subject: Booking confirmed", "See you there"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := notifier.Send(&Message{Channel: ChannelEmail, To: " ", Body: "lost"}); err == nil {
+		t.Error("Expected an empty recipient to be rejected")
+	}
+}
+
+func TestSMSNotifier(t *testing.T) {
+	var received map[string]string
+	var authorization string
+	status := http.StatusAccepted
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+		w.Write([]byte("quota exceeded"))
+	}))
+	defer gateway.Close()
+
+	notifier, err := NewSMSNotifier(gateway.URL+"/messages", "key_123", "THEATER", time.Second)
+	if err != nil {
+		t.Fatalf("NewSMSNotifier() error: %v", err)
+	}
+
+	if err := notifier.Send(&Message{Channel: ChannelMobile, To: "+14155550123", Body: "Confirmed"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if received["to"] != "+14155550123" || received["from"] != "THEATER" || received["body"] != "Confirmed" {
+		t.Errorf("Unexpected gateway payload: %v", received)
+	}
+	if authorization != "Bearer key_123" {
+		t.Errorf("Expected a bearer token, got %q", authorization)
+	}
+
+	status = http.StatusTooManyRequests
+	err = notifier.Send(&Message{Channel: ChannelMobile, To: "+14155550123", Body: "Confirmed"})
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the gateway's 429 to be reported, got %v", err)
+	}
+
+	if _, err := NewSMSNotifier("ftp://gateway", "", "THEATER", time.Second); err == nil {
+		t.Error("Expected a non-HTTP gateway URL to be rejected")
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	from := &mail.Address{Name: "Theater", Address: "tickets@example.com"}
+	to := &mail.Address{Address: "asha@example.com"}
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	email, err := buildEmail(from, to, &Message{Subject: "Booking confirmed: Hamlet – TKT-7QX4", Body: "See you there"}, now)
+	if err != nil {
+		t.Fatalf("buildEmail() error: %v", err)
+	}
+
+	text := string(email)
+	for _, want := range []string{
+		"From: \"Theater\" <tickets@example.com>\r\n",
+		"To: <asha@example.com>\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Sat, 14 Mar 2026 12:00:00 +0000\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\nSee you there\r\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the email to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	_, err = buildEmail(from, to, &Message{Subject: "Hello\r\nBcc: everyone@example.com", Body: "spam"}, now)
+	if err == nil {
+		t.Error("Expected a subject with a line break to be rejected")
+	}
+}
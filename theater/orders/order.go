@@ -0,0 +1,195 @@
+package orders
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+)
+
+// Order statuses. An order moves as a unit: all of its bookings are
+// confirmed, cancelled or expired together.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// maxItems bounds how many shows one order can book
+const maxItems = 20
+
+// transitions lists the statuses each order status may move to
+var transitions = map[string][]string{
+	StatusPending:   {StatusConfirmed, StatusCancelled, StatusExpired},
+	StatusConfirmed: {StatusCancelled},
+	StatusCancelled: {},
+	StatusExpired:   {},
+}
+
+// Order groups bookings for several shows that are reserved, confirmed and
+// cancelled together
+type Order struct {
+	OrderID       string              `json:"order_id"` // Hash-generated unique ID
+	ContactType   string              `json:"contact_type"`
+	ContactValue  string              `json:"contact_value"`
+	CustomerName  string              `json:"customer_name,omitempty"`
+	Status        string              `json:"status"`
+	TotalAmount   int32               `json:"total_amount"`              // Sum of the bookings' totals
+	HoldExpiresAt *time.Time          `json:"hold_expires_at,omitempty"` // Shared by every pending booking in the order
+	Bookings      []*bookings.Booking `json:"bookings"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// OrderRequest represents the request payload for creating an order. Each item
+// takes the same fields as a booking request; the contact fields come from the order.
+type OrderRequest struct {
+	ContactType  string                     `json:"contact_type"`
+	ContactValue string                     `json:"contact_value"`
+	CustomerName string                     `json:"customer_name,omitempty"`
+	Items        []*bookings.BookingRequest `json:"items"`
+}
+
+// NewOrderFromJSON creates a pending order and its draft bookings from a JSON request body
+func NewOrderFromJSON(r *http.Request) (*Order, error) {
+	var req OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	return NewOrder(&req)
+}
+
+// NewOrder validates an order request and creates a pending order with one
+// draft booking per item. Each show may appear only once.
+func NewOrder(req *OrderRequest) (*Order, error) {
+	if req.ContactType == "" || req.ContactValue == "" || len(req.Items) == 0 {
+		return nil, fmt.Errorf("missing required fields: contact_type, contact_value, items")
+	}
+	if len(req.Items) > maxItems {
+		return nil, fmt.Errorf("an order can include at most %d shows", maxItems)
+	}
+	contactValue, err := bookings.NormalizeContact(req.ContactType, req.ContactValue)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	order := &Order{
+		ContactType:  req.ContactType,
+		ContactValue: contactValue,
+		CustomerName: req.CustomerName,
+		Status:       StatusPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	seen := make(map[string]bool, len(req.Items))
+	for i, item := range req.Items {
+		if item == nil {
+			return nil, fmt.Errorf("item %d: item cannot be empty", i+1)
+		}
+
+		item.ContactType = req.ContactType
+		item.ContactValue = contactValue
+		item.CustomerName = req.CustomerName
+
+		booking, err := bookings.NewBookingFromPayload(item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+
+		showID := booking.ShowID.String()
+		if seen[showID] {
+			return nil, fmt.Errorf("item %d: show %s appears more than once; combine its tickets into one item", i+1, showID)
+		}
+		seen[showID] = true
+
+		order.Bookings = append(order.Bookings, booking)
+	}
+
+	order.OrderID = order.generateHashID()
+	for _, booking := range order.Bookings {
+		booking.OrderID = order.OrderID
+	}
+	return order, nil
+}
+
+// generateHashID creates a unique hash-based ID from the order's contact, time and shows
+func (o *Order) generateHashID() string {
+	showIDs := make([]string, 0, len(o.Bookings))
+	for _, booking := range o.Bookings {
+		showIDs = append(showIDs, booking.ShowID.String())
+	}
+	sort.Strings(showIDs)
+
+	hashInput := fmt.Sprintf("%s:%s:%d:%s",
+		o.ContactType,
+		o.ContactValue,
+		o.CreatedAt.UnixNano(),
+		strings.Join(showIDs, ","),
+	)
+
+	hash := sha256.Sum256([]byte(hashInput))
+	return fmt.Sprintf("OR-%X", hash)[:18] // OR- prefix + 16 hex chars
+}
+
+// PlaceHold gives every booking in the order the same hold expiry, so the
+// bookings lapse together
+func (o *Order) PlaceHold(duration time.Duration) {
+	expiresAt := time.Now().Add(duration)
+	o.HoldExpiresAt = &expiresAt
+	for _, booking := range o.Bookings {
+		bookingExpiresAt := expiresAt
+		booking.HoldExpiresAt = &bookingExpiresAt
+	}
+}
+
+// SumTotals sets the order total from its bookings' totals
+func (o *Order) SumTotals() {
+	o.TotalAmount = 0
+	for _, booking := range o.Bookings {
+		o.TotalAmount += booking.TotalAmount
+	}
+}
+
+// IsKnownStatus reports whether status is one of the order statuses
+func IsKnownStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// ValidateTransition returns an error when an order may not move from one status to another
+func ValidateTransition(from, to string) error {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status transition: order %s -> %s", from, to)
+}
+
+// ManagesBookingStatus reports whether a booking that belongs to an order may
+// only move to status together with the rest of the order
+func ManagesBookingStatus(status string) bool {
+	return status == bookings.StatusConfirmed || status == bookings.StatusCancelled
+}
+
+// BookingStatus returns the status an order's bookings move to when the order moves to status
+func BookingStatus(status string) string {
+	switch status {
+	case StatusConfirmed:
+		return bookings.StatusConfirmed
+	case StatusCancelled:
+		return bookings.StatusCancelled
+	case StatusExpired:
+		return bookings.StatusExpired
+	}
+	return bookings.StatusPending
+}
@@ -0,0 +1,132 @@
+package orders
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+func TestNewOrderFromJSON(t *testing.T) {
+	showA, showB := uuid.New(), uuid.New()
+	body := `{"contact_type":"email","contact_value":"fan@example.com","customer_name":"Sam",` +
+		`"items":[{"show_id":"` + showA.String() + `","number_of_tickets":2},` +
+		`{"show_id":"` + showB.String() + `","ticket_types":{"adult":1,"child":1},"promo_code":"spring"}]}`
+
+	order, err := NewOrderFromJSON(httptest.NewRequest("POST", "/api/v1/orders/create", strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("NewOrderFromJSON() error: %v", err)
+	}
+
+	if !strings.HasPrefix(order.OrderID, "OR-") || len(order.OrderID) != 18 {
+		t.Errorf("Expected OR- order ID of 18 characters, got %q", order.OrderID)
+	}
+	if order.Status != StatusPending {
+		t.Errorf("Expected status %s, got %s", StatusPending, order.Status)
+	}
+	if len(order.Bookings) != 2 {
+		t.Fatalf("Expected 2 bookings, got %d", len(order.Bookings))
+	}
+
+	for _, booking := range order.Bookings {
+		if booking.OrderID != order.OrderID {
+			t.Errorf("Booking %s should belong to order %s, got %q", booking.BookingID, order.OrderID, booking.OrderID)
+		}
+		if booking.ContactValue != "fan@example.com" || booking.CustomerName != "Sam" {
+			t.Errorf("Booking should take the order's contact, got %q %q", booking.ContactValue, booking.CustomerName)
+		}
+	}
+	if order.Bookings[1].NumberOfTickets != 2 || order.Bookings[1].PromoCode != "SPRING" {
+		t.Errorf("Expected 2 tickets with promo SPRING, got %d %q", order.Bookings[1].NumberOfTickets, order.Bookings[1].PromoCode)
+	}
+	if order.Bookings[0].BookingID == order.Bookings[1].BookingID {
+		t.Error("Bookings in an order should have distinct IDs")
+	}
+}
+
+func TestNewOrderRejectsInvalidRequests(t *testing.T) {
+	showID := uuid.New().String()
+
+	tests := []struct {
+		name string
+		req  *OrderRequest
+	}{
+		{"no items", &OrderRequest{ContactType: "email", ContactValue: "fan@example.com"}},
+		{"missing contact", &OrderRequest{Items: []*bookings.BookingRequest{{ShowID: showID, NumberOfTickets: 1}}}},
+		{"bad contact", &OrderRequest{ContactType: "email", ContactValue: "not-an-email",
+			Items: []*bookings.BookingRequest{{ShowID: showID, NumberOfTickets: 1}}}},
+		{"empty item", &OrderRequest{ContactType: "email", ContactValue: "fan@example.com",
+			Items: []*bookings.BookingRequest{nil}}},
+		{"bad item", &OrderRequest{ContactType: "email", ContactValue: "fan@example.com",
+			Items: []*bookings.BookingRequest{{ShowID: "not-a-uuid", NumberOfTickets: 1}}}},
+		{"duplicate show", &OrderRequest{ContactType: "email", ContactValue: "fan@example.com",
+			Items: []*bookings.BookingRequest{{ShowID: showID, NumberOfTickets: 1}, {ShowID: showID, NumberOfTickets: 2}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOrder(tt.req); err == nil {
+				t.Errorf("NewOrder() should reject %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestOrderPlaceHoldAndTotals(t *testing.T) {
+	order := &Order{Bookings: []*bookings.Booking{
+		{TotalAmount: 1500},
+		{TotalAmount: 2500},
+	}}
+
+	order.PlaceHold(10 * time.Minute)
+	order.SumTotals()
+
+	if order.TotalAmount != 4000 {
+		t.Errorf("Expected total 4000, got %d", order.TotalAmount)
+	}
+	if order.HoldExpiresAt == nil {
+		t.Fatal("Expected the order to have a hold")
+	}
+	for _, booking := range order.Bookings {
+		if booking.HoldExpiresAt == nil || !booking.HoldExpiresAt.Equal(*order.HoldExpiresAt) {
+			t.Errorf("Every booking should share the order's hold, got %v", booking.HoldExpiresAt)
+		}
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+	allowed := [][2]string{
+		{StatusPending, StatusConfirmed},
+		{StatusPending, StatusCancelled},
+		{StatusPending, StatusExpired},
+		{StatusConfirmed, StatusCancelled},
+	}
+	for _, tt := range allowed {
+		if err := ValidateTransition(tt[0], tt[1]); err != nil {
+			t.Errorf("ValidateTransition(%s, %s) error: %v", tt[0], tt[1], err)
+		}
+	}
+
+	rejected := [][2]string{
+		{StatusConfirmed, StatusPending},
+		{StatusCancelled, StatusConfirmed},
+		{StatusExpired, StatusConfirmed},
+		{StatusConfirmed, StatusConfirmed},
+	}
+	for _, tt := range rejected {
+		err := ValidateTransition(tt[0], tt[1])
+		if err == nil || !strings.Contains(err.Error(), "invalid status transition") {
+			t.Errorf("ValidateTransition(%s, %s) should be rejected, got %v", tt[0], tt[1], err)
+		}
+	}
+
+	if BookingStatus(StatusCancelled) != bookings.StatusCancelled || !ManagesBookingStatus(bookings.StatusConfirmed) {
+		t.Error("Order statuses should map onto booking statuses")
+	}
+	if ManagesBookingStatus(bookings.StatusCheckedIn) {
+		t.Error("Check-in should stay a per-booking status change")
+	}
+}
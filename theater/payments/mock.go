@@ -0,0 +1,167 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MockProviderName is the name of the built-in mock gateway
+const MockProviderName = "mock"
+
+// MockSignatureHeader carries the mock gateway's webhook signature, in the
+// form t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+const MockSignatureHeader = "Mock-Signature"
+
+// mockWebhookTolerance is how old a signed webhook may be before it is
+// rejected as a replay
+const mockWebhookTolerance = 5 * time.Minute
+
+// minMockSecretLength keeps webhook secrets from being guessable
+const minMockSecretLength = 16
+
+// MockProvider is a payment gateway for local development and tests. It
+// accepts every intent and never moves money; payments succeed or fail when
+// a webhook built by SimulateWebhook is delivered. It keeps no state, so
+// several backend instances can share one secret.
+type MockProvider struct {
+	secret []byte
+}
+
+// NewMockProvider creates a mock gateway that signs webhooks with secret
+func NewMockProvider(secret string) (*MockProvider, error) {
+	if len(secret) < minMockSecretLength {
+		return nil, fmt.Errorf("mock webhook secret must be at least %d characters", minMockSecretLength)
+	}
+	return &MockProvider{secret: []byte(secret)}, nil
+}
+
+// Name identifies the mock gateway
+func (m *MockProvider) Name() string {
+	return MockProviderName
+}
+
+// CreateIntent starts a mock payment
+func (m *MockProvider) CreateIntent(req *IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("invalid payment amount: %d", req.Amount)
+	}
+
+	id, err := mockID("pi_mock_")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := mockID("_secret_")
+	if err != nil {
+		return nil, err
+	}
+	return &Intent{ProviderPaymentID: id, ClientSecret: id + secret}, nil
+}
+
+// Capture collects a mock payment
+func (m *MockProvider) Capture(providerPaymentID string, amount int32) error {
+	if !strings.HasPrefix(providerPaymentID, "pi_mock_") {
+		return fmt.Errorf("payment not found: %s", providerPaymentID)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("invalid payment amount: %d", amount)
+	}
+	return nil
+}
+
+// Refund returns a mock payment
+func (m *MockProvider) Refund(providerPaymentID string, amount int32, reason string) (string, error) {
+	if !strings.HasPrefix(providerPaymentID, "pi_mock_") {
+		return "", fmt.Errorf("payment not found: %s", providerPaymentID)
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("invalid refund amount: %d", amount)
+	}
+	return mockID("re_mock_")
+}
+
+// VerifyWebhook checks the Mock-Signature header and parses the event
+func (m *MockProvider) VerifyWebhook(payload []byte, headers http.Header, now time.Time) (*Event, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(headers.Get(MockSignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return nil, ErrInvalidSignature
+	}
+	signedAt := time.Unix(seconds, 0)
+	if now.Sub(signedAt) > mockWebhookTolerance || signedAt.Sub(now) > mockWebhookTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := m.sign(timestamp, payload)
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, expected) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.EventID == "" || event.ProviderPaymentID == "" {
+		return nil, fmt.Errorf("invalid webhook payload: id and payment_intent are required")
+	}
+	return &event, nil
+}
+
+// SimulateWebhook builds the signed webhook the mock gateway would send for
+// event, as the payload and the headers to deliver it with. An empty event
+// ID is filled in.
+func (m *MockProvider) SimulateWebhook(event *Event, now time.Time) ([]byte, http.Header, error) {
+	if event.EventID == "" {
+		id, err := mockID("evt_mock_")
+		if err != nil {
+			return nil, nil, err
+		}
+		event.EventID = id
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode webhook: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set(MockSignatureHeader, "t="+timestamp+",v1="+hex.EncodeToString(m.sign(timestamp, payload)))
+	return payload, headers, nil
+}
+
+func (m *MockProvider) sign(timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// mockID generates a random mock gateway ID with the given prefix
+func mockID(prefix string) (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate mock payment ID: %w", err)
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,104 @@
+package payments
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "mock-secret-0123456789"
+
+func TestMockProviderIntentLifecycle(t *testing.T) {
+	mock, err := NewMockProvider(testWebhookSecret)
+	if err != nil {
+		t.Fatalf("NewMockProvider() error: %v", err)
+	}
+
+	intent, err := mock.CreateIntent(&IntentRequest{PaymentID: "PAY-1", BookingID: "BK-1", Amount: 500, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("CreateIntent() error: %v", err)
+	}
+	if !strings.HasPrefix(intent.ProviderPaymentID, "pi_mock_") || !strings.HasPrefix(intent.ClientSecret, intent.ProviderPaymentID) {
+		t.Errorf("Unexpected intent: %+v", intent)
+	}
+
+	if err := mock.Capture(intent.ProviderPaymentID, 500); err != nil {
+		t.Errorf("Capture() error: %v", err)
+	}
+	refundID, err := mock.Refund(intent.ProviderPaymentID, 500, "test")
+	if err != nil || !strings.HasPrefix(refundID, "re_mock_") {
+		t.Errorf("Refund() = %q, %v", refundID, err)
+	}
+
+	if _, err := mock.CreateIntent(&IntentRequest{PaymentID: "PAY-2", Amount: 0}); err == nil {
+		t.Error("Expected a zero-amount intent to be rejected")
+	}
+	if err := mock.Capture("pi_other_1", 500); err == nil {
+		t.Error("Expected capturing another provider's payment to fail")
+	}
+}
+
+func TestMockProviderWebhookRoundTrip(t *testing.T) {
+	mock, _ := NewMockProvider(testWebhookSecret)
+	now := time.Now()
+
+	payload, headers, err := mock.SimulateWebhook(&Event{
+		Type:              EventPaymentSucceeded,
+		ProviderPaymentID: "pi_mock_abc",
+		Amount:            500,
+		Currency:          "USD",
+	}, now)
+	if err != nil {
+		t.Fatalf("SimulateWebhook() error: %v", err)
+	}
+
+	event, err := mock.VerifyWebhook(payload, headers, now)
+	if err != nil {
+		t.Fatalf("VerifyWebhook() error: %v", err)
+	}
+	if event.Type != EventPaymentSucceeded || event.ProviderPaymentID != "pi_mock_abc" || event.Amount != 500 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if !strings.HasPrefix(event.EventID, "evt_mock_") {
+		t.Errorf("Expected a generated event ID, got %q", event.EventID)
+	}
+}
+
+func TestMockProviderRejectsBadWebhooks(t *testing.T) {
+	mock, _ := NewMockProvider(testWebhookSecret)
+	other, _ := NewMockProvider("another-secret-0123456789")
+	now := time.Now()
+	event := &Event{Type: EventPaymentSucceeded, ProviderPaymentID: "pi_mock_abc", Amount: 500, Currency: "USD"}
+
+	payload, headers, _ := mock.SimulateWebhook(event, now)
+	_, foreignHeaders, _ := other.SimulateWebhook(event, now)
+	_, staleHeaders, _ := mock.SimulateWebhook(event, now.Add(-10*time.Minute))
+
+	tampered := []byte(strings.Replace(string(payload), `"amount":500`, `"amount":5`, 1))
+
+	tests := []struct {
+		name    string
+		payload []byte
+		headers http.Header
+	}{
+		{"tampered payload", tampered, headers},
+		{"wrong secret", payload, foreignHeaders},
+		{"replayed after tolerance", payload, staleHeaders},
+		{"missing signature", payload, http.Header{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mock.VerifyWebhook(tt.payload, tt.headers, now); err != ErrInvalidSignature {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewMockProviderRejectsShortSecrets(t *testing.T) {
+	if _, err := NewMockProvider("short"); err == nil {
+		t.Error("Expected a short webhook secret to be rejected")
+	}
+}
@@ -0,0 +1,143 @@
+package payments
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Payment statuses
+const (
+	StatusPending    = "pending"    // Intent created; waiting for the customer to pay
+	StatusAuthorized = "authorized" // The provider holds the funds; the booking is being confirmed
+	StatusCaptured   = "captured"   // Funds collected and the booking confirmed
+	StatusFailed     = "failed"     // The customer's payment did not go through
+	StatusRefunded   = "refunded"   // Funds returned, e.g. because the hold lapsed before payment arrived
+)
+
+// Payment purposes
+const (
+	PurposeBooking = "booking" // Pays a pending booking's total and confirms it
+	PurposeBalance = "balance" // Pays the balance due on a confirmed booking
+)
+
+// Webhook event types
+const (
+	EventPaymentSucceeded = "payment.succeeded" // The customer paid; funds are authorized
+	EventPaymentFailed    = "payment.failed"
+)
+
+// ErrInvalidSignature is returned for webhooks whose signature does not verify
+var ErrInvalidSignature = fmt.Errorf("invalid webhook signature")
+
+// PaymentProvider is a payment gateway. Amounts are in the same units as
+// booking prices. Payments are authorized first and only captured once the
+// booking they pay for has been confirmed, so money is never collected for
+// tickets that could not be kept.
+type PaymentProvider interface {
+	// Name identifies the provider in payment records and status history
+	Name() string
+	// CreateIntent starts a payment the customer completes with the provider
+	CreateIntent(req *IntentRequest) (*Intent, error)
+	// Capture collects an authorized payment
+	Capture(providerPaymentID string, amount int32) error
+	// Refund returns amount of an authorized or captured payment to the
+	// customer and returns the provider's refund ID
+	Refund(providerPaymentID string, amount int32, reason string) (string, error)
+	// VerifyWebhook checks a webhook's signature and parses its event
+	VerifyWebhook(payload []byte, headers http.Header, now time.Time) (*Event, error)
+}
+
+// IntentRequest asks a provider to start a payment
+type IntentRequest struct {
+	PaymentID string // Our payment ID, also used as the provider's idempotency key
+	BookingID string
+	Amount    int32
+	Currency  string
+}
+
+// Intent is a payment started with a provider
+type Intent struct {
+	ProviderPaymentID string
+	ClientSecret      string // Handed to the client to complete the payment
+}
+
+// Event is a provider webhook, normalized
+type Event struct {
+	EventID           string `json:"id"`
+	Type              string `json:"type"` // One of the Event* constants
+	ProviderPaymentID string `json:"payment_intent"`
+	Amount            int32  `json:"amount"`
+	Currency          string `json:"currency"`
+	FailureReason     string `json:"failure_reason,omitempty"`
+}
+
+// Payment is one attempt to pay for a booking
+type Payment struct {
+	PaymentID         string    `json:"payment_id"` // Random unique ID, e.g. PAY-9F86D081884C7D65
+	BookingID         string    `json:"booking_id"`
+	Provider          string    `json:"provider"`
+	ProviderPaymentID string    `json:"provider_payment_id,omitempty"`
+	Amount            int32     `json:"amount"`
+	Currency          string    `json:"currency"`
+	Purpose           string    `json:"purpose"` // One of the Purpose* constants
+	Status            string    `json:"status"`  // One of the Status* constants
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	RefundID          string    `json:"refund_id,omitempty"`
+	ClientSecret      string    `json:"client_secret,omitempty"` // Only returned when the payment is started; never stored
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// NewPayment creates a pending payment for a booking's total
+func NewPayment(bookingID, provider string, amount int32, currency string) (*Payment, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid payment amount: %d", amount)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate payment ID: %w", err)
+	}
+
+	now := time.Now()
+	return &Payment{
+		PaymentID: fmt.Sprintf("PAY-%X", buf),
+		BookingID: bookingID,
+		Provider:  provider,
+		Amount:    amount,
+		Currency:  currency,
+		Purpose:   PurposeBooking,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// NewBalancePayment creates a pending payment for the balance due on a
+// confirmed booking
+func NewBalancePayment(bookingID, provider string, amount int32, currency string) (*Payment, error) {
+	payment, err := NewPayment(bookingID, provider, amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	payment.Purpose = PurposeBalance
+	return payment, nil
+}
+
+// ChangedBy is the actor recorded in a booking's status history when a
+// payment confirms or cancels it
+func (p *Payment) ChangedBy() string {
+	return "payment:" + p.Provider
+}
+
+// IntentRequest builds the request that starts this payment with its provider
+func (p *Payment) IntentRequest() *IntentRequest {
+	return &IntentRequest{
+		PaymentID: p.PaymentID,
+		BookingID: p.BookingID,
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+	}
+}
@@ -0,0 +1,39 @@
+package payments
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewPayment(t *testing.T) {
+	payment, err := NewPayment("BK-1", MockProviderName, 1200, "USD")
+	if err != nil {
+		t.Fatalf("NewPayment() error: %v", err)
+	}
+	if !strings.HasPrefix(payment.PaymentID, "PAY-") || len(payment.PaymentID) != 20 {
+		t.Errorf("Expected a PAY- ID that fits the payments table, got %q", payment.PaymentID)
+	}
+	if payment.Status != StatusPending || payment.Purpose != PurposeBooking {
+		t.Errorf("Expected a pending booking payment, got %s %s", payment.Status, payment.Purpose)
+	}
+	if payment.ChangedBy() != "payment:mock" {
+		t.Errorf("Expected payment:mock, got %s", payment.ChangedBy())
+	}
+
+	req := payment.IntentRequest()
+	if req.PaymentID != payment.PaymentID || req.Amount != 1200 || req.Currency != "USD" {
+		t.Errorf("Intent request does not match the payment: %+v", req)
+	}
+
+	if _, err := NewPayment("BK-1", MockProviderName, 0, "USD"); err == nil {
+		t.Error("Expected a zero amount to be rejected")
+	}
+
+	balance, err := NewBalancePayment("BK-1", MockProviderName, 300, "USD")
+	if err != nil {
+		t.Fatalf("NewBalancePayment() error: %v", err)
+	}
+	if balance.Purpose != PurposeBalance || balance.Amount != 300 {
+		t.Errorf("Expected a balance payment of 300, got %s %d", balance.Purpose, balance.Amount)
+	}
+}
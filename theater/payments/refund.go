@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+// Refund statuses, as reported by the payment processor
+const (
+	RefundPending   = "pending"   // Not yet returned; bookings paid outside a provider are refunded by staff
+	RefundSucceeded = "succeeded" // The processor returned the money
+	RefundFailed    = "failed"    // The processor refused; see the failure reason and retry
+)
+
+// Refund is an entry in the refund ledger: money owed back to a customer for
+// a cancelled booking, and whether the processor has returned it
+type Refund struct {
+	RefundID          string    `json:"refund_id"` // Random unique ID, e.g. RF-9F86D081884C7D65
+	BookingID         string    `json:"booking_id"`
+	PaymentID         string    `json:"payment_id,omitempty"` // Payment refunded; empty when the booking was not paid through a provider
+	Amount            int32     `json:"amount"`
+	Currency          string    `json:"currency"`
+	Rule              string    `json:"rule"` // Cancellation rule that set the amount, e.g. partial_refund
+	Reason            string    `json:"reason,omitempty"`
+	Status            string    `json:"status"` // One of the Refund* constants
+	ProcessorRefundID string    `json:"processor_refund_id,omitempty"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	CreatedBy         string    `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// NewRefund creates a pending ledger entry. payment may be nil for bookings
+// that were not paid through a provider.
+func NewRefund(bookingID string, payment *Payment, amount int32, currency, rule, reason, createdBy string) (*Refund, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid refund amount: %d", amount)
+	}
+	if payment != nil && amount > payment.Amount {
+		return nil, fmt.Errorf("invalid refund amount: %d is more than the %d paid", amount, payment.Amount)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate refund ID: %w", err)
+	}
+
+	now := time.Now()
+	refund := &Refund{
+		RefundID:  fmt.Sprintf("RF-%X", buf),
+		BookingID: bookingID,
+		Amount:    amount,
+		Currency:  currency,
+		Rule:      rule,
+		Reason:    reason,
+		Status:    RefundPending,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if payment != nil {
+		refund.PaymentID = payment.PaymentID
+		refund.Currency = payment.Currency
+	}
+	return refund, nil
+}
+
+// IsSettled reports whether the refund no longer needs the processor
+func (r *Refund) IsSettled() bool {
+	return r.Status == RefundSucceeded
+}
@@ -0,0 +1,42 @@
+package payments
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRefund(t *testing.T) {
+	payment, err := NewPayment("BK-1", MockProviderName, 1200, "EUR")
+	if err != nil {
+		t.Fatalf("NewPayment() error: %v", err)
+	}
+
+	refund, err := NewRefund("BK-1", payment, 600, "USD", "partial_refund", "cannot attend", "api")
+	if err != nil {
+		t.Fatalf("NewRefund() error: %v", err)
+	}
+	if !strings.HasPrefix(refund.RefundID, "RF-") || len(refund.RefundID) != 19 {
+		t.Errorf("Expected an RF- ID that fits the refunds table, got %q", refund.RefundID)
+	}
+	if refund.Status != RefundPending || refund.IsSettled() {
+		t.Errorf("Expected a pending refund, got %s", refund.Status)
+	}
+	if refund.PaymentID != payment.PaymentID || refund.Currency != "EUR" {
+		t.Errorf("Expected the refund to follow the payment, got %+v", refund)
+	}
+
+	if _, err := NewRefund("BK-1", payment, 1500, "USD", "full_refund", "", "api"); err == nil {
+		t.Error("Expected a refund over the amount paid to be rejected")
+	}
+	if _, err := NewRefund("BK-1", nil, 0, "USD", "full_refund", "", "api"); err == nil {
+		t.Error("Expected a zero refund to be rejected")
+	}
+
+	manual, err := NewRefund("BK-2", nil, 500, "USD", "full_refund", "", "api")
+	if err != nil {
+		t.Fatalf("NewRefund() error: %v", err)
+	}
+	if manual.PaymentID != "" || manual.Currency != "USD" {
+		t.Errorf("Expected a manual refund in the default currency, got %+v", manual)
+	}
+}
@@ -0,0 +1,187 @@
+package promotions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Discount types
+const (
+	DiscountPercentage = "percentage" // DiscountValue is a percentage of the booking total
+	DiscountFixed      = "fixed"      // DiscountValue is an amount off the booking total
+)
+
+// maxCodeLength matches the promo code column width
+const maxCodeLength = 50
+
+// Promotion is a promo code and the rules for redeeming it
+type Promotion struct {
+	Code           string     `json:"code"` // Upper-case code customers enter
+	Description    string     `json:"description,omitempty"`
+	DiscountType   string     `json:"discount_type"`
+	DiscountValue  int32      `json:"discount_value"`            // Percent off (1-100) or amount off
+	ShowID         *uuid.UUID `json:"show_id,omitempty"`         // Limits the code to one show; nil = every show
+	ValidFrom      *time.Time `json:"valid_from,omitempty"`      // nil = valid immediately
+	ValidUntil     *time.Time `json:"valid_until,omitempty"`     // nil = never expires
+	MaxRedemptions *int32     `json:"max_redemptions,omitempty"` // Bookings that may use the code; nil = unlimited
+	MaxPerContact  *int32     `json:"max_per_contact,omitempty"` // Bookings per contact; nil = unlimited
+	MinTickets     int32      `json:"min_tickets,omitempty"`     // Smallest booking the code applies to
+	Active         bool       `json:"active"`
+	Redemptions    int32      `json:"redemptions"` // Bookings currently holding the discount
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PromotionRequest represents the request payload for creating or updating a promotion
+type PromotionRequest struct {
+	Code           string     `json:"code"`
+	Description    string     `json:"description,omitempty"`
+	DiscountType   string     `json:"discount_type"`
+	DiscountValue  int32      `json:"discount_value"`
+	ShowID         string     `json:"show_id,omitempty"`
+	ValidFrom      *time.Time `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time `json:"valid_until,omitempty"`
+	MaxRedemptions *int32     `json:"max_redemptions,omitempty"`
+	MaxPerContact  *int32     `json:"max_per_contact,omitempty"`
+	MinTickets     int32      `json:"min_tickets,omitempty"`
+	Active         *bool      `json:"active,omitempty"` // Defaults to true
+}
+
+// RedemptionSummary reports how a promo code has been used
+type RedemptionSummary struct {
+	Code          string `json:"code"`
+	DiscountType  string `json:"discount_type"`
+	DiscountValue int32  `json:"discount_value"`
+	Redemptions   int32  `json:"redemptions"`    // Bookings holding the discount
+	TicketsSold   int32  `json:"tickets_sold"`   // Tickets in those bookings
+	TotalDiscount int32  `json:"total_discount"` // Discount given on those bookings
+	Revenue       int32  `json:"revenue"`        // What those bookings paid after the discount
+	Released      int32  `json:"released"`       // Bookings that used the code and were later cancelled, expired or refunded
+}
+
+// NewPromotionFromJSON creates a promotion from a JSON request body. The
+// promotion is validated with Validate before it is saved.
+func NewPromotionFromJSON(r *http.Request) (*Promotion, error) {
+	var req PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	promotion := &Promotion{
+		Code:           NormalizeCode(req.Code),
+		Description:    strings.TrimSpace(req.Description),
+		DiscountType:   strings.ToLower(strings.TrimSpace(req.DiscountType)),
+		DiscountValue:  req.DiscountValue,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerContact:  req.MaxPerContact,
+		MinTickets:     req.MinTickets,
+		Active:         req.Active == nil || *req.Active,
+	}
+
+	if req.ShowID != "" {
+		showID, err := uuid.Parse(req.ShowID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid show_id format: %w", err)
+		}
+		promotion.ShowID = &showID
+	}
+
+	return promotion, nil
+}
+
+// NormalizeCode upper-cases and trims a promo code
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks a promotion's code, discount, validity window and limits
+func (p *Promotion) Validate() error {
+	if p.Code == "" {
+		return fmt.Errorf("invalid promotion: code cannot be empty")
+	}
+	if len(p.Code) > maxCodeLength {
+		return fmt.Errorf("invalid promotion: code is longer than %d characters", maxCodeLength)
+	}
+	for _, c := range p.Code {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return fmt.Errorf("invalid promotion: code %s may only contain letters, digits, '_' and '-'", p.Code)
+		}
+	}
+
+	switch p.DiscountType {
+	case DiscountPercentage:
+		if p.DiscountValue < 1 || p.DiscountValue > 100 {
+			return fmt.Errorf("invalid promotion: percentage discount must be between 1 and 100")
+		}
+	case DiscountFixed:
+		if p.DiscountValue <= 0 {
+			return fmt.Errorf("invalid promotion: fixed discount must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("invalid promotion: discount_type must be either '%s' or '%s'", DiscountPercentage, DiscountFixed)
+	}
+
+	if p.ValidFrom != nil && p.ValidUntil != nil && !p.ValidUntil.After(*p.ValidFrom) {
+		return fmt.Errorf("invalid promotion: valid_until must be after valid_from")
+	}
+	if p.MaxRedemptions != nil && *p.MaxRedemptions <= 0 {
+		return fmt.Errorf("invalid promotion: max_redemptions must be greater than 0")
+	}
+	if p.MaxPerContact != nil && *p.MaxPerContact <= 0 {
+		return fmt.Errorf("invalid promotion: max_per_contact must be greater than 0")
+	}
+	if p.MinTickets < 0 {
+		return fmt.Errorf("invalid promotion: min_tickets cannot be negative")
+	}
+	return nil
+}
+
+// CheckEligibility reports why the code cannot be applied to a booking of
+// numberOfTickets tickets for the given show at time now. Usage caps are
+// checked separately because they depend on existing bookings.
+func (p *Promotion) CheckEligibility(showID uuid.UUID, numberOfTickets int32, now time.Time) error {
+	if !p.Active {
+		return fmt.Errorf("invalid promo code: %s is not active", p.Code)
+	}
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return fmt.Errorf("invalid promo code: %s is not valid until %s", p.Code, p.ValidFrom.Format(time.RFC3339))
+	}
+	if p.ValidUntil != nil && !now.Before(*p.ValidUntil) {
+		return fmt.Errorf("invalid promo code: %s expired at %s", p.Code, p.ValidUntil.Format(time.RFC3339))
+	}
+	if p.ShowID != nil && *p.ShowID != showID {
+		return fmt.Errorf("invalid promo code: %s does not apply to this show", p.Code)
+	}
+	if numberOfTickets < p.MinTickets {
+		return fmt.Errorf("invalid promo code: %s requires at least %d tickets", p.Code, p.MinTickets)
+	}
+	return nil
+}
+
+// Discount returns the amount taken off a booking total. The discount never
+// exceeds the total; percentages round down.
+func (p *Promotion) Discount(amount int32) int32 {
+	if amount <= 0 {
+		return 0
+	}
+
+	var discount int32
+	switch p.DiscountType {
+	case DiscountPercentage:
+		discount = int32(int64(amount) * int64(p.DiscountValue) / 100)
+	case DiscountFixed:
+		discount = p.DiscountValue
+	}
+
+	if discount > amount {
+		return amount
+	}
+	return discount
+}
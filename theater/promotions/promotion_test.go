@@ -0,0 +1,125 @@
+package promotions
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewPromotionFromJSON(t *testing.T) {
+	showID := uuid.New()
+	body := `{"code":" spring20 ","discount_type":"Percentage","discount_value":20,"show_id":"` + showID.String() + `","max_per_contact":1}`
+
+	promotion, err := NewPromotionFromJSON(httptest.NewRequest("POST", "/api/v1/admin/promotions/create", strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("NewPromotionFromJSON() error: %v", err)
+	}
+
+	if promotion.Code != "SPRING20" || promotion.DiscountType != DiscountPercentage {
+		t.Errorf("Expected normalized code and type, got %q %q", promotion.Code, promotion.DiscountType)
+	}
+	if promotion.ShowID == nil || *promotion.ShowID != showID {
+		t.Errorf("Expected show scope %s, got %v", showID, promotion.ShowID)
+	}
+	if !promotion.Active {
+		t.Error("Promotions should be active by default")
+	}
+	if err := promotion.Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+
+	body = `{"code":"X","discount_type":"fixed","discount_value":100,"show_id":"not-a-uuid"}`
+	if _, err := NewPromotionFromJSON(httptest.NewRequest("POST", "/api/v1/admin/promotions/create", strings.NewReader(body))); err == nil {
+		t.Error("Expected error for invalid show_id")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	zero := int32(0)
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+	}{
+		{"empty code", Promotion{DiscountType: DiscountFixed, DiscountValue: 100}},
+		{"bad code", Promotion{Code: "SPRING 20", DiscountType: DiscountFixed, DiscountValue: 100}},
+		{"unknown type", Promotion{Code: "SPRING", DiscountType: "bogo", DiscountValue: 100}},
+		{"percentage over 100", Promotion{Code: "SPRING", DiscountType: DiscountPercentage, DiscountValue: 101}},
+		{"zero fixed", Promotion{Code: "SPRING", DiscountType: DiscountFixed}},
+		{"window reversed", Promotion{Code: "SPRING", DiscountType: DiscountFixed, DiscountValue: 100, ValidFrom: &now, ValidUntil: &earlier}},
+		{"zero max redemptions", Promotion{Code: "SPRING", DiscountType: DiscountFixed, DiscountValue: 100, MaxRedemptions: &zero}},
+		{"zero max per contact", Promotion{Code: "SPRING", DiscountType: DiscountFixed, DiscountValue: 100, MaxPerContact: &zero}},
+		{"negative min tickets", Promotion{Code: "SPRING", DiscountType: DiscountFixed, DiscountValue: 100, MinTickets: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.promotion.Validate(); err == nil || !strings.Contains(err.Error(), "invalid promotion") {
+				t.Errorf("Expected invalid promotion error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckEligibility(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	showID := uuid.New()
+
+	base := Promotion{Code: "SPRING", DiscountType: DiscountFixed, DiscountValue: 100, Active: true}
+	if err := base.CheckEligibility(showID, 1, now); err != nil {
+		t.Errorf("Expected global code to apply, got %v", err)
+	}
+
+	otherShow := uuid.New()
+	tests := []struct {
+		name   string
+		modify func(p *Promotion)
+	}{
+		{"inactive", func(p *Promotion) { p.Active = false }},
+		{"not started", func(p *Promotion) { p.ValidFrom = &later }},
+		{"expired", func(p *Promotion) { p.ValidUntil = &earlier }},
+		{"other show", func(p *Promotion) { p.ShowID = &otherShow }},
+		{"too few tickets", func(p *Promotion) { p.MinTickets = 2 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotion := base
+			tt.modify(&promotion)
+			if err := promotion.CheckEligibility(showID, 1, now); err == nil || !strings.Contains(err.Error(), "invalid promo code") {
+				t.Errorf("Expected invalid promo code error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion Promotion
+		amount    int32
+		want      int32
+	}{
+		{"percentage", Promotion{DiscountType: DiscountPercentage, DiscountValue: 20}, 10000, 2000},
+		{"percentage rounds down", Promotion{DiscountType: DiscountPercentage, DiscountValue: 15}, 999, 149},
+		{"full percentage", Promotion{DiscountType: DiscountPercentage, DiscountValue: 100}, 5000, 5000},
+		{"fixed", Promotion{DiscountType: DiscountFixed, DiscountValue: 1500}, 10000, 1500},
+		{"fixed capped at total", Promotion{DiscountType: DiscountFixed, DiscountValue: 1500}, 1000, 1000},
+		{"free booking", Promotion{DiscountType: DiscountFixed, DiscountValue: 1500}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promotion.Discount(tt.amount); got != tt.want {
+				t.Errorf("Discount(%d) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
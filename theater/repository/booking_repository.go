@@ -9,7 +9,11 @@ import (
 
 	"github.com/gsmayya/theater/bookings"
 	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/orders"
+	"github.com/gsmayya/theater/payments"
 	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/waitlist"
 	"github.com/google/uuid"
 )
 
@@ -25,30 +29,33 @@ func NewBookingRepository() *BookingRepository {
 	}
 }
 
+// ShowInventory is the capacity state of a show as committed by a booking transaction
+type ShowInventory struct {
+	ShowID        uuid.UUID
+	TotalTickets  int32
+	BookedTickets int32
+}
+
+// AvailableTickets returns the number of tickets still available for sale
+func (i *ShowInventory) AvailableTickets() int32 {
+	return i.TotalTickets - i.BookedTickets
+}
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // CreateBooking inserts a new booking into the database and cache
 func (r *BookingRepository) CreateBooking(booking *bookings.Booking) error {
-	query := `
-		INSERT INTO bookings (booking_id, show_id, contact_type, contact_value, number_of_tickets, 
-			customer_name, total_amount, booking_date, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err := r.database.GetDB().Exec(query,
-		booking.BookingID,
-		booking.ShowID.String(),
-		booking.ContactType,
-		booking.ContactValue,
-		booking.NumberOfTickets,
-		booking.CustomerName,
-		booking.TotalAmount,
-		booking.BookingDate,
-		booking.Status,
-		booking.CreatedAt,
-		booking.UpdatedAt,
-	)
+	if err := insertBooking(r.database.GetDB(), booking); err != nil {
+		return err
+	}
 
-	if err != nil {
-		return fmt.Errorf("failed to create booking: %w", err)
+	if err := issueTickets(r.database.GetDB(), booking); err != nil {
+		return err
 	}
 
 	// Cache the booking data
@@ -58,6 +65,113 @@ func (r *BookingRepository) CreateBooking(booking *bookings.Booking) error {
 	return nil
 }
 
+// ReserveBooking checks capacity, redeems the booking's promo code, inserts
+// the booking and updates the show's booked_tickets counter as one atomic unit. The show row is locked for the
+// duration of the transaction, so concurrent reservations for the same show
+// are serialized and can never oversell it.
+func (r *BookingRepository) ReserveBooking(booking *bookings.Booking, changedBy string) (*ShowInventory, error) {
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		var err error
+		inventory, err = reserveBookingTx(tx, booking, changedBy)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Only cache once the transaction has been committed
+	r.cacheBooking(booking)
+
+	log.Printf("Booking reserved successfully: %s for show %s (%d/%d booked)",
+		booking.BookingID, booking.ShowID.String(), inventory.BookedTickets, inventory.TotalTickets)
+	return inventory, nil
+}
+
+// reserveBookingTx performs ReserveBooking inside an existing transaction.
+// Callers reserving several bookings lock their shows up front, in a
+// consistent order, before calling it.
+func reserveBookingTx(tx *sql.Tx, booking *bookings.Booking, changedBy string) (*ShowInventory, error) {
+	totalTickets, err := lockShow(tx, booking.ShowID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketsSold, err := ticketsSoldForShow(tx, booking.ShowID)
+	if err != nil {
+		return nil, err
+	}
+
+	availableTickets := totalTickets - ticketsSold
+	if booking.NumberOfTickets > availableTickets {
+		return nil, fmt.Errorf("insufficient tickets available. Requested: %d, Available: %d", booking.NumberOfTickets, availableTickets)
+	}
+
+	if err := checkTicketTypeQuotas(tx, booking); err != nil {
+		return nil, err
+	}
+
+	if booking.PromoCode != "" {
+		if err := redeemPromotion(tx, booking, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Re-check seating under the lock in case a venue was assigned after validation
+	seated, err := showHasSeating(tx, booking.ShowID)
+	if err != nil {
+		return nil, err
+	}
+	if seated && len(booking.Seats) == 0 {
+		return nil, fmt.Errorf("invalid seat selection: seats are required for show %s", booking.ShowID.String())
+	}
+	if !seated && len(booking.Seats) > 0 {
+		return nil, fmt.Errorf("invalid seat selection: show %s does not have reserved seating", booking.ShowID.String())
+	}
+
+	if err := insertBooking(tx, booking); err != nil {
+		return nil, err
+	}
+
+	if err := insertTicketLines(tx, booking.BookingID, booking.TicketLines); err != nil {
+		return nil, err
+	}
+
+	if len(booking.Seats) > 0 {
+		if err := reserveSeats(tx, booking.ShowID, booking.BookingID, booking.Seats); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := issueTickets(tx, booking); err != nil {
+		return nil, err
+	}
+
+	err = insertStatusChange(tx, &bookings.StatusChange{
+		BookingID: booking.BookingID,
+		ToStatus:  booking.Status,
+		ChangedBy: changedBy,
+		Reason:    "booking created",
+		ChangedAt: booking.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bookedTickets := ticketsSold + booking.NumberOfTickets
+	if err := setBookedTickets(tx, booking.ShowID, bookedTickets); err != nil {
+		return nil, err
+	}
+
+	return &ShowInventory{
+		ShowID:        booking.ShowID,
+		TotalTickets:  totalTickets,
+		BookedTickets: bookedTickets,
+	}, nil
+}
+
 // GetBooking retrieves a booking by ID, first checking cache, then database
 func (r *BookingRepository) GetBooking(bookingID string) (*bookings.Booking, error) {
 	// Try to get from cache first
@@ -67,8 +181,8 @@ func (r *BookingRepository) GetBooking(bookingID string) (*bookings.Booking, err
 
 	// If not in cache, get from database
 	query := `
-		SELECT booking_id, show_id, contact_type, contact_value, number_of_tickets, 
-			customer_name, total_amount, booking_date, status, created_at, updated_at
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at
 		FROM bookings 
 		WHERE booking_id = ?
 	`
@@ -77,17 +191,25 @@ func (r *BookingRepository) GetBooking(bookingID string) (*bookings.Booking, err
 
 	booking := &bookings.Booking{}
 	var showIDStr string
+	var holdExpiresAt sql.NullTime
 	
 	err := row.Scan(
 		&booking.BookingID,
+		&booking.Reference,
 		&showIDStr,
 		&booking.ContactType,
 		&booking.ContactValue,
 		&booking.NumberOfTickets,
 		&booking.CustomerName,
 		&booking.TotalAmount,
+		&booking.PromoCode,
+		&booking.DiscountAmount,
+		&booking.OrderID,
+		&booking.UserID,
+		&booking.BalanceDue,
 		&booking.BookingDate,
 		&booking.Status,
+		&holdExpiresAt,
 		&booking.CreatedAt,
 		&booking.UpdatedAt,
 	)
@@ -105,6 +227,19 @@ func (r *BookingRepository) GetBooking(bookingID string) (*bookings.Booking, err
 		return nil, fmt.Errorf("invalid show ID in database: %w", err)
 	}
 	booking.ShowID = showID
+	booking.HoldExpiresAt = nullableTime(holdExpiresAt)
+
+	seats, err := bookingSeats(r.database.GetDB(), bookingID)
+	if err != nil {
+		return nil, err
+	}
+	booking.Seats = seats
+
+	ticketLines, err := bookingTicketLines(r.database.GetDB(), bookingID)
+	if err != nil {
+		return nil, err
+	}
+	booking.TicketLines = ticketLines
 
 	// Cache the booking for future requests
 	r.cacheBooking(booking)
@@ -112,6 +247,20 @@ func (r *BookingRepository) GetBooking(bookingID string) (*bookings.Booking, err
 	return booking, nil
 }
 
+// GetBookingByReference retrieves a booking by its customer-facing reference
+func (r *BookingRepository) GetBookingByReference(reference string) (*bookings.Booking, error) {
+	var bookingID string
+	err := r.database.GetDB().QueryRow(`SELECT booking_id FROM bookings WHERE reference = ?`, reference).Scan(&bookingID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("booking not found: %s", reference)
+		}
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	return r.GetBooking(bookingID)
+}
+
 // UpdateBooking updates an existing booking
 func (r *BookingRepository) UpdateBooking(booking *bookings.Booking) error {
 	query := `
@@ -153,22 +302,107 @@ func (r *BookingRepository) UpdateBooking(booking *bookings.Booking) error {
 	return nil
 }
 
-// UpdateBookingStatus updates only the booking status
-func (r *BookingRepository) UpdateBookingStatus(bookingID, status string) error {
-	query := `UPDATE bookings SET status = ?, updated_at = ? WHERE booking_id = ?`
+// UpdateBookingStatus moves a booking through the status state machine and
+// recounts the show's booked_tickets in the same transaction. Transitions
+// that take tickets back from the show are capacity checked first. The
+// change is recorded in booking_status_history along with who made it and why.
+func (r *BookingRepository) UpdateBookingStatus(bookingID, status, changedBy, reason string) (*ShowInventory, error) {
+	return r.updateBookingStatus(bookingID, status, changedBy, reason, nil)
+}
 
-	result, err := r.database.GetDB().Exec(query, status, time.Now(), bookingID)
-	if err != nil {
-		return fmt.Errorf("failed to update booking status: %w", err)
-	}
+// CancelBookingWithRefund cancels a booking like UpdateBookingStatus and adds
+// the refund it is owed to the ledger in the same transaction
+func (r *BookingRepository) CancelBookingWithRefund(bookingID, changedBy, reason string, refund *payments.Refund) (*ShowInventory, error) {
+	return r.updateBookingStatus(bookingID, bookings.StatusCancelled, changedBy, reason, refund)
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to check affected rows: %w", err)
-	}
+func (r *BookingRepository) updateBookingStatus(bookingID, status, changedBy, reason string, refund *payments.Refund) (*ShowInventory, error) {
+	var inventory *ShowInventory
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("booking not found: %s", bookingID)
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		showID, err := bookingShowID(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		// Lock the show before the booking, in the same order as ReserveBooking
+		totalTickets, err := lockShow(tx, showID)
+		if err != nil {
+			return err
+		}
+
+		booking, err := lockBookingState(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		if booking.OrderID != "" && orders.ManagesBookingStatus(status) {
+			return fmt.Errorf("invalid status transition: booking %s belongs to order %s; confirm or cancel the order instead",
+				bookingID, booking.OrderID)
+		}
+
+		now := time.Now()
+		if err := booking.CheckStatusChange(status, now); err != nil {
+			return err
+		}
+
+		fromStatus := booking.Status
+		effect := bookings.TransitionEffect(fromStatus, status)
+		if effect == bookings.InventoryReserved {
+			ticketsSold, err := ticketsSoldForShow(tx, showID)
+			if err != nil {
+				return err
+			}
+			if availableTickets := totalTickets - ticketsSold; booking.NumberOfTickets > availableTickets {
+				return fmt.Errorf("insufficient tickets available. Requested: %d, Available: %d", booking.NumberOfTickets, availableTickets)
+			}
+		}
+		booking.UpdateStatus(status)
+
+		if effect == bookings.InventoryReleased {
+			if err := releaseSeats(tx, bookingID); err != nil {
+				return err
+			}
+			if err := releaseTickets(tx, bookingID, now); err != nil {
+				return err
+			}
+		}
+
+		if outcome, settled := waitlist.OfferOutcome(status); settled {
+			if err := settleWaitlistOffer(tx, bookingID, outcome, now); err != nil {
+				return err
+			}
+		}
+
+		query := `UPDATE bookings SET status = ?, hold_expires_at = ?, updated_at = ? WHERE booking_id = ?`
+		if _, err := tx.Exec(query, booking.Status, booking.HoldExpiresAt, now, bookingID); err != nil {
+			return fmt.Errorf("failed to update booking status: %w", err)
+		}
+
+		err = insertStatusChange(tx, &bookings.StatusChange{
+			BookingID:  bookingID,
+			FromStatus: fromStatus,
+			ToStatus:   status,
+			ChangedBy:  changedBy,
+			Reason:     reason,
+			ChangedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if refund != nil {
+			if err := insertRefund(tx, refund); err != nil {
+				return err
+			}
+		}
+
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	// Update cache if booking exists in cache
@@ -177,38 +411,408 @@ func (r *BookingRepository) UpdateBookingStatus(bookingID, status string) error
 		r.cacheBooking(cachedBooking)
 	}
 
-	return nil
+	return inventory, nil
 }
 
-// DeleteBooking deletes a booking by ID
-func (r *BookingRepository) DeleteBooking(bookingID string) error {
-	query := "DELETE FROM bookings WHERE booking_id = ?"
+// AmendBooking changes how many tickets a pending or confirmed booking holds,
+// adding or dropping tickets of ticketType, and records the change as the
+// booking's next version. Increases are capacity checked under the show lock
+// like a new reservation. The booking's amounts, ticket lines and tickets,
+// its order's total and the show's booked_tickets all change in the same
+// transaction. Tickets added to a confirmed booking leave their price as the
+// booking's balance due, and a booking with a balance due cannot be amended
+// until it is paid. refundFor, when set, returns the refund owed for the
+// amendment, which is added to the ledger in the same transaction. When
+// expectedVersion is set the amendment is refused if the booking has been
+// amended since.
+func (r *BookingRepository) AmendBooking(bookingID, ticketType string, numberOfTickets int32, expectedVersion *int32, changedBy, reason string,
+	refundFor func(amendment *bookings.Amendment) (*payments.Refund, error)) (*bookings.Amendment, *ShowInventory, error) {
+	var amendment *bookings.Amendment
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		showID, err := bookingShowID(tx, bookingID)
+		if err != nil {
+			return err
+		}
 
-	result, err := r.database.GetDB().Exec(query, bookingID)
-	if err != nil {
-		return fmt.Errorf("failed to delete booking: %w", err)
-	}
+		// Lock the show before the booking, in the same order as ReserveBooking
+		totalTickets, err := lockShow(tx, showID)
+		if err != nil {
+			return err
+		}
+
+		booking, err := lockBookingState(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		booking.ShowID = showID
+
+		now := time.Now()
+		switch {
+		case booking.Status == bookings.StatusPending && booking.HoldExpiresAt != nil && !now.Before(*booking.HoldExpiresAt):
+			return bookings.ErrHoldExpired
+		case booking.Status != bookings.StatusPending && booking.Status != bookings.StatusConfirmed:
+			return fmt.Errorf("invalid amendment: booking %s is %s", bookingID, booking.Status)
+		case booking.BalanceDue > 0:
+			return fmt.Errorf("invalid amendment: booking %s has a balance of %d due; pay it first", bookingID, booking.BalanceDue)
+		}
+
+		seats, err := bookingSeats(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if len(seats) > 0 {
+			return fmt.Errorf("invalid amendment: booking %s has reserved seats; void tickets to give seats back", bookingID)
+		}
+
+		version, err := bookingVersion(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != nil && *expectedVersion != version {
+			return fmt.Errorf("booking version conflict: booking %s is at version %d, not %d", bookingID, version, *expectedVersion)
+		}
+
+		query := `SELECT total_amount, discount_amount FROM bookings WHERE booking_id = ?`
+		if err := tx.QueryRow(query, bookingID).Scan(&booking.TotalAmount, &booking.DiscountAmount); err != nil {
+			return fmt.Errorf("failed to load booking amounts: %w", err)
+		}
+		booking.TicketLines, err = bookingTicketLines(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		before := *booking
+		ticketType, err = booking.AmendTickets(ticketType, numberOfTickets)
+		if err != nil {
+			return err
+		}
+		amendment = bookings.NewAmendment(&before, booking, version+1, ticketType, changedBy, reason)
+		amendment.CreatedAt = now
+
+		// A confirmed booking has been paid for; what the added tickets cost is owed
+		if booking.Status == bookings.StatusConfirmed && amendment.AmountDelta > 0 {
+			booking.BalanceDue = amendment.AmountDelta
+		}
+
+		added := amendment.TicketDelta()
+		if added > 0 {
+			ticketsSold, err := ticketsSoldForShow(tx, showID)
+			if err != nil {
+				return err
+			}
+			if availableTickets := totalTickets - ticketsSold; added > availableTickets {
+				return fmt.Errorf("insufficient tickets available. Requested: %d, Available: %d", added, availableTickets)
+			}
+
+			if ticketType != "" {
+				requested := []*bookings.TicketLine{{TicketType: ticketType, Quantity: added}}
+				line, available, err := ticketTypeShortfall(tx, showID, requested)
+				if err != nil {
+					return err
+				}
+				if line != nil {
+					return fmt.Errorf("insufficient tickets available for ticket type %s. Requested: %d, Available: %d",
+						ticketType, added, available)
+				}
+			}
+		}
+
+		query = `
+			UPDATE bookings SET number_of_tickets = ?, total_amount = ?, discount_amount = ?, balance_due = ?, updated_at = ?
+			WHERE booking_id = ?
+		`
+		_, err = tx.Exec(query, booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount, booking.BalanceDue, now, bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to update booking: %w", err)
+		}
+
+		if ticketType != "" {
+			if err := updateTicketLine(tx, booking, ticketType); err != nil {
+				return err
+			}
+		}
+
+		if added > 0 {
+			// Issue only the added tickets, leaving the existing ones and their attendee names alone
+			addition := &bookings.Booking{BookingID: bookingID, ShowID: showID, NumberOfTickets: added}
+			if ticketType != "" {
+				addition.TicketLines = []*bookings.TicketLine{{TicketType: ticketType, Quantity: added}}
+			}
+			if err := issueTickets(tx, addition); err != nil {
+				return err
+			}
+		} else {
+			voidReason := fmt.Sprintf("amendment v%d", amendment.Version)
+			if err := voidAmendedTickets(tx, bookingID, ticketType, -added, voidReason, now); err != nil {
+				return err
+			}
+		}
+
+		if booking.OrderID != "" {
+			query := `UPDATE orders SET total_amount = total_amount + ?, updated_at = ? WHERE order_id = ?`
+			if _, err := tx.Exec(query, amendment.AmountDelta, now, booking.OrderID); err != nil {
+				return fmt.Errorf("failed to update order total: %w", err)
+			}
+		}
+
+		if err := insertAmendment(tx, amendment); err != nil {
+			return err
+		}
+
+		if refundFor != nil {
+			refund, err := refundFor(amendment)
+			if err != nil {
+				return err
+			}
+			if refund != nil {
+				if err := insertRefund(tx, refund); err != nil {
+					return err
+				}
+			}
+		}
+
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
 
-	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to check affected rows: %w", err)
+		return nil, nil, err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("booking not found: %s", bookingID)
+	// The cached booking's ticket count and amounts are stale now
+	r.removeCachedBooking(bookingID)
+
+	log.Printf("Booking %s amended to version %d: %d -> %d tickets",
+		bookingID, amendment.Version, amendment.FromTickets, amendment.ToTickets)
+	return amendment, inventory, nil
+}
+
+// DeleteBooking deletes a booking by ID and recounts the show's booked_tickets
+// in the same transaction
+func (r *BookingRepository) DeleteBooking(bookingID string) (*ShowInventory, error) {
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		showID, err := bookingShowID(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+
+		if err := releaseSeats(tx, bookingID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := settleWaitlistOffer(tx, bookingID, waitlist.StatusRemoved, now); err != nil {
+			return err
+		}
+
+		deletion := &events.BookingDeletion{BookingID: bookingID, ShowID: showID, DeletedAt: now}
+		if err := writeEvent(tx, events.TypeBookingDeleted, bookingID, deletion); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM bookings WHERE booking_id = ?", bookingID); err != nil {
+			return fmt.Errorf("failed to delete booking: %w", err)
+		}
+
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	// Remove from cache
 	r.removeCachedBooking(bookingID)
 
-	return nil
+	return inventory, nil
+}
+
+// GetExpiredHolds returns pending bookings whose hold lapsed at or before now,
+// oldest first
+func (r *BookingRepository) GetExpiredHolds(now time.Time, limit int) ([]*bookings.Booking, error) {
+	query := `
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at
+		FROM bookings 
+		WHERE status = ? AND hold_expires_at IS NOT NULL AND hold_expires_at <= ?
+		ORDER BY hold_expires_at
+		LIMIT ?
+	`
+
+	return r.executeBookingQuery(query, bookings.StatusPending, now, limit)
+}
+
+// ExpireHold moves a lapsed pending booking to expired and recounts the show's
+// booked_tickets in the same transaction. It reports false without changing
+// anything when the booking was confirmed or cancelled in the meantime.
+func (r *BookingRepository) ExpireHold(bookingID string, now time.Time, changedBy string) (*ShowInventory, bool, error) {
+	var inventory *ShowInventory
+	expired := false
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		showID, err := bookingShowID(tx, bookingID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE bookings SET status = ?, updated_at = ?
+			WHERE booking_id = ? AND status = ? AND hold_expires_at IS NOT NULL AND hold_expires_at <= ?
+		`
+		result, err := tx.Exec(query, bookings.StatusExpired, now, bookingID, bookings.StatusPending, now)
+		if err != nil {
+			return fmt.Errorf("failed to expire booking hold: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		if rowsAffected == 0 {
+			return nil
+		}
+		expired = true
+
+		if err := releaseSeats(tx, bookingID); err != nil {
+			return err
+		}
+		if err := releaseTickets(tx, bookingID, now); err != nil {
+			return err
+		}
+
+		if err := settleWaitlistOffer(tx, bookingID, waitlist.StatusExpired, now); err != nil {
+			return err
+		}
+
+		if err := settleOrderExpiry(tx, bookingID, now); err != nil {
+			return err
+		}
+
+		err = insertStatusChange(tx, &bookings.StatusChange{
+			BookingID:  bookingID,
+			FromStatus: bookings.StatusPending,
+			ToStatus:   bookings.StatusExpired,
+			ChangedBy:  changedBy,
+			Reason:     "hold expired",
+			ChangedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if expired {
+		if cachedBooking, err := r.getBookingFromCache(bookingID); err == nil {
+			cachedBooking.UpdateStatus(bookings.StatusExpired)
+			r.cacheBooking(cachedBooking)
+		}
+	}
+
+	return inventory, expired, nil
+}
+
+// GetStatusHistory returns a booking's status changes, oldest first
+func (r *BookingRepository) GetStatusHistory(bookingID string) ([]*bookings.StatusChange, error) {
+	query := `
+		SELECT id, booking_id, COALESCE(from_status, ''), to_status, changed_by, COALESCE(reason, ''), changed_at
+		FROM booking_status_history
+		WHERE booking_id = ?
+		ORDER BY changed_at, id
+	`
+
+	rows, err := r.database.GetDB().Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*bookings.StatusChange{}
+	for rows.Next() {
+		change := &bookings.StatusChange{}
+		err := rows.Scan(
+			&change.ID,
+			&change.BookingID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.ChangedBy,
+			&change.Reason,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		history = append(history, change)
+	}
+
+	return history, nil
+}
+
+// GetAmendments returns a booking's amendments, oldest first
+func (r *BookingRepository) GetAmendments(bookingID string) ([]*bookings.Amendment, error) {
+	query := `
+		SELECT booking_id, version, COALESCE(ticket_type, ''), from_tickets, to_tickets, from_amount, to_amount,
+			changed_by, COALESCE(reason, ''), created_at
+		FROM booking_amendments
+		WHERE booking_id = ?
+		ORDER BY version
+	`
+
+	rows, err := r.database.GetDB().Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query amendments: %w", err)
+	}
+	defer rows.Close()
+
+	amendments := []*bookings.Amendment{}
+	for rows.Next() {
+		amendment := &bookings.Amendment{}
+		err := rows.Scan(
+			&amendment.BookingID,
+			&amendment.Version,
+			&amendment.TicketType,
+			&amendment.FromTickets,
+			&amendment.ToTickets,
+			&amendment.FromAmount,
+			&amendment.ToAmount,
+			&amendment.ChangedBy,
+			&amendment.Reason,
+			&amendment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan amendment: %w", err)
+		}
+		amendment.AmountDelta = amendment.ToAmount - amendment.FromAmount
+		amendments = append(amendments, amendment)
+	}
+
+	return amendments, rows.Err()
 }
 
 // GetBookingsByShow retrieves all bookings for a specific show
 func (r *BookingRepository) GetBookingsByShow(showID uuid.UUID) ([]*bookings.Booking, error) {
 	query := `
-		SELECT booking_id, show_id, contact_type, contact_value, number_of_tickets, 
-			customer_name, total_amount, booking_date, status, created_at, updated_at
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at
 		FROM bookings 
 		WHERE show_id = ?
 		ORDER BY created_at DESC
@@ -220,8 +824,8 @@ func (r *BookingRepository) GetBookingsByShow(showID uuid.UUID) ([]*bookings.Boo
 // GetBookingsByContact retrieves bookings by contact information
 func (r *BookingRepository) GetBookingsByContact(contactType, contactValue string) ([]*bookings.Booking, error) {
 	query := `
-		SELECT booking_id, show_id, contact_type, contact_value, number_of_tickets, 
-			customer_name, total_amount, booking_date, status, created_at, updated_at
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at
 		FROM bookings 
 		WHERE contact_type = ? AND contact_value = ?
 		ORDER BY created_at DESC
@@ -230,6 +834,19 @@ func (r *BookingRepository) GetBookingsByContact(contactType, contactValue strin
 	return r.executeBookingQuery(query, contactType, contactValue)
 }
 
+// GetBookingsByUser retrieves the bookings made by a customer account, newest first
+func (r *BookingRepository) GetBookingsByUser(userID string) ([]*bookings.Booking, error) {
+	query := `
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at
+		FROM bookings 
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+
+	return r.executeBookingQuery(query, userID)
+}
+
 // GetBookingsWithFilters retrieves bookings with optional filtering and pagination
 func (r *BookingRepository) GetBookingsWithFilters(filter *bookings.BookingFilter) ([]*bookings.Booking, int, error) {
 	// Build the WHERE clause dynamically based on filters
@@ -267,8 +884,8 @@ func (r *BookingRepository) GetBookingsWithFilters(filter *bookings.BookingFilte
 	baseQuery := "FROM bookings"
 	countQuery := "SELECT COUNT(*) " + baseQuery
 	selectQuery := `
-		SELECT booking_id, show_id, contact_type, contact_value, number_of_tickets, 
-			customer_name, total_amount, booking_date, status, created_at, updated_at ` + baseQuery
+		SELECT booking_id, COALESCE(reference, ''), show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, COALESCE(promo_code, ''), discount_amount, COALESCE(order_id, ''), COALESCE(user_id, ''), balance_due, booking_date, status, hold_expires_at, created_at, updated_at ` + baseQuery
 
 	if len(whereConditions) > 0 {
 		whereClause := " WHERE " + strings.Join(whereConditions, " AND ")
@@ -305,17 +922,25 @@ func (r *BookingRepository) GetBookingsWithFilters(filter *bookings.BookingFilte
 	for rows.Next() {
 		booking := &bookings.Booking{}
 		var showIDStr string
+		var holdExpiresAt sql.NullTime
 
 		err := rows.Scan(
 			&booking.BookingID,
+			&booking.Reference,
 			&showIDStr,
 			&booking.ContactType,
 			&booking.ContactValue,
 			&booking.NumberOfTickets,
 			&booking.CustomerName,
 			&booking.TotalAmount,
+			&booking.PromoCode,
+			&booking.DiscountAmount,
+			&booking.OrderID,
+			&booking.UserID,
+			&booking.BalanceDue,
 			&booking.BookingDate,
 			&booking.Status,
+			&holdExpiresAt,
 			&booking.CreatedAt,
 			&booking.UpdatedAt,
 		)
@@ -331,6 +956,7 @@ func (r *BookingRepository) GetBookingsWithFilters(filter *bookings.BookingFilte
 			continue
 		}
 		booking.ShowID = showID
+		booking.HoldExpiresAt = nullableTime(holdExpiresAt)
 
 		bookingsList = append(bookingsList, booking)
 
@@ -457,17 +1083,25 @@ func (r *BookingRepository) executeBookingQuery(query string, args ...interface{
 	for rows.Next() {
 		booking := &bookings.Booking{}
 		var showIDStr string
+		var holdExpiresAt sql.NullTime
 
 		err := rows.Scan(
 			&booking.BookingID,
+			&booking.Reference,
 			&showIDStr,
 			&booking.ContactType,
 			&booking.ContactValue,
 			&booking.NumberOfTickets,
 			&booking.CustomerName,
 			&booking.TotalAmount,
+			&booking.PromoCode,
+			&booking.DiscountAmount,
+			&booking.OrderID,
+			&booking.UserID,
+			&booking.BalanceDue,
 			&booking.BookingDate,
 			&booking.Status,
+			&holdExpiresAt,
 			&booking.CreatedAt,
 			&booking.UpdatedAt,
 		)
@@ -483,6 +1117,7 @@ func (r *BookingRepository) executeBookingQuery(query string, args ...interface{
 			continue
 		}
 		booking.ShowID = showID
+		booking.HoldExpiresAt = nullableTime(holdExpiresAt)
 
 		bookingsList = append(bookingsList, booking)
 	}
@@ -492,32 +1127,490 @@ func (r *BookingRepository) executeBookingQuery(query string, args ...interface{
 
 // GetTicketsSoldForShow returns the total number of tickets sold for a specific show
 func (r *BookingRepository) GetTicketsSoldForShow(showID uuid.UUID) (int32, error) {
+	return ticketsSoldForShow(r.database.GetDB(), showID)
+}
+
+// ValidateBookingCapacity checks if a booking can be made without exceeding show capacity.
+// This is an advisory check only; ReserveBooking repeats it under a row lock.
+func (r *BookingRepository) ValidateBookingCapacity(showID uuid.UUID, requestedTickets int32, showTotalTickets int32) error {
+	ticketsSold, err := r.GetTicketsSoldForShow(showID)
+	if err != nil {
+		return err
+	}
+
+	availableTickets := showTotalTickets - ticketsSold
+	if requestedTickets > availableTickets {
+		return fmt.Errorf("insufficient tickets available. Requested: %d, Available: %d", requestedTickets, availableTickets)
+	}
+
+	return nil
+}
+
+// GetShowInventories compares each show's recorded booked_tickets counter
+// with the tickets actually held by its active bookings, for every show or
+// only the given one
+func (r *BookingRepository) GetShowInventories(showID *uuid.UUID) ([]*ShowInventoryRecord, error) {
+	args := []interface{}{time.Now()}
+	showFilter := ""
+	if showID != nil {
+		showFilter = "WHERE s.id = ?"
+		args = append(args, showID.String())
+	}
+
+	query := `
+		SELECT s.id, s.name, s.total_tickets, COALESCE(s.booked_tickets, 0),
+			COALESCE(SUM(b.number_of_tickets), 0)
+		FROM shows s
+		LEFT JOIN bookings b ON b.show_id = s.id AND ` + activeBookingFilter("b.") + `
+		` + showFilter + `
+		GROUP BY s.id, s.name, s.total_tickets, s.booked_tickets
+		ORDER BY s.name
+	`
+
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query show inventories: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*ShowInventoryRecord
+	for rows.Next() {
+		record := &ShowInventoryRecord{}
+		var showIDStr string
+
+		err := rows.Scan(
+			&showIDStr,
+			&record.ShowName,
+			&record.TotalTickets,
+			&record.RecordedBookedTickets,
+			&record.TicketsSold,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan show inventory: %w", err)
+		}
+
+		showID, err := uuid.Parse(showIDStr)
+		if err != nil {
+			log.Printf("Warning: invalid show ID in database: %s", showIDStr)
+			continue
+		}
+		record.ShowID = showID
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// RecountShow rewrites a show's booked_tickets from its active bookings
+func (r *BookingRepository) RecountShow(showID uuid.UUID) (*ShowInventory, error) {
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		var err error
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// TicketTypeSales is what active bookings of a show hold of one ticket type
+type TicketTypeSales struct {
+	TicketsSold int32
+	Revenue     int32
+}
+
+// GetTicketTypeSales returns tickets sold and revenue per ticket type for a
+// show's active bookings, using the unit prices the bookings were made at
+func (r *BookingRepository) GetTicketTypeSales(showID uuid.UUID) (map[string]*TicketTypeSales, error) {
+	query := `
+		SELECT l.ticket_type, COALESCE(SUM(l.quantity), 0), COALESCE(SUM(l.quantity * l.unit_price), 0)
+		FROM booking_ticket_lines l
+		JOIN bookings b ON b.booking_id = l.booking_id
+		WHERE b.show_id = ? AND ` + activeBookingFilter("b.") + `
+		GROUP BY l.ticket_type
+	`
+
+	rows, err := r.database.GetDB().Query(query, showID.String(), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ticket type sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := make(map[string]*TicketTypeSales)
+	for rows.Next() {
+		var code string
+		record := &TicketTypeSales{}
+		if err := rows.Scan(&code, &record.TicketsSold, &record.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket type sales: %w", err)
+		}
+		sales[code] = record
+	}
+
+	return sales, nil
+}
+
+// ShowInventoryRecord pairs a show's recorded booked_tickets with the
+// authoritative count derived from bookings
+type ShowInventoryRecord struct {
+	ShowID                uuid.UUID
+	ShowName              string
+	TotalTickets          int32
+	RecordedBookedTickets int32
+	TicketsSold           int32
+}
+
+// Transaction helpers
+
+// activeBookingFilter is the SQL condition for bookings that hold inventory:
+// bookings.HoldsInventory statuses, except pending bookings whose hold has lapsed.
+// The prefix is the table alias, e.g. "b." or "" for an unaliased query.
+// The condition takes one argument, the current time; it is compared in Go
+// time rather than NOW() so it matches the hold_expires_at values we write.
+func activeBookingFilter(prefix string) string {
+	return fmt.Sprintf(
+		"(%[1]sstatus IN ('confirmed', 'checked_in', 'no_show') OR (%[1]sstatus = 'pending' AND (%[1]shold_expires_at IS NULL OR %[1]shold_expires_at > ?)))",
+		prefix,
+	)
+}
+
+// bookingShowID returns the show a booking belongs to
+func bookingShowID(tx *sql.Tx, bookingID string) (uuid.UUID, error) {
+	var showIDStr string
+	err := tx.QueryRow(`SELECT show_id FROM bookings WHERE booking_id = ?`, bookingID).Scan(&showIDStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, fmt.Errorf("booking not found: %s", bookingID)
+		}
+		return uuid.Nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	showID, err := uuid.Parse(showIDStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid show ID in database: %w", err)
+	}
+	return showID, nil
+}
+
+// lockBookingState takes a row lock on the booking and loads the fields that
+// govern status changes
+func lockBookingState(tx *sql.Tx, bookingID string) (*bookings.Booking, error) {
+	booking := &bookings.Booking{BookingID: bookingID}
+	var holdExpiresAt sql.NullTime
+
+	query := `SELECT status, number_of_tickets, hold_expires_at, COALESCE(order_id, ''), balance_due FROM bookings WHERE booking_id = ? FOR UPDATE`
+	if err := tx.QueryRow(query, bookingID).Scan(&booking.Status, &booking.NumberOfTickets, &holdExpiresAt, &booking.OrderID, &booking.BalanceDue); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("booking not found: %s", bookingID)
+		}
+		return nil, fmt.Errorf("failed to lock booking: %w", err)
+	}
+	booking.HoldExpiresAt = nullableTime(holdExpiresAt)
+
+	return booking, nil
+}
+
+// recountShow locks the show and rewrites booked_tickets from its active bookings
+func recountShow(tx *sql.Tx, showID uuid.UUID) (*ShowInventory, error) {
+	totalTickets, err := lockShow(tx, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketsSold, err := ticketsSoldForShow(tx, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setBookedTickets(tx, showID, ticketsSold); err != nil {
+		return nil, err
+	}
+
+	return &ShowInventory{
+		ShowID:        showID,
+		TotalTickets:  totalTickets,
+		BookedTickets: ticketsSold,
+	}, nil
+}
+
+// lockShow takes a row lock on the show and returns its total capacity
+func lockShow(tx *sql.Tx, showID uuid.UUID) (int32, error) {
+	var totalTickets int32
+	err := tx.QueryRow(`SELECT total_tickets FROM shows WHERE id = ? FOR UPDATE`, showID.String()).Scan(&totalTickets)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("show not found: %s", showID.String())
+		}
+		return 0, fmt.Errorf("failed to lock show: %w", err)
+	}
+	return totalTickets, nil
+}
+
+// ticketsSoldForShow sums the tickets held by active bookings of a show
+func ticketsSoldForShow(exec sqlExecutor, showID uuid.UUID) (int32, error) {
 	query := `
 		SELECT COALESCE(SUM(number_of_tickets), 0) 
 		FROM bookings 
-		WHERE show_id = ? AND status IN ('confirmed', 'pending')
-	`
+		WHERE show_id = ? AND ` + activeBookingFilter("")
 
 	var ticketsSold int32
-	err := r.database.GetDB().QueryRow(query, showID.String()).Scan(&ticketsSold)
-	if err != nil {
+	if err := exec.QueryRow(query, showID.String(), time.Now()).Scan(&ticketsSold); err != nil {
 		return 0, fmt.Errorf("failed to get tickets sold: %w", err)
 	}
-
 	return ticketsSold, nil
 }
 
-// ValidateBookingCapacity checks if a booking can be made without exceeding show capacity
-func (r *BookingRepository) ValidateBookingCapacity(showID uuid.UUID, requestedTickets int32, showTotalTickets int32) error {
-	ticketsSold, err := r.GetTicketsSoldForShow(showID)
+// setBookedTickets writes an absolute booked_tickets value. Writing the value
+// computed from bookings (rather than incrementing) keeps the counter correct.
+func setBookedTickets(exec sqlExecutor, showID uuid.UUID, bookedTickets int32) error {
+	_, err := exec.Exec(`UPDATE shows SET booked_tickets = ? WHERE id = ?`, bookedTickets, showID.String())
+	if err != nil {
+		return fmt.Errorf("failed to update booked tickets: %w", err)
+	}
+	return nil
+}
+
+// insertStatusChange appends an entry to booking_status_history and, for
+// changes after the initial status, writes a booking.status_changed event
+func insertStatusChange(exec sqlExecutor, change *bookings.StatusChange) error {
+	query := `
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, changed_by, reason, changed_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?)
+	`
+
+	_, err := exec.Exec(query,
+		change.BookingID,
+		change.FromStatus,
+		change.ToStatus,
+		change.ChangedBy,
+		change.Reason,
+		change.ChangedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	// New bookings are announced by booking.created
+	if change.FromStatus == "" {
+		return nil
+	}
+	return writeBookingStatusChanged(exec, change)
+}
+
+// checkTicketTypeQuotas verifies, under the show lock, that a booking's ticket
+// lines match the show's current ticket types and fit within each tier's quota
+func checkTicketTypeQuotas(tx *sql.Tx, booking *bookings.Booking) error {
+	line, available, err := ticketTypeShortfall(tx, booking.ShowID, booking.TicketLines)
 	if err != nil {
 		return err
 	}
+	if line != nil {
+		return fmt.Errorf("insufficient tickets available for ticket type %s. Requested: %d, Available: %d",
+			line.TicketType, line.Quantity, available)
+	}
+	return nil
+}
 
-	availableTickets := showTotalTickets - ticketsSold
-	if requestedTickets > availableTickets {
-		return fmt.Errorf("insufficient tickets available. Requested: %d, Available: %d", requestedTickets, availableTickets)
+// ticketTypeShortfall returns the first ticket line whose tier quota cannot
+// cover it, along with the tickets left in that tier, or nil when every line
+// fits. Lines that do not match the show's ticket types are an error.
+func ticketTypeShortfall(tx *sql.Tx, showID uuid.UUID, lines []*bookings.TicketLine) (*bookings.TicketLine, int32, error) {
+	ticketTypes, err := ticketTypesForShow(tx, showID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(ticketTypes) == 0 {
+		if len(lines) > 0 {
+			return nil, 0, fmt.Errorf("invalid ticket selection: show %s does not offer ticket types", showID.String())
+		}
+		return nil, 0, nil
+	}
+	if len(lines) == 0 {
+		return nil, 0, fmt.Errorf("invalid ticket selection: ticket types are required for show %s", showID.String())
+	}
+
+	quotas := make(map[string]*int32, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		quotas[ticketType.Code] = ticketType.Quota
+	}
+
+	sold, err := ticketTypeSales(tx, showID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, line := range lines {
+		quota, offered := quotas[line.TicketType]
+		if !offered {
+			return nil, 0, fmt.Errorf("invalid ticket selection: unknown ticket type %s", line.TicketType)
+		}
+		if quota == nil {
+			continue
+		}
+		if available := *quota - sold[line.TicketType]; line.Quantity > available {
+			return line, available, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// updateTicketLine writes an amended booking's quantity of one ticket type,
+// removing the line once none are left
+func updateTicketLine(exec sqlExecutor, booking *bookings.Booking, ticketType string) error {
+	for _, line := range booking.TicketLines {
+		if line.TicketType != ticketType {
+			continue
+		}
+		query := `UPDATE booking_ticket_lines SET quantity = ? WHERE booking_id = ? AND ticket_type = ?`
+		if _, err := exec.Exec(query, line.Quantity, booking.BookingID, ticketType); err != nil {
+			return fmt.Errorf("failed to update ticket line: %w", err)
+		}
+		return nil
 	}
 
+	query := `DELETE FROM booking_ticket_lines WHERE booking_id = ? AND ticket_type = ?`
+	if _, err := exec.Exec(query, booking.BookingID, ticketType); err != nil {
+		return fmt.Errorf("failed to remove ticket line: %w", err)
+	}
+	return nil
+}
+
+// bookingVersion returns the version of a locked booking: the number of its
+// last amendment, or 0 when it has never been amended
+func bookingVersion(exec sqlExecutor, bookingID string) (int32, error) {
+	var version int32
+	query := `SELECT COALESCE(MAX(version), 0) FROM booking_amendments WHERE booking_id = ?`
+	if err := exec.QueryRow(query, bookingID).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get booking version: %w", err)
+	}
+	return version, nil
+}
+
+// insertAmendment records an amendment as a version of its booking
+func insertAmendment(exec sqlExecutor, amendment *bookings.Amendment) error {
+	query := `
+		INSERT INTO booking_amendments (booking_id, version, ticket_type, from_tickets, to_tickets, from_amount, to_amount, changed_by, reason, created_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+	_, err := exec.Exec(query,
+		amendment.BookingID,
+		amendment.Version,
+		amendment.TicketType,
+		amendment.FromTickets,
+		amendment.ToTickets,
+		amendment.FromAmount,
+		amendment.ToAmount,
+		amendment.ChangedBy,
+		amendment.Reason,
+		amendment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record amendment: %w", err)
+	}
+	return nil
+}
+
+// insertTicketLines stores a booking's per-type breakdown and unit price snapshot
+func insertTicketLines(exec sqlExecutor, bookingID string, lines []*bookings.TicketLine) error {
+	query := `INSERT INTO booking_ticket_lines (booking_id, ticket_type, quantity, unit_price) VALUES (?, ?, ?, ?)`
+	for _, line := range lines {
+		if _, err := exec.Exec(query, bookingID, line.TicketType, line.Quantity, line.UnitPrice); err != nil {
+			return fmt.Errorf("failed to save ticket line %s: %w", line.TicketType, err)
+		}
+	}
 	return nil
 }
+
+// bookingTicketLines returns a booking's per-type breakdown
+func bookingTicketLines(exec sqlExecutor, bookingID string) ([]*bookings.TicketLine, error) {
+	query := `SELECT ticket_type, quantity, unit_price FROM booking_ticket_lines WHERE booking_id = ? ORDER BY ticket_type`
+	rows, err := exec.Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ticket lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []*bookings.TicketLine
+	for rows.Next() {
+		line := &bookings.TicketLine{}
+		if err := rows.Scan(&line.TicketType, &line.Quantity, &line.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func nullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// insertBooking saves a booking, assigning its customer-facing reference.
+// A reference that collides with an existing one is replaced and the insert
+// retried; MySQL only rolls back the failed statement, so this is safe inside
+// a transaction.
+func insertBooking(exec sqlExecutor, booking *bookings.Booking) error {
+	query := `
+		INSERT INTO bookings (booking_id, reference, show_id, contact_type, contact_value, number_of_tickets, 
+			customer_name, total_amount, promo_code, discount_amount, order_id, user_id, balance_due, booking_date, status, hold_expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)
+	`
+
+	for attempt := 1; ; attempt++ {
+		if booking.Reference == "" {
+			reference, err := bookings.NewReference()
+			if err != nil {
+				return err
+			}
+			booking.Reference = reference
+		}
+
+		_, err := exec.Exec(query,
+			booking.BookingID,
+			booking.Reference,
+			booking.ShowID.String(),
+			booking.ContactType,
+			booking.ContactValue,
+			booking.NumberOfTickets,
+			booking.CustomerName,
+			booking.TotalAmount,
+			booking.PromoCode,
+			booking.DiscountAmount,
+			booking.OrderID,
+			booking.UserID,
+			booking.BalanceDue,
+			booking.BookingDate,
+			booking.Status,
+			booking.HoldExpiresAt,
+			booking.CreatedAt,
+			booking.UpdatedAt,
+		)
+		if err == nil {
+			return writeEvent(exec, events.TypeBookingCreated, booking.BookingID, booking)
+		}
+
+		if isDuplicateReference(err) && attempt < maxReferenceAttempts {
+			log.Printf("Booking reference %s already taken, generating another", booking.Reference)
+			booking.Reference = ""
+			continue
+		}
+		return fmt.Errorf("failed to create booking: %w", err)
+	}
+}
+
+// maxReferenceAttempts bounds how many references insertBooking tries
+const maxReferenceAttempts = 5
+
+// isDuplicateReference reports whether an insert failed because the booking
+// reference is already in use
+func isDuplicateReference(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry") && strings.Contains(err.Error(), "uq_bookings_reference")
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/gsmayya/theater/db"
+)
+
+// contactTables are the tables that store a customer contact, with the
+// primary key column of each
+var contactTables = []struct {
+	name string
+	key  string
+}{
+	{"bookings", "booking_id"},
+	{"orders", "order_id"},
+	{"waitlist_entries", "id"},
+}
+
+// ContactRecord is the contact stored on one row of a contact table
+type ContactRecord struct {
+	ID           string
+	ContactType  string
+	ContactValue string
+}
+
+// ContactRepository reads and rewrites stored contacts across every table
+// that keeps one, for maintenance such as normalizing old rows
+type ContactRepository struct {
+	database          *db.Database
+	bookingRepository *BookingRepository
+}
+
+func NewContactRepository() *ContactRepository {
+	return &ContactRepository{
+		database:          db.GetDatabase(),
+		bookingRepository: NewBookingRepository(),
+	}
+}
+
+// ContactTables lists the tables that store customer contacts
+func ContactTables() []string {
+	tables := make([]string, len(contactTables))
+	for i, table := range contactTables {
+		tables[i] = table.name
+	}
+	return tables
+}
+
+// contactTableKey returns the primary key column of a contact table
+func contactTableKey(table string) (string, error) {
+	for _, t := range contactTables {
+		if t.name == table {
+			return t.key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown contact table: %s", table)
+}
+
+// ListContacts returns up to limit contacts from a table in primary key
+// order, starting after the given key ("" for the first page)
+func (r *ContactRepository) ListContacts(table, afterID string, limit int) ([]*ContactRecord, error) {
+	key, err := contactTableKey(table)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, contact_type, contact_value
+		FROM %s
+		WHERE %s > ?
+		ORDER BY %s
+		LIMIT ?
+	`, key, table, key, key)
+
+	rows, err := r.database.GetDB().Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s contacts: %w", table, err)
+	}
+	defer rows.Close()
+
+	var records []*ContactRecord
+	for rows.Next() {
+		record := &ContactRecord{}
+		if err := rows.Scan(&record.ID, &record.ContactType, &record.ContactValue); err != nil {
+			return nil, fmt.Errorf("failed to scan %s contact: %w", table, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// UpdateContactValue rewrites a row's contact value, provided it still holds
+// the value it was read with. It reports whether the row was changed. The
+// row's updated_at is kept, since the customer's details did not change.
+func (r *ContactRepository) UpdateContactValue(table, id, from, to string) (bool, error) {
+	key, err := contactTableKey(table)
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET contact_value = ?, updated_at = updated_at
+		WHERE %s = ? AND contact_value = ?
+	`, table, key)
+
+	result, err := r.database.GetDB().Exec(query, to, id, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s contact: %w", table, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	// Cached bookings carry their contact
+	if rowsAffected > 0 && table == "bookings" {
+		r.bookingRepository.removeCachedBooking(id)
+	}
+
+	return rowsAffected > 0, nil
+}
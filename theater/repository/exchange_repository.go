@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/payments"
+)
+
+// exchangeColumns lists the booking_exchanges columns in the order scanExchange reads them
+const exchangeColumns = `exchange_id, source_booking_id, target_booking_id, source_show_id, target_show_id,
+	number_of_tickets, source_amount, target_amount, changed_by, COALESCE(reason, ''), created_at`
+
+type ExchangeRepository struct {
+	database          *db.Database
+	bookingRepository *BookingRepository
+}
+
+func NewExchangeRepository() *ExchangeRepository {
+	return &ExchangeRepository{
+		database:          db.GetDatabase(),
+		bookingRepository: NewBookingRepository(),
+	}
+}
+
+// ExchangeBooking moves the selected tickets of a pending or confirmed
+// booking to a new booking on another show, as one transaction. Both shows
+// are locked up front. build turns the tickets taken off the source booking
+// into the priced target booking, which is then reserved like a new booking,
+// capacity checks included. The source booking ends up exchanged when every
+// ticket moved; otherwise it keeps the rest and the change is recorded as its
+// next amendment. refundFor, when set, returns the refund owed for the
+// exchange, which is added to the ledger in the same transaction. It returns
+// the exchange and the inventory of the source and target shows.
+func (r *ExchangeRepository) ExchangeBooking(bookingID string, targetShowID uuid.UUID, selection bookings.ExchangeSelection,
+	build func(moved *bookings.Booking) (*bookings.Booking, error), refundFor func(exchange *bookings.Exchange) (*payments.Refund, error),
+	changedBy, reason string) (*bookings.Exchange, *bookings.Booking, []*ShowInventory, error) {
+	var exchange *bookings.Exchange
+	var target *bookings.Booking
+	var inventories []*ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		sourceShowID, err := bookingShowID(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if sourceShowID == targetShowID {
+			return fmt.Errorf("invalid exchange: booking %s is already for show %s", bookingID, targetShowID.String())
+		}
+
+		// Lock both shows in a consistent order before the booking, as orders do
+		if err := lockShows(tx, []uuid.UUID{sourceShowID, targetShowID}); err != nil {
+			return err
+		}
+
+		source, err := lockBookingState(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if source.OrderID != "" {
+			return fmt.Errorf("invalid exchange: booking %s belongs to order %s", bookingID, source.OrderID)
+		}
+		if source.BalanceDue > 0 {
+			return fmt.Errorf("invalid exchange: booking %s has a balance of %d due; pay it first", bookingID, source.BalanceDue)
+		}
+
+		now := time.Now()
+		switch {
+		case source.IsHoldExpired(now):
+			return bookings.ErrHoldExpired
+		case source.Status != bookings.StatusPending && source.Status != bookings.StatusConfirmed:
+			return fmt.Errorf("invalid exchange: booking %s is %s", bookingID, source.Status)
+		}
+
+		if err := loadExchangeSource(tx, source, sourceShowID); err != nil {
+			return err
+		}
+
+		before := *source
+		moved, all, err := source.SplitTickets(selection)
+		if err != nil {
+			return err
+		}
+		if !all && len(source.Seats) > 0 {
+			return fmt.Errorf("invalid exchange: booking %s has reserved seats; exchange every ticket or void the rest first", bookingID)
+		}
+
+		target, err = build(moved)
+		if err != nil {
+			return err
+		}
+
+		targetInventory, err := reserveBookingTx(tx, target, changedBy)
+		if err != nil {
+			return err
+		}
+
+		exchange, err = bookings.NewExchange(source, moved, target, changedBy, reason)
+		if err != nil {
+			return err
+		}
+		exchange.CreatedAt = now
+
+		note := fmt.Sprintf("exchanged to %s", target.BookingID)
+		if reason != "" {
+			note += ": " + reason
+		}
+		if all {
+			err = exchangeWholeBooking(tx, source, changedBy, note, now)
+		} else {
+			err = exchangePartOfBooking(tx, &before, source, moved, changedBy, note, now)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := insertExchange(tx, exchange); err != nil {
+			return err
+		}
+
+		if refundFor != nil {
+			refund, err := refundFor(exchange)
+			if err != nil {
+				return err
+			}
+			if refund != nil {
+				if err := insertRefund(tx, refund); err != nil {
+					return err
+				}
+			}
+		}
+
+		sourceInventory, err := recountShow(tx, sourceShowID)
+		if err != nil {
+			return err
+		}
+		inventories = []*ShowInventory{sourceInventory, targetInventory}
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The source booking's status or ticket count is stale in the cache now
+	r.bookingRepository.removeCachedBooking(bookingID)
+	r.bookingRepository.cacheBooking(target)
+
+	log.Printf("Booking %s exchanged %d tickets to %s on show %s",
+		bookingID, exchange.NumberOfTickets, target.BookingID, targetShowID.String())
+	return exchange, target, inventories, nil
+}
+
+// GetExchangesByBooking returns the exchanges a booking gave tickets to or received them from, oldest first
+func (r *ExchangeRepository) GetExchangesByBooking(bookingID string) ([]*bookings.Exchange, error) {
+	query := `SELECT ` + exchangeColumns + ` FROM booking_exchanges
+		WHERE source_booking_id = ? OR target_booking_id = ?
+		ORDER BY created_at, exchange_id`
+	return r.queryExchanges(query, bookingID, bookingID)
+}
+
+// GetExchangesByShow returns the exchanges into or out of a show, oldest first
+func (r *ExchangeRepository) GetExchangesByShow(showID uuid.UUID) ([]*bookings.Exchange, error) {
+	query := `SELECT ` + exchangeColumns + ` FROM booking_exchanges
+		WHERE source_show_id = ? OR target_show_id = ?
+		ORDER BY created_at, exchange_id`
+	return r.queryExchanges(query, showID.String(), showID.String())
+}
+
+func (r *ExchangeRepository) queryExchanges(query string, args ...interface{}) ([]*bookings.Exchange, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchanges: %w", err)
+	}
+	defer rows.Close()
+
+	exchanges := make([]*bookings.Exchange, 0)
+	for rows.Next() {
+		exchange, err := scanExchange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan exchange: %w", err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges, rows.Err()
+}
+
+// loadExchangeSource fills in the parts of a locked booking an exchange
+// needs: who it is for and whose account it is in, its amounts, ticket lines and seats
+func loadExchangeSource(tx *sql.Tx, source *bookings.Booking, showID uuid.UUID) error {
+	source.ShowID = showID
+
+	query := `
+		SELECT contact_type, contact_value, COALESCE(customer_name, ''), total_amount, discount_amount, COALESCE(user_id, '')
+		FROM bookings WHERE booking_id = ?
+	`
+	err := tx.QueryRow(query, source.BookingID).Scan(
+		&source.ContactType,
+		&source.ContactValue,
+		&source.CustomerName,
+		&source.TotalAmount,
+		&source.DiscountAmount,
+		&source.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load booking: %w", err)
+	}
+
+	source.TicketLines, err = bookingTicketLines(tx, source.BookingID)
+	if err != nil {
+		return err
+	}
+
+	source.Seats, err = bookingSeats(tx, source.BookingID)
+	return err
+}
+
+// exchangeWholeBooking moves a booking whose every ticket was exchanged to
+// exchanged, giving its seats and tickets back to the show
+func exchangeWholeBooking(tx *sql.Tx, source *bookings.Booking, changedBy, note string, now time.Time) error {
+	fromStatus := source.Status
+	if err := source.CheckStatusChange(bookings.StatusExchanged, now); err != nil {
+		return err
+	}
+	source.UpdateStatus(bookings.StatusExchanged)
+
+	if err := releaseSeats(tx, source.BookingID); err != nil {
+		return err
+	}
+	if err := releaseTickets(tx, source.BookingID, now); err != nil {
+		return err
+	}
+
+	query := `UPDATE bookings SET status = ?, hold_expires_at = ?, updated_at = ? WHERE booking_id = ?`
+	if _, err := tx.Exec(query, source.Status, source.HoldExpiresAt, now, source.BookingID); err != nil {
+		return fmt.Errorf("failed to update booking status: %w", err)
+	}
+
+	return insertStatusChange(tx, &bookings.StatusChange{
+		BookingID:  source.BookingID,
+		FromStatus: fromStatus,
+		ToStatus:   bookings.StatusExchanged,
+		ChangedBy:  changedBy,
+		Reason:     note,
+		ChangedAt:  now,
+	})
+}
+
+// exchangePartOfBooking writes the smaller source booking left by a partial
+// exchange, voids the tickets that moved and records the change as the
+// booking's next amendment
+func exchangePartOfBooking(tx *sql.Tx, before, source, moved *bookings.Booking, changedBy, note string, now time.Time) error {
+	query := `
+		UPDATE bookings SET number_of_tickets = ?, total_amount = ?, discount_amount = ?, updated_at = ?
+		WHERE booking_id = ?
+	`
+	_, err := tx.Exec(query, source.NumberOfTickets, source.TotalAmount, source.DiscountAmount, now, source.BookingID)
+	if err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	if len(moved.TicketLines) == 0 {
+		if err := voidAmendedTickets(tx, source.BookingID, "", moved.NumberOfTickets, note, now); err != nil {
+			return err
+		}
+	}
+	for _, line := range moved.TicketLines {
+		if err := updateTicketLine(tx, source, line.TicketType); err != nil {
+			return err
+		}
+		if err := voidAmendedTickets(tx, source.BookingID, line.TicketType, line.Quantity, note, now); err != nil {
+			return err
+		}
+	}
+
+	version, err := bookingVersion(tx, source.BookingID)
+	if err != nil {
+		return err
+	}
+
+	ticketType := ""
+	if len(moved.TicketLines) == 1 {
+		ticketType = moved.TicketLines[0].TicketType
+	}
+	amendment := bookings.NewAmendment(before, source, version+1, ticketType, changedBy, note)
+	amendment.CreatedAt = now
+	return insertAmendment(tx, amendment)
+}
+
+// insertExchange records the link between an exchanged booking and its replacement
+func insertExchange(exec sqlExecutor, exchange *bookings.Exchange) error {
+	query := `
+		INSERT INTO booking_exchanges (exchange_id, source_booking_id, target_booking_id, source_show_id, target_show_id,
+			number_of_tickets, source_amount, target_amount, changed_by, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+	_, err := exec.Exec(query,
+		exchange.ExchangeID,
+		exchange.SourceBookingID,
+		exchange.TargetBookingID,
+		exchange.SourceShowID.String(),
+		exchange.TargetShowID.String(),
+		exchange.NumberOfTickets,
+		exchange.SourceAmount,
+		exchange.TargetAmount,
+		exchange.ChangedBy,
+		exchange.Reason,
+		exchange.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record exchange: %w", err)
+	}
+	return nil
+}
+
+// scanExchange reads a row selected with exchangeColumns
+func scanExchange(row rowScanner) (*bookings.Exchange, error) {
+	exchange := &bookings.Exchange{}
+	var sourceShowID, targetShowID string
+	err := row.Scan(
+		&exchange.ExchangeID,
+		&exchange.SourceBookingID,
+		&exchange.TargetBookingID,
+		&sourceShowID,
+		&targetShowID,
+		&exchange.NumberOfTickets,
+		&exchange.SourceAmount,
+		&exchange.TargetAmount,
+		&exchange.ChangedBy,
+		&exchange.Reason,
+		&exchange.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if exchange.SourceShowID, err = uuid.Parse(sourceShowID); err != nil {
+		return nil, fmt.Errorf("invalid show ID in database: %w", err)
+	}
+	if exchange.TargetShowID, err = uuid.Parse(targetShowID); err != nil {
+		return nil, fmt.Errorf("invalid show ID in database: %w", err)
+	}
+	exchange.PriceDifference = exchange.TargetAmount - exchange.SourceAmount
+	return exchange, nil
+}
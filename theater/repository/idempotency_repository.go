@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/idempotency"
+	"github.com/gsmayya/theater/utils"
+)
+
+type IdempotencyRepository struct {
+	database    *db.Database
+	redisClient *utils.RedisAccess
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{
+		database:    db.GetDatabase(),
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+// ClaimKey claims record's key for its request. It returns nil when the key
+// was free (or its window had passed) and is now claimed, or the existing
+// record when the key is already in use. A claim whose lease has run out
+// without a response is taken over by a retry of the same request. MySQL's
+// primary key decides races between concurrent requests with the same key.
+func (r *IdempotencyRepository) ClaimKey(record *idempotency.Record) (*idempotency.Record, error) {
+	now := time.Now()
+
+	// Completed responses are served from Redis when possible
+	if cached, err := r.getRecordFromCache(record.Scope, record.Owner, record.Key); err == nil && !cached.IsExpired(now) {
+		return cached, nil
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err := insertIdempotencyRecord(r.database.GetDB(), record)
+		if err == nil {
+			return nil, nil
+		}
+		if !strings.Contains(err.Error(), "Duplicate entry") {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		existing, err := r.getRecord(record.Scope, record.Owner, record.Key)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue // Released between our insert and read; try again
+			}
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+
+		if existing.IsExpired(now) {
+			// The old window has passed, so the key is free to reuse
+			query := `DELETE FROM idempotency_keys WHERE scope = ? AND owner_hash = ? AND idempotency_key = ? AND expires_at <= ?`
+			if _, err := r.database.GetDB().Exec(query, record.Scope, record.Owner, record.Key, now); err != nil {
+				return nil, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+			}
+			continue
+		}
+
+		if !existing.IsStale(now) || existing.Fingerprint != record.Fingerprint {
+			return existing, nil
+		}
+
+		// The request holding the key never finished, so this retry takes over
+		taken, err := r.takeOverKey(record, existing.ClaimID, now)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			record.ExpiresAt = existing.ExpiresAt
+			return nil, nil
+		}
+	}
+
+	return nil, idempotency.ErrInProgress
+}
+
+// CompleteKey stores the response of a claimed key's request so replays can
+// return it. It fails when another request has taken the key over.
+func (r *IdempotencyRepository) CompleteKey(record *idempotency.Record) error {
+	query := `
+		UPDATE idempotency_keys SET status_code = ?, response_body = ?
+		WHERE scope = ? AND owner_hash = ? AND idempotency_key = ? AND claimed_by = ? AND status_code IS NULL
+	`
+	result, err := r.database.GetDB().Exec(query, record.StatusCode, record.Body, record.Scope, record.Owner, record.Key, record.ClaimID)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	stored, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if stored == 0 {
+		return fmt.Errorf("failed to store idempotent response: the claim was taken over by a retry")
+	}
+
+	r.cacheRecord(record)
+	return nil
+}
+
+// ReleaseKey gives up a claimed key without storing a response, so the
+// client can retry the request with the same key
+func (r *IdempotencyRepository) ReleaseKey(record *idempotency.Record) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = ? AND owner_hash = ? AND idempotency_key = ? AND claimed_by = ? AND status_code IS NULL`
+	if _, err := r.database.GetDB().Exec(query, record.Scope, record.Owner, record.Key, record.ClaimID); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// takeOverKey moves an unfinished claim to record's request. It reports
+// false when another retry took the claim over first.
+func (r *IdempotencyRepository) takeOverKey(record *idempotency.Record, staleClaimID string, now time.Time) (bool, error) {
+	query := `
+		UPDATE idempotency_keys SET claimed_by = ?, locked_until = ?
+		WHERE scope = ? AND owner_hash = ? AND idempotency_key = ? AND COALESCE(claimed_by, '') = ?
+			AND status_code IS NULL AND (locked_until IS NULL OR locked_until <= ?)
+	`
+	result, err := r.database.GetDB().Exec(query, record.ClaimID, record.LockedUntil,
+		record.Scope, record.Owner, record.Key, staleClaimID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+
+	taken, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return taken == 1, nil
+}
+
+// DeleteExpiredKeys removes keys whose window passed at or before now
+func (r *IdempotencyRepository) DeleteExpiredKeys(now time.Time) (int64, error) {
+	result, err := r.database.GetDB().Exec(`DELETE FROM idempotency_keys WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return deleted, nil
+}
+
+func (r *IdempotencyRepository) getRecord(scope, owner, key string) (*idempotency.Record, error) {
+	query := `
+		SELECT scope, owner_hash, idempotency_key, fingerprint, COALESCE(status_code, 0), COALESCE(response_body, ''),
+			COALESCE(claimed_by, ''), locked_until, created_at, expires_at
+		FROM idempotency_keys
+		WHERE scope = ? AND owner_hash = ? AND idempotency_key = ?
+	`
+
+	record := &idempotency.Record{}
+	var lockedUntil sql.NullTime
+	err := r.database.GetDB().QueryRow(query, scope, owner, key).Scan(
+		&record.Scope,
+		&record.Owner,
+		&record.Key,
+		&record.Fingerprint,
+		&record.StatusCode,
+		&record.Body,
+		&record.ClaimID,
+		&lockedUntil,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		record.LockedUntil = lockedUntil.Time
+	}
+
+	if record.IsCompleted() {
+		r.cacheRecord(record)
+	}
+	return record, nil
+}
+
+// Helper methods for caching. Only completed records are cached, and only
+// until their window passes.
+func (r *IdempotencyRepository) cacheRecord(record *idempotency.Record) {
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+
+	if data, err := record.ToJSON(); err == nil {
+		cacheKey := idempotencyCacheKey(record.Scope, record.Owner, record.Key)
+		if err := utils.AddToCacheWithExpiry(cacheKey, data, ttl, r.redisClient); err != nil {
+			log.Printf("Warning: Failed to cache idempotency key %s: %v", record.Key, err)
+		}
+	}
+}
+
+func (r *IdempotencyRepository) getRecordFromCache(scope, owner, key string) (*idempotency.Record, error) {
+	data, err := utils.GetFromCache(idempotencyCacheKey(scope, owner, key), r.redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &idempotency.Record{}
+	if err := record.FromJSON(data); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func idempotencyCacheKey(scope, owner, key string) string {
+	return fmt.Sprintf("idempotency:%s:%s:%s", scope, owner, key)
+}
+
+func insertIdempotencyRecord(exec sqlExecutor, record *idempotency.Record) error {
+	query := `
+		INSERT INTO idempotency_keys (scope, owner_hash, idempotency_key, fingerprint, claimed_by, locked_until, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := exec.Exec(query, record.Scope, record.Owner, record.Key, record.Fingerprint,
+		record.ClaimID, record.LockedUntil, record.CreatedAt, record.ExpiresAt)
+	return err
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/notifications"
+)
+
+// notificationColumns lists the notifications columns in the order scanNotification reads them
+const notificationColumns = `notification_id, booking_id, kind, channel, recipient, COALESCE(subject, ''), body, source_id,
+	status, attempts, next_attempt_at, COALESCE(transport, ''), COALESCE(last_error, ''), created_at, sent_at`
+
+type NotificationRepository struct {
+	database *db.Database
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		database: db.GetDatabase(),
+	}
+}
+
+// EnqueueNotifications queues notifications in one transaction. A message of
+// the same kind already queued for a booking by the same source is skipped,
+// so handling an event twice does not notify the customer twice.
+func (r *NotificationRepository) EnqueueNotifications(queued []*notifications.Notification) error {
+	return r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		query := `
+			INSERT IGNORE INTO notifications (notification_id, booking_id, kind, channel, recipient, subject, body,
+				source_id, status, attempts, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, 0, ?, ?)
+		`
+		for _, notification := range queued {
+			_, err := tx.Exec(query,
+				notification.NotificationID,
+				notification.BookingID,
+				notification.Kind,
+				notification.Channel,
+				notification.Recipient,
+				notification.Subject,
+				notification.Body,
+				notification.SourceID,
+				notification.Status,
+				notification.NextAttemptAt,
+				notification.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to queue notification: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimDueNotifications claims up to limit pending notifications whose next
+// attempt is due, oldest first, for the given lease. Notifications claimed by
+// another instance are skipped until its lease runs out.
+func (r *NotificationRepository) ClaimDueNotifications(now time.Time, lease time.Duration, limit int) ([]*notifications.Notification, string, error) {
+	claim := uuid.New().String()
+
+	query := `
+		UPDATE notifications SET claimed_by = ?, claimed_until = ?
+		WHERE status = ? AND next_attempt_at <= ? AND (claimed_until IS NULL OR claimed_until < ?)
+		ORDER BY next_attempt_at, created_at
+		LIMIT ?
+	`
+	result, err := r.database.GetDB().Exec(query, claim, now.Add(lease), notifications.StatusPending, now, now, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return nil, claim, err
+	}
+
+	query = `SELECT ` + notificationColumns + ` FROM notifications WHERE claimed_by = ? ORDER BY next_attempt_at, created_at`
+	claimedNotifications, err := r.queryNotifications(query, claim)
+	return claimedNotifications, claim, err
+}
+
+// SaveAttempt records the outcome of an attempt and releases the claim on the
+// notification. It reports false when the claim had lapsed and another
+// instance has taken the notification over.
+func (r *NotificationRepository) SaveAttempt(notification *notifications.Notification, claim string) (bool, error) {
+	query := `
+		UPDATE notifications
+		SET status = ?, attempts = ?, next_attempt_at = ?, transport = NULLIF(?, ''), last_error = NULLIF(?, ''),
+			sent_at = ?, claimed_by = NULL, claimed_until = NULL
+		WHERE notification_id = ? AND claimed_by = ?
+	`
+	result, err := r.database.GetDB().Exec(query,
+		notification.Status,
+		notification.Attempts,
+		notification.NextAttemptAt,
+		notification.Transport,
+		notification.LastError,
+		notification.SentAt,
+		notification.NotificationID,
+		claim,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save notification attempt: %w", err)
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return saved > 0, nil
+}
+
+// GetNotificationsByBooking returns a booking's delivery log, newest first
+func (r *NotificationRepository) GetNotificationsByBooking(bookingID string) ([]*notifications.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE booking_id = ? ORDER BY created_at DESC`
+	return r.queryNotifications(query, bookingID)
+}
+
+// GetLatestNotification returns the most recent notification of a kind for a
+// booking, or nil when there is none
+func (r *NotificationRepository) GetLatestNotification(bookingID, kind string) (*notifications.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE booking_id = ? AND kind = ? ORDER BY created_at DESC LIMIT 1`
+
+	notification, err := scanNotification(r.database.GetDB().QueryRow(query, bookingID, kind))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return notification, nil
+}
+
+// ReminderCandidate is a confirmed booking whose show falls in a reminder window
+type ReminderCandidate struct {
+	BookingID string
+	ShowDate  time.Time
+}
+
+// GetReminderCandidates returns confirmed bookings over the given contact
+// channels for shows starting after from and no later than to, that have no
+// reminder recorded for source yet, soonest show first
+func (r *NotificationRepository) GetReminderCandidates(source string, channels []string, from, to time.Time, limit int) ([]*ReminderCandidate, error) {
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(channels)), ", ")
+	query := `
+		SELECT b.booking_id, s.show_date
+		FROM bookings b
+		JOIN shows s ON s.id = b.show_id
+		WHERE b.status = ? AND b.contact_type IN (` + placeholders + `) AND s.show_date > ? AND s.show_date <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.source_id = ? AND n.booking_id = b.booking_id AND n.kind = ?
+			)
+		ORDER BY s.show_date, b.booking_id
+		LIMIT ?
+	`
+	args := []interface{}{bookings.StatusConfirmed}
+	for _, channel := range channels {
+		args = append(args, channel)
+	}
+	args = append(args, from, to, source, notifications.KindShowReminder, limit)
+
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminder candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]*ReminderCandidate, 0)
+	for rows.Next() {
+		candidate := &ReminderCandidate{}
+		if err := rows.Scan(&candidate.BookingID, &candidate.ShowDate); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder candidate: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+func (r *NotificationRepository) queryNotifications(query string, args ...interface{}) ([]*notifications.Notification, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	queued := make([]*notifications.Notification, 0)
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		queued = append(queued, notification)
+	}
+
+	return queued, rows.Err()
+}
+
+// scanNotification reads a row selected with notificationColumns
+func scanNotification(row rowScanner) (*notifications.Notification, error) {
+	notification := &notifications.Notification{}
+	var sentAt sql.NullTime
+	err := row.Scan(
+		&notification.NotificationID,
+		&notification.BookingID,
+		&notification.Kind,
+		&notification.Channel,
+		&notification.Recipient,
+		&notification.Subject,
+		&notification.Body,
+		&notification.SourceID,
+		&notification.Status,
+		&notification.Attempts,
+		&notification.NextAttemptAt,
+		&notification.Transport,
+		&notification.LastError,
+		&notification.CreatedAt,
+		&sentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sentAt.Valid {
+		notification.SentAt = &sentAt.Time
+	}
+	return notification, nil
+}
@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/orders"
+)
+
+type OrderRepository struct {
+	database          *db.Database
+	bookingRepository *BookingRepository
+}
+
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		database:          db.GetDatabase(),
+		bookingRepository: NewBookingRepository(),
+	}
+}
+
+// ReserveOrder reserves every booking of an order in one transaction: either
+// all of the order's shows have room and every booking is inserted, or
+// nothing is. The shows are locked in a fixed order so concurrent orders
+// sharing shows cannot deadlock each other.
+func (r *OrderRepository) ReserveOrder(order *orders.Order, changedBy string) ([]*ShowInventory, error) {
+	var inventories []*ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		if err := lockShows(tx, orderShowIDs(order.Bookings)); err != nil {
+			return err
+		}
+
+		if err := insertOrder(tx, order); err != nil {
+			return err
+		}
+
+		inventories = make([]*ShowInventory, 0, len(order.Bookings))
+		for _, booking := range order.Bookings {
+			inventory, err := reserveBookingTx(tx, booking, changedBy)
+			if err != nil {
+				return fmt.Errorf("show %s: %w", booking.ShowID.String(), err)
+			}
+			inventories = append(inventories, inventory)
+		}
+
+		// Promo discounts are only known once each booking has been reserved
+		order.SumTotals()
+		_, err := tx.Exec(`UPDATE orders SET total_amount = ? WHERE order_id = ?`, order.TotalAmount, order.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to update order total: %w", err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Only cache once the transaction has been committed
+	for _, booking := range order.Bookings {
+		r.bookingRepository.cacheBooking(booking)
+	}
+
+	log.Printf("Order reserved successfully: %s with %d bookings", order.OrderID, len(order.Bookings))
+	return inventories, nil
+}
+
+// GetOrder retrieves an order with its bookings
+func (r *OrderRepository) GetOrder(orderID string) (*orders.Order, error) {
+	query := `
+		SELECT order_id, contact_type, contact_value, COALESCE(customer_name, ''), status,
+			total_amount, hold_expires_at, created_at, updated_at
+		FROM orders
+		WHERE order_id = ?
+	`
+
+	order := &orders.Order{}
+	var holdExpiresAt sql.NullTime
+	err := r.database.GetDB().QueryRow(query, orderID).Scan(
+		&order.OrderID,
+		&order.ContactType,
+		&order.ContactValue,
+		&order.CustomerName,
+		&order.Status,
+		&order.TotalAmount,
+		&holdExpiresAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found: %s", orderID)
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	order.HoldExpiresAt = nullableTime(holdExpiresAt)
+
+	bookingIDs, err := orderBookingIDs(r.database.GetDB(), orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Bookings = make([]*bookings.Booking, 0, len(bookingIDs))
+	for _, bookingID := range bookingIDs {
+		booking, err := r.bookingRepository.GetBooking(bookingID)
+		if err != nil {
+			return nil, err
+		}
+		order.Bookings = append(order.Bookings, booking)
+	}
+
+	return order, nil
+}
+
+// UpdateOrderStatus moves an order and every one of its bookings to the
+// matching status in one transaction. If any booking cannot make the
+// transition (for example because it has already been checked in) nothing
+// changes. Each booking's change is recorded in its status history.
+func (r *OrderRepository) UpdateOrderStatus(orderID, status, changedBy, reason string) ([]*ShowInventory, error) {
+	var inventories []*ShowInventory
+	var bookingIDs []string
+	bookingStatus := orders.BookingStatus(status)
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		members, err := orderMembers(tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		// Lock shows, then bookings, then the order, in the same order as ReserveOrder
+		showIDs := make([]uuid.UUID, 0, len(members))
+		for _, member := range members {
+			showIDs = append(showIDs, member.ShowID)
+		}
+		if err := lockShows(tx, showIDs); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		locked := make([]*bookings.Booking, 0, len(members))
+		for _, member := range members {
+			booking, err := lockBookingState(tx, member.BookingID)
+			if err != nil {
+				return err
+			}
+			if err := booking.CheckStatusChange(bookingStatus, now); err != nil {
+				return fmt.Errorf("booking %s: %w", member.BookingID, err)
+			}
+			booking.ShowID = member.ShowID
+			locked = append(locked, booking)
+		}
+
+		var fromStatus string
+		err = tx.QueryRow(`SELECT status FROM orders WHERE order_id = ? FOR UPDATE`, orderID).Scan(&fromStatus)
+		if err != nil {
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+		if err := orders.ValidateTransition(fromStatus, status); err != nil {
+			return err
+		}
+
+		for _, booking := range locked {
+			if err := changeOrderBookingStatus(tx, booking, bookingStatus, changedBy, reason, now); err != nil {
+				return err
+			}
+		}
+
+		// A confirmed or cancelled order no longer has a hold
+		query := `UPDATE orders SET status = ?, hold_expires_at = NULL, updated_at = ? WHERE order_id = ?`
+		if _, err := tx.Exec(query, status, now, orderID); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+
+		inventories = make([]*ShowInventory, 0, len(locked))
+		bookingIDs = make([]string, 0, len(locked))
+		for _, booking := range locked {
+			inventory, err := recountShow(tx, booking.ShowID)
+			if err != nil {
+				return err
+			}
+			inventories = append(inventories, inventory)
+			bookingIDs = append(bookingIDs, booking.BookingID)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Update cache for bookings that exist in cache
+	for _, bookingID := range bookingIDs {
+		if cachedBooking, err := r.bookingRepository.getBookingFromCache(bookingID); err == nil {
+			cachedBooking.UpdateStatus(bookingStatus)
+			r.bookingRepository.cacheBooking(cachedBooking)
+		}
+	}
+
+	return inventories, nil
+}
+
+// Transaction helpers
+
+// orderMember identifies one booking of an order
+type orderMember struct {
+	BookingID string
+	ShowID    uuid.UUID
+}
+
+// orderMembers returns the bookings of an order, or an error when the order does not exist
+func orderMembers(tx *sql.Tx, orderID string) ([]*orderMember, error) {
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM orders WHERE order_id = ?`, orderID).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found: %s", orderID)
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT booking_id, show_id FROM bookings WHERE order_id = ? ORDER BY booking_id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*orderMember
+	for rows.Next() {
+		member := &orderMember{}
+		var showIDStr string
+		if err := rows.Scan(&member.BookingID, &showIDStr); err != nil {
+			return nil, fmt.Errorf("failed to scan order booking: %w", err)
+		}
+		if member.ShowID, err = uuid.Parse(showIDStr); err != nil {
+			return nil, fmt.Errorf("invalid show ID in database: %w", err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// orderBookingIDs returns the IDs of an order's bookings
+func orderBookingIDs(exec sqlExecutor, orderID string) ([]string, error) {
+	rows, err := exec.Query(`SELECT booking_id FROM bookings WHERE order_id = ? ORDER BY booking_id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookingIDs []string
+	for rows.Next() {
+		var bookingID string
+		if err := rows.Scan(&bookingID); err != nil {
+			return nil, fmt.Errorf("failed to scan order booking: %w", err)
+		}
+		bookingIDs = append(bookingIDs, bookingID)
+	}
+	return bookingIDs, rows.Err()
+}
+
+// changeOrderBookingStatus applies an order's status change to one of its
+// locked bookings
+func changeOrderBookingStatus(tx *sql.Tx, booking *bookings.Booking, status, changedBy, reason string, now time.Time) error {
+	fromStatus := booking.Status
+	effect := bookings.TransitionEffect(fromStatus, status)
+	booking.UpdateStatus(status)
+
+	if effect == bookings.InventoryReleased {
+		if err := releaseSeats(tx, booking.BookingID); err != nil {
+			return err
+		}
+		if err := releaseTickets(tx, booking.BookingID, now); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE bookings SET status = ?, hold_expires_at = ?, updated_at = ? WHERE booking_id = ?`
+	if _, err := tx.Exec(query, booking.Status, booking.HoldExpiresAt, now, booking.BookingID); err != nil {
+		return fmt.Errorf("failed to update booking status: %w", err)
+	}
+
+	return insertStatusChange(tx, &bookings.StatusChange{
+		BookingID:  booking.BookingID,
+		FromStatus: fromStatus,
+		ToStatus:   status,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+		ChangedAt:  now,
+	})
+}
+
+// settleOrderExpiry marks a pending order expired when one of its bookings'
+// holds lapses. Every booking of an order shares the same hold, so the rest
+// lapse with it. Bookings outside an order are left alone.
+func settleOrderExpiry(exec sqlExecutor, bookingID string, now time.Time) error {
+	query := `
+		UPDATE orders o
+		JOIN bookings b ON b.order_id = o.order_id
+		SET o.status = ?, o.hold_expires_at = NULL, o.updated_at = ?
+		WHERE b.booking_id = ? AND o.status = ?
+	`
+	if _, err := exec.Exec(query, orders.StatusExpired, now, bookingID, orders.StatusPending); err != nil {
+		return fmt.Errorf("failed to expire order: %w", err)
+	}
+	return nil
+}
+
+// lockShows takes row locks on several shows in ascending ID order
+func lockShows(tx *sql.Tx, showIDs []uuid.UUID) error {
+	sorted := append([]uuid.UUID(nil), showIDs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	for _, showID := range sorted {
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderShowIDs returns the shows an order's bookings are for
+func orderShowIDs(orderBookings []*bookings.Booking) []uuid.UUID {
+	showIDs := make([]uuid.UUID, 0, len(orderBookings))
+	for _, booking := range orderBookings {
+		showIDs = append(showIDs, booking.ShowID)
+	}
+	return showIDs
+}
+
+func insertOrder(exec sqlExecutor, order *orders.Order) error {
+	query := `
+		INSERT INTO orders (order_id, contact_type, contact_value, customer_name, status,
+			total_amount, hold_expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?)
+	`
+
+	_, err := exec.Exec(query,
+		order.OrderID,
+		order.ContactType,
+		order.ContactValue,
+		order.CustomerName,
+		order.Status,
+		order.TotalAmount,
+		order.HoldExpiresAt,
+		order.CreatedAt,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	return nil
+}
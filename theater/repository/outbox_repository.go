@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/shows"
+)
+
+// outboxColumns lists the outbox_events columns in the order scanEvent reads them
+const outboxColumns = `sequence, event_id, event_type, aggregate_type, aggregate_id, payload, status, attempts,
+	next_attempt_at, COALESCE(delivered_to, ''), COALESCE(last_error, ''), created_at, delivered_at`
+
+type OutboxRepository struct {
+	database *db.Database
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{
+		database: db.GetDatabase(),
+	}
+}
+
+// ClaimDueEvents claims up to limit pending events whose next attempt is due,
+// oldest first, for the given lease. Events claimed by another dispatcher are
+// skipped until its lease runs out, so several server instances can share the
+// outbox without delivering the same event at the same time.
+func (r *OutboxRepository) ClaimDueEvents(now time.Time, lease time.Duration, limit int) ([]*events.Event, string, error) {
+	claim := uuid.New().String()
+
+	query := `
+		UPDATE outbox_events SET claimed_by = ?, claimed_until = ?
+		WHERE status = ? AND next_attempt_at <= ? AND (claimed_until IS NULL OR claimed_until < ?)
+		ORDER BY sequence
+		LIMIT ?
+	`
+	result, err := r.database.GetDB().Exec(query, claim, now.Add(lease), events.StatusPending, now, now, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to claim events: %w", err)
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return nil, claim, err
+	}
+
+	query = `SELECT ` + outboxColumns + ` FROM outbox_events WHERE claimed_by = ? ORDER BY sequence`
+	claimedEvents, err := r.queryEvents(query, claim)
+	return claimedEvents, claim, err
+}
+
+// SaveDelivery records the outcome of a delivery attempt and releases the
+// claim on the event. It reports false when the claim had lapsed and another
+// dispatcher has taken the event over.
+func (r *OutboxRepository) SaveDelivery(event *events.Event, claim string) (bool, error) {
+	query := `
+		UPDATE outbox_events
+		SET status = ?, attempts = ?, next_attempt_at = ?, delivered_to = NULLIF(?, ''), last_error = NULLIF(?, ''),
+			delivered_at = ?, claimed_by = NULL, claimed_until = NULL
+		WHERE sequence = ? AND claimed_by = ?
+	`
+	result, err := r.database.GetDB().Exec(query,
+		event.Status,
+		event.Attempts,
+		event.NextAttemptAt,
+		strings.Join(event.DeliveredTo, ","),
+		event.LastError,
+		event.DeliveredAt,
+		event.Sequence,
+		claim,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save event delivery: %w", err)
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return saved > 0, nil
+}
+
+// GetEvent retrieves an outbox event by its event ID
+func (r *OutboxRepository) GetEvent(eventID string) (*events.Event, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE event_id = ?`
+
+	event, err := scanEvent(r.database.GetDB().QueryRow(query, eventID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("event not found: %s", eventID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return event, nil
+}
+
+// GetEventsByStatus returns up to limit events in a status, newest first
+func (r *OutboxRepository) GetEventsByStatus(status string, limit int) ([]*events.Event, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE status = ? ORDER BY sequence DESC LIMIT ?`
+	return r.queryEvents(query, status, limit)
+}
+
+// GetEventsByAggregate returns the events of one show or booking in the order they were written
+func (r *OutboxRepository) GetEventsByAggregate(aggregateType, aggregateID string) ([]*events.Event, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE aggregate_type = ? AND aggregate_id = ? ORDER BY sequence`
+	return r.queryEvents(query, aggregateType, aggregateID)
+}
+
+// RequeueEvent puts a dead event back in the queue with a fresh set of
+// attempts. Subscribers that already handled it are not called again.
+func (r *OutboxRepository) RequeueEvent(eventID string, now time.Time) (*events.Event, error) {
+	query := `
+		UPDATE outbox_events SET status = ?, attempts = 0, next_attempt_at = ?, claimed_by = NULL, claimed_until = NULL
+		WHERE event_id = ? AND status = ?
+	`
+	result, err := r.database.GetDB().Exec(query, events.StatusPending, now, eventID, events.StatusDead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue event: %w", err)
+	}
+
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	event, err := r.GetEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if requeued == 0 {
+		return nil, fmt.Errorf("invalid event status: event %s is %s, only dead events can be retried", eventID, event.Status)
+	}
+	return event, nil
+}
+
+// DeleteDeliveredEvents removes events delivered at or before the given time
+func (r *OutboxRepository) DeleteDeliveredEvents(before time.Time) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE status = ? AND delivered_at <= ?`
+	result, err := r.database.GetDB().Exec(query, events.StatusDelivered, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered events: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return deleted, nil
+}
+
+func (r *OutboxRepository) queryEvents(query string, args ...interface{}) ([]*events.Event, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	outbox := make([]*events.Event, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		outbox = append(outbox, event)
+	}
+
+	return outbox, rows.Err()
+}
+
+// writeEvent adds an event to the outbox. Called inside the transaction that
+// makes the change, so the event is stored if and only if the change is.
+func writeEvent(exec sqlExecutor, eventType, aggregateID string, payload interface{}) error {
+	event, err := events.NewEvent(eventType, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, status, attempts,
+			next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`
+	_, err = exec.Exec(query,
+		event.EventID,
+		event.Type,
+		event.AggregateType,
+		event.AggregateID,
+		string(event.Payload),
+		event.Status,
+		event.NextAttemptAt,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// writeShowUpdated records that part of a show changed
+func writeShowUpdated(exec sqlExecutor, showID uuid.UUID, change string) error {
+	return writeEvent(exec, events.TypeShowUpdated, showID.String(), &events.ShowUpdate{ShowID: showID, Change: change})
+}
+
+// writeShowCreated records a new show
+func writeShowCreated(exec sqlExecutor, show *shows.ShowData) error {
+	return writeEvent(exec, events.TypeShowCreated, show.Show_Id.String(), show)
+}
+
+// writeBookingStatusChanged records a booking's move from one status to another
+func writeBookingStatusChanged(exec sqlExecutor, change *bookings.StatusChange) error {
+	var showIDStr string
+	err := exec.QueryRow(`SELECT show_id FROM bookings WHERE booking_id = ?`, change.BookingID).Scan(&showIDStr)
+	if err != nil {
+		return fmt.Errorf("failed to get booking show: %w", err)
+	}
+	showID, err := uuid.Parse(showIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid show ID in database: %w", err)
+	}
+
+	return writeEvent(exec, events.TypeBookingStatusChanged, change.BookingID, &events.BookingStatusChange{
+		BookingID:  change.BookingID,
+		ShowID:     showID,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		ChangedBy:  change.ChangedBy,
+		Reason:     change.Reason,
+		ChangedAt:  change.ChangedAt,
+	})
+}
+
+// scanEvent reads a row selected with outboxColumns
+func scanEvent(row rowScanner) (*events.Event, error) {
+	event := &events.Event{}
+	var payload, deliveredTo string
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&event.Sequence,
+		&event.EventID,
+		&event.Type,
+		&event.AggregateType,
+		&event.AggregateID,
+		&payload,
+		&event.Status,
+		&event.Attempts,
+		&event.NextAttemptAt,
+		&deliveredTo,
+		&event.LastError,
+		&event.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Payload = []byte(payload)
+	if deliveredTo != "" {
+		event.DeliveredTo = strings.Split(deliveredTo, ",")
+	}
+	if deliveredAt.Valid {
+		event.DeliveredAt = &deliveredAt.Time
+	}
+	return event, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/payments"
+)
+
+type PaymentRepository struct {
+	database          *db.Database
+	bookingRepository *BookingRepository
+}
+
+func NewPaymentRepository() *PaymentRepository {
+	return &PaymentRepository{
+		database:          db.GetDatabase(),
+		bookingRepository: NewBookingRepository(),
+	}
+}
+
+// paymentColumns lists the payments columns in the order scanPayment reads them
+const paymentColumns = `payment_id, booking_id, provider, COALESCE(provider_payment_id, ''), amount, currency, purpose, status,
+	COALESCE(failure_reason, ''), COALESCE(refund_id, ''), created_at, updated_at`
+
+// InsertPayment records a payment before it is started with the provider,
+// so an attempt is never lost even if the provider call fails
+func (r *PaymentRepository) InsertPayment(payment *payments.Payment) error {
+	query := `
+		INSERT INTO payments (payment_id, booking_id, provider, amount, currency, purpose, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.database.GetDB().Exec(query,
+		payment.PaymentID,
+		payment.BookingID,
+		payment.Provider,
+		payment.Amount,
+		payment.Currency,
+		payment.Purpose,
+		payment.Status,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment: %w", err)
+	}
+	return nil
+}
+
+// SetProviderPaymentID links a payment to the intent its provider created
+func (r *PaymentRepository) SetProviderPaymentID(payment *payments.Payment, providerPaymentID string) error {
+	now := time.Now()
+	query := `UPDATE payments SET provider_payment_id = ?, updated_at = ? WHERE payment_id = ?`
+	if _, err := r.database.GetDB().Exec(query, providerPaymentID, now, payment.PaymentID); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	payment.ProviderPaymentID = providerPaymentID
+	payment.UpdatedAt = now
+	return nil
+}
+
+// TransitionPayment moves a payment from one status to another, recording a
+// failure reason or refund ID when given. It reports false, changing
+// nothing, when the payment is no longer in the from status, so of two
+// deliveries of the same webhook only one acts on it.
+func (r *PaymentRepository) TransitionPayment(payment *payments.Payment, from, to, failureReason, refundID string) (bool, error) {
+	now := time.Now()
+	query := `
+		UPDATE payments
+		SET status = ?, failure_reason = COALESCE(NULLIF(?, ''), failure_reason),
+			refund_id = COALESCE(NULLIF(?, ''), refund_id), updated_at = ?
+		WHERE payment_id = ? AND status = ?
+	`
+	result, err := r.database.GetDB().Exec(query, to, failureReason, refundID, now, payment.PaymentID, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	payment.Status = to
+	if failureReason != "" {
+		payment.FailureReason = failureReason
+	}
+	if refundID != "" {
+		payment.RefundID = refundID
+	}
+	payment.UpdatedAt = now
+	return true, nil
+}
+
+// CaptureBalancePayment records an authorized balance payment as captured
+// and takes its amount off its booking's balance due, in one transaction. It
+// reports false, changing nothing, when the booking no longer holds its
+// tickets or owes less than the payment, or when the payment is no longer
+// authorized.
+func (r *PaymentRepository) CaptureBalancePayment(payment *payments.Payment) (bool, error) {
+	captured := false
+	now := time.Now()
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		booking, err := lockBookingState(tx, payment.BookingID)
+		if err != nil {
+			return err
+		}
+		if !bookings.HoldsInventory(booking.Status) || booking.Status == bookings.StatusPending || booking.BalanceDue < payment.Amount {
+			return nil
+		}
+
+		query := `UPDATE payments SET status = ?, updated_at = ? WHERE payment_id = ? AND status = ?`
+		result, err := tx.Exec(query, payments.StatusCaptured, now, payment.PaymentID, payments.StatusAuthorized)
+		if err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		query = `UPDATE bookings SET balance_due = balance_due - ?, updated_at = ? WHERE booking_id = ?`
+		if _, err := tx.Exec(query, payment.Amount, now, payment.BookingID); err != nil {
+			return fmt.Errorf("failed to update balance due: %w", err)
+		}
+
+		captured = true
+		return nil
+	})
+	if err != nil || !captured {
+		return false, err
+	}
+
+	// The cached booking's balance is stale now
+	r.bookingRepository.removeCachedBooking(payment.BookingID)
+
+	payment.Status = payments.StatusCaptured
+	payment.UpdatedAt = now
+	return true, nil
+}
+
+// GetPayment retrieves a payment by ID
+func (r *PaymentRepository) GetPayment(paymentID string) (*payments.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE payment_id = ?`
+	payment, err := scanPayment(r.database.GetDB().QueryRow(query, paymentID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payment not found: %s", paymentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// GetPaymentByProviderID retrieves a payment by the ID its provider gave it
+func (r *PaymentRepository) GetPaymentByProviderID(provider, providerPaymentID string) (*payments.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = ? AND provider_payment_id = ?`
+	payment, err := scanPayment(r.database.GetDB().QueryRow(query, provider, providerPaymentID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payment not found: %s %s", provider, providerPaymentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// GetPaymentsByBooking returns every payment attempt for a booking, oldest first
+func (r *PaymentRepository) GetPaymentsByBooking(bookingID string) ([]*payments.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE booking_id = ? ORDER BY created_at, payment_id`
+	rows, err := r.database.GetDB().Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	defer rows.Close()
+
+	bookingPayments := make([]*payments.Payment, 0)
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		bookingPayments = append(bookingPayments, payment)
+	}
+
+	return bookingPayments, rows.Err()
+}
+
+// scanPayment reads a row selected with paymentColumns
+func scanPayment(row rowScanner) (*payments.Payment, error) {
+	payment := &payments.Payment{}
+	err := row.Scan(
+		&payment.PaymentID,
+		&payment.BookingID,
+		&payment.Provider,
+		&payment.ProviderPaymentID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Purpose,
+		&payment.Status,
+		&payment.FailureReason,
+		&payment.RefundID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
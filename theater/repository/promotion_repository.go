@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/promotions"
+	"github.com/gsmayya/theater/utils"
+)
+
+type PromotionRepository struct {
+	database    *db.Database
+	redisClient *utils.RedisAccess
+}
+
+func NewPromotionRepository() *PromotionRepository {
+	return &PromotionRepository{
+		database:    db.GetDatabase(),
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+const promotionColumns = `
+	code, COALESCE(description, ''), discount_type, discount_value, show_id, valid_from, valid_until,
+	max_redemptions, max_per_contact, min_tickets, active, created_at, updated_at`
+
+// CreatePromotion saves a new promotion
+func (r *PromotionRepository) CreatePromotion(promotion *promotions.Promotion) error {
+	now := time.Now()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	query := `
+		INSERT INTO promotions (code, description, discount_type, discount_value, show_id, valid_from,
+			valid_until, max_redemptions, max_per_contact, min_tickets, active, created_at, updated_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.database.GetDB().Exec(query,
+		promotion.Code,
+		promotion.Description,
+		promotion.DiscountType,
+		promotion.DiscountValue,
+		nullableUUID(promotion.ShowID),
+		promotion.ValidFrom,
+		promotion.ValidUntil,
+		promotion.MaxRedemptions,
+		promotion.MaxPerContact,
+		promotion.MinTickets,
+		promotion.Active,
+		promotion.CreatedAt,
+		promotion.UpdatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return fmt.Errorf("promo code %s already exists", promotion.Code)
+		}
+		return fmt.Errorf("failed to create promotion: %w", err)
+	}
+
+	log.Printf("Promotion created: %s", promotion.Code)
+	return nil
+}
+
+// GetPromotion retrieves a promotion with its current redemption count
+func (r *PromotionRepository) GetPromotion(code string) (*promotions.Promotion, error) {
+	promotion, err := scanPromotion(r.database.GetDB().QueryRow(
+		`SELECT `+promotionColumns+` FROM promotions WHERE code = ?`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("promotion not found: %s", code)
+		}
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	promotion.Redemptions, err = countRedemptions(r.database.GetDB(), code, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// GetAllPromotions lists promotions, newest first, with their redemption counts
+func (r *PromotionRepository) GetAllPromotions() ([]*promotions.Promotion, error) {
+	rows, err := r.database.GetDB().Query(`SELECT ` + promotionColumns + ` FROM promotions ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotionList := []*promotions.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotionList = append(promotionList, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read promotions: %w", err)
+	}
+
+	summaries, err := r.GetRedemptionReport("")
+	if err != nil {
+		return nil, err
+	}
+	redemptions := make(map[string]int32, len(summaries))
+	for _, summary := range summaries {
+		redemptions[summary.Code] = summary.Redemptions
+	}
+	for _, promotion := range promotionList {
+		promotion.Redemptions = redemptions[promotion.Code]
+	}
+
+	return promotionList, nil
+}
+
+// UpdatePromotion replaces a promotion's rules. Bookings that already used the
+// code keep the discount they were given.
+func (r *PromotionRepository) UpdatePromotion(promotion *promotions.Promotion) error {
+	promotion.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE promotions
+		SET description = NULLIF(?, ''), discount_type = ?, discount_value = ?, show_id = ?, valid_from = ?,
+			valid_until = ?, max_redemptions = ?, max_per_contact = ?, min_tickets = ?, active = ?, updated_at = ?
+		WHERE code = ?
+	`
+	result, err := r.database.GetDB().Exec(query,
+		promotion.Description,
+		promotion.DiscountType,
+		promotion.DiscountValue,
+		nullableUUID(promotion.ShowID),
+		promotion.ValidFrom,
+		promotion.ValidUntil,
+		promotion.MaxRedemptions,
+		promotion.MaxPerContact,
+		promotion.MinTickets,
+		promotion.Active,
+		promotion.UpdatedAt,
+		promotion.Code,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("promotion not found: %s", promotion.Code)
+	}
+
+	log.Printf("Promotion updated: %s", promotion.Code)
+	return nil
+}
+
+// DeletePromotion deletes a promotion that no booking has used. Used codes
+// must be deactivated instead so the redemption report stays complete.
+func (r *PromotionRepository) DeletePromotion(code string) error {
+	return r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		if _, err := lockPromotion(tx, code); err != nil {
+			return err
+		}
+
+		var used int32
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM bookings WHERE promo_code = ?`, code).Scan(&used); err != nil {
+			return fmt.Errorf("failed to check promotion usage: %w", err)
+		}
+		if used > 0 {
+			return fmt.Errorf("cannot delete promo code %s: used by %d bookings, deactivate it instead", code, used)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM promotions WHERE code = ?`, code); err != nil {
+			return fmt.Errorf("failed to delete promotion: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetRedemptionReport summarizes how each promo code has been used, or just
+// the given code when one is passed
+func (r *PromotionRepository) GetRedemptionReport(code string) ([]*promotions.RedemptionSummary, error) {
+	query := `
+		SELECT p.code, p.discount_type, p.discount_value,
+			COALESCE(SUM(CASE WHEN ` + activeBookingFilter("b.") + ` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + activeBookingFilter("b.") + ` THEN b.number_of_tickets ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + activeBookingFilter("b.") + ` THEN b.discount_amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + activeBookingFilter("b.") + ` THEN b.total_amount ELSE 0 END), 0),
+			COUNT(b.booking_id)
+		FROM promotions p
+		LEFT JOIN bookings b ON b.promo_code = p.code
+	`
+	now := time.Now()
+	args := []interface{}{now, now, now, now}
+	if code != "" {
+		query += ` WHERE p.code = ?`
+		args = append(args, code)
+	}
+	query += ` GROUP BY p.code, p.discount_type, p.discount_value ORDER BY p.code`
+
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotion redemptions: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []*promotions.RedemptionSummary{}
+	for rows.Next() {
+		summary := &promotions.RedemptionSummary{}
+		var used int32
+		err := rows.Scan(
+			&summary.Code,
+			&summary.DiscountType,
+			&summary.DiscountValue,
+			&summary.Redemptions,
+			&summary.TicketsSold,
+			&summary.TotalDiscount,
+			&summary.Revenue,
+			&used,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion redemptions: %w", err)
+		}
+		summary.Released = used - summary.Redemptions
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read promotion redemptions: %w", err)
+	}
+
+	if code != "" && len(summaries) == 0 {
+		return nil, fmt.Errorf("promotion not found: %s", code)
+	}
+	return summaries, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPromotion(row rowScanner) (*promotions.Promotion, error) {
+	promotion := &promotions.Promotion{}
+	var showID sql.NullString
+	var validFrom, validUntil sql.NullTime
+	var maxRedemptions, maxPerContact sql.NullInt32
+
+	err := row.Scan(
+		&promotion.Code,
+		&promotion.Description,
+		&promotion.DiscountType,
+		&promotion.DiscountValue,
+		&showID,
+		&validFrom,
+		&validUntil,
+		&maxRedemptions,
+		&maxPerContact,
+		&promotion.MinTickets,
+		&promotion.Active,
+		&promotion.CreatedAt,
+		&promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.ShowID = parseNullUUID(showID)
+	promotion.ValidFrom = nullableTime(validFrom)
+	promotion.ValidUntil = nullableTime(validUntil)
+	if maxRedemptions.Valid {
+		promotion.MaxRedemptions = &maxRedemptions.Int32
+	}
+	if maxPerContact.Valid {
+		promotion.MaxPerContact = &maxPerContact.Int32
+	}
+	return promotion, nil
+}
+
+// Transaction helpers
+
+// lockPromotion loads a promotion and locks its row so concurrent redemptions
+// of the same code are counted one at a time
+func lockPromotion(tx *sql.Tx, code string) (*promotions.Promotion, error) {
+	promotion, err := scanPromotion(tx.QueryRow(`SELECT `+promotionColumns+` FROM promotions WHERE code = ? FOR UPDATE`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("promotion not found: %s", code)
+		}
+		return nil, fmt.Errorf("failed to lock promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+// countRedemptions counts active bookings using a promo code, optionally for
+// one contact. Cancelled, expired and refunded bookings give their redemption back.
+func countRedemptions(exec sqlExecutor, code, contactType, contactValue string) (int32, error) {
+	query := `SELECT COUNT(*) FROM bookings WHERE promo_code = ? AND ` + activeBookingFilter("")
+	args := []interface{}{code, time.Now()}
+	if contactType != "" {
+		query += ` AND contact_type = ? AND contact_value = ?`
+		args = append(args, contactType, contactValue)
+	}
+
+	var count int32
+	if err := exec.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count promotion redemptions: %w", err)
+	}
+	return count, nil
+}
+
+// redeemPromotion applies a booking's promo code under the promotion's row
+// lock: it checks the code's rules and usage caps, then takes the discount off
+// the booking's priced total. Call it after the booking has been priced and
+// the show has been locked.
+func redeemPromotion(tx *sql.Tx, booking *bookings.Booking, now time.Time) error {
+	promotion, err := lockPromotion(tx, booking.PromoCode)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("invalid promo code: %s does not exist", booking.PromoCode)
+		}
+		return err
+	}
+
+	if err := promotion.CheckEligibility(booking.ShowID, booking.NumberOfTickets, now); err != nil {
+		return err
+	}
+
+	if promotion.MaxRedemptions != nil {
+		redemptions, err := countRedemptions(tx, promotion.Code, "", "")
+		if err != nil {
+			return err
+		}
+		if redemptions >= *promotion.MaxRedemptions {
+			return fmt.Errorf("promo code limit reached: %s has been redeemed %d times", promotion.Code, redemptions)
+		}
+	}
+
+	if promotion.MaxPerContact != nil {
+		redemptions, err := countRedemptions(tx, promotion.Code, booking.ContactType, booking.ContactValue)
+		if err != nil {
+			return err
+		}
+		if redemptions >= *promotion.MaxPerContact {
+			return fmt.Errorf("promo code limit reached: %s can be used %d times per contact", promotion.Code, *promotion.MaxPerContact)
+		}
+	}
+
+	booking.ApplyDiscount(promotion.Discount(booking.TotalAmount))
+	return nil
+}
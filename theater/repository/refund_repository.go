@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/payments"
+)
+
+type RefundRepository struct {
+	database *db.Database
+}
+
+func NewRefundRepository() *RefundRepository {
+	return &RefundRepository{
+		database: db.GetDatabase(),
+	}
+}
+
+// refundColumns lists the refunds columns in the order scanRefund reads them
+const refundColumns = `refund_id, booking_id, COALESCE(payment_id, ''), amount, currency, rule, COALESCE(reason, ''),
+	status, COALESCE(processor_refund_id, ''), COALESCE(failure_reason, ''), created_by, created_at, updated_at`
+
+// InsertRefund adds a refund to the ledger
+func (r *RefundRepository) InsertRefund(refund *payments.Refund) error {
+	return insertRefund(r.database.GetDB(), refund)
+}
+
+// insertRefund adds a refund to the ledger, inside a transaction when exec is one,
+// so the booking change it pays back is never saved without it
+func insertRefund(exec sqlExecutor, refund *payments.Refund) error {
+	query := `
+		INSERT INTO refunds (refund_id, booking_id, payment_id, amount, currency, rule, reason, status, created_by, created_at, updated_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
+	`
+	_, err := exec.Exec(query,
+		refund.RefundID,
+		refund.BookingID,
+		refund.PaymentID,
+		refund.Amount,
+		refund.Currency,
+		refund.Rule,
+		refund.Reason,
+		refund.Status,
+		refund.CreatedBy,
+		refund.CreatedAt,
+		refund.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert refund: %w", err)
+	}
+	return nil
+}
+
+// UpdateRefundStatus records what the processor did with a refund
+func (r *RefundRepository) UpdateRefundStatus(refund *payments.Refund, status, processorRefundID, failureReason string) error {
+	now := time.Now()
+	query := `
+		UPDATE refunds
+		SET status = ?, processor_refund_id = COALESCE(NULLIF(?, ''), processor_refund_id),
+			failure_reason = NULLIF(?, ''), updated_at = ?
+		WHERE refund_id = ?
+	`
+	if _, err := r.database.GetDB().Exec(query, status, processorRefundID, failureReason, now, refund.RefundID); err != nil {
+		return fmt.Errorf("failed to update refund status: %w", err)
+	}
+
+	refund.Status = status
+	if processorRefundID != "" {
+		refund.ProcessorRefundID = processorRefundID
+	}
+	refund.FailureReason = failureReason
+	refund.UpdatedAt = now
+	return nil
+}
+
+// GetRefund retrieves a refund by ID
+func (r *RefundRepository) GetRefund(refundID string) (*payments.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE refund_id = ?`
+	refund, err := scanRefund(r.database.GetDB().QueryRow(query, refundID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refund not found: %s", refundID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return refund, nil
+}
+
+// GetRefundsByBooking returns a booking's ledger entries, oldest first
+func (r *RefundRepository) GetRefundsByBooking(bookingID string) ([]*payments.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE booking_id = ? ORDER BY created_at, refund_id`
+	return r.queryRefunds(query, bookingID)
+}
+
+// GetRefundsByStatus returns ledger entries in a status, oldest first
+func (r *RefundRepository) GetRefundsByStatus(status string, limit int) ([]*payments.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE status = ? ORDER BY created_at, refund_id LIMIT ?`
+	return r.queryRefunds(query, status, limit)
+}
+
+func (r *RefundRepository) queryRefunds(query string, args ...interface{}) ([]*payments.Refund, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make([]*payments.Refund, 0)
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+// scanRefund reads a row selected with refundColumns
+func scanRefund(row rowScanner) (*payments.Refund, error) {
+	refund := &payments.Refund{}
+	err := row.Scan(
+		&refund.RefundID,
+		&refund.BookingID,
+		&refund.PaymentID,
+		&refund.Amount,
+		&refund.Currency,
+		&refund.Rule,
+		&refund.Reason,
+		&refund.Status,
+		&refund.ProcessorRefundID,
+		&refund.FailureReason,
+		&refund.CreatedBy,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
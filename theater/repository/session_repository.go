@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gsmayya/theater/utils"
+)
+
+// Redis key prefixes for account sessions
+const (
+	sessionPrefix      = "auth:session:"        // Hash of a session's user and current refresh token
+	userSessionsPrefix = "auth:user-sessions:"  // Set of a user's session IDs
+	loginAttemptPrefix = "auth:login-attempts:" // Failed sign-ins for an account in the current window
+)
+
+// Fields of a session hash
+const (
+	sessionUserField    = "user_id"
+	sessionRefreshField = "refresh_id"
+)
+
+// SessionRepository keeps account sessions in Redis. A session lives as long
+// as its refresh token; deleting it revokes every token issued for it.
+type SessionRepository struct {
+	redisClient *utils.RedisAccess
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+// CreateSession stores a new session for a user with the ID of its first
+// refresh token
+func (r *SessionRepository) CreateSession(sessionID, userID, refreshID string, ttl time.Duration) error {
+	fields := map[string]interface{}{
+		sessionUserField:    userID,
+		sessionRefreshField: refreshID,
+	}
+	if err := utils.HashSetWithExpiry(sessionPrefix+sessionID, fields, ttl, r.redisClient); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	if err := utils.AddToSetWithExpiry(userSessionsPrefix+userID, sessionID, ttl, r.redisClient); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionUser returns the user a session belongs to, or "" when the
+// session was revoked or has expired
+func (r *SessionRepository) GetSessionUser(sessionID string) (string, error) {
+	userID, err := utils.HashGet(sessionPrefix+sessionID, sessionUserField, r.redisClient)
+	if err != nil {
+		if utils.IsCacheMiss(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	return userID, nil
+}
+
+// RotateRefreshToken moves a session from one refresh token to the next and
+// extends it by ttl. It reports false when the session no longer holds the
+// given refresh token, because it was revoked or the token was already used.
+func (r *SessionRepository) RotateRefreshToken(sessionID, userID, refreshID, nextRefreshID string, ttl time.Duration) (bool, error) {
+	rotated, err := utils.HashCompareAndSwap(sessionPrefix+sessionID, sessionRefreshField, refreshID, nextRefreshID, ttl, r.redisClient)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rotated {
+		if err := utils.AddToSetWithExpiry(userSessionsPrefix+userID, sessionID, ttl, r.redisClient); err != nil {
+			return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+		}
+	}
+	return rotated, nil
+}
+
+// DeleteSession revokes a session
+func (r *SessionRepository) DeleteSession(sessionID, userID string) error {
+	if err := utils.DeleteFromCache(sessionPrefix+sessionID, r.redisClient); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := utils.RemoveFromSet(userSessionsPrefix+userID, sessionID, r.redisClient); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions revokes every session of a user and returns how many there were
+func (r *SessionRepository) DeleteUserSessions(userID string) (int, error) {
+	sessionIDs, err := utils.SetMembers(userSessionsPrefix+userID, r.redisClient)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := utils.DeleteFromCache(sessionPrefix+sessionID, r.redisClient); err != nil {
+			return 0, fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+	if err := utils.DeleteFromCache(userSessionsPrefix+userID, r.redisClient); err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return len(sessionIDs), nil
+}
+
+// CountFailedLogin counts a failed sign-in for an account, identified by a
+// fingerprint of its email, and returns how many there have been in the
+// current window
+func (r *SessionRepository) CountFailedLogin(account string, window time.Duration) (int64, error) {
+	count, err := utils.IncrementWithExpiry(loginAttemptPrefix+account, window, r.redisClient)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sign-in attempt: %w", err)
+	}
+	return count, nil
+}
+
+// GetFailedLogins returns the failed sign-ins for an account in the current window
+func (r *SessionRepository) GetFailedLogins(account string) (int64, error) {
+	count, err := utils.GetFromCache(loginAttemptPrefix+account, r.redisClient)
+	if err != nil {
+		if utils.IsCacheMiss(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get sign-in attempts: %w", err)
+	}
+	attempts, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse sign-in attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// ClearFailedLogins forgets the failed sign-ins for an account
+func (r *SessionRepository) ClearFailedLogins(account string) error {
+	if err := utils.DeleteFromCache(loginAttemptPrefix+account, r.redisClient); err != nil {
+		return fmt.Errorf("failed to clear sign-in attempts: %w", err)
+	}
+	return nil
+}
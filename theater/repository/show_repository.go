@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/events"
 	"github.com/gsmayya/theater/shows"
 	"github.com/gsmayya/theater/utils"
 )
@@ -45,26 +47,33 @@ func (r *ShowRepository) CreateShow(show *shows.ShowData) error {
 	videosJSON, _ := json.Marshal(show.Videos)
 
 	query := `
-		INSERT INTO shows (id, name, details, price, total_tickets, booked_tickets, location, show_number, show_date, images, videos)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO shows (id, name, details, price, total_tickets, booked_tickets, location, show_number, show_date, images, videos, hold_minutes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.database.GetDB().Exec(query,
-		show.Show_Id.String(),
-		show.ShowName,
-		show.Details,
-		show.Price,
-		show.Total_Tickets,
-		show.Booked_Tickets,
-		show.ShowLocation,
-		show.ShowNumber,
-		show.ShowDate,
-		string(imagesJSON),
-		string(videosJSON),
-	)
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(query,
+			show.Show_Id.String(),
+			show.ShowName,
+			show.Details,
+			show.Price,
+			show.Total_Tickets,
+			show.ShowLocation,
+			show.ShowNumber,
+			show.ShowDate,
+			string(imagesJSON),
+			string(videosJSON),
+			show.HoldMinutes,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create show: %w", err)
+		}
+
+		return writeShowCreated(tx, show)
+	})
 
 	if err != nil {
-		return fmt.Errorf("failed to create show: %w", err)
+		return err
 	}
 
 	// Cache the show data
@@ -84,7 +93,7 @@ func (r *ShowRepository) GetShow(showID string) (*shows.ShowData, error) {
 	// If not in cache, get from database
 	query := `
 		SELECT id, name, details, price, total_tickets, booked_tickets, location, 
-		       show_number, show_date, images, videos, created_at, updated_at
+		       show_number, show_date, images, videos, hold_minutes, require_verified_contact, venue_id, created_at, updated_at
 		FROM shows 
 		WHERE id = ?
 	`
@@ -94,6 +103,7 @@ func (r *ShowRepository) GetShow(showID string) (*shows.ShowData, error) {
 	show := &shows.ShowData{}
 	var createdAt, updatedAt time.Time
 	var imagesJSON, videosJSON string
+	var venueID sql.NullString
 
 	err := row.Scan(
 		&show.Show_Id,
@@ -107,6 +117,9 @@ func (r *ShowRepository) GetShow(showID string) (*shows.ShowData, error) {
 		&show.ShowDate,
 		&imagesJSON,
 		&videosJSON,
+		&show.HoldMinutes,
+		&show.RequireVerifiedContact,
+		&venueID,
 		&createdAt,
 		&updatedAt,
 	)
@@ -119,6 +132,7 @@ func (r *ShowRepository) GetShow(showID string) (*shows.ShowData, error) {
 		if videosJSON != "" {
 			json.Unmarshal([]byte(videosJSON), &show.Videos)
 		}
+		show.VenueID = parseNullUUID(venueID)
 	}
 
 	if err != nil {
@@ -128,6 +142,16 @@ func (r *ShowRepository) GetShow(showID string) (*shows.ShowData, error) {
 		return nil, fmt.Errorf("failed to get show: %w", err)
 	}
 
+	show.TicketTypes, err = ticketTypesForShow(r.database.GetDB(), show.Show_Id)
+	if err != nil {
+		return nil, err
+	}
+
+	show.CancellationPolicy, err = cancellationPolicyForShow(r.database.GetDB(), show.Show_Id)
+	if err != nil {
+		return nil, err
+	}
+
 	// Cache the show for future requests
 	r.cacheShow(show)
 
@@ -176,7 +200,7 @@ func (r *ShowRepository) GetAllShows(filters *SearchFilters, pagination *Paginat
 	countQuery := "SELECT COUNT(*) " + baseQuery
 	selectQuery := `
 		SELECT id, name, details, price, total_tickets, booked_tickets, location, 
-		       show_number, show_date, images, videos, created_at, updated_at 
+		       show_number, show_date, images, videos, hold_minutes, require_verified_contact, venue_id, created_at, updated_at 
 		` + baseQuery
 
 	if len(whereConditions) > 0 {
@@ -210,6 +234,7 @@ func (r *ShowRepository) GetAllShows(filters *SearchFilters, pagination *Paginat
 		show := &shows.ShowData{}
 		var createdAt, updatedAt time.Time
 		var imagesJSON, videosJSON string
+		var venueID sql.NullString
 
 		err := rows.Scan(
 			&show.Show_Id,
@@ -223,6 +248,9 @@ func (r *ShowRepository) GetAllShows(filters *SearchFilters, pagination *Paginat
 			&show.ShowDate,
 			&imagesJSON,
 			&videosJSON,
+			&show.HoldMinutes,
+			&show.RequireVerifiedContact,
+			&venueID,
 			&createdAt,
 			&updatedAt,
 		)
@@ -235,6 +263,7 @@ func (r *ShowRepository) GetAllShows(filters *SearchFilters, pagination *Paginat
 			if videosJSON != "" {
 				json.Unmarshal([]byte(videosJSON), &show.Videos)
 			}
+			show.VenueID = parseNullUUID(venueID)
 		}
 
 		if err != nil {
@@ -301,47 +330,250 @@ func (r *ShowRepository) GetShowsByPriceRange(minPrice, maxPrice int32, location
 	return r.executeShowQuery(query, args...)
 }
 
-// UpdateShow updates an existing show
+// UpdateShow updates an existing show's details. booked_tickets is left to
+// the locked recount in BookingRepository, so a stale copy cannot undo bookings
 func (r *ShowRepository) UpdateShow(show *shows.ShowData) error {
 	imagesJSON, _ := json.Marshal(show.Images)
 	videosJSON, _ := json.Marshal(show.Videos)
 
 	query := `
 		UPDATE shows 
-		SET show_name = ?, details = ?, price = ?, total_tickets = ?, booked_tickets = ?, show_location = ?, 
-		    show_number = ?, show_date = ?, images = ?, videos = ?, updated_at = CURRENT_TIMESTAMP
+		SET name = ?, details = ?, price = ?, total_tickets = ?, location = ?, 
+		    show_number = ?, show_date = ?, images = ?, videos = ?, hold_minutes = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	result, err := r.database.GetDB().Exec(query,
-		show.ShowName,
-		show.Details,
-		show.Price,
-		show.Total_Tickets,
-		show.Booked_Tickets,
-		show.ShowLocation,
-		show.ShowNumber,
-		show.ShowDate,
-		string(imagesJSON),
-		string(videosJSON),
-		show.Show_Id.String(),
-	)
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query,
+			show.ShowName,
+			show.Details,
+			show.Price,
+			show.Total_Tickets,
+			show.Booked_Tickets,
+			show.ShowLocation,
+			show.ShowNumber,
+			show.ShowDate,
+			string(imagesJSON),
+			string(videosJSON),
+			show.HoldMinutes,
+			show.Show_Id.String(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update show: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("show not found: %s", show.Show_Id.String())
+		}
+
+		return writeShowUpdated(tx, show.Show_Id, events.ChangeDetails)
+	})
 
 	if err != nil {
-		return fmt.Errorf("failed to update show: %w", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// Drop the cached copy rather than caching a possibly stale booked count
+	r.removeCachedShow(show.Show_Id.String())
+
+	return nil
+}
+
+// UpdateHoldMinutes sets a show's pending booking hold without touching its
+// other columns, so a stale cached copy cannot overwrite booked_tickets
+func (r *ShowRepository) UpdateHoldMinutes(showID string, holdMinutes int32) error {
+	id, err := uuid.Parse(showID)
 	if err != nil {
-		return fmt.Errorf("failed to check affected rows: %w", err)
+		return fmt.Errorf("show not found: %s", showID)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("show not found: %s", show.Show_Id.String())
+	query := `UPDATE shows SET hold_minutes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	err = r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, holdMinutes, showID)
+		if err != nil {
+			return fmt.Errorf("failed to update hold minutes: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+
+		// MySQL reports 0 affected rows when the value is unchanged, so confirm the show exists
+		if rowsAffected == 0 {
+			var exists int
+			err := tx.QueryRow(`SELECT 1 FROM shows WHERE id = ?`, showID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("show not found: %s", showID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get show: %w", err)
+			}
+		}
+
+		return writeShowUpdated(tx, id, events.ChangeHoldMinutes)
+	})
+
+	if err != nil {
+		return err
 	}
 
-	// Update cache
-	r.cacheShow(show)
+	// The next read repopulates the cache from the updated row
+	r.removeCachedShow(showID)
+
+	return nil
+}
+
+// UpdateContactVerification sets whether a show's bookings need a verified
+// contact, without touching its other columns
+func (r *ShowRepository) UpdateContactVerification(showID string, required bool) error {
+	id, err := uuid.Parse(showID)
+	if err != nil {
+		return fmt.Errorf("show not found: %s", showID)
+	}
+
+	query := `UPDATE shows SET require_verified_contact = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	err = r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, required, showID)
+		if err != nil {
+			return fmt.Errorf("failed to update contact verification: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+
+		// MySQL reports 0 affected rows when the value is unchanged, so confirm the show exists
+		if rowsAffected == 0 {
+			var exists int
+			err := tx.QueryRow(`SELECT 1 FROM shows WHERE id = ?`, showID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("show not found: %s", showID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get show: %w", err)
+			}
+		}
+
+		return writeShowUpdated(tx, id, events.ChangeContactVerification)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The next read repopulates the cache from the updated row
+	r.removeCachedShow(showID)
+
+	return nil
+}
+
+// ReplaceTicketTypes swaps a show's ticket types for a new set. The show row is
+// locked so sales cannot race the change. A ticket type with tickets sold
+// cannot be removed, and its quota cannot drop below what has been sold;
+// existing bookings keep the unit prices they were sold at.
+func (r *ShowRepository) ReplaceTicketTypes(showID uuid.UUID, ticketTypes []*shows.TicketType) error {
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+
+		sold, err := ticketTypeSales(tx, showID)
+		if err != nil {
+			return err
+		}
+
+		replacements := make(map[string]*shows.TicketType, len(ticketTypes))
+		for _, ticketType := range ticketTypes {
+			replacements[ticketType.Code] = ticketType
+		}
+		for code, quantity := range sold {
+			ticketType, kept := replacements[code]
+			if !kept {
+				return fmt.Errorf("cannot remove ticket type %s: %d tickets already sold", code, quantity)
+			}
+			if ticketType.Quota != nil && *ticketType.Quota < quantity {
+				return fmt.Errorf("cannot set %s quota to %d: %d tickets already sold", code, *ticketType.Quota, quantity)
+			}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM show_ticket_types WHERE show_id = ?`, showID.String()); err != nil {
+			return fmt.Errorf("failed to clear ticket types: %w", err)
+		}
+
+		query := `
+			INSERT INTO show_ticket_types (show_id, code, name, price, quota, eligibility, sort_order)
+			VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+		`
+		for i, ticketType := range ticketTypes {
+			_, err := tx.Exec(query,
+				showID.String(),
+				ticketType.Code,
+				ticketType.Name,
+				ticketType.Price,
+				ticketType.Quota,
+				ticketType.Eligibility,
+				i,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to save ticket type %s: %w", ticketType.Code, err)
+			}
+		}
+
+		return writeShowUpdated(tx, showID, events.ChangeTicketTypes)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The next read repopulates the cache with the new ticket types
+	r.removeCachedShow(showID.String())
+
+	return nil
+}
+
+// ReplaceCancellationPolicy sets a show's cancellation policy, or removes it
+// when policy is nil. It applies to cancellations from now on, including of
+// bookings made before the change.
+func (r *ShowRepository) ReplaceCancellationPolicy(showID uuid.UUID, policy *shows.CancellationPolicy) error {
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM show_cancellation_policies WHERE show_id = ?`, showID.String()); err != nil {
+			return fmt.Errorf("failed to clear cancellation policy: %w", err)
+		}
+
+		if policy != nil {
+			query := `
+				INSERT INTO show_cancellation_policies (show_id, full_refund_hours, partial_refund_percent, cutoff_hours)
+				VALUES (?, ?, ?, ?)
+			`
+			_, err := tx.Exec(query, showID.String(), policy.FullRefundHours, policy.PartialRefundPercent, policy.CutoffHours)
+			if err != nil {
+				return fmt.Errorf("failed to save cancellation policy: %w", err)
+			}
+		}
+
+		return writeShowUpdated(tx, showID, events.ChangeCancellationPolicy)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The next read repopulates the cache with the new policy
+	r.removeCachedShow(showID.String())
 
 	return nil
 }
@@ -370,6 +602,16 @@ func (r *ShowRepository) DeleteShow(showID string) error {
 	return nil
 }
 
+// GetCachedShow returns the cached copy of a show without falling back to the database
+func (r *ShowRepository) GetCachedShow(showID string) (*shows.ShowData, error) {
+	return r.getShowFromCache(showID)
+}
+
+// InvalidateShowCache drops the cached copy of a show so the next read goes to the database
+func (r *ShowRepository) InvalidateShowCache(showID string) {
+	r.removeCachedShow(showID)
+}
+
 // Helper methods for caching
 func (r *ShowRepository) cacheShow(show *shows.ShowData) {
 	if jsonData, err := show.ShowToJSON(); err == nil {
@@ -428,3 +670,104 @@ func (r *ShowRepository) executeShowQuery(query string, args ...interface{}) ([]
 
 	return showsList, nil
 }
+
+// parseNullUUID converts a nullable UUID column, ignoring malformed values
+func parseNullUUID(value sql.NullString) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	parsed, err := uuid.Parse(value.String)
+	if err != nil {
+		log.Printf("Warning: invalid UUID in database: %s", value.String)
+		return nil
+	}
+	return &parsed
+}
+
+// nullableUUID converts an optional UUID for a nullable column
+func nullableUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+// ticketTypesForShow loads a show's ticket types in display order
+func ticketTypesForShow(exec sqlExecutor, showID uuid.UUID) ([]*shows.TicketType, error) {
+	query := `
+		SELECT code, name, price, quota, COALESCE(eligibility, '')
+		FROM show_ticket_types
+		WHERE show_id = ?
+		ORDER BY sort_order, code
+	`
+	rows, err := exec.Query(query, showID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ticket types: %w", err)
+	}
+	defer rows.Close()
+
+	var ticketTypes []*shows.TicketType
+	for rows.Next() {
+		ticketType := &shows.TicketType{}
+		var quota sql.NullInt32
+		if err := rows.Scan(&ticketType.Code, &ticketType.Name, &ticketType.Price, &quota, &ticketType.Eligibility); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket type: %w", err)
+		}
+		if quota.Valid {
+			ticketType.Quota = &quota.Int32
+		}
+		ticketTypes = append(ticketTypes, ticketType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ticket types: %w", err)
+	}
+	return ticketTypes, nil
+}
+
+// cancellationPolicyForShow loads a show's cancellation policy, or nil when it has none
+func cancellationPolicyForShow(exec sqlExecutor, showID uuid.UUID) (*shows.CancellationPolicy, error) {
+	query := `
+		SELECT full_refund_hours, partial_refund_percent, cutoff_hours
+		FROM show_cancellation_policies
+		WHERE show_id = ?
+	`
+	policy := &shows.CancellationPolicy{}
+	err := exec.QueryRow(query, showID.String()).Scan(&policy.FullRefundHours, &policy.PartialRefundPercent, &policy.CutoffHours)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// ticketTypeSales sums the tickets of each type held by active bookings of a show
+func ticketTypeSales(exec sqlExecutor, showID uuid.UUID) (map[string]int32, error) {
+	query := `
+		SELECT l.ticket_type, COALESCE(SUM(l.quantity), 0)
+		FROM booking_ticket_lines l
+		JOIN bookings b ON b.booking_id = l.booking_id
+		WHERE b.show_id = ? AND ` + activeBookingFilter("b.") + `
+		GROUP BY l.ticket_type
+	`
+	rows, err := exec.Query(query, showID.String(), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ticket type sales: %w", err)
+	}
+	defer rows.Close()
+
+	sold := make(map[string]int32)
+	for rows.Next() {
+		var code string
+		var quantity int32
+		if err := rows.Scan(&code, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket type sales: %w", err)
+		}
+		sold[code] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ticket type sales: %w", err)
+	}
+	return sold, nil
+}
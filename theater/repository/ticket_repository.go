@@ -0,0 +1,644 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/tickets"
+)
+
+// ticketColumns is the column list read by every ticket query
+const ticketColumns = `ticket_id, booking_id, show_id, COALESCE(ticket_type, ''), COALESCE(seat_id, ''),
+	COALESCE(attendee_name, ''), status, COALESCE(void_reason, ''), checked_in_at, COALESCE(checked_in_gate, ''),
+	COALESCE(checked_in_scan_id, ''), created_at, updated_at`
+
+type TicketRepository struct {
+	database          *db.Database
+	bookingRepository *BookingRepository
+}
+
+func NewTicketRepository() *TicketRepository {
+	return &TicketRepository{
+		database:          db.GetDatabase(),
+		bookingRepository: NewBookingRepository(),
+	}
+}
+
+// GetTicketsByBooking returns a booking's tickets in the order they were issued
+func (r *TicketRepository) GetTicketsByBooking(bookingID string) ([]*tickets.Ticket, error) {
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE booking_id = ? ORDER BY created_at, seat_id, ticket_id`
+
+	rows, err := r.database.GetDB().Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tickets: %w", err)
+	}
+	defer rows.Close()
+
+	bookingTickets := make([]*tickets.Ticket, 0)
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookingTickets = append(bookingTickets, ticket)
+	}
+	return bookingTickets, rows.Err()
+}
+
+// GetTicket retrieves a ticket by ID
+func (r *TicketRepository) GetTicket(ticketID string) (*tickets.Ticket, error) {
+	return getTicket(r.database.GetDB(), ticketID, false)
+}
+
+// UpdateAttendeeName names (or, with an empty name, un-names) the attendee of
+// an active ticket
+func (r *TicketRepository) UpdateAttendeeName(ticketID, attendeeName string) (*tickets.Ticket, error) {
+	var ticket *tickets.Ticket
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		var err error
+		ticket, err = getTicket(tx, ticketID, true)
+		if err != nil {
+			return err
+		}
+		if !ticket.IsActive() {
+			return fmt.Errorf("invalid ticket status: ticket %s is %s", ticketID, ticket.Status)
+		}
+
+		ticket.AttendeeName = attendeeName
+		ticket.UpdatedAt = time.Now()
+
+		query := `UPDATE tickets SET attendee_name = NULLIF(?, ''), updated_at = ? WHERE ticket_id = ?`
+		if _, err := tx.Exec(query, ticket.AttendeeName, ticket.UpdatedAt, ticketID); err != nil {
+			return fmt.Errorf("failed to update attendee name: %w", err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// VoidTicket takes one ticket out of a pending or confirmed booking and puts
+// its place (and seat) back on sale. The booking's ticket count, ticket lines
+// and amount due shrink with it, as does its order's total. The last active
+// ticket of a booking cannot be voided; the booking is cancelled instead.
+func (r *TicketRepository) VoidTicket(ticketID, reason string) (*tickets.Ticket, *ShowInventory, error) {
+	var ticket *tickets.Ticket
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		owner, err := getTicket(tx, ticketID, false)
+		if err != nil {
+			return err
+		}
+
+		// Lock the show before the booking, in the same order as ReserveBooking
+		if _, err := lockShow(tx, owner.ShowID); err != nil {
+			return err
+		}
+
+		booking, err := lockBookingState(tx, owner.BookingID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch {
+		case booking.Status == bookings.StatusPending && booking.HoldExpiresAt != nil && !now.Before(*booking.HoldExpiresAt):
+			return bookings.ErrHoldExpired
+		case booking.Status != bookings.StatusPending && booking.Status != bookings.StatusConfirmed:
+			return fmt.Errorf("invalid ticket status: booking %s is %s", booking.BookingID, booking.Status)
+		case booking.NumberOfTickets <= 1:
+			return fmt.Errorf("invalid ticket status: ticket %s is the last on booking %s; cancel the booking instead",
+				ticketID, booking.BookingID)
+		}
+
+		ticket, err = getTicket(tx, ticketID, true)
+		if err != nil {
+			return err
+		}
+		if !ticket.IsActive() {
+			return fmt.Errorf("invalid ticket status: ticket %s is %s", ticketID, ticket.Status)
+		}
+
+		reduction, err := removeBookingTicket(tx, booking, ticket.TicketType, now)
+		if err != nil {
+			return err
+		}
+
+		if ticket.SeatID != "" {
+			if err := releaseSeat(tx, booking.BookingID, ticket.SeatID); err != nil {
+				return err
+			}
+		}
+
+		if booking.OrderID != "" && reduction > 0 {
+			query := `UPDATE orders SET total_amount = total_amount - ?, updated_at = ? WHERE order_id = ?`
+			if _, err := tx.Exec(query, reduction, now, booking.OrderID); err != nil {
+				return fmt.Errorf("failed to update order total: %w", err)
+			}
+		}
+
+		ticket.Status = tickets.StatusVoided
+		ticket.VoidReason = reason
+		ticket.UpdatedAt = now
+		query := `UPDATE tickets SET status = ?, void_reason = NULLIF(?, ''), updated_at = ? WHERE ticket_id = ?`
+		if _, err := tx.Exec(query, ticket.Status, ticket.VoidReason, now, ticketID); err != nil {
+			return fmt.Errorf("failed to void ticket: %w", err)
+		}
+
+		inventory, err = recountShow(tx, owner.ShowID)
+		return err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The cached booking's ticket count and amounts are stale now
+	r.bookingRepository.removeCachedBooking(ticket.BookingID)
+
+	log.Printf("Ticket voided: %s on booking %s", ticketID, ticket.BookingID)
+	return ticket, inventory, nil
+}
+
+// CheckIn records a scan of a ticket and, when admit lets it in, checks the
+// ticket in. The first ticket checked in moves its booking to checked_in. A
+// scan ID that was already recorded returns its stored outcome, so scanners
+// can safely resend. A scan of a ticket that is already checked in is a
+// duplicate, unless it is an offline scan made before the recorded check-in:
+// the earliest scan wins and takes the check-in over.
+func (r *TicketRepository) CheckIn(scan *tickets.Scan, admit func(ticket *tickets.Ticket, booking *bookings.Booking) string) (*tickets.ScanResult, error) {
+	result := &tickets.ScanResult{Scan: scan}
+	bookingCheckedIn := false
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		recorded, err := getScan(tx, scan.ScanID)
+		if err != nil {
+			return err
+		}
+		if recorded != nil {
+			return replayScan(tx, recorded, result)
+		}
+
+		owner, err := getTicket(tx, scan.TicketID, false)
+		if err != nil {
+			if !strings.Contains(err.Error(), "not found") {
+				return err
+			}
+			scan.Deny(tickets.ReasonTicketNotFound)
+			return insertScan(tx, scan)
+		}
+		scan.BookingID = owner.BookingID
+		scan.ShowID = owner.ShowID
+
+		// Lock the booking before the ticket, in the same order as VoidTicket
+		booking, err := lockBookingState(tx, owner.BookingID)
+		if err != nil {
+			return err
+		}
+
+		ticket, err := getTicket(tx, scan.TicketID, true)
+		if err != nil {
+			return err
+		}
+		result.Ticket = ticket
+
+		if ticket.Status == tickets.StatusCheckedIn {
+			first, err := getScan(tx, ticket.CheckedInScanID)
+			if err != nil {
+				return err
+			}
+
+			if scan.Source != tickets.SourceBatch || ticket.CheckedInAt == nil || !scan.ScannedAt.Before(*ticket.CheckedInAt) {
+				scan.Result = tickets.ScanDuplicate
+				scan.Reason = tickets.ReasonAlreadyCheckedIn
+				result.FirstScan = first
+				return insertScan(tx, scan)
+			}
+
+			if first != nil {
+				first.Result = tickets.ScanDuplicate
+				first.Reason = tickets.ReasonSuperseded
+				query := `UPDATE ticket_scans SET result = ?, reason = ? WHERE scan_id = ?`
+				if _, err := tx.Exec(query, first.Result, first.Reason, first.ScanID); err != nil {
+					return fmt.Errorf("failed to supersede scan: %w", err)
+				}
+				result.Superseded = first
+			}
+		} else if reason := admit(ticket, booking); reason != tickets.ReasonAdmitted {
+			scan.Deny(reason)
+			return insertScan(tx, scan)
+		}
+
+		scan.Result = tickets.ScanCheckedIn
+		ticket.CheckIn(scan)
+		query := `
+			UPDATE tickets SET status = ?, checked_in_at = ?, checked_in_gate = ?, checked_in_scan_id = ?, updated_at = ?
+			WHERE ticket_id = ?
+		`
+		_, err = tx.Exec(query, ticket.Status, ticket.CheckedInAt, ticket.CheckedInGate, ticket.CheckedInScanID,
+			ticket.UpdatedAt, ticket.TicketID)
+		if err != nil {
+			return fmt.Errorf("failed to check in ticket: %w", err)
+		}
+
+		if booking.Status != bookings.StatusCheckedIn {
+			if err := bookings.ValidateTransition(booking.Status, bookings.StatusCheckedIn); err != nil {
+				return err
+			}
+
+			query := `UPDATE bookings SET status = ?, updated_at = ? WHERE booking_id = ?`
+			if _, err := tx.Exec(query, bookings.StatusCheckedIn, scan.ReceivedAt, booking.BookingID); err != nil {
+				return fmt.Errorf("failed to update booking status: %w", err)
+			}
+
+			err = insertStatusChange(tx, &bookings.StatusChange{
+				BookingID:  booking.BookingID,
+				FromStatus: booking.Status,
+				ToStatus:   bookings.StatusCheckedIn,
+				ChangedBy:  scan.ChangedBy(),
+				Reason:     fmt.Sprintf("ticket %s scanned in", ticket.TicketID),
+				ChangedAt:  scan.ReceivedAt,
+			})
+			if err != nil {
+				return err
+			}
+			bookingCheckedIn = true
+		}
+
+		return insertScan(tx, scan)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if bookingCheckedIn {
+		if cachedBooking, err := r.bookingRepository.getBookingFromCache(scan.BookingID); err == nil {
+			cachedBooking.UpdateStatus(bookings.StatusCheckedIn)
+			r.bookingRepository.cacheBooking(cachedBooking)
+		}
+	}
+
+	return result, nil
+}
+
+// RecordScan logs a scan that was denied before it could be tied to a
+// ticket, such as one with a forged token. A scan ID that was already
+// recorded returns its stored outcome instead.
+func (r *TicketRepository) RecordScan(scan *tickets.Scan) (*tickets.ScanResult, error) {
+	result := &tickets.ScanResult{Scan: scan}
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		recorded, err := getScan(tx, scan.ScanID)
+		if err != nil {
+			return err
+		}
+		if recorded != nil {
+			return replayScan(tx, recorded, result)
+		}
+		return insertScan(tx, scan)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAttendance counts the tickets of a show's confirmed bookings and how many
+// of them have been checked in, overall and per gate
+func (r *TicketRepository) GetAttendance(showID uuid.UUID) (*tickets.Attendance, error) {
+	attendance := &tickets.Attendance{ShowID: showID, ByGate: make(map[string]int32)}
+
+	query := `
+		SELECT t.status, COUNT(*)
+		FROM tickets t
+		JOIN bookings b ON b.booking_id = t.booking_id
+		WHERE t.show_id = ? AND t.status IN (?, ?) AND b.status IN (?, ?, ?)
+		GROUP BY t.status
+	`
+	rows, err := r.database.GetDB().Query(query, showID.String(), tickets.StatusActive, tickets.StatusCheckedIn,
+		bookings.StatusConfirmed, bookings.StatusCheckedIn, bookings.StatusNoShow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int32
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan attendance: %w", err)
+		}
+		attendance.Expected += count
+		if status == tickets.StatusCheckedIn {
+			attendance.CheckedIn = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	gateQuery := `
+		SELECT checked_in_gate, COUNT(*), MAX(checked_in_at)
+		FROM tickets
+		WHERE show_id = ? AND status = ?
+		GROUP BY checked_in_gate
+	`
+	gateRows, err := r.database.GetDB().Query(gateQuery, showID.String(), tickets.StatusCheckedIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attendance by gate: %w", err)
+	}
+	defer gateRows.Close()
+
+	for gateRows.Next() {
+		var gateID sql.NullString
+		var count int32
+		var lastScanAt sql.NullTime
+		if err := gateRows.Scan(&gateID, &count, &lastScanAt); err != nil {
+			return nil, fmt.Errorf("failed to scan gate attendance: %w", err)
+		}
+		attendance.ByGate[gateID.String] = count
+		if last := nullableTime(lastScanAt); last != nil && (attendance.LastScanAt == nil || last.After(*attendance.LastScanAt)) {
+			attendance.LastScanAt = last
+		}
+	}
+
+	return attendance, gateRows.Err()
+}
+
+// removeBookingTicket takes one ticket of the given type off a locked booking,
+// updating its ticket count, ticket lines and amounts. It returns how much the
+// amount due went down.
+func removeBookingTicket(tx *sql.Tx, booking *bookings.Booking, ticketType string, now time.Time) (int32, error) {
+	query := `SELECT total_amount, discount_amount FROM bookings WHERE booking_id = ?`
+	if err := tx.QueryRow(query, booking.BookingID).Scan(&booking.TotalAmount, &booking.DiscountAmount); err != nil {
+		return 0, fmt.Errorf("failed to load booking amounts: %w", err)
+	}
+
+	var err error
+	booking.TicketLines, err = bookingTicketLines(tx, booking.BookingID)
+	if err != nil {
+		return 0, err
+	}
+
+	reduction, err := booking.RemoveTicket(ticketType)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		UPDATE bookings SET number_of_tickets = ?, total_amount = ?, discount_amount = ?, updated_at = ?
+		WHERE booking_id = ?
+	`
+	_, err = tx.Exec(query, booking.NumberOfTickets, booking.TotalAmount, booking.DiscountAmount, now, booking.BookingID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	if ticketType != "" {
+		query = `UPDATE booking_ticket_lines SET quantity = quantity - 1 WHERE booking_id = ? AND ticket_type = ?`
+		if _, err := tx.Exec(query, booking.BookingID, ticketType); err != nil {
+			return 0, fmt.Errorf("failed to update ticket line: %w", err)
+		}
+		query = `DELETE FROM booking_ticket_lines WHERE booking_id = ? AND ticket_type = ? AND quantity <= 0`
+		if _, err := tx.Exec(query, booking.BookingID, ticketType); err != nil {
+			return 0, fmt.Errorf("failed to remove ticket line: %w", err)
+		}
+	}
+
+	return reduction, nil
+}
+
+// voidAmendedTickets voids count active tickets of a type (empty for shows
+// without ticket types) dropped from a booking by an amendment. Tickets
+// without an attendee name go first, newest first, so named guests keep theirs.
+func voidAmendedTickets(tx *sql.Tx, bookingID, ticketType string, count int32, reason string, now time.Time) error {
+	query := `
+		SELECT ticket_id FROM tickets
+		WHERE booking_id = ? AND status = ? AND COALESCE(ticket_type, '') = ?
+		ORDER BY attendee_name IS NULL DESC, created_at DESC, ticket_id DESC
+		LIMIT ?
+		FOR UPDATE
+	`
+	rows, err := tx.Query(query, bookingID, tickets.StatusActive, ticketType, count)
+	if err != nil {
+		return fmt.Errorf("failed to query tickets: %w", err)
+	}
+
+	var ticketIDs []string
+	for rows.Next() {
+		var ticketID string
+		if err := rows.Scan(&ticketID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan ticket: %w", err)
+		}
+		ticketIDs = append(ticketIDs, ticketID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query tickets: %w", err)
+	}
+	if int32(len(ticketIDs)) < count {
+		return fmt.Errorf("invalid amendment: booking %s has only %d active tickets to drop", bookingID, len(ticketIDs))
+	}
+
+	query = `UPDATE tickets SET status = ?, void_reason = ?, updated_at = ? WHERE ticket_id = ?`
+	for _, ticketID := range ticketIDs {
+		if _, err := tx.Exec(query, tickets.StatusVoided, reason, now, ticketID); err != nil {
+			return fmt.Errorf("failed to void ticket: %w", err)
+		}
+	}
+	return nil
+}
+
+// issueTickets creates the individual tickets of a newly inserted booking
+func issueTickets(exec sqlExecutor, booking *bookings.Booking) error {
+	issued, err := tickets.NewTicketsForBooking(booking)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tickets (ticket_id, booking_id, show_id, ticket_type, seat_id, status, created_at, updated_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
+	`
+	for _, ticket := range issued {
+		_, err := exec.Exec(query,
+			ticket.TicketID,
+			ticket.BookingID,
+			ticket.ShowID.String(),
+			ticket.TicketType,
+			ticket.SeatID,
+			ticket.Status,
+			ticket.CreatedAt,
+			ticket.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to issue ticket: %w", err)
+		}
+	}
+	return nil
+}
+
+// releaseTickets marks a booking's active tickets released once the booking
+// gives its tickets back to the show
+func releaseTickets(exec sqlExecutor, bookingID string, now time.Time) error {
+	query := `UPDATE tickets SET status = ?, updated_at = ? WHERE booking_id = ? AND status = ?`
+	if _, err := exec.Exec(query, tickets.StatusReleased, now, bookingID, tickets.StatusActive); err != nil {
+		return fmt.Errorf("failed to release tickets: %w", err)
+	}
+	return nil
+}
+
+// replayScan fills in a result from a scan that was already recorded
+func replayScan(tx *sql.Tx, recorded *tickets.Scan, result *tickets.ScanResult) error {
+	result.Scan = recorded
+	result.Replayed = true
+	if recorded.TicketID == "" {
+		return nil
+	}
+
+	ticket, err := getTicket(tx, recorded.TicketID, false)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+		return err
+	}
+	result.Ticket = ticket
+
+	if recorded.Result == tickets.ScanDuplicate && ticket.CheckedInScanID != recorded.ScanID {
+		result.FirstScan, err = getScan(tx, ticket.CheckedInScanID)
+	}
+	return err
+}
+
+// insertScan adds a scan to the scan log
+func insertScan(exec sqlExecutor, scan *tickets.Scan) error {
+	var showID interface{}
+	if scan.ShowID != uuid.Nil {
+		showID = scan.ShowID.String()
+	}
+
+	query := `
+		INSERT INTO ticket_scans (scan_id, ticket_id, booking_id, show_id, gate_id, source, result, reason, scanned_at, received_at)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	_, err := exec.Exec(query,
+		scan.ScanID,
+		scan.TicketID,
+		scan.BookingID,
+		showID,
+		scan.GateID,
+		scan.Source,
+		scan.Result,
+		scan.Reason,
+		scan.ScannedAt,
+		scan.ReceivedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record scan: %w", err)
+	}
+	return nil
+}
+
+// getScan loads a scan from the scan log, returning nil when there is none
+func getScan(exec sqlExecutor, scanID string) (*tickets.Scan, error) {
+	if scanID == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT scan_id, COALESCE(ticket_id, ''), COALESCE(booking_id, ''), show_id, gate_id, source, result,
+			COALESCE(reason, ''), scanned_at, received_at
+		FROM ticket_scans
+		WHERE scan_id = ?
+	`
+	scan := &tickets.Scan{}
+	var showID sql.NullString
+	err := exec.QueryRow(query, scanID).Scan(
+		&scan.ScanID,
+		&scan.TicketID,
+		&scan.BookingID,
+		&showID,
+		&scan.GateID,
+		&scan.Source,
+		&scan.Result,
+		&scan.Reason,
+		&scan.ScannedAt,
+		&scan.ReceivedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan: %w", err)
+	}
+	if parsed := parseNullUUID(showID); parsed != nil {
+		scan.ShowID = *parsed
+	}
+	return scan, nil
+}
+
+// getTicket loads a ticket, optionally taking a row lock on it
+func getTicket(exec sqlExecutor, ticketID string, forUpdate bool) (*tickets.Ticket, error) {
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ticket_id = ?`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	ticket, err := scanTicket(exec.QueryRow(query, ticketID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("ticket not found: %s", ticketID)
+		}
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func scanTicket(row rowScanner) (*tickets.Ticket, error) {
+	ticket := &tickets.Ticket{}
+	var showIDStr string
+	var checkedInAt sql.NullTime
+	err := row.Scan(
+		&ticket.TicketID,
+		&ticket.BookingID,
+		&showIDStr,
+		&ticket.TicketType,
+		&ticket.SeatID,
+		&ticket.AttendeeName,
+		&ticket.Status,
+		&ticket.VoidReason,
+		&checkedInAt,
+		&ticket.CheckedInGate,
+		&ticket.CheckedInScanID,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan ticket: %w", err)
+	}
+
+	ticket.ShowID, err = uuid.Parse(showIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show ID in database: %w", err)
+	}
+	ticket.CheckedInAt = nullableTime(checkedInAt)
+	return ticket, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/users"
+)
+
+type UserRepository struct {
+	database *db.Database
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		database: db.GetDatabase(),
+	}
+}
+
+const userColumns = `user_id, email, name, COALESCE(phone, ''), provider, password_hash, created_at, updated_at`
+
+// CreateUser saves a new account. Each email address can have one account.
+func (r *UserRepository) CreateUser(user *users.User) error {
+	query := `
+		INSERT INTO users (user_id, email, name, phone, provider, password_hash, created_at, updated_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
+	`
+	_, err := r.database.GetDB().Exec(query,
+		user.UserID,
+		user.Email,
+		user.Name,
+		user.Phone,
+		user.Provider,
+		user.PasswordHash,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return fmt.Errorf("email already registered: %s", user.Email)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	log.Printf("User created: %s", user.UserID)
+	return nil
+}
+
+// GetUser retrieves an account by ID
+func (r *UserRepository) GetUser(userID string) (*users.User, error) {
+	user, err := scanUser(r.database.GetDB().QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE user_id = ?`, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found: %s", userID)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// GetUserByEmail retrieves an account by its normalized email address
+func (r *UserRepository) GetUserByEmail(email string) (*users.User, error) {
+	user, err := scanUser(r.database.GetDB().QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found: %s", email)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+func scanUser(row rowScanner) (*users.User, error) {
+	user := &users.User{}
+	err := row.Scan(
+		&user.UserID,
+		&user.Email,
+		&user.Name,
+		&user.Phone,
+		&user.Provider,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/venues"
+)
+
+type VenueRepository struct {
+	database    *db.Database
+	redisClient *utils.RedisAccess
+}
+
+func NewVenueRepository() *VenueRepository {
+	return &VenueRepository{
+		database:    db.GetDatabase(),
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+// CreateVenue inserts a venue and its seat layout in one transaction
+func (r *VenueRepository) CreateVenue(venue *venues.Venue) error {
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		query := `INSERT INTO venues (id, name, location, created_at) VALUES (?, ?, ?, ?)`
+		if _, err := tx.Exec(query, venue.VenueID.String(), venue.Name, venue.Location, venue.CreatedAt); err != nil {
+			return fmt.Errorf("failed to create venue: %w", err)
+		}
+
+		seatQuery := `
+			INSERT INTO venue_seats (venue_id, seat_id, section, row_label, seat_number, accessible, restricted_view)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+		stmt, err := tx.Prepare(seatQuery)
+		if err != nil {
+			return fmt.Errorf("failed to prepare seat insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, seat := range venue.Seats {
+			_, err := stmt.Exec(
+				venue.VenueID.String(),
+				seat.SeatID,
+				seat.Section,
+				seat.Row,
+				seat.Number,
+				seat.Accessible,
+				seat.RestrictedView,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create seat %s: %w", seat.SeatID, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Venue created successfully: %s (%d seats)", venue.Name, venue.SeatCount)
+	return nil
+}
+
+// GetVenue retrieves a venue with its full seat layout
+func (r *VenueRepository) GetVenue(venueID string) (*venues.Venue, error) {
+	query := `SELECT id, name, location, created_at FROM venues WHERE id = ?`
+
+	venue := &venues.Venue{}
+	err := r.database.GetDB().QueryRow(query, venueID).Scan(
+		&venue.VenueID,
+		&venue.Name,
+		&venue.Location,
+		&venue.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("venue not found: %s", venueID)
+		}
+		return nil, fmt.Errorf("failed to get venue: %w", err)
+	}
+
+	seatQuery := `
+		SELECT seat_id, section, row_label, seat_number, accessible, restricted_view
+		FROM venue_seats
+		WHERE venue_id = ?
+		ORDER BY section, row_label, seat_number
+	`
+	rows, err := r.database.GetDB().Query(seatQuery, venueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query venue seats: %w", err)
+	}
+	defer rows.Close()
+
+	venue.Seats = []*venues.Seat{}
+	for rows.Next() {
+		seat := &venues.Seat{}
+		err := rows.Scan(
+			&seat.SeatID,
+			&seat.Section,
+			&seat.Row,
+			&seat.Number,
+			&seat.Accessible,
+			&seat.RestrictedView,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan venue seat: %w", err)
+		}
+		venue.Seats = append(venue.Seats, seat)
+	}
+	venue.SeatCount = int32(len(venue.Seats))
+
+	return venue, nil
+}
+
+// GetAllVenues lists venues with their seat counts but without seat layouts
+func (r *VenueRepository) GetAllVenues() ([]*venues.Venue, error) {
+	query := `
+		SELECT v.id, v.name, v.location, v.created_at, COUNT(vs.seat_id)
+		FROM venues v
+		LEFT JOIN venue_seats vs ON vs.venue_id = v.id
+		GROUP BY v.id, v.name, v.location, v.created_at
+		ORDER BY v.name
+	`
+
+	rows, err := r.database.GetDB().Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query venues: %w", err)
+	}
+	defer rows.Close()
+
+	venueList := []*venues.Venue{}
+	for rows.Next() {
+		venue := &venues.Venue{}
+		err := rows.Scan(
+			&venue.VenueID,
+			&venue.Name,
+			&venue.Location,
+			&venue.CreatedAt,
+			&venue.SeatCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan venue: %w", err)
+		}
+		venueList = append(venueList, venue)
+	}
+
+	return venueList, nil
+}
+
+// AssignVenue turns a show into a reserved-seating show by copying the venue's
+// seats into the show's seat inventory. The show's capacity becomes the
+// venue's seat count. Shows that already hold bookings cannot be reassigned.
+func (r *VenueRepository) AssignVenue(showID, venueID uuid.UUID) (*ShowInventory, error) {
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		if _, err := lockShow(tx, showID); err != nil {
+			return err
+		}
+
+		ticketsSold, err := ticketsSoldForShow(tx, showID)
+		if err != nil {
+			return err
+		}
+		if ticketsSold > 0 {
+			return fmt.Errorf("cannot assign venue: show %s already has %d tickets booked", showID.String(), ticketsSold)
+		}
+
+		var seatCount int32
+		query := `
+			SELECT COUNT(vs.seat_id)
+			FROM venues v
+			LEFT JOIN venue_seats vs ON vs.venue_id = v.id
+			WHERE v.id = ?
+			GROUP BY v.id
+		`
+		if err := tx.QueryRow(query, venueID.String()).Scan(&seatCount); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("venue not found: %s", venueID.String())
+			}
+			return fmt.Errorf("failed to get venue: %w", err)
+		}
+		if seatCount == 0 {
+			return fmt.Errorf("venue %s has no seats", venueID.String())
+		}
+
+		if _, err := tx.Exec(`DELETE FROM show_seats WHERE show_id = ?`, showID.String()); err != nil {
+			return fmt.Errorf("failed to clear show seats: %w", err)
+		}
+
+		copyQuery := `
+			INSERT INTO show_seats (show_id, seat_id, section, row_label, seat_number, accessible, restricted_view, status)
+			SELECT ?, seat_id, section, row_label, seat_number, accessible, restricted_view, ?
+			FROM venue_seats
+			WHERE venue_id = ?
+		`
+		if _, err := tx.Exec(copyQuery, showID.String(), venues.SeatAvailable, venueID.String()); err != nil {
+			return fmt.Errorf("failed to create show seats: %w", err)
+		}
+
+		updateQuery := `UPDATE shows SET venue_id = ?, total_tickets = ?, booked_tickets = 0 WHERE id = ?`
+		if _, err := tx.Exec(updateQuery, venueID.String(), seatCount, showID.String()); err != nil {
+			return fmt.Errorf("failed to assign venue: %w", err)
+		}
+
+		if err := writeShowUpdated(tx, showID, events.ChangeVenue); err != nil {
+			return err
+		}
+
+		inventory = &ShowInventory{
+			ShowID:        showID,
+			TotalTickets:  seatCount,
+			BookedTickets: 0,
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Venue %s assigned to show %s (%d seats)", venueID.String(), showID.String(), inventory.TotalTickets)
+	return inventory, nil
+}
+
+// GetShowSeats returns a show's seat inventory as committed in MySQL
+func (r *VenueRepository) GetShowSeats(showID string) ([]*venues.ShowSeat, error) {
+	query := `
+		SELECT seat_id, section, row_label, seat_number, accessible, restricted_view, status, COALESCE(booking_id, '')
+		FROM show_seats
+		WHERE show_id = ?
+		ORDER BY section, row_label, seat_number
+	`
+
+	rows, err := r.database.GetDB().Query(query, showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query show seats: %w", err)
+	}
+	defer rows.Close()
+
+	seats := []*venues.ShowSeat{}
+	for rows.Next() {
+		seat := &venues.ShowSeat{}
+		err := rows.Scan(
+			&seat.SeatID,
+			&seat.Section,
+			&seat.Row,
+			&seat.Number,
+			&seat.Accessible,
+			&seat.RestrictedView,
+			&seat.Status,
+			&seat.BookingID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan show seat: %w", err)
+		}
+		seats = append(seats, seat)
+	}
+
+	return seats, nil
+}
+
+// Transaction helpers
+
+// showHasSeating reports whether a show sells reserved seats
+func showHasSeating(tx *sql.Tx, showID uuid.UUID) (bool, error) {
+	var venueID sql.NullString
+	if err := tx.QueryRow(`SELECT venue_id FROM shows WHERE id = ?`, showID.String()).Scan(&venueID); err != nil {
+		return false, fmt.Errorf("failed to get show venue: %w", err)
+	}
+	return venueID.Valid, nil
+}
+
+// reserveSeats assigns the requested seats to a booking. Every seat must exist
+// for the show and be available; the seat rows stay locked until commit.
+func reserveSeats(tx *sql.Tx, showID uuid.UUID, bookingID string, seatIDs []string) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(seatIDs)), ", ")
+	args := []interface{}{showID.String()}
+	for _, seatID := range seatIDs {
+		args = append(args, seatID)
+	}
+
+	query := `SELECT seat_id, status FROM show_seats WHERE show_id = ? AND seat_id IN (` + placeholders + `) FOR UPDATE`
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to lock seats: %w", err)
+	}
+
+	found := make(map[string]bool, len(seatIDs))
+	var unavailable []string
+	for rows.Next() {
+		var seatID, status string
+		if err := rows.Scan(&seatID, &status); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan seat: %w", err)
+		}
+		found[seatID] = true
+		if status != venues.SeatAvailable {
+			unavailable = append(unavailable, seatID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock seats: %w", err)
+	}
+
+	for _, seatID := range seatIDs {
+		if !found[seatID] {
+			return fmt.Errorf("invalid seat selection: seat %s does not exist for show %s", seatID, showID.String())
+		}
+	}
+	if len(unavailable) > 0 {
+		return fmt.Errorf("seats unavailable: %s", strings.Join(unavailable, ", "))
+	}
+
+	updateQuery := `UPDATE show_seats SET status = ?, booking_id = ? WHERE show_id = ? AND seat_id IN (` + placeholders + `)`
+	updateArgs := append([]interface{}{venues.SeatReserved, bookingID}, args...)
+	if _, err := tx.Exec(updateQuery, updateArgs...); err != nil {
+		return fmt.Errorf("failed to reserve seats: %w", err)
+	}
+	return nil
+}
+
+// firstAvailableSeats picks up to count available seats in seat map order and
+// locks them until commit
+func firstAvailableSeats(tx *sql.Tx, showID uuid.UUID, count int32) ([]string, error) {
+	query := `
+		SELECT seat_id FROM show_seats
+		WHERE show_id = ? AND status = ?
+		ORDER BY section, row_label, seat_number
+		LIMIT ?
+		FOR UPDATE
+	`
+	rows, err := tx.Query(query, showID.String(), venues.SeatAvailable, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find available seats: %w", err)
+	}
+	defer rows.Close()
+
+	var seats []string
+	for rows.Next() {
+		var seatID string
+		if err := rows.Scan(&seatID); err != nil {
+			return nil, fmt.Errorf("failed to scan seat: %w", err)
+		}
+		seats = append(seats, seatID)
+	}
+	return seats, nil
+}
+
+// releaseSeats returns every seat held by a booking to the show
+func releaseSeats(exec sqlExecutor, bookingID string) error {
+	query := `UPDATE show_seats SET status = ?, booking_id = NULL WHERE booking_id = ?`
+	if _, err := exec.Exec(query, venues.SeatAvailable, bookingID); err != nil {
+		return fmt.Errorf("failed to release seats: %w", err)
+	}
+	return nil
+}
+
+// releaseSeat returns one of a booking's seats to the show
+func releaseSeat(exec sqlExecutor, bookingID, seatID string) error {
+	query := `UPDATE show_seats SET status = ?, booking_id = NULL WHERE booking_id = ? AND seat_id = ?`
+	if _, err := exec.Exec(query, venues.SeatAvailable, bookingID, seatID); err != nil {
+		return fmt.Errorf("failed to release seat: %w", err)
+	}
+	return nil
+}
+
+// bookingSeats returns the seat IDs currently held by a booking
+func bookingSeats(exec sqlExecutor, bookingID string) ([]string, error) {
+	rows, err := exec.Query(`SELECT seat_id FROM show_seats WHERE booking_id = ? ORDER BY seat_id`, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking seats: %w", err)
+	}
+	defer rows.Close()
+
+	var seats []string
+	for rows.Next() {
+		var seatID string
+		if err := rows.Scan(&seatID); err != nil {
+			return nil, fmt.Errorf("failed to scan booking seat: %w", err)
+		}
+		seats = append(seats, seatID)
+	}
+	return seats, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gsmayya/theater/utils"
+)
+
+// Redis key prefixes for contact verification. Keys end in a fingerprint of
+// the contact or token rather than the value itself.
+const (
+	verificationCodePrefix     = "otp:code:"     // Hash of the pending code and its failed attempts
+	verificationCooldownPrefix = "otp:cooldown:" // Present while no new code may be sent
+	verificationRequestsPrefix = "otp:requests:" // Codes sent in the current rate limit window
+	verificationTokenPrefix    = "otp:token:"    // Contact fingerprint a token was issued for
+)
+
+// Fields of a pending code hash
+const (
+	verificationCodeHashField = "code_hash"
+	verificationAttemptsField = "attempts"
+)
+
+// VerificationRepository keeps one-time codes and verification tokens in
+// Redis, where they expire on their own
+type VerificationRepository struct {
+	redisClient *utils.RedisAccess
+}
+
+func NewVerificationRepository() *VerificationRepository {
+	return &VerificationRepository{
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+// StartCooldown starts the resend cooldown for a contact. When one is
+// already running it reports false and how long is left of it.
+func (r *VerificationRepository) StartCooldown(contact string, cooldown time.Duration) (bool, time.Duration, error) {
+	key := verificationCooldownPrefix + contact
+	started, err := utils.SetIfAbsent(key, "1", cooldown, r.redisClient)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to start verification cooldown: %w", err)
+	}
+	if started {
+		return true, 0, nil
+	}
+
+	remaining, err := utils.TimeToLive(key, r.redisClient)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get verification cooldown: %w", err)
+	}
+	return false, remaining, nil
+}
+
+// CountRequest counts a code sent to a contact and returns how many have
+// been sent in the current window
+func (r *VerificationRepository) CountRequest(contact string, window time.Duration) (int64, error) {
+	count, err := utils.IncrementWithExpiry(verificationRequestsPrefix+contact, window, r.redisClient)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count verification request: %w", err)
+	}
+	return count, nil
+}
+
+// SaveCode stores the hash of a new code for a contact, replacing any
+// pending one along with its failed attempts
+func (r *VerificationRepository) SaveCode(contact, codeHash string, ttl time.Duration) error {
+	fields := map[string]interface{}{
+		verificationCodeHashField: codeHash,
+		verificationAttemptsField: 0,
+	}
+	if err := utils.HashSetWithExpiry(verificationCodePrefix+contact, fields, ttl, r.redisClient); err != nil {
+		return fmt.Errorf("failed to save verification code: %w", err)
+	}
+	return nil
+}
+
+// RecordAttempt counts an attempt at a contact's pending code and returns
+// the code's hash and the attempts made so far, including this one. The hash
+// is empty when no code is pending. Counting comes first so concurrent
+// guesses cannot get past the attempt limit.
+func (r *VerificationRepository) RecordAttempt(contact string) (string, int64, error) {
+	key := verificationCodePrefix + contact
+	attempts, err := utils.HashIncrement(key, verificationAttemptsField, 1, r.redisClient)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to record verification attempt: %w", err)
+	}
+
+	codeHash, err := utils.HashGet(key, verificationCodeHashField, r.redisClient)
+	if err != nil {
+		if utils.IsCacheMiss(err) {
+			// The code expired, so the counter just created has no expiry of its own
+			r.DeleteCode(contact)
+			return "", attempts, nil
+		}
+		return "", 0, fmt.Errorf("failed to get verification code: %w", err)
+	}
+	return codeHash, attempts, nil
+}
+
+// DeleteCode removes a contact's pending code
+func (r *VerificationRepository) DeleteCode(contact string) error {
+	if err := utils.DeleteFromCache(verificationCodePrefix+contact, r.redisClient); err != nil {
+		return fmt.Errorf("failed to delete verification code: %w", err)
+	}
+	return nil
+}
+
+// SaveToken records that a token was issued for a contact
+func (r *VerificationRepository) SaveToken(token, contact string, ttl time.Duration) error {
+	if err := utils.AddToCacheWithExpiry(verificationTokenPrefix+token, contact, ttl, r.redisClient); err != nil {
+		return fmt.Errorf("failed to save verification token: %w", err)
+	}
+	return nil
+}
+
+// GetTokenContact returns the contact a token was issued for, or "" when the
+// token is unknown or has expired
+func (r *VerificationRepository) GetTokenContact(token string) (string, error) {
+	contact, err := utils.GetFromCache(verificationTokenPrefix+token, r.redisClient)
+	if err != nil {
+		if utils.IsCacheMiss(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get verification token: %w", err)
+	}
+	return contact, nil
+}
@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/waitlist"
+)
+
+type WaitlistRepository struct {
+	database    *db.Database
+	redisClient *utils.RedisAccess
+}
+
+func NewWaitlistRepository() *WaitlistRepository {
+	return &WaitlistRepository{
+		database:    db.GetDatabase(),
+		redisClient: utils.GetStoreAccess(),
+	}
+}
+
+const waitlistColumns = `
+	id, show_id, contact_type, contact_value, COALESCE(customer_name, ''), number_of_tickets,
+	ticket_types, status, COALESCE(booking_id, ''), offer_expires_at, created_at, updated_at`
+
+// AddEntry puts a customer on a show's waitlist. The show row is locked so the
+// availability check cannot race with bookings and releases: customers can only
+// join while the show, or one of the ticket types they asked for, cannot
+// satisfy their request.
+func (r *WaitlistRepository) AddEntry(entry *waitlist.Entry) error {
+	lines, err := entry.TicketLines()
+	if err != nil {
+		return err
+	}
+
+	ticketTypes, err := encodeTicketTypes(entry.TicketTypes)
+	if err != nil {
+		return err
+	}
+
+	err = r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		totalTickets, err := lockShow(tx, entry.ShowID)
+		if err != nil {
+			return err
+		}
+
+		shortfall, _, err := ticketTypeShortfall(tx, entry.ShowID, lines)
+		if err != nil {
+			return err
+		}
+
+		ticketsSold, err := ticketsSoldForShow(tx, entry.ShowID)
+		if err != nil {
+			return err
+		}
+		if availableTickets := totalTickets - ticketsSold; shortfall == nil && entry.NumberOfTickets <= availableTickets {
+			return fmt.Errorf("tickets available: %d tickets can be booked directly", availableTickets)
+		}
+
+		var existing int
+		query := `
+			SELECT COUNT(*) FROM waitlist_entries
+			WHERE show_id = ? AND contact_type = ? AND contact_value = ? AND status IN (?, ?)
+		`
+		err = tx.QueryRow(query, entry.ShowID.String(), entry.ContactType, entry.ContactValue,
+			waitlist.StatusWaiting, waitlist.StatusOffered).Scan(&existing)
+		if err != nil {
+			return fmt.Errorf("failed to check waitlist: %w", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("contact is already on the waitlist for show %s", entry.ShowID.String())
+		}
+
+		insertQuery := `
+			INSERT INTO waitlist_entries (id, show_id, contact_type, contact_value, customer_name,
+				number_of_tickets, ticket_types, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err = tx.Exec(insertQuery,
+			entry.EntryID,
+			entry.ShowID.String(),
+			entry.ContactType,
+			entry.ContactValue,
+			entry.CustomerName,
+			entry.NumberOfTickets,
+			ticketTypes,
+			entry.Status,
+			entry.CreatedAt,
+			entry.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add waitlist entry: %w", err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Waitlist entry added: %s for show %s (%d tickets)", entry.EntryID, entry.ShowID.String(), entry.NumberOfTickets)
+	return nil
+}
+
+// GetEntry retrieves a waitlist entry, with its queue position while waiting
+func (r *WaitlistRepository) GetEntry(entryID string) (*waitlist.Entry, error) {
+	query := `SELECT ` + waitlistColumns + ` FROM waitlist_entries WHERE id = ?`
+
+	entries, err := r.queryEntries(query, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("waitlist entry not found: %s", entryID)
+	}
+
+	entry := entries[0]
+	if entry.Status == waitlist.StatusWaiting {
+		positionQuery := `
+			SELECT COUNT(*) FROM waitlist_entries
+			WHERE show_id = ? AND status = ? AND (created_at < ? OR (created_at = ? AND id <= ?))
+		`
+		err := r.database.GetDB().QueryRow(positionQuery, entry.ShowID.String(), waitlist.StatusWaiting,
+			entry.CreatedAt, entry.CreatedAt, entry.EntryID).Scan(&entry.Position)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get waitlist position: %w", err)
+		}
+	}
+
+	return entry, nil
+}
+
+// GetEntriesByShow lists a show's waitlist in queue order, optionally filtered by status
+func (r *WaitlistRepository) GetEntriesByShow(showID uuid.UUID, status string) ([]*waitlist.Entry, error) {
+	query := `SELECT ` + waitlistColumns + ` FROM waitlist_entries WHERE show_id = ?`
+	args := []interface{}{showID.String()}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at, id`
+
+	entries, err := r.queryEntries(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	position := int32(0)
+	for _, entry := range entries {
+		if entry.Status == waitlist.StatusWaiting {
+			position++
+			entry.Position = position
+		}
+	}
+	return entries, nil
+}
+
+// RemoveEntry takes a waiting entry off the waitlist. Offered entries are
+// settled through their booking instead.
+func (r *WaitlistRepository) RemoveEntry(entryID string) error {
+	return r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRow(`SELECT status FROM waitlist_entries WHERE id = ? FOR UPDATE`, entryID).Scan(&status)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("waitlist entry not found: %s", entryID)
+			}
+			return fmt.Errorf("failed to get waitlist entry: %w", err)
+		}
+		if status != waitlist.StatusWaiting {
+			return fmt.Errorf("cannot remove waitlist entry %s: entry is %s", entryID, status)
+		}
+
+		query := `UPDATE waitlist_entries SET status = ?, updated_at = ? WHERE id = ?`
+		if _, err := tx.Exec(query, waitlist.StatusRemoved, time.Now(), entryID); err != nil {
+			return fmt.Errorf("failed to remove waitlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// OfferReleasedTickets offers a show's available tickets to its waitlist in
+// FIFO order. Each offer is a pending booking, held for holdDuration, created
+// in the same transaction that marks the entry offered. Entries asking for
+// more tickets than remain, overall or in a ticket type's quota, are skipped
+// so smaller requests behind them can still be served. Offers are priced at
+// the show's current prices. Reserved-seating offers take the first available
+// seats in seat map order.
+func (r *WaitlistRepository) OfferReleasedTickets(showID uuid.UUID, holdDuration time.Duration, changedBy string) ([]*waitlist.Entry, *ShowInventory, error) {
+	var offers []*waitlist.Entry
+	var inventory *ShowInventory
+
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		totalTickets, err := lockShow(tx, showID)
+		if err != nil {
+			return err
+		}
+
+		ticketsSold, err := ticketsSoldForShow(tx, showID)
+		if err != nil {
+			return err
+		}
+		availableTickets := totalTickets - ticketsSold
+		if availableTickets <= 0 {
+			return nil
+		}
+
+		var price int32
+		if err := tx.QueryRow(`SELECT price FROM shows WHERE id = ?`, showID.String()).Scan(&price); err != nil {
+			return fmt.Errorf("failed to get show price: %w", err)
+		}
+
+		ticketTypes, err := ticketTypesForShow(tx, showID)
+		if err != nil {
+			return err
+		}
+		prices := shows.TicketPrices(ticketTypes)
+
+		seated, err := showHasSeating(tx, showID)
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + waitlistColumns + `
+			FROM waitlist_entries
+			WHERE show_id = ? AND status = ?
+			ORDER BY created_at, id
+			FOR UPDATE`
+		waiting, err := scanEntries(tx, query, showID.String(), waitlist.StatusWaiting)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range waiting {
+			if availableTickets <= 0 {
+				break
+			}
+			if entry.NumberOfTickets > availableTickets {
+				continue
+			}
+
+			booking, err := entry.NewOfferBooking(holdDuration)
+			if err != nil {
+				return err
+			}
+			if err := booking.ApplyPrices(price, prices); err != nil {
+				// The show's ticket types changed after the customer joined
+				log.Printf("Warning: Skipping waitlist entry %s: %v", entry.EntryID, err)
+				continue
+			}
+
+			shortfall, _, err := ticketTypeShortfall(tx, showID, booking.TicketLines)
+			if err != nil {
+				return err
+			}
+			if shortfall != nil {
+				continue
+			}
+
+			if seated {
+				seats, err := firstAvailableSeats(tx, showID, entry.NumberOfTickets)
+				if err != nil {
+					return err
+				}
+				if int32(len(seats)) < entry.NumberOfTickets {
+					continue
+				}
+				booking.Seats = seats
+			}
+
+			if err := insertBooking(tx, booking); err != nil {
+				return err
+			}
+			if err := insertTicketLines(tx, booking.BookingID, booking.TicketLines); err != nil {
+				return err
+			}
+			if len(booking.Seats) > 0 {
+				if err := reserveSeats(tx, showID, booking.BookingID, booking.Seats); err != nil {
+					return err
+				}
+			}
+			if err := issueTickets(tx, booking); err != nil {
+				return err
+			}
+
+			err = insertStatusChange(tx, &bookings.StatusChange{
+				BookingID: booking.BookingID,
+				ToStatus:  booking.Status,
+				ChangedBy: changedBy,
+				Reason:    fmt.Sprintf("waitlist offer for entry %s", entry.EntryID),
+				ChangedAt: booking.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+
+			updateQuery := `
+				UPDATE waitlist_entries SET status = ?, booking_id = ?, offer_expires_at = ?, updated_at = ?
+				WHERE id = ?
+			`
+			_, err = tx.Exec(updateQuery, waitlist.StatusOffered, booking.BookingID, booking.HoldExpiresAt,
+				booking.CreatedAt, entry.EntryID)
+			if err != nil {
+				return fmt.Errorf("failed to record waitlist offer: %w", err)
+			}
+
+			entry.Status = waitlist.StatusOffered
+			entry.BookingID = booking.BookingID
+			entry.OfferExpiresAt = booking.HoldExpiresAt
+			entry.UpdatedAt = booking.CreatedAt
+			offers = append(offers, entry)
+
+			availableTickets -= entry.NumberOfTickets
+		}
+
+		if len(offers) == 0 {
+			return nil
+		}
+
+		inventory, err = recountShow(tx, showID)
+		return err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return offers, inventory, nil
+}
+
+func (r *WaitlistRepository) queryEntries(query string, args ...interface{}) ([]*waitlist.Entry, error) {
+	return scanEntries(r.database.GetDB(), query, args...)
+}
+
+func scanEntries(exec sqlExecutor, query string, args ...interface{}) ([]*waitlist.Entry, error) {
+	rows, err := exec.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query waitlist: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*waitlist.Entry{}
+	for rows.Next() {
+		entry := &waitlist.Entry{}
+		var showIDStr string
+		var ticketTypes sql.NullString
+		var offerExpiresAt sql.NullTime
+
+		err := rows.Scan(
+			&entry.EntryID,
+			&showIDStr,
+			&entry.ContactType,
+			&entry.ContactValue,
+			&entry.CustomerName,
+			&entry.NumberOfTickets,
+			&ticketTypes,
+			&entry.Status,
+			&entry.BookingID,
+			&offerExpiresAt,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+
+		showID, err := uuid.Parse(showIDStr)
+		if err != nil {
+			log.Printf("Warning: invalid show ID in database: %s", showIDStr)
+			continue
+		}
+		entry.ShowID = showID
+		entry.OfferExpiresAt = nullableTime(offerExpiresAt)
+
+		if ticketTypes.Valid && ticketTypes.String != "" {
+			if err := json.Unmarshal([]byte(ticketTypes.String), &entry.TicketTypes); err != nil {
+				log.Printf("Warning: invalid ticket types for waitlist entry %s: %v", entry.EntryID, err)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read waitlist: %w", err)
+	}
+
+	return entries, nil
+}
+
+// encodeTicketTypes stores an entry's per-type quantities as JSON, or NULL
+// for shows without ticket types
+func encodeTicketTypes(quantities map[string]int32) (interface{}, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(quantities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ticket types: %w", err)
+	}
+	return string(data), nil
+}
+
+// Transaction helpers
+
+// settleWaitlistOffer records the outcome of a waitlist offer when the
+// booking it created changes status. Bookings that did not come from the
+// waitlist are left alone.
+func settleWaitlistOffer(exec sqlExecutor, bookingID, entryStatus string, now time.Time) error {
+	query := `UPDATE waitlist_entries SET status = ?, updated_at = ? WHERE booking_id = ? AND status = ?`
+	if _, err := exec.Exec(query, entryStatus, now, bookingID, waitlist.StatusOffered); err != nil {
+		return fmt.Errorf("failed to settle waitlist offer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/webhooks"
+)
+
+// webhookSubscriptionColumns lists the webhook_subscriptions columns in the order scanWebhookSubscription reads them
+const webhookSubscriptionColumns = `subscription_id, url, COALESCE(event_types, ''), secret, COALESCE(description, ''),
+	active, consecutive_failures, disabled_at, COALESCE(disabled_reason, ''), created_at, updated_at`
+
+// webhookDeliveryColumns lists the webhook_deliveries columns in the order scanWebhookDelivery reads them
+const webhookDeliveryColumns = `delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at`
+
+type WebhookRepository struct {
+	database *db.Database
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		database: db.GetDatabase(),
+	}
+}
+
+// CreateSubscription saves a new webhook subscription
+func (r *WebhookRepository) CreateSubscription(subscription *webhooks.Subscription) error {
+	now := time.Now()
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+
+	query := `
+		INSERT INTO webhook_subscriptions (subscription_id, url, event_types, secret, description, active,
+			consecutive_failures, disabled_at, disabled_reason, created_at, updated_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	_, err := r.database.GetDB().Exec(query,
+		subscription.SubscriptionID,
+		subscription.URL,
+		strings.Join(subscription.EventTypes, ","),
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.ConsecutiveFailures,
+		subscription.DisabledAt,
+		subscription.DisabledReason,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	log.Printf("Webhook subscription created: %s -> %s", subscription.SubscriptionID, subscription.URL)
+	return nil
+}
+
+// GetSubscription retrieves a webhook subscription, including its secret
+func (r *WebhookRepository) GetSubscription(subscriptionID string) (*webhooks.Subscription, error) {
+	subscription, err := scanWebhookSubscription(r.database.GetDB().QueryRow(
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE subscription_id = ?`, subscriptionID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook subscription not found: %s", subscriptionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// GetAllSubscriptions lists webhook subscriptions, newest first
+func (r *WebhookRepository) GetAllSubscriptions() ([]*webhooks.Subscription, error) {
+	return r.querySubscriptions(`SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY created_at DESC`)
+}
+
+// GetActiveSubscriptions lists the subscriptions that receive deliveries
+func (r *WebhookRepository) GetActiveSubscriptions() ([]*webhooks.Subscription, error) {
+	return r.querySubscriptions(`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE active = ?`, true)
+}
+
+// UpdateSubscription saves every field of a subscription
+func (r *WebhookRepository) UpdateSubscription(subscription *webhooks.Subscription) error {
+	subscription.UpdatedAt = time.Now()
+
+	result, err := updateWebhookSubscription(r.database.GetDB(), subscription)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("webhook subscription not found: %s", subscription.SubscriptionID)
+	}
+	return nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (r *WebhookRepository) DeleteSubscription(subscriptionID string) error {
+	result, err := r.database.GetDB().Exec(`DELETE FROM webhook_subscriptions WHERE subscription_id = ?`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("webhook subscription not found: %s", subscriptionID)
+	}
+
+	log.Printf("Webhook subscription deleted: %s", subscriptionID)
+	return nil
+}
+
+// EnqueueDeliveries queues deliveries in one transaction. An event already
+// queued for a subscription is skipped, so handling the same event twice
+// does not deliver it twice.
+func (r *WebhookRepository) EnqueueDeliveries(deliveries []*webhooks.Delivery) error {
+	return r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		query := `
+			INSERT IGNORE INTO webhook_deliveries (delivery_id, subscription_id, event_id, event_type, payload, status,
+				attempts, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+		`
+		for _, delivery := range deliveries {
+			_, err := tx.Exec(query,
+				delivery.DeliveryID,
+				delivery.SubscriptionID,
+				delivery.EventID,
+				delivery.EventType,
+				string(delivery.Payload),
+				delivery.Status,
+				delivery.NextAttemptAt,
+				delivery.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to queue webhook delivery: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimDueDeliveries claims up to limit pending deliveries to active
+// subscriptions whose next attempt is due, oldest first, for the given lease.
+// Deliveries claimed by another instance are skipped until its lease runs out.
+func (r *WebhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]*webhooks.Delivery, string, error) {
+	claim := uuid.New().String()
+
+	query := `
+		UPDATE webhook_deliveries SET claimed_by = ?, claimed_until = ?
+		WHERE status = ? AND next_attempt_at <= ? AND (claimed_until IS NULL OR claimed_until < ?)
+			AND subscription_id IN (SELECT subscription_id FROM webhook_subscriptions WHERE active = ?)
+		ORDER BY next_attempt_at, created_at
+		LIMIT ?
+	`
+	result, err := r.database.GetDB().Exec(query, claim, now.Add(lease), webhooks.DeliveryPending, now, now, true, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return nil, claim, err
+	}
+
+	query = `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE claimed_by = ? ORDER BY next_attempt_at, created_at`
+	claimedDeliveries, err := r.queryDeliveries(query, claim)
+	return claimedDeliveries, claim, err
+}
+
+// SaveAttempt records an attempt in the delivery log, saves the delivery's
+// outcome and releases the claim on it, and counts the attempt against the
+// subscription, disabling it after disableAfter failures in a row. It returns
+// the updated subscription and whether this attempt disabled it; the
+// subscription is nil when the claim had lapsed and nothing was saved.
+func (r *WebhookRepository) SaveAttempt(delivery *webhooks.Delivery, attempt *webhooks.Attempt, claim string,
+	disableAfter int32) (*webhooks.Subscription, bool, error) {
+	var subscription *webhooks.Subscription
+	disabled := false
+	err := r.database.ExecuteInTransaction(func(tx *sql.Tx) error {
+		query := `
+			UPDATE webhook_deliveries
+			SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = NULLIF(?, 0),
+				last_error = NULLIF(?, ''), delivered_at = ?, claimed_by = NULL, claimed_until = NULL
+			WHERE delivery_id = ? AND claimed_by = ?
+		`
+		result, err := tx.Exec(query,
+			delivery.Status,
+			delivery.Attempts,
+			delivery.NextAttemptAt,
+			delivery.LastStatusCode,
+			delivery.LastError,
+			delivery.DeliveredAt,
+			delivery.DeliveryID,
+			claim,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save webhook delivery: %w", err)
+		}
+		if saved, err := result.RowsAffected(); err != nil || saved == 0 {
+			return err
+		}
+
+		if err := insertWebhookAttempt(tx, delivery.DeliveryID, attempt); err != nil {
+			return err
+		}
+
+		subscription, err = scanWebhookSubscription(tx.QueryRow(
+			`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE subscription_id = ? FOR UPDATE`,
+			delivery.SubscriptionID))
+		if err != nil {
+			return fmt.Errorf("failed to lock webhook subscription: %w", err)
+		}
+
+		if attempt.Succeeded() {
+			subscription.RecordSuccess()
+		} else {
+			disabled = subscription.RecordFailure(attempt.AttemptedAt, disableAfter)
+		}
+		subscription.UpdatedAt = time.Now()
+		_, err = updateWebhookSubscription(tx, subscription)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return subscription, disabled, nil
+}
+
+// GetDelivery retrieves a delivery with its attempt log
+func (r *WebhookRepository) GetDelivery(deliveryID string) (*webhooks.Delivery, error) {
+	delivery, err := scanWebhookDelivery(r.database.GetDB().QueryRow(
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE delivery_id = ?`, deliveryID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery not found: %s", deliveryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	query := `
+		SELECT attempted_at, COALESCE(status_code, 0), COALESCE(response_body, ''), COALESCE(error, ''), duration_ms
+		FROM webhook_attempts WHERE delivery_id = ? ORDER BY attempted_at, id
+	`
+	rows, err := r.database.GetDB().Query(query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	delivery.AttemptLog = make([]*webhooks.Attempt, 0)
+	for rows.Next() {
+		attempt := &webhooks.Attempt{}
+		if err := rows.Scan(&attempt.AttemptedAt, &attempt.StatusCode, &attempt.ResponseBody, &attempt.Error, &attempt.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// GetDeliveries returns up to limit deliveries to a subscription, newest
+// first, optionally only those in one status
+func (r *WebhookRepository) GetDeliveries(subscriptionID, status string, limit int) ([]*webhooks.Delivery, error) {
+	if status == "" {
+		query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = ? ORDER BY created_at DESC LIMIT ?`
+		return r.queryDeliveries(query, subscriptionID, limit)
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = ? AND status = ? ORDER BY created_at DESC LIMIT ?`
+	return r.queryDeliveries(query, subscriptionID, status, limit)
+}
+
+// RequeueDelivery queues a succeeded or failed delivery again with a fresh
+// set of attempts. Its attempt log is kept.
+func (r *WebhookRepository) RequeueDelivery(deliveryID string, now time.Time) (*webhooks.Delivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, claimed_by = NULL, claimed_until = NULL
+		WHERE delivery_id = ? AND status != ?
+	`
+	result, err := r.database.GetDB().Exec(query, webhooks.DeliveryPending, now, deliveryID, webhooks.DeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	delivery, err := r.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if requeued == 0 {
+		return nil, fmt.Errorf("invalid delivery status: delivery %s is already pending", deliveryID)
+	}
+	return delivery, nil
+}
+
+// DeleteSucceededDeliveries removes succeeded deliveries, and their attempt
+// logs, delivered at or before the given time
+func (r *WebhookRepository) DeleteSucceededDeliveries(before time.Time) (int64, error) {
+	query := `DELETE FROM webhook_deliveries WHERE status = ? AND delivered_at <= ?`
+	result, err := r.database.GetDB().Exec(query, webhooks.DeliverySucceeded, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return deleted, nil
+}
+
+func (r *WebhookRepository) querySubscriptions(query string, args ...interface{}) ([]*webhooks.Subscription, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*webhooks.Subscription, 0)
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (r *WebhookRepository) queryDeliveries(query string, args ...interface{}) ([]*webhooks.Delivery, error) {
+	rows, err := r.database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*webhooks.Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// updateWebhookSubscription writes every mutable column of a subscription
+func updateWebhookSubscription(exec sqlExecutor, subscription *webhooks.Subscription) (sql.Result, error) {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = ?, event_types = NULLIF(?, ''), secret = ?, description = NULLIF(?, ''), active = ?,
+			consecutive_failures = ?, disabled_at = ?, disabled_reason = NULLIF(?, ''), updated_at = ?
+		WHERE subscription_id = ?
+	`
+	result, err := exec.Exec(query,
+		subscription.URL,
+		strings.Join(subscription.EventTypes, ","),
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.ConsecutiveFailures,
+		subscription.DisabledAt,
+		subscription.DisabledReason,
+		subscription.UpdatedAt,
+		subscription.SubscriptionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return result, nil
+}
+
+// insertWebhookAttempt adds an attempt to a delivery's log
+func insertWebhookAttempt(exec sqlExecutor, deliveryID string, attempt *webhooks.Attempt) error {
+	query := `
+		INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, response_body, error, duration_ms)
+		VALUES (?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?)
+	`
+	_, err := exec.Exec(query,
+		deliveryID,
+		attempt.AttemptedAt,
+		attempt.StatusCode,
+		attempt.ResponseBody,
+		attempt.Error,
+		attempt.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// scanWebhookSubscription reads a row selected with webhookSubscriptionColumns
+func scanWebhookSubscription(row rowScanner) (*webhooks.Subscription, error) {
+	subscription := &webhooks.Subscription{}
+	var eventTypes string
+	var disabledAt sql.NullTime
+	err := row.Scan(
+		&subscription.SubscriptionID,
+		&subscription.URL,
+		&eventTypes,
+		&subscription.Secret,
+		&subscription.Description,
+		&subscription.Active,
+		&subscription.ConsecutiveFailures,
+		&disabledAt,
+		&subscription.DisabledReason,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.EventTypes = []string{}
+	if eventTypes != "" {
+		subscription.EventTypes = strings.Split(eventTypes, ",")
+	}
+	if disabledAt.Valid {
+		subscription.DisabledAt = &disabledAt.Time
+	}
+	return subscription, nil
+}
+
+// scanWebhookDelivery reads a row selected with webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (*webhooks.Delivery, error) {
+	delivery := &webhooks.Delivery{}
+	var payload string
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&delivery.DeliveryID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = []byte(payload)
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
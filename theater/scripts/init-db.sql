@@ -12,6 +12,34 @@ USE theater_booking;
 -- Set MySQL 8.0 specific SQL modes for better compatibility
 SET sql_mode = 'STRICT_TRANS_TABLES,NO_ZERO_DATE,NO_ZERO_IN_DATE,ERROR_FOR_DIVISION_BY_ZERO';
 
+-- Venues table (physical seat layouts that shows can be assigned to)
+CREATE TABLE IF NOT EXISTS venues (
+    id VARCHAR(36) PRIMARY KEY,                    -- UUID as string
+    name VARCHAR(255) NOT NULL,                    -- Venue name
+    location VARCHAR(255) NOT NULL,                -- Venue address or city
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    INDEX idx_venues_name (name)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Venue seats (the seat layout of each venue)
+CREATE TABLE IF NOT EXISTS venue_seats (
+    venue_id VARCHAR(36) NOT NULL,                 -- Foreign key to venues.id
+    seat_id VARCHAR(64) NOT NULL,                  -- Seat label (e.g., STALLS-A-12)
+    section VARCHAR(50) NOT NULL,                  -- Section name (e.g., STALLS)
+    row_label VARCHAR(10) NOT NULL,                -- Row name (e.g., A)
+    seat_number INT NOT NULL,                      -- Seat number within the row
+    accessible BOOLEAN DEFAULT FALSE,              -- Wheelchair accessible
+    restricted_view BOOLEAN DEFAULT FALSE,         -- Partially obstructed view
+    
+    PRIMARY KEY (venue_id, seat_id),
+    FOREIGN KEY (venue_id) REFERENCES venues(id) ON DELETE CASCADE
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
 -- Shows table with updated structure
 CREATE TABLE IF NOT EXISTS shows (
     id VARCHAR(36) PRIMARY KEY,                    -- UUID as string
@@ -25,9 +53,14 @@ CREATE TABLE IF NOT EXISTS shows (
     show_date DATETIME NOT NULL,                   -- Date and time of the show
     images JSON,                                   -- Array of CMS image IDs
     videos JSON,                                   -- Array of CMS video IDs
+    hold_minutes INT DEFAULT 0,                    -- Pending booking hold in minutes (0 = system default)
+    require_verified_contact BOOLEAN NOT NULL DEFAULT FALSE, -- Bookings need an OTP-verified contact
+    venue_id VARCHAR(36) NULL,                     -- Reserved-seating venue (NULL = general admission)
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
+    FOREIGN KEY (venue_id) REFERENCES venues(id),
+    
     -- Indexes for performance (MySQL 8.0 optimized)
     INDEX idx_location (location),
     INDEX idx_price (price),
@@ -47,25 +80,73 @@ CREATE TABLE IF NOT EXISTS shows (
   ROW_FORMAT=DYNAMIC 
   COMPRESSION='ZLIB';
 
+-- Customer accounts (sessions and their tokens live in Redis)
+CREATE TABLE IF NOT EXISTS users (
+    user_id VARCHAR(36) PRIMARY KEY,               -- UUID
+    email VARCHAR(255) NOT NULL,                   -- Lower-cased sign-in email
+    name VARCHAR(255) NOT NULL,                    -- Display name
+    phone VARCHAR(20) NULL,                        -- Optional mobile number in E.164
+    provider ENUM('local', 'google') NOT NULL DEFAULT 'local',
+    password_hash VARCHAR(255) NOT NULL,           -- bcrypt hash of the password
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY uq_users_email (email)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Orders (bookings for several shows made in one checkout)
+CREATE TABLE IF NOT EXISTS orders (
+    order_id VARCHAR(20) PRIMARY KEY,              -- Hash-based unique ID (OR-XXXXXXXXX)
+    contact_type ENUM('mobile', 'email') NOT NULL, -- Type of contact information
+    contact_value VARCHAR(255) NOT NULL,           -- Mobile number or email address
+    customer_name VARCHAR(255),                    -- Optional customer name
+    status ENUM('pending', 'confirmed', 'cancelled', 'expired') DEFAULT 'pending',
+    total_amount INT NOT NULL,                     -- Sum of the bookings' totals
+    hold_expires_at DATETIME NULL,                 -- Shared hold of the order's pending bookings
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    INDEX idx_orders_contact (contact_type, contact_value),
+    INDEX idx_orders_status (status)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
 -- Bookings table
 CREATE TABLE IF NOT EXISTS bookings (
-    booking_id VARCHAR(20) PRIMARY KEY,            -- Hash-based unique ID (BK-XXXXXXXXX)
+    booking_id VARCHAR(20) PRIMARY KEY,            -- Hash-based internal ID (BK-XXXXXXXXX)
+    reference VARCHAR(11) NULL,                    -- Customer-facing reference (XXXXX-XXXXX)
     show_id VARCHAR(36) NOT NULL,                  -- Foreign key to shows.id
     contact_type ENUM('mobile', 'email') NOT NULL, -- Type of contact information
     contact_value VARCHAR(255) NOT NULL,           -- Mobile number or email address
     number_of_tickets INT NOT NULL,                -- Number of tickets booked
     customer_name VARCHAR(255),                    -- Optional customer name
     total_amount INT NOT NULL,                     -- Total amount paid/to be paid
+    promo_code VARCHAR(50) NULL,                   -- Promo code redeemed by the booking
+    discount_amount INT NOT NULL DEFAULT 0,        -- Amount taken off by the promo code
+    order_id VARCHAR(20) NULL,                     -- Parent order for multi-show checkouts
+    user_id VARCHAR(36) NULL,                      -- Account of the signed-in customer, if any
+    balance_due INT NOT NULL DEFAULT 0,            -- Owed for tickets added to a confirmed booking
     booking_date DATETIME NOT NULL,                -- When the booking was made for
-    status ENUM('pending', 'confirmed', 'cancelled') DEFAULT 'pending',
+    status ENUM('pending', 'confirmed', 'cancelled', 'expired', 'checked_in', 'refunded', 'no_show', 'exchanged') DEFAULT 'pending',
+    hold_expires_at DATETIME NULL,                 -- When a pending booking releases its tickets
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
     -- Foreign key constraint
     FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    
+    -- Customer-facing references are unique
+    UNIQUE KEY uq_bookings_reference (reference),
     
     -- Indexes for performance (MySQL 8.0 optimized)
     INDEX idx_bookings_show_id (show_id),
+    INDEX idx_bookings_order_id (order_id),
+    INDEX idx_bookings_user_id (user_id),
     INDEX idx_bookings_contact (contact_type, contact_value),
     INDEX idx_bookings_status (status),
     INDEX idx_bookings_date (booking_date),
@@ -73,9 +154,403 @@ CREATE TABLE IF NOT EXISTS bookings (
     
     -- Composite indexes for common queries
     INDEX idx_bookings_show_status (show_id, status),
+    INDEX idx_bookings_status_hold (status, hold_expires_at),
     INDEX idx_bookings_contact_status (contact_type, contact_value, status),
     INDEX idx_bookings_date_status (booking_date, status),
-    INDEX idx_bookings_show_date (show_id, booking_date)
+    INDEX idx_bookings_show_date (show_id, booking_date),
+    INDEX idx_bookings_promo_contact (promo_code, contact_type, contact_value)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Show seats (per-performance seat inventory, copied from venue_seats)
+CREATE TABLE IF NOT EXISTS show_seats (
+    show_id VARCHAR(36) NOT NULL,                  -- Foreign key to shows.id
+    seat_id VARCHAR(64) NOT NULL,                  -- Seat label (e.g., STALLS-A-12)
+    section VARCHAR(50) NOT NULL,
+    row_label VARCHAR(10) NOT NULL,
+    seat_number INT NOT NULL,
+    accessible BOOLEAN DEFAULT FALSE,
+    restricted_view BOOLEAN DEFAULT FALSE,
+    status ENUM('available', 'reserved') NOT NULL DEFAULT 'available',
+    booking_id VARCHAR(20) NULL,                   -- Booking holding the seat
+    
+    PRIMARY KEY (show_id, seat_id),
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_show_seats_booking (booking_id),
+    INDEX idx_show_seats_status (show_id, status)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Booking status history (audit trail of every status transition)
+CREATE TABLE IF NOT EXISTS booking_status_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    from_status VARCHAR(20),                       -- NULL for the initial status
+    to_status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,              -- Who made the change (e.g. api, system:hold-reaper)
+    reason VARCHAR(500),                           -- Optional free-text reason
+    changed_at DATETIME NOT NULL,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_status_history_booking (booking_id, changed_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Booking amendments (versioned changes to a booking's ticket count)
+CREATE TABLE IF NOT EXISTS booking_amendments (
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    version INT NOT NULL,                          -- Booking version after the change, from 1
+    ticket_type VARCHAR(30) NULL,                  -- Ticket type added or dropped; NULL for single-price shows
+    from_tickets INT NOT NULL,
+    to_tickets INT NOT NULL,
+    from_amount INT NOT NULL,                      -- Amount due before the change
+    to_amount INT NOT NULL,                        -- Amount due after the change
+    changed_by VARCHAR(255) NOT NULL,              -- Who amended the booking
+    reason VARCHAR(500),                           -- Optional free-text reason
+    created_at DATETIME NOT NULL,
+    
+    PRIMARY KEY (booking_id, version),
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Booking exchanges (tickets moved from one show's booking to a new booking on another)
+CREATE TABLE IF NOT EXISTS booking_exchanges (
+    exchange_id VARCHAR(20) PRIMARY KEY,           -- EX- followed by 16 hex characters
+    source_booking_id VARCHAR(20) NOT NULL,        -- Booking the tickets were moved from
+    target_booking_id VARCHAR(20) NOT NULL,        -- Booking created on the target show
+    source_show_id VARCHAR(36) NOT NULL,
+    target_show_id VARCHAR(36) NOT NULL,
+    number_of_tickets INT NOT NULL,                -- Tickets moved
+    source_amount INT NOT NULL,                    -- What the moved tickets cost on the source booking
+    target_amount INT NOT NULL,                    -- What they cost on the target show
+    changed_by VARCHAR(255) NOT NULL,              -- Who exchanged the booking
+    reason VARCHAR(500),                           -- Optional free-text reason
+    created_at DATETIME NOT NULL,
+    
+    FOREIGN KEY (source_booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    FOREIGN KEY (target_booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    
+    INDEX idx_exchanges_source (source_booking_id),
+    INDEX idx_exchanges_target (target_booking_id),
+    INDEX idx_exchanges_source_show (source_show_id, created_at),
+    INDEX idx_exchanges_target_show (target_show_id, created_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Show ticket types (price tiers such as adult, child or VIP)
+CREATE TABLE IF NOT EXISTS show_ticket_types (
+    show_id VARCHAR(36) NOT NULL,                  -- Foreign key to shows.id
+    code VARCHAR(30) NOT NULL,                     -- Lower-case code used in booking requests
+    name VARCHAR(100) NOT NULL,
+    price INT NOT NULL,
+    quota INT NULL,                                -- Most tickets of this type; NULL = no tier limit
+    eligibility VARCHAR(255) NULL,                 -- e.g. Under 12 years old
+    sort_order INT NOT NULL DEFAULT 0,             -- Display order
+    
+    PRIMARY KEY (show_id, code),
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Show cancellation policies (refund windows; shows without a row refund in full)
+CREATE TABLE IF NOT EXISTS show_cancellation_policies (
+    show_id VARCHAR(36) PRIMARY KEY,               -- Foreign key to shows.id
+    full_refund_hours INT NOT NULL,                -- Full refund until this many hours before the show
+    partial_refund_percent INT NOT NULL,           -- Share refunded after that, 0-100
+    cutoff_hours INT NOT NULL,                     -- No cancellation within this many hours of the show
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Booking ticket lines (per-type breakdown with unit price snapshot)
+CREATE TABLE IF NOT EXISTS booking_ticket_lines (
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    ticket_type VARCHAR(30) NOT NULL,              -- show_ticket_types.code at booking time
+    quantity INT NOT NULL,
+    unit_price INT NOT NULL,                       -- Price of one ticket when booked
+    
+    PRIMARY KEY (booking_id, ticket_type),
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_ticket_lines_type (ticket_type)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Tickets (one row per admission of a booking, each with its own status and attendee)
+CREATE TABLE IF NOT EXISTS tickets (
+    ticket_id VARCHAR(20) PRIMARY KEY,             -- Random unique ID (TK-XXXXXXXXXXXXXXXX)
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    show_id VARCHAR(36) NOT NULL,                  -- Show the ticket admits to
+    ticket_type VARCHAR(30) NULL,                  -- show_ticket_types.code for shows with ticket types
+    seat_id VARCHAR(64) NULL,                      -- Seat for reserved-seating shows
+    attendee_name VARCHAR(255) NULL,               -- Optional name of the person using the ticket
+    status ENUM('active', 'checked_in', 'voided', 'released') DEFAULT 'active',
+    void_reason VARCHAR(255) NULL,                 -- Why a single ticket was voided
+    checked_in_at TIMESTAMP NULL,                  -- When the ticket was scanned in
+    checked_in_gate VARCHAR(50) NULL,              -- Gate that scanned the ticket in
+    checked_in_scan_id VARCHAR(64) NULL,           -- ticket_scans.scan_id that let the ticket in
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    INDEX idx_tickets_booking (booking_id),
+    INDEX idx_tickets_show_status (show_id, status)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Payments (attempts to pay for a booking through the payment provider)
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id VARCHAR(20) PRIMARY KEY,            -- Random unique ID (PAY-XXXXXXXXXXXXXXXX)
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    provider VARCHAR(30) NOT NULL,                 -- PAYMENT_PROVIDER that handled the payment, e.g. mock
+    provider_payment_id VARCHAR(100) NULL,         -- Provider's intent ID, set once the intent is created
+    amount INT NOT NULL,                           -- Booking total, or balance due, at the time of payment
+    currency CHAR(3) NOT NULL,                     -- ISO 4217 code from PAYMENT_CURRENCY
+    purpose ENUM('booking', 'balance') NOT NULL DEFAULT 'booking', -- Pays the booking or its balance due
+    status ENUM('pending', 'authorized', 'captured', 'failed', 'refunded') DEFAULT 'pending',
+    failure_reason VARCHAR(255) NULL,              -- Why the payment failed or was refunded
+    refund_id VARCHAR(100) NULL,                   -- Provider's refund ID
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    UNIQUE KEY uq_payments_provider_id (provider, provider_payment_id),
+    INDEX idx_payments_booking (booking_id),
+    INDEX idx_payments_status (status)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Refunds (ledger of money owed back for cancelled bookings)
+CREATE TABLE IF NOT EXISTS refunds (
+    refund_id VARCHAR(20) PRIMARY KEY,             -- Random unique ID (RF-XXXXXXXXXXXXXXXX)
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings.booking_id
+    payment_id VARCHAR(20) NULL,                   -- Payment refunded; NULL when paid outside a provider
+    amount INT NOT NULL,                           -- Refund due under the cancellation rule
+    currency CHAR(3) NOT NULL,
+    rule VARCHAR(30) NOT NULL,                     -- full_refund, partial_refund, admin_override, amendment or exchange
+    reason VARCHAR(255) NULL,                      -- Reason given for the cancellation
+    status ENUM('pending', 'succeeded', 'failed') DEFAULT 'pending',
+    processor_refund_id VARCHAR(100) NULL,         -- Provider's refund ID, or staff reference for manual refunds
+    failure_reason VARCHAR(255) NULL,              -- Why the processor refused the refund
+    created_by VARCHAR(255) NOT NULL,              -- Who cancelled the booking
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    FOREIGN KEY (payment_id) REFERENCES payments(payment_id),
+    INDEX idx_refunds_booking (booking_id),
+    INDEX idx_refunds_status (status)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Ticket scans (door check-in log, including denied and duplicate scans)
+CREATE TABLE IF NOT EXISTS ticket_scans (
+    scan_id VARCHAR(64) PRIMARY KEY,               -- Scanner-generated for offline scans, else SC-XXXXXXXXXXXXXXXX
+    ticket_id VARCHAR(20) NULL,                    -- Ticket scanned, when the scan named one
+    booking_id VARCHAR(20) NULL,                   -- Booking of the ticket
+    show_id VARCHAR(36) NULL,                      -- Show the ticket admits to
+    gate_id VARCHAR(50) NOT NULL,                  -- Gate or scanner that made the scan
+    source ENUM('live', 'batch') NOT NULL,         -- Sent live or synced from an offline buffer
+    result ENUM('checked_in', 'duplicate', 'denied') NOT NULL,
+    reason VARCHAR(50) NULL,                       -- Why the scan was denied or superseded
+    scanned_at TIMESTAMP NOT NULL,                 -- When the scanner read the ticket
+    received_at TIMESTAMP NOT NULL,                -- When the backend recorded the scan
+    
+    INDEX idx_ticket_scans_ticket (ticket_id),
+    INDEX idx_ticket_scans_show (show_id, scanned_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Waitlist entries (customers queued for released tickets of sold-out shows)
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id VARCHAR(36) PRIMARY KEY,                    -- UUID as string
+    show_id VARCHAR(36) NOT NULL,                  -- Foreign key to shows.id
+    contact_type ENUM('mobile', 'email') NOT NULL,
+    contact_value VARCHAR(255) NOT NULL,
+    customer_name VARCHAR(255),
+    number_of_tickets INT NOT NULL,                -- Tickets wanted
+    ticket_types JSON NULL,                        -- Quantity per ticket type, e.g. {"adult": 2}
+    status ENUM('waiting', 'offered', 'accepted', 'declined', 'expired', 'removed') NOT NULL DEFAULT 'waiting',
+    booking_id VARCHAR(20) NULL,                   -- Pending booking created by the offer
+    offer_expires_at DATETIME NULL,                -- When the offered booking's hold lapses
+    created_at DATETIME(6) NOT NULL,               -- Queue order (microsecond precision)
+    updated_at DATETIME(6) NOT NULL,
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_waitlist_queue (show_id, status, created_at),
+    INDEX idx_waitlist_contact (show_id, contact_type, contact_value),
+    INDEX idx_waitlist_booking (booking_id)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Promotions (promo codes with their discount and redemption rules)
+CREATE TABLE IF NOT EXISTS promotions (
+    code VARCHAR(50) PRIMARY KEY,                  -- Upper-case code customers enter
+    description VARCHAR(255),
+    discount_type ENUM('percentage', 'fixed') NOT NULL,
+    discount_value INT NOT NULL,                   -- Percent off (1-100) or amount off
+    show_id VARCHAR(36) NULL,                      -- Limits the code to one show; NULL = every show
+    valid_from DATETIME NULL,
+    valid_until DATETIME NULL,
+    max_redemptions INT NULL,                      -- Active bookings that may use the code; NULL = unlimited
+    max_per_contact INT NULL,                      -- Active bookings per contact; NULL = unlimited
+    min_tickets INT NOT NULL DEFAULT 0,            -- Smallest booking the code applies to
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE,
+    INDEX idx_promotions_show (show_id)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Idempotency keys (retried create requests replay the first response)
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(50) NOT NULL,                    -- Endpoint the key was used with, e.g. bookings.create
+    owner_hash CHAR(64) NOT NULL DEFAULT '',       -- SHA-256 of the caller's account or Authorization, '' if anonymous
+    idempotency_key VARCHAR(255) NOT NULL,         -- Client-supplied Idempotency-Key header
+    fingerprint CHAR(64) NOT NULL,                 -- SHA-256 of method, path, query and body
+    status_code INT NULL,                          -- NULL while the first request is in progress
+    response_body MEDIUMTEXT NULL,                 -- Response replayed to retries
+    claimed_by VARCHAR(36) NULL,                   -- Request holding the key
+    locked_until DATETIME NULL,                    -- When a retry may take over an unfinished request
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,                  -- When the key may be reused
+    
+    PRIMARY KEY (scope, owner_hash, idempotency_key),
+    INDEX idx_idempotency_expires (expires_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Transactional outbox (domain events delivered to in-process subscribers)
+CREATE TABLE IF NOT EXISTS outbox_events (
+    sequence BIGINT AUTO_INCREMENT PRIMARY KEY,    -- Delivery order
+    event_id VARCHAR(20) NOT NULL UNIQUE,          -- e.g. EV-9F86D081884C7D65
+    event_type VARCHAR(50) NOT NULL,               -- e.g. booking.status_changed
+    aggregate_type VARCHAR(20) NOT NULL,           -- show or booking
+    aggregate_id VARCHAR(50) NOT NULL,             -- Show or booking ID
+    payload JSON NOT NULL,
+    status ENUM('pending', 'delivered', 'dead') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,               -- Failed delivery attempts
+    next_attempt_at DATETIME NOT NULL,             -- When the next attempt is due
+    delivered_to VARCHAR(500) NULL,                -- Comma-separated subscribers that handled the event
+    last_error VARCHAR(1000) NULL,
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME NULL,
+    claimed_by VARCHAR(36) NULL,                   -- Dispatcher currently delivering the event
+    claimed_until DATETIME NULL,                   -- Claim lease; expired claims are taken over
+    
+    INDEX idx_outbox_due (status, next_attempt_at),
+    INDEX idx_outbox_aggregate (aggregate_type, aggregate_id, sequence),
+    INDEX idx_outbox_delivered (status, delivered_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Partner webhook subscriptions
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    subscription_id VARCHAR(20) PRIMARY KEY,       -- e.g. WH-9F86D081884C7D65
+    url VARCHAR(500) NOT NULL,                     -- Partner endpoint payloads are posted to
+    event_types VARCHAR(255) NULL,                 -- Comma-separated event types; NULL = every type
+    secret VARCHAR(255) NOT NULL,                  -- HMAC-SHA256 signing secret
+    description VARCHAR(255) NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INT NOT NULL DEFAULT 0,   -- Failed attempts since the last successful one
+    disabled_at DATETIME NULL,
+    disabled_reason VARCHAR(255) NULL,             -- e.g. disabled after 20 consecutive failed deliveries
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    
+    INDEX idx_webhook_subscriptions_active (active)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Events queued for each webhook subscription
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id VARCHAR(20) PRIMARY KEY,           -- e.g. WD-9F86D081884C7D65
+    subscription_id VARCHAR(20) NOT NULL,          -- Foreign key to webhook_subscriptions
+    event_id VARCHAR(20) NOT NULL,                 -- Outbox event delivered
+    event_type VARCHAR(50) NOT NULL,
+    payload JSON NOT NULL,                         -- Body posted to the endpoint
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_status_code INT NULL,                     -- HTTP status of the last attempt
+    last_error VARCHAR(1000) NULL,
+    created_at DATETIME NOT NULL,
+    delivered_at DATETIME NULL,
+    claimed_by VARCHAR(36) NULL,                   -- Deliverer currently sending it
+    claimed_until DATETIME NULL,                   -- Claim lease; expired claims are taken over
+    
+    FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    
+    UNIQUE KEY uk_webhook_deliveries_event (subscription_id, event_id),
+    INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_subscription (subscription_id, created_at),
+    INDEX idx_webhook_deliveries_delivered (status, delivered_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Delivery log: every request made for a webhook delivery
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    delivery_id VARCHAR(20) NOT NULL,              -- Foreign key to webhook_deliveries
+    attempted_at DATETIME NOT NULL,
+    status_code INT NULL,                          -- NULL when no response was received
+    response_body VARCHAR(1000) NULL,              -- Start of the endpoint's response
+    error VARCHAR(1000) NULL,                      -- NULL on success
+    duration_ms INT NOT NULL,
+    
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(delivery_id) ON DELETE CASCADE,
+    
+    INDEX idx_webhook_attempts_delivery (delivery_id, attempted_at)
+) ENGINE=InnoDB 
+  ROW_FORMAT=DYNAMIC 
+  COMPRESSION='ZLIB';
+
+-- Emails and text messages queued for bookings, and their delivery log
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id VARCHAR(20) PRIMARY KEY,       -- e.g. NT-9F86D081884C7D65
+    booking_id VARCHAR(20) NOT NULL,               -- Foreign key to bookings
+    kind VARCHAR(50) NOT NULL,                     -- booking_created, booking_confirmed, ...
+    channel ENUM('email', 'mobile') NOT NULL,
+    recipient VARCHAR(255) NOT NULL,               -- Email address or phone number
+    subject VARCHAR(255) NULL,                     -- NULL for text messages
+    body TEXT NOT NULL,                            -- Rendered when queued
+    source_id VARCHAR(20) NOT NULL,                -- Event that caused it; own ID for a resend
+    status ENUM('pending', 'sent', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    transport VARCHAR(20) NULL,                    -- Transport of the last attempt: smtp, sms or log
+    last_error VARCHAR(1000) NULL,
+    created_at DATETIME NOT NULL,
+    sent_at DATETIME NULL,
+    claimed_by VARCHAR(36) NULL,                   -- Sender currently delivering it
+    claimed_until DATETIME NULL,                   -- Claim lease; expired claims are taken over
+    
+    FOREIGN KEY (booking_id) REFERENCES bookings(booking_id) ON DELETE CASCADE,
+    
+    UNIQUE KEY uk_notifications_source (source_id, booking_id, kind),
+    INDEX idx_notifications_due (status, next_attempt_at),
+    INDEX idx_notifications_booking (booking_id, created_at)
 ) ENGINE=InnoDB 
   ROW_FORMAT=DYNAMIC 
   COMPRESSION='ZLIB';
@@ -360,7 +835,28 @@ SHOW TABLES;
 -- Display table structures
 DESCRIBE shows;
 DESCRIBE bookings;
+DESCRIBE booking_amendments;
+DESCRIBE booking_exchanges;
 DESCRIBE show_availability_index;
+DESCRIBE venues;
+DESCRIBE show_seats;
+DESCRIBE waitlist_entries;
+DESCRIBE show_ticket_types;
+DESCRIBE show_cancellation_policies;
+DESCRIBE booking_ticket_lines;
+DESCRIBE tickets;
+DESCRIBE ticket_scans;
+DESCRIBE payments;
+DESCRIBE refunds;
+DESCRIBE promotions;
+DESCRIBE orders;
+DESCRIBE idempotency_keys;
+DESCRIBE outbox_events;
+DESCRIBE webhook_subscriptions;
+DESCRIBE webhook_deliveries;
+DESCRIBE webhook_attempts;
+DESCRIBE notifications;
+DESCRIBE users;
 
 -- Show MySQL version and configuration
 SELECT VERSION() as mysql_version;
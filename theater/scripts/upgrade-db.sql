@@ -0,0 +1,83 @@
+-- Theater Booking System Database Upgrades
+-- init-db.sql only creates tables that are missing, so a database created by an
+-- earlier version keeps its old columns. Run init-db.sql first to add new
+-- tables, then the sections below that the database predates, in order.
+-- MySQL 8.0 has no ADD COLUMN IF NOT EXISTS, so each section runs only once.
+
+USE theater_booking;
+
+-- Pending booking holds
+ALTER TABLE shows
+    ADD COLUMN hold_minutes INT DEFAULT 0;         -- Pending booking hold in minutes (0 = system default)
+
+ALTER TABLE bookings
+    MODIFY COLUMN status ENUM('pending', 'confirmed', 'cancelled', 'expired') DEFAULT 'pending',
+    ADD COLUMN hold_expires_at DATETIME NULL,      -- When a pending booking releases its tickets
+    ADD INDEX idx_bookings_status_hold (status, hold_expires_at);
+
+-- Check-in, refund and no-show statuses
+ALTER TABLE bookings
+    MODIFY COLUMN status ENUM('pending', 'confirmed', 'cancelled', 'expired', 'checked_in', 'refunded', 'no_show') DEFAULT 'pending';
+
+-- Reserved seating
+ALTER TABLE shows
+    ADD COLUMN venue_id VARCHAR(36) NULL,          -- Reserved-seating venue (NULL = general admission)
+    ADD FOREIGN KEY (venue_id) REFERENCES venues(id);
+
+-- Ticket types on waitlist entries
+ALTER TABLE waitlist_entries
+    ADD COLUMN ticket_types JSON NULL;             -- Quantity per ticket type, e.g. {"adult": 2}
+
+-- Promo codes
+ALTER TABLE bookings
+    ADD COLUMN promo_code VARCHAR(50) NULL,        -- Promo code redeemed by the booking
+    ADD COLUMN discount_amount INT NOT NULL DEFAULT 0, -- Amount taken off by the promo code
+    ADD INDEX idx_bookings_promo_contact (promo_code, contact_type, contact_value);
+
+-- Multi-show orders
+ALTER TABLE bookings
+    ADD COLUMN order_id VARCHAR(20) NULL,          -- Parent order for multi-show checkouts
+    ADD FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    ADD INDEX idx_bookings_order_id (order_id);
+
+-- Booking references
+ALTER TABLE bookings
+    ADD COLUMN reference VARCHAR(11) NULL,         -- Customer-facing reference (XXXXX-XXXXX)
+    ADD UNIQUE KEY uq_bookings_reference (reference);
+
+-- Gate check-in
+ALTER TABLE tickets
+    MODIFY COLUMN status ENUM('active', 'checked_in', 'voided', 'released') DEFAULT 'active',
+    ADD COLUMN checked_in_at TIMESTAMP NULL,       -- When the ticket was scanned in
+    ADD COLUMN checked_in_gate VARCHAR(50) NULL,   -- Gate that scanned the ticket in
+    ADD COLUMN checked_in_scan_id VARCHAR(64) NULL; -- ticket_scans.scan_id that let the ticket in
+
+-- Exchanges
+ALTER TABLE bookings
+    MODIFY COLUMN status ENUM('pending', 'confirmed', 'cancelled', 'expired', 'checked_in', 'refunded', 'no_show', 'exchanged') DEFAULT 'pending';
+
+-- Contact verification
+ALTER TABLE shows
+    ADD COLUMN require_verified_contact BOOLEAN NOT NULL DEFAULT FALSE; -- Bookings need an OTP-verified contact
+
+-- Customer accounts
+ALTER TABLE bookings
+    ADD COLUMN user_id VARCHAR(36) NULL,           -- Account of the signed-in customer, if any
+    ADD FOREIGN KEY (user_id) REFERENCES users(user_id),
+    ADD INDEX idx_bookings_user_id (user_id);
+
+-- Balance due on amended bookings
+ALTER TABLE bookings
+    ADD COLUMN balance_due INT NOT NULL DEFAULT 0; -- Owed for tickets added to a confirmed booking
+
+-- Balance payments
+ALTER TABLE payments
+    ADD COLUMN purpose ENUM('booking', 'balance') NOT NULL DEFAULT 'booking'; -- Pays the booking or its balance due
+
+-- Idempotency keys scoped to their caller, with claims on unfinished requests
+ALTER TABLE idempotency_keys
+    ADD COLUMN owner_hash CHAR(64) NOT NULL DEFAULT '' AFTER scope, -- SHA-256 of the caller's account or Authorization, '' if anonymous
+    ADD COLUMN claimed_by VARCHAR(36) NULL,        -- Request holding the key
+    ADD COLUMN locked_until DATETIME NULL,         -- When a retry may take over an unfinished request
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (scope, owner_hash, idempotency_key);
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+	"github.com/gsmayya/theater/repository"
+)
+
+// AmendmentService changes the number of tickets on existing bookings and
+// settles the difference in money: tickets dropped from a confirmed booking
+// are refunded through the refund ledger, tickets added to one leave a
+// balance due that must be paid before check-in
+type AmendmentService struct {
+	bookingService    *BookingService
+	refundService     *RefundService
+	paymentRepository *repository.PaymentRepository
+}
+
+// NewAmendmentService creates a new amendment service
+func NewAmendmentService() *AmendmentService {
+	return &AmendmentService{
+		bookingService:    NewBookingService(),
+		refundService:     NewRefundService(),
+		paymentRepository: repository.NewPaymentRepository(),
+	}
+}
+
+// AmendmentResult is an amended booking and how its amount due was settled
+type AmendmentResult struct {
+	Booking    *bookings.Booking   `json:"booking"`
+	Amendment  *bookings.Amendment `json:"amendment"`
+	Refund     *payments.Refund    `json:"refund,omitempty"`      // Tickets dropped from a confirmed booking
+	BalanceDue int32               `json:"balance_due,omitempty"` // Tickets added to a confirmed booking, owed on the booking
+}
+
+// AmendBooking changes a booking, looked up by booking ID or reference, to
+// numberOfTickets tickets. Pending bookings have paid nothing, so only their
+// amount due changes; they cannot be amended while a payment for the old
+// amount is under way.
+func (s *AmendmentService) AmendBooking(idOrReference, ticketType string, numberOfTickets int32, expectedVersion *int32, changedBy, reason string) (*AmendmentResult, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status == bookings.StatusPending {
+		if err := s.checkNoPaymentInProgress(booking); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tickets dropped from a confirmed booking are refunded, with the refund
+	// recorded in the amendment's transaction. The amendment runs on the
+	// locked booking; a pending booking cannot have been confirmed in between
+	// without its payment having been in progress.
+	result := &AmendmentResult{}
+	var refundFor func(amendment *bookings.Amendment) (*payments.Refund, error)
+	if booking.Status == bookings.StatusConfirmed {
+		refundFor = func(amendment *bookings.Amendment) (*payments.Refund, error) {
+			refund, err := s.refundService.AmendmentRefund(amendment)
+			result.Refund = refund
+			return refund, err
+		}
+	}
+
+	amendment, err := s.bookingService.AmendBooking(booking.BookingID, ticketType, numberOfTickets, expectedVersion, changedBy, reason, refundFor)
+	if err != nil {
+		return nil, err
+	}
+	result.Amendment = amendment
+
+	if result.Refund != nil {
+		s.refundService.SendRefund(result.Refund)
+	}
+	if booking.Status == bookings.StatusConfirmed && amendment.AmountDelta > 0 {
+		result.BalanceDue = amendment.AmountDelta
+	}
+
+	result.Booking, err = s.bookingService.GetBooking(booking.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAmendments returns the amendments of a booking, looked up by booking ID or reference
+func (s *AmendmentService) GetAmendments(idOrReference string) ([]*bookings.Amendment, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	return s.bookingService.GetAmendments(booking.BookingID)
+}
+
+// checkNoPaymentInProgress refuses to amend a pending booking whose payment
+// was started for the old amount; the provider would charge the wrong total
+func (s *AmendmentService) checkNoPaymentInProgress(booking *bookings.Booking) error {
+	payment, err := paymentInProgress(s.paymentRepository, booking.BookingID)
+	if err != nil {
+		return err
+	}
+	if payment != nil {
+		return fmt.Errorf("invalid amendment: booking %s is waiting for payment %s; cancel it and book again",
+			booking.BookingID, payment.PaymentID)
+	}
+	return nil
+}
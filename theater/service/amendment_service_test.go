@@ -0,0 +1,64 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+)
+
+func TestAmendBookingSettlesTheDifference(t *testing.T) {
+	requireDatabase(t)
+
+	amendmentService := NewAmendmentService()
+	bookingService := amendmentService.bookingService
+
+	show := createTestShow(t, "Amendment Test", 1000, 4)
+	booking := createConfirmedBooking(t, bookingService, show.Show_Id, "amend@example.com", 2)
+	if _, err := bookingService.CreateBooking(bookings.NewBooking(show.Show_Id, "email", "other@example.com", 1, 0)); err != nil {
+		t.Fatalf("Failed to create booking: %v", err)
+	}
+
+	// Growing to 4 would need 2 more tickets with only 1 left
+	_, err := amendmentService.AmendBooking(booking.BookingID, "", 4, nil, "test", "")
+	if err == nil || !strings.Contains(err.Error(), "insufficient tickets") {
+		t.Fatalf("Expected the amendment to be refused for capacity, got %v", err)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 3 {
+		t.Errorf("Expected the refused amendment to leave 3 booked, got %d", booked)
+	}
+
+	// Dropping a ticket from a confirmed booking refunds its price
+	result, err := amendmentService.AmendBooking(booking.BookingID, "", 1, nil, "test", "")
+	if err != nil {
+		t.Fatalf("AmendBooking() error: %v", err)
+	}
+	if result.Amendment.AmountDelta != -1000 || result.Refund == nil || result.Refund.Amount != 1000 || result.Refund.Status != payments.RefundPending {
+		t.Errorf("Expected a pending refund of 1000, got delta %d and %+v", result.Amendment.AmountDelta, result.Refund)
+	}
+	if result.Booking.NumberOfTickets != 1 || result.Booking.TotalAmount != 1000 {
+		t.Errorf("Expected 1 ticket for 1000, got %d for %d", result.Booking.NumberOfTickets, result.Booking.TotalAmount)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 2 {
+		t.Errorf("Expected the dropped ticket to be released, got %d booked", booked)
+	}
+
+	// Adding tickets leaves their price due on the booking
+	result, err = amendmentService.AmendBooking(booking.BookingID, "", 3, nil, "test", "")
+	if err != nil {
+		t.Fatalf("AmendBooking() error: %v", err)
+	}
+	if result.BalanceDue != 2000 || result.Booking.BalanceDue != 2000 || result.Refund != nil {
+		t.Errorf("Expected a balance of 2000 due and no refund, got %d and %+v", result.Booking.BalanceDue, result.Refund)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 4 {
+		t.Errorf("Expected the added tickets to be reserved, got %d booked", booked)
+	}
+
+	// The balance has to be paid before the booking changes again
+	_, err = amendmentService.AmendBooking(booking.BookingID, "", 2, nil, "test", "")
+	if err == nil || !strings.Contains(err.Error(), "balance of 2000 due") {
+		t.Errorf("Expected the amendment to wait for the balance, got %v", err)
+	}
+}
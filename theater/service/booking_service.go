@@ -1,19 +1,33 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
 	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/tickets"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/venues"
 )
 
+// expiredHoldBatchSize caps how many lapsed holds one reaper pass releases
+const expiredHoldBatchSize = 500
+
 // BookingService provides business logic for theater bookings
 type BookingService struct {
 	bookingRepository *repository.BookingRepository
 	showService       *ShowService
+	ticketRepository  *repository.TicketRepository
+	waitlistService   *WaitlistService
+	holdDuration      time.Duration
+	entryWindow       tickets.EntryWindow
 }
 
 // NewBookingService creates a new booking service
@@ -21,53 +35,81 @@ func NewBookingService() *BookingService {
 	return &BookingService{
 		bookingRepository: repository.NewBookingRepository(),
 		showService:       NewShowService(),
+		ticketRepository:  repository.NewTicketRepository(),
+		waitlistService:   NewWaitlistService(),
+		holdDuration:      utils.GetDurationOrDefault("BOOKING_HOLD_DURATION", 15*time.Minute),
+		entryWindow:       ticketEntryWindow(),
 	}
 }
 
-// CreateBooking creates a new booking with validation and availability checks
-func (s *BookingService) CreateBooking(showID uuid.UUID, contactType, contactValue string, numberOfTickets int32, customerName string) (*bookings.Booking, error) {
+// CreateBooking validates a draft booking, prices it and reserves its tickets
+// (and seats, for reserved-seating shows) in one transaction. Ticket type
+// quotas and the booking's promo code are checked by the reservation under
+// the show lock, which also takes the promo discount off the total.
+func (s *BookingService) CreateBooking(booking *bookings.Booking) (*bookings.Booking, error) {
 	// Validate input parameters
-	if numberOfTickets <= 0 {
+	if booking.NumberOfTickets <= 0 {
 		return nil, fmt.Errorf("number of tickets must be greater than 0")
 	}
 
 	// Get show details to validate and calculate price
-	show, err := s.showService.GetShow(showID.String())
+	show, err := s.showService.GetShow(booking.ShowID.String())
 	if err != nil {
 		return nil, fmt.Errorf("show not found: %w", err)
 	}
 
-	// Validate ticket availability
-	err = s.bookingRepository.ValidateBookingCapacity(showID, numberOfTickets, show.Total_Tickets)
+	seats, err := validateSeatSelection(show, booking)
 	if err != nil {
-		return nil, fmt.Errorf("booking validation failed: %w", err)
+		return nil, err
 	}
+	booking.Seats = seats
 
-	// Calculate total amount
-	totalAmount := show.Price * numberOfTickets
-
-	// Create the booking
-	booking := bookings.NewBooking(showID, contactType, contactValue, numberOfTickets, totalAmount)
-	if customerName != "" {
-		booking.CustomerName = customerName
+	// Price each ticket type at its current price, or every ticket at the show price
+	if err := booking.ApplyPrices(show.Price, shows.TicketPrices(show.TicketTypes)); err != nil {
+		return nil, err
 	}
 
-	// Save to repository
-	if err := s.bookingRepository.CreateBooking(booking); err != nil {
+	// Pending bookings only keep their tickets until the hold lapses
+	booking.PlaceHold(show.HoldDuration(s.holdDuration))
+
+	// Capacity check, seat reservation, insert and booked_tickets update happen in one transaction
+	inventory, err := s.bookingRepository.ReserveBooking(booking, bookings.ActorCustomer)
+	if err != nil {
 		return nil, fmt.Errorf("failed to create booking: %w", err)
 	}
 
-	// Update show's booked tickets count
-	show.Booked_Tickets += numberOfTickets
-	if err := s.showService.UpdateShow(show); err != nil {
-		log.Printf("Warning: Failed to update show booked tickets count: %v", err)
-		// Don't fail the booking creation for this, but log it
-	}
+	// Redis is only updated once the reservation has been committed
+	s.syncShowAvailability(inventory)
 
-	log.Printf("Successfully created booking: %s for show %s", booking.BookingID, showID.String())
+	log.Printf("Successfully created booking: %s for show %s", booking.BookingID, booking.ShowID.String())
 	return booking, nil
 }
 
+// validateSeatSelection checks requested seats against the show's seating mode
+// and returns them normalized
+func validateSeatSelection(show *shows.ShowData, booking *bookings.Booking) ([]string, error) {
+	seats, err := venues.NormalizeSeatIDs(booking.Seats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seat selection: %w", err)
+	}
+
+	if show.VenueID == nil {
+		if len(seats) > 0 {
+			return nil, fmt.Errorf("invalid seat selection: show %s does not have reserved seating", show.Show_Id.String())
+		}
+		return nil, nil
+	}
+
+	if len(seats) == 0 {
+		return nil, fmt.Errorf("invalid seat selection: seats are required for show %s", show.Show_Id.String())
+	}
+	if int32(len(seats)) != booking.NumberOfTickets {
+		return nil, fmt.Errorf("invalid seat selection: %d seats requested for %d tickets", len(seats), booking.NumberOfTickets)
+	}
+
+	return seats, nil
+}
+
 // GetBooking retrieves a booking by its ID
 func (s *BookingService) GetBooking(bookingID string) (*bookings.Booking, error) {
 	if bookingID == "" {
@@ -82,65 +124,165 @@ func (s *BookingService) GetBooking(bookingID string) (*bookings.Booking, error)
 	return booking, nil
 }
 
-// UpdateBookingStatus updates the status of a booking
-func (s *BookingService) UpdateBookingStatus(bookingID, status string) error {
+// FindBooking retrieves a booking by its internal ID or its customer-facing
+// reference, which may be typed in any case and with or without the dash
+func (s *BookingService) FindBooking(idOrReference string) (*bookings.Booking, error) {
+	if idOrReference == "" {
+		return nil, fmt.Errorf("booking ID cannot be empty")
+	}
+
+	if bookings.IsBookingID(idOrReference) {
+		return s.bookingRepository.GetBooking(idOrReference)
+	}
+
+	reference, err := bookings.ParseReference(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	return s.bookingRepository.GetBookingByReference(reference)
+}
+
+// UpdateBookingStatus moves a booking to a new status. The transition must be
+// allowed by the bookings state machine; changedBy and reason are recorded in
+// the booking's status history.
+func (s *BookingService) UpdateBookingStatus(bookingID, status, changedBy, reason string) error {
 	if bookingID == "" {
 		return fmt.Errorf("booking ID cannot be empty")
 	}
 
 	// Validate status
-	validStatuses := []string{"pending", "confirmed", "cancelled"}
-	isValid := false
-	for _, validStatus := range validStatuses {
-		if status == validStatus {
-			isValid = true
-			break
-		}
+	if !bookings.IsKnownStatus(status) {
+		return fmt.Errorf("invalid status: %s. Valid statuses are: %s", status, strings.Join(bookings.AllStatuses, ", "))
 	}
-	if !isValid {
-		return fmt.Errorf("invalid status: %s. Valid statuses are: pending, confirmed, cancelled", status)
+
+	// Exchanged bookings must be linked to the booking that replaced them
+	if status == bookings.StatusExchanged {
+		return fmt.Errorf("invalid status transition: bookings are exchanged through /api/v1/bookings/exchange")
+	}
+
+	if changedBy == "" {
+		return fmt.Errorf("changed by cannot be empty")
 	}
 
-	// Get current booking to check current status
-	currentBooking, err := s.bookingRepository.GetBooking(bookingID)
+	// Update booking status; the show's booked tickets are recounted in the same transaction
+	inventory, err := s.bookingRepository.UpdateBookingStatus(bookingID, status, changedBy, reason)
 	if err != nil {
-		return fmt.Errorf("failed to get current booking: %w", err)
+		return fmt.Errorf("failed to update booking status: %w", err)
 	}
 
-	// If cancelling a previously confirmed/pending booking, adjust show availability
-	if status == "cancelled" && (currentBooking.Status == "confirmed" || currentBooking.Status == "pending") {
-		show, err := s.showService.GetShow(currentBooking.ShowID.String())
-		if err != nil {
-			log.Printf("Warning: Failed to get show for booking cancellation: %v", err)
-		} else {
-			// Reduce booked tickets count
-			show.Booked_Tickets -= currentBooking.NumberOfTickets
-			if show.Booked_Tickets < 0 {
-				show.Booked_Tickets = 0 // Prevent negative values
-			}
-			if err := s.showService.UpdateShow(show); err != nil {
-				log.Printf("Warning: Failed to update show availability after cancellation: %v", err)
-			}
-		}
+	s.statusChanged(bookingID, status, changedBy, inventory)
+	return nil
+}
+
+// CancelBookingWithRefund cancels a booking and records the refund it is
+// owed in the same transaction, so a cancellation is never saved without it
+func (s *BookingService) CancelBookingWithRefund(bookingID, changedBy, reason string, refund *payments.Refund) error {
+	if bookingID == "" {
+		return fmt.Errorf("booking ID cannot be empty")
+	}
+	if changedBy == "" {
+		return fmt.Errorf("changed by cannot be empty")
 	}
 
-	// Update booking status
-	if err := s.bookingRepository.UpdateBookingStatus(bookingID, status); err != nil {
+	inventory, err := s.bookingRepository.CancelBookingWithRefund(bookingID, changedBy, reason, refund)
+	if err != nil {
 		return fmt.Errorf("failed to update booking status: %w", err)
 	}
 
-	log.Printf("Successfully updated booking %s status to %s", bookingID, status)
+	s.statusChanged(bookingID, bookings.StatusCancelled, changedBy, inventory)
 	return nil
 }
 
+// statusChanged brings Redis availability up to date after a status change
+// and offers tickets it released to the waitlist
+func (s *BookingService) statusChanged(bookingID, status, changedBy string, inventory *repository.ShowInventory) {
+	s.syncShowAvailability(inventory)
+
+	if !bookings.HoldsInventory(status) {
+		s.offerReleasedTickets(inventory)
+	}
+
+	log.Printf("Successfully updated booking %s status to %s (by %s)", bookingID, status, changedBy)
+}
+
 // ConfirmBooking confirms a pending booking
-func (s *BookingService) ConfirmBooking(bookingID string) error {
-	return s.UpdateBookingStatus(bookingID, "confirmed")
+func (s *BookingService) ConfirmBooking(bookingID, changedBy, reason string) error {
+	return s.UpdateBookingStatus(bookingID, bookings.StatusConfirmed, changedBy, reason)
 }
 
 // CancelBooking cancels a booking
-func (s *BookingService) CancelBooking(bookingID string) error {
-	return s.UpdateBookingStatus(bookingID, "cancelled")
+func (s *BookingService) CancelBooking(bookingID, changedBy, reason string) error {
+	return s.UpdateBookingStatus(bookingID, bookings.StatusCancelled, changedBy, reason)
+}
+
+// GetBookingHistory returns every status change of a booking, oldest first
+func (s *BookingService) GetBookingHistory(bookingID string) ([]*bookings.StatusChange, error) {
+	if bookingID == "" {
+		return nil, fmt.Errorf("booking ID cannot be empty")
+	}
+
+	// Distinguish an unknown booking from one without history
+	if _, err := s.bookingRepository.GetBooking(bookingID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.bookingRepository.GetStatusHistory(bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking history: %w", err)
+	}
+
+	return history, nil
+}
+
+// AmendBooking changes how many tickets a pending or confirmed booking holds.
+// Added tickets are capacity checked against the show and ticket type quotas;
+// dropped tickets go back on sale and may be offered to the waitlist.
+// refundFor, when set, returns the refund the amendment owes, which is
+// recorded along with it.
+func (s *BookingService) AmendBooking(bookingID, ticketType string, numberOfTickets int32, expectedVersion *int32, changedBy, reason string,
+	refundFor func(amendment *bookings.Amendment) (*payments.Refund, error)) (*bookings.Amendment, error) {
+	if bookingID == "" {
+		return nil, fmt.Errorf("booking ID cannot be empty")
+	}
+
+	if changedBy == "" {
+		return nil, fmt.Errorf("changed by cannot be empty")
+	}
+
+	// Ticket counts, amounts and booked_tickets change in one transaction
+	amendment, inventory, err := s.bookingRepository.AmendBooking(bookingID, ticketType, numberOfTickets, expectedVersion, changedBy, reason, refundFor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to amend booking: %w", err)
+	}
+
+	s.syncShowAvailability(inventory)
+
+	if amendment.TicketDelta() < 0 {
+		s.offerReleasedTickets(inventory)
+	}
+
+	log.Printf("Successfully amended booking %s to %d tickets (version %d, by %s)",
+		bookingID, amendment.ToTickets, amendment.Version, changedBy)
+	return amendment, nil
+}
+
+// GetAmendments returns every amendment of a booking, oldest first
+func (s *BookingService) GetAmendments(bookingID string) ([]*bookings.Amendment, error) {
+	if bookingID == "" {
+		return nil, fmt.Errorf("booking ID cannot be empty")
+	}
+
+	// Distinguish an unknown booking from one that was never amended
+	if _, err := s.bookingRepository.GetBooking(bookingID); err != nil {
+		return nil, err
+	}
+
+	amendments, err := s.bookingRepository.GetAmendments(bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking amendments: %w", err)
+	}
+
+	return amendments, nil
 }
 
 // GetBookingsByShow retrieves all bookings for a specific show
@@ -159,6 +301,12 @@ func (s *BookingService) GetBookingsByContact(contactType, contactValue string)
 		return nil, fmt.Errorf("contact type and value cannot be empty")
 	}
 
+	// Contacts are stored normalized, so look them up the same way
+	contactValue, err := bookings.NormalizeContact(contactType, contactValue)
+	if err != nil {
+		return nil, err
+	}
+
 	bookingsList, err := s.bookingRepository.GetBookingsByContact(contactType, contactValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bookings by contact: %w", err)
@@ -234,9 +382,10 @@ func (s *BookingService) GetShowBookingSummary(showID uuid.UUID) (*ShowBookingSu
 	// Calculate statistics
 	totalRevenue := int32(0)
 	bookingsByStatus := make(map[string]int32)
+	now := time.Now()
 
 	for _, booking := range bookingsList {
-		if booking.Status == "confirmed" || booking.Status == "pending" {
+		if bookings.HoldsInventory(booking.Status) && !booking.IsHoldExpired(now) {
 			totalRevenue += booking.TotalAmount
 		}
 		bookingsByStatus[booking.Status]++
@@ -256,60 +405,186 @@ func (s *BookingService) GetShowBookingSummary(showID uuid.UUID) (*ShowBookingSu
 		RecentBookings:   bookingsList[:min(len(bookingsList), 5)], // Last 5 bookings
 	}
 
+	if show.HasTicketTypes() {
+		sales, err := s.bookingRepository.GetTicketTypeSales(showID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ticket type sales: %w", err)
+		}
+		summary.TicketTypes = summarizeTicketTypes(show.TicketTypes, sales, summary.TicketsAvailable)
+	}
+
+	attendance, err := s.ticketRepository.GetAttendance(showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+	attendance.Settle(show.ShowDate, s.entryWindow, now)
+	summary.Attendance = attendance
+
 	return summary, nil
 }
 
+// GetShowAttendance counts a show's checked-in tickets against those of its
+// confirmed bookings. Once entry has closed, tickets that never arrived are
+// reported as no-shows.
+func (s *BookingService) GetShowAttendance(showID uuid.UUID) (*tickets.Attendance, error) {
+	show, err := s.showService.GetShow(showID.String())
+	if err != nil {
+		return nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	attendance, err := s.ticketRepository.GetAttendance(showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+	attendance.Settle(show.ShowDate, s.entryWindow, time.Now())
+	return attendance, nil
+}
+
+// summarizeTicketTypes combines a show's ticket types with their sales. A
+// tier can never offer more than the show has left, whatever its quota.
+func summarizeTicketTypes(ticketTypes []*shows.TicketType, sales map[string]*repository.TicketTypeSales, showAvailable int32) []*TicketTypeSummary {
+	summaries := make([]*TicketTypeSummary, 0, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		summary := &TicketTypeSummary{
+			Code:             ticketType.Code,
+			Name:             ticketType.Name,
+			Price:            ticketType.Price,
+			Quota:            ticketType.Quota,
+			TicketsAvailable: showAvailable,
+		}
+		if sold, ok := sales[ticketType.Code]; ok {
+			summary.TicketsSold = sold.TicketsSold
+			summary.Revenue = sold.Revenue
+		}
+		if ticketType.Quota != nil {
+			if remaining := *ticketType.Quota - summary.TicketsSold; remaining < summary.TicketsAvailable {
+				summary.TicketsAvailable = remaining
+			}
+		}
+		if summary.TicketsAvailable < 0 {
+			summary.TicketsAvailable = 0
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
 // DeleteBooking deletes a booking (admin function)
 func (s *BookingService) DeleteBooking(bookingID string) error {
 	if bookingID == "" {
 		return fmt.Errorf("booking ID cannot be empty")
 	}
 
-	// Get booking details before deletion for show update
-	booking, err := s.bookingRepository.GetBooking(bookingID)
+	// Delete the booking; the show's booked tickets are recounted in the same transaction
+	inventory, err := s.bookingRepository.DeleteBooking(bookingID)
 	if err != nil {
-		return fmt.Errorf("booking not found: %w", err)
+		return fmt.Errorf("failed to delete booking: %w", err)
 	}
 
-	// Delete the booking
-	if err := s.bookingRepository.DeleteBooking(bookingID); err != nil {
-		return fmt.Errorf("failed to delete booking: %w", err)
+	s.syncShowAvailability(inventory)
+	s.offerReleasedTickets(inventory)
+
+	log.Printf("Successfully deleted booking: %s", bookingID)
+	return nil
+}
+
+// ExpireHolds moves every pending booking whose hold has lapsed to expired and
+// releases its tickets back to the show. It returns the number of bookings expired.
+func (s *BookingService) ExpireHolds() (int, error) {
+	now := time.Now()
+
+	expiredHolds, err := s.bookingRepository.GetExpiredHolds(now, expiredHoldBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired holds: %w", err)
 	}
 
-	// Update show availability if booking was active
-	if booking.Status == "confirmed" || booking.Status == "pending" {
-		show, err := s.showService.GetShow(booking.ShowID.String())
+	count := 0
+	for _, booking := range expiredHolds {
+		inventory, expired, err := s.bookingRepository.ExpireHold(booking.BookingID, now, bookings.ActorHoldReaper)
 		if err != nil {
-			log.Printf("Warning: Failed to get show for booking deletion: %v", err)
-		} else {
-			// Reduce booked tickets count
-			show.Booked_Tickets -= booking.NumberOfTickets
-			if show.Booked_Tickets < 0 {
-				show.Booked_Tickets = 0
-			}
-			if err := s.showService.UpdateShow(show); err != nil {
-				log.Printf("Warning: Failed to update show availability after deletion: %v", err)
+			log.Printf("Warning: Failed to expire booking %s: %v", booking.BookingID, err)
+			continue
+		}
+		if !expired {
+			// Confirmed or cancelled after we listed it
+			continue
+		}
+
+		s.syncShowAvailability(inventory)
+		s.offerReleasedTickets(inventory)
+		count++
+		log.Printf("Expired booking hold %s, released %d tickets for show %s",
+			booking.BookingID, booking.NumberOfTickets, booking.ShowID.String())
+	}
+
+	return count, nil
+}
+
+// RunHoldReaper expires lapsed holds on a fixed interval until the context is cancelled
+func (s *BookingService) RunHoldReaper(ctx context.Context, interval time.Duration) {
+	log.Printf("Booking hold reaper running every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Booking hold reaper stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.ExpireHolds(); err != nil {
+				log.Printf("Warning: Booking hold reaper failed: %v", err)
 			}
 		}
 	}
+}
 
-	log.Printf("Successfully deleted booking: %s", bookingID)
-	return nil
+// syncShowAvailability refreshes the show cache and Redis indexes after a committed inventory change
+func (s *BookingService) syncShowAvailability(inventory *repository.ShowInventory) {
+	if err := s.showService.SyncAvailability(inventory.ShowID.String()); err != nil {
+		log.Printf("Warning: Failed to sync availability for show %s: %v", inventory.ShowID.String(), err)
+	}
+}
+
+// offerReleasedTickets hands tickets freed by a committed release to the show's waitlist
+func (s *BookingService) offerReleasedTickets(inventory *repository.ShowInventory) {
+	if inventory.AvailableTickets() <= 0 {
+		return
+	}
+
+	if _, err := s.waitlistService.PromoteWaitlist(inventory.ShowID); err != nil {
+		log.Printf("Warning: Failed to offer released tickets for show %s: %v", inventory.ShowID.String(), err)
+	}
 }
 
 // ShowBookingSummary represents a comprehensive booking summary for a show
 type ShowBookingSummary struct {
-	ShowID           uuid.UUID           `json:"show_id"`
-	ShowName         string              `json:"show_name"`
-	ShowNumber       string              `json:"show_number"`
-	ShowDate         time.Time           `json:"show_date"`
-	TotalTickets     int32               `json:"total_tickets"`
-	TicketsSold      int32               `json:"tickets_sold"`
-	TicketsAvailable int32               `json:"tickets_available"`
-	TotalBookings    int32               `json:"total_bookings"`
-	TotalRevenue     int32               `json:"total_revenue"`
-	BookingsByStatus map[string]int32    `json:"bookings_by_status"`
-	RecentBookings   []*bookings.Booking `json:"recent_bookings"`
+	ShowID           uuid.UUID            `json:"show_id"`
+	ShowName         string               `json:"show_name"`
+	ShowNumber       string               `json:"show_number"`
+	ShowDate         time.Time            `json:"show_date"`
+	TotalTickets     int32                `json:"total_tickets"`
+	TicketsSold      int32                `json:"tickets_sold"`
+	TicketsAvailable int32                `json:"tickets_available"`
+	TotalBookings    int32                `json:"total_bookings"`
+	TotalRevenue     int32                `json:"total_revenue"`
+	BookingsByStatus map[string]int32     `json:"bookings_by_status"`
+	TicketTypes      []*TicketTypeSummary `json:"ticket_types,omitempty"`
+	Attendance       *tickets.Attendance  `json:"attendance"`
+	RecentBookings   []*bookings.Booking  `json:"recent_bookings"`
+}
+
+// TicketTypeSummary reports sales and remaining availability of one ticket type.
+// A tier's availability is capped by both its quota and the show's remaining capacity.
+type TicketTypeSummary struct {
+	Code             string `json:"code"`
+	Name             string `json:"name"`
+	Price            int32  `json:"price"`
+	Quota            *int32 `json:"quota,omitempty"`
+	TicketsSold      int32  `json:"tickets_sold"`
+	TicketsAvailable int32  `json:"tickets_available"`
+	Revenue          int32  `json:"revenue"`
 }
 
 // Helper function to get minimum of two integers
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/db"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/utils"
+)
+
+// requireDatabase skips the test when MySQL is not reachable, since
+// db.GetDatabase exits the process if it cannot connect
+func requireDatabase(t *testing.T) {
+	t.Helper()
+
+	addr := net.JoinHostPort(
+		utils.GetEnvOrDefault("DB_HOST", "localhost"),
+		utils.GetEnvOrDefault("DB_PORT", "3306"),
+	)
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Skipf("Skipping integration test - database not reachable at %s", addr)
+	}
+	conn.Close()
+}
+
+// readBookedTickets reads a show's booked_tickets straight from MySQL,
+// bypassing the show cache
+func readBookedTickets(t *testing.T, showID uuid.UUID) int32 {
+	t.Helper()
+
+	var bookedTickets int32
+	err := db.GetDatabase().GetDB().
+		QueryRow("SELECT booked_tickets FROM shows WHERE id = ?", showID.String()).
+		Scan(&bookedTickets)
+	if err != nil {
+		t.Fatalf("Failed to read booked_tickets: %v", err)
+	}
+	return bookedTickets
+}
+
+// createTestShow creates a show starting in 30 days, deleted with its
+// bookings when the test ends
+func createTestShow(t *testing.T, name string, price, totalTickets int32) *shows.ShowData {
+	t.Helper()
+
+	showService := NewShowService()
+	show, err := showService.CreateShow(name, "Integration test", "Test Hall", price, totalTickets, 0)
+	if err != nil {
+		t.Fatalf("Failed to create show: %v", err)
+	}
+	t.Cleanup(func() { showService.DeleteShow(show.Show_Id.String()) })
+	return show
+}
+
+// createConfirmedBooking reserves tickets for a contact and confirms the
+// booking as if it had been paid for outside the provider
+func createConfirmedBooking(t *testing.T, bookingService *BookingService, showID uuid.UUID, contact string, numberOfTickets int32) *bookings.Booking {
+	t.Helper()
+
+	booking, err := bookingService.CreateBooking(bookings.NewBooking(showID, "email", contact, numberOfTickets, 0))
+	if err != nil {
+		t.Fatalf("Failed to create booking: %v", err)
+	}
+	if err := bookingService.ConfirmBooking(booking.BookingID, "test", ""); err != nil {
+		t.Fatalf("Failed to confirm booking: %v", err)
+	}
+	return booking
+}
+
+func TestCreateBookingConcurrentDoesNotOversell(t *testing.T) {
+	requireDatabase(t)
+
+	const totalTickets = 10
+	const attempts = 300
+
+	bookingService := NewBookingService()
+	show := createTestShow(t, "Concurrency Test", 100, totalTickets)
+
+	var succeeded, rejected int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			contact := fmt.Sprintf("buyer%03d@example.com", i)
+			draft := bookings.NewBooking(show.Show_Id, "email", contact, 1, 0)
+			_, err := bookingService.CreateBooking(draft)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&succeeded, 1)
+			case strings.Contains(err.Error(), "insufficient tickets"):
+				atomic.AddInt32(&rejected, 1)
+			default:
+				t.Errorf("Unexpected booking error: %v", err)
+			}
+		}(i)
+	}
+
+	// Release all goroutines at once to maximize contention
+	close(start)
+	wg.Wait()
+
+	if succeeded != totalTickets {
+		t.Errorf("Expected exactly %d successful bookings, got %d", totalTickets, succeeded)
+	}
+
+	if rejected != attempts-totalTickets {
+		t.Errorf("Expected %d rejected bookings, got %d", attempts-totalTickets, rejected)
+	}
+
+	ticketsSold, err := bookingService.bookingRepository.GetTicketsSoldForShow(show.Show_Id)
+	if err != nil {
+		t.Fatalf("Failed to get tickets sold: %v", err)
+	}
+	if ticketsSold != totalTickets {
+		t.Errorf("Expected %d tickets sold, got %d", totalTickets, ticketsSold)
+	}
+
+	// Read the counter straight from MySQL, bypassing the show cache
+	var bookedTickets int32
+	err = db.GetDatabase().GetDB().
+		QueryRow("SELECT booked_tickets FROM shows WHERE id = ?", show.Show_Id.String()).
+		Scan(&bookedTickets)
+	if err != nil {
+		t.Fatalf("Failed to read booked_tickets: %v", err)
+	}
+	if bookedTickets != totalTickets {
+		t.Errorf("Expected booked_tickets %d, got %d", totalTickets, bookedTickets)
+	}
+}
+
+func TestValidateSeatSelection(t *testing.T) {
+	venueID := uuid.New()
+	seated := &shows.ShowData{Show_Id: uuid.New(), VenueID: &venueID}
+	general := &shows.ShowData{Show_Id: uuid.New()}
+
+	tests := []struct {
+		name    string
+		show    *shows.ShowData
+		tickets int32
+		seats   []string
+		want    []string
+		message string
+	}{
+		{"general admission without seats", general, 2, nil, nil, ""},
+		{"general admission with seats", general, 1, []string{"A-1"}, nil, "does not have reserved seating"},
+		{"reserved seating without seats", seated, 2, nil, nil, "seats are required"},
+		{"seat count mismatch", seated, 2, []string{"STALLS-A-1"}, nil, "1 seats requested for 2 tickets"},
+		{"duplicate seats", seated, 2, []string{"stalls-a-1", "STALLS-A-1"}, nil, "more than once"},
+		{"normalizes seats", seated, 2, []string{"stalls-a-1", " STALLS-A-2 "}, []string{"STALLS-A-1", "STALLS-A-2"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := bookings.NewBooking(tt.show.Show_Id, "email", "test@example.com", tt.tickets, 0)
+			booking.Seats = tt.seats
+
+			seats, err := validateSeatSelection(tt.show, booking)
+			if tt.message != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.message) {
+					t.Errorf("Expected error containing %q, got %v", tt.message, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fmt.Sprint(seats) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected seats %v, got %v", tt.want, seats)
+			}
+		})
+	}
+}
+
+func TestSummarizeTicketTypes(t *testing.T) {
+	childQuota := int32(10)
+	vipQuota := int32(5)
+	ticketTypes := []*shows.TicketType{
+		{Code: "adult", Name: "Adult", Price: 5000},
+		{Code: "child", Name: "Child", Price: 2500, Quota: &childQuota},
+		{Code: "vip", Name: "VIP", Price: 12000, Quota: &vipQuota},
+	}
+	sales := map[string]*repository.TicketTypeSales{
+		"adult": {TicketsSold: 20, Revenue: 100000},
+		"child": {TicketsSold: 8, Revenue: 20000},
+		"vip":   {TicketsSold: 5, Revenue: 60000},
+	}
+
+	summaries := summarizeTicketTypes(ticketTypes, sales, 7)
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 3 summaries, got %d", len(summaries))
+	}
+
+	tests := []struct {
+		code      string
+		sold      int32
+		available int32
+	}{
+		{"adult", 20, 7}, // No quota: limited by the show
+		{"child", 8, 2},  // Quota leaves fewer than the show
+		{"vip", 5, 0},    // Quota used up
+	}
+
+	for i, tt := range tests {
+		summary := summaries[i]
+		if summary.Code != tt.code || summary.TicketsSold != tt.sold || summary.TicketsAvailable != tt.available {
+			t.Errorf("Expected %s sold=%d available=%d, got %+v", tt.code, tt.sold, tt.available, summary)
+		}
+	}
+}
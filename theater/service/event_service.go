@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+)
+
+const (
+	// eventBatchSize caps how many outbox events one claim takes
+	eventBatchSize = 100
+	// eventClaimLease is how long a claimed batch is reserved for this
+	// process; events not saved by then are picked up again
+	eventClaimLease = 2 * time.Minute
+	// maxEventListSize caps how many events one listing returns
+	maxEventListSize = 500
+)
+
+// eventSubscriptions are the in-process subscribers, shared by every
+// EventService in the process
+var eventSubscriptions struct {
+	sync.RWMutex
+	list []*events.Subscription
+}
+
+// EventService delivers the domain events written to the outbox to
+// in-process subscribers. Delivery is at least once: failed events are
+// retried with backoff and end up on the dead-letter list after
+// EVENT_MAX_ATTEMPTS attempts.
+type EventService struct {
+	outboxRepository *repository.OutboxRepository
+	maxAttempts      int32
+}
+
+// NewEventService creates a new event service
+func NewEventService() *EventService {
+	maxAttempts := utils.GetInt32OrDefault("EVENT_MAX_ATTEMPTS", 8)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &EventService{
+		outboxRepository: repository.NewOutboxRepository(),
+		maxAttempts:      maxAttempts,
+	}
+}
+
+// Subscribe registers a handler for the given event types, or for every
+// type when none are given. Names must be unique, since deliveries are
+// tracked per subscriber.
+func (s *EventService) Subscribe(name string, handler events.Handler, eventTypes ...string) error {
+	subscription, err := events.NewSubscription(name, handler, eventTypes...)
+	if err != nil {
+		return err
+	}
+
+	eventSubscriptions.Lock()
+	defer eventSubscriptions.Unlock()
+
+	for _, existing := range eventSubscriptions.list {
+		if existing.Name == subscription.Name {
+			return fmt.Errorf("invalid subscription: %s is already subscribed", subscription.Name)
+		}
+	}
+	eventSubscriptions.list = append(eventSubscriptions.list, subscription)
+
+	log.Printf("Event subscriber %s registered", subscription.Name)
+	return nil
+}
+
+// RegisterDefaultSubscribers subscribes the application's own event handlers
+func (s *EventService) RegisterDefaultSubscribers() error {
+	// Repairs the Redis show indexes when the inline update after a change failed
+	showService := NewShowService()
+	if err := s.Subscribe("search-index", showService.maintainIndexes); err != nil {
+		return err
+	}
+
+	// Queues events for partner webhook subscriptions
+	webhookService := NewWebhookService()
+	if err := s.Subscribe("webhooks", webhookService.EnqueueEvent); err != nil {
+		return err
+	}
+
+	// Queues booking emails and text messages for customers
+	notificationService := NewNotificationService()
+	return s.Subscribe("notifications", notificationService.HandleEvent,
+		events.TypeBookingCreated, events.TypeBookingStatusChanged, events.TypeShowUpdated)
+}
+
+// DispatchDue delivers every pending event whose next attempt is due, oldest
+// first. It returns the number of events delivered.
+func (s *EventService) DispatchDue() (int, error) {
+	delivered := 0
+	for {
+		now := time.Now()
+		due, claim, err := s.outboxRepository.ClaimDueEvents(now, eventClaimLease, eventBatchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		subscriptions := currentSubscriptions()
+		for _, event := range due {
+			if err := events.Deliver(event, subscriptions, s.maxAttempts, time.Now()); err != nil {
+				log.Printf("Warning: %v (attempt %d of %d)", err, event.Attempts, s.maxAttempts)
+				if event.Status == events.StatusDead {
+					log.Printf("Error: event %s (%s) moved to the dead-letter list", event.EventID, event.Type)
+				}
+			}
+
+			saved, err := s.outboxRepository.SaveDelivery(event, claim)
+			if err != nil {
+				return delivered, err
+			}
+			if !saved {
+				log.Printf("Warning: claim on event %s lapsed before delivery was recorded", event.EventID)
+				continue
+			}
+			if event.Status == events.StatusDelivered {
+				delivered++
+			}
+		}
+
+		if len(due) < eventBatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// RunDispatcher delivers due events on a fixed interval until the context is
+// cancelled. Delivered events are deleted once they are older than retention;
+// a retention of 0 keeps them.
+func (s *EventService) RunDispatcher(ctx context.Context, interval, retention time.Duration) {
+	log.Printf("Event dispatcher running every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Event dispatcher stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.DispatchDue(); err != nil {
+				log.Printf("Warning: Event dispatcher failed: %v", err)
+			}
+		case <-purge.C:
+			if retention <= 0 {
+				continue
+			}
+			deleted, err := s.outboxRepository.DeleteDeliveredEvents(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Warning: Failed to purge delivered events: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d delivered events", deleted)
+			}
+		}
+	}
+}
+
+// ListEvents returns outbox events in a status, newest first; dead events
+// make up the dead-letter list
+func (s *EventService) ListEvents(status string) ([]*events.Event, error) {
+	switch status {
+	case events.StatusPending, events.StatusDelivered, events.StatusDead:
+	default:
+		return nil, fmt.Errorf("invalid status filter: %q", status)
+	}
+	return s.outboxRepository.GetEventsByStatus(status, maxEventListSize)
+}
+
+// GetEvent returns an outbox event by its event ID
+func (s *EventService) GetEvent(eventID string) (*events.Event, error) {
+	if eventID == "" {
+		return nil, fmt.Errorf("event ID cannot be empty")
+	}
+	return s.outboxRepository.GetEvent(eventID)
+}
+
+// GetAggregateEvents returns the events of one show or booking in the order they were written
+func (s *EventService) GetAggregateEvents(aggregateType, aggregateID string) ([]*events.Event, error) {
+	if aggregateID == "" {
+		return nil, fmt.Errorf("%s ID cannot be empty", aggregateType)
+	}
+	return s.outboxRepository.GetEventsByAggregate(aggregateType, aggregateID)
+}
+
+// RetryEvent moves a dead event back to the queue for another round of
+// attempts. Subscribers that already handled it are skipped.
+func (s *EventService) RetryEvent(eventID string) (*events.Event, error) {
+	if eventID == "" {
+		return nil, fmt.Errorf("event ID cannot be empty")
+	}
+
+	event, err := s.outboxRepository.RequeueEvent(eventID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Event %s (%s) requeued from the dead-letter list", event.EventID, event.Type)
+	return event, nil
+}
+
+// currentSubscriptions returns a snapshot of the registered subscribers
+func currentSubscriptions() []*events.Subscription {
+	eventSubscriptions.RLock()
+	defer eventSubscriptions.RUnlock()
+
+	return append([]*events.Subscription(nil), eventSubscriptions.list...)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+)
+
+// ExchangeService moves bookings, or some of their tickets, to another
+// performance and settles the price difference: a cheaper performance
+// refunds a confirmed booking through the refund ledger, a dearer one leaves
+// the new booking with a balance due that must be paid before check-in
+type ExchangeService struct {
+	exchangeRepository *repository.ExchangeRepository
+	paymentRepository  *repository.PaymentRepository
+	bookingService     *BookingService
+	refundService      *RefundService
+}
+
+// NewExchangeService creates a new exchange service
+func NewExchangeService() *ExchangeService {
+	return &ExchangeService{
+		exchangeRepository: repository.NewExchangeRepository(),
+		paymentRepository:  repository.NewPaymentRepository(),
+		bookingService:     NewBookingService(),
+		refundService:      NewRefundService(),
+	}
+}
+
+// ExchangeResult is an exchange, the booking it created and how the price
+// difference was settled
+type ExchangeResult struct {
+	Exchange   *bookings.Exchange `json:"exchange"`
+	Source     *bookings.Booking  `json:"source_booking"`
+	Target     *bookings.Booking  `json:"target_booking"`
+	Refund     *payments.Refund   `json:"refund,omitempty"`      // Confirmed booking moved to a cheaper performance
+	BalanceDue int32              `json:"balance_due,omitempty"` // Confirmed booking moved to a dearer performance, owed on the target booking
+}
+
+// ExchangeBooking moves the selected tickets of a booking, looked up by
+// booking ID or reference, to a new booking on the target show. The new
+// booking is priced at the target show's current prices and keeps the
+// source booking's status; seats are required for reserved-seating shows.
+// Pending bookings cannot be exchanged while a payment for them is under way.
+func (s *ExchangeService) ExchangeBooking(idOrReference string, targetShowID uuid.UUID, selection bookings.ExchangeSelection, seats []string, changedBy, reason string) (*ExchangeResult, error) {
+	if changedBy == "" {
+		return nil, fmt.Errorf("changed by cannot be empty")
+	}
+
+	source, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.Status == bookings.StatusPending {
+		payment, err := paymentInProgress(s.paymentRepository, source.BookingID)
+		if err != nil {
+			return nil, err
+		}
+		if payment != nil {
+			return nil, fmt.Errorf("invalid exchange: booking %s is waiting for payment %s; cancel it and book again",
+				source.BookingID, payment.PaymentID)
+		}
+	}
+
+	show, err := s.bookingService.showService.GetShow(targetShowID.String())
+	if err != nil {
+		return nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	build := func(moved *bookings.Booking) (*bookings.Booking, error) {
+		return priceExchangedTickets(show, moved, seats)
+	}
+
+	// A confirmed booking moved to a cheaper performance is refunded, with
+	// the refund recorded in the exchange's transaction. The exchange runs on
+	// the locked booking; a pending booking cannot have been confirmed in
+	// between without its payment having been in progress.
+	var refund *payments.Refund
+	var refundFor func(exchange *bookings.Exchange) (*payments.Refund, error)
+	if source.Status == bookings.StatusConfirmed {
+		refundFor = func(exchange *bookings.Exchange) (*payments.Refund, error) {
+			var err error
+			refund, err = s.refundService.ExchangeRefund(exchange)
+			return refund, err
+		}
+	}
+
+	// Both shows' inventory, the source booking, the new booking and any
+	// refund change in one transaction
+	exchange, target, inventories, err := s.exchangeRepository.ExchangeBooking(source.BookingID, targetShowID, selection, build, refundFor, changedBy, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange booking: %w", err)
+	}
+
+	for _, inventory := range inventories {
+		s.bookingService.syncShowAvailability(inventory)
+	}
+	s.bookingService.offerReleasedTickets(inventories[0])
+
+	result := &ExchangeResult{Exchange: exchange, Target: target, Refund: refund, BalanceDue: target.BalanceDue}
+	if refund != nil {
+		s.refundService.SendRefund(refund)
+	}
+
+	result.Source, err = s.bookingService.GetBooking(source.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully exchanged booking %s to %s (%s, difference %d, by %s)",
+		source.BookingID, target.BookingID, exchange.ExchangeID, exchange.PriceDifference, changedBy)
+	return result, nil
+}
+
+// GetBookingExchanges returns the exchanges of a booking, looked up by booking ID or reference
+func (s *ExchangeService) GetBookingExchanges(idOrReference string) ([]*bookings.Exchange, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges, err := s.exchangeRepository.GetExchangesByBooking(booking.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking exchanges: %w", err)
+	}
+	return exchanges, nil
+}
+
+// GetShowExchanges returns the exchanges into or out of a show
+func (s *ExchangeService) GetShowExchanges(showID uuid.UUID) ([]*bookings.Exchange, error) {
+	if _, err := s.bookingService.showService.GetShow(showID.String()); err != nil {
+		return nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	exchanges, err := s.exchangeRepository.GetExchangesByShow(showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show exchanges: %w", err)
+	}
+	return exchanges, nil
+}
+
+// priceExchangedTickets turns tickets taken off a booking into a new booking
+// for the same customer on show, at the show's current prices. Ticket types
+// carry over when the show sells them; a show without ticket types sells the
+// tickets at its single price.
+func priceExchangedTickets(show *shows.ShowData, moved *bookings.Booking, seats []string) (*bookings.Booking, error) {
+	target := bookings.NewBooking(show.Show_Id, moved.ContactType, moved.ContactValue, moved.NumberOfTickets, 0)
+	target.CustomerName = moved.CustomerName
+	target.UserID = moved.UserID
+	target.Status = moved.Status
+	if target.Status == bookings.StatusPending {
+		// The customer gets no longer to pay than the booking they had
+		target.HoldExpiresAt = moved.HoldExpiresAt
+	}
+	target.Seats = seats
+
+	if show.HasTicketTypes() {
+		for _, line := range moved.TicketLines {
+			copied := *line
+			target.TicketLines = append(target.TicketLines, &copied)
+		}
+	}
+
+	validSeats, err := validateSeatSelection(show, target)
+	if err != nil {
+		return nil, err
+	}
+	target.Seats = validSeats
+
+	if err := target.ApplyPrices(show.Price, shows.TicketPrices(show.TicketTypes)); err != nil {
+		return nil, fmt.Errorf("invalid exchange: %w", err)
+	}
+
+	// The moved tickets were paid for; what the dearer performance costs on
+	// top is owed before the tickets get in
+	if target.Status == bookings.StatusConfirmed && target.TotalAmount > moved.TotalAmount {
+		target.BalanceDue = target.TotalAmount - moved.TotalAmount
+	}
+	return target, nil
+}
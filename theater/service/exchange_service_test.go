@@ -0,0 +1,92 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/shows"
+)
+
+func TestPriceExchangedTickets(t *testing.T) {
+	dearer := &shows.ShowData{Show_Id: uuid.New(), Price: 1500}
+	cheaper := &shows.ShowData{Show_Id: uuid.New(), Price: 500}
+
+	tests := []struct {
+		name       string
+		show       *shows.ShowData
+		status     string
+		total      int32
+		balanceDue int32
+	}{
+		{"confirmed to a dearer show", dearer, bookings.StatusConfirmed, 3000, 1000},
+		{"confirmed to a cheaper show", cheaper, bookings.StatusConfirmed, 1000, 0},
+		{"pending to a dearer show", dearer, bookings.StatusPending, 3000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved := bookings.NewBooking(uuid.New(), "email", "fan@example.com", 2, 2000)
+			moved.Status = tt.status
+			moved.UserID = "user-1"
+
+			target, err := priceExchangedTickets(tt.show, moved, nil)
+			if err != nil {
+				t.Fatalf("priceExchangedTickets() error: %v", err)
+			}
+			if target.TotalAmount != tt.total || target.BalanceDue != tt.balanceDue {
+				t.Errorf("Expected total %d with %d due, got %d with %d due",
+					tt.total, tt.balanceDue, target.TotalAmount, target.BalanceDue)
+			}
+			if target.Status != tt.status || target.UserID != "user-1" {
+				t.Errorf("Expected the booking's status and account to carry over, got %s %q", target.Status, target.UserID)
+			}
+		})
+	}
+}
+
+func TestExchangeBookingMovesInventoryBetweenShows(t *testing.T) {
+	requireDatabase(t)
+
+	exchangeService := NewExchangeService()
+	bookingService := exchangeService.bookingService
+
+	source := createTestShow(t, "Exchange Source", 1000, 4)
+	target := createTestShow(t, "Exchange Target", 500, 2)
+	booking := createConfirmedBooking(t, bookingService, source.Show_Id, "swap@example.com", 3)
+
+	// The target only has room for 2, and a refused exchange changes neither show
+	_, err := exchangeService.ExchangeBooking(booking.BookingID, target.Show_Id, bookings.ExchangeSelection{NumberOfTickets: 3}, nil, "test", "")
+	if err == nil || !strings.Contains(err.Error(), "insufficient tickets") {
+		t.Fatalf("Expected the exchange to be refused for capacity, got %v", err)
+	}
+	if booked := readBookedTickets(t, source.Show_Id); booked != 3 {
+		t.Errorf("Expected the source show to keep 3 booked, got %d", booked)
+	}
+	if booked := readBookedTickets(t, target.Show_Id); booked != 0 {
+		t.Errorf("Expected the target show to keep 0 booked, got %d", booked)
+	}
+
+	// Moving 2 tickets to the cheaper show refunds the 1000 difference
+	result, err := exchangeService.ExchangeBooking(booking.BookingID, target.Show_Id, bookings.ExchangeSelection{NumberOfTickets: 2}, nil, "test", "")
+	if err != nil {
+		t.Fatalf("ExchangeBooking() error: %v", err)
+	}
+	if booked := readBookedTickets(t, source.Show_Id); booked != 1 {
+		t.Errorf("Expected the source show to have 1 booked, got %d", booked)
+	}
+	if booked := readBookedTickets(t, target.Show_Id); booked != 2 {
+		t.Errorf("Expected the target show to have 2 booked, got %d", booked)
+	}
+	if result.Source.NumberOfTickets != 1 || result.Source.Status != bookings.StatusConfirmed {
+		t.Errorf("Expected the source booking to keep 1 confirmed ticket, got %d %s", result.Source.NumberOfTickets, result.Source.Status)
+	}
+	if result.Target.NumberOfTickets != 2 || result.Target.TotalAmount != 1000 || result.Target.Status != bookings.StatusConfirmed {
+		t.Errorf("Expected a confirmed target booking of 2 for 1000, got %d for %d, %s",
+			result.Target.NumberOfTickets, result.Target.TotalAmount, result.Target.Status)
+	}
+	if result.Exchange.PriceDifference != -1000 || result.Refund == nil || result.Refund.Amount != 1000 {
+		t.Errorf("Expected a refund of 1000, got difference %d and %+v", result.Exchange.PriceDifference, result.Refund)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gsmayya/theater/idempotency"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+)
+
+// idempotencyClaimLease is how long a request holds its key before a retry
+// may take it over. It is well beyond the server's write timeout, so only a
+// request that died without answering loses its key.
+const idempotencyClaimLease = time.Minute
+
+// IdempotencyService lets clients retry create requests safely. The first
+// request with a key claims it; retries with the same payload get the stored
+// response back instead of running again.
+type IdempotencyService struct {
+	idempotencyRepository *repository.IdempotencyRepository
+	window                time.Duration
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepository: repository.NewIdempotencyRepository(),
+		window:                utils.GetDurationOrDefault("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+	}
+}
+
+// BeginRequest claims an owner's key for a request with the given fingerprint. It
+// returns the claim when the request should run, or the stored record when
+// its response should be replayed instead. A key reused with a different
+// payload returns idempotency.ErrFingerprintMismatch, and a retry that
+// arrives while the original is still running returns idempotency.ErrInProgress.
+func (s *IdempotencyService) BeginRequest(scope, owner, key, fingerprint string) (claim *idempotency.Record, replay *idempotency.Record, err error) {
+	if err := idempotency.ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	record := idempotency.NewRecord(scope, owner, key, fingerprint, s.window, idempotencyClaimLease)
+	existing, err := s.idempotencyRepository.ClaimKey(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing == nil {
+		return record, nil, nil
+	}
+
+	if err := existing.CheckReplay(fingerprint); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("Replaying response for idempotency key %s (%s)", key, scope)
+	return nil, existing, nil
+}
+
+// FinishRequest stores the response of a claimed request. Server errors are
+// not stored; the claim is released so the client can retry with the same key.
+func (s *IdempotencyService) FinishRequest(claim *idempotency.Record, statusCode int, body []byte) {
+	if statusCode >= http.StatusInternalServerError {
+		if err := s.idempotencyRepository.ReleaseKey(claim); err != nil {
+			log.Printf("Warning: Failed to release idempotency key %s: %v", claim.Key, err)
+		}
+		return
+	}
+
+	claim.StatusCode = statusCode
+	claim.Body = string(body)
+	if err := s.idempotencyRepository.CompleteKey(claim); err != nil {
+		log.Printf("Warning: Failed to store response for idempotency key %s: %v", claim.Key, err)
+	}
+}
+
+// PurgeExpiredKeys deletes keys whose window has passed
+func (s *IdempotencyService) PurgeExpiredKeys() (int64, error) {
+	deleted, err := s.idempotencyRepository.DeleteExpiredKeys(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		log.Printf("Purged %d expired idempotency keys", deleted)
+	}
+	return deleted, nil
+}
+
+// RunPurger deletes expired keys on a fixed interval until the context is cancelled
+func (s *IdempotencyService) RunPurger(ctx context.Context, interval time.Duration) {
+	log.Printf("Idempotency key purger running every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Idempotency key purger stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpiredKeys(); err != nil {
+				log.Printf("Warning: Idempotency key purger failed: %v", err)
+			}
+		}
+	}
+}
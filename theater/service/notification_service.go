@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/notifications"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/utils"
+)
+
+const (
+	// notificationBatchSize caps how many notifications one claim takes
+	notificationBatchSize = 100
+	// notificationClaimLease is how long a claimed batch is reserved for this process
+	notificationClaimLease = 5 * time.Minute
+)
+
+// NotificationService tells customers about their bookings over the channel
+// of their contact type. Messages are rendered when they are queued, by the
+// "notifications" event subscriber or a resend, and sent by RunSender with
+// retries; every message stays in the booking's delivery log.
+type NotificationService struct {
+	notificationRepository *repository.NotificationRepository
+	bookingService         *BookingService
+	showService            *ShowService
+	notifiers              map[string]notifications.Notifier // By channel; a missing channel is not notified
+	maxAttempts            int32
+	resendCooldown         time.Duration
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService() *NotificationService {
+	maxAttempts := utils.GetInt32OrDefault("NOTIFY_MAX_ATTEMPTS", 6)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &NotificationService{
+		notificationRepository: repository.NewNotificationRepository(),
+		bookingService:         NewBookingService(),
+		showService:            NewShowService(),
+		notifiers:              notificationTransports(),
+		maxAttempts:            maxAttempts,
+		resendCooldown:         utils.GetDurationOrDefault("NOTIFY_RESEND_COOLDOWN", 5*time.Minute),
+	}
+}
+
+var (
+	notifiersOnce   sync.Once
+	sharedNotifiers map[string]notifications.Notifier
+)
+
+// notificationTransports sets up the transports named by
+// NOTIFY_EMAIL_TRANSPORT (log, smtp or none) and NOTIFY_SMS_TRANSPORT (log,
+// sms or none) once per process. The log transport writes to NOTIFY_LOG_FILE,
+// or stdout when it is unset.
+func notificationTransports() map[string]notifications.Notifier {
+	notifiersOnce.Do(func() {
+		sharedNotifiers = make(map[string]notifications.Notifier)
+
+		var logOut io.Writer
+		logWriter := func() io.Writer {
+			if logOut != nil {
+				return logOut
+			}
+			logOut = os.Stdout
+			if path := utils.GetEnvOrDefault("NOTIFY_LOG_FILE", ""); path != "" {
+				file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+				if err != nil {
+					log.Printf("Warning: cannot open NOTIFY_LOG_FILE, logging notifications to stdout: %v", err)
+				} else {
+					logOut = file
+				}
+			}
+			return logOut
+		}
+
+		switch name := utils.GetEnvOrDefault("NOTIFY_EMAIL_TRANSPORT", "log"); name {
+		case "none":
+			log.Printf("Email notifications disabled")
+		case "log":
+			sharedNotifiers[notifications.ChannelEmail] = notifications.NewLogNotifier(notifications.ChannelEmail, logWriter())
+		case "smtp":
+			notifier, err := notifications.NewSMTPNotifier(
+				utils.GetEnvOrDefault("SMTP_HOST", ""),
+				utils.GetEnvOrDefault("SMTP_PORT", "587"),
+				utils.GetEnvOrDefault("SMTP_USERNAME", ""),
+				utils.GetEnvOrDefault("SMTP_PASSWORD", ""),
+				utils.GetEnvOrDefault("SMTP_FROM", ""),
+			)
+			if err != nil {
+				log.Printf("Warning: invalid SMTP configuration, email notifications disabled: %v", err)
+				break
+			}
+			sharedNotifiers[notifications.ChannelEmail] = notifier
+		default:
+			log.Printf("Warning: unknown NOTIFY_EMAIL_TRANSPORT %q, email notifications disabled", name)
+		}
+
+		switch name := utils.GetEnvOrDefault("NOTIFY_SMS_TRANSPORT", "log"); name {
+		case "none":
+			log.Printf("SMS notifications disabled")
+		case "log":
+			sharedNotifiers[notifications.ChannelMobile] = notifications.NewLogNotifier(notifications.ChannelMobile, logWriter())
+		case "sms":
+			notifier, err := notifications.NewSMSNotifier(
+				utils.GetEnvOrDefault("SMS_GATEWAY_URL", ""),
+				utils.GetEnvOrDefault("SMS_API_KEY", ""),
+				utils.GetEnvOrDefault("SMS_FROM", ""),
+				utils.GetDurationOrDefault("SMS_TIMEOUT", 10*time.Second),
+			)
+			if err != nil {
+				log.Printf("Warning: invalid SMS gateway configuration, SMS notifications disabled: %v", err)
+				break
+			}
+			sharedNotifiers[notifications.ChannelMobile] = notifier
+		default:
+			log.Printf("Warning: unknown NOTIFY_SMS_TRANSPORT %q, SMS notifications disabled", name)
+		}
+	})
+	return sharedNotifiers
+}
+
+// HandleEvent queues the customer messages an outbox event calls for. It is
+// the handler of the "notifications" event subscriber.
+func (s *NotificationService) HandleEvent(event *events.Event) error {
+	switch event.Type {
+	case events.TypeBookingCreated:
+		var booking bookings.Booking
+		if err := event.Decode(&booking); err != nil {
+			return err
+		}
+		switch booking.Status {
+		case bookings.StatusPending:
+			return s.notifyBooking(booking.BookingID, notifications.KindBookingCreated, event.EventID, "")
+		case bookings.StatusConfirmed:
+			// Exchanges and waitlist offers can create bookings that are already confirmed
+			return s.notifyBooking(booking.BookingID, notifications.KindBookingConfirmed, event.EventID, "")
+		}
+
+	case events.TypeBookingStatusChanged:
+		var change events.BookingStatusChange
+		if err := event.Decode(&change); err != nil {
+			return err
+		}
+		switch change.ToStatus {
+		case bookings.StatusConfirmed:
+			return s.notifyBooking(change.BookingID, notifications.KindBookingConfirmed, event.EventID, "")
+		case bookings.StatusCancelled:
+			return s.notifyBooking(change.BookingID, notifications.KindBookingCancelled, event.EventID, "")
+		case bookings.StatusExpired:
+			return s.notifyBooking(change.BookingID, notifications.KindBookingCancelled, event.EventID,
+				"the hold expired before payment was completed")
+		}
+
+	case events.TypeShowUpdated:
+		var update events.ShowUpdate
+		if err := event.Decode(&update); err != nil {
+			return err
+		}
+		switch update.Change {
+		case events.ChangeDetails:
+			return s.notifyShowChange(update.ShowID, event.EventID, "the show details have been updated")
+		case events.ChangeVenue:
+			return s.notifyShowChange(update.ShowID, event.EventID, "the show has moved to a different venue")
+		}
+	}
+	return nil
+}
+
+// notifyBooking queues a message of a kind for a booking's contact. Bookings
+// and shows deleted since the event are skipped, as are booking_created
+// messages for bookings that are no longer pending.
+func (s *NotificationService) notifyBooking(bookingID, kind, sourceID, reason string) error {
+	booking, err := s.bookingService.GetBooking(bookingID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+		return err
+	}
+	if kind == notifications.KindBookingCreated && booking.Status != bookings.StatusPending {
+		return nil
+	}
+
+	show, err := s.showService.GetShow(booking.ShowID.String())
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+		return err
+	}
+
+	notification, err := s.buildNotification(booking, show, kind, sourceID, reason)
+	if err != nil || notification == nil {
+		return err
+	}
+	return s.notificationRepository.EnqueueNotifications([]*notifications.Notification{notification})
+}
+
+// notifyShowChange queues a show_changed message for every pending or
+// confirmed booking of a show
+func (s *NotificationService) notifyShowChange(showID uuid.UUID, sourceID, reason string) error {
+	show, err := s.showService.GetShow(showID.String())
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+		return err
+	}
+
+	bookingList, err := s.bookingService.GetBookingsByShow(showID)
+	if err != nil {
+		return err
+	}
+
+	var queued []*notifications.Notification
+	for _, booking := range bookingList {
+		if booking.Status != bookings.StatusPending && booking.Status != bookings.StatusConfirmed {
+			continue
+		}
+		notification, err := s.buildNotification(booking, show, notifications.KindShowChanged, sourceID, reason)
+		if err != nil {
+			return err
+		}
+		if notification != nil {
+			queued = append(queued, notification)
+		}
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+
+	return s.notificationRepository.EnqueueNotifications(queued)
+}
+
+// buildNotification renders a message for a booking's contact. It returns
+// nil when the booking's channel has no transport.
+func (s *NotificationService) buildNotification(booking *bookings.Booking, show *shows.ShowData, kind, sourceID, reason string) (*notifications.Notification, error) {
+	data := notificationData(booking, show)
+	data.Reason = reason
+	return s.renderNotification(booking, kind, sourceID, data)
+}
+
+// renderNotification renders a message of a kind from data for a booking's
+// contact. It returns nil when the booking's channel has no transport.
+func (s *NotificationService) renderNotification(booking *bookings.Booking, kind, sourceID string, data *notifications.TemplateData) (*notifications.Notification, error) {
+	if _, ok := s.notifiers[booking.ContactType]; !ok {
+		return nil, nil
+	}
+
+	message, err := notifications.Render(kind, booking.ContactType, booking.ContactValue, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications.NewNotification(booking.BookingID, kind, sourceID, message)
+}
+
+// channels returns the contact channels that have a transport
+func (s *NotificationService) channels() []string {
+	channels := make([]string, 0, len(s.notifiers))
+	for channel := range s.notifiers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// notificationData is what a booking's messages are rendered from
+func notificationData(booking *bookings.Booking, show *shows.ShowData) *notifications.TemplateData {
+	return &notifications.TemplateData{
+		CustomerName:    booking.CustomerName,
+		Reference:       booking.Reference,
+		ShowName:        show.ShowName,
+		ShowDate:        show.ShowDate,
+		ShowLocation:    show.ShowLocation,
+		NumberOfTickets: booking.NumberOfTickets,
+		TotalAmount:     booking.TotalAmount,
+		Seats:           booking.Seats,
+		HoldExpiresAt:   booking.HoldExpiresAt,
+	}
+}
+
+// SendDue sends every pending notification whose next attempt is due. It
+// returns the number sent.
+func (s *NotificationService) SendDue() (int, error) {
+	sent := 0
+	for {
+		due, claim, err := s.notificationRepository.ClaimDueNotifications(time.Now(), notificationClaimLease, notificationBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, notification := range due {
+			transport, sendErr := "", error(nil)
+			if notifier, ok := s.notifiers[notification.Channel]; ok {
+				transport, sendErr = notifier.Name(), notifier.Send(notification.Message())
+			} else {
+				sendErr = fmt.Errorf("no %s transport configured", notification.Channel)
+			}
+
+			notification.Record(transport, sendErr, time.Now(), s.maxAttempts)
+			if sendErr != nil {
+				log.Printf("Warning: notification %s to booking %s failed: %v (attempt %d of %d)",
+					notification.NotificationID, notification.BookingID, sendErr, notification.Attempts, s.maxAttempts)
+			}
+
+			saved, err := s.notificationRepository.SaveAttempt(notification, claim)
+			if err != nil {
+				return sent, err
+			}
+			if !saved {
+				log.Printf("Warning: claim on notification %s lapsed before the attempt was recorded", notification.NotificationID)
+				continue
+			}
+			if notification.Status == notifications.StatusSent {
+				sent++
+			}
+		}
+
+		if len(due) < notificationBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// RunSender sends due notifications on a fixed interval until the context is cancelled
+func (s *NotificationService) RunSender(ctx context.Context, interval time.Duration) {
+	log.Printf("Notification sender running every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Notification sender stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.SendDue(); err != nil {
+				log.Printf("Warning: Notification sender failed: %v", err)
+			}
+		}
+	}
+}
+
+// GetBookingNotifications returns every message queued for a booking, found
+// by ID or reference, newest first
+func (s *NotificationService) GetBookingNotifications(idOrReference string) ([]*notifications.Notification, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.notificationRepository.GetNotificationsByBooking(booking.BookingID)
+}
+
+// ResendConfirmation queues the confirmation of a confirmed booking again,
+// rendered with the booking's current details. Resends are refused while the
+// last confirmation is less than NOTIFY_RESEND_COOLDOWN old.
+func (s *NotificationService) ResendConfirmation(idOrReference string) (*notifications.Notification, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	bookingID := booking.BookingID
+	if booking.Status != bookings.StatusConfirmed && booking.Status != bookings.StatusCheckedIn {
+		return nil, fmt.Errorf("invalid booking status: booking %s is %s, only confirmed bookings have a confirmation to resend",
+			bookingID, booking.Status)
+	}
+	if _, ok := s.notifiers[booking.ContactType]; !ok {
+		return nil, fmt.Errorf("notifications unavailable: no %s transport configured", booking.ContactType)
+	}
+
+	latest, err := s.notificationRepository.GetLatestNotification(bookingID, notifications.KindBookingConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		if wait := latest.CreatedAt.Add(s.resendCooldown).Sub(time.Now()); wait > 0 {
+			return nil, fmt.Errorf("too many requests: a confirmation was queued at %s, try again in %s",
+				latest.CreatedAt.Format(time.RFC3339), wait.Round(time.Second))
+		}
+	}
+
+	show, err := s.showService.GetShow(booking.ShowID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := s.buildNotification(booking, show, notifications.KindBookingConfirmed, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepository.EnqueueNotifications([]*notifications.Notification{notification}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Confirmation resend queued for booking %s", bookingID)
+	return notification, nil
+}
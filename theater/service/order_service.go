@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/orders"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+)
+
+// OrderService provides business logic for multi-show orders. An order's
+// bookings are reserved, confirmed and cancelled together.
+type OrderService struct {
+	orderRepository *repository.OrderRepository
+	bookingService  *BookingService
+	showService     *ShowService
+}
+
+// NewOrderService creates a new order service
+func NewOrderService() *OrderService {
+	bookingService := NewBookingService()
+	return &OrderService{
+		orderRepository: repository.NewOrderRepository(),
+		bookingService:  bookingService,
+		showService:     bookingService.showService,
+	}
+}
+
+// CreateOrder validates and prices each of the order's bookings, then
+// reserves all of them in one transaction. The bookings share one hold,
+// the shortest of their shows' hold durations, so they lapse together.
+func (s *OrderService) CreateOrder(order *orders.Order) (*orders.Order, error) {
+	var holdDuration time.Duration
+	for _, booking := range order.Bookings {
+		show, err := s.showService.GetShow(booking.ShowID.String())
+		if err != nil {
+			return nil, fmt.Errorf("show not found: %w", err)
+		}
+
+		seats, err := validateSeatSelection(show, booking)
+		if err != nil {
+			return nil, err
+		}
+		booking.Seats = seats
+
+		if err := booking.ApplyPrices(show.Price, shows.TicketPrices(show.TicketTypes)); err != nil {
+			return nil, err
+		}
+
+		if duration := show.HoldDuration(s.bookingService.holdDuration); holdDuration == 0 || duration < holdDuration {
+			holdDuration = duration
+		}
+	}
+	order.PlaceHold(holdDuration)
+	order.SumTotals()
+
+	inventories, err := s.orderRepository.ReserveOrder(order, bookings.ActorCustomer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	for _, inventory := range inventories {
+		s.bookingService.syncShowAvailability(inventory)
+	}
+
+	log.Printf("Successfully created order: %s with %d bookings", order.OrderID, len(order.Bookings))
+	return order, nil
+}
+
+// GetOrder retrieves an order with its bookings
+func (s *OrderService) GetOrder(orderID string) (*orders.Order, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("order ID cannot be empty")
+	}
+
+	return s.orderRepository.GetOrder(orderID)
+}
+
+// ConfirmOrder confirms a pending order and every one of its bookings
+func (s *OrderService) ConfirmOrder(orderID, changedBy, reason string) (*orders.Order, error) {
+	return s.updateOrderStatus(orderID, orders.StatusConfirmed, changedBy, reason)
+}
+
+// CancelOrder cancels an order and every one of its bookings, offering the
+// released tickets to each show's waitlist
+func (s *OrderService) CancelOrder(orderID, changedBy, reason string) (*orders.Order, error) {
+	return s.updateOrderStatus(orderID, orders.StatusCancelled, changedBy, reason)
+}
+
+func (s *OrderService) updateOrderStatus(orderID, status, changedBy, reason string) (*orders.Order, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("order ID cannot be empty")
+	}
+	if changedBy == "" {
+		return nil, fmt.Errorf("changed by cannot be empty")
+	}
+
+	inventories, err := s.orderRepository.UpdateOrderStatus(orderID, status, changedBy, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	for _, inventory := range inventories {
+		s.bookingService.syncShowAvailability(inventory)
+		if status == orders.StatusCancelled {
+			s.bookingService.offerReleasedTickets(inventory)
+		}
+	}
+
+	log.Printf("Successfully updated order %s status to %s (by %s)", orderID, status, changedBy)
+	return s.orderRepository.GetOrder(orderID)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/orders"
+)
+
+func TestOrderReservesAndCancelsAllShowsTogether(t *testing.T) {
+	requireDatabase(t)
+
+	orderService := NewOrderService()
+
+	matinee := createTestShow(t, "Order Test Matinee", 100, 5)
+	evening := createTestShow(t, "Order Test Evening", 250, 1)
+
+	newOrder := func(matineeTickets, eveningTickets int32) *orders.Order {
+		order, err := orders.NewOrder(&orders.OrderRequest{
+			ContactType:  "email",
+			ContactValue: "fan@example.com",
+			Items: []*bookings.BookingRequest{
+				{ShowID: matinee.Show_Id.String(), NumberOfTickets: matineeTickets},
+				{ShowID: evening.Show_Id.String(), NumberOfTickets: eveningTickets},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewOrder() error: %v", err)
+		}
+		return order
+	}
+
+	// The evening show cannot take 2, so nothing is reserved on either show
+	if _, err := orderService.CreateOrder(newOrder(2, 2)); err == nil || !strings.Contains(err.Error(), "insufficient tickets") {
+		t.Fatalf("Expected the order to be refused for insufficient tickets, got %v", err)
+	}
+	if booked := readBookedTickets(t, matinee.Show_Id); booked != 0 {
+		t.Errorf("Expected no matinee tickets booked after the refused order, got %d", booked)
+	}
+
+	order, err := orderService.CreateOrder(newOrder(2, 1))
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if order.TotalAmount != 450 || order.HoldExpiresAt == nil {
+		t.Errorf("Expected a held order totalling 450, got %d", order.TotalAmount)
+	}
+	if matineeBooked, eveningBooked := readBookedTickets(t, matinee.Show_Id), readBookedTickets(t, evening.Show_Id); matineeBooked != 2 || eveningBooked != 1 {
+		t.Errorf("Expected 2 and 1 tickets booked, got %d and %d", matineeBooked, eveningBooked)
+	}
+
+	cancelled, err := orderService.CancelOrder(order.OrderID, "test", "changed plans")
+	if err != nil {
+		t.Fatalf("Failed to cancel order: %v", err)
+	}
+	if cancelled.Status != orders.StatusCancelled || len(cancelled.Bookings) != 2 {
+		t.Fatalf("Expected a cancelled order of 2 bookings, got %s with %d", cancelled.Status, len(cancelled.Bookings))
+	}
+	for _, booking := range cancelled.Bookings {
+		if booking.Status != bookings.StatusCancelled || booking.OrderID != order.OrderID {
+			t.Errorf("Expected booking %s cancelled with its order, got %s", booking.BookingID, booking.Status)
+		}
+	}
+	if matineeBooked, eveningBooked := readBookedTickets(t, matinee.Show_Id), readBookedTickets(t, evening.Show_Id); matineeBooked != 0 || eveningBooked != 0 {
+		t.Errorf("Expected every ticket released, got %d and %d booked", matineeBooked, eveningBooked)
+	}
+}
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+)
+
+// PaymentService ties bookings to money: creating a booking starts a payment
+// with the configured provider, and the provider's webhook confirms the
+// booking once the customer has paid or cancels it when the payment fails.
+// A balance left on a confirmed booking by an amendment or exchange is paid
+// the same way and clears the balance instead.
+type PaymentService struct {
+	paymentRepository *repository.PaymentRepository
+	bookingService    *BookingService
+	provider          payments.PaymentProvider // nil when payments are disabled
+	currency          string
+}
+
+// NewPaymentService creates a new payment service
+func NewPaymentService() *PaymentService {
+	return &PaymentService{
+		paymentRepository: repository.NewPaymentRepository(),
+		bookingService:    NewBookingService(),
+		provider:          paymentProvider(),
+		currency:          strings.ToUpper(utils.GetEnvOrDefault("PAYMENT_CURRENCY", "USD")),
+	}
+}
+
+var (
+	providerOnce   sync.Once
+	sharedProvider payments.PaymentProvider
+)
+
+// paymentProvider sets up the gateway named by PAYMENT_PROVIDER once per
+// process. Payments are off unless a provider is named, leaving bookings to
+// be confirmed by an admin through the confirm endpoint. The mock gateway
+// confirms bookings without taking money, so it has to be asked for by name
+// and is refused when GO_ENV is production.
+func paymentProvider() payments.PaymentProvider {
+	providerOnce.Do(func() {
+		switch name := utils.GetEnvOrDefault("PAYMENT_PROVIDER", "none"); name {
+		case "none":
+			log.Printf("Payments disabled; bookings are confirmed by an admin through /api/v1/bookings/confirm")
+		case payments.MockProviderName:
+			if utils.GetEnvOrDefault("GO_ENV", "") == "production" {
+				log.Printf("Warning: the mock payment provider is for local development and is refused when GO_ENV=production, payments disabled")
+				return
+			}
+			log.Printf("Warning: using the mock payment provider; bookings are confirmed without taking payment")
+			secret := utils.GetEnvOrDefault("MOCK_PAYMENT_WEBHOOK_SECRET", "")
+			if secret == "" {
+				log.Printf("Warning: MOCK_PAYMENT_WEBHOOK_SECRET not configured, signing mock payment webhooks with a temporary secret")
+				buf := make([]byte, 32)
+				if _, err := rand.Read(buf); err != nil {
+					log.Fatalf("Failed to create mock payment webhook secret: %v", err)
+				}
+				secret = hex.EncodeToString(buf)
+			}
+
+			provider, err := payments.NewMockProvider(secret)
+			if err != nil {
+				log.Printf("Warning: invalid MOCK_PAYMENT_WEBHOOK_SECRET, payments disabled: %v", err)
+				return
+			}
+			sharedProvider = provider
+		default:
+			log.Printf("Warning: unknown PAYMENT_PROVIDER %q, payments disabled", name)
+		}
+	})
+	return sharedProvider
+}
+
+// Enabled reports whether bookings are paid for through a payment provider
+func (s *PaymentService) Enabled() bool {
+	return s.provider != nil
+}
+
+// StartPayment starts paying for a newly created booking's total with the
+// provider. The returned payment carries the client secret the customer
+// completes the payment with. A booking with nothing to pay is confirmed
+// straight away and no payment is returned. If the payment cannot be
+// started, for whatever reason, the booking is cancelled: its tickets are not
+// held for a payment that never started, and a retry of the request books
+// afresh rather than leaving this booking behind.
+func (s *PaymentService) StartPayment(booking *bookings.Booking) (*payments.Payment, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	payment, err := s.startBookingPayment(booking)
+	if err != nil && booking.Status == bookings.StatusPending {
+		if cancelErr := s.bookingService.CancelBooking(booking.BookingID, "payment:"+s.provider.Name(), "payment could not be started"); cancelErr != nil {
+			log.Printf("Warning: Failed to cancel booking %s: %v", booking.BookingID, cancelErr)
+		}
+	}
+	return payment, err
+}
+
+// startBookingPayment starts paying for a pending booking's total, or
+// confirms it when there is nothing to pay
+func (s *PaymentService) startBookingPayment(booking *bookings.Booking) (*payments.Payment, error) {
+	if booking.Status != bookings.StatusPending {
+		return nil, fmt.Errorf("invalid status transition: booking %s is %s; only pending bookings are paid for", booking.BookingID, booking.Status)
+	}
+
+	if booking.TotalAmount <= 0 {
+		if err := s.bookingService.ConfirmBooking(booking.BookingID, "payment:"+s.provider.Name(), "nothing to pay"); err != nil {
+			return nil, err
+		}
+		booking.UpdateStatus(bookings.StatusConfirmed)
+		return nil, nil
+	}
+
+	payment, err := payments.NewPayment(booking.BookingID, s.provider.Name(), booking.TotalAmount, s.currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.paymentRepository.InsertPayment(payment); err != nil {
+		return nil, err
+	}
+	if err := s.startIntent(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// startIntent creates a recorded payment's intent with the provider and
+// links the two. A payment the provider refuses to start is marked failed.
+func (s *PaymentService) startIntent(payment *payments.Payment) error {
+	intent, err := s.provider.CreateIntent(payment.IntentRequest())
+	if err != nil {
+		log.Printf("Error starting payment %s for booking %s: %v", payment.PaymentID, payment.BookingID, err)
+		if _, markErr := s.paymentRepository.TransitionPayment(payment, payments.StatusPending, payments.StatusFailed, err.Error(), ""); markErr != nil {
+			log.Printf("Warning: Failed to mark payment %s failed: %v", payment.PaymentID, markErr)
+		}
+		return fmt.Errorf("payment provider unavailable: %w", err)
+	}
+
+	if err := s.paymentRepository.SetProviderPaymentID(payment, intent.ProviderPaymentID); err != nil {
+		return err
+	}
+	payment.ClientSecret = intent.ClientSecret
+
+	log.Printf("Started %s payment %s (%s) for booking %s", payment.Purpose, payment.PaymentID, intent.ProviderPaymentID, payment.BookingID)
+	return nil
+}
+
+// PayBooking starts paying for a pending booking that was not paid for when
+// it was made, such as a waitlist offer, or for the balance due on a
+// confirmed booking. Pending bookings of an order are confirmed with their
+// order, and a booking already being paid for is refused.
+func (s *PaymentService) PayBooking(idOrReference string) (*payments.Payment, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("payments are not enabled")
+	}
+
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case booking.Status == bookings.StatusPending && booking.OrderID != "":
+		return nil, fmt.Errorf("invalid payment: booking %s belongs to order %s and is confirmed with it", booking.BookingID, booking.OrderID)
+	case booking.Status != bookings.StatusPending && (booking.BalanceDue <= 0 || !bookings.HoldsInventory(booking.Status)):
+		return nil, fmt.Errorf("invalid payment: booking %s is %s with nothing to pay", booking.BookingID, booking.Status)
+	}
+
+	inProgress, err := paymentInProgress(s.paymentRepository, booking.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if inProgress != nil {
+		return nil, fmt.Errorf("invalid status transition: payment %s for booking %s is already in progress", inProgress.PaymentID, booking.BookingID)
+	}
+
+	if booking.Status == bookings.StatusPending {
+		// An offer that cannot be paid for now keeps its hold for another try
+		return s.startBookingPayment(booking)
+	}
+
+	payment, err := payments.NewBalancePayment(booking.BookingID, s.provider.Name(), booking.BalanceDue, s.currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.paymentRepository.InsertPayment(payment); err != nil {
+		return nil, err
+	}
+	if err := s.startIntent(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// HandleWebhook verifies and applies a provider webhook. A successful
+// payment confirms its booking and is then captured; if the booking can no
+// longer be confirmed, because its hold lapsed first, the payment is
+// refunded instead. A failed payment cancels its booking. Repeated
+// deliveries of an event leave the payment as it is. An error means the
+// webhook should be delivered again.
+func (s *PaymentService) HandleWebhook(payload []byte, headers http.Header) (*payments.Payment, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("payments are not enabled")
+	}
+
+	event, err := s.provider.VerifyWebhook(payload, headers, time.Now())
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid webhook") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid webhook: %w", err)
+	}
+
+	payment, err := s.paymentRepository.GetPaymentByProviderID(s.provider.Name(), event.ProviderPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		return s.settleSucceeded(payment, event)
+	case payments.EventPaymentFailed:
+		return s.settleFailed(payment, event)
+	default:
+		log.Printf("Ignoring %s webhook %s for payment %s", event.Type, event.EventID, payment.PaymentID)
+		return payment, nil
+	}
+}
+
+// GetBookingPayments returns the payment attempts of a booking, looked up by booking ID or reference
+func (s *PaymentService) GetBookingPayments(idOrReference string) ([]*payments.Payment, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	return s.paymentRepository.GetPaymentsByBooking(booking.BookingID)
+}
+
+// SimulateMockPayment completes a mock payment as if the customer had paid
+// (or their payment had failed), by delivering the webhook the mock gateway
+// would send. Only available while the mock provider is configured.
+func (s *PaymentService) SimulateMockPayment(paymentID, outcome, failureReason string) (*payments.Payment, error) {
+	mock, ok := s.provider.(*payments.MockProvider)
+	if !ok {
+		return nil, fmt.Errorf("mock payments are not enabled")
+	}
+
+	payment, err := s.paymentRepository.GetPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.ProviderPaymentID == "" {
+		return nil, fmt.Errorf("invalid payment: %s was never started with the provider", paymentID)
+	}
+
+	event := &payments.Event{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Amount:            payment.Amount,
+		Currency:          payment.Currency,
+	}
+	switch outcome {
+	case "succeeded":
+		event.Type = payments.EventPaymentSucceeded
+	case "failed":
+		event.Type = payments.EventPaymentFailed
+		event.FailureReason = failureReason
+		if event.FailureReason == "" {
+			event.FailureReason = "card_declined"
+		}
+	default:
+		return nil, fmt.Errorf("invalid outcome: %q must be succeeded or failed", outcome)
+	}
+
+	payload, headers, err := mock.SimulateWebhook(event, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.HandleWebhook(payload, headers)
+}
+
+// settleSucceeded confirms the booking a payment is for, then captures the payment
+func (s *PaymentService) settleSucceeded(payment *payments.Payment, event *payments.Event) (*payments.Payment, error) {
+	if payment.Status == payments.StatusPending {
+		claimed, err := s.paymentRepository.TransitionPayment(payment, payments.StatusPending, payments.StatusAuthorized, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			if payment, err = s.paymentRepository.GetPayment(payment.PaymentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Anything past authorized was settled by an earlier delivery
+	if payment.Status != payments.StatusAuthorized {
+		return payment, nil
+	}
+
+	if event.Amount != payment.Amount || !strings.EqualFold(event.Currency, payment.Currency) {
+		log.Printf("Warning: payment %s was paid %d %s, expected %d %s", payment.PaymentID,
+			event.Amount, event.Currency, payment.Amount, payment.Currency)
+		refundAmount := event.Amount
+		if refundAmount <= 0 {
+			refundAmount = payment.Amount
+		}
+		return s.refund(payment, refundAmount, "amount paid does not match the booking")
+	}
+
+	if payment.Purpose == payments.PurposeBalance {
+		return s.settleBalance(payment)
+	}
+
+	err := s.bookingService.ConfirmBooking(payment.BookingID, payment.ChangedBy(), "payment "+payment.PaymentID+" received")
+	if err != nil {
+		booking, getErr := s.bookingService.GetBooking(payment.BookingID)
+		if getErr != nil {
+			return nil, getErr
+		}
+
+		switch {
+		case booking.Status != bookings.StatusPending && bookings.HoldsInventory(booking.Status):
+			// Confirmed by another delivery of this webhook, unless another
+			// payment for the booking got there first
+			captured, err := otherCapturedPayment(s.paymentRepository, payment)
+			if err != nil {
+				return nil, err
+			}
+			if captured != nil {
+				return s.refund(payment, payment.Amount, "booking was already paid for by payment "+captured.PaymentID)
+			}
+		case booking.Status == bookings.StatusPending && !strings.Contains(err.Error(), "hold has expired") &&
+			!strings.Contains(err.Error(), "invalid status transition"):
+			return nil, err
+		default:
+			log.Printf("Booking %s could not be confirmed after payment %s: %v", payment.BookingID, payment.PaymentID, err)
+			return s.refund(payment, payment.Amount, "booking is "+booking.Status+"; its hold lapsed before payment arrived")
+		}
+	}
+
+	if err := s.provider.Capture(payment.ProviderPaymentID, payment.Amount); err != nil {
+		return nil, fmt.Errorf("failed to capture payment %s: %w", payment.PaymentID, err)
+	}
+	if _, err := s.paymentRepository.TransitionPayment(payment, payments.StatusAuthorized, payments.StatusCaptured, "", ""); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Payment %s captured; booking %s confirmed", payment.PaymentID, payment.BookingID)
+	return payment, nil
+}
+
+// settleBalance captures a payment of a confirmed booking's balance due and
+// clears that much of the balance. If the booking was cancelled, or its
+// balance paid, in the meantime the payment is refunded instead.
+func (s *PaymentService) settleBalance(payment *payments.Payment) (*payments.Payment, error) {
+	booking, err := s.bookingService.GetBooking(payment.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status == bookings.StatusPending || !bookings.HoldsInventory(booking.Status) || booking.BalanceDue < payment.Amount {
+		return s.refund(payment, payment.Amount, fmt.Sprintf("booking is %s with a balance of %d due", booking.Status, booking.BalanceDue))
+	}
+
+	if err := s.provider.Capture(payment.ProviderPaymentID, payment.Amount); err != nil {
+		return nil, fmt.Errorf("failed to capture payment %s: %w", payment.PaymentID, err)
+	}
+	captured, err := s.paymentRepository.CaptureBalancePayment(payment)
+	if err != nil {
+		return nil, err
+	}
+	if !captured {
+		current, err := s.paymentRepository.GetPayment(payment.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != payments.StatusAuthorized {
+			// Settled by another delivery of this webhook
+			return current, nil
+		}
+		// The booking changed between the check and the capture
+		return s.refund(payment, payment.Amount, "booking no longer owes this balance")
+	}
+
+	log.Printf("Payment %s captured; balance of %d on booking %s paid", payment.PaymentID, payment.Amount, payment.BookingID)
+	return payment, nil
+}
+
+// settleFailed records a failed payment and cancels its booking, releasing
+// the tickets, unless it was paying a balance
+func (s *PaymentService) settleFailed(payment *payments.Payment, event *payments.Event) (*payments.Payment, error) {
+	reason := event.FailureReason
+	if reason == "" {
+		reason = "payment failed"
+	}
+
+	claimed, err := s.paymentRepository.TransitionPayment(payment, payments.StatusPending, payments.StatusFailed, reason, "")
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return s.paymentRepository.GetPayment(payment.PaymentID)
+	}
+
+	// A failed balance payment leaves the booking as it was, balance and all
+	if payment.Purpose == payments.PurposeBalance {
+		log.Printf("Balance payment %s failed (%s) for booking %s", payment.PaymentID, reason, payment.BookingID)
+		return payment, nil
+	}
+
+	// The hold reaper releases the tickets anyway if this does not go through
+	err = s.bookingService.CancelBooking(payment.BookingID, payment.ChangedBy(), "payment failed: "+reason)
+	if err != nil {
+		log.Printf("Warning: Failed to cancel booking %s after payment %s failed: %v", payment.BookingID, payment.PaymentID, err)
+	}
+
+	log.Printf("Payment %s failed (%s) for booking %s", payment.PaymentID, reason, payment.BookingID)
+	return payment, nil
+}
+
+// refund returns an authorized payment to the customer
+func (s *PaymentService) refund(payment *payments.Payment, amount int32, reason string) (*payments.Payment, error) {
+	refundID, err := s.provider.Refund(payment.ProviderPaymentID, amount, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refund payment %s: %w", payment.PaymentID, err)
+	}
+	if _, err := s.paymentRepository.TransitionPayment(payment, payments.StatusAuthorized, payments.StatusRefunded, reason, refundID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Payment %s refunded (%s): %s", payment.PaymentID, refundID, reason)
+	return payment, nil
+}
+
+// paymentInProgress returns a payment of the booking that was started but has
+// not yet been captured or failed, if there is one
+func paymentInProgress(paymentRepository *repository.PaymentRepository, bookingID string) (*payments.Payment, error) {
+	bookingPayments, err := paymentRepository.GetPaymentsByBooking(bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, payment := range bookingPayments {
+		if payment.Status == payments.StatusPending || payment.Status == payments.StatusAuthorized {
+			return payment, nil
+		}
+	}
+	return nil, nil
+}
+
+// otherCapturedPayment returns a payment for the same booking and purpose
+// other than the given one that has been captured, if there is one
+func otherCapturedPayment(paymentRepository *repository.PaymentRepository, payment *payments.Payment) (*payments.Payment, error) {
+	bookingPayments, err := paymentRepository.GetPaymentsByBooking(payment.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range bookingPayments {
+		if other.PaymentID != payment.PaymentID && other.Purpose == payment.Purpose && other.Status == payments.StatusCaptured {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
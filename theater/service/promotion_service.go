@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gsmayya/theater/promotions"
+	"github.com/gsmayya/theater/repository"
+)
+
+// PromotionService manages promo codes. Codes are redeemed by
+// BookingService.CreateBooking when a booking is reserved.
+type PromotionService struct {
+	promotionRepository *repository.PromotionRepository
+	showService         *ShowService
+}
+
+// NewPromotionService creates a new promotion service
+func NewPromotionService() *PromotionService {
+	return &PromotionService{
+		promotionRepository: repository.NewPromotionRepository(),
+		showService:         NewShowService(),
+	}
+}
+
+// CreatePromotion validates and saves a new promo code
+func (s *PromotionService) CreatePromotion(promotion *promotions.Promotion) (*promotions.Promotion, error) {
+	if err := s.validatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepository.CreatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully created promotion: %s", promotion.Code)
+	return promotion, nil
+}
+
+// GetPromotion retrieves a promo code with its redemption count
+func (s *PromotionService) GetPromotion(code string) (*promotions.Promotion, error) {
+	code = promotions.NormalizeCode(code)
+	if code == "" {
+		return nil, fmt.Errorf("promo code cannot be empty")
+	}
+
+	return s.promotionRepository.GetPromotion(code)
+}
+
+// GetAllPromotions lists all promo codes
+func (s *PromotionService) GetAllPromotions() ([]*promotions.Promotion, error) {
+	promotionList, err := s.promotionRepository.GetAllPromotions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotions: %w", err)
+	}
+
+	return promotionList, nil
+}
+
+// UpdatePromotion replaces the rules of an existing promo code
+func (s *PromotionService) UpdatePromotion(code string, promotion *promotions.Promotion) (*promotions.Promotion, error) {
+	code = promotions.NormalizeCode(code)
+	if promotion.Code != "" && promotion.Code != code {
+		return nil, fmt.Errorf("invalid promotion: code cannot be changed from %s to %s", code, promotion.Code)
+	}
+	promotion.Code = code
+
+	if err := s.validatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepository.UpdatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully updated promotion: %s", code)
+	return s.promotionRepository.GetPromotion(code)
+}
+
+// DeletePromotion deletes a promo code that has never been used
+func (s *PromotionService) DeletePromotion(code string) error {
+	code = promotions.NormalizeCode(code)
+	if code == "" {
+		return fmt.Errorf("promo code cannot be empty")
+	}
+
+	if err := s.promotionRepository.DeletePromotion(code); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully deleted promotion: %s", code)
+	return nil
+}
+
+// GetRedemptionReport summarizes redemptions of every promo code, or of one
+// code when given
+func (s *PromotionService) GetRedemptionReport(code string) ([]*promotions.RedemptionSummary, error) {
+	return s.promotionRepository.GetRedemptionReport(promotions.NormalizeCode(code))
+}
+
+// validatePromotion checks a promotion's rules and that a show-scoped code
+// refers to an existing show
+func (s *PromotionService) validatePromotion(promotion *promotions.Promotion) error {
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+
+	if promotion.ShowID != nil {
+		if _, err := s.showService.GetShow(promotion.ShowID.String()); err != nil {
+			return fmt.Errorf("show not found: %s", promotion.ShowID.String())
+		}
+	}
+	return nil
+}
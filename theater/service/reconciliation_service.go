@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/repository"
+)
+
+// ReconciliationService detects and repairs drift between the shows.booked_tickets
+// counter, the show cache, the Redis availability index and the bookings table.
+// The bookings table is always treated as the source of truth.
+type ReconciliationService struct {
+	bookingRepository *repository.BookingRepository
+	showService       *ShowService
+}
+
+// ShowDrift describes how far a show's derived counters are from its bookings
+type ShowDrift struct {
+	ShowID                  uuid.UUID `json:"show_id"`
+	ShowName                string    `json:"show_name"`
+	TotalTickets            int32     `json:"total_tickets"`
+	TicketsSold             int32     `json:"tickets_sold"`
+	RecordedBookedTickets   int32     `json:"recorded_booked_tickets"`
+	CachedBookedTickets     *int32    `json:"cached_booked_tickets,omitempty"`
+	IndexedAvailableTickets *int32    `json:"indexed_available_tickets,omitempty"`
+	DatabaseDrift           int32     `json:"database_drift"`
+	CacheDrift              bool      `json:"cache_drift"`
+	IndexDrift              bool      `json:"index_drift"`
+	Repaired                bool      `json:"repaired"`
+	RepairError             string    `json:"repair_error,omitempty"`
+}
+
+// HasDrift reports whether any counter disagrees with the bookings table
+func (d *ShowDrift) HasDrift() bool {
+	return d.DatabaseDrift != 0 || d.CacheDrift || d.IndexDrift
+}
+
+// ReconciliationReport summarizes a reconciliation run
+type ReconciliationReport struct {
+	StartedAt      time.Time    `json:"started_at"`
+	CompletedAt    time.Time    `json:"completed_at"`
+	Repair         bool         `json:"repair"`
+	ShowsChecked   int          `json:"shows_checked"`
+	ShowsWithDrift int          `json:"shows_with_drift"`
+	ShowsRepaired  int          `json:"shows_repaired"`
+	Drifts         []*ShowDrift `json:"drifts"`
+}
+
+// NewReconciliationService creates a new reconciliation service
+func NewReconciliationService() *ReconciliationService {
+	return &ReconciliationService{
+		bookingRepository: repository.NewBookingRepository(),
+		showService:       NewShowService(),
+	}
+}
+
+// Reconcile recomputes sold counts from bookings and reports every show whose
+// counters have drifted. When repair is true the drifted counters are rewritten.
+// A non-nil showID restricts the run to a single show.
+func (s *ReconciliationService) Reconcile(showID *uuid.UUID, repair bool) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{
+		StartedAt: time.Now(),
+		Repair:    repair,
+		Drifts:    []*ShowDrift{},
+	}
+
+	records, err := s.bookingRepository.GetShowInventories(showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load show inventories: %w", err)
+	}
+
+	for _, record := range records {
+		report.ShowsChecked++
+
+		drift := s.checkShow(record)
+		if !drift.HasDrift() {
+			continue
+		}
+		report.ShowsWithDrift++
+
+		if repair {
+			if err := s.repairShow(drift); err != nil {
+				drift.RepairError = err.Error()
+				log.Printf("Warning: Failed to repair show %s: %v", drift.ShowID.String(), err)
+			} else {
+				drift.Repaired = true
+				report.ShowsRepaired++
+			}
+		}
+
+		report.Drifts = append(report.Drifts, drift)
+	}
+
+	if showID != nil && report.ShowsChecked == 0 {
+		return nil, fmt.Errorf("show not found: %s", showID.String())
+	}
+
+	report.CompletedAt = time.Now()
+	return report, nil
+}
+
+// Run reconciles on a fixed interval until the context is cancelled
+func (s *ReconciliationService) Run(ctx context.Context, interval time.Duration, repair bool) {
+	log.Printf("Booking reconciliation running every %s (repair: %t)", interval, repair)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Booking reconciliation stopped")
+			return
+		case <-ticker.C:
+			report, err := s.Reconcile(nil, repair)
+			if err != nil {
+				log.Printf("Warning: Booking reconciliation failed: %v", err)
+				continue
+			}
+			if report.ShowsWithDrift > 0 {
+				log.Printf("Booking reconciliation: %d of %d shows drifted, %d repaired",
+					report.ShowsWithDrift, report.ShowsChecked, report.ShowsRepaired)
+			}
+		}
+	}
+}
+
+// checkShow compares the MySQL counter, the show cache and the Redis
+// availability index against the sold count derived from bookings
+func (s *ReconciliationService) checkShow(record *repository.ShowInventoryRecord) *ShowDrift {
+	drift := &ShowDrift{
+		ShowID:                record.ShowID,
+		ShowName:              record.ShowName,
+		TotalTickets:          record.TotalTickets,
+		TicketsSold:           record.TicketsSold,
+		RecordedBookedTickets: record.RecordedBookedTickets,
+		DatabaseDrift:         record.RecordedBookedTickets - record.TicketsSold,
+	}
+
+	showID := record.ShowID.String()
+
+	// A cache miss is not drift; the next read repopulates from MySQL
+	if cached, err := s.showService.GetCachedShow(showID); err == nil {
+		drift.CachedBookedTickets = &cached.Booked_Tickets
+		drift.CacheDrift = cached.Booked_Tickets != record.TicketsSold ||
+			cached.Total_Tickets != record.TotalTickets
+	}
+
+	expectedAvailable := record.TotalTickets - record.TicketsSold
+	if indexed, err := s.showService.GetIndexedAvailability(showID); err == nil {
+		drift.IndexedAvailableTickets = &indexed
+		drift.IndexDrift = indexed != expectedAvailable
+	} else {
+		// Shows missing from the index never appear in availability searches
+		drift.IndexDrift = true
+	}
+
+	return drift
+}
+
+// repairShow rewrites the MySQL counter from bookings, then rebuilds the
+// cache and Redis indexes from the corrected row
+func (s *ReconciliationService) repairShow(drift *ShowDrift) error {
+	if drift.DatabaseDrift != 0 {
+		inventory, err := s.bookingRepository.RecountShow(drift.ShowID)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reconciled show %s booked_tickets: %d -> %d",
+			drift.ShowID.String(), drift.RecordedBookedTickets, inventory.BookedTickets)
+	}
+
+	return s.showService.SyncAvailability(drift.ShowID.String())
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/utils"
+)
+
+// maxRefundListSize caps how many ledger entries one listing returns
+const maxRefundListSize = 500
+
+// RefundService cancels bookings under their show's cancellation policy and
+// keeps the refund ledger: what is owed back for each cancellation and
+// whether the payment processor has returned it
+type RefundService struct {
+	refundRepository  *repository.RefundRepository
+	paymentRepository *repository.PaymentRepository
+	bookingService    *BookingService
+	provider          payments.PaymentProvider // nil when payments are disabled
+	currency          string
+}
+
+// NewRefundService creates a new refund service
+func NewRefundService() *RefundService {
+	return &RefundService{
+		refundRepository:  repository.NewRefundRepository(),
+		paymentRepository: repository.NewPaymentRepository(),
+		bookingService:    NewBookingService(),
+		provider:          paymentProvider(),
+		currency:          strings.ToUpper(utils.GetEnvOrDefault("PAYMENT_CURRENCY", "USD")),
+	}
+}
+
+// Cancellation is the outcome of cancelling a booking
+type Cancellation struct {
+	BookingID string                    `json:"booking_id"`
+	Status    string                    `json:"status"` // cancelled, or refunded once the processor has returned the money
+	Quote     *shows.RefundQuote        `json:"quote"`
+	Refund    *payments.Refund          `json:"refund,omitempty"`
+	Policy    *shows.CancellationPolicy `json:"policy,omitempty"`
+}
+
+// QuoteCancellation works out what cancelling a booking now would refund,
+// without cancelling it
+func (s *RefundService) QuoteCancellation(idOrReference string) (*Cancellation, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, show, _, err := s.quote(booking)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cancellation{
+		BookingID: booking.BookingID,
+		Status:    booking.Status,
+		Quote:     quote,
+		Policy:    show.CancellationPolicy,
+	}, nil
+}
+
+// CancelBooking cancels a booking and refunds what its show's cancellation
+// policy allows. Pending bookings have paid nothing and can always be
+// cancelled. Cancellations the policy forbids are refused unless override is
+// set, which refunds the policy's partial percentage; refundPercent, for
+// overrides only, sets the share refunded instead. Bookings paid through the
+// payment provider are refunded straight away and end up refunded; others
+// get a pending ledger entry for staff to settle.
+func (s *RefundService) CancelBooking(idOrReference, changedBy, reason string, override bool, refundPercent *int32) (*Cancellation, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, show, payment, err := s.quote(booking)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case refundPercent != nil:
+		if !override {
+			return nil, fmt.Errorf("invalid refund percent: only an admin override can set the refund")
+		}
+		if *refundPercent < 0 || *refundPercent > 100 {
+			return nil, fmt.Errorf("invalid refund percent: %d must be between 0 and 100", *refundPercent)
+		}
+		quote = shows.NewRefundQuote(shows.RuleAdminOverride, quote.Paid, *refundPercent)
+	case !quote.Allowed && !override:
+		return nil, fmt.Errorf("cancellation not allowed: show %s starts at %s; %s",
+			show.Show_Id.String(), show.ShowDate.Format(time.RFC3339), show.CancellationPolicy.Describe())
+	case !quote.Allowed:
+		quote = shows.NewRefundQuote(shows.RuleAdminOverride, quote.Paid, show.CancellationPolicy.PartialRefundPercent)
+	}
+
+	cancellation := &Cancellation{
+		BookingID: booking.BookingID,
+		Status:    bookings.StatusCancelled,
+		Quote:     quote,
+		Policy:    show.CancellationPolicy,
+	}
+	if quote.Amount <= 0 {
+		if err := s.bookingService.CancelBooking(booking.BookingID, changedBy, reason); err != nil {
+			return nil, err
+		}
+		return cancellation, nil
+	}
+
+	refund, err := payments.NewRefund(booking.BookingID, payment, quote.Amount, s.currency, quote.Rule, reason, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bookingService.CancelBookingWithRefund(booking.BookingID, changedBy, reason, refund); err != nil {
+		return nil, err
+	}
+	cancellation.Refund = refund
+
+	if s.process(refund, payment) {
+		s.markBookingRefunded(refund, changedBy)
+		cancellation.Status = bookings.StatusRefunded
+	}
+
+	log.Printf("Cancelled booking %s under %s; refund %s of %d is %s",
+		booking.BookingID, quote.Rule, refund.RefundID, refund.Amount, refund.Status)
+	return cancellation, nil
+}
+
+// ProcessRefund settles a refund that is not yet done. Refunds of provider
+// payments are sent to the processor again; others are marked as returned by
+// staff, who give the reference of the transfer they made.
+func (s *RefundService) ProcessRefund(refundID, reference, changedBy string) (*payments.Refund, error) {
+	refund, err := s.refundRepository.GetRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.IsSettled() {
+		return nil, fmt.Errorf("invalid refund status: refund %s has already succeeded", refundID)
+	}
+
+	if refund.PaymentID == "" {
+		if reference == "" {
+			return nil, fmt.Errorf("invalid refund reference: refund %s was not paid through a provider; give the reference of the manual refund", refundID)
+		}
+		if err := s.refundRepository.UpdateRefundStatus(refund, payments.RefundSucceeded, reference, ""); err != nil {
+			return nil, err
+		}
+	} else {
+		payment, err := s.paymentRepository.GetPayment(refund.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		if !s.process(refund, payment) {
+			return refund, fmt.Errorf("refund failed: %s", refund.FailureReason)
+		}
+	}
+
+	// Amendment and exchange refunds do not cancel the booking
+	if refund.Rule != shows.RuleAmendment && refund.Rule != shows.RuleExchange {
+		s.markBookingRefunded(refund, changedBy)
+	}
+	return refund, nil
+}
+
+// AmendmentRefund returns the refund owed for the tickets an amendment
+// dropped from a confirmed booking, or nil when nothing is owed. The caller
+// records it in the amendment's transaction and then sends it with
+// SendRefund; the booking stays confirmed either way.
+func (s *RefundService) AmendmentRefund(amendment *bookings.Amendment) (*payments.Refund, error) {
+	reason := fmt.Sprintf("amendment v%d: %d -> %d tickets", amendment.Version, amendment.FromTickets, amendment.ToTickets)
+	return s.differenceRefund(amendment.BookingID, -amendment.AmountDelta, shows.RuleAmendment, reason, amendment.ChangedBy)
+}
+
+// ExchangeRefund returns the refund owed for the price difference of tickets
+// a confirmed booking exchanged to a cheaper performance, against the payment
+// of the booking they came from, or nil when nothing is owed. As with
+// amendments, the caller records it with the exchange.
+func (s *RefundService) ExchangeRefund(exchange *bookings.Exchange) (*payments.Refund, error) {
+	reason := fmt.Sprintf("exchange %s: %d tickets to %s", exchange.ExchangeID, exchange.NumberOfTickets, exchange.TargetBookingID)
+	return s.differenceRefund(exchange.SourceBookingID, -exchange.PriceDifference, shows.RuleExchange, reason, exchange.ChangedBy)
+}
+
+// SendRefund sends a refund recorded with a booking change to the processor
+// when the booking was paid through it; otherwise it is left pending for staff
+func (s *RefundService) SendRefund(refund *payments.Refund) {
+	if refund.PaymentID == "" {
+		return
+	}
+
+	payment, err := s.paymentRepository.GetPayment(refund.PaymentID)
+	if err != nil {
+		log.Printf("Warning: refund %s left pending, its payment could not be loaded: %v", refund.RefundID, err)
+		return
+	}
+	s.process(refund, payment)
+}
+
+// differenceRefund creates a refund of part of a booking's payment that does
+// not cancel the booking
+func (s *RefundService) differenceRefund(bookingID string, amount int32, rule, reason, changedBy string) (*payments.Refund, error) {
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	payment, _, err := s.capturedPayment(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	return payments.NewRefund(bookingID, payment, amount, s.currency, rule, reason, changedBy)
+}
+
+// GetBookingRefunds returns the ledger entries of a booking, looked up by booking ID or reference
+func (s *RefundService) GetBookingRefunds(idOrReference string) ([]*payments.Refund, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+	return s.refundRepository.GetRefundsByBooking(booking.BookingID)
+}
+
+// ListRefunds returns ledger entries in a status, e.g. failed ones to retry
+func (s *RefundService) ListRefunds(status string) ([]*payments.Refund, error) {
+	switch status {
+	case payments.RefundPending, payments.RefundSucceeded, payments.RefundFailed:
+	default:
+		return nil, fmt.Errorf("invalid refund status: %q", status)
+	}
+	return s.refundRepository.GetRefundsByStatus(status, maxRefundListSize)
+}
+
+// quote works out the refund for cancelling a booking now, along with its
+// show and the captured payment it was paid with, if any
+func (s *RefundService) quote(booking *bookings.Booking) (*shows.RefundQuote, *shows.ShowData, *payments.Payment, error) {
+	show, err := s.bookingService.showService.GetShow(booking.ShowID.String())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	// Nothing has been paid for a pending booking, so there is nothing to keep
+	if booking.Status == bookings.StatusPending {
+		return shows.NewRefundQuote(shows.RuleFullRefund, 0, 100), show, nil, nil
+	}
+
+	payment, unrefunded, err := s.capturedPayment(booking.BookingID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Bookings confirmed by hand were paid for outside the provider. A
+	// balance left by an amendment or exchange has not been paid, and paid
+	// bookings can have had part of their payments refunded by amendments.
+	paid := booking.TotalAmount - booking.BalanceDue
+	if payment != nil && unrefunded < paid {
+		paid = unrefunded
+	}
+
+	return show.CancellationPolicy.Quote(paid, show.ShowDate, time.Now()), show, payment, nil
+}
+
+// capturedPayment returns the captured payment of a booking with the most
+// left to refund, if any, and how much of all its captured payments, the
+// booking's own and any balance payments, the ledger does not yet owe back
+func (s *RefundService) capturedPayment(bookingID string) (*payments.Payment, int32, error) {
+	bookingPayments, err := s.paymentRepository.GetPaymentsByBooking(bookingID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	left := make(map[string]int32)
+	for _, candidate := range bookingPayments {
+		if candidate.Status == payments.StatusCaptured {
+			left[candidate.PaymentID] = candidate.Amount
+		}
+	}
+	if len(left) == 0 {
+		return nil, 0, nil
+	}
+
+	refunds, err := s.refundRepository.GetRefundsByBooking(bookingID)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, refund := range refunds {
+		if _, ok := left[refund.PaymentID]; ok {
+			left[refund.PaymentID] -= refund.Amount
+		}
+	}
+
+	var payment *payments.Payment
+	unrefunded := int32(0)
+	for _, candidate := range bookingPayments {
+		remaining, ok := left[candidate.PaymentID]
+		if !ok {
+			continue
+		}
+		unrefunded += remaining
+		if payment == nil || remaining > left[payment.PaymentID] {
+			payment = candidate
+		}
+	}
+	return payment, unrefunded, nil
+}
+
+// process sends a refund to the payment processor when the booking was paid
+// through it and records the outcome. It reports whether the money has been
+// returned.
+func (s *RefundService) process(refund *payments.Refund, payment *payments.Payment) bool {
+	if payment == nil || s.provider == nil || payment.Provider != s.provider.Name() {
+		// Left pending for staff to refund outside the provider
+		return false
+	}
+
+	processorRefundID, err := s.provider.Refund(payment.ProviderPaymentID, refund.Amount, refund.Reason)
+	if err != nil {
+		log.Printf("Error refunding %s for booking %s: %v", refund.RefundID, refund.BookingID, err)
+		if updateErr := s.refundRepository.UpdateRefundStatus(refund, payments.RefundFailed, "", err.Error()); updateErr != nil {
+			log.Printf("Warning: Failed to record refund %s failure: %v", refund.RefundID, updateErr)
+		}
+		return false
+	}
+
+	if err := s.refundRepository.UpdateRefundStatus(refund, payments.RefundSucceeded, processorRefundID, ""); err != nil {
+		log.Printf("Warning: refund %s went through as %s but could not be recorded: %v", refund.RefundID, processorRefundID, err)
+		return false
+	}
+
+	if refund.Amount == payment.Amount {
+		if _, err := s.paymentRepository.TransitionPayment(payment, payments.StatusCaptured, payments.StatusRefunded, "", processorRefundID); err != nil {
+			log.Printf("Warning: Failed to mark payment %s refunded: %v", payment.PaymentID, err)
+		}
+	}
+	return true
+}
+
+// markBookingRefunded moves a cancelled booking to refunded once its money is back
+func (s *RefundService) markBookingRefunded(refund *payments.Refund, changedBy string) {
+	reason := fmt.Sprintf("refund %s of %d %s", refund.RefundID, refund.Amount, refund.Currency)
+	err := s.bookingService.UpdateBookingStatus(refund.BookingID, bookings.StatusRefunded, changedBy, reason)
+	if err != nil {
+		log.Printf("Warning: Failed to mark booking %s refunded: %v", refund.BookingID, err)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/payments"
+	"github.com/gsmayya/theater/shows"
+)
+
+func TestCancelBookingRefundsUnderPolicy(t *testing.T) {
+	requireDatabase(t)
+
+	showService := NewShowService()
+	refundService := NewRefundService()
+	bookingService := refundService.bookingService
+
+	// Test shows start in 30 days, inside the 60 day full refund window
+	show := createTestShow(t, "Refund Test", 1000, 4)
+
+	policy := &shows.CancellationPolicy{FullRefundHours: 60 * 24, PartialRefundPercent: 50}
+	if err := showService.UpdateCancellationPolicy(show.Show_Id.String(), policy); err != nil {
+		t.Fatalf("Failed to set cancellation policy: %v", err)
+	}
+
+	// Inside the partial window half of the 2000 paid comes back. Without a
+	// payment provider it is left pending for staff to return.
+	partial := createConfirmedBooking(t, bookingService, show.Show_Id, "partial@example.com", 2)
+	cancellation, err := refundService.CancelBooking(partial.BookingID, "test", "", false, nil)
+	if err != nil {
+		t.Fatalf("CancelBooking() error: %v", err)
+	}
+	if cancellation.Status != bookings.StatusCancelled || cancellation.Quote.Rule != shows.RulePartialRefund {
+		t.Errorf("Expected a partial refund cancellation, got %s under %s", cancellation.Status, cancellation.Quote.Rule)
+	}
+	refund := cancellation.Refund
+	if refund == nil || refund.Amount != 1000 || refund.Status != payments.RefundPending || refund.PaymentID != "" {
+		t.Fatalf("Expected a pending refund of 1000 with no payment, got %+v", refund)
+	}
+
+	ledger, err := refundService.GetBookingRefunds(partial.BookingID)
+	if err != nil || len(ledger) != 1 || ledger[0].RefundID != refund.RefundID {
+		t.Errorf("Expected the refund in the ledger, got %v (%v)", ledger, err)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 0 {
+		t.Errorf("Expected the cancelled tickets to be released, got %d booked", booked)
+	}
+
+	// Past the cutoff only an admin override can cancel
+	policy.CutoffHours = 40 * 24
+	if err := showService.UpdateCancellationPolicy(show.Show_Id.String(), policy); err != nil {
+		t.Fatalf("Failed to set cancellation policy: %v", err)
+	}
+	closed := createConfirmedBooking(t, bookingService, show.Show_Id, "closed@example.com", 2)
+
+	_, err = refundService.CancelBooking(closed.BookingID, "test", "", false, nil)
+	if err == nil || !strings.Contains(err.Error(), "cancellation not allowed") {
+		t.Fatalf("Expected the cancellation to be refused, got %v", err)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 2 {
+		t.Errorf("Expected the refused cancellation to keep its tickets, got %d booked", booked)
+	}
+
+	cancellation, err = refundService.CancelBooking(closed.BookingID, "admin", "", true, nil)
+	if err != nil {
+		t.Fatalf("CancelBooking() with override error: %v", err)
+	}
+	if cancellation.Quote.Rule != shows.RuleAdminOverride || cancellation.Refund == nil || cancellation.Refund.Amount != 1000 {
+		t.Errorf("Expected the override to refund the partial 1000, got %+v", cancellation.Quote)
+	}
+	if booked := readBookedTickets(t, show.Show_Id); booked != 0 {
+		t.Errorf("Expected the cancelled tickets to be released, got %d booked", booked)
+	}
+}
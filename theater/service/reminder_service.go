@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/notifications"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/shows"
+	"github.com/gsmayya/theater/utils"
+)
+
+const (
+	// defaultReminderLeadTimes is used when REMINDER_LEAD_TIMES is unset or malformed
+	defaultReminderLeadTimes = "48h,2h"
+	// reminderBatchSize caps how many bookings one scan of a lead time takes at a time
+	reminderBatchSize = 200
+	// maxReminderPreviewSize caps how many upcoming reminders a preview lists
+	maxReminderPreviewSize = 500
+)
+
+// ReminderService queues pre-show reminders for confirmed bookings. Each
+// lead time in REMINDER_LEAD_TIMES sends one reminder per booking, recorded
+// in the notifications table under the lead time's source ID, so a restart
+// or a second instance never queues the same reminder twice. Reminders are
+// sent by the notification sender like any other notification.
+type ReminderService struct {
+	notificationService    *NotificationService
+	notificationRepository *repository.NotificationRepository
+	bookingService         *BookingService
+	showService            *ShowService
+	leadTimes              []time.Duration // Longest first
+}
+
+// NewReminderService creates a new reminder service
+func NewReminderService() *ReminderService {
+	leadTimes, _ := notifications.ParseLeadTimes(defaultReminderLeadTimes)
+	if value := utils.GetEnvOrDefault("REMINDER_LEAD_TIMES", ""); value != "" {
+		parsed, err := notifications.ParseLeadTimes(value)
+		if err != nil {
+			log.Printf("Warning: %v for REMINDER_LEAD_TIMES, using default %s", err, defaultReminderLeadTimes)
+		} else {
+			leadTimes = parsed
+		}
+	}
+
+	notificationService := NewNotificationService()
+	return &ReminderService{
+		notificationService:    notificationService,
+		notificationRepository: notificationService.notificationRepository,
+		bookingService:         notificationService.bookingService,
+		showService:            notificationService.showService,
+		leadTimes:              leadTimes,
+	}
+}
+
+// LeadTimes returns the configured lead times, longest first, e.g. ["48h", "2h"]
+func (s *ReminderService) LeadTimes() []string {
+	leadTimes := make([]string, len(s.leadTimes))
+	for i, lead := range s.leadTimes {
+		leadTimes[i] = notifications.FormatLeadTime(lead)
+	}
+	return leadTimes
+}
+
+// window returns the show start times the i-th lead time covers at now:
+// shows starting no later than the lead time from now, but after the next
+// shorter lead time. A booking made inside a shorter window therefore only
+// gets the reminders still ahead of its show, not every longer one at once.
+func (s *ReminderService) window(i int, now time.Time) (time.Time, time.Time) {
+	from := now
+	if i+1 < len(s.leadTimes) {
+		from = now.Add(s.leadTimes[i+1])
+	}
+	return from, now.Add(s.leadTimes[i])
+}
+
+// QueueReminders queues a reminder for every confirmed booking whose show has
+// entered one of the lead time windows and has not had that reminder yet. It
+// returns the number queued.
+func (s *ReminderService) QueueReminders() (int, error) {
+	now := time.Now()
+	channels := s.notificationService.channels()
+	showCache := make(map[string]*shows.ShowData)
+
+	queued := 0
+	for i, lead := range s.leadTimes {
+		from, to := s.window(i, now)
+		source := notifications.ReminderSource(lead)
+
+		for {
+			candidates, err := s.notificationRepository.GetReminderCandidates(source, channels, from, to, reminderBatchSize)
+			if err != nil {
+				return queued, err
+			}
+
+			var reminders []*notifications.Notification
+			for _, candidate := range candidates {
+				booking, show, ok := s.loadCandidate(candidate, showCache)
+				if !ok {
+					continue
+				}
+
+				data := notificationData(booking, show)
+				data.StartsIn = notifications.StartsIn(show.ShowDate.Sub(now))
+				reminder, err := s.notificationService.renderNotification(booking, notifications.KindShowReminder, source, data)
+				if err != nil {
+					return queued, err
+				}
+				if reminder != nil {
+					reminders = append(reminders, reminder)
+				}
+			}
+
+			if len(reminders) > 0 {
+				if err := s.notificationRepository.EnqueueNotifications(reminders); err != nil {
+					return queued, err
+				}
+				queued += len(reminders)
+				log.Printf("Queued %d %s show reminders", len(reminders), notifications.FormatLeadTime(lead))
+			}
+
+			// Skipped candidates stay unrecorded, so stop once a batch makes no progress
+			if len(candidates) < reminderBatchSize || len(reminders) == 0 {
+				break
+			}
+		}
+	}
+
+	return queued, nil
+}
+
+// RunScheduler queues due reminders on a fixed interval until the context is cancelled
+func (s *ReminderService) RunScheduler(ctx context.Context, interval time.Duration) {
+	log.Printf("Reminder scheduler running every %s for lead times %v", interval, s.LeadTimes())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Reminder scheduler stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.QueueReminders(); err != nil {
+				log.Printf("Warning: Reminder scheduler failed: %v", err)
+			}
+		}
+	}
+}
+
+// UpcomingReminders previews the reminders falling due within the given
+// duration from now, soonest first. Overdue reminders the scheduler has not
+// queued yet are included with a due_at in the past.
+func (s *ReminderService) UpcomingReminders(within time.Duration) ([]*notifications.Reminder, error) {
+	if within < 0 {
+		return nil, fmt.Errorf("invalid preview window: %s must not be negative", within)
+	}
+
+	now := time.Now()
+	channels := s.notificationService.channels()
+	showCache := make(map[string]*shows.ShowData)
+
+	upcoming := make([]*notifications.Reminder, 0)
+	for i, lead := range s.leadTimes {
+		from, to := s.window(i, now)
+		candidates, err := s.notificationRepository.GetReminderCandidates(
+			notifications.ReminderSource(lead), channels, from, to.Add(within), maxReminderPreviewSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range candidates {
+			booking, show, ok := s.loadCandidate(candidate, showCache)
+			if !ok {
+				continue
+			}
+			upcoming = append(upcoming, &notifications.Reminder{
+				BookingID: booking.BookingID,
+				Reference: booking.Reference,
+				ShowID:    show.Show_Id.String(),
+				ShowName:  show.ShowName,
+				ShowDate:  show.ShowDate,
+				LeadTime:  notifications.FormatLeadTime(lead),
+				DueAt:     show.ShowDate.Add(-lead),
+				Channel:   booking.ContactType,
+				Recipient: booking.ContactValue,
+			})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].DueAt.Before(upcoming[j].DueAt) })
+	if len(upcoming) > maxReminderPreviewSize {
+		upcoming = upcoming[:maxReminderPreviewSize]
+	}
+	return upcoming, nil
+}
+
+// loadCandidate loads a reminder candidate's booking and show, caching shows
+// across a scan. Bookings that are gone or no longer confirmed are skipped.
+func (s *ReminderService) loadCandidate(candidate *repository.ReminderCandidate, showCache map[string]*shows.ShowData) (*bookings.Booking, *shows.ShowData, bool) {
+	booking, err := s.bookingService.GetBooking(candidate.BookingID)
+	if err != nil {
+		log.Printf("Warning: Skipping reminder for booking %s: %v", candidate.BookingID, err)
+		return nil, nil, false
+	}
+	if booking.Status != bookings.StatusConfirmed {
+		return nil, nil, false
+	}
+
+	showID := booking.ShowID.String()
+	show, ok := showCache[showID]
+	if !ok {
+		show, err = s.showService.GetShow(showID)
+		if err != nil {
+			log.Printf("Warning: Skipping reminder for booking %s: %v", candidate.BookingID, err)
+			return nil, nil, false
+		}
+		showCache[showID] = show
+	}
+	return booking, show, true
+}
@@ -3,17 +3,22 @@ package service
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/gsmayya/theater/events"
 	"github.com/gsmayya/theater/repository"
 	"github.com/gsmayya/theater/shows"
 	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/venues"
 )
 
 // ShowService provides business logic for theater shows with optimized caching
 type ShowService struct {
-	repository *repository.ShowRepository
-	redisIndex *utils.IndexedRedisClient
+	repository        *repository.ShowRepository
+	venueRepository   *repository.VenueRepository
+	bookingRepository *repository.BookingRepository
+	redisIndex        *utils.IndexedRedisClient
 }
 
 // SearchRequest represents a search query with all possible filters
@@ -40,16 +45,24 @@ type SearchResponse struct {
 // NewShowService creates a new show service with optimized caching
 func NewShowService() *ShowService {
 	return &ShowService{
-		repository: repository.NewShowRepository(),
-		redisIndex: utils.NewIndexedRedisClient(),
+		repository:        repository.NewShowRepository(),
+		venueRepository:   repository.NewVenueRepository(),
+		bookingRepository: repository.NewBookingRepository(),
+		redisIndex:        utils.NewIndexedRedisClient(),
 	}
 }
 
-// CreateShow creates a new show with full indexing
-func (s *ShowService) CreateShow(show_name, details, show_location string, price, totalTickets int32) (*shows.ShowData, error) {
+// CreateShow creates a new show with full indexing. A holdMinutes of 0 uses
+// the system default hold for pending bookings.
+func (s *ShowService) CreateShow(show_name, details, show_location string, price, totalTickets, holdMinutes int32) (*shows.ShowData, error) {
+	if holdMinutes < 0 {
+		return nil, fmt.Errorf("invalid hold minutes: %d. Must not be negative", holdMinutes)
+	}
+
 	// Create show data
 	show := &shows.ShowData{}
 	show.NewShow(show_name, details, price, totalTickets, show_location)
+	show.HoldMinutes = holdMinutes
 
 	// Save to database
 	if err := s.repository.CreateShow(show); err != nil {
@@ -57,17 +70,7 @@ func (s *ShowService) CreateShow(show_name, details, show_location string, price
 	}
 
 	// Index in Redis for fast searches
-	indexData := utils.ShowIndexData{
-		ID:               show.Show_Id.String(),
-		ShowName:         show.ShowName,
-		ShowLocation:     show.ShowLocation,
-		Price:            show.Price,
-		AvailableTickets: show.Total_Tickets - show.Booked_Tickets,
-		TotalTickets:     show.Total_Tickets,
-		Details:          show.Details,
-	}
-
-	if err := s.redisIndex.IndexShow(indexData); err != nil {
+	if err := s.redisIndex.IndexShow(indexDataFor(show)); err != nil {
 		log.Printf("Warning: Failed to index show in Redis: %v", err)
 		// Don't fail the entire operation for indexing errors
 	}
@@ -191,48 +194,236 @@ func (s *ShowService) UpdateShow(show *shows.ShowData) error {
 	}
 
 	// Update Redis indexes
-	indexData := utils.ShowIndexData{
-		ID:               show.Show_Id.String(),
-		ShowName:         show.ShowName,
-		ShowLocation:     show.ShowLocation,
-		Price:            show.Price,
-		AvailableTickets: show.Total_Tickets - show.Booked_Tickets,
-		TotalTickets:     show.Total_Tickets,
-		Details:          show.Details,
-	}
-
-	if err := s.redisIndex.IndexShow(indexData); err != nil {
+	if err := s.redisIndex.IndexShow(indexDataFor(show)); err != nil {
 		log.Printf("Warning: Failed to update show in Redis index: %v", err)
 	}
 
 	return nil
 }
 
-// UpdateTicketAvailability efficiently updates just the availability information
-func (s *ShowService) UpdateTicketAvailability(showID string, bookedTickets int32) error {
-	// Get current show data
+// RecountAvailability rewrites a show's booked_tickets from its active
+// bookings under the show lock, then brings the cache and Redis indexes in
+// line with the corrected row
+func (s *ShowService) RecountAvailability(showID string) (*repository.ShowInventory, error) {
+	parsedShowID, err := uuid.Parse(showID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show ID format: %s", showID)
+	}
+
+	inventory, err := s.bookingRepository.RecountShow(parsedShowID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SyncAvailability(showID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Recounted show %s: %d of %d tickets booked", showID, inventory.BookedTickets, inventory.TotalTickets)
+	return inventory, nil
+}
+
+// UpdateHoldMinutes sets how long pending bookings for the show keep their
+// tickets. It applies to bookings made from now on; a value of 0 restores the
+// system default.
+func (s *ShowService) UpdateHoldMinutes(showID string, holdMinutes int32) error {
+	if holdMinutes < 0 {
+		return fmt.Errorf("invalid hold minutes: %d. Must not be negative", holdMinutes)
+	}
+
+	if _, err := uuid.Parse(showID); err != nil {
+		return fmt.Errorf("invalid show ID format: %s", showID)
+	}
+
+	return s.repository.UpdateHoldMinutes(showID, holdMinutes)
+}
+
+// UpdateContactVerification sets whether bookings for the show must carry a
+// token from a verified contact. It applies to bookings made from now on.
+func (s *ShowService) UpdateContactVerification(showID string, required bool) error {
+	if _, err := uuid.Parse(showID); err != nil {
+		return fmt.Errorf("invalid show ID format: %s", showID)
+	}
+
+	return s.repository.UpdateContactVerification(showID, required)
+}
+
+// GetTicketTypes returns a show's ticket types in display order
+func (s *ShowService) GetTicketTypes(showID string) ([]*shows.TicketType, error) {
+	show, err := s.GetShow(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	if show.TicketTypes == nil {
+		return []*shows.TicketType{}, nil
+	}
+	return show.TicketTypes, nil
+}
+
+// UpdateTicketTypes replaces a show's ticket types. An empty list turns the
+// show back into a single-price show. Prices apply to bookings made from now on.
+func (s *ShowService) UpdateTicketTypes(showID string, ticketTypes []*shows.TicketType) error {
+	parsedShowID, err := uuid.Parse(showID)
+	if err != nil {
+		return fmt.Errorf("invalid show ID format: %s", showID)
+	}
+
 	show, err := s.GetShow(showID)
 	if err != nil {
 		return err
 	}
 
-	// Update booked tickets
-	show.Booked_Tickets = bookedTickets
+	if err := shows.ValidateTicketTypes(ticketTypes, show.Total_Tickets); err != nil {
+		return err
+	}
 
-	// Update in database
-	if err := s.repository.UpdateShow(show); err != nil {
+	if err := s.repository.ReplaceTicketTypes(parsedShowID, ticketTypes); err != nil {
+		return err
+	}
+
+	log.Printf("Updated ticket types for show %s (%d types)", showID, len(ticketTypes))
+	return nil
+}
+
+// UpdateCancellationPolicy sets a show's cancellation policy. A nil policy
+// removes it, making the show's bookings cancellable with a full refund.
+func (s *ShowService) UpdateCancellationPolicy(showID string, policy *shows.CancellationPolicy) error {
+	parsedShowID, err := uuid.Parse(showID)
+	if err != nil {
+		return fmt.Errorf("invalid show ID format: %s", showID)
+	}
+
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repository.ReplaceCancellationPolicy(parsedShowID, policy); err != nil {
 		return err
 	}
 
-	// Update Redis availability index efficiently
-	availableTickets := show.Total_Tickets - show.Booked_Tickets
-	if err := s.redisIndex.UpdateShowAvailability(showID, availableTickets); err != nil {
+	log.Printf("Updated cancellation policy for show %s", showID)
+	return nil
+}
+
+// SyncAvailability brings the show cache and Redis indexes in line with the
+// booked_tickets value that has already been committed to the database
+func (s *ShowService) SyncAvailability(showID string) error {
+	// Drop the cached show rather than patch it, so the reload below reads
+	// the committed row even when concurrent bookings finish out of order
+	s.repository.InvalidateShowCache(showID)
+
+	show, err := s.repository.GetShow(showID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.redisIndex.IndexShow(indexDataFor(show)); err != nil {
 		log.Printf("Warning: Failed to update availability in Redis: %v", err)
+		return err
+	}
+
+	if show.VenueID != nil {
+		if _, err := s.syncSeatMap(showID); err != nil {
+			log.Printf("Warning: Failed to sync seat map: %v", err)
+			return err
+		}
 	}
 
 	return nil
 }
 
+// maintainIndexes brings the Redis show indexes in line with MySQL for the
+// show an event is about. Shows deleted since the event need no indexing.
+func (s *ShowService) maintainIndexes(event *events.Event) error {
+	showID, err := event.ShowID()
+	if err != nil {
+		return err
+	}
+
+	err = s.SyncAvailability(showID.String())
+	if err != nil && strings.Contains(err.Error(), "show not found") {
+		return nil
+	}
+	return err
+}
+
+// GetSeatMap returns seat-level availability for a reserved-seating show.
+// The seat map is served from Redis and rebuilt from MySQL on a miss.
+func (s *ShowService) GetSeatMap(showID string) (*venues.SeatMap, error) {
+	show, err := s.GetShow(showID)
+	if err != nil {
+		return nil, err
+	}
+	if show.VenueID == nil {
+		return nil, fmt.Errorf("seat map not found: show %s does not have reserved seating", showID)
+	}
+
+	seats, err := s.getIndexedSeats(showID)
+	if err != nil || len(seats) == 0 {
+		seats, err = s.syncSeatMap(showID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return venues.NewSeatMap(show.Show_Id, *show.VenueID, seats), nil
+}
+
+// getIndexedSeats reads a show's seats from the Redis seat map
+func (s *ShowService) getIndexedSeats(showID string) ([]*venues.ShowSeat, error) {
+	indexed, err := s.redisIndex.GetShowSeats(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	seats := make([]*venues.ShowSeat, 0, len(indexed))
+	for _, data := range indexed {
+		seat := &venues.ShowSeat{}
+		if err := seat.FromJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to decode seat: %w", err)
+		}
+		seats = append(seats, seat)
+	}
+	return seats, nil
+}
+
+// syncSeatMap mirrors a show's committed seat inventory into Redis
+func (s *ShowService) syncSeatMap(showID string) ([]*venues.ShowSeat, error) {
+	seats, err := s.venueRepository.GetShowSeats(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(seats))
+	for _, seat := range seats {
+		data, err := seat.ToJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode seat: %w", err)
+		}
+		fields[seat.SeatID] = data
+	}
+
+	if err := s.redisIndex.SetShowSeats(showID, fields); err != nil {
+		// MySQL is authoritative; the next read retries the mirror
+		log.Printf("Warning: Failed to mirror seat map for show %s: %v", showID, err)
+	}
+
+	return seats, nil
+}
+
+// GetCachedShow returns the cached copy of a show, if any
+func (s *ShowService) GetCachedShow(showID string) (*shows.ShowData, error) {
+	return s.repository.GetCachedShow(showID)
+}
+
+// GetIndexedAvailability returns the available tickets recorded in the Redis availability index
+func (s *ShowService) GetIndexedAvailability(showID string) (int32, error) {
+	return s.redisIndex.GetShowAvailability(showID)
+}
+
 // DeleteShow removes a show from all systems
 func (s *ShowService) DeleteShow(showID string) error {
 	// Get show data for cleanup
@@ -382,6 +573,19 @@ func (s *ShowService) convertFromIndexed(indexed utils.ShowIndexData) *shows.Sho
 	}
 }
 
+// indexDataFor builds the Redis index representation of a show
+func indexDataFor(show *shows.ShowData) utils.ShowIndexData {
+	return utils.ShowIndexData{
+		ID:               show.Show_Id.String(),
+		ShowName:         show.ShowName,
+		ShowLocation:     show.ShowLocation,
+		Price:            show.Price,
+		AvailableTickets: show.Total_Tickets - show.Booked_Tickets,
+		TotalTickets:     show.Total_Tickets,
+		Details:          show.Details,
+	}
+}
+
 // Utility function to find intersection of two string slices
 func intersectSlices(a, b []string) []string {
 	set := make(map[string]bool)
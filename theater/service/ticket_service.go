@@ -0,0 +1,371 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/tickets"
+	"github.com/gsmayya/theater/utils"
+)
+
+// TicketService provides business logic for the individual tickets of a
+// booking and the signed tokens they are scanned with
+type TicketService struct {
+	ticketRepository *repository.TicketRepository
+	bookingService   *BookingService
+	keyRing          *tickets.KeyRing
+	entryWindow      tickets.EntryWindow
+}
+
+// NewTicketService creates a new ticket service
+func NewTicketService() *TicketService {
+	return &TicketService{
+		ticketRepository: repository.NewTicketRepository(),
+		bookingService:   NewBookingService(),
+		keyRing:          ticketKeyRing(),
+		entryWindow:      ticketEntryWindow(),
+	}
+}
+
+// ticketEntryWindow reads when doors open and entry closes around a show's start
+func ticketEntryWindow() tickets.EntryWindow {
+	return tickets.EntryWindow{
+		OpensBefore: utils.GetDurationOrDefault("TICKET_ENTRY_OPENS_BEFORE", 3*time.Hour),
+		ClosesAfter: utils.GetDurationOrDefault("TICKET_ENTRY_CLOSES_AFTER", 3*time.Hour),
+	}
+}
+
+var (
+	keyRingOnce   sync.Once
+	sharedKeyRing *tickets.KeyRing
+)
+
+// ticketKeyRing loads the ticket signing keys from TICKET_SIGNING_KEYS once
+// per process. Without usable keys it falls back to a random key, which is
+// fine for local development but invalidates every token on restart and
+// cannot be shared between instances.
+func ticketKeyRing() *tickets.KeyRing {
+	keyRingOnce.Do(func() {
+		spec := utils.GetEnvOrDefault("TICKET_SIGNING_KEYS", "")
+		if spec != "" {
+			ring, err := tickets.NewKeyRing(spec)
+			if err == nil {
+				sharedKeyRing = ring
+				return
+			}
+			log.Printf("Warning: invalid TICKET_SIGNING_KEYS: %v", err)
+		}
+
+		log.Printf("Warning: TICKET_SIGNING_KEYS not configured, signing ticket tokens with a temporary key")
+		ring, err := tickets.NewEphemeralKeyRing()
+		if err != nil {
+			log.Fatalf("Failed to create ticket signing key: %v", err)
+		}
+		sharedKeyRing = ring
+	})
+	return sharedKeyRing
+}
+
+// GetBookingTickets returns the tickets of a booking, looked up by booking ID or reference
+func (s *TicketService) GetBookingTickets(idOrReference string) ([]*tickets.Ticket, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingTickets, err := s.ticketRepository.GetTicketsByBooking(booking.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+	return bookingTickets, nil
+}
+
+// GetTicket retrieves a ticket by ID
+func (s *TicketService) GetTicket(ticketID string) (*tickets.Ticket, error) {
+	if ticketID == "" {
+		return nil, fmt.Errorf("ticket ID cannot be empty")
+	}
+
+	return s.ticketRepository.GetTicket(ticketID)
+}
+
+// SetAttendeeName names the attendee of an active ticket. An empty name
+// clears it.
+func (s *TicketService) SetAttendeeName(ticketID, attendeeName string) (*tickets.Ticket, error) {
+	if ticketID == "" {
+		return nil, fmt.Errorf("ticket ID cannot be empty")
+	}
+
+	name, err := tickets.NormalizeAttendeeName(attendeeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ticketRepository.UpdateAttendeeName(ticketID, name)
+}
+
+// VoidTicket voids a single ticket of a booking and offers its place to the
+// show's waitlist
+func (s *TicketService) VoidTicket(ticketID, reason string) (*tickets.Ticket, error) {
+	if ticketID == "" {
+		return nil, fmt.Errorf("ticket ID cannot be empty")
+	}
+
+	ticket, inventory, err := s.ticketRepository.VoidTicket(ticketID, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to void ticket: %w", err)
+	}
+
+	s.bookingService.syncShowAvailability(inventory)
+	s.bookingService.offerReleasedTickets(inventory)
+
+	log.Printf("Successfully voided ticket %s on booking %s", ticketID, ticket.BookingID)
+	return ticket, nil
+}
+
+// IssueTokens signs a token for each active ticket of a confirmed booking,
+// or only for ticketID when it is given
+func (s *TicketService) IssueTokens(idOrReference, ticketID string) ([]*tickets.IssuedToken, error) {
+	booking, err := s.bookingService.FindBooking(idOrReference)
+	if err != nil {
+		return nil, err
+	}
+
+	switch booking.Status {
+	case bookings.StatusConfirmed, bookings.StatusCheckedIn, bookings.StatusNoShow:
+	case bookings.StatusPending:
+		return nil, fmt.Errorf("invalid ticket status: booking %s is pending; tickets are issued once it is confirmed", booking.BookingID)
+	default:
+		return nil, fmt.Errorf("invalid ticket status: booking %s is %s", booking.BookingID, booking.Status)
+	}
+
+	bookingTickets, err := s.ticketRepository.GetTicketsByBooking(booking.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+
+	now := time.Now()
+	issued := make([]*tickets.IssuedToken, 0, len(bookingTickets))
+	for _, ticket := range bookingTickets {
+		if !ticket.IsActive() || (ticketID != "" && ticket.TicketID != ticketID) {
+			continue
+		}
+
+		token, err := s.keyRing.Sign(tickets.NewClaims(ticket, now))
+		if err != nil {
+			return nil, err
+		}
+		issued = append(issued, &tickets.IssuedToken{
+			TicketID:     ticket.TicketID,
+			TicketType:   ticket.TicketType,
+			SeatID:       ticket.SeatID,
+			AttendeeName: ticket.AttendeeName,
+			Token:        token,
+		})
+	}
+
+	if ticketID != "" && len(issued) == 0 {
+		return nil, fmt.Errorf("ticket not found: no active ticket %s on booking %s", ticketID, booking.BookingID)
+	}
+	return issued, nil
+}
+
+// VerifyToken checks a scanned ticket token: its signature, the ticket and
+// booking on file and the show's entry window. A non-empty expectedShowID
+// denies tickets for other shows. Denials are returned as a verification
+// with a reason; an error means the check itself could not be made.
+func (s *TicketService) VerifyToken(token, expectedShowID string) (*tickets.Verification, error) {
+	var expected *uuid.UUID
+	if expectedShowID != "" {
+		showID, err := uuid.Parse(expectedShowID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid show_id format: %w", err)
+		}
+		expected = &showID
+	}
+
+	claims, err := s.keyRing.Verify(token)
+	if err != nil {
+		return tickets.Deny(tickets.TokenErrorReason(err)), nil
+	}
+
+	ticket, err := s.ticketRepository.GetTicket(claims.TicketID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return tickets.Deny(tickets.ReasonTicketNotFound), nil
+		}
+		return nil, err
+	}
+
+	booking, err := s.bookingService.GetBooking(ticket.BookingID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return tickets.Deny(tickets.ReasonTicketNotFound), nil
+		}
+		return nil, err
+	}
+
+	show, err := s.bookingService.showService.GetShow(ticket.ShowID.String())
+	if err != nil {
+		return nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	reason := tickets.CheckAdmission(claims, ticket, booking.Status, booking.BalanceDue, show.ShowDate, s.entryWindow, expected, time.Now())
+	return &tickets.Verification{
+		Admit:    reason == tickets.ReasonAdmitted,
+		Reason:   reason,
+		Ticket:   ticket,
+		ShowDate: &show.ShowDate,
+	}, nil
+}
+
+// Scan checks in what a scanner read at a gate. A token or ticket ID checks in
+// one ticket; a booking ID or reference checks in every ticket of the booking
+// that has not been voided or released, each with its own generated scan ID.
+// Denied and duplicate scans are results, not errors, and every scan is
+// recorded in the scan log.
+func (s *TicketService) Scan(req *tickets.ScanRequest) ([]*tickets.ScanResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if req.BookingID == "" {
+		result, err := s.scan(req, tickets.SourceLive, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return []*tickets.ScanResult{result}, nil
+	}
+
+	booking, err := s.bookingService.FindBooking(req.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingTickets, err := s.ticketRepository.GetTicketsByBooking(booking.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+
+	results := make([]*tickets.ScanResult, 0, len(bookingTickets))
+	for _, ticket := range bookingTickets {
+		if !ticket.IsActive() && ticket.Status != tickets.StatusCheckedIn {
+			continue
+		}
+
+		ticketReq := *req
+		ticketReq.ScanID = ""
+		ticketReq.BookingID = ""
+		ticketReq.TicketID = ticket.TicketID
+		result, err := s.scan(&ticketReq, tickets.SourceLive, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("ticket not found: booking %s has no tickets to check in", booking.BookingID)
+	}
+	return results, nil
+}
+
+// SyncBatch checks in the scans an offline scanner buffered. Scans are
+// applied in the order they were made, so when two gates scanned the same
+// ticket the earlier scan checks it in and the later one is a duplicate,
+// even if it was synced first. Scan IDs that were already synced return
+// their stored outcome, so a scanner can resend a batch that failed partway.
+func (s *TicketService) SyncBatch(batch *tickets.BatchRequest) ([]*tickets.ScanResult, error) {
+	now := time.Now()
+	if err := batch.Validate(now); err != nil {
+		return nil, err
+	}
+	batch.SortByScanTime()
+
+	results := make([]*tickets.ScanResult, 0, len(batch.Scans))
+	for _, req := range batch.Scans {
+		result, err := s.scan(req, tickets.SourceBatch, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync scan %s: %w", req.ScanID, err)
+		}
+		results = append(results, result)
+	}
+
+	log.Printf("Synced %d scans from gate %s", len(results), batch.GateID)
+	return results, nil
+}
+
+// GetAttendance returns the live attendance count of a show
+func (s *TicketService) GetAttendance(showID uuid.UUID) (*tickets.Attendance, error) {
+	return s.bookingService.GetShowAttendance(showID)
+}
+
+// scan checks in a single ticket named by a token or ticket ID
+func (s *TicketService) scan(req *tickets.ScanRequest, source string, now time.Time) (*tickets.ScanResult, error) {
+	var expected *uuid.UUID
+	if req.ShowID != "" {
+		showID, err := uuid.Parse(req.ShowID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid show_id format: %w", err)
+		}
+		expected = &showID
+	}
+
+	scan, err := tickets.NewScan(req, source, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims *tickets.TokenClaims
+	if req.Token != "" {
+		claims, err = s.keyRing.Verify(req.Token)
+		if err != nil {
+			// A forged or garbled token names no ticket we can trust
+			scan.Deny(tickets.TokenErrorReason(err))
+			if expected != nil {
+				scan.ShowID = *expected
+			}
+			return s.ticketRepository.RecordScan(scan)
+		}
+		scan.TicketID = claims.TicketID
+	}
+
+	// The show date is looked up before the check-in transaction; a ticket
+	// never moves between shows
+	var showDate time.Time
+	if ticket, err := s.ticketRepository.GetTicket(scan.TicketID); err == nil {
+		show, err := s.bookingService.showService.GetShow(ticket.ShowID.String())
+		if err != nil {
+			return nil, fmt.Errorf("show not found: %w", err)
+		}
+		showDate = show.ShowDate
+	} else if !strings.Contains(err.Error(), "not found") {
+		return nil, err
+	}
+
+	admit := func(ticket *tickets.Ticket, booking *bookings.Booking) string {
+		ticketClaims := claims
+		if ticketClaims == nil {
+			// Staff typed the ticket ID in, so it vouches for itself
+			ticketClaims = tickets.NewClaims(ticket, now)
+		}
+		return tickets.CheckAdmission(ticketClaims, ticket, booking.Status, booking.BalanceDue, showDate, s.entryWindow, expected, scan.ScannedAt)
+	}
+
+	result, err := s.ticketRepository.CheckIn(scan, admit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check in ticket: %w", err)
+	}
+
+	if result.Superseded != nil {
+		log.Printf("Scan %s at gate %s superseded scan %s of ticket %s",
+			scan.ScanID, scan.GateID, result.Superseded.ScanID, scan.TicketID)
+	}
+	return result, nil
+}
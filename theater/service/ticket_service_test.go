@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/tickets"
+)
+
+func TestCheckInDetectsDuplicateScans(t *testing.T) {
+	requireDatabase(t)
+
+	ticketService := NewTicketService()
+	bookingService := ticketService.bookingService
+
+	// Test shows start in 30 days; open the doors now
+	ticketService.entryWindow = tickets.EntryWindow{OpensBefore: 31 * 24 * time.Hour, ClosesAfter: time.Hour}
+
+	show := createTestShow(t, "Check-in Test", 100, 2)
+	booking := createConfirmedBooking(t, bookingService, show.Show_Id, "fan@example.com", 2)
+
+	bookingTickets, err := ticketService.GetBookingTickets(booking.BookingID)
+	if err != nil || len(bookingTickets) != 2 {
+		t.Fatalf("Expected 2 tickets, got %d (%v)", len(bookingTickets), err)
+	}
+	first, second := bookingTickets[0].TicketID, bookingTickets[1].TicketID
+
+	// A live scan checks the ticket in; scanning it again at another gate is a duplicate
+	checkedIn, err := ticketService.Scan(&tickets.ScanRequest{TicketID: first, GateID: "north"})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if checkedIn[0].Scan.Result != tickets.ScanCheckedIn {
+		t.Fatalf("Expected the first scan to check in, got %s (%s)", checkedIn[0].Scan.Result, checkedIn[0].Scan.Reason)
+	}
+
+	duplicate, err := ticketService.Scan(&tickets.ScanRequest{TicketID: first, GateID: "south"})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if duplicate[0].Scan.Result != tickets.ScanDuplicate || duplicate[0].FirstScan == nil || duplicate[0].FirstScan.GateID != "north" {
+		t.Errorf("Expected a duplicate pointing at the north gate scan, got %+v", duplicate[0])
+	}
+
+	// An offline batch is applied in scan order, whatever order it was sent in
+	now := time.Now()
+	earlier, later := now.Add(-2*time.Minute), now.Add(-time.Minute)
+	earlierID, laterID := uuid.New().String(), uuid.New().String()
+	newBatch := func() *tickets.BatchRequest {
+		return &tickets.BatchRequest{GateID: "east", Scans: []*tickets.ScanRequest{
+			{ScanID: laterID, TicketID: second, ScannedAt: &later},
+			{ScanID: earlierID, TicketID: second, ScannedAt: &earlier},
+		}}
+	}
+
+	synced, err := ticketService.SyncBatch(newBatch())
+	if err != nil {
+		t.Fatalf("SyncBatch() error: %v", err)
+	}
+	if len(synced) != 2 || synced[0].Scan.ScanID != earlierID || synced[0].Scan.Result != tickets.ScanCheckedIn ||
+		synced[1].Scan.ScanID != laterID || synced[1].Scan.Result != tickets.ScanDuplicate {
+		t.Fatalf("Expected the earlier scan to check in and the later one to be a duplicate, got %+v", synced)
+	}
+
+	// Resending the batch returns the stored outcomes
+	resent, err := ticketService.SyncBatch(newBatch())
+	if err != nil {
+		t.Fatalf("SyncBatch() error: %v", err)
+	}
+	for _, result := range resent {
+		if !result.Replayed {
+			t.Errorf("Expected scan %s to be replayed, got %+v", result.Scan.ScanID, result)
+		}
+	}
+
+	attendance, err := ticketService.GetAttendance(show.Show_Id)
+	if err != nil {
+		t.Fatalf("GetAttendance() error: %v", err)
+	}
+	if attendance.Expected != 2 || attendance.CheckedIn != 2 || attendance.ByGate["north"] != 1 || attendance.ByGate["east"] != 1 {
+		t.Errorf("Expected 2 of 2 checked in through north and east, got %+v", attendance)
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/users"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/verification"
+)
+
+// loginAttemptWindow is the window AUTH_MAX_LOGIN_ATTEMPTS counts failed sign-ins over
+const loginAttemptWindow = 15 * time.Minute
+
+// UserService manages customer accounts and their sessions. Signing in
+// starts a session in Redis and returns a short-lived access token with a
+// refresh token. Refresh tokens are single use: each refresh replaces it,
+// and presenting a replaced one revokes the whole session, since it means
+// the token was copied. Logging out deletes the session, which revokes its
+// access token too.
+type UserService struct {
+	userRepository    *repository.UserRepository
+	sessionRepository *repository.SessionRepository
+	bookingRepository *repository.BookingRepository
+	signer            *users.Signer
+	accessTTL         time.Duration
+	refreshTTL        time.Duration
+	maxLoginAttempts  int64
+}
+
+// NewUserService creates a new user service
+func NewUserService() *UserService {
+	maxLoginAttempts := utils.GetInt32OrDefault("AUTH_MAX_LOGIN_ATTEMPTS", 10)
+	if maxLoginAttempts < 1 {
+		maxLoginAttempts = 1
+	}
+
+	return &UserService{
+		userRepository:    repository.NewUserRepository(),
+		sessionRepository: repository.NewSessionRepository(),
+		bookingRepository: repository.NewBookingRepository(),
+		signer:            accountTokenSigner(),
+		accessTTL:         utils.GetDurationOrDefault("AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+		refreshTTL:        utils.GetDurationOrDefault("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		maxLoginAttempts:  int64(maxLoginAttempts),
+	}
+}
+
+var (
+	accountSignerOnce   sync.Once
+	sharedAccountSigner *users.Signer
+)
+
+// accountTokenSigner loads the key account tokens are signed with from
+// AUTH_JWT_SECRET once per process. Without one it falls back to a random
+// key, which signs everyone out on restart and cannot be shared between
+// instances.
+func accountTokenSigner() *users.Signer {
+	accountSignerOnce.Do(func() {
+		if secret := utils.GetEnvOrDefault("AUTH_JWT_SECRET", ""); secret != "" {
+			signer, err := users.NewSigner([]byte(secret))
+			if err == nil {
+				sharedAccountSigner = signer
+				return
+			}
+			log.Printf("Warning: invalid AUTH_JWT_SECRET: %v", err)
+		}
+
+		log.Printf("Warning: AUTH_JWT_SECRET not configured, signing account tokens with a temporary key")
+		secret := make([]byte, users.MinSigningKeyLength)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to create account signing key: %v", err)
+		}
+		sharedAccountSigner, _ = users.NewSigner(secret)
+	})
+	return sharedAccountSigner
+}
+
+// Register creates a local account and signs it in
+func (s *UserService) Register(req *users.RegisterRequest) (*users.Session, error) {
+	user, err := users.NewUser(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepository.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	return &users.Session{User: user, Tokens: tokens}, nil
+}
+
+// Login checks an email address and password and starts a session. After
+// AUTH_MAX_LOGIN_ATTEMPTS failures in 15 minutes the account cannot sign in
+// until the window has passed.
+func (s *UserService) Login(req *users.LoginRequest) (*users.Session, error) {
+	email, err := users.NormalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	account := verification.Fingerprint("account:" + email)
+
+	failures, err := s.sessionRepository.GetFailedLogins(account)
+	if err != nil {
+		return nil, err
+	}
+	if failures >= s.maxLoginAttempts {
+		return nil, fmt.Errorf("too many sign-in attempts: try again later")
+	}
+
+	user, err := s.userRepository.GetUserByEmail(email)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, err
+	}
+	if user == nil {
+		users.ComparePasswordWithoutAccount(req.Password)
+	}
+	if user == nil || !user.PasswordMatches(req.Password) {
+		if _, err := s.sessionRepository.CountFailedLogin(account, loginAttemptWindow); err != nil {
+			log.Printf("Warning: Failed to count sign-in attempt: %v", err)
+		}
+		return nil, fmt.Errorf("invalid credentials: email or password is incorrect")
+	}
+
+	if err := s.sessionRepository.ClearFailedLogins(account); err != nil {
+		log.Printf("Warning: Failed to clear sign-in attempts: %v", err)
+	}
+
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	return &users.Session{User: user, Tokens: tokens}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair in the same session
+func (s *UserService) Refresh(refreshToken string) (*users.TokenPair, error) {
+	now := time.Now()
+	claims, err := s.signer.Parse(refreshToken, users.TokenRefresh, now)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := users.NewClaims(claims.Subject, claims.SessionID, users.TokenRefresh, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessionRepository.RotateRefreshToken(claims.SessionID, claims.Subject, claims.TokenID, next.TokenID, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		userID, err := s.sessionRepository.GetSessionUser(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if userID == "" {
+			return nil, fmt.Errorf("invalid token: the session has ended")
+		}
+
+		// The session moved on to a newer refresh token, so this one was used
+		// before: whoever holds the other copy must not keep the session
+		log.Printf("Warning: refresh token reused for session of user %s, revoking the session", claims.Subject)
+		if err := s.sessionRepository.DeleteSession(claims.SessionID, claims.Subject); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid token: refresh token was already used, the session has been revoked")
+	}
+
+	return s.issueTokens(next, now)
+}
+
+// Logout ends the session an access token belongs to, or with all set every
+// session of the user. It returns how many sessions were ended.
+func (s *UserService) Logout(user *users.User, sessionID string, all bool) (int, error) {
+	if all {
+		return s.sessionRepository.DeleteUserSessions(user.UserID)
+	}
+	if err := s.sessionRepository.DeleteSession(sessionID, user.UserID); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Authenticate checks an access token and returns the account it was issued
+// to with the token's session. Tokens of ended sessions are refused even
+// before they expire.
+func (s *UserService) Authenticate(accessToken string) (*users.User, string, error) {
+	claims, err := s.signer.Parse(accessToken, users.TokenAccess, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	userID, err := s.sessionRepository.GetSessionUser(claims.SessionID)
+	if err != nil {
+		return nil, "", err
+	}
+	if userID != claims.Subject {
+		return nil, "", fmt.Errorf("invalid token: the session has ended")
+	}
+
+	user, err := s.userRepository.GetUser(claims.Subject)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, "", fmt.Errorf("invalid token: the account no longer exists")
+		}
+		return nil, "", err
+	}
+	return user, claims.SessionID, nil
+}
+
+// GetUserBookings returns the bookings made while signed in to an account
+func (s *UserService) GetUserBookings(userID string) ([]*bookings.Booking, error) {
+	return s.bookingRepository.GetBookingsByUser(userID)
+}
+
+// startSession creates a session for a user and issues its first tokens
+func (s *UserService) startSession(user *users.User) (*users.TokenPair, error) {
+	sessionID, err := users.NewRandomID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refresh, err := users.NewClaims(user.UserID, sessionID, users.TokenRefresh, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepository.CreateSession(sessionID, user.UserID, refresh.TokenID, s.refreshTTL); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Session started for user %s", user.UserID)
+	return s.issueTokens(refresh, now)
+}
+
+// issueTokens signs a refresh token and an access token for its session
+func (s *UserService) issueTokens(refresh *users.Claims, now time.Time) (*users.TokenPair, error) {
+	access, err := users.NewClaims(refresh.Subject, refresh.SessionID, users.TokenAccess, now, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.signer.Sign(access)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.signer.Sign(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &users.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.accessTTL.Seconds()),
+		RefreshExpiresIn: int64(s.refreshTTL.Seconds()),
+	}, nil
+}
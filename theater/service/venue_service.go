@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/venues"
+)
+
+// VenueService provides business logic for venue layouts and seat inventory
+type VenueService struct {
+	venueRepository *repository.VenueRepository
+	showService     *ShowService
+}
+
+// NewVenueService creates a new venue service
+func NewVenueService() *VenueService {
+	return &VenueService{
+		venueRepository: repository.NewVenueRepository(),
+		showService:     NewShowService(),
+	}
+}
+
+// CreateVenue saves a venue and its seat layout
+func (s *VenueService) CreateVenue(venue *venues.Venue) (*venues.Venue, error) {
+	if venue == nil || venue.SeatCount == 0 {
+		return nil, fmt.Errorf("venue must have at least one seat")
+	}
+
+	if err := s.venueRepository.CreateVenue(venue); err != nil {
+		return nil, fmt.Errorf("failed to create venue: %w", err)
+	}
+
+	log.Printf("Successfully created venue: %s (ID: %s)", venue.Name, venue.VenueID.String())
+	return venue, nil
+}
+
+// GetVenue retrieves a venue with its seat layout
+func (s *VenueService) GetVenue(venueID string) (*venues.Venue, error) {
+	if _, err := uuid.Parse(venueID); err != nil {
+		return nil, fmt.Errorf("invalid venue ID format: %s", venueID)
+	}
+
+	return s.venueRepository.GetVenue(venueID)
+}
+
+// GetAllVenues lists all venues
+func (s *VenueService) GetAllVenues() ([]*venues.Venue, error) {
+	venueList, err := s.venueRepository.GetAllVenues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get venues: %w", err)
+	}
+
+	return venueList, nil
+}
+
+// AssignVenue gives a show the venue's seat layout, making it a reserved-seating
+// show whose capacity is the venue's seat count
+func (s *VenueService) AssignVenue(showID, venueID string) error {
+	parsedShowID, err := uuid.Parse(showID)
+	if err != nil {
+		return fmt.Errorf("invalid show ID format: %s", showID)
+	}
+	parsedVenueID, err := uuid.Parse(venueID)
+	if err != nil {
+		return fmt.Errorf("invalid venue ID format: %s", venueID)
+	}
+
+	if _, err := s.venueRepository.AssignVenue(parsedShowID, parsedVenueID); err != nil {
+		return err
+	}
+
+	// Refresh the show cache, availability index and seat map from the committed rows
+	if err := s.showService.SyncAvailability(showID); err != nil {
+		log.Printf("Warning: Failed to sync show %s after venue assignment: %v", showID, err)
+	}
+
+	return nil
+}
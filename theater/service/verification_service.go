@@ -0,0 +1,232 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/notifications"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/verification"
+)
+
+// verificationRequestWindow is the window OTP_MAX_SENDS counts codes over
+const verificationRequestWindow = time.Hour
+
+// VerificationService proves that customers own the contact they book with.
+// A one-time code is sent to the contact through the notification transport
+// for its channel, and checking it returns a short-lived token that bookings
+// for shows requiring verified contacts must carry. Codes are stored hashed
+// in Redis with a limited number of attempts.
+type VerificationService struct {
+	verificationRepository *repository.VerificationRepository
+	showService            *ShowService
+	notifiers              map[string]notifications.Notifier
+	secret                 []byte
+	codeTTL                time.Duration
+	tokenTTL               time.Duration
+	resendCooldown         time.Duration
+	maxAttempts            int64
+	maxSends               int64
+}
+
+// NewVerificationService creates a new verification service
+func NewVerificationService() *VerificationService {
+	maxAttempts := utils.GetInt32OrDefault("OTP_MAX_ATTEMPTS", 5)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	maxSends := utils.GetInt32OrDefault("OTP_MAX_SENDS", 5)
+	if maxSends < 1 {
+		maxSends = 1
+	}
+
+	return &VerificationService{
+		verificationRepository: repository.NewVerificationRepository(),
+		showService:            NewShowService(),
+		notifiers:              notificationTransports(),
+		secret:                 verificationSecret(),
+		codeTTL:                utils.GetDurationOrDefault("OTP_CODE_TTL", 10*time.Minute),
+		tokenTTL:               utils.GetDurationOrDefault("OTP_TOKEN_TTL", 30*time.Minute),
+		resendCooldown:         utils.GetDurationOrDefault("OTP_RESEND_COOLDOWN", time.Minute),
+		maxAttempts:            int64(maxAttempts),
+		maxSends:               int64(maxSends),
+	}
+}
+
+var (
+	verificationSecretOnce sync.Once
+	sharedVerificationKey  []byte
+)
+
+// verificationSecret loads the key codes are hashed with from OTP_SECRET
+// once per process. Every instance must share it, or codes sent by one
+// cannot be checked by another.
+func verificationSecret() []byte {
+	verificationSecretOnce.Do(func() {
+		if secret := utils.GetEnvOrDefault("OTP_SECRET", ""); len(secret) >= 16 {
+			sharedVerificationKey = []byte(secret)
+			return
+		} else if secret != "" {
+			log.Printf("Warning: OTP_SECRET is shorter than 16 characters, ignoring it")
+		}
+
+		log.Printf("Warning: OTP_SECRET not configured, hashing verification codes with a temporary secret")
+		sharedVerificationKey = make([]byte, 32)
+		if _, err := rand.Read(sharedVerificationKey); err != nil {
+			log.Fatalf("Failed to create verification secret: %v", err)
+		}
+	})
+	return sharedVerificationKey
+}
+
+// RequestCode sends a new one-time code to a contact, replacing any code
+// sent before. Contacts get one code per OTP_RESEND_COOLDOWN and at most
+// OTP_MAX_SENDS an hour.
+func (s *VerificationService) RequestCode(contactType, contactValue string) (*verification.Challenge, error) {
+	contactKey, err := verification.ContactKey(contactType, contactValue)
+	if err != nil {
+		return nil, err
+	}
+	// Send to the contact in the form bookings store it, e.g. an E.164 number
+	contactValue, _ = bookings.NormalizeContact(contactType, contactValue)
+	notifier, ok := s.notifiers[contactType]
+	if !ok {
+		return nil, fmt.Errorf("verification unavailable: no %s transport configured", contactType)
+	}
+	contact := verification.Fingerprint(contactKey)
+
+	started, remaining, err := s.verificationRepository.StartCooldown(contact, s.resendCooldown)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, fmt.Errorf("too many requests: a code was sent recently, try again in %s", remaining.Round(time.Second))
+	}
+
+	sends, err := s.verificationRepository.CountRequest(contact, verificationRequestWindow)
+	if err != nil {
+		return nil, err
+	}
+	if sends > s.maxSends {
+		return nil, fmt.Errorf("too many requests: %d codes were sent to this contact in the last hour", s.maxSends)
+	}
+
+	code, err := verification.GenerateCode()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verificationRepository.SaveCode(contact, verification.HashCode(s.secret, contactKey, code), s.codeTTL); err != nil {
+		return nil, err
+	}
+
+	if err := notifier.Send(verification.Message(contactType, contactValue, code, s.codeTTL)); err != nil {
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	now := time.Now()
+	log.Printf("Verification code sent to %s contact %s", contactType, verification.MaskContact(contactType, contactValue))
+	return &verification.Challenge{
+		ContactType: contactType,
+		SentTo:      verification.MaskContact(contactType, contactValue),
+		ExpiresAt:   now.Add(s.codeTTL),
+		ResendAfter: now.Add(s.resendCooldown),
+	}, nil
+}
+
+// VerifyCode checks a code sent to a contact. A correct code is used up and
+// exchanged for a token valid for OTP_TOKEN_TTL; after OTP_MAX_ATTEMPTS wrong
+// codes the pending code is discarded and a new one must be requested.
+func (s *VerificationService) VerifyCode(contactType, contactValue, code string) (*verification.Verification, error) {
+	contactKey, err := verification.ContactKey(contactType, contactValue)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, fmt.Errorf("verification code cannot be empty")
+	}
+	contact := verification.Fingerprint(contactKey)
+
+	codeHash, attempts, err := s.verificationRepository.RecordAttempt(contact)
+	if err != nil {
+		return nil, err
+	}
+	if codeHash == "" {
+		return nil, fmt.Errorf("invalid verification code: no code is pending for this contact, request a new one")
+	}
+	if attempts > s.maxAttempts {
+		if err := s.verificationRepository.DeleteCode(contact); err != nil {
+			log.Printf("Warning: Failed to discard verification code: %v", err)
+		}
+		return nil, fmt.Errorf("too many attempts: request a new code")
+	}
+	if !verification.CodeMatches(s.secret, contactKey, code, codeHash) {
+		return nil, fmt.Errorf("invalid verification code: %d attempts left", s.maxAttempts-attempts)
+	}
+
+	// Codes are single use
+	if err := s.verificationRepository.DeleteCode(contact); err != nil {
+		return nil, err
+	}
+
+	token, err := verification.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verificationRepository.SaveToken(verification.Fingerprint(token), contact, s.tokenTTL); err != nil {
+		return nil, err
+	}
+
+	return &verification.Verification{
+		Token:       token,
+		ContactType: contactType,
+		ExpiresAt:   time.Now().Add(s.tokenTTL),
+	}, nil
+}
+
+// CheckBookingContact makes sure a booking for a show that requires verified
+// contacts carries a token issued for the booking's contact. Bookings for
+// other shows pass without one.
+func (s *VerificationService) CheckBookingContact(booking *bookings.Booking, token string) error {
+	show, err := s.showService.GetShow(booking.ShowID.String())
+	if err != nil {
+		return err
+	}
+	if !show.RequireVerifiedContact {
+		return nil
+	}
+
+	if token == "" {
+		return fmt.Errorf("contact not verified: show %s requires a token in the %s header", show.Show_Id.String(), verification.TokenHeader)
+	}
+	return s.CheckContactToken(booking, token)
+}
+
+// CheckContactToken makes sure a verification token was issued for a
+// booking's contact, proving whoever presents it owns the booking
+func (s *VerificationService) CheckContactToken(booking *bookings.Booking, token string) error {
+	if token == "" {
+		return fmt.Errorf("contact not verified: a token is required in the %s header", verification.TokenHeader)
+	}
+
+	contact, err := s.verificationRepository.GetTokenContact(verification.Fingerprint(token))
+	if err != nil {
+		return err
+	}
+	if contact == "" {
+		return fmt.Errorf("contact not verified: the verification token is invalid or has expired")
+	}
+
+	contactKey, err := verification.ContactKey(booking.ContactType, booking.ContactValue)
+	if err != nil {
+		return err
+	}
+	if contact != verification.Fingerprint(contactKey) {
+		return fmt.Errorf("contact not verified: the verification token was issued for a different contact")
+	}
+	return nil
+}
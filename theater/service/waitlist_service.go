@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/waitlist"
+)
+
+// WaitlistService manages per-show waitlists and turns released tickets into
+// time-limited offers for the customers at the front of the queue
+type WaitlistService struct {
+	waitlistRepository *repository.WaitlistRepository
+	showService        *ShowService
+	offerDuration      time.Duration
+}
+
+// NewWaitlistService creates a new waitlist service
+func NewWaitlistService() *WaitlistService {
+	return &WaitlistService{
+		waitlistRepository: repository.NewWaitlistRepository(),
+		showService:        NewShowService(),
+		offerDuration:      utils.GetDurationOrDefault("WAITLIST_OFFER_DURATION", 30*time.Minute),
+	}
+}
+
+// JoinWaitlist queues a customer for a show that cannot currently satisfy their request
+func (s *WaitlistService) JoinWaitlist(entry *waitlist.Entry) (*waitlist.Entry, error) {
+	if _, err := s.showService.GetShow(entry.ShowID.String()); err != nil {
+		return nil, fmt.Errorf("show not found: %w", err)
+	}
+
+	if err := s.waitlistRepository.AddEntry(entry); err != nil {
+		return nil, fmt.Errorf("failed to join waitlist: %w", err)
+	}
+
+	// Reload to report the entry's queue position
+	joined, err := s.waitlistRepository.GetEntry(entry.EntryID)
+	if err != nil {
+		log.Printf("Warning: Failed to load waitlist entry %s: %v", entry.EntryID, err)
+		return entry, nil
+	}
+
+	return joined, nil
+}
+
+// GetEntry retrieves a waitlist entry
+func (s *WaitlistService) GetEntry(entryID string) (*waitlist.Entry, error) {
+	if entryID == "" {
+		return nil, fmt.Errorf("entry ID cannot be empty")
+	}
+
+	return s.waitlistRepository.GetEntry(entryID)
+}
+
+// GetWaitlist lists a show's waitlist in queue order, optionally filtered by status
+func (s *WaitlistService) GetWaitlist(showID uuid.UUID, status string) ([]*waitlist.Entry, error) {
+	if status != "" && !waitlist.IsValidStatus(status) {
+		return nil, fmt.Errorf("invalid status: %s. Valid statuses are: %s", status, strings.Join(waitlist.AllStatuses, ", "))
+	}
+
+	entries, err := s.waitlistRepository.GetEntriesByShow(showID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RemoveEntry takes a waiting customer off the waitlist (admin function)
+func (s *WaitlistService) RemoveEntry(entryID string) error {
+	if entryID == "" {
+		return fmt.Errorf("entry ID cannot be empty")
+	}
+
+	if err := s.waitlistRepository.RemoveEntry(entryID); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully removed waitlist entry: %s", entryID)
+	return nil
+}
+
+// PromoteWaitlist offers a show's available tickets to its waitlist and
+// returns the offers made
+func (s *WaitlistService) PromoteWaitlist(showID uuid.UUID) ([]*waitlist.Entry, error) {
+	offers, inventory, err := s.waitlistRepository.OfferReleasedTickets(showID, s.offerDuration, bookings.ActorWaitlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote waitlist: %w", err)
+	}
+
+	if inventory != nil {
+		if err := s.showService.SyncAvailability(showID.String()); err != nil {
+			log.Printf("Warning: Failed to sync availability for show %s: %v", showID.String(), err)
+		}
+	}
+
+	for _, offer := range offers {
+		log.Printf("Offered %d tickets for show %s to waitlist entry %s (booking %s, expires %s)",
+			offer.NumberOfTickets, showID.String(), offer.EntryID, offer.BookingID, offer.OfferExpiresAt.Format(time.RFC3339))
+	}
+
+	if offers == nil {
+		offers = []*waitlist.Entry{}
+	}
+	return offers, nil
+}
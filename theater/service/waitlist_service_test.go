@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/waitlist"
+)
+
+func TestCancelledTicketsAreOfferedToWaitlist(t *testing.T) {
+	requireDatabase(t)
+
+	bookingService := NewBookingService()
+	waitlistService := bookingService.waitlistService
+
+	show := createTestShow(t, "Waitlist Test", 100, 2)
+
+	held, err := bookingService.CreateBooking(bookings.NewBooking(show.Show_Id, "email", "holder@example.com", 2, 0))
+	if err != nil {
+		t.Fatalf("Failed to create booking: %v", err)
+	}
+
+	// The first entry wants more than will be released, so the second is served
+	tooMany, _ := waitlist.NewEntry(show.Show_Id, "email", "first@example.com", 3, "")
+	fits, _ := waitlist.NewEntry(show.Show_Id, "email", "second@example.com", 2, "")
+	for _, entry := range []*waitlist.Entry{tooMany, fits} {
+		if _, err := waitlistService.JoinWaitlist(entry); err != nil {
+			t.Fatalf("Failed to join waitlist: %v", err)
+		}
+	}
+
+	if err := bookingService.CancelBooking(held.BookingID, "test", "released"); err != nil {
+		t.Fatalf("Failed to cancel booking: %v", err)
+	}
+
+	skipped, err := waitlistService.GetEntry(tooMany.EntryID)
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if skipped.Status != waitlist.StatusWaiting || skipped.Position != 1 {
+		t.Errorf("Expected the larger request to keep waiting first in line, got %s at %d", skipped.Status, skipped.Position)
+	}
+
+	offered, err := waitlistService.GetEntry(fits.EntryID)
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if offered.Status != waitlist.StatusOffered || offered.BookingID == "" || offered.OfferExpiresAt == nil {
+		t.Fatalf("Expected the released tickets to be offered, got %+v", offered)
+	}
+
+	offer, err := bookingService.GetBooking(offered.BookingID)
+	if err != nil {
+		t.Fatalf("Failed to get offered booking: %v", err)
+	}
+	if offer.Status != bookings.StatusPending || offer.NumberOfTickets != 2 || offer.ContactValue != "second@example.com" {
+		t.Errorf("Expected a pending booking of 2 tickets for the waiting customer, got %s %d %s",
+			offer.Status, offer.NumberOfTickets, offer.ContactValue)
+	}
+	if offer.TotalAmount != 200 {
+		t.Errorf("Expected the offer priced at the show price, got %d", offer.TotalAmount)
+	}
+
+	// The offer holds the released tickets, so the show stays sold out
+	if booked := readBookedTickets(t, show.Show_Id); booked != 2 {
+		t.Errorf("Expected booked_tickets 2, got %d", booked)
+	}
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/repository"
+	"github.com/gsmayya/theater/utils"
+	"github.com/gsmayya/theater/webhooks"
+)
+
+const (
+	// webhookBatchSize caps how many deliveries one claim takes
+	webhookBatchSize = 50
+	// webhookSendConcurrency caps how many requests to partners run at once
+	webhookSendConcurrency = 8
+	// webhookClaimLease is how long a claimed batch is reserved for this
+	// process; it must outlast a batch of requests that all time out
+	webhookClaimLease = 5 * time.Minute
+	// maxDeliveryListSize caps how many deliveries one listing returns
+	maxDeliveryListSize = 500
+)
+
+// WebhookService manages partner webhook subscriptions and delivers outbox
+// events to them as signed JSON payloads. Events are queued per subscription
+// by the "webhooks" event subscriber and sent by RunDeliverer, which retries
+// failed attempts with backoff and disables a subscription after
+// WEBHOOK_DISABLE_AFTER failed attempts in a row.
+type WebhookService struct {
+	webhookRepository *repository.WebhookRepository
+	sender            *webhooks.Sender
+	maxAttempts       int32
+	disableAfter      int32
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService() *WebhookService {
+	maxAttempts := utils.GetInt32OrDefault("WEBHOOK_MAX_ATTEMPTS", 12)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	disableAfter := utils.GetInt32OrDefault("WEBHOOK_DISABLE_AFTER", 20)
+	if disableAfter < 1 {
+		disableAfter = 1
+	}
+
+	return &WebhookService{
+		webhookRepository: repository.NewWebhookRepository(),
+		sender:            webhooks.NewSender(utils.GetDurationOrDefault("WEBHOOK_TIMEOUT", 10*time.Second)),
+		maxAttempts:       maxAttempts,
+		disableAfter:      disableAfter,
+	}
+}
+
+// CreateSubscription validates and saves a new subscription. A secret is
+// generated when none is given; the result is the only response that
+// includes it.
+func (s *WebhookService) CreateSubscription(subscription *webhooks.Subscription) (*webhooks.Subscription, error) {
+	id, err := webhooks.NewSubscriptionID()
+	if err != nil {
+		return nil, err
+	}
+	subscription.SubscriptionID = id
+
+	if subscription.Secret == "" {
+		if subscription.Secret, err = webhooks.GenerateSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if !subscription.Active {
+		subscription.Disable(time.Now(), "created disabled")
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepository.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully created webhook subscription: %s", subscription.SubscriptionID)
+	return subscription, nil
+}
+
+// GetSubscription retrieves a subscription without its secret
+func (s *WebhookService) GetSubscription(subscriptionID string) (*webhooks.Subscription, error) {
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID cannot be empty")
+	}
+
+	subscription, err := s.webhookRepository.GetSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return subscription.Redacted(), nil
+}
+
+// GetAllSubscriptions lists subscriptions without their secrets
+func (s *WebhookService) GetAllSubscriptions() ([]*webhooks.Subscription, error) {
+	subscriptions, err := s.webhookRepository.GetAllSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, subscription := range subscriptions {
+		subscriptions[i] = subscription.Redacted()
+	}
+	return subscriptions, nil
+}
+
+// UpdateSubscription replaces a subscription's URL, event types, description
+// and active flag. The secret is replaced only when a new one is given, and
+// is then returned once. Enabling a subscription clears its failure count,
+// and its pending deliveries resume.
+func (s *WebhookService) UpdateSubscription(subscriptionID string, update *webhooks.Subscription) (*webhooks.Subscription, error) {
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID cannot be empty")
+	}
+
+	subscription, err := s.webhookRepository.GetSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := update.Secret != ""
+	if rotated {
+		subscription.Secret = update.Secret
+	}
+	subscription.URL = update.URL
+	subscription.EventTypes = update.EventTypes
+	subscription.Description = update.Description
+	switch {
+	case update.Active && !subscription.Active:
+		subscription.Enable()
+	case !update.Active && subscription.Active:
+		subscription.Disable(time.Now(), "disabled by an administrator")
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepository.UpdateSubscription(subscription); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully updated webhook subscription: %s", subscriptionID)
+	if rotated {
+		return subscription, nil
+	}
+	return subscription.Redacted(), nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (s *WebhookService) DeleteSubscription(subscriptionID string) error {
+	if subscriptionID == "" {
+		return fmt.Errorf("subscription ID cannot be empty")
+	}
+
+	return s.webhookRepository.DeleteSubscription(subscriptionID)
+}
+
+// EnqueueEvent queues an outbox event for every active subscription that
+// wants it. It is the handler of the "webhooks" event subscriber.
+func (s *WebhookService) EnqueueEvent(event *events.Event) error {
+	subscriptions, err := s.webhookRepository.GetActiveSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	var deliveries []*webhooks.Delivery
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(event.Type) {
+			continue
+		}
+		delivery, err := webhooks.NewDelivery(subscription.SubscriptionID, event)
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return s.webhookRepository.EnqueueDeliveries(deliveries)
+}
+
+// DeliverDue sends every pending delivery whose next attempt is due, to
+// active subscriptions only. It returns the number of deliveries that
+// succeeded.
+func (s *WebhookService) DeliverDue() (int, error) {
+	succeeded := 0
+	for {
+		due, claim, err := s.webhookRepository.ClaimDueDeliveries(time.Now(), webhookClaimLease, webhookBatchSize)
+		if err != nil {
+			return succeeded, err
+		}
+
+		var mu sync.Mutex
+		var firstErr error
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, webhookSendConcurrency)
+		for _, delivery := range due {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(delivery *webhooks.Delivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				ok, err := s.deliver(delivery, claim)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if ok {
+					succeeded++
+				}
+			}(delivery)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return succeeded, firstErr
+		}
+		if len(due) < webhookBatchSize {
+			return succeeded, nil
+		}
+	}
+}
+
+// deliver makes one attempt at a claimed delivery and saves the outcome. It
+// reports whether the delivery succeeded.
+func (s *WebhookService) deliver(delivery *webhooks.Delivery, claim string) (bool, error) {
+	subscription, err := s.webhookRepository.GetSubscription(delivery.SubscriptionID)
+	if err != nil {
+		return false, err
+	}
+	if !subscription.Active {
+		// Disabled since the batch was claimed; the claim lapses and the
+		// delivery waits until the subscription is enabled again
+		return false, nil
+	}
+
+	attempt := s.sender.Send(subscription, delivery, time.Now())
+	delivery.Record(attempt, s.maxAttempts)
+	if !attempt.Succeeded() {
+		log.Printf("Warning: webhook delivery %s to %s failed: %s (attempt %d of %d)",
+			delivery.DeliveryID, subscription.URL, attempt.Error, delivery.Attempts, s.maxAttempts)
+	}
+
+	updated, disabled, err := s.webhookRepository.SaveAttempt(delivery, attempt, claim, s.disableAfter)
+	if err != nil {
+		return false, err
+	}
+	if updated == nil {
+		log.Printf("Warning: claim on webhook delivery %s lapsed before the attempt was recorded", delivery.DeliveryID)
+		return false, nil
+	}
+	if disabled {
+		log.Printf("Error: webhook subscription %s disabled: %s", updated.SubscriptionID, updated.DisabledReason)
+	}
+	return delivery.Status == webhooks.DeliverySucceeded, nil
+}
+
+// RunDeliverer sends due deliveries on a fixed interval until the context is
+// cancelled. Succeeded deliveries are deleted from the log once they are
+// older than retention; a retention of 0 keeps them.
+func (s *WebhookService) RunDeliverer(ctx context.Context, interval, retention time.Duration) {
+	log.Printf("Webhook deliverer running every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook deliverer stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverDue(); err != nil {
+				log.Printf("Warning: Webhook deliverer failed: %v", err)
+			}
+		case <-purge.C:
+			if retention <= 0 {
+				continue
+			}
+			deleted, err := s.webhookRepository.DeleteSucceededDeliveries(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Warning: Failed to purge webhook deliveries: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d succeeded webhook deliveries", deleted)
+			}
+		}
+	}
+}
+
+// GetDeliveries returns a subscription's deliveries, newest first,
+// optionally only those in one status
+func (s *WebhookService) GetDeliveries(subscriptionID, status string) ([]*webhooks.Delivery, error) {
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID cannot be empty")
+	}
+	switch status {
+	case "", webhooks.DeliveryPending, webhooks.DeliverySucceeded, webhooks.DeliveryFailed:
+	default:
+		return nil, fmt.Errorf("invalid status filter: %q", status)
+	}
+
+	if _, err := s.webhookRepository.GetSubscription(subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepository.GetDeliveries(subscriptionID, status, maxDeliveryListSize)
+}
+
+// GetDelivery returns a delivery with every attempt made for it
+func (s *WebhookService) GetDelivery(deliveryID string) (*webhooks.Delivery, error) {
+	if deliveryID == "" {
+		return nil, fmt.Errorf("delivery ID cannot be empty")
+	}
+	return s.webhookRepository.GetDelivery(deliveryID)
+}
+
+// Redeliver queues a succeeded or failed delivery for another round of
+// attempts, with the same payload and event ID. The subscription must be
+// active.
+func (s *WebhookService) Redeliver(deliveryID string) (*webhooks.Delivery, error) {
+	delivery, err := s.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.webhookRepository.GetSubscription(delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.Active {
+		return nil, fmt.Errorf("invalid delivery status: subscription %s is disabled, enable it before redelivering", subscription.SubscriptionID)
+	}
+
+	delivery, err = s.webhookRepository.RequeueDelivery(deliveryID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Webhook delivery %s requeued for %s", deliveryID, subscription.SubscriptionID)
+	return delivery, nil
+}
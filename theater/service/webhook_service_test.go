@@ -0,0 +1,97 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/webhooks"
+)
+
+func TestDeliverDueSendsSignedPayloads(t *testing.T) {
+	requireDatabase(t)
+
+	const secret = "partner-secret-0123456789"
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	webhookService := NewWebhookService()
+	subscribe := func(url string, eventTypes ...string) *webhooks.Subscription {
+		subscription, err := webhookService.CreateSubscription(&webhooks.Subscription{
+			URL: url, EventTypes: eventTypes, Secret: secret, Active: true,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubscription() error: %v", err)
+		}
+		return subscription
+	}
+	wanted := subscribe(receiver.URL, events.TypeBookingStatusChanged)
+	defer webhookService.DeleteSubscription(wanted.SubscriptionID)
+	unwanted := subscribe(receiver.URL, events.TypeShowCreated)
+	defer webhookService.DeleteSubscription(unwanted.SubscriptionID)
+	failing := subscribe(broken.URL)
+	defer webhookService.DeleteSubscription(failing.SubscriptionID)
+
+	event, err := events.NewEvent(events.TypeBookingStatusChanged, "BK-WEBHOOK", map[string]string{"status": "confirmed"})
+	if err != nil {
+		t.Fatalf("NewEvent() error: %v", err)
+	}
+	if err := webhookService.EnqueueEvent(event); err != nil {
+		t.Fatalf("EnqueueEvent() error: %v", err)
+	}
+	if _, err := webhookService.DeliverDue(); err != nil {
+		t.Fatalf("DeliverDue() error: %v", err)
+	}
+
+	// Only the subscription that wants the event receives it, signed with its secret
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 request to the receiver, got %d", len(received))
+	}
+	header := received[0].Header.Get(webhooks.SignatureHeader)
+	if err := webhooks.VerifySignature(secret, bodies[0], header, time.Now(), time.Minute); err != nil {
+		t.Errorf("Expected the payload signature to verify, got %v", err)
+	}
+	if received[0].Header.Get(webhooks.EventHeader) != events.TypeBookingStatusChanged {
+		t.Errorf("Expected the event type header, got %q", received[0].Header.Get(webhooks.EventHeader))
+	}
+
+	deliveries, err := webhookService.GetDeliveries(wanted.SubscriptionID, "")
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != webhooks.DeliverySucceeded {
+		t.Errorf("Expected one succeeded delivery, got %+v (%v)", deliveries, err)
+	}
+	deliveries, err = webhookService.GetDeliveries(unwanted.SubscriptionID, "")
+	if err != nil || len(deliveries) != 0 {
+		t.Errorf("Expected no deliveries for an unwanted event type, got %+v (%v)", deliveries, err)
+	}
+
+	// A failed attempt leaves the delivery pending for a retry
+	deliveries, err = webhookService.GetDeliveries(failing.SubscriptionID, "")
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery to the failing endpoint, got %+v (%v)", deliveries, err)
+	}
+	if deliveries[0].Status != webhooks.DeliveryPending || deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a pending delivery after 1 failed attempt, got %+v", deliveries[0])
+	}
+}
@@ -0,0 +1,99 @@
+package shows
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Refund rules a cancellation or amendment can fall under
+const (
+	RuleFullRefund         = "full_refund"         // Cancelled early enough for all the money back
+	RulePartialRefund      = "partial_refund"      // Cancelled after the full-refund window
+	RuleCancellationClosed = "cancellation_closed" // Too close to the show to cancel
+	RuleAdminOverride      = "admin_override"      // Staff cancelled outside the policy
+	RuleAmendment          = "amendment"           // Tickets dropped from a booking that stays confirmed
+	RuleExchange           = "exchange"            // Tickets moved to a cheaper performance
+)
+
+// CancellationPolicy decides whether a show's bookings can still be cancelled
+// and how much of the amount paid is refunded. Until FullRefundHours before
+// the show everything is refunded; after that PartialRefundPercent is, until
+// cancellation closes CutoffHours before the show.
+type CancellationPolicy struct {
+	FullRefundHours      int32 `json:"full_refund_hours"`
+	PartialRefundPercent int32 `json:"partial_refund_percent"`
+	CutoffHours          int32 `json:"cutoff_hours"`
+}
+
+// RefundQuote is what cancelling a booking now would return
+type RefundQuote struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule"`    // One of the Rule* constants
+	Percent int32  `json:"percent"` // Share of the amount paid that is refunded
+	Paid    int32  `json:"paid"`
+	Amount  int32  `json:"amount"` // Refund due
+}
+
+// CancellationPolicyFromJSON reads a policy from a JSON request body. A JSON
+// null removes the show's policy and returns nil.
+func CancellationPolicyFromJSON(r *http.Request) (*CancellationPolicy, error) {
+	var policy *CancellationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	return policy, nil
+}
+
+// Validate checks the policy's windows are consistent
+func (p *CancellationPolicy) Validate() error {
+	if p.CutoffHours < 0 {
+		return fmt.Errorf("invalid cancellation policy: cutoff_hours cannot be negative")
+	}
+	if p.FullRefundHours < p.CutoffHours {
+		return fmt.Errorf("invalid cancellation policy: full_refund_hours must be at least cutoff_hours")
+	}
+	if p.PartialRefundPercent < 0 || p.PartialRefundPercent > 100 {
+		return fmt.Errorf("invalid cancellation policy: partial_refund_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Quote works out the refund for cancelling at now a booking that paid paid.
+// Shows without a policy refund everything and can always be cancelled.
+func (p *CancellationPolicy) Quote(paid int32, showDate, now time.Time) *RefundQuote {
+	if p == nil {
+		return NewRefundQuote(RuleFullRefund, paid, 100)
+	}
+
+	before := showDate.Sub(now)
+	switch {
+	case before < time.Duration(p.CutoffHours)*time.Hour || before < 0:
+		quote := NewRefundQuote(RuleCancellationClosed, paid, 0)
+		quote.Allowed = false
+		return quote
+	case before >= time.Duration(p.FullRefundHours)*time.Hour:
+		return NewRefundQuote(RuleFullRefund, paid, 100)
+	default:
+		return NewRefundQuote(RulePartialRefund, paid, p.PartialRefundPercent)
+	}
+}
+
+// Describe explains the policy's windows in a sentence, for error messages
+func (p *CancellationPolicy) Describe() string {
+	return fmt.Sprintf("full refund until %dh before the show, %d%% until %dh before, no cancellation after that",
+		p.FullRefundHours, p.PartialRefundPercent, p.CutoffHours)
+}
+
+// NewRefundQuote refunds percent of paid, rounded down
+func NewRefundQuote(rule string, paid, percent int32) *RefundQuote {
+	amount := int32(int64(paid) * int64(percent) / 100)
+	return &RefundQuote{
+		Allowed: true,
+		Rule:    rule,
+		Percent: percent,
+		Paid:    paid,
+		Amount:  amount,
+	}
+}
@@ -0,0 +1,74 @@
+package shows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCancellationPolicyQuote(t *testing.T) {
+	policy := &CancellationPolicy{FullRefundHours: 48, PartialRefundPercent: 50, CutoffHours: 2}
+	showDate := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		before  time.Duration
+		allowed bool
+		rule    string
+		amount  int32
+	}{
+		{"a week before", 7 * 24 * time.Hour, true, RuleFullRefund, 1001},
+		{"exactly at the full-refund window", 48 * time.Hour, true, RuleFullRefund, 1001},
+		{"a day before", 24 * time.Hour, true, RulePartialRefund, 500},
+		{"exactly at the cutoff", 2 * time.Hour, true, RulePartialRefund, 500},
+		{"inside the cutoff", time.Hour, false, RuleCancellationClosed, 0},
+		{"after the show started", -time.Hour, false, RuleCancellationClosed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := policy.Quote(1001, showDate, showDate.Add(-tt.before))
+			if quote.Allowed != tt.allowed || quote.Rule != tt.rule || quote.Amount != tt.amount {
+				t.Errorf("Expected allowed=%v rule=%s amount=%d, got %+v", tt.allowed, tt.rule, tt.amount, quote)
+			}
+			if quote.Paid != 1001 {
+				t.Errorf("Expected paid 1001, got %d", quote.Paid)
+			}
+		})
+	}
+}
+
+func TestCancellationPolicyQuoteWithoutPolicy(t *testing.T) {
+	var policy *CancellationPolicy
+	showDate := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+
+	quote := policy.Quote(800, showDate, showDate.Add(-time.Minute))
+	if !quote.Allowed || quote.Rule != RuleFullRefund || quote.Amount != 800 {
+		t.Errorf("Expected a full refund without a policy, got %+v", quote)
+	}
+}
+
+func TestCancellationPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CancellationPolicy
+		valid  bool
+	}{
+		{"typical", CancellationPolicy{FullRefundHours: 48, PartialRefundPercent: 50, CutoffHours: 2}, true},
+		{"no partial window", CancellationPolicy{FullRefundHours: 24, PartialRefundPercent: 0, CutoffHours: 24}, true},
+		{"negative cutoff", CancellationPolicy{FullRefundHours: 24, PartialRefundPercent: 50, CutoffHours: -1}, false},
+		{"full refund inside the cutoff", CancellationPolicy{FullRefundHours: 1, PartialRefundPercent: 50, CutoffHours: 2}, false},
+		{"percent over 100", CancellationPolicy{FullRefundHours: 48, PartialRefundPercent: 120, CutoffHours: 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid policy, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -12,17 +12,24 @@ import (
 
 // Show represents a show in theater
 type ShowData struct {
-	Show_Id        uuid.UUID `json:"show_id"`
-	ShowName       string    `json:"show_name"`
-	Details        string    `json:"details"`
-	Price          int32     `json:"price"`
-	Total_Tickets  int32     `json:"total_tickets"`
-	ShowLocation   string    `json:"show_location"`
-	Booked_Tickets int32     `json:"booked_tickets"`
-	ShowNumber     string    `json:"show_number"`
-	ShowDate       time.Time `json:"show_date"`
-	Images         []string  `json:"images,omitempty"` // CMS image IDs
-	Videos         []string  `json:"videos,omitempty"` // CMS video IDs
+	Show_Id        uuid.UUID     `json:"show_id"`
+	ShowName       string        `json:"show_name"`
+	Details        string        `json:"details"`
+	Price          int32         `json:"price"` // Single ticket price, used when the show has no ticket types
+	Total_Tickets  int32         `json:"total_tickets"`
+	ShowLocation   string        `json:"show_location"`
+	Booked_Tickets int32         `json:"booked_tickets"`
+	ShowNumber     string        `json:"show_number"`
+	ShowDate       time.Time     `json:"show_date"`
+	Images         []string      `json:"images,omitempty"`       // CMS image IDs
+	Videos         []string      `json:"videos,omitempty"`       // CMS video IDs
+	HoldMinutes    int32         `json:"hold_minutes,omitempty"` // Pending booking hold; 0 uses the system default
+	VenueID        *uuid.UUID    `json:"venue_id,omitempty"`     // Set for reserved-seating shows
+	TicketTypes    []*TicketType `json:"ticket_types,omitempty"` // Price tiers; empty for single-price shows
+	// Cancellation windows and refunds; nil means always cancellable with a full refund
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
+	// Bookings must carry a verification token proving the customer owns the contact
+	RequireVerifiedContact bool `json:"require_verified_contact,omitempty"`
 }
 
 func (s *ShowData) NewShow(show_name string, details string, price int32, total_tickets int32, show_location string) *ShowData {
@@ -88,6 +95,15 @@ func (s *ShowData) NewShowFromPut(r *http.Request) *ShowData {
 	return s
 }
 
+// HoldDuration returns how long pending bookings for this show keep their
+// tickets, or the fallback when the show does not override it
+func (s *ShowData) HoldDuration(fallback time.Duration) time.Duration {
+	if s.HoldMinutes > 0 {
+		return time.Duration(s.HoldMinutes) * time.Minute
+	}
+	return fallback
+}
+
 func (s *ShowData) ShowToMap() map[string]string {
 	imagesJson, _ := json.Marshal(s.Images)
 	videosJson, _ := json.Marshal(s.Videos)
@@ -103,6 +119,7 @@ func (s *ShowData) ShowToMap() map[string]string {
 		"show_date":      s.ShowDate.Format(time.RFC3339),
 		"images":         string(imagesJson),
 		"videos":         string(videosJson),
+		"hold_minutes":   fmt.Sprintf("%d", s.HoldMinutes),
 	}
 }
 
@@ -338,4 +338,17 @@ func TestShowDataDefaultValues(t *testing.T) {
 	if len(show.Videos) != 0 {
 		t.Errorf("Expected empty Videos slice, got %v", show.Videos)
 	}
-}
\ No newline at end of file
+}
+func TestShowDataHoldDuration(t *testing.T) {
+	fallback := 15 * time.Minute
+
+	show := &ShowData{}
+	if got := show.HoldDuration(fallback); got != fallback {
+		t.Errorf("Expected fallback hold %v, got %v", fallback, got)
+	}
+
+	show.HoldMinutes = 5
+	if got := show.HoldDuration(fallback); got != 5*time.Minute {
+		t.Errorf("Expected hold 5m, got %v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package shows
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxTicketTypeCodeLength matches the ticket type code column width
+const maxTicketTypeCodeLength = 30
+
+// TicketType is a price tier of a show, e.g. adult, child, senior, student or VIP
+type TicketType struct {
+	Code        string `json:"code"` // Lower-case identifier used in booking requests
+	Name        string `json:"name"`
+	Price       int32  `json:"price"`
+	Quota       *int32 `json:"quota,omitempty"`       // Most tickets of this type that can be sold; nil = no tier limit
+	Eligibility string `json:"eligibility,omitempty"` // e.g. "Under 12 years old"
+}
+
+// TicketTypesRequest is the payload for replacing a show's ticket types
+type TicketTypesRequest struct {
+	TicketTypes []*TicketType `json:"ticket_types"`
+}
+
+// TicketTypesFromJSON reads ticket types from a JSON request body. They are
+// validated against the show's capacity with ValidateTicketTypes.
+func TicketTypesFromJSON(r *http.Request) ([]*TicketType, error) {
+	var req TicketTypesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	if req.TicketTypes == nil {
+		req.TicketTypes = []*TicketType{}
+	}
+	return req.TicketTypes, nil
+}
+
+// NormalizeTicketTypeCode lower-cases and trims a ticket type code
+func NormalizeTicketTypeCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// ValidateTicketTypes normalizes codes in place and checks prices, quotas and
+// uniqueness. An empty list is valid and means the show has a single price.
+func ValidateTicketTypes(ticketTypes []*TicketType, totalTickets int32) error {
+	seen := make(map[string]bool, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		if ticketType == nil {
+			return fmt.Errorf("invalid ticket type: entry cannot be empty")
+		}
+
+		ticketType.Code = NormalizeTicketTypeCode(ticketType.Code)
+		if err := validateTicketTypeCode(ticketType.Code); err != nil {
+			return err
+		}
+		if seen[ticketType.Code] {
+			return fmt.Errorf("invalid ticket type: duplicate code %s", ticketType.Code)
+		}
+		seen[ticketType.Code] = true
+
+		ticketType.Name = strings.TrimSpace(ticketType.Name)
+		if ticketType.Name == "" {
+			return fmt.Errorf("invalid ticket type: %s is missing a name", ticketType.Code)
+		}
+		if ticketType.Price < 0 {
+			return fmt.Errorf("invalid ticket type: %s price cannot be negative", ticketType.Code)
+		}
+		if ticketType.Quota != nil && (*ticketType.Quota < 0 || *ticketType.Quota > totalTickets) {
+			return fmt.Errorf("invalid ticket type: %s quota must be between 0 and %d", ticketType.Code, totalTickets)
+		}
+	}
+	return nil
+}
+
+func validateTicketTypeCode(code string) error {
+	if code == "" {
+		return fmt.Errorf("invalid ticket type: code cannot be empty")
+	}
+	if len(code) > maxTicketTypeCodeLength {
+		return fmt.Errorf("invalid ticket type: code %s is longer than %d characters", code, maxTicketTypeCodeLength)
+	}
+	for _, c := range code {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return fmt.Errorf("invalid ticket type: code %s may only contain letters, digits, '_' and '-'", code)
+		}
+	}
+	return nil
+}
+
+// TicketPrices maps ticket type codes to their prices
+func TicketPrices(ticketTypes []*TicketType) map[string]int32 {
+	prices := make(map[string]int32, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		prices[ticketType.Code] = ticketType.Price
+	}
+	return prices
+}
+
+// HasTicketTypes reports whether the show sells tiered tickets rather than a single price
+func (s *ShowData) HasTicketTypes() bool {
+	return len(s.TicketTypes) > 0
+}
@@ -0,0 +1,62 @@
+package shows
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTicketTypes(t *testing.T) {
+	quota := int32(40)
+	ticketTypes := []*TicketType{
+		{Code: " Adult ", Name: "Adult", Price: 5000},
+		{Code: "child", Name: "Child", Price: 2500, Quota: &quota, Eligibility: "Under 12 years old"},
+	}
+
+	if err := ValidateTicketTypes(ticketTypes, 100); err != nil {
+		t.Fatalf("ValidateTicketTypes() error: %v", err)
+	}
+	if ticketTypes[0].Code != "adult" {
+		t.Errorf("Expected code to be normalized to adult, got %q", ticketTypes[0].Code)
+	}
+
+	tooLarge := int32(101)
+	negative := int32(-1)
+	tests := []struct {
+		name        string
+		ticketTypes []*TicketType
+	}{
+		{"empty code", []*TicketType{{Code: "", Name: "Adult", Price: 100}}},
+		{"bad code", []*TicketType{{Code: "adult price", Name: "Adult", Price: 100}}},
+		{"long code", []*TicketType{{Code: strings.Repeat("a", 31), Name: "Adult", Price: 100}}},
+		{"duplicate code", []*TicketType{{Code: "adult", Name: "Adult", Price: 100}, {Code: "ADULT", Name: "Adult 2", Price: 200}}},
+		{"missing name", []*TicketType{{Code: "adult", Price: 100}}},
+		{"negative price", []*TicketType{{Code: "adult", Name: "Adult", Price: -1}}},
+		{"quota above capacity", []*TicketType{{Code: "vip", Name: "VIP", Price: 100, Quota: &tooLarge}}},
+		{"negative quota", []*TicketType{{Code: "vip", Name: "VIP", Price: 100, Quota: &negative}}},
+		{"nil entry", []*TicketType{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTicketTypes(tt.ticketTypes, 100)
+			if err == nil || !strings.Contains(err.Error(), "invalid ticket type") {
+				t.Errorf("Expected invalid ticket type error, got %v", err)
+			}
+		})
+	}
+
+	if err := ValidateTicketTypes(nil, 100); err != nil {
+		t.Errorf("An empty list should be valid, got %v", err)
+	}
+}
+
+func TestTicketPrices(t *testing.T) {
+	prices := TicketPrices([]*TicketType{
+		{Code: "adult", Name: "Adult", Price: 5000},
+		{Code: "child", Name: "Child", Price: 2500},
+	})
+
+	if len(prices) != 2 || prices["adult"] != 5000 || prices["child"] != 2500 {
+		t.Errorf("Unexpected prices: %v", prices)
+	}
+}
@@ -0,0 +1,108 @@
+package tickets
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+// Reasons given to a scanner for admitting or denying a ticket
+const (
+	ReasonAdmitted            = "admitted"
+	ReasonMalformedToken      = "malformed_token"
+	ReasonUnknownSigningKey   = "unknown_signing_key"
+	ReasonInvalidSignature    = "invalid_signature"
+	ReasonTicketNotFound      = "ticket_not_found"
+	ReasonTicketVoided        = "ticket_voided"
+	ReasonTicketReleased      = "ticket_released"
+	ReasonWrongShow           = "wrong_show"
+	ReasonBookingNotConfirmed = "booking_not_confirmed"
+	ReasonBookingInactive     = "booking_inactive"
+	ReasonBalanceDue          = "balance_due"
+	ReasonAlreadyCheckedIn    = "already_checked_in"
+	ReasonTooEarly            = "too_early"
+	ReasonShowEnded           = "show_ended"
+)
+
+// EntryWindow is when tickets for a show are accepted, relative to its start
+type EntryWindow struct {
+	OpensBefore time.Duration // How long before the show starts doors open
+	ClosesAfter time.Duration // How long after the show starts entry closes
+}
+
+// VerifyRequest is the payload a scanner sends to check a ticket token
+type VerifyRequest struct {
+	Token  string `json:"token"`
+	ShowID string `json:"show_id,omitempty"` // Show being scanned for; tickets for other shows are denied
+}
+
+// Verification is the outcome of checking a ticket token at the door
+type Verification struct {
+	Admit    bool       `json:"admit"`
+	Reason   string     `json:"reason"` // One of the Reason* constants
+	Ticket   *Ticket    `json:"ticket,omitempty"`
+	ShowDate *time.Time `json:"show_date,omitempty"`
+}
+
+// Deny returns a verification that refuses entry
+func Deny(reason string) *Verification {
+	return &Verification{Reason: reason}
+}
+
+// TokenErrorReason returns the deny reason for a token that failed to verify
+func TokenErrorReason(err error) string {
+	switch err {
+	case ErrUnknownSigningKey:
+		return ReasonUnknownSigningKey
+	case ErrInvalidSignature:
+		return ReasonInvalidSignature
+	default:
+		return ReasonMalformedToken
+	}
+}
+
+// CheckAdmission decides whether a verified token's ticket gets in. The token
+// must match the ticket on file, the ticket must be active, its booking
+// confirmed with no balance left to pay and the scan inside the show's entry
+// window. A non-nil expectedShowID makes scanners at one show's door reject
+// other shows' tickets.
+func CheckAdmission(claims *TokenClaims, ticket *Ticket, bookingStatus string, balanceDue int32, showDate time.Time,
+	window EntryWindow, expectedShowID *uuid.UUID, now time.Time) string {
+	switch {
+	case ticket.BookingID != claims.BookingID || ticket.ShowID != claims.ShowID:
+		return ReasonTicketNotFound
+	case expectedShowID != nil && *expectedShowID != ticket.ShowID:
+		return ReasonWrongShow
+	case ticket.Status == StatusCheckedIn:
+		return ReasonAlreadyCheckedIn
+	case ticket.Status == StatusVoided:
+		return ReasonTicketVoided
+	case ticket.Status == StatusReleased:
+		return ReasonTicketReleased
+	}
+
+	switch bookingStatus {
+	case bookings.StatusConfirmed, bookings.StatusCheckedIn, bookings.StatusNoShow:
+		// A checked-in booking may still have guests to arrive, and a
+		// no-show who turns up late can still be let in
+	case bookings.StatusPending:
+		return ReasonBookingNotConfirmed
+	default:
+		return ReasonBookingInactive
+	}
+
+	// Tickets added to the booking after it was paid have to be paid for first
+	if balanceDue > 0 {
+		return ReasonBalanceDue
+	}
+
+	switch {
+	case now.Before(showDate.Add(-window.OpensBefore)):
+		return ReasonTooEarly
+	case now.After(showDate.Add(window.ClosesAfter)):
+		return ReasonShowEnded
+	}
+
+	return ReasonAdmitted
+}
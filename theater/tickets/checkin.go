@@ -0,0 +1,217 @@
+package tickets
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scan results
+const (
+	ScanCheckedIn = "checked_in" // The scan let the ticket in
+	ScanDuplicate = "duplicate"  // The ticket was already checked in by another scan
+	ScanDenied    = "denied"     // The ticket was refused; see the scan's reason
+)
+
+// Scan sources
+const (
+	SourceLive  = "live"  // Sent by a connected scanner as it happened
+	SourceBatch = "batch" // Buffered by an offline scanner and uploaded later
+)
+
+// ReasonSuperseded marks a check-in replaced by an earlier offline scan of the same ticket
+const ReasonSuperseded = "superseded_by_earlier_scan"
+
+// Batch limits
+const (
+	maxBatchScans   = 500
+	maxGateIDLength = 50
+	maxScanIDLength = 64
+	// maxClockSkew is how far in the future a scanner's clock may be
+	maxClockSkew = 5 * time.Minute
+)
+
+// Scan is one read of a ticket at a gate, as recorded in the scan log
+type Scan struct {
+	ScanID     string    `json:"scan_id"`
+	TicketID   string    `json:"ticket_id,omitempty"`
+	BookingID  string    `json:"booking_id,omitempty"`
+	ShowID     uuid.UUID `json:"show_id"`
+	GateID     string    `json:"gate_id"`
+	Source     string    `json:"source"`           // One of the Source* constants
+	Result     string    `json:"result"`           // One of the Scan* constants
+	Reason     string    `json:"reason,omitempty"` // Why the scan was denied or superseded
+	ScannedAt  time.Time `json:"scanned_at"`       // When the scanner read the ticket
+	ReceivedAt time.Time `json:"received_at"`      // When the backend recorded the scan
+}
+
+// ScanResult is the outcome of a scan as reported back to the scanner
+type ScanResult struct {
+	Scan       *Scan   `json:"scan"`
+	Ticket     *Ticket `json:"ticket,omitempty"`
+	FirstScan  *Scan   `json:"first_scan,omitempty"` // For duplicates, the scan that checked the ticket in
+	Superseded *Scan   `json:"superseded,omitempty"` // A later check-in this earlier offline scan replaced
+	Replayed   bool    `json:"replayed,omitempty"`   // The scan ID was already synced; this is its stored outcome
+}
+
+// ScanRequest identifies what was scanned: a signed ticket token, a ticket ID
+// typed in by staff, or (live scans only) a booking ID or reference, which
+// checks in every ticket of the booking
+type ScanRequest struct {
+	ScanID    string     `json:"scan_id,omitempty"` // Client-generated; required for batch scans
+	Token     string     `json:"token,omitempty"`
+	TicketID  string     `json:"ticket_id,omitempty"`
+	BookingID string     `json:"booking_id,omitempty"`
+	GateID    string     `json:"gate_id"`
+	ShowID    string     `json:"show_id,omitempty"`    // Show being scanned for; tickets for other shows are denied
+	ScannedAt *time.Time `json:"scanned_at,omitempty"` // Required for batch scans
+}
+
+// BatchRequest is a set of scans buffered by an offline scanner. Gate and
+// show apply to every scan that does not set its own.
+type BatchRequest struct {
+	GateID string         `json:"gate_id"`
+	ShowID string         `json:"show_id,omitempty"`
+	Scans  []*ScanRequest `json:"scans"`
+}
+
+// Validate checks a live scan request
+func (r *ScanRequest) Validate() error {
+	given := 0
+	for _, value := range []string{r.Token, r.TicketID, r.BookingID} {
+		if value != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return fmt.Errorf("invalid scan: exactly one of token, ticket_id or booking_id is required")
+	}
+	return r.validateIDs()
+}
+
+// Validate checks a batch and fills in each scan's gate and show from the
+// batch. Batch scans must carry a scan ID and the time they were made, and
+// name a single ticket.
+func (b *BatchRequest) Validate(now time.Time) error {
+	if len(b.Scans) == 0 {
+		return fmt.Errorf("invalid scan: a batch needs at least one scan")
+	}
+	if len(b.Scans) > maxBatchScans {
+		return fmt.Errorf("invalid scan: a batch can hold at most %d scans", maxBatchScans)
+	}
+
+	seen := make(map[string]bool, len(b.Scans))
+	for i, scan := range b.Scans {
+		if scan.GateID == "" {
+			scan.GateID = b.GateID
+		}
+		if scan.ShowID == "" {
+			scan.ShowID = b.ShowID
+		}
+
+		if scan.BookingID != "" {
+			return fmt.Errorf("invalid scan %d: batch scans must name a token or ticket_id", i)
+		}
+		if err := scan.Validate(); err != nil {
+			return fmt.Errorf("invalid scan %d: %w", i, err)
+		}
+		if scan.ScanID == "" || scan.ScannedAt == nil {
+			return fmt.Errorf("invalid scan %d: batch scans need scan_id and scanned_at", i)
+		}
+		if scan.ScannedAt.After(now.Add(maxClockSkew)) {
+			return fmt.Errorf("invalid scan %d: scanned_at %s is in the future", i, scan.ScannedAt.Format(time.RFC3339))
+		}
+		if seen[scan.ScanID] {
+			return fmt.Errorf("invalid scan %d: duplicate scan_id %s", i, scan.ScanID)
+		}
+		seen[scan.ScanID] = true
+	}
+	return nil
+}
+
+// SortByScanTime orders a batch's scans by when they were made, so that when
+// two scans of one ticket conflict the earlier one checks it in
+func (b *BatchRequest) SortByScanTime() {
+	sort.SliceStable(b.Scans, func(i, j int) bool {
+		return b.Scans[i].ScannedAt.Before(*b.Scans[j].ScannedAt)
+	})
+}
+
+func (r *ScanRequest) validateIDs() error {
+	r.GateID = strings.TrimSpace(r.GateID)
+	if r.GateID == "" {
+		return fmt.Errorf("invalid scan: gate_id is required")
+	}
+	if len(r.GateID) > maxGateIDLength {
+		return fmt.Errorf("invalid scan: gate_id must be at most %d characters", maxGateIDLength)
+	}
+	if len(r.ScanID) > maxScanIDLength {
+		return fmt.Errorf("invalid scan: scan_id must be at most %d characters", maxScanIDLength)
+	}
+	return nil
+}
+
+// NewScan starts a scan log entry for a request. Live scans without a scan ID
+// get a generated one and are timed on arrival.
+func NewScan(req *ScanRequest, source string, now time.Time) (*Scan, error) {
+	scan := &Scan{
+		ScanID:     req.ScanID,
+		TicketID:   req.TicketID,
+		GateID:     req.GateID,
+		Source:     source,
+		ScannedAt:  now,
+		ReceivedAt: now,
+	}
+	if req.ScannedAt != nil && source == SourceBatch {
+		scan.ScannedAt = *req.ScannedAt
+	}
+	if scan.ScanID == "" {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate scan ID: %w", err)
+		}
+		scan.ScanID = fmt.Sprintf("SC-%X", buf)
+	}
+	return scan, nil
+}
+
+// Deny records that the scan was refused
+func (s *Scan) Deny(reason string) {
+	s.Result = ScanDenied
+	s.Reason = reason
+}
+
+// ChangedBy is the actor recorded in a booking's status history when a scan
+// checks it in
+func (s *Scan) ChangedBy() string {
+	return "gate:" + s.GateID
+}
+
+// Attendance counts who has come through the doors for a show
+type Attendance struct {
+	ShowID      uuid.UUID        `json:"show_id"`
+	Expected    int32            `json:"expected"`   // Tickets of confirmed bookings
+	CheckedIn   int32            `json:"checked_in"` // Tickets scanned in
+	NotArrived  int32            `json:"not_arrived"`
+	NoShows     int32            `json:"no_shows"`     // Tickets not scanned in by the time entry closed
+	EntryClosed bool             `json:"entry_closed"` // Whether the show's entry window has passed
+	ByGate      map[string]int32 `json:"by_gate"`
+	LastScanAt  *time.Time       `json:"last_scan_at,omitempty"`
+}
+
+// Settle fills in the derived counts. Tickets that have not arrived only
+// count as no-shows once entry has closed.
+func (a *Attendance) Settle(showDate time.Time, window EntryWindow, now time.Time) {
+	a.NotArrived = a.Expected - a.CheckedIn
+	if a.NotArrived < 0 {
+		a.NotArrived = 0
+	}
+	a.EntryClosed = now.After(showDate.Add(window.ClosesAfter))
+	if a.EntryClosed {
+		a.NoShows = a.NotArrived
+	}
+}
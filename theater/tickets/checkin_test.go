@@ -0,0 +1,148 @@
+package tickets
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ScanRequest
+		wantErr string
+	}{
+		{"token", ScanRequest{Token: "k1.a.b", GateID: "north"}, ""},
+		{"booking", ScanRequest{BookingID: "BK-1", GateID: " north "}, ""},
+		{"nothing scanned", ScanRequest{GateID: "north"}, "exactly one"},
+		{"two things scanned", ScanRequest{Token: "k1.a.b", TicketID: "TK-1", GateID: "north"}, "exactly one"},
+		{"no gate", ScanRequest{TicketID: "TK-1", GateID: "  "}, "gate_id is required"},
+		{"long gate", ScanRequest{TicketID: "TK-1", GateID: strings.Repeat("g", 51)}, "gate_id must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBatchRequestValidate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC)
+	earlier := now.Add(-10 * time.Minute)
+	future := now.Add(10 * time.Minute)
+
+	valid := func() *BatchRequest {
+		return &BatchRequest{
+			GateID: "north",
+			ShowID: "show-1",
+			Scans: []*ScanRequest{
+				{ScanID: "s1", TicketID: "TK-1", ScannedAt: &earlier},
+				{ScanID: "s2", Token: "k1.a.b", GateID: "south", ScannedAt: &now},
+			},
+		}
+	}
+
+	batch := valid()
+	if err := batch.Validate(now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if batch.Scans[0].GateID != "north" || batch.Scans[0].ShowID != "show-1" {
+		t.Errorf("Expected scans to inherit the batch gate and show, got %+v", batch.Scans[0])
+	}
+	if batch.Scans[1].GateID != "south" {
+		t.Errorf("Expected a scan's own gate to be kept, got %s", batch.Scans[1].GateID)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(b *BatchRequest)
+		wantErr string
+	}{
+		{"empty", func(b *BatchRequest) { b.Scans = nil }, "at least one scan"},
+		{"booking scan", func(b *BatchRequest) { b.Scans[0].TicketID, b.Scans[0].BookingID = "", "BK-1" }, "token or ticket_id"},
+		{"no scan ID", func(b *BatchRequest) { b.Scans[0].ScanID = "" }, "need scan_id and scanned_at"},
+		{"no scan time", func(b *BatchRequest) { b.Scans[0].ScannedAt = nil }, "need scan_id and scanned_at"},
+		{"future scan", func(b *BatchRequest) { b.Scans[0].ScannedAt = &future }, "in the future"},
+		{"repeated scan ID", func(b *BatchRequest) { b.Scans[1].ScanID = "s1" }, "duplicate scan_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := valid()
+			tt.mutate(batch)
+			err := batch.Validate(now)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBatchSortByScanTime(t *testing.T) {
+	base := time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC)
+	first, second, third := base, base.Add(time.Minute), base.Add(2*time.Minute)
+
+	batch := &BatchRequest{Scans: []*ScanRequest{
+		{ScanID: "c", ScannedAt: &third},
+		{ScanID: "a", ScannedAt: &first},
+		{ScanID: "b", ScannedAt: &second},
+	}}
+	batch.SortByScanTime()
+
+	for i, want := range []string{"a", "b", "c"} {
+		if batch.Scans[i].ScanID != want {
+			t.Errorf("Scan %d: expected %s, got %s", i, want, batch.Scans[i].ScanID)
+		}
+	}
+}
+
+func TestNewScan(t *testing.T) {
+	now := time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC)
+	offline := now.Add(-time.Hour)
+
+	live, err := NewScan(&ScanRequest{TicketID: "TK-1", GateID: "north", ScannedAt: &offline}, SourceLive, now)
+	if err != nil {
+		t.Fatalf("NewScan() error: %v", err)
+	}
+	if !strings.HasPrefix(live.ScanID, "SC-") {
+		t.Errorf("Expected a generated scan ID, got %q", live.ScanID)
+	}
+	if !live.ScannedAt.Equal(now) {
+		t.Errorf("Live scans should be timed on arrival, got %v", live.ScannedAt)
+	}
+
+	batch, _ := NewScan(&ScanRequest{ScanID: "s1", TicketID: "TK-1", GateID: "north", ScannedAt: &offline}, SourceBatch, now)
+	if batch.ScanID != "s1" || !batch.ScannedAt.Equal(offline) || !batch.ReceivedAt.Equal(now) {
+		t.Errorf("Batch scans should keep their ID and scan time, got %+v", batch)
+	}
+	if batch.ChangedBy() != "gate:north" {
+		t.Errorf("Expected gate:north, got %s", batch.ChangedBy())
+	}
+}
+
+func TestAttendanceSettle(t *testing.T) {
+	showDate := time.Date(2025, 6, 1, 19, 30, 0, 0, time.UTC)
+	window := EntryWindow{OpensBefore: 2 * time.Hour, ClosesAfter: time.Hour}
+
+	during := &Attendance{Expected: 10, CheckedIn: 7}
+	during.Settle(showDate, window, showDate)
+	if during.NotArrived != 3 || during.NoShows != 0 || during.EntryClosed {
+		t.Errorf("During entry: expected 3 not arrived and no no-shows, got %+v", during)
+	}
+
+	after := &Attendance{Expected: 10, CheckedIn: 7}
+	after.Settle(showDate, window, showDate.Add(2*time.Hour))
+	if after.NotArrived != 3 || after.NoShows != 3 || !after.EntryClosed {
+		t.Errorf("After entry closed: expected 3 no-shows, got %+v", after)
+	}
+}
@@ -1,13 +1,122 @@
 package tickets
 
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+// Ticket statuses
+const (
+	StatusActive    = "active"     // Valid for entry while its booking holds inventory
+	StatusCheckedIn = "checked_in" // Scanned in at a gate
+	StatusVoided    = "voided"     // Removed from its booking on its own; its place went back on sale
+	StatusReleased  = "released"   // Its booking was cancelled or expired
+)
+
+// maxAttendeeNameLength matches the tickets.attendee_name column
+const maxAttendeeNameLength = 255
+
+// Ticket is one admission within a booking. A booking of four tickets has
+// four Ticket records, each of which can carry its own attendee name and be
+// voided on its own.
 type Ticket struct {
-	Id                  string `json:"id"`
-	MobileNumber        string `json:"mobileNumber"`
-	NumberOfTickets     int    `json:"numberOfTickets"`
-	Name                string `json:"name"`
-	PaymentMobileNumber string `json:"paymentMobileNumber"`
-	DateTime            string `json:"dateTime"`
-	Item                string `json:"item"`
-	UniqueId            string `json:"uniqueId"`
-	BookedBy            string `json:"bookedBy"`
+	TicketID        string     `json:"ticket_id"` // Random unique ID, e.g. TK-9F86D081884C7D65
+	BookingID       string     `json:"booking_id"`
+	ShowID          uuid.UUID  `json:"show_id"`
+	TicketType      string     `json:"ticket_type,omitempty"` // Set for shows with ticket types
+	SeatID          string     `json:"seat_id,omitempty"`     // Set for reserved-seating shows
+	AttendeeName    string     `json:"attendee_name,omitempty"`
+	Status          string     `json:"status"` // One of the Status* constants
+	VoidReason      string     `json:"void_reason,omitempty"`
+	CheckedInAt     *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInGate   string     `json:"checked_in_gate,omitempty"`
+	CheckedInScanID string     `json:"checked_in_scan_id,omitempty"` // Scan that let the ticket in
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// NewTicketsForBooking creates one active ticket per admission in a booking.
+// Seats are handed out in the booking's seat order and ticket types in the
+// order of its ticket lines.
+func NewTicketsForBooking(booking *bookings.Booking) ([]*Ticket, error) {
+	count := int(booking.NumberOfTickets)
+	if len(booking.Seats) > 0 && len(booking.Seats) != count {
+		return nil, fmt.Errorf("booking %s has %d seats for %d tickets", booking.BookingID, len(booking.Seats), count)
+	}
+
+	ticketTypes := make([]string, 0, count)
+	for _, line := range booking.TicketLines {
+		for i := int32(0); i < line.Quantity; i++ {
+			ticketTypes = append(ticketTypes, line.TicketType)
+		}
+	}
+	if len(ticketTypes) > 0 && len(ticketTypes) != count {
+		return nil, fmt.Errorf("booking %s has %d typed tickets for %d tickets", booking.BookingID, len(ticketTypes), count)
+	}
+
+	now := time.Now()
+	issued := make([]*Ticket, 0, count)
+	for i := 0; i < count; i++ {
+		ticketID, err := newTicketID()
+		if err != nil {
+			return nil, err
+		}
+
+		ticket := &Ticket{
+			TicketID:  ticketID,
+			BookingID: booking.BookingID,
+			ShowID:    booking.ShowID,
+			Status:    StatusActive,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if len(booking.Seats) > 0 {
+			ticket.SeatID = booking.Seats[i]
+		}
+		if len(ticketTypes) > 0 {
+			ticket.TicketType = ticketTypes[i]
+		}
+		issued = append(issued, ticket)
+	}
+
+	return issued, nil
+}
+
+// NormalizeAttendeeName trims an attendee name and checks its length. An
+// empty name clears the attendee.
+func NormalizeAttendeeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) > maxAttendeeNameLength {
+		return "", fmt.Errorf("invalid attendee name: must be at most %d characters", maxAttendeeNameLength)
+	}
+	return name, nil
+}
+
+// IsActive reports whether the ticket is still valid for entry
+func (t *Ticket) IsActive() bool {
+	return t.Status == StatusActive
+}
+
+// CheckIn marks the ticket as scanned in by a scan
+func (t *Ticket) CheckIn(scan *Scan) {
+	checkedInAt := scan.ScannedAt
+	t.Status = StatusCheckedIn
+	t.CheckedInAt = &checkedInAt
+	t.CheckedInGate = scan.GateID
+	t.CheckedInScanID = scan.ScanID
+	t.UpdatedAt = scan.ReceivedAt
+}
+
+// newTicketID generates a random ticket ID with a TK- prefix
+func newTicketID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ticket ID: %w", err)
+	}
+	return fmt.Sprintf("TK-%X", buf), nil
 }
@@ -0,0 +1,96 @@
+package tickets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+func TestNewTicketsForBooking(t *testing.T) {
+	booking := bookings.NewBooking(uuid.New(), "email", "fan@example.com", 3, 300)
+
+	issued, err := NewTicketsForBooking(booking)
+	if err != nil {
+		t.Fatalf("NewTicketsForBooking() error: %v", err)
+	}
+	if len(issued) != 3 {
+		t.Fatalf("Expected 3 tickets, got %d", len(issued))
+	}
+
+	seen := make(map[string]bool)
+	for _, ticket := range issued {
+		if !strings.HasPrefix(ticket.TicketID, "TK-") || len(ticket.TicketID) != 19 {
+			t.Errorf("Expected TK- ticket ID of 19 characters, got %q", ticket.TicketID)
+		}
+		if seen[ticket.TicketID] {
+			t.Errorf("Duplicate ticket ID %s", ticket.TicketID)
+		}
+		seen[ticket.TicketID] = true
+
+		if ticket.BookingID != booking.BookingID || ticket.ShowID != booking.ShowID {
+			t.Errorf("Ticket should belong to booking %s, got %s", booking.BookingID, ticket.BookingID)
+		}
+		if !ticket.IsActive() {
+			t.Errorf("New tickets should be active, got %s", ticket.Status)
+		}
+		if ticket.SeatID != "" || ticket.TicketType != "" {
+			t.Errorf("Unexpected seat %q or ticket type %q", ticket.SeatID, ticket.TicketType)
+		}
+	}
+}
+
+func TestNewTicketsForBookingAssignsSeatsAndTypes(t *testing.T) {
+	booking := bookings.NewBooking(uuid.New(), "email", "fan@example.com", 3, 300)
+	booking.Seats = []string{"STALLS-A-1", "STALLS-A-2", "STALLS-A-3"}
+	booking.TicketLines = []*bookings.TicketLine{
+		{TicketType: "adult", Quantity: 2},
+		{TicketType: "child", Quantity: 1},
+	}
+
+	issued, err := NewTicketsForBooking(booking)
+	if err != nil {
+		t.Fatalf("NewTicketsForBooking() error: %v", err)
+	}
+
+	expected := []struct{ seat, ticketType string }{
+		{"STALLS-A-1", "adult"},
+		{"STALLS-A-2", "adult"},
+		{"STALLS-A-3", "child"},
+	}
+	for i, want := range expected {
+		if issued[i].SeatID != want.seat || issued[i].TicketType != want.ticketType {
+			t.Errorf("Ticket %d: expected %s/%s, got %s/%s", i, want.seat, want.ticketType, issued[i].SeatID, issued[i].TicketType)
+		}
+	}
+}
+
+func TestNewTicketsForBookingRejectsMismatches(t *testing.T) {
+	booking := bookings.NewBooking(uuid.New(), "email", "fan@example.com", 2, 200)
+	booking.Seats = []string{"STALLS-A-1"}
+	if _, err := NewTicketsForBooking(booking); err == nil {
+		t.Error("Expected an error when seats do not match the ticket count")
+	}
+
+	booking.Seats = nil
+	booking.TicketLines = []*bookings.TicketLine{{TicketType: "adult", Quantity: 3}}
+	if _, err := NewTicketsForBooking(booking); err == nil {
+		t.Error("Expected an error when ticket lines do not match the ticket count")
+	}
+}
+
+func TestNormalizeAttendeeName(t *testing.T) {
+	name, err := NormalizeAttendeeName("  Alex Doe ")
+	if err != nil || name != "Alex Doe" {
+		t.Errorf("NormalizeAttendeeName() = %q, %v; expected \"Alex Doe\"", name, err)
+	}
+
+	if name, err := NormalizeAttendeeName("   "); err != nil || name != "" {
+		t.Errorf("Blank names should clear the attendee, got %q, %v", name, err)
+	}
+
+	if _, err := NormalizeAttendeeName(strings.Repeat("x", maxAttendeeNameLength+1)); err == nil {
+		t.Error("Expected an error for an overly long attendee name")
+	}
+}
@@ -0,0 +1,172 @@
+package tickets
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// minSigningKeyLength is the shortest secret accepted for signing tickets
+const minSigningKeyLength = 32
+
+// Token errors. Each is reported to scanners as a deny reason.
+var (
+	ErrMalformedToken    = errors.New("malformed ticket token")
+	ErrUnknownSigningKey = errors.New("ticket token signed with an unknown key")
+	ErrInvalidSignature  = errors.New("ticket token signature is invalid")
+)
+
+// TokenClaims is what a ticket token vouches for
+type TokenClaims struct {
+	TicketID  string    `json:"t"`
+	BookingID string    `json:"b"`
+	ShowID    uuid.UUID `json:"s"`
+	IssuedAt  int64     `json:"iat"` // Unix seconds
+}
+
+// IssuedToken is a signed token for one ticket, ready to be rendered as a QR code
+type IssuedToken struct {
+	TicketID     string `json:"ticket_id"`
+	TicketType   string `json:"ticket_type,omitempty"`
+	SeatID       string `json:"seat_id,omitempty"`
+	AttendeeName string `json:"attendee_name,omitempty"`
+	Token        string `json:"token"`
+}
+
+// KeyRing holds the secrets ticket tokens are signed with. New tokens are
+// signed with the active key; tokens signed with any key in the ring still
+// verify, so a key can be rotated out once the tokens it signed are no longer
+// needed.
+type KeyRing struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewKeyRing parses a key ring from a spec such as "k2:<secret>,k1:<secret>".
+// The first key is the active one. Key IDs may use letters, digits, '-' and
+// '_'; secrets must be at least 32 characters.
+func NewKeyRing(spec string) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string][]byte)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, secret, found := strings.Cut(entry, ":")
+		if !found || !isValidKeyID(keyID) {
+			return nil, fmt.Errorf("invalid signing key %q, expected <key id>:<secret>", keyID)
+		}
+		if len(secret) < minSigningKeyLength {
+			return nil, fmt.Errorf("signing key %s must be at least %d characters", keyID, minSigningKeyLength)
+		}
+		if _, exists := ring.keys[keyID]; exists {
+			return nil, fmt.Errorf("duplicate signing key %s", keyID)
+		}
+
+		ring.keys[keyID] = []byte(secret)
+		if ring.activeKeyID == "" {
+			ring.activeKeyID = keyID
+		}
+	}
+
+	if ring.activeKeyID == "" {
+		return nil, fmt.Errorf("no signing keys configured")
+	}
+	return ring, nil
+}
+
+// NewEphemeralKeyRing creates a key ring with a single random key. Tokens it
+// signs stop verifying when the process restarts.
+func NewEphemeralKeyRing() (*KeyRing, error) {
+	secret := make([]byte, minSigningKeyLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return &KeyRing{
+		activeKeyID: "ephemeral",
+		keys:        map[string][]byte{"ephemeral": secret},
+	}, nil
+}
+
+// ActiveKeyID returns the ID of the key new tokens are signed with
+func (k *KeyRing) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Sign returns a compact token "<key id>.<payload>.<signature>" for a
+// ticket, small enough for a QR code
+func (k *KeyRing) Sign(claims *TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ticket token: %w", err)
+	}
+
+	signed := k.activeKeyID + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + k.signature(k.activeKeyID, signed), nil
+}
+
+// Verify checks a token's signature and returns its claims
+func (k *KeyRing) Verify(token string) (*TokenClaims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, ErrMalformedToken
+	}
+
+	keyID := parts[0]
+	if _, ok := k.keys[keyID]; !ok {
+		return nil, ErrUnknownSigningKey
+	}
+
+	expected := k.signature(keyID, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	claims := &TokenClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil || claims.TicketID == "" || claims.BookingID == "" {
+		return nil, ErrMalformedToken
+	}
+	return claims, nil
+}
+
+// NewClaims returns the claims of a token for a ticket
+func NewClaims(ticket *Ticket, issuedAt time.Time) *TokenClaims {
+	return &TokenClaims{
+		TicketID:  ticket.TicketID,
+		BookingID: ticket.BookingID,
+		ShowID:    ticket.ShowID,
+		IssuedAt:  issuedAt.Unix(),
+	}
+}
+
+func (k *KeyRing) signature(keyID, signed string) string {
+	mac := hmac.New(sha256.New, k.keys[keyID])
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isValidKeyID(keyID string) bool {
+	if keyID == "" {
+		return false
+	}
+	for _, c := range keyID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package tickets
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+const (
+	testKeyOld = "old-secret-0123456789abcdefghijklmnop"
+	testKeyNew = "new-secret-0123456789abcdefghijklmnop"
+)
+
+func testTicket() *Ticket {
+	return &Ticket{
+		TicketID:  "TK-0123456789ABCDEF",
+		BookingID: "BK-0123456789ABCDEF",
+		ShowID:    uuid.New(),
+		Status:    StatusActive,
+	}
+}
+
+func TestKeyRingSignAndVerify(t *testing.T) {
+	ring, err := NewKeyRing("k1:" + testKeyOld)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error: %v", err)
+	}
+
+	ticket := testTicket()
+	token, err := ring.Sign(NewClaims(ticket, time.Now()))
+	if err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+	if !strings.HasPrefix(token, "k1.") || strings.Count(token, ".") != 2 {
+		t.Errorf("Expected <kid>.<payload>.<signature>, got %q", token)
+	}
+
+	claims, err := ring.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if claims.TicketID != ticket.TicketID || claims.BookingID != ticket.BookingID || claims.ShowID != ticket.ShowID {
+		t.Errorf("Claims do not match the ticket: %+v", claims)
+	}
+}
+
+func TestKeyRingRejectsTamperedTokens(t *testing.T) {
+	ring, _ := NewKeyRing("k1:" + testKeyOld)
+	token, _ := ring.Sign(NewClaims(testTicket(), time.Now()))
+	parts := strings.Split(token, ".")
+
+	// Swap in a payload for another ticket, keeping the original signature
+	other := testTicket()
+	other.TicketID = "TK-FFFFFFFFFFFFFFFF"
+	forged, _ := ring.Sign(NewClaims(other, time.Now()))
+	forgedParts := strings.Split(forged, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"swapped payload", parts[0] + "." + forgedParts[1] + "." + parts[2], ErrInvalidSignature},
+		{"unknown key", "k9." + parts[1] + "." + parts[2], ErrUnknownSigningKey},
+		{"missing signature", parts[0] + "." + parts[1], ErrMalformedToken},
+		{"plain JSON", `{"bookingId":"BK-1"}`, ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ring.Verify(tt.token); err != tt.err {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	oldRing, _ := NewKeyRing("k1:" + testKeyOld)
+	oldToken, _ := oldRing.Sign(NewClaims(testTicket(), time.Now()))
+
+	rotated, err := NewKeyRing("k2:" + testKeyNew + ", k1:" + testKeyOld)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error: %v", err)
+	}
+	if rotated.ActiveKeyID() != "k2" {
+		t.Errorf("Expected the first key to be active, got %s", rotated.ActiveKeyID())
+	}
+	if _, err := rotated.Verify(oldToken); err != nil {
+		t.Errorf("Tokens signed with a retired key should still verify: %v", err)
+	}
+
+	newToken, _ := rotated.Sign(NewClaims(testTicket(), time.Now()))
+	if !strings.HasPrefix(newToken, "k2.") {
+		t.Errorf("Expected new tokens to use k2, got %q", newToken)
+	}
+	if _, err := oldRing.Verify(newToken); err != ErrUnknownSigningKey {
+		t.Errorf("Expected ErrUnknownSigningKey, got %v", err)
+	}
+}
+
+func TestNewKeyRingRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"", "k1", "k1:short", "bad id:" + testKeyOld, "k1:" + testKeyOld + ",k1:" + testKeyNew} {
+		if _, err := NewKeyRing(spec); err == nil {
+			t.Errorf("NewKeyRing(%q) should be rejected", spec)
+		}
+	}
+}
+
+func TestCheckAdmission(t *testing.T) {
+	ticket := testTicket()
+	claims := NewClaims(ticket, time.Now())
+	showDate := time.Date(2025, 6, 1, 19, 30, 0, 0, time.UTC)
+	window := EntryWindow{OpensBefore: 2 * time.Hour, ClosesAfter: time.Hour}
+	duringShow := showDate.Add(-30 * time.Minute)
+	otherShow := uuid.New()
+
+	voided := *ticket
+	voided.Status = StatusVoided
+	scanned := *ticket
+	scanned.Status = StatusCheckedIn
+	moved := *ticket
+	moved.BookingID = "BK-FFFFFFFFFFFFFFFF"
+
+	tests := []struct {
+		name       string
+		ticket     *Ticket
+		status     string
+		balanceDue int32
+		expected   *uuid.UUID
+		now        time.Time
+		reason     string
+	}{
+		{"confirmed", ticket, bookings.StatusConfirmed, 0, &ticket.ShowID, duringShow, ReasonAdmitted},
+		{"late no-show", ticket, bookings.StatusNoShow, 0, nil, showDate.Add(30 * time.Minute), ReasonAdmitted},
+		{"token for another booking", &moved, bookings.StatusConfirmed, 0, nil, duringShow, ReasonTicketNotFound},
+		{"wrong door", ticket, bookings.StatusConfirmed, 0, &otherShow, duringShow, ReasonWrongShow},
+		{"voided", &voided, bookings.StatusConfirmed, 0, nil, duringShow, ReasonTicketVoided},
+		{"unpaid", ticket, bookings.StatusPending, 0, nil, duringShow, ReasonBookingNotConfirmed},
+		{"added tickets unpaid", ticket, bookings.StatusConfirmed, 1500, nil, duringShow, ReasonBalanceDue},
+		{"cancelled with a balance", ticket, bookings.StatusCancelled, 1500, nil, duringShow, ReasonBookingInactive},
+		{"already in", &scanned, bookings.StatusCheckedIn, 0, nil, duringShow, ReasonAlreadyCheckedIn},
+		{"rest of a checked-in booking", ticket, bookings.StatusCheckedIn, 0, nil, duringShow, ReasonAdmitted},
+		{"cancelled", ticket, bookings.StatusCancelled, 0, nil, duringShow, ReasonBookingInactive},
+		{"too early", ticket, bookings.StatusConfirmed, 0, nil, showDate.Add(-3 * time.Hour), ReasonTooEarly},
+		{"too late", ticket, bookings.StatusConfirmed, 0, nil, showDate.Add(2 * time.Hour), ReasonShowEnded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckAdmission(claims, tt.ticket, tt.status, tt.balanceDue, showDate, window, tt.expected, tt.now); got != tt.reason {
+				t.Errorf("Expected %s, got %s", tt.reason, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package users
+
+import "context"
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	sessionKey
+)
+
+// NewContext returns a copy of ctx carrying the signed-in user and the
+// session their access token belongs to
+func NewContext(ctx context.Context, user *User, sessionID string) context.Context {
+	ctx = context.WithValue(ctx, userKey, user)
+	return context.WithValue(ctx, sessionKey, sessionID)
+}
+
+// FromContext returns the signed-in user, if the request was authenticated
+func FromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey).(*User)
+	return user, ok && user != nil
+}
+
+// SessionFromContext returns the session of the signed-in user, or "" when
+// the request was not authenticated
+func SessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey).(string)
+	return sessionID
+}
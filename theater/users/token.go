@@ -0,0 +1,151 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinSigningKeyLength is the shortest secret accepted for signing account tokens
+const MinSigningKeyLength = 32
+
+// Token types. Access tokens authenticate requests; refresh tokens are only
+// exchanged for a new pair.
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+)
+
+// tokenHeader is the JWT header of every token: HMAC-SHA256, nothing else is accepted
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Token errors. All of them mean the request is not authenticated.
+var (
+	ErrMalformedToken   = errors.New("invalid token: malformed")
+	ErrInvalidSignature = errors.New("invalid token: signature is invalid")
+	ErrTokenExpired     = errors.New("invalid token: expired")
+	ErrWrongTokenType   = errors.New("invalid token: wrong token type")
+)
+
+// Claims is what an account token vouches for
+type Claims struct {
+	Subject   string `json:"sub"` // User ID
+	SessionID string `json:"sid"` // Session the token belongs to; revoking it revokes the token
+	TokenID   string `json:"jti"`
+	Type      string `json:"typ"` // TokenAccess or TokenRefresh
+	IssuedAt  int64  `json:"iat"` // Unix seconds
+	ExpiresAt int64  `json:"exp"` // Unix seconds
+}
+
+// TokenPair is the tokens issued when signing in or refreshing
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`         // Always "Bearer"
+	ExpiresIn        int64  `json:"expires_in"`         // Seconds until the access token expires
+	RefreshExpiresIn int64  `json:"refresh_expires_in"` // Seconds until the refresh token expires
+}
+
+// Signer signs and checks account tokens, which are HS256 JSON Web Tokens
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner creates a signer for a secret of at least MinSigningKeyLength bytes
+func NewSigner(secret []byte) (*Signer, error) {
+	if len(secret) < MinSigningKeyLength {
+		return nil, fmt.Errorf("signing key must be at least %d characters", MinSigningKeyLength)
+	}
+	return &Signer{secret: secret}, nil
+}
+
+// NewClaims returns the claims of a token of the given type for a session,
+// valid for ttl from now
+func NewClaims(userID, sessionID, tokenType string, now time.Time, ttl time.Duration) (*Claims, error) {
+	tokenID, err := NewRandomID()
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{
+		Subject:   userID,
+		SessionID: sessionID,
+		TokenID:   tokenID,
+		Type:      tokenType,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}, nil
+}
+
+// NewRandomID returns a random ID for a session or token
+func NewRandomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Sign returns a signed token for claims
+func (s *Signer) Sign(claims *Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + s.signature(signed), nil
+}
+
+// Parse checks a token's signature, type and expiry and returns its claims
+func (s *Signer) Parse(token, tokenType string, now time.Time) (*Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return nil, ErrMalformedToken
+	}
+	// Only the header Sign writes is accepted, so "alg":"none" and other
+	// algorithms never reach the signature check
+	if parts[0] != tokenHeader {
+		return nil, ErrMalformedToken
+	}
+
+	expected := s.signature(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil || claims.Subject == "" || claims.SessionID == "" || claims.TokenID == "" {
+		return nil, ErrMalformedToken
+	}
+
+	if claims.Type != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func (s *Signer) signature(signed string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LooksLikeToken reports whether a bearer credential has the shape of an
+// account token rather than some other secret, such as the admin API key
+func LooksLikeToken(token string) bool {
+	return strings.HasPrefix(token, "eyJ") && strings.Count(token, ".") == 2
+}
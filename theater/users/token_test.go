@@ -0,0 +1,124 @@
+package users
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningKey = "account-secret-0123456789abcdefghijkl"
+
+func testSigner(t *testing.T) *Signer {
+	signer, err := NewSigner([]byte(testSigningKey))
+	if err != nil {
+		t.Fatalf("NewSigner() error: %v", err)
+	}
+	return signer
+}
+
+func TestNewSignerRejectsShortKeys(t *testing.T) {
+	if _, err := NewSigner([]byte("too-short")); err == nil {
+		t.Error("Expected a short signing key to be refused")
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	signer := testSigner(t)
+	now := time.Now()
+
+	claims, err := NewClaims("user-1", "session-1", TokenAccess, now, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewClaims() error: %v", err)
+	}
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+	if !LooksLikeToken(token) {
+		t.Errorf("Expected a JWT, got %q", token)
+	}
+
+	parsed, err := signer.Parse(token, TokenAccess, now)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if *parsed != *claims {
+		t.Errorf("Expected %+v, got %+v", claims, parsed)
+	}
+
+	other, _ := NewClaims("user-1", "session-1", TokenAccess, now, 15*time.Minute)
+	if other.TokenID == claims.TokenID {
+		t.Error("Expected every token to get its own ID")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	signer := testSigner(t)
+	now := time.Now()
+
+	claims, _ := NewClaims("user-1", "session-1", TokenAccess, now, 15*time.Minute)
+	token, _ := signer.Sign(claims)
+	parts := strings.Split(token, ".")
+
+	forgedClaims, _ := NewClaims("user-2", "session-1", TokenAccess, now, 15*time.Minute)
+	forged, _ := signer.Sign(forgedClaims)
+	forgedParts := strings.Split(forged, ".")
+
+	otherSigner, _ := NewSigner([]byte(strings.Repeat("x", MinSigningKeyLength)))
+	otherToken, _ := otherSigner.Sign(claims)
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"swapped payload", parts[0] + "." + forgedParts[1] + "." + parts[2], ErrInvalidSignature},
+		{"other key", otherToken, ErrInvalidSignature},
+		{"alg none", noneHeader + "." + parts[1] + ".", ErrMalformedToken},
+		{"alg none with signature", noneHeader + "." + parts[1] + "." + parts[2], ErrMalformedToken},
+		{"missing signature", parts[0] + "." + parts[1], ErrMalformedToken},
+		{"admin key", "admin-api-key", ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Parse(tt.token, TokenAccess, now); err != tt.err {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseChecksTypeAndExpiry(t *testing.T) {
+	signer := testSigner(t)
+	now := time.Now()
+
+	refresh, _ := NewClaims("user-1", "session-1", TokenRefresh, now, time.Hour)
+	token, _ := signer.Sign(refresh)
+	if _, err := signer.Parse(token, TokenAccess, now); err != ErrWrongTokenType {
+		t.Errorf("Expected a refresh token to be refused as an access token, got %v", err)
+	}
+	if _, err := signer.Parse(token, TokenRefresh, now); err != nil {
+		t.Errorf("Expected the refresh token to parse, got %v", err)
+	}
+	if _, err := signer.Parse(token, TokenRefresh, now.Add(time.Hour)); err != ErrTokenExpired {
+		t.Errorf("Expected the refresh token to expire, got %v", err)
+	}
+}
+
+func TestLooksLikeToken(t *testing.T) {
+	tests := map[string]bool{
+		"eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln": true,
+		"admin-api-key":                        false,
+		"eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0": false,
+		"":                                     false,
+	}
+	for token, expected := range tests {
+		if got := LooksLikeToken(token); got != expected {
+			t.Errorf("LooksLikeToken(%q) = %v, expected %v", token, got, expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ProviderLocal marks accounts that sign in with an email address and password
+const ProviderLocal = "local"
+
+const (
+	// MinPasswordLength is the shortest password accepted at registration
+	MinPasswordLength = 8
+	// maxPasswordLength is the most bytes bcrypt hashes; longer passwords would be truncated
+	maxPasswordLength = 72
+	maxNameLength     = 255
+)
+
+// passwordHashCost is the bcrypt work factor passwords are hashed with
+var passwordHashCost = 12
+
+// User is a customer account
+type User struct {
+	UserID       string    `json:"id"`
+	Email        string    `json:"email"` // Lower-cased, unique across accounts
+	Name         string    `json:"name"`
+	Phone        string    `json:"phone,omitempty"` // E.164
+	Provider     string    `json:"provider"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RegisterRequest represents the request payload for creating an account
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone,omitempty"`
+}
+
+// LoginRequest represents the request payload for signing in
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest carries the refresh token of a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session is what signing in returns: the account and its tokens
+type Session struct {
+	User   *User      `json:"user"`
+	Tokens *TokenPair `json:"tokens"`
+}
+
+// RegisterRequestFromJSON decodes a registration request body
+func RegisterRequestFromJSON(r *http.Request) (*RegisterRequest, error) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	if req.Email == "" || req.Password == "" || req.Name == "" {
+		return nil, fmt.Errorf("missing required fields: email, password, name")
+	}
+	return &req, nil
+}
+
+// LoginRequestFromJSON decodes a login request body
+func LoginRequestFromJSON(r *http.Request) (*LoginRequest, error) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	if req.Email == "" || req.Password == "" {
+		return nil, fmt.Errorf("missing required fields: email, password")
+	}
+	return &req, nil
+}
+
+// RefreshRequestFromJSON decodes a refresh or logout request body
+func RefreshRequestFromJSON(r *http.Request) (*RefreshRequest, error) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	if req.RefreshToken == "" {
+		return nil, fmt.Errorf("missing required fields: refresh_token")
+	}
+	return &req, nil
+}
+
+// NewUser validates a registration request and creates a local account with
+// the password hashed
+func NewUser(req *RegisterRequest) (*User, error) {
+	email, err := NormalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid name: name cannot be empty")
+	}
+	if len(name) > maxNameLength {
+		return nil, fmt.Errorf("invalid name: name cannot be longer than %d characters", maxNameLength)
+	}
+
+	phone := ""
+	if strings.TrimSpace(req.Phone) != "" {
+		phone, err = bookings.NormalizeContact(bookings.ContactMobile, req.Phone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid phone: %w", err)
+		}
+	}
+
+	passwordHash, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &User{
+		UserID:       uuid.New().String(),
+		Email:        email,
+		Name:         name,
+		Phone:        phone,
+		Provider:     ProviderLocal,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// NormalizeEmail checks an account email address and returns it in the form
+// accounts are stored and looked up in
+func NormalizeEmail(email string) (string, error) {
+	normalized, err := bookings.NormalizeContact(bookings.ContactEmail, email)
+	if err != nil {
+		return "", fmt.Errorf("invalid email: %w", err)
+	}
+	return normalized, nil
+}
+
+// ValidatePassword checks that a password can be hashed and is long enough
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("invalid password: must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("invalid password: must be at most %d bytes", maxPasswordLength)
+	}
+	return nil
+}
+
+// HashPassword validates a password and returns its bcrypt hash
+func HashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// PasswordMatches reports whether a password is the account's password
+func (u *User) PasswordMatches(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// ComparePasswordWithoutAccount takes as long as checking a password against
+// an account, so sign-ins for unknown emails cannot be told apart by timing
+func ComparePasswordWithoutAccount(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("no account has this password"), passwordHashCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
@@ -0,0 +1,90 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	// Full-cost hashing only slows the tests down
+	passwordHashCost = bcrypt.MinCost
+}
+
+func TestNewUser(t *testing.T) {
+	user, err := NewUser(&RegisterRequest{
+		Email:    " Jane.Doe@Example.COM ",
+		Password: "correct horse battery",
+		Name:     "  Jane Doe ",
+		Phone:    "+1 555-123-4567",
+	})
+	if err != nil {
+		t.Fatalf("NewUser() error: %v", err)
+	}
+
+	if user.UserID == "" || user.Provider != ProviderLocal {
+		t.Errorf("Expected a local account with an ID, got %+v", user)
+	}
+	if user.Email != "jane.doe@example.com" || user.Name != "Jane Doe" || user.Phone != "+15551234567" {
+		t.Errorf("Expected normalized details, got %q %q %q", user.Email, user.Name, user.Phone)
+	}
+	if user.PasswordHash == "" || strings.Contains(user.PasswordHash, "correct horse battery") {
+		t.Errorf("Expected the password to be hashed, got %q", user.PasswordHash)
+	}
+	if !user.PasswordMatches("correct horse battery") || user.PasswordMatches("wrong horse battery") {
+		t.Error("Expected only the registered password to match")
+	}
+
+	encoded, _ := json.Marshal(user)
+	if strings.Contains(string(encoded), user.PasswordHash) || strings.Contains(string(encoded), "password") {
+		t.Errorf("Expected the password hash to be left out of JSON, got %s", encoded)
+	}
+}
+
+func TestNewUserRejects(t *testing.T) {
+	valid := RegisterRequest{Email: "fan@example.com", Password: "long enough", Name: "Fan"}
+
+	tests := []struct {
+		name   string
+		modify func(req *RegisterRequest)
+		err    string
+	}{
+		{"bad email", func(req *RegisterRequest) { req.Email = "fan@example" }, "invalid email"},
+		{"short password", func(req *RegisterRequest) { req.Password = "short" }, "invalid password"},
+		{"long password", func(req *RegisterRequest) { req.Password = strings.Repeat("p", 73) }, "invalid password"},
+		{"blank name", func(req *RegisterRequest) { req.Name = "   " }, "invalid name"},
+		{"long name", func(req *RegisterRequest) { req.Name = strings.Repeat("n", 256) }, "invalid name"},
+		{"bad phone", func(req *RegisterRequest) { req.Phone = "555-CALL-NOW" }, "invalid phone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if _, err := NewUser(&req); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an %q error, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("Expected no user in an empty context")
+	}
+	if SessionFromContext(context.Background()) != "" {
+		t.Error("Expected no session in an empty context")
+	}
+
+	user := &User{UserID: "user-1"}
+	ctx := NewContext(context.Background(), user, "session-1")
+	if got, ok := FromContext(ctx); !ok || got != user {
+		t.Errorf("Expected the user back, got %v", got)
+	}
+	if SessionFromContext(ctx) != "session-1" {
+		t.Errorf("Expected session-1, got %q", SessionFromContext(ctx))
+	}
+}
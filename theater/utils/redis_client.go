@@ -2,8 +2,10 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
@@ -65,6 +67,63 @@ func AddToCache(key string, value string, redisAccess *RedisAccess) error {
 	return err
 }
 
+// AddToCacheWithExpiry sets a value that Redis removes once ttl has passed
+func AddToCacheWithExpiry(key string, value string, ttl time.Duration, redisAccess *RedisAccess) error {
+	err := redisAccess.client.Set(*redisAccess.context, key, value, ttl).Err()
+	if err != nil {
+		log.Println("Error setting value in Redis:", err)
+	} else {
+		log.Println("Value set in Redis:", key, "expires in", ttl)
+	}
+	return err
+}
+
+// SetIfAbsent sets a value that expires once ttl has passed, only when the
+// key does not exist yet. It reports whether the value was set.
+func SetIfAbsent(key string, value string, ttl time.Duration, redisAccess *RedisAccess) (bool, error) {
+	set, err := redisAccess.client.SetNX(*redisAccess.context, key, value, ttl).Result()
+	if err != nil {
+		log.Println("Error setting value in Redis:", err)
+		return false, err
+	}
+	return set, nil
+}
+
+// IncrementWithExpiry increments a counter that Redis removes once ttl has
+// passed since it was first incremented, and returns the new count
+func IncrementWithExpiry(key string, ttl time.Duration, redisAccess *RedisAccess) (int64, error) {
+	var count *redis.IntCmd
+	_, err := redisAccess.client.TxPipelined(*redisAccess.context, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(*redisAccess.context, key, 0, ttl)
+		count = pipe.Incr(*redisAccess.context, key)
+		return nil
+	})
+	if err != nil {
+		log.Println("Error incrementing counter in Redis:", err)
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// TimeToLive returns how long until key expires, or 0 when it does not
+// exist or never expires
+func TimeToLive(key string, redisAccess *RedisAccess) (time.Duration, error) {
+	ttl, err := redisAccess.client.TTL(*redisAccess.context, key).Result()
+	if err != nil {
+		log.Println("Error getting TTL from Redis:", err)
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// IsCacheMiss reports whether an error from a read means the key does not exist
+func IsCacheMiss(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
 func GetFromCache(key string, redisAccess *RedisAccess) (string, error) {
 	val, err := redisAccess.client.Get(*redisAccess.context, key).Result()
 	if err != nil {
@@ -105,6 +164,58 @@ func HashSet(key string, fields map[string]interface{}, redisAccess *RedisAccess
 	return res, nil
 }
 
+// HashSetWithExpiry replaces a hash with fields and sets it to expire once
+// ttl has passed, in one transaction
+func HashSetWithExpiry(key string, fields map[string]interface{}, ttl time.Duration, redisAccess *RedisAccess) error {
+	_, err := redisAccess.client.TxPipelined(*redisAccess.context, func(pipe redis.Pipeliner) error {
+		pipe.Del(*redisAccess.context, key)
+		pipe.HSet(*redisAccess.context, key, fields)
+		pipe.Expire(*redisAccess.context, key, ttl)
+		return nil
+	})
+	if err != nil {
+		log.Println("Error setting hash in Redis:", err)
+		return err
+	}
+	log.Println("Hash set in Redis:", key, "expires in", ttl)
+	return nil
+}
+
+// HashIncrement adds by to a hash field and returns the new value
+func HashIncrement(key string, field string, by int64, redisAccess *RedisAccess) (int64, error) {
+	res, err := redisAccess.client.HIncrBy(*redisAccess.context, key, field, by).Result()
+	if err != nil {
+		log.Println("Error incrementing hash field in Redis:", err)
+		return 0, err
+	}
+	return res, nil
+}
+
+// hashCompareAndSwap sets a hash field to a new value and renews the hash's
+// expiry, only while the field still holds the expected value
+var hashCompareAndSwap = redis.NewScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[4])
+	return 1
+end
+return 0
+`)
+
+// HashCompareAndSwap replaces a hash field's value with next and sets the
+// hash to expire once ttl has passed, provided the field still holds
+// expected. It reports whether the value was replaced; of several concurrent
+// swaps from the same value, only one succeeds.
+func HashCompareAndSwap(key string, field string, expected string, next string, ttl time.Duration, redisAccess *RedisAccess) (bool, error) {
+	swapped, err := hashCompareAndSwap.Run(*redisAccess.context, redisAccess.client, []string{key},
+		field, expected, next, ttl.Milliseconds()).Int()
+	if err != nil {
+		log.Println("Error swapping hash field in Redis:", err)
+		return false, err
+	}
+	return swapped == 1, nil
+}
+
 func HashGet(key string, field string, redisAccess *RedisAccess) (string, error) {
 	res, err := redisAccess.client.HGet(*redisAccess.context, key, field).Result()
 	if err != nil {
@@ -158,3 +269,37 @@ func addInstrumentation(client *redis.Client) {
 		panic(err)
 	}
 }
+
+// AddToSetWithExpiry adds a member to a set and sets the set to expire once
+// ttl has passed, in one transaction
+func AddToSetWithExpiry(key string, member string, ttl time.Duration, redisAccess *RedisAccess) error {
+	_, err := redisAccess.client.TxPipelined(*redisAccess.context, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(*redisAccess.context, key, member)
+		pipe.Expire(*redisAccess.context, key, ttl)
+		return nil
+	})
+	if err != nil {
+		log.Println("Error adding set member in Redis:", err)
+		return err
+	}
+	return nil
+}
+
+// SetMembers returns the members of a set, or none when it does not exist
+func SetMembers(key string, redisAccess *RedisAccess) ([]string, error) {
+	members, err := redisAccess.client.SMembers(*redisAccess.context, key).Result()
+	if err != nil {
+		log.Println("Error getting set members from Redis:", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// RemoveFromSet removes a member from a set
+func RemoveFromSet(key string, member string, redisAccess *RedisAccess) error {
+	if err := redisAccess.client.SRem(*redisAccess.context, key, member).Err(); err != nil {
+		log.Println("Error removing set member in Redis:", err)
+		return err
+	}
+	return nil
+}
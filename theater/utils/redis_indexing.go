@@ -17,6 +17,7 @@ const (
 	ShowsByAvailabilityPrefix = "shows:availability"
 	ShowsSearchPrefix         = "shows:search:"
 	ShowsAllKey               = "shows:all"
+	ShowSeatsPrefix           = "show:seats:"
 )
 
 // IndexedRedisClient extends the basic Redis functionality with indexing
@@ -115,6 +116,9 @@ func (irc *IndexedRedisClient) RemoveShowFromIndexes(showID string, showLocation
 	showHashKey := "show:" + showID
 	pipe.Del(ctx, showHashKey)
 
+	// Remove seat map
+	pipe.Del(ctx, ShowSeatsPrefix+showID)
+
 	// Note: Removing from search indexes would require knowing all terms,
 	// which is expensive. In practice, these can be cleaned up periodically.
 
@@ -381,6 +385,46 @@ func (irc *IndexedRedisClient) UpdateShowAvailability(showID string, availableTi
 	return nil
 }
 
+// GetShowAvailability returns the available tickets recorded for a show in the availability index
+func (irc *IndexedRedisClient) GetShowAvailability(showID string) (int32, error) {
+	ctx := *irc.context
+
+	score, err := irc.client.ZScore(ctx, ShowsByAvailabilityPrefix, showID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get show availability: %w", err)
+	}
+
+	return int32(score), nil
+}
+
+// SetShowSeats replaces a show's seat map hash (seat ID -> seat JSON)
+func (irc *IndexedRedisClient) SetShowSeats(showID string, seats map[string]interface{}) error {
+	ctx := *irc.context
+	key := ShowSeatsPrefix + showID
+
+	pipe := irc.client.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(seats) > 0 {
+		pipe.HSet(ctx, key, seats)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set show seats: %w", err)
+	}
+	return nil
+}
+
+// GetShowSeats returns a show's seat map hash (seat ID -> seat JSON)
+func (irc *IndexedRedisClient) GetShowSeats(showID string) (map[string]string, error) {
+	ctx := *irc.context
+
+	seats, err := irc.client.HGetAll(ctx, ShowSeatsPrefix+showID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show seats: %w", err)
+	}
+	return seats, nil
+}
+
 // GetShowStatistics returns statistics about indexed shows
 func (irc *IndexedRedisClient) GetShowStatistics() (map[string]interface{}, error) {
 	ctx := *irc.context
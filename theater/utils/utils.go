@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetEnvOrDefault(key, defaultValue string) string {
@@ -14,6 +15,65 @@ func GetEnvOrDefault(key, defaultValue string) string {
 	return val
 }
 
+// GetDurationOrDefault reads a duration such as "15m" from the environment,
+// falling back to the default when the variable is unset or malformed
+func GetDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	val := GetEnvOrDefault(key, "")
+	if val == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q for %s, using default %s", val, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// GetBoolOrDefault reads a boolean such as "true" or "0" from the environment,
+// falling back to the default when the variable is unset or malformed
+func GetBoolOrDefault(key string, defaultValue bool) bool {
+	val := GetEnvOrDefault(key, "")
+	if val == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Warning: invalid boolean %q for %s, using default %t", val, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetInt32OrDefault reads a whole number such as "8" from the environment,
+// falling back to the default when the variable is unset or malformed
+func GetInt32OrDefault(key string, defaultValue int32) int32 {
+	val := GetEnvOrDefault(key, "")
+	if val == "" {
+		return defaultValue
+	}
+	parsed, err := GetInt32(val)
+	if err != nil {
+		log.Printf("Warning: invalid number %q for %s, using default %d", val, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// RetryDelay returns how long to wait before the next attempt after the
+// given number of failed attempts: base after the first, doubling each time
+// up to max
+func RetryDelay(attempts int32, base, max time.Duration) time.Duration {
+	delay := base
+	for i := int32(1); i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
 func GetInt32(str string) (int32, error) {
 	val, err := strconv.ParseInt(str, 10, 32)
 	if err != nil {
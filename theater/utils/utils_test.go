@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGetEnvOrDefault(t *testing.T) {
@@ -56,6 +57,105 @@ func TestGetEnvOrDefault(t *testing.T) {
 	}
 }
 
+func TestGetDurationOrDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{"valid duration", "90s", 90 * time.Second},
+		{"zero duration", "0s", 0},
+		{"unset", "", 15 * time.Minute},
+		{"malformed", "soon", 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TEST_DURATION", tt.envValue)
+			defer os.Unsetenv("TEST_DURATION")
+
+			result := GetDurationOrDefault("TEST_DURATION", 15*time.Minute)
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetBoolOrDefault(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue bool
+		expected     bool
+	}{
+		{"true", "true", false, true},
+		{"zero", "0", true, false},
+		{"unset", "", true, true},
+		{"malformed", "maybe", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TEST_BOOL", tt.envValue)
+			defer os.Unsetenv("TEST_BOOL")
+
+			result := GetBoolOrDefault("TEST_BOOL", tt.defaultValue)
+			if result != tt.expected {
+				t.Errorf("Expected %t, got %t", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetInt32OrDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int32
+	}{
+		{"set", "3", 3},
+		{"unset", "", 8},
+		{"malformed", "many", 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TEST_INT32", tt.envValue)
+			defer os.Unsetenv("TEST_INT32")
+
+			result := GetInt32OrDefault("TEST_INT32", 8)
+			if result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		base     time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{1, 5 * time.Second, 30 * time.Minute, 5 * time.Second},
+		{2, 5 * time.Second, 30 * time.Minute, 10 * time.Second},
+		{3, 5 * time.Second, 30 * time.Minute, 20 * time.Second},
+		{10, 5 * time.Second, 30 * time.Minute, 30 * time.Minute},
+		{100, 5 * time.Second, 30 * time.Minute, 30 * time.Minute},
+		{10, 30 * time.Second, 6 * time.Hour, 256 * time.Minute},
+		{11, 30 * time.Second, 6 * time.Hour, 6 * time.Hour},
+		{0, time.Minute, time.Hour, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := RetryDelay(tt.attempts, tt.base, tt.max); got != tt.expected {
+			t.Errorf("RetryDelay(%d, %s, %s): expected %s, got %s", tt.attempts, tt.base, tt.max, tt.expected, got)
+		}
+	}
+}
+
 func TestGetInt32(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,254 @@
+package venues
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Show seat statuses
+const (
+	SeatAvailable = "available"
+	SeatReserved  = "reserved"
+)
+
+// Venue represents a physical layout that shows can be performed in
+type Venue struct {
+	VenueID   uuid.UUID `json:"venue_id"`
+	Name      string    `json:"name"`
+	Location  string    `json:"location"`
+	Seats     []*Seat   `json:"seats,omitempty"`
+	SeatCount int32     `json:"seat_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Seat is a single seat in a venue layout
+type Seat struct {
+	SeatID         string `json:"seat_id"` // "<section>-<row>-<number>", e.g. "STALLS-A-12"
+	Section        string `json:"section"`
+	Row            string `json:"row"`
+	Number         int32  `json:"number"`
+	Accessible     bool   `json:"accessible"`      // Wheelchair accessible
+	RestrictedView bool   `json:"restricted_view"` // Partially obstructed view of the stage
+}
+
+// ShowSeat is a venue seat's inventory for one performance
+type ShowSeat struct {
+	Seat
+	Status    string `json:"status"` // "available" or "reserved"
+	BookingID string `json:"-"`      // Never exposed on the public seat map
+}
+
+// SeatMap is the seat-level availability of a show
+type SeatMap struct {
+	ShowID         uuid.UUID   `json:"show_id"`
+	VenueID        uuid.UUID   `json:"venue_id"`
+	TotalSeats     int32       `json:"total_seats"`
+	AvailableSeats int32       `json:"available_seats"`
+	Seats          []*ShowSeat `json:"seats"`
+}
+
+// LayoutRequest is the payload for creating a venue
+type LayoutRequest struct {
+	Name     string          `json:"name"`
+	Location string          `json:"location"`
+	Sections []SectionLayout `json:"sections"`
+}
+
+// SectionLayout describes the rows of one section
+type SectionLayout struct {
+	Name string      `json:"name"`
+	Rows []RowLayout `json:"rows"`
+}
+
+// RowLayout describes a row of consecutively numbered seats, starting at 1
+type RowLayout struct {
+	Name           string  `json:"name"`
+	Seats          int32   `json:"seats"`
+	Accessible     []int32 `json:"accessible,omitempty"`      // Seat numbers that are wheelchair accessible
+	RestrictedView []int32 `json:"restricted_view,omitempty"` // Seat numbers with a restricted view
+}
+
+// NewVenueFromJSON creates a venue from a JSON layout request body
+func NewVenueFromJSON(r *http.Request) (*Venue, error) {
+	var req LayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	return NewVenue(req)
+}
+
+// NewVenue validates a layout and expands it into individual seats
+func NewVenue(req LayoutRequest) (*Venue, error) {
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Location) == "" {
+		return nil, fmt.Errorf("missing required fields: name, location")
+	}
+	if len(req.Sections) == 0 {
+		return nil, fmt.Errorf("venue layout must have at least one section")
+	}
+
+	venue := &Venue{
+		VenueID:   uuid.New(),
+		Name:      req.Name,
+		Location:  req.Location,
+		Seats:     []*Seat{},
+		CreatedAt: time.Now(),
+	}
+
+	sections := make(map[string]bool)
+	for _, section := range req.Sections {
+		sectionName := normalizeLabel(section.Name)
+		if sectionName == "" {
+			return nil, fmt.Errorf("section name cannot be empty")
+		}
+		if sections[sectionName] {
+			return nil, fmt.Errorf("duplicate section: %s", sectionName)
+		}
+		sections[sectionName] = true
+
+		if len(section.Rows) == 0 {
+			return nil, fmt.Errorf("section %s must have at least one row", sectionName)
+		}
+
+		rows := make(map[string]bool)
+		for _, row := range section.Rows {
+			seats, err := expandRow(sectionName, row)
+			if err != nil {
+				return nil, err
+			}
+
+			rowName := seats[0].Row
+			if rows[rowName] {
+				return nil, fmt.Errorf("duplicate row %s in section %s", rowName, sectionName)
+			}
+			rows[rowName] = true
+
+			venue.Seats = append(venue.Seats, seats...)
+		}
+	}
+
+	venue.SeatCount = int32(len(venue.Seats))
+	return venue, nil
+}
+
+// expandRow turns a row layout into its seats
+func expandRow(section string, row RowLayout) ([]*Seat, error) {
+	rowName := normalizeLabel(row.Name)
+	if rowName == "" {
+		return nil, fmt.Errorf("row name cannot be empty in section %s", section)
+	}
+	if row.Seats <= 0 {
+		return nil, fmt.Errorf("row %s in section %s must have at least one seat", rowName, section)
+	}
+
+	accessible, err := seatNumberSet(row.Accessible, row.Seats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accessible seats in %s-%s: %w", section, rowName, err)
+	}
+	restricted, err := seatNumberSet(row.RestrictedView, row.Seats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid restricted view seats in %s-%s: %w", section, rowName, err)
+	}
+
+	seats := make([]*Seat, 0, row.Seats)
+	for number := int32(1); number <= row.Seats; number++ {
+		seats = append(seats, &Seat{
+			SeatID:         SeatLabel(section, rowName, number),
+			Section:        section,
+			Row:            rowName,
+			Number:         number,
+			Accessible:     accessible[number],
+			RestrictedView: restricted[number],
+		})
+	}
+	return seats, nil
+}
+
+func seatNumberSet(numbers []int32, seatsInRow int32) (map[int32]bool, error) {
+	set := make(map[int32]bool, len(numbers))
+	for _, number := range numbers {
+		if number < 1 || number > seatsInRow {
+			return nil, fmt.Errorf("seat %d is outside 1-%d", number, seatsInRow)
+		}
+		set[number] = true
+	}
+	return set, nil
+}
+
+// SeatLabel builds the seat ID used in bookings and seat maps
+func SeatLabel(section, row string, number int32) string {
+	return fmt.Sprintf("%s-%s-%d", section, row, number)
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToUpper(strings.TrimSpace(label))
+}
+
+// NormalizeSeatIDs upper-cases and trims requested seat IDs and rejects duplicates
+func NormalizeSeatIDs(seatIDs []string) ([]string, error) {
+	normalized := make([]string, 0, len(seatIDs))
+	seen := make(map[string]bool, len(seatIDs))
+	for _, seatID := range seatIDs {
+		seatID = normalizeLabel(seatID)
+		if seatID == "" {
+			continue
+		}
+		if seen[seatID] {
+			return nil, fmt.Errorf("seat %s requested more than once", seatID)
+		}
+		seen[seatID] = true
+		normalized = append(normalized, seatID)
+	}
+	return normalized, nil
+}
+
+// SortSeats orders seats by section, row and seat number
+func SortSeats(seats []*ShowSeat) {
+	sort.Slice(seats, func(i, j int) bool {
+		if seats[i].Section != seats[j].Section {
+			return seats[i].Section < seats[j].Section
+		}
+		if seats[i].Row != seats[j].Row {
+			return seats[i].Row < seats[j].Row
+		}
+		return seats[i].Number < seats[j].Number
+	})
+}
+
+// NewSeatMap builds a seat map and its counts from a show's seats
+func NewSeatMap(showID, venueID uuid.UUID, seats []*ShowSeat) *SeatMap {
+	SortSeats(seats)
+
+	seatMap := &SeatMap{
+		ShowID:     showID,
+		VenueID:    venueID,
+		TotalSeats: int32(len(seats)),
+		Seats:      seats,
+	}
+	for _, seat := range seats {
+		if seat.Status == SeatAvailable {
+			seatMap.AvailableSeats++
+		}
+	}
+	return seatMap
+}
+
+// ToJSON converts a show seat to a JSON string
+func (s *ShowSeat) ToJSON() (string, error) {
+	jsonData, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// FromJSON populates a show seat from a JSON string
+func (s *ShowSeat) FromJSON(data string) error {
+	return json.Unmarshal([]byte(data), s)
+}
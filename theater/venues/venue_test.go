@@ -0,0 +1,150 @@
+package venues
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewVenue(t *testing.T) {
+	venue, err := NewVenue(LayoutRequest{
+		Name:     "Main Hall",
+		Location: "Downtown",
+		Sections: []SectionLayout{
+			{Name: "stalls", Rows: []RowLayout{
+				{Name: "a", Seats: 4, Accessible: []int32{1, 4}},
+				{Name: "b", Seats: 3, RestrictedView: []int32{3}},
+			}},
+			{Name: "Circle", Rows: []RowLayout{{Name: "A", Seats: 2}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewVenue() error: %v", err)
+	}
+
+	if venue.SeatCount != 9 || len(venue.Seats) != 9 {
+		t.Errorf("Expected 9 seats, got %d (%d in slice)", venue.SeatCount, len(venue.Seats))
+	}
+
+	if venue.VenueID == uuid.Nil {
+		t.Error("VenueID should not be nil")
+	}
+
+	first := venue.Seats[0]
+	if first.SeatID != "STALLS-A-1" || !first.Accessible || first.RestrictedView {
+		t.Errorf("Unexpected first seat: %+v", first)
+	}
+
+	restricted := venue.Seats[6]
+	if restricted.SeatID != "STALLS-B-3" || !restricted.RestrictedView {
+		t.Errorf("Expected STALLS-B-3 to have a restricted view, got %+v", restricted)
+	}
+}
+
+func TestNewVenueInvalidLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     LayoutRequest
+		message string
+	}{
+		{"missing name", LayoutRequest{Location: "Downtown", Sections: []SectionLayout{{Name: "S", Rows: []RowLayout{{Name: "A", Seats: 1}}}}}, "missing required fields"},
+		{"no sections", LayoutRequest{Name: "Hall", Location: "Downtown"}, "at least one section"},
+		{"empty row", LayoutRequest{Name: "Hall", Location: "Downtown", Sections: []SectionLayout{{Name: "S", Rows: []RowLayout{{Name: "A", Seats: 0}}}}}, "at least one seat"},
+		{"duplicate section", LayoutRequest{Name: "Hall", Location: "Downtown", Sections: []SectionLayout{
+			{Name: "S", Rows: []RowLayout{{Name: "A", Seats: 1}}},
+			{Name: "s", Rows: []RowLayout{{Name: "B", Seats: 1}}},
+		}}, "duplicate section"},
+		{"duplicate row", LayoutRequest{Name: "Hall", Location: "Downtown", Sections: []SectionLayout{
+			{Name: "S", Rows: []RowLayout{{Name: "A", Seats: 1}, {Name: "a", Seats: 2}}},
+		}}, "duplicate row"},
+		{"accessible out of range", LayoutRequest{Name: "Hall", Location: "Downtown", Sections: []SectionLayout{
+			{Name: "S", Rows: []RowLayout{{Name: "A", Seats: 2, Accessible: []int32{3}}}},
+		}}, "outside 1-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVenue(tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestNewVenueFromJSON(t *testing.T) {
+	body := `{"name":"Studio","location":"Uptown","sections":[{"name":"Floor","rows":[{"name":"A","seats":5}]}]}`
+	req := httptest.NewRequest("POST", "/api/v1/venues/create", strings.NewReader(body))
+
+	venue, err := NewVenueFromJSON(req)
+	if err != nil {
+		t.Fatalf("NewVenueFromJSON() error: %v", err)
+	}
+	if venue.SeatCount != 5 {
+		t.Errorf("Expected 5 seats, got %d", venue.SeatCount)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/venues/create", strings.NewReader("not json"))
+	if _, err := NewVenueFromJSON(req); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestNormalizeSeatIDs(t *testing.T) {
+	seatIDs, err := NormalizeSeatIDs([]string{" stalls-a-1", "STALLS-A-2", ""})
+	if err != nil {
+		t.Fatalf("NormalizeSeatIDs() error: %v", err)
+	}
+	if len(seatIDs) != 2 || seatIDs[0] != "STALLS-A-1" {
+		t.Errorf("Unexpected seat IDs: %v", seatIDs)
+	}
+
+	if _, err := NormalizeSeatIDs([]string{"STALLS-A-1", "stalls-a-1"}); err == nil {
+		t.Error("Expected error for duplicate seats")
+	}
+}
+
+func TestNewSeatMap(t *testing.T) {
+	seats := []*ShowSeat{
+		{Seat: Seat{SeatID: "STALLS-B-1", Section: "STALLS", Row: "B", Number: 1}, Status: SeatAvailable},
+		{Seat: Seat{SeatID: "STALLS-A-2", Section: "STALLS", Row: "A", Number: 2}, Status: SeatReserved, BookingID: "BK-1"},
+		{Seat: Seat{SeatID: "STALLS-A-1", Section: "STALLS", Row: "A", Number: 1}, Status: SeatAvailable},
+	}
+
+	seatMap := NewSeatMap(uuid.New(), uuid.New(), seats)
+
+	if seatMap.TotalSeats != 3 || seatMap.AvailableSeats != 2 {
+		t.Errorf("Expected 3 seats with 2 available, got %d/%d", seatMap.TotalSeats, seatMap.AvailableSeats)
+	}
+	if seatMap.Seats[0].SeatID != "STALLS-A-1" || seatMap.Seats[2].SeatID != "STALLS-B-1" {
+		t.Errorf("Seats not sorted: %s, %s, %s", seatMap.Seats[0].SeatID, seatMap.Seats[1].SeatID, seatMap.Seats[2].SeatID)
+	}
+}
+
+func TestShowSeatJSONRoundTrip(t *testing.T) {
+	seat := &ShowSeat{
+		Seat:      Seat{SeatID: "STALLS-A-1", Section: "STALLS", Row: "A", Number: 1, Accessible: true},
+		Status:    SeatReserved,
+		BookingID: "BK-1",
+	}
+
+	jsonStr, err := seat.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error: %v", err)
+	}
+
+	if strings.Contains(jsonStr, "BK-1") {
+		t.Errorf("Seat JSON should not expose the booking ID: %s", jsonStr)
+	}
+
+	decoded := &ShowSeat{}
+	if err := decoded.FromJSON(jsonStr); err != nil {
+		t.Fatalf("FromJSON() error: %v", err)
+	}
+	seat.BookingID = ""
+	if *decoded != *seat {
+		t.Errorf("Expected %+v, got %+v", seat, decoded)
+	}
+}
@@ -0,0 +1,149 @@
+package verification
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/bookings"
+	"github.com/gsmayya/theater/notifications"
+)
+
+// CodeLength is the number of digits in a one-time code
+const CodeLength = 6
+
+// TokenHeader carries a verification token on booking requests
+const TokenHeader = "Verification-Token"
+
+// Request asks for a code to be sent to a contact, or checks one
+type Request struct {
+	ContactType  string `json:"contact_type"` // "mobile" or "email"
+	ContactValue string `json:"contact_value"`
+	Code         string `json:"code,omitempty"` // Only when verifying
+}
+
+// RequestFromJSON decodes a verification request body
+func RequestFromJSON(r *http.Request) (*Request, error) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	if req.ContactType == "" || req.ContactValue == "" {
+		return nil, fmt.Errorf("missing required fields: contact_type, contact_value")
+	}
+	return &req, nil
+}
+
+// Challenge describes a code sent to a contact
+type Challenge struct {
+	ContactType string    `json:"contact_type"`
+	SentTo      string    `json:"sent_to"`      // Masked contact, e.g. a***@example.com
+	ExpiresAt   time.Time `json:"expires_at"`   // The code stops working after this
+	ResendAfter time.Time `json:"resend_after"` // No new code is sent before this
+}
+
+// Verification is the proof that a contact was verified. The token is
+// presented in the Verification-Token header when booking.
+type Verification struct {
+	Token       string    `json:"token"`
+	ContactType string    `json:"contact_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ContactKey identifies a contact regardless of how it was typed, using the
+// same normalized form bookings store it in
+func ContactKey(contactType, contactValue string) (string, error) {
+	value, err := bookings.NormalizeContact(contactType, contactValue)
+	if err != nil {
+		return "", fmt.Errorf("invalid contact: %w", err)
+	}
+	return contactType + ":" + value, nil
+}
+
+// GenerateCode returns a random numeric code of CodeLength digits
+func GenerateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < CodeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", CodeLength, n), nil
+}
+
+// HashCode returns the keyed hash a code is stored as. The contact key is
+// mixed in so a hash cannot be replayed for another contact, and the secret
+// stops a leaked store from being brute-forced offline.
+func HashCode(secret []byte, contactKey, code string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(contactKey + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CodeMatches compares a code entered by a customer with a stored hash in constant time
+func CodeMatches(secret []byte, contactKey, code, storedHash string) bool {
+	expected := HashCode(secret, contactKey, code)
+	return hmac.Equal([]byte(expected), []byte(storedHash))
+}
+
+// NewToken returns a random verification token
+func NewToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return "vt_" + hex.EncodeToString(buf), nil
+}
+
+// Fingerprint returns a stable digest of a contact key or token, so neither
+// appears in plain text in Redis keys or logs
+func Fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+// MaskContact hides most of a contact for display, keeping enough for the
+// customer to recognise it
+func MaskContact(contactType, contactValue string) string {
+	value := strings.TrimSpace(contactValue)
+	if contactType == notifications.ChannelEmail {
+		at := strings.LastIndex(value, "@")
+		if at <= 0 {
+			return "***"
+		}
+		return value[:1] + "***" + value[at:]
+	}
+
+	if len(value) <= 4 {
+		return "***"
+	}
+	return "***" + value[len(value)-4:]
+}
+
+// Message builds the text that delivers a code over a contact's channel
+func Message(contactType, contactValue, code string, validFor time.Duration) *notifications.Message {
+	validFor = validFor.Round(time.Minute)
+	if validFor < time.Minute {
+		validFor = time.Minute
+	}
+
+	message := &notifications.Message{
+		Channel: contactType,
+		To:      strings.TrimSpace(contactValue),
+		Body:    fmt.Sprintf("Your theater booking code is %s. It expires in %s. Do not share it with anyone.", code, notifications.StartsIn(validFor)),
+	}
+	if contactType == notifications.ChannelEmail {
+		message.Subject = "Your booking verification code"
+	}
+	return message
+}
@@ -0,0 +1,139 @@
+package verification
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		code, err := GenerateCode()
+		if err != nil {
+			t.Fatalf("GenerateCode() error: %v", err)
+		}
+		if len(code) != CodeLength {
+			t.Fatalf("Expected a %d digit code, got %q", CodeLength, code)
+		}
+		if strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("Expected only digits, got %q", code)
+		}
+	}
+}
+
+func TestCodeMatches(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	key, err := ContactKey("email", "Jane@Example.com")
+	if err != nil {
+		t.Fatalf("ContactKey() error: %v", err)
+	}
+	stored := HashCode(secret, key, "042917")
+
+	if stored == "042917" || strings.Contains(stored, "042917") {
+		t.Fatal("Expected the stored hash not to contain the code")
+	}
+	if !CodeMatches(secret, key, "042917", stored) {
+		t.Error("Expected the code to match its hash")
+	}
+	if !CodeMatches(secret, key, " 042917 ", stored) {
+		t.Error("Expected surrounding whitespace to be ignored")
+	}
+	if CodeMatches(secret, key, "042918", stored) {
+		t.Error("Expected a different code not to match")
+	}
+	if CodeMatches([]byte("fedcba9876543210"), key, "042917", stored) {
+		t.Error("Expected the code not to match under another secret")
+	}
+
+	otherKey, _ := ContactKey("email", "john@example.com")
+	if CodeMatches(secret, otherKey, "042917", stored) {
+		t.Error("Expected the code not to match for another contact")
+	}
+}
+
+func TestContactKey(t *testing.T) {
+	tests := []struct {
+		contactType  string
+		contactValue string
+		expected     string
+	}{
+		{"email", " Jane.Doe@Example.COM ", "email:jane.doe@example.com"},
+		{"mobile", "+1-555-123-4567", "mobile:+15551234567"},
+		{"mobile", "15551234567", "mobile:+15551234567"},
+		{"mobile", "(555) 123-4567", "mobile:+15551234567"},
+	}
+
+	for _, tt := range tests {
+		got, err := ContactKey(tt.contactType, tt.contactValue)
+		if err != nil {
+			t.Fatalf("ContactKey(%q, %q) error: %v", tt.contactType, tt.contactValue, err)
+		}
+		if got != tt.expected {
+			t.Errorf("ContactKey(%q, %q) = %q, expected %q", tt.contactType, tt.contactValue, got, tt.expected)
+		}
+	}
+
+	for _, contactType := range []string{"fax", "email"} {
+		if _, err := ContactKey(contactType, "not-a-contact"); err == nil || !strings.Contains(err.Error(), "invalid contact") {
+			t.Errorf("Expected an invalid contact error for %s, got %v", contactType, err)
+		}
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	first, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken() error: %v", err)
+	}
+	second, _ := NewToken()
+
+	if !strings.HasPrefix(first, "vt_") || len(first) != 51 {
+		t.Errorf("Unexpected token format %q", first)
+	}
+	if first == second {
+		t.Error("Expected tokens to differ")
+	}
+	if Fingerprint(first) == Fingerprint(second) || Fingerprint(first) != Fingerprint(first) {
+		t.Error("Expected fingerprints to be stable and distinct")
+	}
+	if strings.Contains(Fingerprint(first), first[3:]) {
+		t.Error("Expected the fingerprint not to contain the token")
+	}
+}
+
+func TestMaskContact(t *testing.T) {
+	tests := []struct {
+		contactType  string
+		contactValue string
+		expected     string
+	}{
+		{"email", "jane@example.com", "j***@example.com"},
+		{"email", "@example.com", "***"},
+		{"mobile", "+15551234567", "***4567"},
+		{"mobile", "123", "***"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskContact(tt.contactType, tt.contactValue); got != tt.expected {
+			t.Errorf("MaskContact(%q, %q) = %q, expected %q", tt.contactType, tt.contactValue, got, tt.expected)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	email := Message("email", " jane@example.com ", "042917", 10*time.Minute)
+	if email.Channel != "email" || email.To != "jane@example.com" || email.Subject == "" {
+		t.Errorf("Unexpected email message %+v", email)
+	}
+	if !strings.Contains(email.Body, "042917") || !strings.Contains(email.Body, "10 minutes") {
+		t.Errorf("Expected the code and its expiry in the body, got %q", email.Body)
+	}
+
+	sms := Message("mobile", "+15551234567", "042917", 30*time.Second)
+	if sms.Subject != "" {
+		t.Errorf("Expected no subject for a text message, got %q", sms.Subject)
+	}
+	if !strings.Contains(sms.Body, "expires in 1 minute.") {
+		t.Errorf("Expected short expiries to round up to a minute, got %q", sms.Body)
+	}
+}
@@ -0,0 +1,220 @@
+package waitlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+// Waitlist entry statuses
+const (
+	StatusWaiting  = "waiting"  // Queued for released tickets
+	StatusOffered  = "offered"  // Holding a pending booking until the offer expires
+	StatusAccepted = "accepted" // The offered booking was confirmed
+	StatusDeclined = "declined" // The offered booking was cancelled
+	StatusExpired  = "expired"  // The offered booking's hold lapsed
+	StatusRemoved  = "removed"  // Taken off the waitlist by an admin
+)
+
+// AllStatuses lists every waitlist entry status
+var AllStatuses = []string{
+	StatusWaiting,
+	StatusOffered,
+	StatusAccepted,
+	StatusDeclined,
+	StatusExpired,
+	StatusRemoved,
+}
+
+// Entry is a customer's place in a show's waitlist
+type Entry struct {
+	EntryID         string           `json:"entry_id"`
+	ShowID          uuid.UUID        `json:"show_id"`
+	ContactType     string           `json:"contact_type"` // "mobile" or "email"
+	ContactValue    string           `json:"contact_value"`
+	CustomerName    string           `json:"customer_name,omitempty"`
+	NumberOfTickets int32            `json:"number_of_tickets"`
+	TicketTypes     map[string]int32 `json:"ticket_types,omitempty"` // Quantity per ticket type code for shows with ticket types
+	Status          string           `json:"status"`
+	Position        int32            `json:"position,omitempty"`         // 1-based place in the queue while waiting
+	BookingID       string           `json:"booking_id,omitempty"`       // Pending booking created by the offer
+	OfferExpiresAt  *time.Time       `json:"offer_expires_at,omitempty"` // When the offered booking's hold lapses
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// EntryRequest represents the request payload for joining a waitlist
+type EntryRequest struct {
+	ShowID          string           `json:"show_id"`
+	ContactType     string           `json:"contact_type"`
+	ContactValue    string           `json:"contact_value"`
+	NumberOfTickets int32            `json:"number_of_tickets"` // May be omitted when ticket_types is given
+	CustomerName    string           `json:"customer_name,omitempty"`
+	TicketTypes     map[string]int32 `json:"ticket_types,omitempty"`
+}
+
+// NewEntry validates a waitlist request and creates a waiting entry
+func NewEntry(showID uuid.UUID, contactType, contactValue string, numberOfTickets int32, customerName string) (*Entry, error) {
+	if numberOfTickets <= 0 {
+		return nil, fmt.Errorf("number_of_tickets must be greater than 0")
+	}
+	contactValue, err := bookings.NormalizeContact(contactType, contactValue)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Entry{
+		EntryID:         uuid.New().String(),
+		ShowID:          showID,
+		ContactType:     contactType,
+		ContactValue:    contactValue,
+		CustomerName:    customerName,
+		NumberOfTickets: numberOfTickets,
+		Status:          StatusWaiting,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}, nil
+}
+
+// NewEntryFromRequest creates a waitlist entry from query parameters
+func NewEntryFromRequest(r *http.Request) (*Entry, error) {
+	showIDStr := r.URL.Query().Get("show_id")
+	contactType := r.URL.Query().Get("contact_type")
+	contactValue := r.URL.Query().Get("contact_value")
+	numberOfTicketsStr := r.URL.Query().Get("number_of_tickets")
+	customerName := r.URL.Query().Get("customer_name")
+
+	ticketTypes, err := bookings.ParseTicketSelection(r.URL.Query().Get("ticket_types"))
+	if err != nil {
+		return nil, err
+	}
+
+	if showIDStr == "" || contactType == "" || contactValue == "" || (numberOfTicketsStr == "" && len(ticketTypes) == 0) {
+		return nil, fmt.Errorf("missing required parameters: show_id, contact_type, contact_value, number_of_tickets")
+	}
+
+	showID, err := uuid.Parse(showIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show_id format: %w", err)
+	}
+
+	var numberOfTickets int64
+	if numberOfTicketsStr != "" {
+		numberOfTickets, err = strconv.ParseInt(numberOfTicketsStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number_of_tickets: %w", err)
+		}
+	}
+
+	return newEntryWithTicketTypes(showID, contactType, contactValue, int32(numberOfTickets), customerName, ticketTypes)
+}
+
+// NewEntryFromJSON creates a waitlist entry from a JSON request body
+func NewEntryFromJSON(r *http.Request) (*Entry, error) {
+	var req EntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	if req.ShowID == "" || req.ContactType == "" || req.ContactValue == "" || (req.NumberOfTickets <= 0 && len(req.TicketTypes) == 0) {
+		return nil, fmt.Errorf("missing required fields: show_id, contact_type, contact_value, number_of_tickets")
+	}
+
+	showID, err := uuid.Parse(req.ShowID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid show_id format: %w", err)
+	}
+
+	return newEntryWithTicketTypes(showID, req.ContactType, req.ContactValue, req.NumberOfTickets, req.CustomerName, req.TicketTypes)
+}
+
+// newEntryWithTicketTypes creates an entry whose ticket count is derived from
+// per-type quantities when they are given. number_of_tickets, if also given,
+// must agree with them.
+func newEntryWithTicketTypes(showID uuid.UUID, contactType, contactValue string, numberOfTickets int32, customerName string, quantities map[string]int32) (*Entry, error) {
+	if len(quantities) == 0 {
+		return NewEntry(showID, contactType, contactValue, numberOfTickets, customerName)
+	}
+
+	lines, err := bookings.NewTicketLines(quantities)
+	if err != nil {
+		return nil, err
+	}
+
+	total := bookings.TotalQuantity(lines)
+	if numberOfTickets != 0 && numberOfTickets != total {
+		return nil, fmt.Errorf("number_of_tickets (%d) does not match ticket type quantities (%d)", numberOfTickets, total)
+	}
+
+	entry, err := NewEntry(showID, contactType, contactValue, total, customerName)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.TicketTypes = make(map[string]int32, len(lines))
+	for _, line := range lines {
+		entry.TicketTypes[line.TicketType] = line.Quantity
+	}
+	return entry, nil
+}
+
+// IsActive reports whether the entry is still waiting for, or holding, an offer
+func (e *Entry) IsActive() bool {
+	return e.Status == StatusWaiting || e.Status == StatusOffered
+}
+
+// TicketLines returns the entry's requested ticket types as booking ticket lines
+func (e *Entry) TicketLines() ([]*bookings.TicketLine, error) {
+	if len(e.TicketTypes) == 0 {
+		return nil, nil
+	}
+	return bookings.NewTicketLines(e.TicketTypes)
+}
+
+// NewOfferBooking creates the pending booking that holds an offer's tickets
+// for the given duration. The booking is unpriced; the caller prices it with
+// the show's current prices.
+func (e *Entry) NewOfferBooking(holdDuration time.Duration) (*bookings.Booking, error) {
+	lines, err := e.TicketLines()
+	if err != nil {
+		return nil, err
+	}
+
+	booking := bookings.NewBooking(e.ShowID, e.ContactType, e.ContactValue, e.NumberOfTickets, 0)
+	booking.CustomerName = e.CustomerName
+	booking.TicketLines = lines
+	booking.PlaceHold(holdDuration)
+	return booking, nil
+}
+
+// OfferOutcome maps the status an offered booking moved to onto the entry
+// status it settles the offer with. It reports false for booking statuses
+// that leave the offer outstanding.
+func OfferOutcome(bookingStatus string) (string, bool) {
+	switch bookingStatus {
+	case bookings.StatusConfirmed:
+		return StatusAccepted, true
+	case bookings.StatusCancelled:
+		return StatusDeclined, true
+	case bookings.StatusExpired:
+		return StatusExpired, true
+	default:
+		return "", false
+	}
+}
+
+// IsValidStatus checks whether a status is a known waitlist entry status
+func IsValidStatus(status string) bool {
+	for _, known := range AllStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package waitlist
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/bookings"
+)
+
+func TestNewEntry(t *testing.T) {
+	showID := uuid.New()
+
+	entry, err := NewEntry(showID, "email", "fan@example.com", 2, "Fan")
+	if err != nil {
+		t.Fatalf("NewEntry() error: %v", err)
+	}
+
+	if entry.Status != StatusWaiting {
+		t.Errorf("Expected status %s, got %s", StatusWaiting, entry.Status)
+	}
+	if _, err := uuid.Parse(entry.EntryID); err != nil {
+		t.Errorf("EntryID should be a UUID, got %q", entry.EntryID)
+	}
+	if !entry.IsActive() {
+		t.Error("A waiting entry should be active")
+	}
+
+	tests := []struct {
+		name         string
+		contactType  string
+		contactValue string
+		tickets      int32
+	}{
+		{"zero tickets", "email", "fan@example.com", 0},
+		{"bad contact type", "fax", "fan@example.com", 1},
+		{"bad email", "email", "fan", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEntry(showID, tt.contactType, tt.contactValue, tt.tickets, ""); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestNewEntryFromRequest(t *testing.T) {
+	showID := uuid.New()
+
+	req := httptest.NewRequest("POST", "/api/v1/waitlist/join?show_id="+showID.String()+
+		"&contact_type=mobile&contact_value=1234567890&number_of_tickets=3", nil)
+	entry, err := NewEntryFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewEntryFromRequest() error: %v", err)
+	}
+	if entry.ShowID != showID || entry.NumberOfTickets != 3 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/waitlist/join?show_id=not-a-uuid&contact_type=mobile&contact_value=1234567890&number_of_tickets=3", nil)
+	if _, err := NewEntryFromRequest(req); err == nil {
+		t.Error("Expected error for invalid show_id")
+	}
+}
+
+func TestNewEntryFromJSON(t *testing.T) {
+	showID := uuid.New()
+	body := `{"show_id":"` + showID.String() + `","contact_type":"email","contact_value":"fan@example.com","number_of_tickets":2}`
+
+	entry, err := NewEntryFromJSON(httptest.NewRequest("POST", "/api/v1/waitlist/join", strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("NewEntryFromJSON() error: %v", err)
+	}
+	if entry.ContactValue != "fan@example.com" || entry.NumberOfTickets != 2 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	body = `{"show_id":"` + showID.String() + `","contact_type":"email"}`
+	if _, err := NewEntryFromJSON(httptest.NewRequest("POST", "/api/v1/waitlist/join", strings.NewReader(body))); err == nil {
+		t.Error("Expected error for missing fields")
+	}
+}
+
+func TestNewOfferBooking(t *testing.T) {
+	entry, err := NewEntry(uuid.New(), "email", "fan@example.com", 2, "Fan")
+	if err != nil {
+		t.Fatalf("NewEntry() error: %v", err)
+	}
+
+	before := time.Now()
+	booking, err := entry.NewOfferBooking(30 * time.Minute)
+	if err != nil {
+		t.Fatalf("NewOfferBooking() error: %v", err)
+	}
+	if err := booking.ApplyPrices(150, nil); err != nil {
+		t.Fatalf("ApplyPrices() error: %v", err)
+	}
+
+	if booking.Status != bookings.StatusPending {
+		t.Errorf("Expected pending booking, got %s", booking.Status)
+	}
+	if booking.TotalAmount != 300 || booking.NumberOfTickets != 2 || booking.CustomerName != "Fan" {
+		t.Errorf("Unexpected offer booking: %+v", booking)
+	}
+	if booking.HoldExpiresAt == nil || booking.HoldExpiresAt.Before(before.Add(30*time.Minute)) {
+		t.Errorf("Expected a 30 minute hold, got %v", booking.HoldExpiresAt)
+	}
+}
+
+func TestNewOfferBookingWithTicketTypes(t *testing.T) {
+	showID := uuid.New()
+	body := `{"show_id":"` + showID.String() + `","contact_type":"email","contact_value":"fan@example.com","ticket_types":{"Adult":2,"child":1}}`
+
+	entry, err := NewEntryFromJSON(httptest.NewRequest("POST", "/api/v1/waitlist/join", strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("NewEntryFromJSON() error: %v", err)
+	}
+	if entry.NumberOfTickets != 3 || entry.TicketTypes["adult"] != 2 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	booking, err := entry.NewOfferBooking(30 * time.Minute)
+	if err != nil {
+		t.Fatalf("NewOfferBooking() error: %v", err)
+	}
+	if err := booking.ApplyPrices(100, map[string]int32{"adult": 100, "child": 60}); err != nil {
+		t.Fatalf("ApplyPrices() error: %v", err)
+	}
+	if booking.TotalAmount != 260 || len(booking.TicketLines) != 2 {
+		t.Errorf("Unexpected offer booking: %+v", booking)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/waitlist/join?show_id="+showID.String()+
+		"&contact_type=email&contact_value=fan@example.com&number_of_tickets=2&ticket_types=adult:2,child:1", nil)
+	if _, err := NewEntryFromRequest(req); err == nil {
+		t.Error("Expected error when number_of_tickets does not match ticket types")
+	}
+}
+
+func TestOfferOutcome(t *testing.T) {
+	tests := []struct {
+		bookingStatus string
+		want          string
+		settled       bool
+	}{
+		{bookings.StatusConfirmed, StatusAccepted, true},
+		{bookings.StatusCancelled, StatusDeclined, true},
+		{bookings.StatusExpired, StatusExpired, true},
+		{bookings.StatusPending, "", false},
+		{bookings.StatusRefunded, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bookingStatus, func(t *testing.T) {
+			got, settled := OfferOutcome(tt.bookingStatus)
+			if got != tt.want || settled != tt.settled {
+				t.Errorf("OfferOutcome(%s) = %q, %t; want %q, %t", tt.bookingStatus, got, settled, tt.want, tt.settled)
+			}
+		})
+	}
+}
+
+func TestIsValidStatus(t *testing.T) {
+	for _, status := range AllStatuses {
+		if !IsValidStatus(status) {
+			t.Errorf("Expected %s to be valid", status)
+		}
+	}
+	if IsValidStatus("pending") {
+		t.Error("Booking statuses are not waitlist statuses")
+	}
+}
@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gsmayya/theater/events"
+	"github.com/gsmayya/theater/utils"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"   // Waiting for (another) attempt
+	DeliverySucceeded = "succeeded" // The endpoint answered 2xx
+	DeliveryFailed    = "failed"    // Gave up after too many failed attempts; can be redelivered
+)
+
+// Retry backoff: the first retry waits retryBaseDelay, doubling each time up to retryMaxDelay
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+)
+
+// maxErrorLength matches the last_error and error column widths
+const maxErrorLength = 1000
+
+// Payload is the JSON body posted to a partner's endpoint
+type Payload struct {
+	ID        string          `json:"id"` // Event ID; the same on every retry and redelivery, for de-duplication
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"` // The event payload
+}
+
+// Delivery is one event queued for one subscription, with its delivery log
+type Delivery struct {
+	DeliveryID     string          `json:"delivery_id"` // Random unique ID, e.g. WD-9F86D081884C7D65
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"` // Body posted to the endpoint
+	Status         string          `json:"status"`  // One of the Delivery* constants
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	AttemptLog     []*Attempt      `json:"attempt_log,omitempty"` // Every attempt, oldest first; only loaded for a single delivery
+}
+
+// Attempt is one HTTP request made for a delivery
+type Attempt struct {
+	AttemptedAt  time.Time `json:"attempted_at"`
+	StatusCode   int       `json:"status_code,omitempty"` // 0 when no response was received
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"` // Why the attempt failed; empty on success
+	DurationMs   int64     `json:"duration_ms"`
+}
+
+// NewDelivery queues an event for a subscription
+func NewDelivery(subscriptionID string, event *events.Event) (*Delivery, error) {
+	payload, err := json.Marshal(&Payload{
+		ID:        event.EventID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook delivery ID: %w", err)
+	}
+
+	now := time.Now()
+	return &Delivery{
+		DeliveryID:     fmt.Sprintf("WD-%X", buf),
+		SubscriptionID: subscriptionID,
+		EventID:        event.EventID,
+		EventType:      event.Type,
+		Payload:        payload,
+		Status:         DeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}, nil
+}
+
+// Succeeded reports whether the endpoint accepted the attempt
+func (a *Attempt) Succeeded() bool {
+	return a.Error == ""
+}
+
+// Record applies the outcome of an attempt: the delivery succeeds, is
+// retried with backoff, or fails for good once maxAttempts is reached
+func (d *Delivery) Record(attempt *Attempt, maxAttempts int32) {
+	d.Attempts++
+	d.LastStatusCode = attempt.StatusCode
+	d.LastError = attempt.Error
+
+	switch {
+	case attempt.Succeeded():
+		d.Status = DeliverySucceeded
+		d.DeliveredAt = &attempt.AttemptedAt
+	case d.Attempts >= maxAttempts:
+		d.Status = DeliveryFailed
+	default:
+		d.NextAttemptAt = attempt.AttemptedAt.Add(utils.RetryDelay(d.Attempts, retryBaseDelay, retryMaxDelay))
+	}
+}
+
+// truncate keeps a message or response body within its column
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,48 @@
+package webhooks
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDelivery(t *testing.T) {
+	delivery := newTestDelivery(t)
+
+	if !strings.HasPrefix(delivery.DeliveryID, "WD-") || len(delivery.DeliveryID) != 19 {
+		t.Errorf("Expected a WD- delivery ID of 19 characters, got %s", delivery.DeliveryID)
+	}
+	if delivery.Status != DeliveryPending || delivery.Attempts != 0 {
+		t.Errorf("Expected a pending delivery with no attempts, got %s with %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestDeliveryRecord(t *testing.T) {
+	now := time.Now()
+	failed := &Attempt{AttemptedAt: now, StatusCode: http.StatusBadGateway, Error: "endpoint answered 502 Bad Gateway"}
+
+	delivery := newTestDelivery(t)
+	delivery.Record(failed, 3)
+	if delivery.Status != DeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("Expected a pending delivery after 1 attempt, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+	if !delivery.NextAttemptAt.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("Expected the next attempt 30s later, got %s", delivery.NextAttemptAt.Sub(now))
+	}
+	if delivery.LastStatusCode != http.StatusBadGateway || delivery.LastError == "" {
+		t.Errorf("Expected the last status and error to be kept, got %d %q", delivery.LastStatusCode, delivery.LastError)
+	}
+
+	delivery.Record(failed, 3)
+	delivery.Record(failed, 3)
+	if delivery.Status != DeliveryFailed {
+		t.Errorf("Expected a failed delivery after 3 attempts, got %s", delivery.Status)
+	}
+
+	delivery = newTestDelivery(t)
+	delivery.Record(&Attempt{AttemptedAt: now, StatusCode: http.StatusOK}, 3)
+	if delivery.Status != DeliverySucceeded || delivery.DeliveredAt == nil || delivery.LastError != "" {
+		t.Errorf("Expected a succeeded delivery, got %s", delivery.Status)
+	}
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// userAgent identifies deliveries to partners
+const userAgent = "Theater-Webhooks/1.0"
+
+// Sender posts deliveries to subscription endpoints
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender whose requests give up after timeout. Redirects
+// are not followed: a 3xx answer counts as a failed attempt, so a moved
+// endpoint shows up in the delivery log instead of payloads silently going
+// somewhere else.
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send makes one attempt to post a delivery's payload, signed with the
+// subscription's secret. Any 2xx answer is a success.
+func (s *Sender) Send(subscription *Subscription, delivery *Delivery, now time.Time) *Attempt {
+	attempt := &Attempt{AttemptedAt: now}
+	start := time.Now()
+	defer func() {
+		attempt.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = truncate(fmt.Sprintf("failed to build request: %v", err))
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.DeliveryID)
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, delivery.Payload, now))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		attempt.Error = truncate(err.Error())
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	attempt.StatusCode = resp.StatusCode
+	attempt.ResponseBody = strings.ToValidUTF8(string(body), "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("endpoint answered %s", resp.Status)
+	}
+	return attempt
+}
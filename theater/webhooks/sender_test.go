@@ -0,0 +1,158 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gsmayya/theater/events"
+)
+
+const testSecret = "whsec_test_secret_0123456789"
+
+func newTestDelivery(t *testing.T) *Delivery {
+	t.Helper()
+	showID := uuid.New()
+	event, err := events.NewEvent(events.TypeBookingStatusChanged, "BK-1", &events.BookingStatusChange{
+		BookingID:  "BK-1",
+		ShowID:     showID,
+		FromStatus: "pending",
+		ToStatus:   "confirmed",
+	})
+	if err != nil {
+		t.Fatalf("NewEvent() error: %v", err)
+	}
+
+	delivery, err := NewDelivery("WH-1", event)
+	if err != nil {
+		t.Fatalf("NewDelivery() error: %v", err)
+	}
+	return delivery
+}
+
+func TestSendSignedPayload(t *testing.T) {
+	delivery := newTestDelivery(t)
+
+	var received *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	subscription := &Subscription{SubscriptionID: "WH-1", URL: receiver.URL + "/hooks", Secret: testSecret, Active: true}
+	now := time.Now()
+	attempt := NewSender(time.Second).Send(subscription, delivery, now)
+
+	if !attempt.Succeeded() || attempt.StatusCode != http.StatusAccepted || attempt.ResponseBody != "ok" {
+		t.Fatalf("Expected a successful 202 attempt, got %d %q (%s)", attempt.StatusCode, attempt.ResponseBody, attempt.Error)
+	}
+	if received.Method != http.MethodPost || received.URL.Path != "/hooks" {
+		t.Errorf("Expected POST /hooks, got %s %s", received.Method, received.URL.Path)
+	}
+	if received.Header.Get(EventHeader) != events.TypeBookingStatusChanged {
+		t.Errorf("Expected %s header %s, got %q", EventHeader, events.TypeBookingStatusChanged, received.Header.Get(EventHeader))
+	}
+	if received.Header.Get(DeliveryHeader) != delivery.DeliveryID {
+		t.Errorf("Expected %s header %s, got %q", DeliveryHeader, delivery.DeliveryID, received.Header.Get(DeliveryHeader))
+	}
+	if err := VerifySignature(testSecret, body, received.Header.Get(SignatureHeader), now, time.Minute); err != nil {
+		t.Errorf("Expected the receiver to verify the signature, got %v", err)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.ID != delivery.EventID || payload.Type != events.TypeBookingStatusChanged {
+		t.Errorf("Expected event %s of type %s, got %s of type %s", delivery.EventID, events.TypeBookingStatusChanged, payload.ID, payload.Type)
+	}
+	var change events.BookingStatusChange
+	if err := json.Unmarshal(payload.Data, &change); err != nil || change.ToStatus != "confirmed" {
+		t.Errorf("Expected the event payload in data, got %s", payload.Data)
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			http.Error(w, "database down", http.StatusInternalServerError)
+		case "/moved":
+			http.Redirect(w, r, "/elsewhere", http.StatusMovedPermanently)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		path       string
+		statusCode int
+		errorText  string
+	}{
+		{"/error", http.StatusInternalServerError, "500"},
+		{"/moved", http.StatusMovedPermanently, "301"},
+		{"/slow", 0, "Client.Timeout"},
+	}
+
+	sender := NewSender(50 * time.Millisecond)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			subscription := &Subscription{URL: receiver.URL + tt.path, Secret: testSecret, Active: true}
+			attempt := sender.Send(subscription, newTestDelivery(t), time.Now())
+
+			if attempt.Succeeded() {
+				t.Fatal("Expected the attempt to fail")
+			}
+			if attempt.StatusCode != tt.statusCode {
+				t.Errorf("Expected status %d, got %d", tt.statusCode, attempt.StatusCode)
+			}
+			if !strings.Contains(attempt.Error, tt.errorText) {
+				t.Errorf("Expected an error mentioning %q, got %q", tt.errorText, attempt.Error)
+			}
+		})
+	}
+}
+
+func TestSendRetriesUntilReceiverRecovers(t *testing.T) {
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	subscription := &Subscription{URL: receiver.URL, Secret: testSecret, Active: true}
+	delivery := newTestDelivery(t)
+	sender := NewSender(time.Second)
+
+	now := time.Now()
+	for delivery.Status == DeliveryPending {
+		attempt := sender.Send(subscription, delivery, now)
+		delivery.Record(attempt, 5)
+		if attempt.Succeeded() {
+			subscription.RecordSuccess()
+		} else {
+			subscription.RecordFailure(now, 10)
+		}
+		now = delivery.NextAttemptAt
+	}
+
+	if delivery.Status != DeliverySucceeded || delivery.Attempts != 3 {
+		t.Errorf("Expected success on attempt 3, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+	if subscription.ConsecutiveFailures != 0 || !subscription.Active {
+		t.Errorf("Expected an active subscription without failures, got %d failures", subscription.ConsecutiveFailures)
+	}
+}
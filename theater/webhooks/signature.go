@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	// SignatureHeader carries the payload signature, in the form
+	// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>"> keyed with the
+	// subscription secret
+	SignatureHeader = "Theater-Signature"
+	// EventHeader carries the event type
+	EventHeader = "Theater-Event"
+	// DeliveryHeader carries the delivery ID
+	DeliveryHeader = "Theater-Delivery"
+)
+
+// ErrInvalidSignature is returned by VerifySignature for payloads whose signature does not verify
+var ErrInvalidSignature = fmt.Errorf("invalid webhook signature")
+
+// Sign returns the SignatureHeader value for a payload signed at the given time
+func Sign(secret string, body []byte, signedAt time.Time) string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(computeSignature(secret, timestamp, body))
+}
+
+// VerifySignature checks a SignatureHeader value the way a receiver should:
+// the HMAC must match and the timestamp must be within tolerance of now, so
+// captured payloads cannot be replayed later
+func VerifySignature(secret string, body []byte, header string, now time.Time, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+	signedAt := time.Unix(seconds, 0)
+	if now.Sub(signedAt) > tolerance || signedAt.Sub(now) > tolerance {
+		return ErrInvalidSignature
+	}
+
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, computeSignature(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func computeSignature(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
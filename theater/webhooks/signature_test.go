@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"id":"EV-1","type":"show.created"}`)
+	now := time.Now()
+	header := Sign(testSecret, body, now)
+
+	if err := VerifySignature(testSecret, body, header, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		body   []byte
+		header string
+		now    time.Time
+	}{
+		{"wrong secret", "whsec_some_other_secret", body, header, now},
+		{"tampered body", testSecret, []byte(`{"id":"EV-2","type":"show.created"}`), header, now},
+		{"stale timestamp", testSecret, body, header, now.Add(10 * time.Minute)},
+		{"missing signature", testSecret, body, strings.Split(header, ",")[0], now},
+		{"empty header", testSecret, body, "", now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifySignature(tt.secret, tt.body, tt.header, tt.now, 5*time.Minute); err != ErrInvalidSignature {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gsmayya/theater/events"
+)
+
+// Column widths of webhook_subscriptions
+const (
+	maxURLLength         = 500
+	maxSecretLength      = 255
+	maxDescriptionLength = 255
+)
+
+// minSecretLength keeps signing secrets from being guessable
+const minSecretLength = 16
+
+// Subscription is a partner endpoint that receives signed payloads for the
+// event types it subscribed to
+type Subscription struct {
+	SubscriptionID      string     `json:"subscription_id"` // Random unique ID, e.g. WH-9F86D081884C7D65
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`      // Empty for every event type
+	Secret              string     `json:"secret,omitempty"` // Only returned when it is set; see Redacted
+	Description         string     `json:"description,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"` // Failed attempts since the last successful one
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// SubscriptionRequest represents the request payload for creating or updating a webhook subscription
+type SubscriptionRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Secret      string   `json:"secret,omitempty"` // Generated on create when empty; kept on update when empty
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"` // Defaults to true
+}
+
+// NewSubscriptionFromJSON creates a subscription from a JSON request body.
+// The subscription is validated with Validate before it is saved.
+func NewSubscriptionFromJSON(r *http.Request) (*Subscription, error) {
+	var req SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	subscription := &Subscription{
+		URL:         strings.TrimSpace(req.URL),
+		Secret:      strings.TrimSpace(req.Secret),
+		Description: strings.TrimSpace(req.Description),
+		Active:      req.Active == nil || *req.Active,
+	}
+	seen := make(map[string]bool, len(req.EventTypes))
+	for _, eventType := range req.EventTypes {
+		eventType = strings.ToLower(strings.TrimSpace(eventType))
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		seen[eventType] = true
+		subscription.EventTypes = append(subscription.EventTypes, eventType)
+	}
+
+	return subscription, nil
+}
+
+// NewSubscriptionID generates a random subscription ID
+func NewSubscriptionID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook subscription ID: %w", err)
+	}
+	return fmt.Sprintf("WH-%X", buf), nil
+}
+
+// GenerateSecret creates a random signing secret
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Validate checks a subscription's URL, event types and secret
+func (s *Subscription) Validate() error {
+	if s.URL == "" {
+		return fmt.Errorf("invalid webhook subscription: url cannot be empty")
+	}
+	if len(s.URL) > maxURLLength {
+		return fmt.Errorf("invalid webhook subscription: url is longer than %d characters", maxURLLength)
+	}
+	endpoint, err := url.Parse(s.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid webhook subscription: url %q must be an absolute http or https URL", s.URL)
+	}
+	if endpoint.User != nil {
+		return fmt.Errorf("invalid webhook subscription: url cannot contain credentials")
+	}
+
+	for _, eventType := range s.EventTypes {
+		if !events.IsKnownType(eventType) {
+			return fmt.Errorf("invalid webhook subscription: unknown event type %s", eventType)
+		}
+	}
+
+	if len(s.Secret) < minSecretLength {
+		return fmt.Errorf("invalid webhook subscription: secret must be at least %d characters", minSecretLength)
+	}
+	if len(s.Secret) > maxSecretLength {
+		return fmt.Errorf("invalid webhook subscription: secret is longer than %d characters", maxSecretLength)
+	}
+	if len(s.Description) > maxDescriptionLength {
+		return fmt.Errorf("invalid webhook subscription: description is longer than %d characters", maxDescriptionLength)
+	}
+	return nil
+}
+
+// Wants reports whether the subscription receives events of the given type
+func (s *Subscription) Wants(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, wanted := range s.EventTypes {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSuccess resets the failure count after a successful delivery attempt
+func (s *Subscription) RecordSuccess() {
+	s.ConsecutiveFailures = 0
+}
+
+// RecordFailure counts a failed delivery attempt and disables the
+// subscription once disableAfter attempts in a row have failed. It reports
+// whether this failure disabled it.
+func (s *Subscription) RecordFailure(now time.Time, disableAfter int32) bool {
+	s.ConsecutiveFailures++
+	if !s.Active || s.ConsecutiveFailures < disableAfter {
+		return false
+	}
+
+	s.Disable(now, fmt.Sprintf("disabled after %d consecutive failed deliveries", s.ConsecutiveFailures))
+	return true
+}
+
+// Disable stops deliveries to the subscription. Pending deliveries wait
+// until it is enabled again.
+func (s *Subscription) Disable(now time.Time, reason string) {
+	s.Active = false
+	s.DisabledAt = &now
+	s.DisabledReason = reason
+}
+
+// Enable resumes deliveries and clears the failure count
+func (s *Subscription) Enable() {
+	s.Active = true
+	s.ConsecutiveFailures = 0
+	s.DisabledAt = nil
+	s.DisabledReason = ""
+}
+
+// Redacted returns a copy of the subscription without its secret, for listings
+func (s *Subscription) Redacted() *Subscription {
+	redacted := *s
+	redacted.Secret = ""
+	return &redacted
+}